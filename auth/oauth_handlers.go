@@ -24,14 +24,22 @@ type Flash struct {
 	Message string `json:"message"`
 }
 
-// UserOperationsProvider interface that will be implemented by models package
+// UserOperationsProvider interface that will be implemented by models package.
+// Users are authorized by their email. The preferred username is the one
+// given by the provider's username claim, or empty to use the email.
 type UserOperationsProvider interface {
-	FindOrCreateUser(provider, oauthID, email string) (userID int64, username string, accountLocked bool, isAdmin bool, err error)
+	FindOrCreateUser(provider, oauthID, email, preferredUsername string) (userID int64, username string, accountLocked bool, isAdmin bool, err error)
 	UpdateLastLogin(userID int64) error
 	ValidateAdminPrivilege(userID int64) (bool, error)
 	LogSecurityEvent(userID int64, event, details string) error
 }
 
+// LoginAlerter is implemented by user operations which alert security
+// monitoring to authentication failures, such as locked accounts
+type LoginAlerter interface {
+	SendLoginAlert(r *http.Request, alertType, username, details string)
+}
+
 // OAuthHandler handles OAuth authentication flows with enhanced security
 type OAuthHandler struct {
 	config       *config.Config
@@ -225,7 +233,7 @@ func (h *OAuthHandler) HandleMicrosoftCallback(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	userID, username, accountLocked, isAdmin, err := h.userOps.FindOrCreateUser(userInfo.Provider, userInfo.ID, userInfo.Email)
+	userID, username, accountLocked, isAdmin, err := h.userOps.FindOrCreateUser(userInfo.Provider, userInfo.ID, userInfo.Email, userInfo.Username)
 	if err != nil {
 		log.Printf("Failed to find/create OAuth user: %v", err)
 		h.flashMessage(session, "danger", err.Error())
@@ -237,6 +245,7 @@ func (h *OAuthHandler) HandleMicrosoftCallback(w http.ResponseWriter, r *http.Re
 	// Check if account is locked
 	if accountLocked {
 		h.logSecurityEvent(userID, "login_blocked", "Account locked")
+		h.sendLoginAlert(r, "account_locked", username, fmt.Sprintf("Provider: %s", userInfo.Provider))
 		h.flashMessage(session, "danger", "Account is locked. Please contact your administrator.")
 		session.Save(r, w)
 		http.Redirect(w, r, "/login", http.StatusTemporaryRedirect)
@@ -249,6 +258,7 @@ func (h *OAuthHandler) HandleMicrosoftCallback(w http.ResponseWriter, r *http.Re
 		if err != nil || !isValidAdmin {
 			log.Printf("Admin validation failed for user %s: %v", userInfo.Email, err)
 			h.logSecurityEvent(userID, "admin_validation_failed", fmt.Sprintf("Email: %s", userInfo.Email))
+			h.sendLoginAlert(r, "admin_validation_failed", username, fmt.Sprintf("Provider: %s", userInfo.Provider))
 			h.flashMessage(session, "danger", "Admin access validation failed")
 			session.Save(r, w)
 			http.Redirect(w, r, "/login", http.StatusTemporaryRedirect)
@@ -408,6 +418,14 @@ func (h *OAuthHandler) logSecurityEvent(userID int64, event, details string) {
 	}
 }
 
+// sendLoginAlert alerts security monitoring to an authentication failure, if
+// the user operations support it
+func (h *OAuthHandler) sendLoginAlert(r *http.Request, alertType, username, details string) {
+	if alerter, ok := h.userOps.(LoginAlerter); ok {
+		alerter.SendLoginAlert(r, alertType, username, details)
+	}
+}
+
 // logSuspiciousActivity logs suspicious authentication attempts
 func (h *OAuthHandler) logSuspiciousActivity(r *http.Request, event, details string) {
 	ipAddress := h.extractIPFromRequest(r)
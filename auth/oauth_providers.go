@@ -5,14 +5,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strings"
 
 	"github.com/gophish/gophish/config"
+	"github.com/gophish/gophish/dialer"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/microsoft"
 )
 
+// withOutboundClient returns a context which makes the oauth2 package call the
+// provider with the configured outbound TLS settings.
+func withOutboundClient(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: dialer.Transport()})
+}
+
 // OAuthProvider interface for different OAuth providers
 type OAuthProvider interface {
 	GetAuthURL(state string, opts ...oauth2.AuthCodeOption) string
@@ -25,12 +33,42 @@ type OAuthProvider interface {
 	ValidateDomain(email string, allowedDomains []string) bool
 }
 
+// DefaultMicrosoftScopes are the scopes always requested from Microsoft.
+// Scopes configured for the provider are requested as well.
+var DefaultMicrosoftScopes = []string{"openid", "profile", "email", "User.Read"}
+
+// providerScopes returns the default scopes followed by any configured ones,
+// with blanks and duplicates removed. The defaults are always kept, since
+// the user can't be identified without them.
+func providerScopes(configured []string, defaults []string) []string {
+	scopes := []string{}
+	seen := map[string]bool{}
+	for _, s := range append(append([]string{}, defaults...), configured...) {
+		s = strings.TrimSpace(s)
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		scopes = append(scopes, s)
+	}
+	return scopes
+}
+
 // MicrosoftProvider implements Microsoft OAuth
 type MicrosoftProvider struct {
-	config   *oauth2.Config
-	tenantID string
+	config        *oauth2.Config
+	tenantID      string
+	usernameClaim string
 }
 
+// microsoftGraphUserURL is the Microsoft Graph endpoint returning the signed
+// in user's profile.
+const microsoftGraphUserURL = "https://graph.microsoft.com/v1.0/me"
+
+// microsoftUserFields are the fields of the Microsoft Graph user profile used
+// to identify the user.
+var microsoftUserFields = []string{"id", "displayName", "givenName", "surname", "mail", "userPrincipalName"}
+
 // NewMicrosoftProvider creates a new Microsoft OAuth provider
 func NewMicrosoftProvider(cfg *config.SSOProvider) *MicrosoftProvider {
 	tenantID := cfg.TenantID
@@ -43,14 +81,20 @@ func NewMicrosoftProvider(cfg *config.SSOProvider) *MicrosoftProvider {
 	oauthConfig := &oauth2.Config{
 		ClientID:     cfg.ClientID,
 		ClientSecret: cfg.ClientSecret,
-		Scopes:       []string{"openid", "profile", "email", "User.Read"},
+		Scopes:       providerScopes(cfg.Scopes, DefaultMicrosoftScopes),
 		Endpoint:     endpoint,
 		// RedirectURL will be set dynamically
 	}
 
+	usernameClaim := cfg.UsernameClaim
+	if usernameClaim == config.UsernameClaimEmail {
+		usernameClaim = ""
+	}
+
 	return &MicrosoftProvider{
-		config:   oauthConfig,
-		tenantID: tenantID,
+		config:        oauthConfig,
+		tenantID:      tenantID,
+		usernameClaim: usernameClaim,
 	}
 }
 
@@ -82,21 +126,27 @@ func (p *MicrosoftProvider) GetAuthURLWithPKCE(state string, pkce *PKCEChallenge
 }
 
 func (p *MicrosoftProvider) ExchangeCode(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error) {
-	return p.config.Exchange(ctx, code, opts...)
+	return p.config.Exchange(withOutboundClient(ctx), code, opts...)
 }
 
 func (p *MicrosoftProvider) ExchangeCodeWithPKCE(ctx context.Context, code string, pkce *PKCEChallenge) (*oauth2.Token, error) {
 	opts := []oauth2.AuthCodeOption{
 		oauth2.SetAuthURLParam("code_verifier", pkce.CodeVerifier),
 	}
-	return p.config.Exchange(ctx, code, opts...)
+	return p.config.Exchange(withOutboundClient(ctx), code, opts...)
 }
 
 func (p *MicrosoftProvider) GetUserInfo(ctx context.Context, token *oauth2.Token) (*OAuthUserInfo, error) {
-	client := p.config.Client(ctx, token)
-
-	// Use Microsoft Graph API to get user info
-	resp, err := client.Get("https://graph.microsoft.com/v1.0/me")
+	client := p.config.Client(withOutboundClient(ctx), token)
+
+	// Use Microsoft Graph API to get user info. Only the default fields are
+	// returned unless others are selected, so the username claim is
+	// requested explicitly.
+	userURL := microsoftGraphUserURL
+	if p.usernameClaim != "" {
+		userURL += "?$select=" + strings.Join(append(microsoftUserFields, p.usernameClaim), ",")
+	}
+	resp, err := client.Get(userURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user info: %w", err)
 	}
@@ -107,6 +157,17 @@ func (p *MicrosoftProvider) GetUserInfo(ctx context.Context, token *oauth2.Token
 		return nil, fmt.Errorf("Microsoft API error: %s", string(body))
 	}
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user info: %w", err)
+	}
+	return parseMicrosoftUserInfo(body, p.usernameClaim)
+}
+
+// parseMicrosoftUserInfo returns the user info from the Microsoft Graph user
+// profile. If a username claim is given, the user's username is taken from
+// it, falling back to their email if the profile doesn't have the claim.
+func parseMicrosoftUserInfo(body []byte, usernameClaim string) (*OAuthUserInfo, error) {
 	var msUser struct {
 		ID                string `json:"id"`
 		DisplayName       string `json:"displayName"`
@@ -119,7 +180,7 @@ func (p *MicrosoftProvider) GetUserInfo(ctx context.Context, token *oauth2.Token
 		Department        string `json:"department,omitempty"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&msUser); err != nil {
+	if err := json.Unmarshal(body, &msUser); err != nil {
 		return nil, fmt.Errorf("failed to decode user info: %w", err)
 	}
 
@@ -132,6 +193,20 @@ func (p *MicrosoftProvider) GetUserInfo(ctx context.Context, token *oauth2.Token
 		email = msUser.PreferredUsername
 	}
 
+	username := ""
+	if usernameClaim != "" {
+		claims := map[string]interface{}{}
+		if err := json.Unmarshal(body, &claims); err != nil {
+			return nil, fmt.Errorf("failed to decode user info: %w", err)
+		}
+		if claim, ok := claims[usernameClaim].(string); ok {
+			username = strings.TrimSpace(claim)
+		}
+		if username == "" {
+			log.Printf("Username claim %s missing for %s, using email as username", usernameClaim, email)
+		}
+	}
+
 	return &OAuthUserInfo{
 		Provider:  "microsoft",
 		ID:        msUser.ID,
@@ -139,6 +214,7 @@ func (p *MicrosoftProvider) GetUserInfo(ctx context.Context, token *oauth2.Token
 		Name:      msUser.DisplayName,
 		FirstName: msUser.GivenName,
 		LastName:  msUser.Surname,
+		Username:  username,
 	}, nil
 }
 
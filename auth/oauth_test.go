@@ -2,6 +2,9 @@ package auth
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -20,15 +23,15 @@ var _ = check.Suite(&OAuthSuite{})
 
 // Mock UserOperationsProvider for testing
 type mockUserOperationsProvider struct {
-	findOrCreateUserFunc    func(provider, oauthID, email string) (int64, string, bool, bool, error)
+	findOrCreateUserFunc    func(provider, oauthID, email, preferredUsername string) (int64, string, bool, bool, error)
 	updateLastLoginFunc     func(userID int64) error
 	validateAdminPrivilegeFunc func(userID int64) (bool, error)
 	logSecurityEventFunc   func(userID int64, event, details string) error
 }
 
-func (m *mockUserOperationsProvider) FindOrCreateUser(provider, oauthID, email string) (int64, string, bool, bool, error) {
+func (m *mockUserOperationsProvider) FindOrCreateUser(provider, oauthID, email, preferredUsername string) (int64, string, bool, bool, error) {
 	if m.findOrCreateUserFunc != nil {
-		return m.findOrCreateUserFunc(provider, oauthID, email)
+		return m.findOrCreateUserFunc(provider, oauthID, email, preferredUsername)
 	}
 	return 1, "test-user", false, true, nil
 }
@@ -195,6 +198,52 @@ func (s *OAuthSuite) TestMicrosoftProviderAuthURL(c *check.C) {
 	c.Assert(strings.Contains(authURL, "client_id=test-client-id"), check.Equals, true)
 }
 
+func (s *OAuthSuite) TestMicrosoftProviderConfiguredScopes(c *check.C) {
+	cfg := &config.SSOProvider{
+		ClientID:     "test-client-id",
+		ClientSecret: "test-client-secret",
+		Scopes:       []string{"offline_access", "email", " ", "api://gophish/Campaigns.Read", "offline_access"},
+	}
+
+	provider := NewMicrosoftProvider(cfg)
+	provider.SetRedirectURL("http://localhost:3333/auth/microsoft/callback")
+	c.Assert(provider.GetConfig().Scopes, check.DeepEquals,
+		[]string{"openid", "profile", "email", "User.Read", "offline_access", "api://gophish/Campaigns.Read"})
+
+	authURL, err := url.Parse(provider.GetAuthURL("test-state-123"))
+	c.Assert(err, check.IsNil)
+	scope := authURL.Query().Get("scope")
+	c.Assert(scope, check.Equals, "openid profile email User.Read offline_access api://gophish/Campaigns.Read")
+}
+
+func (s *OAuthSuite) TestMicrosoftUserInfoEmailAsUsername(c *check.C) {
+	body := []byte(`{"id":"ms-id","displayName":"Jane Doe","mail":"jane.doe@example.com","userPrincipalName":"jdoe@example.onmicrosoft.com"}`)
+	cfg := &config.SSOProvider{ClientID: "test-client-id", UsernameClaim: config.UsernameClaimEmail}
+	provider := NewMicrosoftProvider(cfg)
+
+	info, err := parseMicrosoftUserInfo(body, provider.usernameClaim)
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Email, check.Equals, "jane.doe@example.com")
+	c.Assert(info.Username, check.Equals, "")
+}
+
+func (s *OAuthSuite) TestMicrosoftUserInfoClaimAsUsername(c *check.C) {
+	body := []byte(`{"id":"ms-id","displayName":"Jane Doe","mail":"jane.doe@example.com","userPrincipalName":"jdoe@example.onmicrosoft.com"}`)
+	cfg := &config.SSOProvider{ClientID: "test-client-id", UsernameClaim: "userPrincipalName"}
+	provider := NewMicrosoftProvider(cfg)
+
+	info, err := parseMicrosoftUserInfo(body, provider.usernameClaim)
+	c.Assert(err, check.IsNil)
+	// The email is still used to authorize the user
+	c.Assert(info.Email, check.Equals, "jane.doe@example.com")
+	c.Assert(info.Username, check.Equals, "jdoe@example.onmicrosoft.com")
+
+	// Users without the claim fall back to their email
+	info, err = parseMicrosoftUserInfo(body, "onPremisesSamAccountName")
+	c.Assert(err, check.IsNil)
+	c.Assert(info.Username, check.Equals, "")
+}
+
 func (s *OAuthSuite) TestMicrosoftProviderPKCEAuthURL(c *check.C) {
 	cfg := &config.SSOProvider{
 		ClientID:     "test-client-id",
@@ -240,6 +289,31 @@ func (s *OAuthSuite) TestNewOAuthHandler(c *check.C) {
 	c.Assert(handler.maxAttempts, check.Equals, 5)
 }
 
+// mockLoginAlerter records the login alerts sent through it
+type mockLoginAlerter struct {
+	mockUserOperationsProvider
+	alerts []string
+}
+
+func (m *mockLoginAlerter) SendLoginAlert(r *http.Request, alertType, username, details string) {
+	m.alerts = append(m.alerts, alertType+":"+username)
+}
+
+func (s *OAuthSuite) TestOAuthHandlerSendsLoginAlerts(c *check.C) {
+	cfg := &config.Config{SSO: &config.SSOConfig{Enabled: true}}
+	r := httptest.NewRequest("GET", "/auth/microsoft/callback", nil)
+
+	alerter := &mockLoginAlerter{}
+	handler := NewOAuthHandler(cfg, &mockOAuthProvider{}, alerter)
+	handler.sendLoginAlert(r, "account_locked", "locked-user", "")
+	handler.sendLoginAlert(r, "admin_validation_failed", "admin-user", "")
+	c.Assert(alerter.alerts, check.DeepEquals, []string{"account_locked:locked-user", "admin_validation_failed:admin-user"})
+
+	// User operations which don't support alerts are left alone
+	handler = NewOAuthHandler(cfg, &mockOAuthProvider{}, &mockUserOperationsProvider{})
+	handler.sendLoginAlert(r, "account_locked", "locked-user", "")
+}
+
 func (s *OAuthSuite) TestOAuthHandlerStateGeneration(c *check.C) {
 	cfg := &config.Config{
 		SSO: &config.SSOConfig{
@@ -275,7 +349,7 @@ func (s *OAuthSuite) TestOAuthHandlerStateGeneration(c *check.C) {
 // Integration Tests
 func (s *OAuthSuite) TestOAuthUserOperationsInterface(c *check.C) {
 	mockUserOps := &mockUserOperationsProvider{
-		findOrCreateUserFunc: func(provider, oauthID, email string) (int64, string, bool, bool, error) {
+		findOrCreateUserFunc: func(provider, oauthID, email, preferredUsername string) (int64, string, bool, bool, error) {
 			c.Assert(provider, check.Equals, "microsoft")
 			c.Assert(oauthID, check.Equals, "test-user-id")
 			c.Assert(email, check.Equals, "test@example.com")
@@ -297,7 +371,7 @@ func (s *OAuthSuite) TestOAuthUserOperationsInterface(c *check.C) {
 	}
 
 	// Test FindOrCreateUser
-	userID, username, locked, admin, err := mockUserOps.FindOrCreateUser("microsoft", "test-user-id", "test@example.com")
+	userID, username, locked, admin, err := mockUserOps.FindOrCreateUser("microsoft", "test-user-id", "test@example.com", "")
 	c.Assert(err, check.IsNil)
 	c.Assert(userID, check.Equals, int64(42))
 	c.Assert(username, check.Equals, "test-username")
@@ -11,6 +11,9 @@ type OAuthUserInfo struct {
 	FirstName   string `json:"first_name,omitempty"`
 	LastName    string `json:"last_name,omitempty"`
 	AvatarURL   string `json:"avatar_url,omitempty"`
+	// Username is the user's username, taken from the provider's configured
+	// username claim. It's empty when the email is used as the username.
+	Username string `json:"username,omitempty"`
 }
 
 // OAuthState represents state for OAuth flow security
@@ -1,8 +1,14 @@
 package config
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
 
 	log "github.com/gophish/gophish/logger"
 )
@@ -14,8 +20,28 @@ type AdminServer struct {
 	CertPath             string   `json:"cert_path"`
 	KeyPath              string   `json:"key_path"`
 	CSRFKey              string   `json:"csrf_key"`
+	CSRFPreviousKey      string   `json:"csrf_previous_key,omitempty"`
+	CSRFKeyGraceMinutes  int      `json:"csrf_key_grace_minutes,omitempty"`
 	AllowedInternalHosts []string `json:"allowed_internal_hosts"`
 	TrustedOrigins       []string `json:"trusted_origins"`
+	TrustedProxies       []string `json:"trusted_proxies,omitempty"`
+	ExternalScheme       string   `json:"external_scheme,omitempty"`
+
+	SecurityHeaders map[string]string `json:"security_headers,omitempty"`
+
+	// LoginChallenge is the captcha required of IP addresses with repeated
+	// failed logins
+	LoginChallenge LoginChallenge `json:"login_challenge,omitempty"`
+}
+
+// LoginChallenge configures the captcha which IP addresses with repeated
+// failed logins must solve before they can log in. Provider is one of
+// "recaptcha", "hcaptcha" or "turnstile". The secret may also be set with
+// LOGIN_CHALLENGE_SECRET.
+type LoginChallenge struct {
+	Provider string `json:"provider,omitempty"`
+	SiteKey  string `json:"site_key,omitempty"`
+	Secret   string `json:"secret,omitempty"`
 }
 
 // PhishServer represents the Phish server configuration details
@@ -24,6 +50,70 @@ type PhishServer struct {
 	UseTLS    bool   `json:"use_tls"`
 	CertPath  string `json:"cert_path"`
 	KeyPath   string `json:"key_path"`
+
+	SecurityHeaders map[string]string `json:"security_headers,omitempty"`
+}
+
+// DefaultAdminSecurityHeaders returns the security headers sent with every
+// admin response unless they're overridden.
+func DefaultAdminSecurityHeaders() map[string]string {
+	return map[string]string{
+		"Content-Security-Policy": "frame-ancestors 'none';",
+		"X-Frame-Options":         "DENY",
+		"X-Content-Type-Options":  "nosniff",
+		"Referrer-Policy":         "same-origin",
+	}
+}
+
+// DefaultPhishSecurityHeaders returns the security headers sent with every
+// response from the phishing server unless they're overridden. Landing pages
+// often load assets from elsewhere, so no restrictions are placed on content
+// by default, but the recipient ID in the URL isn't leaked to those sites.
+func DefaultPhishSecurityHeaders() map[string]string {
+	return map[string]string{
+		"Content-Security-Policy": "frame-ancestors 'none';",
+		"X-Content-Type-Options":  "nosniff",
+		"Referrer-Policy":         "no-referrer",
+	}
+}
+
+// mergeSecurityHeaders returns the default headers overridden by the
+// configured ones. Headers configured with an empty value are left out.
+func mergeSecurityHeaders(defaults map[string]string, configured map[string]string) map[string]string {
+	for name, value := range configured {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if value == "" {
+			delete(defaults, name)
+			continue
+		}
+		defaults[name] = value
+	}
+	return defaults
+}
+
+// DefaultCSRFKeyGraceMinutes is how long the previous CSRF key is accepted
+// by default, matching the lifetime of the CSRF cookie.
+const DefaultCSRFKeyGraceMinutes = 12 * 60
+
+// GetCSRFKeyGracePeriod returns how long the previous CSRF key is accepted
+// after it's replaced. The configured csrf_previous_key is accepted for this
+// long after starting, so that the key can be changed across a restart.
+func (as AdminServer) GetCSRFKeyGracePeriod() time.Duration {
+	if as.CSRFKeyGraceMinutes <= 0 {
+		return DefaultCSRFKeyGraceMinutes * time.Minute
+	}
+	return time.Duration(as.CSRFKeyGraceMinutes) * time.Minute
+}
+
+// GetSecurityHeaders returns the security headers sent with admin responses.
+func (as AdminServer) GetSecurityHeaders() map[string]string {
+	return mergeSecurityHeaders(DefaultAdminSecurityHeaders(), as.SecurityHeaders)
+}
+
+// GetSecurityHeaders returns the security headers sent with responses from
+// the phishing server, such as landing pages.
+func (ps PhishServer) GetSecurityHeaders() map[string]string {
+	return mergeSecurityHeaders(DefaultPhishSecurityHeaders(), ps.SecurityHeaders)
 }
 
 // Config represents the configuration information.
@@ -38,8 +128,149 @@ type Config struct {
 	ContactAddress string      `json:"contact_address"`
 	Logging        *log.Config `json:"logging"`
 	SSO            *SSOConfig  `json:"sso,omitempty"`
+
+	RecipientSanitization    *RecipientSanitization `json:"recipient_sanitization,omitempty"`
+	TestRecipients           *TestRecipients        `json:"test_recipients,omitempty"`
+	MaxContentSize           int                    `json:"max_content_size,omitempty"`
+	TrackingHealthCheck      *TrackingHealthCheck   `json:"tracking_health_check,omitempty"`
+	StartJitterMinutes       int                    `json:"start_jitter_minutes,omitempty"`
+	DisableCompletionWebhook bool                   `json:"disable_completion_webhook,omitempty"`
+	QueuedCampaigns          *QueuedCampaigns       `json:"queued_campaigns,omitempty"`
+	DedupNormalizeEmails     bool                   `json:"dedup_normalize_emails,omitempty"`
+	CampaignCreatedNotify    *CampaignCreatedNotify `json:"campaign_created_notification,omitempty"`
+	DBPool                   *DBPool                `json:"db_pool,omitempty"`
+	MaxNameLength            int                    `json:"max_name_length,omitempty"`
+	LandingPageContext       *LandingPageContext    `json:"landing_page_context,omitempty"`
+	UniqueCampaignNames      string                 `json:"unique_campaign_names,omitempty"`
+	LoginAlertNotify         *LoginAlertNotify      `json:"login_alert_notification,omitempty"`
+	OutboundTLS              *OutboundTLS           `json:"outbound_tls,omitempty"`
+	AllowedTrackingDomains   []string               `json:"allowed_tracking_domains,omitempty"`
+	FieldEncryptionKeys      []string               `json:"field_encryption_keys,omitempty"`
+	TemplateFunctions        []string               `json:"template_functions,omitempty"`
+}
+
+// How campaigns named the same as one of the user's existing campaigns are
+// handled. Names are compared case-insensitively.
+const (
+	// UniqueNamesReject rejects the new campaign.
+	UniqueNamesReject = "reject"
+	// UniqueNamesSuffix renames the new campaign with a numbered suffix,
+	// such as "Quarterly Test (2)".
+	UniqueNamesSuffix = "suffix"
+)
+
+// RecipientSanitization controls how recipient names and positions are
+// normalized before they are stored or rendered into templates. By default,
+// template delimiters and control characters are stripped and the values are
+// HTML-escaped when rendered into HTML content.
+type RecipientSanitization struct {
+	Disabled  bool `json:"disabled"`
+	AllowHTML bool `json:"allow_html"`
+}
+
+// LandingPageContext controls which recipient details are available to
+// landing page templates, such as {{.FirstName}}. Fields lists the recipient
+// attributes exposed (first_name, last_name, email and position); if none
+// are listed, all of them are. Disabled withholds the recipient details
+// entirely.
+type LandingPageContext struct {
+	Disabled bool     `json:"disabled"`
+	Fields   []string `json:"fields"`
+}
+
+// TestRecipients restricts which addresses test emails may be sent to. If
+// both lists are empty, test emails may be sent to any address.
+type TestRecipients struct {
+	AllowedEmails  []string `json:"allowed_emails"`
+	AllowedDomains []string `json:"allowed_domains"`
+}
+
+// TrackingHealthCheck controls the check made before a campaign is launched
+// to confirm that its tracking URL routes back to this instance. By default a
+// failed check only logs a warning; in strict mode the campaign is rejected.
+// Path sets the path the phishing server answers the check on, which is
+// otherwise derived from the check's secret. The phishing server doesn't
+// answer the check at all when it's disabled.
+type TrackingHealthCheck struct {
+	Disabled bool   `json:"disabled"`
+	Strict   bool   `json:"strict"`
+	Path     string `json:"path"`
+}
+
+// QueuedCampaigns controls how the campaigns with emails due to be sent are
+// loaded on each poll of the worker. Workers sets how many campaigns are
+// loaded concurrently and BudgetSeconds caps how long a poll may spend loading
+// them. The emails of campaigns which aren't loaded within the budget are left
+// queued for the next poll.
+type QueuedCampaigns struct {
+	Workers       int `json:"workers"`
+	BudgetSeconds int `json:"budget_seconds"`
+}
+
+// DBPool controls the database connection pool. MaxOpenConns and
+// MaxIdleConns bound the number of open and idle connections, and
+// ConnMaxLifetime is the number of seconds a connection may be reused for, or
+// 0 to reuse connections forever. QueryTimeout is the number of seconds the
+// heavier queries, such as campaign statistics, may run before being
+// cancelled.
+//
+// SQLite databases should keep the default of a single open connection.
+type DBPool struct {
+	MaxOpenConns    int `json:"max_open_conns"`
+	MaxIdleConns    int `json:"max_idle_conns"`
+	ConnMaxLifetime int `json:"conn_max_lifetime"`
+	QueryTimeout    int `json:"query_timeout"`
+}
+
+// DefaultDBMaxOpenConns is the default maximum number of open database
+// connections.
+const DefaultDBMaxOpenConns = 1
+
+// DefaultDBMaxIdleConns is the default maximum number of idle database
+// connections.
+const DefaultDBMaxIdleConns = 2
+
+// DefaultDBQueryTimeout is the default number of seconds the heavier queries
+// may run for.
+const DefaultDBQueryTimeout = 30
+
+// CampaignCreatedNotify controls the notification sent whenever a campaign is
+// created. The notification is sent to URL, signed with Secret, if one is
+// given, and otherwise to every active webhook.
+type CampaignCreatedNotify struct {
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url,omitempty"`
+	Secret  string `json:"secret,omitempty"`
 }
 
+// LoginAlertNotify controls the alerts sent for authentication failures,
+// such as failed logins and lockouts, and for emergency access logins. Alerts
+// are sent to URL, signed with Secret, if one is given, and otherwise to
+// every active webhook.
+type LoginAlertNotify struct {
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url,omitempty"`
+	Secret  string `json:"secret,omitempty"`
+}
+
+// DefaultQueuedCampaignWorkers is the default number of queued campaigns
+// loaded concurrently.
+const DefaultQueuedCampaignWorkers = 4
+
+// DefaultQueuedCampaignBudget is the default number of seconds a poll may
+// spend loading queued campaigns. It is kept below the one minute polling
+// interval.
+const DefaultQueuedCampaignBudget = 45
+
+// DefaultMaxContentSize is the default maximum size, in bytes, of a template
+// or landing page body.
+const DefaultMaxContentSize = 1 << 20
+
+// DefaultMaxNameLength is the default maximum length, in characters, of the
+// name of a campaign, template, landing page or group. It is also the most
+// the name columns can hold.
+const DefaultMaxNameLength = 255
+
 // Version contains the current gophish version
 var Version = ""
 
@@ -68,6 +299,227 @@ func LoadConfig(filepath string) (*Config, error) {
 	return config, nil
 }
 
+// GetRecipientSanitization returns the recipient sanitization settings with
+// safe defaults if none were configured.
+func (c *Config) GetRecipientSanitization() *RecipientSanitization {
+	if c.RecipientSanitization != nil {
+		return c.RecipientSanitization
+	}
+	return &RecipientSanitization{}
+}
+
+// LandingPageFieldAllowed returns true if the given recipient attribute may
+// be rendered into landing pages.
+func (c *Config) LandingPageFieldAllowed(field string) bool {
+	lc := c.LandingPageContext
+	if lc == nil {
+		return true
+	}
+	if lc.Disabled {
+		return false
+	}
+	if len(lc.Fields) == 0 {
+		return true
+	}
+	for _, f := range lc.Fields {
+		if strings.EqualFold(strings.TrimSpace(f), field) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTestRecipientAllowed returns true if a test email may be sent to the
+// given address.
+func (c *Config) IsTestRecipientAllowed(email string) bool {
+	tr := c.TestRecipients
+	if tr == nil || (len(tr.AllowedEmails) == 0 && len(tr.AllowedDomains) == 0) {
+		return true
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	for _, allowed := range tr.AllowedEmails {
+		if strings.ToLower(strings.TrimSpace(allowed)) == email {
+			return true
+		}
+	}
+	at := strings.LastIndex(email, "@")
+	if at == -1 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, allowed := range tr.AllowedDomains {
+		allowed = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(allowed)), "@")
+		if allowed != "" && domain == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTrackingHostAllowed returns true if campaigns may track through the
+// given host, which may include a port. If no tracking domains are
+// configured, every host is allowed. Domains starting with "*." allow any
+// subdomain, while other domains only allow themselves.
+func (c *Config) IsTrackingHostAllowed(host string) bool {
+	if len(c.AllowedTrackingDomains) == 0 {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(host, ".")
+	if host == "" {
+		return false
+	}
+	for _, allowed := range c.AllowedTrackingDomains {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if strings.HasPrefix(allowed, "*.") {
+			if strings.HasSuffix(host, allowed[1:]) {
+				return true
+			}
+			continue
+		}
+		if allowed != "" && host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldEncryptionKeySize is the size in bytes of the keys encrypting
+// sensitive database fields.
+const FieldEncryptionKeySize = 32
+
+// ErrInvalidFieldEncryptionKey is returned when a field encryption key isn't
+// a base64 encoded 32 byte key.
+var ErrInvalidFieldEncryptionKey = errors.New("field encryption keys must be base64 encoded 32 byte keys")
+
+// GetFieldEncryptionKeys returns the decoded keys encrypting sensitive
+// database fields. The first key encrypts new values, while the rest are
+// earlier keys which are still used to decrypt values while the keys are
+// rotated. If no keys are configured, the fields are stored unencrypted.
+func (c *Config) GetFieldEncryptionKeys() ([][]byte, error) {
+	keys := [][]byte{}
+	for _, k := range c.FieldEncryptionKeys {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(k)
+		if err != nil || len(key) != FieldEncryptionKeySize {
+			return nil, ErrInvalidFieldEncryptionKey
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// GetMaxContentSize returns the maximum size, in bytes, of a template or
+// landing page body.
+func (c *Config) GetMaxContentSize() int {
+	if c.MaxContentSize > 0 {
+		return c.MaxContentSize
+	}
+	return DefaultMaxContentSize
+}
+
+// GetMaxNameLength returns the maximum length, in characters, of the name of
+// a campaign, template, landing page or group.
+func (c *Config) GetMaxNameLength() int {
+	if c.MaxNameLength > 0 && c.MaxNameLength < DefaultMaxNameLength {
+		return c.MaxNameLength
+	}
+	return DefaultMaxNameLength
+}
+
+// GetUniqueCampaignNames returns how duplicate campaign names are handled,
+// either UniqueNamesReject or UniqueNamesSuffix, or an empty string if
+// duplicate names are allowed.
+func (c *Config) GetUniqueCampaignNames() string {
+	mode := strings.ToLower(strings.TrimSpace(c.UniqueCampaignNames))
+	switch mode {
+	case "", UniqueNamesReject, UniqueNamesSuffix:
+		return mode
+	}
+	log.Warnf("Invalid unique_campaign_names value '%s', allowing duplicate campaign names", c.UniqueCampaignNames)
+	return ""
+}
+
+// GetTrackingHealthCheck returns the tracking health check settings with
+// safe defaults if none were configured.
+func (c *Config) GetTrackingHealthCheck() *TrackingHealthCheck {
+	if c.TrackingHealthCheck != nil {
+		return c.TrackingHealthCheck
+	}
+	return &TrackingHealthCheck{}
+}
+
+// GetQueuedCampaigns returns the queued campaign loading settings, filling in
+// defaults for any values which weren't configured.
+func (c *Config) GetQueuedCampaigns() QueuedCampaigns {
+	qc := QueuedCampaigns{}
+	if c.QueuedCampaigns != nil {
+		qc = *c.QueuedCampaigns
+	}
+	if qc.Workers <= 0 {
+		qc.Workers = DefaultQueuedCampaignWorkers
+	}
+	if qc.BudgetSeconds <= 0 {
+		qc.BudgetSeconds = DefaultQueuedCampaignBudget
+	}
+	return qc
+}
+
+// GetDBPool returns the database connection pool settings, filling in
+// defaults for any values which weren't configured.
+func (c *Config) GetDBPool() DBPool {
+	p := DBPool{}
+	if c.DBPool != nil {
+		p = *c.DBPool
+	}
+	if p.MaxOpenConns <= 0 {
+		p.MaxOpenConns = DefaultDBMaxOpenConns
+	}
+	if p.MaxIdleConns <= 0 {
+		p.MaxIdleConns = DefaultDBMaxIdleConns
+	}
+	if p.ConnMaxLifetime < 0 {
+		p.ConnMaxLifetime = 0
+	}
+	if p.QueryTimeout <= 0 {
+		p.QueryTimeout = DefaultDBQueryTimeout
+	}
+	return p
+}
+
+// IsTrustedProxy returns true if the given address (with or without a port)
+// matches one of the configured trusted proxies. Entries may be either single
+// IP addresses or CIDR ranges.
+func (as AdminServer) IsTrustedProxy(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(strings.TrimSpace(host))
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range as.TrustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if trusted := net.ParseIP(proxy); trusted != nil && trusted.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 // LoadConfigWithSSO loads the configuration and automatically populates OAuth secrets from environment
 // This is a convenience function that combines LoadConfig + LoadSecretsFromEnv
 func LoadConfigWithSSO(filepath string) (*Config, error) {
@@ -1,10 +1,17 @@
 package config
 
 import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	log "github.com/gophish/gophish/logger"
@@ -76,3 +83,288 @@ func TestLoadConfig(t *testing.T) {
 		t.Fatalf("expected error when loading invalid config, but got %v", err)
 	}
 }
+
+func TestIsTestRecipientAllowed(t *testing.T) {
+	conf := &Config{}
+	if !conf.IsTestRecipientAllowed("anyone@example.com") {
+		t.Fatalf("expected any recipient to be allowed without an allow-list")
+	}
+
+	conf.TestRecipients = &TestRecipients{
+		AllowedEmails:  []string{"Security-Team@Partner.com"},
+		AllowedDomains: []string{"@example.com", "corp.example.org"},
+	}
+	allowed := []string{
+		"security-team@partner.com",
+		"alice@example.com",
+		"BOB@Corp.Example.org",
+	}
+	for _, email := range allowed {
+		if !conf.IsTestRecipientAllowed(email) {
+			t.Fatalf("expected %s to be allowed", email)
+		}
+	}
+	blocked := []string{
+		"victim@partner.com",
+		"alice@evil-example.com",
+		"alice@sub.example.com",
+		"example.com",
+	}
+	for _, email := range blocked {
+		if conf.IsTestRecipientAllowed(email) {
+			t.Fatalf("expected %s to be blocked", email)
+		}
+	}
+}
+
+func TestIsTrustedProxy(t *testing.T) {
+	as := AdminServer{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1", "2001:db8::/32"}}
+	testCases := map[string]bool{
+		"10.1.2.3":           true,
+		"10.1.2.3:8080":      true,
+		"192.0.2.1:443":      true,
+		"192.0.2.2:443":      false,
+		"[2001:db8::1]:3333": true,
+		"198.51.100.1":       false,
+		"not-an-ip":          false,
+	}
+	for addr, expected := range testCases {
+		if got := as.IsTrustedProxy(addr); got != expected {
+			t.Fatalf("unexpected result for %s. expected %v got %v", addr, expected, got)
+		}
+	}
+	if (AdminServer{}).IsTrustedProxy("10.1.2.3") {
+		t.Fatalf("expected no trusted proxies when none are configured")
+	}
+}
+
+func TestGetQueuedCampaigns(t *testing.T) {
+	conf := &Config{}
+	qc := conf.GetQueuedCampaigns()
+	if qc.Workers != DefaultQueuedCampaignWorkers || qc.BudgetSeconds != DefaultQueuedCampaignBudget {
+		t.Fatalf("unexpected defaults: %+v", qc)
+	}
+	conf.QueuedCampaigns = &QueuedCampaigns{Workers: 8}
+	qc = conf.GetQueuedCampaigns()
+	if qc.Workers != 8 || qc.BudgetSeconds != DefaultQueuedCampaignBudget {
+		t.Fatalf("unexpected settings: %+v", qc)
+	}
+}
+
+func TestGetDBPool(t *testing.T) {
+	conf := &Config{}
+	p := conf.GetDBPool()
+	if p.MaxOpenConns != DefaultDBMaxOpenConns || p.MaxIdleConns != DefaultDBMaxIdleConns ||
+		p.ConnMaxLifetime != 0 || p.QueryTimeout != DefaultDBQueryTimeout {
+		t.Fatalf("unexpected defaults: %+v", p)
+	}
+	conf.DBPool = &DBPool{MaxOpenConns: 20, ConnMaxLifetime: 300}
+	p = conf.GetDBPool()
+	if p.MaxOpenConns != 20 || p.MaxIdleConns != DefaultDBMaxIdleConns || p.ConnMaxLifetime != 300 {
+		t.Fatalf("unexpected settings: %+v", p)
+	}
+}
+
+func TestGetMaxNameLength(t *testing.T) {
+	conf := &Config{}
+	if got := conf.GetMaxNameLength(); got != DefaultMaxNameLength {
+		t.Fatalf("unexpected default: %d", got)
+	}
+	conf.MaxNameLength = 64
+	if got := conf.GetMaxNameLength(); got != 64 {
+		t.Fatalf("expected 64, got %d", got)
+	}
+	// Names can't be longer than the database columns allow
+	conf.MaxNameLength = 1000
+	if got := conf.GetMaxNameLength(); got != DefaultMaxNameLength {
+		t.Fatalf("expected %d, got %d", DefaultMaxNameLength, got)
+	}
+}
+
+func TestGetUniqueCampaignNames(t *testing.T) {
+	conf := &Config{}
+	if got := conf.GetUniqueCampaignNames(); got != "" {
+		t.Fatalf("expected duplicates to be allowed by default, got %q", got)
+	}
+	conf.UniqueCampaignNames = " Suffix "
+	if got := conf.GetUniqueCampaignNames(); got != UniqueNamesSuffix {
+		t.Fatalf("expected %q, got %q", UniqueNamesSuffix, got)
+	}
+	conf.UniqueCampaignNames = "rename"
+	if got := conf.GetUniqueCampaignNames(); got != "" {
+		t.Fatalf("expected an invalid mode to be ignored, got %q", got)
+	}
+}
+
+func TestSSOProviderRedactsSecret(t *testing.T) {
+	sso := &SSOConfig{Providers: map[string]*SSOProvider{
+		"microsoft": {Enabled: true, ClientID: "client-id", ClientSecret: "s3cr3t-value"},
+	}}
+	b, err := json.Marshal(sso)
+	if err != nil {
+		t.Fatalf("error marshaling SSO config: %v", err)
+	}
+	if strings.Contains(string(b), "s3cr3t-value") {
+		t.Fatalf("client secret exposed: %s", b)
+	}
+	if !strings.Contains(string(b), "client-id") {
+		t.Fatalf("client ID missing: %s", b)
+	}
+}
+
+func TestValidateOAuthConfigScopes(t *testing.T) {
+	// Configured scopes are added to the provider's defaults, so they don't
+	// need to include openid
+	conf := &Config{SSO: &SSOConfig{Enabled: true, Providers: map[string]*SSOProvider{
+		"microsoft": {Enabled: true, ClientID: "id", ClientSecret: "secret", Scopes: []string{"offline_access"}},
+	}}}
+	if err := conf.ValidateOAuthConfig("microsoft"); err != nil {
+		t.Fatalf("unexpected error validating scopes: %v", err)
+	}
+	// Providers without scopes use their defaults
+	conf.SSO.Providers["microsoft"].Scopes = nil
+	if err := conf.ValidateOAuthConfig("microsoft"); err != nil {
+		t.Fatalf("unexpected error validating default scopes: %v", err)
+	}
+}
+
+func TestValidateOAuthConfigUsernameClaim(t *testing.T) {
+	conf := &Config{SSO: &SSOConfig{Enabled: true, Providers: map[string]*SSOProvider{
+		"microsoft": {Enabled: true, ClientID: "id", ClientSecret: "secret", UsernameClaim: "userPrincipalName"},
+	}}}
+	if err := conf.ValidateOAuthConfig("microsoft"); err != nil {
+		t.Fatalf("unexpected error validating username claim: %v", err)
+	}
+	conf.SSO.Providers["microsoft"].UsernameClaim = "upn,mail&$top=1"
+	if err := conf.ValidateOAuthConfig("microsoft"); err == nil {
+		t.Fatalf("expected invalid username claim to be rejected")
+	}
+}
+
+func TestGetSecurityHeaders(t *testing.T) {
+	as := AdminServer{}
+	if !reflect.DeepEqual(as.GetSecurityHeaders(), DefaultAdminSecurityHeaders()) {
+		t.Fatalf("unexpected default admin headers %v", as.GetSecurityHeaders())
+	}
+	ps := PhishServer{SecurityHeaders: map[string]string{
+		"content-security-policy": "default-src 'self' https://cdn.example.com",
+		"X-Content-Type-Options":  "",
+		"Permissions-Policy":      "camera=()",
+	}}
+	expected := map[string]string{
+		"Content-Security-Policy": "default-src 'self' https://cdn.example.com",
+		"Referrer-Policy":         "no-referrer",
+		"Permissions-Policy":      "camera=()",
+	}
+	got := ps.GetSecurityHeaders()
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected phish headers. expected %v got %v", expected, got)
+	}
+	// Overriding the phishing server's headers leaves the admin's alone
+	if DefaultAdminSecurityHeaders()["X-Content-Type-Options"] != "nosniff" {
+		t.Fatalf("admin defaults were modified")
+	}
+}
+
+func TestOutboundTLSConfig(t *testing.T) {
+	var o *OutboundTLS
+	tc, err := o.TLSConfig()
+	if err != nil || tc != nil {
+		t.Fatalf("expected the default TLS configuration, got %v, %v", tc, err)
+	}
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	f, err := ioutil.TempFile("", "gophish-ca")
+	if err != nil {
+		t.Fatalf("unable to create temporary CA bundle: %v", err)
+	}
+	defer os.Remove(f.Name())
+	err = pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err != nil {
+		t.Fatalf("error writing CA bundle: %v", err)
+	}
+	f.Close()
+
+	// The test server's certificate isn't trusted by default
+	client := &http.Client{Transport: &http.Transport{}}
+	if _, err := client.Get(ts.URL); err == nil {
+		t.Fatalf("expected an untrusted certificate error")
+	}
+
+	o = &OutboundTLS{RootCAsPath: f.Name(), MinVersion: "1.2"}
+	tc, err = o.TLSConfig()
+	if err != nil {
+		t.Fatalf("error building TLS configuration: %v", err)
+	}
+	if tc.MinVersion != tls.VersionTLS12 {
+		t.Fatalf("unexpected min version %d", tc.MinVersion)
+	}
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: tc}}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("error calling server trusted through the custom CA: %v", err)
+	}
+	resp.Body.Close()
+
+	for _, invalid := range []*OutboundTLS{
+		{MinVersion: "1.4"},
+		{RootCAsPath: "/nonexistent/ca.pem"},
+	} {
+		if _, err := invalid.TLSConfig(); err == nil {
+			t.Fatalf("expected an error for %+v", invalid)
+		}
+	}
+}
+
+func TestIsTrackingHostAllowed(t *testing.T) {
+	conf := &Config{}
+	if !conf.IsTrackingHostAllowed("anything.example.net") {
+		t.Fatalf("expected any tracking host to be allowed without an allow-list")
+	}
+
+	conf.AllowedTrackingDomains = []string{"Phish.Example.com", "*.corp.example.org"}
+	allowed := []string{
+		"phish.example.com",
+		"PHISH.example.com:8443",
+		"links.corp.example.org",
+		"a.b.corp.example.org:80",
+	}
+	for _, host := range allowed {
+		if !conf.IsTrackingHostAllowed(host) {
+			t.Fatalf("expected %s to be allowed", host)
+		}
+	}
+	blocked := []string{
+		"",
+		"example.com",
+		"sub.phish.example.com",
+		"corp.example.org",
+		"evilcorp.example.org",
+		"phish.example.com.attacker.net",
+	}
+	for _, host := range blocked {
+		if conf.IsTrackingHostAllowed(host) {
+			t.Fatalf("expected %s to be blocked", host)
+		}
+	}
+}
+
+func TestGetFieldEncryptionKeys(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), FieldEncryptionKeySize)
+	c := &Config{FieldEncryptionKeys: []string{base64.StdEncoding.EncodeToString(key), " "}}
+	keys, err := c.GetFieldEncryptionKeys()
+	if err != nil {
+		t.Fatalf("unexpected error getting keys: %v", err)
+	}
+	if len(keys) != 1 || !bytes.Equal(keys[0], key) {
+		t.Fatalf("unexpected keys %v", keys)
+	}
+
+	for _, invalid := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		c.FieldEncryptionKeys = []string{invalid}
+		if _, err := c.GetFieldEncryptionKeys(); err != ErrInvalidFieldEncryptionKey {
+			t.Fatalf("expected ErrInvalidFieldEncryptionKey for %q got %v", invalid, err)
+		}
+	}
+}
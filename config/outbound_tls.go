@@ -0,0 +1,58 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// OutboundTLS controls the TLS settings of the HTTP clients used to call
+// n8n and the OAuth provider. RootCAsPath is a PEM bundle of certificate
+// authorities trusted in addition to the system roots, such as the private
+// CA an internal n8n instance is issued from. MinVersion is the lowest TLS
+// version accepted, one of "1.0", "1.1", "1.2" or "1.3".
+type OutboundTLS struct {
+	RootCAsPath string `json:"root_cas_path,omitempty"`
+	MinVersion  string `json:"min_version,omitempty"`
+}
+
+// tlsVersions maps the configurable TLS versions to their identifiers
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig returns the TLS configuration for outbound clients. If no
+// settings are configured, nil is returned so that clients use the Go
+// defaults, which trust the system roots.
+func (o *OutboundTLS) TLSConfig() (*tls.Config, error) {
+	if o == nil || (o.RootCAsPath == "" && o.MinVersion == "") {
+		return nil, nil
+	}
+	tc := &tls.Config{}
+	if o.MinVersion != "" {
+		version, ok := tlsVersions[o.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid outbound TLS min_version %q, must be one of 1.0, 1.1, 1.2 or 1.3", o.MinVersion)
+		}
+		tc.MinVersion = version
+	}
+	if o.RootCAsPath != "" {
+		pem, err := ioutil.ReadFile(o.RootCAsPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read outbound TLS root CAs: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.RootCAsPath)
+		}
+		tc.RootCAs = pool
+	}
+	return tc, nil
+}
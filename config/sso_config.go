@@ -1,8 +1,11 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
 
 	"github.com/joho/godotenv"
 	log "github.com/gophish/gophish/logger"
@@ -17,6 +20,37 @@ type SSOProvider struct {
 	AllowedDomains []string `json:"allowed_domains"`
 	AdminDomains   []string `json:"admin_domains"`
 	DefaultRole    string   `json:"default_role"`
+	// Scopes requested from the provider in addition to its defaults, such
+	// as offline_access.
+	Scopes []string `json:"scopes,omitempty"`
+	// UsernameClaim is the claim used as the username of users signing in
+	// with the provider, such as userPrincipalName. The email is used by
+	// default, and is always used to authorize the user.
+	UsernameClaim string `json:"username_claim,omitempty"`
+}
+
+// UsernameClaimEmail is the username claim which uses the user's email as
+// their username.
+const UsernameClaimEmail = "email"
+
+// usernameClaimRegex matches the names of the claims which can be used as
+// usernames.
+var usernameClaimRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// IsValidUsernameClaim returns true if the claim can be used as a username.
+// An empty claim uses the email.
+func IsValidUsernameClaim(claim string) bool {
+	return claim == "" || usernameClaimRegex.MatchString(claim)
+}
+
+// MarshalJSON masks the client secret, so that it isn't exposed if the
+// provider is returned by an endpoint or logged. The config is only ever
+// read from disk, so it never needs to be written out in full.
+func (p SSOProvider) MarshalJSON() ([]byte, error) {
+	type provider SSOProvider
+	redacted := provider(p)
+	redacted.ClientSecret = log.RedactSecret(p.ClientSecret)
+	return json.Marshal(redacted)
 }
 
 // SSOConfig represents the SSO configuration
@@ -26,6 +60,15 @@ type SSOConfig struct {
 	HideLocalLogin   bool                    `json:"hide_local_login,omitempty"`
 	EmergencyAccess  bool                    `json:"emergency_access,omitempty"`
 	AdminEmails      []string                `json:"admin_emails,omitempty"`
+	// AllowSSOManagedPasswords lets accounts managed by SSO set and sign in
+	// with a local password
+	AllowSSOManagedPasswords bool `json:"allow_sso_managed_passwords,omitempty"`
+	// DisableAutoLink stops SSO sign-ins from being linked to existing
+	// accounts with the same email, unless the account was set up for SSO
+	DisableAutoLink bool `json:"disable_auto_link,omitempty"`
+	// AutoLinkAdmins lets SSO sign-ins be linked to existing admin accounts
+	// with the same email. Otherwise admin accounts must be set up for SSO.
+	AutoLinkAdmins bool `json:"auto_link_admins,omitempty"`
 	Providers        map[string]*SSOProvider `json:"providers"`
 }
 
@@ -72,6 +115,25 @@ func (c *Config) LoadSecretsFromEnv() {
 		log.Info("Using PostgreSQL connection string from environment variable")
 	}
 
+	// Load the CSRF keys from environment, so that they can be kept in a
+	// secret store
+	if key := os.Getenv("CSRF_KEY"); key != "" {
+		c.AdminConf.CSRFKey = key
+	}
+	if key := os.Getenv("CSRF_PREVIOUS_KEY"); key != "" {
+		c.AdminConf.CSRFPreviousKey = key
+	}
+
+	// Load the login captcha secret from environment
+	if secret := os.Getenv("LOGIN_CHALLENGE_SECRET"); secret != "" {
+		c.AdminConf.LoginChallenge.Secret = secret
+	}
+
+	// Load the keys encrypting sensitive database fields from environment
+	if keys := os.Getenv("FIELD_ENCRYPTION_KEYS"); keys != "" {
+		c.FieldEncryptionKeys = strings.Split(keys, ",")
+	}
+
 	// Load SSO configuration if available
 	if c.SSO == nil || c.SSO.Providers == nil {
 		return
@@ -131,6 +193,9 @@ func (c *Config) ValidateOAuthConfig(provider string) error {
 	if p.ClientSecret == "" {
 		return fmt.Errorf("OAuth provider '%s': client_secret is required", provider)
 	}
+	if !IsValidUsernameClaim(p.UsernameClaim) {
+		return fmt.Errorf("OAuth provider '%s': invalid username_claim '%s'", provider, p.UsernameClaim)
+	}
 
 	return nil
 }
@@ -156,6 +221,8 @@ func (c *Config) GetEffectiveProvider(provider string) *SSOProvider {
 		AllowedDomains: p.AllowedDomains,
 		AdminDomains:   p.AdminDomains,
 		DefaultRole:    p.DefaultRole,
+		Scopes:         p.Scopes,
+		UsernameClaim:  p.UsernameClaim,
 	}
 
 	// Override with environment variables if present
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	ctx "github.com/gophish/gophish/context"
+	"github.com/gophish/gophish/dialer"
 	log "github.com/gophish/gophish/logger"
 	"github.com/gophish/gophish/models"
 )
@@ -28,6 +29,7 @@ type AutopilotAgent1Response struct {
 	Confidence     int     `json:"confidence"`
 	Reasoning      string  `json:"reasoning"`
 	Error          string  `json:"error,omitempty"`
+	models.AutopilotGate
 }
 
 // AutopilotAgent2Request represents the request for target filtering
@@ -69,6 +71,7 @@ type AutopilotAgent3Response struct {
 	Confidence          int    `json:"confidence"`
 	Reasoning           string `json:"reasoning"`
 	Error               string `json:"error,omitempty"`
+	models.AutopilotGate
 }
 
 // AutopilotAgent1 handles email type matching via n8n workflow
@@ -127,6 +130,13 @@ func (as *Server) AutopilotAgent1(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Low confidence results are only suggested, so that the wrong email type
+	// isn't picked without the user noticing
+	agentResponse.AutopilotGate = models.AutopilotGate{}
+	if agentResponse.Success {
+		agentResponse.AutopilotGate = models.GateAutopilotResult(agentResponse.Confidence)
+	}
+
 	JSONResponse(w, agentResponse, http.StatusOK)
 }
 
@@ -211,6 +221,14 @@ func (as *Server) AutopilotAgent2(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Tag the group so it's cleaned up once no campaign needs it
+	if agentResponse.Success && agentResponse.GroupID != 0 {
+		err = models.MarkAutopilotGroup(agentResponse.GroupID, userID)
+		if err != nil {
+			log.Errorf("Failed to tag autopilot group %d: %v", agentResponse.GroupID, err)
+		}
+	}
+
 	JSONResponse(w, agentResponse, http.StatusOK)
 }
 
@@ -274,6 +292,13 @@ func (as *Server) AutopilotAgent3(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Low confidence results are only suggested, so that the wrong template
+	// isn't picked without the user noticing
+	agentResponse.AutopilotGate = models.AutopilotGate{}
+	if agentResponse.Success {
+		agentResponse.AutopilotGate = models.GateAutopilotResult(agentResponse.Confidence)
+	}
+
 	JSONResponse(w, agentResponse, http.StatusOK)
 }
 
@@ -296,7 +321,7 @@ func callN8NWebhook(webhookURL string, payload map[string]interface{}) ([]byte,
 		return nil, fmt.Errorf("failed to marshal payload: %v", err)
 	}
 
-	log.Debugf("Sending to n8n webhook: %s", string(payloadBytes))
+	log.Debugf("Sending to n8n webhook: %s", log.RedactJSON(payloadBytes))
 
 	// Create context with timeout
 	httpCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -312,9 +337,15 @@ func callN8NWebhook(webhookURL string, payload map[string]interface{}) ([]byte,
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	// Send request
+	// Send request, waiting for a free slot if n8n is busy
+	release, err := models.GetN8NLimiter().Acquire(httpCtx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout:   30 * time.Second,
+		Transport: dialer.Transport(),
 	}
 
 	resp, err := client.Do(req)
@@ -334,7 +365,7 @@ func callN8NWebhook(webhookURL string, payload map[string]interface{}) ([]byte,
 		return nil, fmt.Errorf("n8n webhook returned error (status %d): %s", resp.StatusCode, string(body))
 	}
 
-	log.Debugf("n8n webhook response: %s", string(body))
+	log.Debugf("n8n webhook response: %s", log.RedactJSON(body))
 	return body, nil
 }
 
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,12 +13,15 @@ import (
 	ctx "github.com/gophish/gophish/context"
 	log "github.com/gophish/gophish/logger"
 	"github.com/gophish/gophish/models"
+	"github.com/gophish/gophish/report"
 	"github.com/gorilla/mux"
 	"github.com/jinzhu/gorm"
 )
 
 // Campaigns returns a list of campaigns if requested via GET.
 // If requested via POST, APICampaigns creates a new campaign and returns a reference to it.
+// Campaigns whose send-by date is too aggressive are created with a
+// rate_limit_warning, or refused if reject_aggressive=true is given.
 func (as *Server) Campaigns(w http.ResponseWriter, r *http.Request) {
 	switch {
 	case r.Method == "GET":
@@ -33,7 +39,16 @@ func (as *Server) Campaigns(w http.ResponseWriter, r *http.Request) {
 			JSONResponse(w, models.Response{Success: false, Message: "Invalid JSON structure"}, http.StatusBadRequest)
 			return
 		}
-		err = models.PostCampaign(&c, ctx.Get(r, "user_id").(int64))
+		c.EnforceSendInterval, _ = strconv.ParseBool(r.URL.Query().Get("reject_aggressive"))
+		err = models.PostCampaignContext(r.Context(), &c, ctx.Get(r, "user_id").(int64))
+		if errors.Is(err, models.ErrCampaignNameExists) {
+			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusConflict)
+			return
+		}
+		if errors.Is(err, models.ErrSendIntervalTooShort) && c.EnforceSendInterval {
+			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusUnprocessableEntity)
+			return
+		}
 		if err != nil {
 			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
 			return
@@ -47,10 +62,66 @@ func (as *Server) Campaigns(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// CampaignsPreviewRecipients resolves the groups of a campaign which hasn't
+// been created yet, returning who it would be sent to and who would be
+// skipped as a duplicate, excluded or fatigued. Nothing is persisted.
+func (as *Server) CampaignsPreviewRecipients(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	c := models.Campaign{}
+	err := json.NewDecoder(r.Body).Decode(&c)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid JSON structure"}, http.StatusBadRequest)
+		return
+	}
+	p, err := models.PreviewCampaignRecipients(&c, ctx.Get(r, "user_id").(int64))
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	JSONResponse(w, p, http.StatusOK)
+}
+
+// CampaignsValidate resolves a campaign which hasn't been created yet the same
+// way as creating it, returning the send schedule and recipient counts it
+// would be created with and any rate limit warning. Nothing is persisted.
+// POST /api/campaigns/validate
+func (as *Server) CampaignsValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	c := models.Campaign{}
+	err := json.NewDecoder(r.Body).Decode(&c)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid JSON structure"}, http.StatusBadRequest)
+		return
+	}
+	v, err := models.ValidateCampaign(&c, ctx.Get(r, "user_id").(int64))
+	if errors.Is(err, models.ErrCampaignNameExists) {
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	JSONResponse(w, v, http.StatusOK)
+}
+
 // CampaignsSummary returns the summary for the current user's campaigns
 func (as *Server) CampaignsSummary(w http.ResponseWriter, r *http.Request) {
 	switch {
 	case r.Method == "GET":
+		q := r.URL.Query()
+		for _, param := range campaignSummaryFilterParams {
+			if q.Get(param) != "" {
+				as.filteredCampaignSummaries(w, r)
+				return
+			}
+		}
 		cs, err := models.GetCampaignSummaries(ctx.Get(r, "user_id").(int64))
 		if err != nil {
 			log.Error(err)
@@ -61,6 +132,74 @@ func (as *Server) CampaignsSummary(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// campaignSummaryFilterParams are the query parameters which filter or
+// paginate the campaign summaries.
+var campaignSummaryFilterParams = []string{
+	"launched_after", "launched_before", "completed_after", "completed_before", "archived", "limit", "offset",
+}
+
+// filteredCampaignSummaries returns a single page of the summaries of
+// campaigns launched or completed within the dates given by the
+// "launched_after", "launched_before", "completed_after" and
+// "completed_before" query parameters, paginated by "limit" and "offset".
+// Dates are either RFC 3339 timestamps or dates, and the "before" dates are
+// exclusive. The "archived" parameter lists only archived campaigns when
+// true, or only unarchived ones when false.
+func (as *Server) filteredCampaignSummaries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := models.CampaignSummaryFilter{}
+	dates := []struct {
+		param string
+		date  *time.Time
+	}{
+		{"launched_after", &f.LaunchedAfter},
+		{"launched_before", &f.LaunchedBefore},
+		{"completed_after", &f.CompletedAfter},
+		{"completed_before", &f.CompletedBefore},
+	}
+	var err error
+	for _, d := range dates {
+		*d.date, err = parseActivityDate(q.Get(d.param), false)
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: fmt.Sprintf("Invalid %s date", d.param)}, http.StatusBadRequest)
+			return
+		}
+	}
+	if a := q.Get("archived"); a != "" {
+		archived, err := strconv.ParseBool(a)
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Invalid archived value"}, http.StatusBadRequest)
+			return
+		}
+		f.Archived = &archived
+	}
+	if l := q.Get("limit"); l != "" {
+		f.Limit, err = strconv.Atoi(l)
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Invalid limit"}, http.StatusBadRequest)
+			return
+		}
+	}
+	if o := q.Get("offset"); o != "" {
+		f.Offset, err = strconv.Atoi(o)
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Invalid offset"}, http.StatusBadRequest)
+			return
+		}
+	}
+	cs, err := models.GetFilteredCampaignSummaries(ctx.Get(r, "user_id").(int64), f)
+	switch {
+	case err == models.ErrInvalidDateRange || err == models.ErrInvalidPagination:
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, cs, http.StatusOK)
+}
+
 // Campaign returns details about the requested campaign. If the campaign is not
 // valid, APICampaign returns null.
 func (as *Server) Campaign(w http.ResponseWriter, r *http.Request) {
@@ -90,6 +229,11 @@ func (as *Server) Campaign(w http.ResponseWriter, r *http.Request) {
 func (as *Server) CampaignResults(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, _ := strconv.ParseInt(vars["id"], 0, 64)
+	q := r.URL.Query()
+	if r.Method == "GET" && (q.Get("status") != "" || q.Get("limit") != "" || q.Get("offset") != "") {
+		as.filteredCampaignResults(w, r, id)
+		return
+	}
 	cr, err := models.GetCampaignResults(id, ctx.Get(r, "user_id").(int64))
 	if err != nil {
 		log.Error(err)
@@ -102,6 +246,77 @@ func (as *Server) CampaignResults(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// filteredCampaignResults returns a single page of campaign results matching
+// the "status", "limit" and "offset" query parameters.
+func (as *Server) filteredCampaignResults(w http.ResponseWriter, r *http.Request, id int64) {
+	q := r.URL.Query()
+	f := models.ResultsFilter{Status: strings.ToLower(q.Get("status"))}
+	var err error
+	if l := q.Get("limit"); l != "" {
+		f.Limit, err = strconv.Atoi(l)
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Invalid limit"}, http.StatusBadRequest)
+			return
+		}
+	}
+	if o := q.Get("offset"); o != "" {
+		f.Offset, err = strconv.Atoi(o)
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Invalid offset"}, http.StatusBadRequest)
+			return
+		}
+	}
+	fr, err := models.GetFilteredCampaignResults(id, ctx.Get(r, "user_id").(int64), f)
+	switch {
+	case err == models.ErrInvalidResultStatus || err == models.ErrInvalidPagination:
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: "Campaign not found"}, http.StatusNotFound)
+		return
+	}
+	JSONResponse(w, fr, http.StatusOK)
+}
+
+// CampaignEventReplayWebhook re-sends a past campaign event to the currently
+// active webhooks. This is useful when a webhook consumer was misconfigured
+// while the campaign was running.
+func (as *Server) CampaignEventReplayWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 0, 64)
+	eid, _ := strconv.ParseInt(vars["event_id"], 0, 64)
+	user := ctx.Get(r, "user").(models.User)
+	wr, err := models.ReplayEventWebhook(eid, id, user.Id)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		JSONResponse(w, models.Response{Success: false, Message: "Campaign not found"}, http.StatusNotFound)
+		return
+	case err == models.ErrEventNotFound:
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusNotFound)
+		return
+	case err == models.ErrNoActiveWebhooks:
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	result := "success"
+	if len(wr.Failed) > 0 {
+		result = "failure"
+	}
+	service := models.NewEmailAuthorizationService()
+	details := fmt.Sprintf("Replayed event %d of campaign %d to %d webhook(s), %d failed", eid, id, wr.Delivered+len(wr.Failed), len(wr.Failed))
+	service.LogAuthorizationAttempt(r.Context(), user.Username, "webhook_replay", result, &user.Id, details)
+	JSONResponse(w, wr, http.StatusOK)
+}
+
 // CampaignSummary returns the summary for a given campaign.
 func (as *Server) CampaignSummary(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -122,6 +337,108 @@ func (as *Server) CampaignSummary(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// CampaignPageStats returns the conversion of each landing page served by the
+// campaign.
+func (as *Server) CampaignPageStats(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 0, 64)
+	switch {
+	case r.Method == "GET":
+		ps, err := models.GetCampaignPageStats(id, ctx.Get(r, "user_id").(int64))
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				JSONResponse(w, models.Response{Success: false, Message: "Campaign not found"}, http.StatusNotFound)
+			} else {
+				JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusInternalServerError)
+			}
+			log.Error(err)
+			return
+		}
+		JSONResponse(w, ps, http.StatusOK)
+	}
+}
+
+// campaignReport returns the report of the campaign requested, writing an
+// error response if it can't be loaded.
+func campaignReport(w http.ResponseWriter, r *http.Request) (models.CampaignReport, bool) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 0, 64)
+	cr, err := models.GetCampaignReport(id, ctx.Get(r, "user_id").(int64))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			JSONResponse(w, models.Response{Success: false, Message: "Campaign not found"}, http.StatusNotFound)
+		} else {
+			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusInternalServerError)
+		}
+		log.Error(err)
+		return cr, false
+	}
+	return cr, true
+}
+
+// CampaignReportPDF returns the report of a campaign as a PDF file.
+// GET /api/campaigns/{id}/report.pdf
+func (as *Server) CampaignReportPDF(w http.ResponseWriter, r *http.Request) {
+	cr, ok := campaignReport(w, r)
+	if !ok {
+		return
+	}
+	// The document is rendered before anything is written, so that a
+	// failure can still be reported
+	buf := &bytes.Buffer{}
+	err := report.WriteCampaignPDF(buf, cr)
+	if err != nil {
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: "Error rendering report"}, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=campaign-%d-report.pdf", cr.Id))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Write(buf.Bytes())
+}
+
+// CampaignReportCSV streams the results of a campaign as CSV.
+// GET /api/campaigns/{id}/report.csv
+func (as *Server) CampaignReportCSV(w http.ResponseWriter, r *http.Request) {
+	cr, ok := campaignReport(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=campaign-%d-results.csv", cr.Id))
+	err := cr.WriteCSV(w)
+	if err != nil {
+		// The response has already started, so the error can only be logged
+		log.Errorf("Failed to export campaign results: %v", err)
+	}
+}
+
+// CampaignTimelineJSONL streams the events of a campaign as JSON Lines, oldest
+// first, for ingestion into log analytics.
+// GET /api/campaigns/{id}/timeline.jsonl
+func (as *Server) CampaignTimelineJSONL(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 0, 64)
+	ct, err := models.GetCampaignTimeline(id, ctx.Get(r, "user_id").(int64))
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		JSONResponse(w, models.Response{Success: false, Message: "Campaign not found"}, http.StatusNotFound)
+		return
+	case err != nil:
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=campaign-%d-timeline.jsonl", id))
+	err = ct.WriteJSONL(w)
+	if err != nil {
+		// The response has already started, so the error can only be logged
+		log.Errorf("Failed to export campaign timeline: %v", err)
+	}
+}
+
 // CampaignComplete effectively "ends" a campaign.
 // Future phishing emails clicked will return a simple "404" page.
 func (as *Server) CampaignComplete(w http.ResponseWriter, r *http.Request) {
@@ -138,6 +455,276 @@ func (as *Server) CampaignComplete(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// CampaignResumeRequest is the request to resume a paused campaign. Confirm
+// must be set to show that the cause of the pause has been looked into.
+type CampaignResumeRequest struct {
+	Confirm bool `json:"confirm"`
+}
+
+// CampaignResume resumes a campaign which was paused because too many of its
+// emails bounced.
+func (as *Server) CampaignResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 0, 64)
+	req := CampaignResumeRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid JSON structure"}, http.StatusBadRequest)
+		return
+	}
+	c, err := models.ResumeCampaign(id, ctx.Get(r, "user").(models.User), req.Confirm)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		JSONResponse(w, models.Response{Success: false, Message: "Campaign not found"}, http.StatusNotFound)
+		return
+	case err == models.ErrCampaignNotPaused, err == models.ErrResumeNotConfirmed:
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	case err != nil:
+		JSONResponse(w, models.Response{Success: false, Message: "Error resuming campaign"}, http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, c, http.StatusOK)
+}
+
+// CampaignScheduleRequest is the request to move a campaign's "send emails
+// by" date.
+type CampaignScheduleRequest struct {
+	SendByDate time.Time `json:"send_by_date"`
+}
+
+// CampaignScheduleResponse reports how many of a campaign's emails were
+// rescheduled.
+type CampaignScheduleResponse struct {
+	SendByDate  time.Time `json:"send_by_date"`
+	Rescheduled int       `json:"rescheduled"`
+}
+
+// CampaignSchedule moves a campaign's "send emails by" date, spreading the
+// emails which are still scheduled over the remaining time.
+// PUT /api/campaigns/{id}/schedule
+func (as *Server) CampaignSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PUT" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 0, 64)
+	req := CampaignScheduleRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid JSON structure"}, http.StatusBadRequest)
+		return
+	}
+	n, err := models.RecalculateSendSchedule(id, ctx.Get(r, "user_id").(int64), req.SendByDate)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		JSONResponse(w, models.Response{Success: false, Message: "Campaign not found"}, http.StatusNotFound)
+		return
+	case err == models.ErrSendByDateInPast, err == models.ErrInvalidSendByDate, err == models.ErrCampaignNotScheduling,
+		err == models.ErrN8NScheduleFixed, err == models.ErrNoSendsToReschedule:
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: "Error rescheduling campaign"}, http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, models.Response{
+		Success: true,
+		Message: "Campaign rescheduled",
+		Data:    CampaignScheduleResponse{SendByDate: req.SendByDate.UTC(), Rescheduled: n},
+	}, http.StatusOK)
+}
+
+// CampaignCompact purges the detailed results and events of a completed
+// campaign, keeping a snapshot of its statistics.
+func (as *Server) CampaignCompact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 0, 64)
+	snapshot, err := models.CompactCampaign(id, ctx.Get(r, "user_id").(int64))
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		JSONResponse(w, models.Response{Success: false, Message: "Campaign not found"}, http.StatusNotFound)
+		return
+	case err == models.ErrCampaignNotComplete, err == models.ErrCampaignAlreadyCompacted:
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	case err != nil:
+		JSONResponse(w, models.Response{Success: false, Message: "Error compacting campaign"}, http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, models.Response{Success: true, Message: "Campaign compacted successfully!", Data: snapshot}, http.StatusOK)
+}
+
+// CampaignRecomputeStats recounts the statistics of a campaign from its
+// results and events, rewriting its statistics snapshot if it has one, and
+// returns the statistics before and after.
+func (as *Server) CampaignRecomputeStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 0, 64)
+	rc, err := models.RecomputeCampaignStats(id)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		JSONResponse(w, models.Response{Success: false, Message: "Campaign not found"}, http.StatusNotFound)
+		return
+	case err == models.ErrCampaignResultsPurged:
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	case err != nil:
+		JSONResponse(w, models.Response{Success: false, Message: "Error recomputing campaign statistics"}, http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, rc, http.StatusOK)
+}
+
+// AdminQueuedCampaigns returns the queued and in progress campaigns of every
+// user, so that admins can review upcoming sends on a shared instance.
+// GET /api/admin/campaigns/queued
+func (as *Server) AdminQueuedCampaigns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	cs, err := models.GetScheduledCampaigns()
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Error fetching queued campaigns"}, http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, cs, http.StatusOK)
+}
+
+// CancelResultsRequest is the request to cancel the scheduled sends of some
+// of a campaign's recipients, identified by rid or email address.
+type CancelResultsRequest struct {
+	RIds   []string `json:"rids"`
+	Emails []string `json:"emails"`
+}
+
+// CampaignCancelResults cancels the scheduled sends of the given recipients
+// of a campaign. Recipients who have already been sent their email are
+// reported as skipped.
+func (as *Server) CampaignCancelResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 0, 64)
+	req := CancelResultsRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid request"}, http.StatusBadRequest)
+		return
+	}
+	recipients := append(req.RIds, req.Emails...)
+	report, err := models.CancelScheduledResults(id, ctx.Get(r, "user_id").(int64), recipients)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		JSONResponse(w, models.Response{Success: false, Message: "Campaign not found"}, http.StatusNotFound)
+		return
+	case err == models.ErrNoRecipientsToCancel:
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: "Error cancelling scheduled sends"}, http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, models.Response{Success: true, Message: "Scheduled sends cancelled", Data: report}, http.StatusOK)
+}
+
+// CampaignResultsToGroup creates a group from the recipients whose results in
+// the campaign match any of the requested statuses, so that they can be
+// targeted by a follow-up campaign.
+// POST /api/campaigns/{id}/results/to-group
+func (as *Server) CampaignResultsToGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 0, 64)
+	uid := ctx.Get(r, "user_id").(int64)
+	req := models.ResultsGroupRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid JSON structure"}, http.StatusBadRequest)
+		return
+	}
+	_, err = models.GetGroupByName(req.Name, uid)
+	if err != gorm.ErrRecordNotFound {
+		JSONResponse(w, models.Response{Success: false, Message: "Group name already in use"}, http.StatusConflict)
+		return
+	}
+	rg, err := models.CreateGroupFromResults(id, uid, req)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		JSONResponse(w, models.Response{Success: false, Message: "Campaign not found"}, http.StatusNotFound)
+		return
+	case err != nil:
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	log.Infof("Created group %d with %d targets from the results of campaign %d", rg.Group.Id, len(rg.Group.Targets), id)
+	JSONResponse(w, rg, http.StatusCreated)
+}
+
+// ResultNoteRequest is the request to add a note to a recipient's result.
+type ResultNoteRequest struct {
+	Note string `json:"note"`
+}
+
+// CampaignResultNotes returns the result of the recipient with the rid given
+// in the path, along with its notes, or adds a note to it.
+func (as *Server) CampaignResultNotes(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 0, 64)
+	user := ctx.Get(r, "user").(models.User)
+	var result models.Result
+	var err error
+	switch {
+	case r.Method == "GET":
+		result, err = models.GetCampaignResult(id, user.Id, vars["rid"])
+	case r.Method == "POST":
+		req := ResultNoteRequest{}
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Invalid request"}, http.StatusBadRequest)
+			return
+		}
+		result, err = models.AddResultNote(id, user, vars["rid"], req.Note)
+	default:
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		JSONResponse(w, models.Response{Success: false, Message: "Result not found"}, http.StatusNotFound)
+		return
+	case err == models.ErrResultNoteEmpty || err == models.ErrResultNoteTooLong:
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: "Error fetching result notes"}, http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, result, http.StatusOK)
+}
+
 // FlexibleTime is a time.Time wrapper that handles both RFC3339 and ISO 8601 without timezone
 type FlexibleTime struct {
 	time.Time
@@ -159,10 +746,9 @@ func (ft *FlexibleTime) UnmarshalJSON(b []byte) error {
 	t, err = time.Parse("2006-01-02T15:04:05", s)
 	if err == nil {
 		// Default to Asia/Singapore timezone (UTC+8)
-		location, locErr := time.LoadLocation("Asia/Singapore")
+		location, locErr := models.LoadScheduleLocation("Asia/Singapore")
 		if locErr != nil {
-			// Fallback to fixed offset if timezone database not available
-			location = time.FixedZone("UTC+8", 8*60*60)
+			return locErr
 		}
 		ft.Time = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), location)
 		log.Infof("Parsed date without timezone '%s', defaulting to %s", s, location)
@@ -177,6 +763,12 @@ type ValidateCampaignRateLimitRequest struct {
 	LaunchDate FlexibleTime `json:"launch_date"`
 	SendByDate FlexibleTime `json:"send_by_date"`
 	GroupIDs   []int64      `json:"group_ids"`
+	// EmailAccountID is the account the campaign is sent from, whose send
+	// interval is checked instead of the default if it has one
+	EmailAccountID int64 `json:"email_account_id"`
+	// EmailType picks the account the campaign is sent from by its type, as
+	// campaigns do, if no EmailAccountID is given
+	EmailType string `json:"email_type"`
 }
 
 // ValidateCampaignRateLimitResponse represents the response for rate limit validation
@@ -237,8 +829,25 @@ func (as *Server) ValidateCampaignRateLimit(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// The interval is that of the account the campaign would be sent from,
+	// resolved the same way as when the campaign is created
+	interval := models.GetDefaultSendInterval()
+	if req.EmailAccountID != 0 || req.EmailType != "" {
+		var ea models.EmailAccount
+		if req.EmailAccountID != 0 {
+			ea, err = models.GetEmailAccount(req.EmailAccountID)
+		} else {
+			ea, err = models.GetEmailAccountByType(req.EmailType)
+		}
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Email account not found"}, http.StatusNotFound)
+			return
+		}
+		interval = ea.SendInterval()
+	}
+
 	// Validate rate limit
-	warning := models.ValidateCampaignRateLimit(req.LaunchDate.Time, req.SendByDate.Time, totalRecipients)
+	warning := models.ValidateCampaignRateLimit(req.LaunchDate.Time, req.SendByDate.Time, totalRecipients, interval)
 
 	if warning != nil {
 		// Rate limit is too aggressive - return warning
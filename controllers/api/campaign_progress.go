@@ -0,0 +1,128 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	ctx "github.com/gophish/gophish/context"
+	log "github.com/gophish/gophish/logger"
+	"github.com/gophish/gophish/models"
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+)
+
+// progressDebounce is how long to wait after a campaign event before sending
+// updated progress. This gives the result the event belongs to time to be
+// updated, and batches bursts of events into a single update.
+var progressDebounce = 250 * time.Millisecond
+
+// progressKeepAlive is how often progress is re-checked and a comment sent
+// to keep idle connections open through proxies.
+var progressKeepAlive = 15 * time.Second
+
+// writeProgressEvent writes a single server-sent event to the stream.
+func writeProgressEvent(w http.ResponseWriter, name string, p models.CampaignProgress) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	if err != nil {
+		return err
+	}
+	w.(http.Flusher).Flush()
+	return nil
+}
+
+// CampaignProgress streams the sending progress of a campaign as
+// server-sent events. A "progress" event is sent with the current counts when
+// the stream opens and whenever they change. Once the campaign is completed a
+// final "complete" event is sent and the stream is closed.
+func (as *Server) CampaignProgress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 0, 64)
+	uid := ctx.Get(r, "user_id").(int64)
+	if _, ok := w.(http.Flusher); !ok {
+		JSONResponse(w, models.Response{Success: false, Message: "Streaming not supported"}, http.StatusInternalServerError)
+		return
+	}
+	// Subscribe before reading the initial progress so that no change
+	// between the two is missed
+	notify, unsubscribe := models.SubscribeCampaignProgress(id)
+	defer unsubscribe()
+	p, err := models.GetCampaignProgress(id, uid)
+	if err == gorm.ErrRecordNotFound {
+		JSONResponse(w, models.Response{Success: false, Message: "Campaign not found"}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: "Error fetching campaign progress"}, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Stop nginx from buffering the stream, and the gzip handler from
+	// holding back events until it has enough data to compress
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.Header().Set("Content-Encoding", "identity")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(p models.CampaignProgress) bool {
+		name := "progress"
+		if p.Status == models.CampaignComplete {
+			name = "complete"
+		}
+		if err := writeProgressEvent(w, name, p); err != nil {
+			log.Debug(err)
+			return false
+		}
+		return name != "complete"
+	}
+	if !send(p) {
+		return
+	}
+	last := p
+
+	keepAlive := time.NewTicker(progressKeepAlive)
+	defer keepAlive.Stop()
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-notify:
+			if debounce == nil {
+				debounce = time.After(progressDebounce)
+			}
+			continue
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+		case <-debounce:
+			debounce = nil
+		}
+		p, err := models.GetCampaignProgress(id, uid)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+		if p == last {
+			continue
+		}
+		last = p
+		if !send(p) {
+			return
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 
+	ctx "github.com/gophish/gophish/context"
 	log "github.com/gophish/gophish/logger"
 	"github.com/gophish/gophish/models"
 	"github.com/gorilla/mux"
@@ -129,6 +130,44 @@ func (as *Server) EmailAccount(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// BulkEmailAccountTypeRequest is the payload for changing the type of several
+// email accounts at once.
+type BulkEmailAccountTypeRequest struct {
+	Ids  []int64 `json:"ids"`
+	Type string  `json:"type"`
+}
+
+// EmailAccountsBulkType handles requests for the /api/email_accounts/bulk-type
+// endpoint, changing the type of several email accounts at once.
+func (as *Server) EmailAccountsBulkType(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	req := BulkEmailAccountTypeRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid request"}, http.StatusBadRequest)
+		return
+	}
+	if len(req.Ids) == 0 {
+		JSONResponse(w, models.Response{Success: false, Message: models.ErrNoEmailAccountsSpecified.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = models.ValidateEmailType(req.Type)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	changes, err := models.BulkUpdateEmailAccountType(req.Ids, req.Type, ctx.Get(r, "user_id").(int64))
+	if err != nil {
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: "Error updating email accounts"}, http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, changes, http.StatusOK)
+}
+
 // EmailAccountByType handles requests for the /api/email_accounts/type/:type endpoint
 // Returns the first active email account of the specified type
 func (as *Server) EmailAccountByType(w http.ResponseWriter, r *http.Request) {
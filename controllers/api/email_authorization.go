@@ -2,6 +2,8 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"regexp"
 	"strconv"
@@ -12,6 +14,7 @@ import (
 	log "github.com/gophish/gophish/logger"
 	"github.com/gophish/gophish/models"
 	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
 )
 
 // EmailAuthorizationAPI handles API requests for email authorization management
@@ -41,6 +44,14 @@ type AuthorizedEmailResponse struct {
 	Notes           string                   `json:"notes"`
 }
 
+// AuthorizedDomainRequest represents a request to add/update an authorized domain
+type AuthorizedDomainRequest struct {
+	Domain      string `json:"domain"`
+	Status      string `json:"status"`
+	DefaultRole string `json:"default_role"`
+	Notes       string `json:"notes"`
+}
+
 // AuthorizationLogResponse represents an authorization log entry
 type AuthorizationLogResponse struct {
 	ID        int64        `json:"id"`
@@ -87,24 +98,53 @@ func (api *EmailAuthorizationAPI) GetAuthorizedEmails(w http.ResponseWriter, r *
 	// Convert to response format
 	var response []AuthorizedEmailResponse
 	for _, email := range emails {
-		response = append(response, AuthorizedEmailResponse{
-			ID:          email.Id,
-			Email:       email.Email,
-			Status:      email.Status,
-			Role:        email.Role,
-			DefaultRole: email.DefaultRole,
-			CreatedBy:   email.CreatedByUser,
-			CreatedAt:   email.CreatedAt,
-			UpdatedAt:   email.UpdatedAt,
-			ExpiresAt:   email.ExpiresAt,
-			LastUsedAt:  email.LastUsedAt,
-			Notes:       email.Notes,
-		})
+		response = append(response, newAuthorizedEmailResponse(email))
 	}
 
 	JSONResponse(w, response, http.StatusOK)
 }
 
+// GetAuthorizedEmail returns a single authorized email
+// GET /api/email-authorization/emails/{id}
+func (api *EmailAuthorizationAPI) GetAuthorizedEmail(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid ID"}, http.StatusBadRequest)
+		return
+	}
+
+	email, err := models.GetAuthorizedEmail(id)
+	if err == gorm.ErrRecordNotFound {
+		JSONResponse(w, models.Response{Success: false, Message: "Authorized email not found"}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Errorf("Failed to get authorized email: %v", err)
+		JSONResponse(w, models.Response{Success: false, Message: "Failed to retrieve authorized email"}, http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, newAuthorizedEmailResponse(email), http.StatusOK)
+}
+
+// newAuthorizedEmailResponse converts an authorized email to its response
+// format
+func newAuthorizedEmailResponse(email models.AuthorizedEmail) AuthorizedEmailResponse {
+	return AuthorizedEmailResponse{
+		ID:          email.Id,
+		Email:       email.Email,
+		Status:      email.Status,
+		Role:        email.Role,
+		DefaultRole: email.DefaultRole,
+		CreatedBy:   email.CreatedByUser,
+		CreatedAt:   email.CreatedAt,
+		UpdatedAt:   email.UpdatedAt,
+		ExpiresAt:   email.ExpiresAt,
+		LastUsedAt:  email.LastUsedAt,
+		Notes:       email.Notes,
+	}
+}
+
 // AddAuthorizedEmail adds a new authorized email
 // POST /api/email-authorization/emails
 func (api *EmailAuthorizationAPI) AddAuthorizedEmail(w http.ResponseWriter, r *http.Request) {
@@ -282,13 +322,74 @@ func (api *EmailAuthorizationAPI) DeleteAuthorizedEmail(w http.ResponseWriter, r
 	JSONResponse(w, models.Response{Success: true, Message: "Authorized email deleted successfully"}, http.StatusOK)
 }
 
-// GetAuthorizationLogs returns authorization audit logs
-// GET /api/email-authorization/logs
-func (api *EmailAuthorizationAPI) GetAuthorizationLogs(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
-	email := r.URL.Query().Get("email")
-	action := r.URL.Query().Get("action")
-	result := r.URL.Query().Get("result")
+// ExportAuthorizedEmailsCSV streams all authorized emails as CSV, honoring
+// the same status filter as the JSON listing
+// GET /api/email-authorization/emails.csv
+func (api *EmailAuthorizationAPI) ExportAuthorizedEmailsCSV(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	user := ctx.Get(r, "user").(models.User)
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=authorized-emails-%s.csv", time.Now().UTC().Format("20060102")))
+	err := models.WriteAuthorizedEmailsCSV(w, status)
+	if err != nil {
+		// The response has already started, so the error can only be logged
+		log.Errorf("Failed to export authorized emails: %v", err)
+		return
+	}
+
+	service := models.NewEmailAuthorizationService()
+	service.LogAuthorizationAttempt(r.Context(), "", "export", "success", &user.Id, "Exported via API")
+}
+
+// ImportAuthorizedEmailsCSV adds the authorized emails from a CSV export,
+// given either as a multipart file upload or as the request body
+// POST /api/email-authorization/emails.csv
+func (api *EmailAuthorizationAPI) ImportAuthorizedEmailsCSV(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		mr, err := r.MultipartReader()
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Invalid multipart request"}, http.StatusBadRequest)
+			return
+		}
+		body = nil
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			if part.FileName() != "" {
+				defer part.Close()
+				body = part
+				break
+			}
+		}
+		if body == nil {
+			JSONResponse(w, models.Response{Success: false, Message: "No CSV file provided"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	user := ctx.Get(r, "user").(models.User)
+
+	report, err := models.ImportAuthorizedEmailsCSV(body, &user.Id)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Error parsing CSV: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	service := models.NewEmailAuthorizationService()
+	service.LogAuthorizationAttempt(r.Context(), "", "import", "success", &user.Id,
+		fmt.Sprintf("Imported %d of %d emails via API (%d skipped, %d failed)", report.Imported, report.Total, report.Skipped, len(report.Errors)))
+
+	JSONResponse(w, report, http.StatusOK)
+}
+
+// GetAuthorizedDomains returns all authorized domains
+// GET /api/email-authorization/domains
+func (api *EmailAuthorizationAPI) GetAuthorizedDomains(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
 	limitStr := r.URL.Query().Get("limit")
 	offsetStr := r.URL.Query().Get("offset")
 
@@ -306,8 +407,196 @@ func (api *EmailAuthorizationAPI) GetAuthorizationLogs(w http.ResponseWriter, r
 		}
 	}
 
+	domains, err := models.GetAuthorizedDomains(status, limit, offset)
+	if err != nil {
+		log.Errorf("Failed to get authorized domains: %v", err)
+		JSONResponse(w, models.Response{Success: false, Message: "Failed to retrieve authorized domains"}, http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, domains, http.StatusOK)
+}
+
+// AddAuthorizedDomain adds a new authorized domain
+// POST /api/email-authorization/domains
+func (api *EmailAuthorizationAPI) AddAuthorizedDomain(w http.ResponseWriter, r *http.Request) {
+	var req AuthorizedDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid JSON format"}, http.StatusBadRequest)
+		return
+	}
+
+	service := models.NewEmailAuthorizationService()
+	if err := service.ValidateDomainFormat(req.Domain); err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid domain format: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	user := ctx.Get(r, "user").(models.User)
+
+	if req.DefaultRole == "" {
+		req.DefaultRole = "user"
+	}
+
+	authorizedDomain, err := models.AddAuthorizedDomain(req.Domain, req.DefaultRole, &user.Id, sanitizeInput(req.Notes))
+	if err != nil {
+		if err == models.ErrInvalidDefaultRole {
+			JSONResponse(w, models.Response{Success: false, Message: "Invalid default role"}, http.StatusBadRequest)
+			return
+		}
+		if strings.Contains(err.Error(), "UNIQUE constraint") || strings.Contains(err.Error(), "duplicate") {
+			JSONResponse(w, models.Response{Success: false, Message: "Domain already authorized"}, http.StatusConflict)
+			return
+		}
+		log.Errorf("Failed to add authorized domain: %v", err)
+		JSONResponse(w, models.Response{Success: false, Message: "Failed to add authorized domain"}, http.StatusInternalServerError)
+		return
+	}
+
+	service.LogAuthorizationAttempt(r.Context(), authorizedDomain.Domain, "add_domain", "success", &user.Id, "Added via API")
+
+	JSONResponse(w, authorizedDomain, http.StatusCreated)
+}
+
+// UpdateAuthorizedDomain updates an existing authorized domain
+// PUT /api/email-authorization/domains/{id}
+func (api *EmailAuthorizationAPI) UpdateAuthorizedDomain(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid ID"}, http.StatusBadRequest)
+		return
+	}
+
+	var req AuthorizedDomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid JSON format"}, http.StatusBadRequest)
+		return
+	}
+
+	domain, err := models.GetAuthorizedDomain(id)
+	if err == gorm.ErrRecordNotFound {
+		JSONResponse(w, models.Response{Success: false, Message: "Authorized domain not found"}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Errorf("Failed to get authorized domain: %v", err)
+		JSONResponse(w, models.Response{Success: false, Message: "Failed to update authorized domain"}, http.StatusInternalServerError)
+		return
+	}
+
+	// The domain itself can't be renamed, since that would silently change
+	// who is able to sign in
+	service := models.NewEmailAuthorizationService()
+	if req.Domain != "" && service.NormalizeDomain(req.Domain) != domain.Domain {
+		JSONResponse(w, models.Response{Success: false, Message: "Domain cannot be changed"}, http.StatusBadRequest)
+		return
+	}
+
+	user := ctx.Get(r, "user").(models.User)
+
+	err = models.UpdateAuthorizedDomain(id, req.Status, req.DefaultRole, sanitizeInput(req.Notes))
+	if err != nil {
+		if err == models.ErrInvalidAuthorizationStatus {
+			JSONResponse(w, models.Response{Success: false, Message: "Invalid status. Must be: active, suspended, or revoked"}, http.StatusBadRequest)
+			return
+		}
+		if err == models.ErrInvalidDefaultRole {
+			JSONResponse(w, models.Response{Success: false, Message: "Invalid default role"}, http.StatusBadRequest)
+			return
+		}
+		log.Errorf("Failed to update authorized domain: %v", err)
+		JSONResponse(w, models.Response{Success: false, Message: "Failed to update authorized domain"}, http.StatusInternalServerError)
+		return
+	}
+
+	service.LogAuthorizationAttempt(r.Context(), domain.Domain, "update_domain", "success", &user.Id, "Updated via API")
+
+	JSONResponse(w, models.Response{Success: true, Message: "Authorized domain updated successfully"}, http.StatusOK)
+}
+
+// DeleteAuthorizedDomain removes an authorized domain
+// DELETE /api/email-authorization/domains/{id}
+func (api *EmailAuthorizationAPI) DeleteAuthorizedDomain(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid ID"}, http.StatusBadRequest)
+		return
+	}
+
+	domain, err := models.GetAuthorizedDomain(id)
+	if err == gorm.ErrRecordNotFound {
+		JSONResponse(w, models.Response{Success: false, Message: "Authorized domain not found"}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Errorf("Failed to get authorized domain: %v", err)
+		JSONResponse(w, models.Response{Success: false, Message: "Failed to delete authorized domain"}, http.StatusInternalServerError)
+		return
+	}
+
+	user := ctx.Get(r, "user").(models.User)
+
+	err = models.DeleteAuthorizedDomain(id)
+	if err != nil {
+		log.Errorf("Failed to delete authorized domain: %v", err)
+		JSONResponse(w, models.Response{Success: false, Message: "Failed to delete authorized domain"}, http.StatusInternalServerError)
+		return
+	}
+
+	service := models.NewEmailAuthorizationService()
+	service.LogAuthorizationAttempt(r.Context(), domain.Domain, "delete_domain", "success", &user.Id, "Deleted via API")
+
+	JSONResponse(w, models.Response{Success: true, Message: "Authorized domain deleted successfully"}, http.StatusOK)
+}
+
+// GetAuthorizationLogs returns authorization audit logs, optionally filtered
+// by "email", "action", "result", "ip" (exact, or a prefix ending in "*"),
+// "user_agent" (any part) and the "start" and "end" dates.
+// GET /api/email-authorization/logs
+func (api *EmailAuthorizationAPI) GetAuthorizationLogs(w http.ResponseWriter, r *http.Request) {
+	// Parse query parameters
+	q := r.URL.Query()
+	f := models.AuthorizationLogFilter{
+		Email:     q.Get("email"),
+		Action:    q.Get("action"),
+		Result:    q.Get("result"),
+		IPAddress: strings.TrimSpace(q.Get("ip")),
+		UserAgent: q.Get("user_agent"),
+		Limit:     100, // Default limit
+	}
+	limitStr := q.Get("limit")
+	offsetStr := q.Get("offset")
+
+	if limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 {
+			f.Limit = parsed
+		}
+	}
+
+	if offsetStr != "" {
+		if parsed, err := strconv.Atoi(offsetStr); err == nil && parsed >= 0 {
+			f.Offset = parsed
+		}
+	}
+
+	var err error
+	f.Start, err = parseActivityDate(q.Get("start"), false)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid start date"}, http.StatusBadRequest)
+		return
+	}
+	f.End, err = parseActivityDate(q.Get("end"), true)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid end date"}, http.StatusBadRequest)
+		return
+	}
+
 	// Get logs from database
-	logs, err := models.GetAuthorizationLogs(email, action, result, limit, offset)
+	logs, err := models.GetFilteredAuthorizationLogs(f)
+	if err == models.ErrInvalidActivityRange {
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		log.Errorf("Failed to get authorization logs: %v", err)
 		JSONResponse(w, models.Response{Success: false, Message: "Failed to retrieve authorization logs"}, http.StatusInternalServerError)
@@ -376,6 +665,79 @@ func (api *EmailAuthorizationAPI) CheckEmailAuthorization(w http.ResponseWriter,
 	JSONResponse(w, response, http.StatusOK)
 }
 
+// SimulateEmailAuthorization reports whether an email would be authorized,
+// with what role, and which rules decided it, without logging the check
+// GET /api/email-authorization/simulate?email=user@example.com
+func (api *EmailAuthorizationAPI) SimulateEmailAuthorization(w http.ResponseWriter, r *http.Request) {
+	email := r.URL.Query().Get("email")
+	if email == "" {
+		JSONResponse(w, models.Response{Success: false, Message: "Email parameter is required"}, http.StatusBadRequest)
+		return
+	}
+
+	service := models.NewEmailAuthorizationService()
+	sim, err := service.SimulateEmailAuthorization(email)
+	if err != nil {
+		log.Errorf("Failed to simulate email authorization: %v", err)
+		JSONResponse(w, models.Response{Success: false, Message: "Failed to simulate email authorization"}, http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, sim, http.StatusOK)
+}
+
+// CheckEmailAuthorizationBulk checks whether each of a list of emails is
+// authorized, without adding them
+// POST /api/email-authorization/check-bulk
+func (api *EmailAuthorizationAPI) CheckEmailAuthorizationBulk(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Emails []string `json:"emails"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid JSON format"}, http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Emails) == 0 {
+		JSONResponse(w, models.Response{Success: false, Message: "At least one email is required"}, http.StatusBadRequest)
+		return
+	}
+
+	service := models.NewEmailAuthorizationService()
+	results, err := service.CheckEmailAuthorizationBulk(req.Emails)
+	if err == models.ErrTooManyAuthorizationChecks {
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Errorf("Failed to check email authorization: %v", err)
+		JSONResponse(w, models.Response{Success: false, Message: "Failed to check email authorization"}, http.StatusInternalServerError)
+		return
+	}
+
+	authorizedCount := 0
+	for _, result := range results {
+		if result.Authorized {
+			authorizedCount++
+		}
+	}
+
+	// Log the bulk check against the user performing the review
+	user := ctx.Get(r, "user").(models.User)
+	details := fmt.Sprintf("Bulk check via API: %d of %d emails authorized", authorizedCount, len(results))
+	service.LogAuthorizationAttempt(r.Context(), user.Username, "bulk_check", "success", &user.Id, details)
+
+	response := map[string]interface{}{
+		"total":        len(results),
+		"authorized":   authorizedCount,
+		"unauthorized": len(results) - authorizedCount,
+		"results":      results,
+	}
+
+	JSONResponse(w, response, http.StatusOK)
+}
+
 // BulkAddAuthorizedEmails adds multiple emails at once
 // POST /api/email-authorization/emails/bulk
 func (api *EmailAuthorizationAPI) BulkAddAuthorizedEmails(w http.ResponseWriter, r *http.Request) {
@@ -410,51 +772,26 @@ func (api *EmailAuthorizationAPI) BulkAddAuthorizedEmails(w http.ResponseWriter,
 		req.DefaultRole = "user"
 	}
 
+	// Transient failures are retried, while invalid and already authorized
+	// emails fail immediately
 	service := models.NewEmailAuthorizationService()
-	var results []map[string]interface{}
+	results := models.BulkAddAuthorizedEmails(
+		req.Emails,
+		req.RoleID,
+		req.DefaultRole,
+		&user.Id,
+		req.ExpiresAt,
+		req.Notes,
+	)
 	successCount := 0
-
-	for _, email := range req.Emails {
-		result := map[string]interface{}{
-			"email": email,
-		}
-
-		// Validate email format
-		if err := service.ValidateEmailFormat(email); err != nil {
-			result["success"] = false
-			result["error"] = "Invalid email format: " + err.Error()
-			results = append(results, result)
+	for _, result := range results {
+		if !result.Success {
 			continue
 		}
+		successCount++
 
-		// Add authorized email
-		_, err := models.AddAuthorizedEmail(
-			email,
-			req.RoleID,
-			req.DefaultRole,
-			&user.Id,
-			req.ExpiresAt,
-			req.Notes,
-		)
-
-		if err != nil {
-			result["success"] = false
-			if strings.Contains(err.Error(), "UNIQUE constraint") || strings.Contains(err.Error(), "duplicate") {
-				result["error"] = "Email already authorized"
-			} else {
-				result["error"] = "Failed to add email"
-				log.Errorf("Failed to add authorized email %s: %v", email, err)
-			}
-		} else {
-			result["success"] = true
-			successCount++
-
-			// Log the action
-			reqCtx := r.Context()
-			service.LogAuthorizationAttempt(reqCtx, email, "bulk_add", "success", &user.Id, "Added via bulk API")
-		}
-
-		results = append(results, result)
+		// Log the action
+		service.LogAuthorizationAttempt(r.Context(), result.Email, "bulk_add", "success", &user.Id, "Added via bulk API")
 	}
 
 	response := map[string]interface{}{
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gophish/gophish/models"
+)
+
+func TestGetAuthorizedEmail(t *testing.T) {
+	testCtx := setupTest(t)
+	role, err := models.GetRoleBySlug(models.RoleUser)
+	if err != nil {
+		t.Fatalf("error getting role: %v", err)
+	}
+	email, err := models.AddAuthorizedEmail("jdoe@example.com", &role.ID, "", &testCtx.admin.Id, nil, "Contractor")
+	if err != nil {
+		t.Fatalf("error adding authorized email: %v", err)
+	}
+
+	get := func(id int64) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/email-authorization/emails/%d", id), nil)
+		r.Header.Set("Authorization", fmt.Sprintf("Bearer %s", testCtx.apiKey))
+		w := httptest.NewRecorder()
+		testCtx.apiServer.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get(email.Id)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status getting authorized email: got %d expected %d", w.Code, http.StatusOK)
+	}
+	got := AuthorizedEmailResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("error decoding authorized email: %v", err)
+	}
+	if got.ID != email.Id || got.Email != "jdoe@example.com" || got.Notes != "Contractor" {
+		t.Fatalf("unexpected authorized email %+v", got)
+	}
+	if got.Role == nil || got.Role.ID != role.ID {
+		t.Fatalf("expected role %d to be included, got %+v", role.ID, got.Role)
+	}
+	if got.CreatedBy == nil || got.CreatedBy.Id != testCtx.admin.Id {
+		t.Fatalf("expected creator %d to be included, got %+v", testCtx.admin.Id, got.CreatedBy)
+	}
+
+	if w := get(email.Id + 1000); w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status getting missing authorized email: got %d expected %d", w.Code, http.StatusNotFound)
+	}
+}
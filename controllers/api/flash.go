@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+
+	ctx "github.com/gophish/gophish/context"
+	log "github.com/gophish/gophish/logger"
+	mid "github.com/gophish/gophish/middleware"
+	"github.com/gophish/gophish/models"
+	"github.com/gorilla/sessions"
+)
+
+// Flashes returns the flash messages queued in the current session, such as
+// those left after signing in with SSO, and clears them so that each is only
+// shown once. Requests without a session have no flashes.
+func (as *Server) Flashes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	flashes := []models.Flash{}
+	session, ok := ctx.Get(r, "session").(*sessions.Session)
+	if !ok || session == nil {
+		JSONResponse(w, flashes, http.StatusOK)
+		return
+	}
+	queued := mid.PopFlashes(session)
+	for _, f := range queued {
+		switch f := f.(type) {
+		case models.Flash:
+			flashes = append(flashes, f)
+		case *models.Flash:
+			flashes = append(flashes, *f)
+		}
+	}
+	if len(queued) > 0 {
+		if err := session.Save(r, w); err != nil {
+			log.Error(err)
+		}
+	}
+	JSONResponse(w, flashes, http.StatusOK)
+}
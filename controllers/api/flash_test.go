@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ctx "github.com/gophish/gophish/context"
+	mid "github.com/gophish/gophish/middleware"
+	"github.com/gophish/gophish/models"
+)
+
+func TestFlashesReturnedOnce(t *testing.T) {
+	as := NewServer()
+	r := httptest.NewRequest(http.MethodGet, "/api/flashes", nil)
+	session, err := mid.Store.New(r, "gophish")
+	if err != nil {
+		t.Fatalf("error creating session: %v", err)
+	}
+	session.AddFlash(models.Flash{Type: "info", Message: "Signed in with SSO"})
+	session.Values["oauth_state"] = "state"
+	r = ctx.Set(r, "session", session)
+
+	getFlashes := func() []models.Flash {
+		w := httptest.NewRecorder()
+		as.Flashes(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status getting flashes: got %d expected %d", w.Code, http.StatusOK)
+		}
+		flashes := []models.Flash{}
+		if err := json.NewDecoder(w.Body).Decode(&flashes); err != nil {
+			t.Fatalf("error decoding flashes: %v", err)
+		}
+		return flashes
+	}
+	flashes := getFlashes()
+	if len(flashes) != 1 || flashes[0].Type != "info" || flashes[0].Message != "Signed in with SSO" {
+		t.Fatalf("unexpected flashes: got %v", flashes)
+	}
+	if flashes = getFlashes(); len(flashes) != 0 {
+		t.Fatalf("expected flashes to be cleared, got %v", flashes)
+	}
+	if session.Values["oauth_state"] != "state" {
+		t.Fatalf("expected the OAuth state to be kept in the session")
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -85,7 +86,8 @@ func (as *Server) Group(w http.ResponseWriter, r *http.Request) {
 		JSONResponse(w, models.Response{Success: true, Message: "Group deleted successfully!"}, http.StatusOK)
 	case r.Method == "PUT":
 		// Change this to get from URL and uid (don't bother with id in r.Body)
-		g = models.Group{}
+		// Groups stay tagged as autopilot groups unless the tag is cleared
+		g = models.Group{Autopilot: g.Autopilot}
 		err = json.NewDecoder(r.Body).Decode(&g)
 		if err != nil {
 			log.Errorf("error decoding group: %v", err)
@@ -121,3 +123,32 @@ func (as *Server) GroupSummary(w http.ResponseWriter, r *http.Request) {
 		JSONResponse(w, g, http.StatusOK)
 	}
 }
+
+// AutopilotGroupOrphans lists the current user's autopilot groups which no
+// campaign needs and which are past the retention period if requested via
+// GET. If requested via DELETE, the groups are deleted.
+// GET|DELETE /api/groups/autopilot/orphans
+func (as *Server) AutopilotGroupOrphans(w http.ResponseWriter, r *http.Request) {
+	uid := ctx.Get(r, "user_id").(int64)
+	cutoff := time.Now().UTC().Add(-models.GetAutopilotGroupRetention())
+	switch {
+	case r.Method == "GET":
+		gs, err := models.GetOrphanedAutopilotGroups(uid, cutoff)
+		if err != nil {
+			log.Error(err)
+			JSONResponse(w, models.Response{Success: false, Message: "Error loading autopilot groups"}, http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, gs, http.StatusOK)
+	case r.Method == "DELETE":
+		gs, err := models.PurgeOrphanedAutopilotGroups(uid, cutoff)
+		if err != nil {
+			log.Error(err)
+			JSONResponse(w, models.Response{Success: false, Message: "Error deleting autopilot groups"}, http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, models.Response{Success: true, Message: fmt.Sprintf("Deleted %d autopilot groups", len(gs)), Data: gs}, http.StatusOK)
+	default:
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
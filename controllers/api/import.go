@@ -1,7 +1,6 @@
 package api
 
 import (
-	"bytes"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -10,11 +9,11 @@ import (
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
+	ctx "github.com/gophish/gophish/context"
 	"github.com/gophish/gophish/dialer"
 	log "github.com/gophish/gophish/logger"
 	"github.com/gophish/gophish/models"
 	"github.com/gophish/gophish/util"
-	"github.com/jordan-wright/email"
 )
 
 type cloneRequest struct {
@@ -34,9 +33,10 @@ type cloneResponse struct {
 }
 
 type emailResponse struct {
-	Text    string `json:"text"`
-	HTML    string `json:"html"`
-	Subject string `json:"subject"`
+	Text        string              `json:"text"`
+	HTML        string              `json:"html"`
+	Subject     string              `json:"subject"`
+	Attachments []models.Attachment `json:"attachments"`
 }
 
 // ImportGroup imports a CSV of group members
@@ -49,6 +49,43 @@ func (as *Server) ImportGroup(w http.ResponseWriter, r *http.Request) {
 	JSONResponse(w, ts, http.StatusOK)
 }
 
+// ValidateImportGroup checks a CSV of group members without creating the
+// group, reporting any invalid or duplicate rows.
+func (as *Server) ValidateImportGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	report, err := util.ValidateCSV(r)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Error parsing CSV: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	JSONResponse(w, report, http.StatusOK)
+}
+
+// ImportCampaign creates a completed campaign from the known results of a
+// campaign run elsewhere, so that its statistics and reports are available
+// without re-running it.
+func (as *Server) ImportCampaign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	ci := models.CampaignImport{}
+	err := json.NewDecoder(r.Body).Decode(&ci)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid JSON structure"}, http.StatusBadRequest)
+		return
+	}
+	c, err := models.ImportCampaignResults(&ci, ctx.Get(r, "user_id").(int64))
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	JSONResponse(w, c, http.StatusCreated)
+}
+
 // ImportEmail allows for the importing of email.
 // Returns a Message object
 func (as *Server) ImportEmail(w http.ResponseWriter, r *http.Request) {
@@ -65,15 +102,17 @@ func (as *Server) ImportEmail(w http.ResponseWriter, r *http.Request) {
 		JSONResponse(w, models.Response{Success: false, Message: "Error decoding JSON Request"}, http.StatusBadRequest)
 		return
 	}
-	e, err := email.NewEmailFromReader(strings.NewReader(ir.Content))
+	e, err := models.ImportEmailTemplate(strings.NewReader(ir.Content))
 	if err != nil {
 		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: "Error parsing email: " + err.Error()}, http.StatusBadRequest)
+		return
 	}
 	// If the user wants to convert links to point to
 	// the landing page, let's make it happen by changing up
 	// e.HTML
 	if ir.ConvertLinks {
-		d, err := goquery.NewDocumentFromReader(bytes.NewReader(e.HTML))
+		d, err := goquery.NewDocumentFromReader(strings.NewReader(e.HTML))
 		if err != nil {
 			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
 			return
@@ -86,12 +125,13 @@ func (as *Server) ImportEmail(w http.ResponseWriter, r *http.Request) {
 			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusInternalServerError)
 			return
 		}
-		e.HTML = []byte(h)
+		e.HTML = h
 	}
 	er := emailResponse{
-		Subject: e.Subject,
-		Text:    string(e.Text),
-		HTML:    string(e.HTML),
+		Subject:     e.Subject,
+		Text:        e.Text,
+		HTML:        e.HTML,
+		Attachments: e.Attachments,
 	}
 	JSONResponse(w, er, http.StatusOK)
 }
@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"strconv"
 	"time"
 
 	log "github.com/gophish/gophish/logger"
 	"github.com/gophish/gophish/models"
+	"github.com/jinzhu/gorm"
 )
 
 // FlexibleInt64 is a custom type that can unmarshal from both string and int
@@ -43,10 +45,37 @@ func (f *FlexibleInt64) UnmarshalJSON(data []byte) error {
 type N8NEmailStatusPayload struct {
 	RId        string                 `json:"rid"`         // Result ID to identify the recipient
 	CampaignId FlexibleInt64          `json:"campaign_id"` // Campaign ID for validation (accepts string or int)
-	Event      string                 `json:"event"`       // Event type: "sent", "error", "bounce"
+	Event      string                 `json:"event"`       // Event type: "sent", "error", "bounce", "auto_reply", ...
 	Timestamp  time.Time              `json:"timestamp"`   // When the event occurred
+	SentAt     time.Time              `json:"sent_at"`     // When the email was accepted for delivery, for "sent" events
 	Details    map[string]interface{} `json:"details"`     // Additional event details
 	Error      string                 `json:"error,omitempty"` // Error message if applicable
+
+	IdempotencyKey string `json:"idempotency_key,omitempty"` // Identifies repeated deliveries of the same callback
+}
+
+// N8NHealth reports the state of the n8n integration, including the strength
+// of the JWT secret used to sign requests. The secret itself isn't included.
+type N8NHealth struct {
+	WebhookConfigured bool                  `json:"webhook_configured"`
+	APIConfigured     bool                  `json:"api_configured"`
+	InFlight          int                   `json:"in_flight"`
+	JWTSecret         models.JWTSecretCheck `json:"jwt_secret"`
+}
+
+// N8NHealthCheck returns the state of the n8n integration
+// GET /api/n8n/health
+func (as *Server) N8NHealthCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	JSONResponse(w, N8NHealth{
+		WebhookConfigured: os.Getenv("N8N_SEND_EMAIL") != "",
+		APIConfigured:     os.Getenv("N8N_API_URL") != "",
+		InFlight:          models.GetN8NLimiter().InFlight(),
+		JWTSecret:         models.CheckJWTSecret(os.Getenv("JWT_SECRET")),
+	}, http.StatusOK)
 }
 
 // N8NEmailCallback handles email status callbacks from n8n
@@ -76,88 +105,102 @@ func (as *Server) N8NEmailCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Infof("Received n8n callback: RId=%s, Event=%s, CampaignId=%d", payload.RId, payload.Event, int64(payload.CampaignId))
-
-	// Get the result by RId
-	result, err := models.GetResult(payload.RId)
-	if err != nil {
-		log.Errorf("Result not found for RId %s: %v", payload.RId, err)
-		JSONResponse(w, models.Response{Success: false, Message: "Result not found"}, http.StatusNotFound)
-		return
-	}
-
-	// Validate campaign ID matches (security check)
-	campaignId := int64(payload.CampaignId)
-	if campaignId != 0 && result.CampaignId != campaignId {
-		log.Warnf("Campaign ID mismatch for RId %s: expected %d, got %d", payload.RId, result.CampaignId, campaignId)
-		JSONResponse(w, models.Response{Success: false, Message: "Campaign ID mismatch"}, http.StatusBadRequest)
+	if !models.ValidN8NEvent(payload.Event) {
+		log.Warnf("Unknown event type from n8n: %s for RId %s", payload.Event, payload.RId)
+		JSONResponse(w, models.Response{Success: false, Message: "Unknown event type"}, http.StatusBadRequest)
 		return
 	}
 
-	// Process the event based on type
-	switch payload.Event {
-	case "sent":
-		err = result.HandleEmailSent()
-		if err != nil {
-			log.Errorf("Failed to handle email sent event for RId %s: %v", payload.RId, err)
-			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusInternalServerError)
-			return
-		}
-		log.Infof("Email sent event recorded for RId %s", payload.RId)
-
-	case "error", "bounce", "failed":
-		// Extract error message
-		errorMsg := payload.Error
-		if errorMsg == "" && payload.Details != nil {
-			if msg, ok := payload.Details["error"].(string); ok {
-				errorMsg = msg
-			} else if msg, ok := payload.Details["message"].(string); ok {
-				errorMsg = msg
-			}
-		}
-		if errorMsg == "" {
-			errorMsg = fmt.Sprintf("Email %s", payload.Event)
-		}
+	log.Infof("Received n8n callback: RId=%s, Event=%s, CampaignId=%d", payload.RId, payload.Event, int64(payload.CampaignId))
 
-		err = result.HandleEmailError(fmt.Errorf("%s", errorMsg))
-		if err != nil {
-			log.Errorf("Failed to handle email error event for RId %s: %v", payload.RId, err)
-			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusInternalServerError)
-			return
+	// Extract error message
+	errorMsg := payload.Error
+	if errorMsg == "" && payload.Details != nil {
+		if msg, ok := payload.Details["error"].(string); ok {
+			errorMsg = msg
+		} else if msg, ok := payload.Details["message"].(string); ok {
+			errorMsg = msg
 		}
-		log.Warnf("Email error event recorded for RId %s: %s", payload.RId, errorMsg)
+	}
 
-	case "opened":
-		// Email opened tracking (if n8n supports tracking pixels)
-		details := models.EventDetails{}
-		err = result.HandleEmailOpened(details)
-		if err != nil {
-			log.Errorf("Failed to handle email opened event for RId %s: %v", payload.RId, err)
-			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusInternalServerError)
-			return
-		}
-		log.Infof("Email opened event recorded for RId %s", payload.RId)
+	// The actual send time, which is kept apart from the scheduled send date.
+	// If n8n doesn't give it, the time of the sent event is used.
+	sentAt := payload.SentAt
+	if sentAt.IsZero() && payload.Event == "sent" {
+		sentAt = payload.Timestamp
+	}
 
-	case "clicked":
-		// Link clicked tracking
-		details := models.EventDetails{}
-		err = result.HandleClickedLink(details)
-		if err != nil {
-			log.Errorf("Failed to handle clicked link event for RId %s: %v", payload.RId, err)
-			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusInternalServerError)
-			return
-		}
-		log.Infof("Clicked link event recorded for RId %s", payload.RId)
+	// Repeated deliveries of the same callback are only processed once
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		key = payload.IdempotencyKey
+	}
+	if key == "" {
+		key = models.N8NCallbackKey(payload.RId, payload.Event, payload.Timestamp)
+	}
 
-	default:
-		log.Warnf("Unknown event type from n8n: %s for RId %s", payload.Event, payload.RId)
-		JSONResponse(w, models.Response{Success: false, Message: "Unknown event type"}, http.StatusBadRequest)
+	// Store the callback before acknowledging it, so that it's retried by
+	// the worker if updating the result fails
+	cb := models.N8NCallback{
+		IdempotencyKey: key,
+		RId:            payload.RId,
+		CampaignId:     int64(payload.CampaignId),
+		Event:          payload.Event,
+		ErrorMessage:   errorMsg,
+		SentDate:       sentAt,
+	}
+	duplicate, err := models.EnqueueN8NCallback(&cb)
+	if err != nil {
+		log.Errorf("Failed to store n8n callback for RId %s: %v", payload.RId, err)
+		JSONResponse(w, models.Response{Success: false, Message: "Failed to store callback"}, http.StatusInternalServerError)
 		return
 	}
+	if duplicate {
+		JSONResponse(w, models.Response{
+			Success: true,
+			Message: fmt.Sprintf("Event %s already received for RId %s", payload.Event, payload.RId),
+		}, http.StatusOK)
+		return
+	}
+	go func() {
+		err := models.ProcessN8NCallback(cb.Id)
+		if err != nil {
+			log.Errorf("Error processing n8n callback %d: %v", cb.Id, err)
+		}
+	}()
 
-	// Return success response
 	JSONResponse(w, models.Response{
 		Success: true,
-		Message: fmt.Sprintf("Event %s processed successfully for RId %s", payload.Event, payload.RId),
+		Message: fmt.Sprintf("Event %s received for RId %s", payload.Event, payload.RId),
 	}, http.StatusOK)
 }
+
+// N8NSendCheck tells n8n whether it may still send the email to a recipient.
+// n8n calls it right before each scheduled send, and skips the send unless
+// "send" is true, so that halting all sending or cancelling the recipient
+// stops emails which n8n has already scheduled.
+// GET /api/webhooks/n8n/send-check?rid={rid}
+func (as *Server) N8NSendCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	rid := r.URL.Query().Get("rid")
+	if rid == "" {
+		JSONResponse(w, models.Response{Success: false, Message: "Missing rid parameter"}, http.StatusBadRequest)
+		return
+	}
+	check, err := models.CheckN8NSend(rid)
+	if err == gorm.ErrRecordNotFound {
+		JSONResponse(w, models.Response{Success: false, Message: "Recipient not found"}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Errorf("Failed to check n8n send for RId %s: %v", rid, err)
+		JSONResponse(w, models.Response{Success: false, Message: "Failed to check send"}, http.StatusInternalServerError)
+		return
+	}
+	if !check.Send {
+		log.Infof("Told n8n not to send to RId %s: %s", rid, check.Reason)
+	}
+	JSONResponse(w, check, http.StatusOK)
+}
@@ -89,3 +89,24 @@ func (as *Server) Page(w http.ResponseWriter, r *http.Request) {
 		JSONResponse(w, p, http.StatusOK)
 	}
 }
+
+// ValidatePage checks that the forms in the landing page sent in the request
+// will record submissions once it is saved, without saving it.
+func (as *Server) ValidatePage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	p := models.Page{}
+	err := json.NewDecoder(r.Body).Decode(&p)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid request"}, http.StatusBadRequest)
+		return
+	}
+	report, err := p.LintForms()
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	JSONResponse(w, report, http.StatusOK)
+}
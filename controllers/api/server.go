@@ -21,6 +21,11 @@ type Server struct {
 	handler http.Handler
 	worker  worker.Worker
 	limiter *ratelimit.PostLimiter
+	// autopilotLimiter limits how often each user can call the AI workflows
+	autopilotLimiter *ratelimit.UserLimiter
+	// csrfKeys protects the admin server against CSRF, and is used to
+	// rotate its key
+	csrfKeys *mid.CSRFKeyRing
 }
 
 // NewServer returns a new instance of the API handler with the provided
@@ -29,8 +34,9 @@ func NewServer(options ...ServerOption) *Server {
 	defaultWorker, _ := worker.New()
 	defaultLimiter := ratelimit.NewPostLimiter()
 	as := &Server{
-		worker:  defaultWorker,
-		limiter: defaultLimiter,
+		worker:           defaultWorker,
+		limiter:          defaultLimiter,
+		autopilotLimiter: ratelimit.NewUserLimiter(models.GetAutopilotRateLimit()),
 	}
 	for _, opt := range options {
 		opt(as)
@@ -52,6 +58,22 @@ func WithLimiter(limiter *ratelimit.PostLimiter) ServerOption {
 	}
 }
 
+// WithCSRFKeys is an option that sets the CSRF keys of the admin server, so
+// that the key can be rotated.
+func WithCSRFKeys(csrfKeys *mid.CSRFKeyRing) ServerOption {
+	return func(as *Server) {
+		as.csrfKeys = csrfKeys
+	}
+}
+
+// WithAutopilotLimiter is an option that sets the per-user rate limiter used
+// for the AI workflow endpoints.
+func WithAutopilotLimiter(limiter *ratelimit.UserLimiter) ServerOption {
+	return func(as *Server) {
+		as.autopilotLimiter = limiter
+	}
+}
+
 func (as *Server) registerRoutes() {
 	root := mux.NewRouter()
 	root = root.StrictSlash(true)
@@ -60,50 +82,89 @@ func (as *Server) registerRoutes() {
 	// Must be registered on root router BEFORE /api/ subrouter to bypass RequireAPIKey middleware
 	// Note: Full path /api/webhooks/n8n/status because admin server uses .Handler() not .Subrouter()
 	root.HandleFunc("/api/webhooks/n8n/status", mid.RequireN8NJWT(as.N8NEmailCallback))
+	// Checked by n8n right before each scheduled send
+	root.HandleFunc("/api/webhooks/n8n/send-check", mid.RequireN8NJWT(as.N8NSendCheck))
 
 	router := root.PathPrefix("/api/").Subrouter()
 	router.Use(mid.RequireAPIKey)
 	router.Use(mid.EnforceViewOnly)
+	router.HandleFunc("/n8n/health", mid.Use(as.N8NHealthCheck, mid.RequirePermission(models.PermissionModifySystem)))
 	router.HandleFunc("/imap/", as.IMAPServer)
 	router.HandleFunc("/imap/validate", as.IMAPServerValidate)
 	router.HandleFunc("/reset", as.Reset)
-	router.HandleFunc("/campaigns/", as.Campaigns)
-	router.HandleFunc("/campaigns/summary", as.CampaignsSummary)
+	router.HandleFunc("/campaigns/", mid.Use(as.Campaigns, mid.RequireWritePermission(models.PermissionCreateCampaigns)))
+	router.HandleFunc("/campaigns/summary", mid.Use(as.CampaignsSummary, mid.RequirePermission(models.PermissionViewResults)))
 	router.HandleFunc("/campaigns/validate-rate-limit", as.ValidateCampaignRateLimit)
-	router.HandleFunc("/campaigns/{id:[0-9]+}", as.Campaign)
-	router.HandleFunc("/campaigns/{id:[0-9]+}/results", as.CampaignResults)
-	router.HandleFunc("/campaigns/{id:[0-9]+}/summary", as.CampaignSummary)
-	router.HandleFunc("/campaigns/{id:[0-9]+}/complete", as.CampaignComplete)
+	router.HandleFunc("/campaigns/preview-recipients", mid.Use(as.CampaignsPreviewRecipients, mid.RequirePermission(models.PermissionCreateCampaigns)))
+	router.HandleFunc("/campaigns/validate", mid.Use(as.CampaignsValidate, mid.RequirePermission(models.PermissionCreateCampaigns)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}", mid.Use(as.Campaign, mid.RequireWritePermission(models.PermissionCreateCampaigns)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/results", mid.Use(as.CampaignResults, mid.RequirePermission(models.PermissionViewResults)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/results/cancel", mid.Use(as.CampaignCancelResults, mid.RequirePermission(models.PermissionCreateCampaigns)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/results/to-group", mid.Use(as.CampaignResultsToGroup, mid.RequirePermission(models.PermissionModifyObjects), mid.RequirePermission(models.PermissionViewResults)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/results/{rid}/notes", mid.Use(as.CampaignResultNotes, mid.RequirePermission(models.PermissionCreateCampaigns)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/summary", mid.Use(as.CampaignSummary, mid.RequirePermission(models.PermissionViewResults)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/pages/stats", mid.Use(as.CampaignPageStats, mid.RequirePermission(models.PermissionViewResults)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/report.pdf", mid.Use(as.CampaignReportPDF, mid.RequirePermission(models.PermissionViewResults)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/report.csv", mid.Use(as.CampaignReportCSV, mid.RequirePermission(models.PermissionViewResults)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/timeline.jsonl", mid.Use(as.CampaignTimelineJSONL, mid.RequirePermission(models.PermissionViewResults)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/progress", mid.Use(as.CampaignProgress, mid.RequirePermission(models.PermissionViewResults)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/complete", mid.Use(as.CampaignComplete, mid.RequirePermission(models.PermissionCreateCampaigns)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/resume", mid.Use(as.CampaignResume, mid.RequirePermission(models.PermissionCreateCampaigns)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/schedule", mid.Use(as.CampaignSchedule, mid.RequirePermission(models.PermissionCreateCampaigns)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/compact", mid.Use(as.CampaignCompact, mid.RequirePermission(models.PermissionCreateCampaigns)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/recompute-stats", mid.Use(as.CampaignRecomputeStats, mid.RequirePermission(models.PermissionModifySystem)))
+	router.HandleFunc("/campaigns/{id:[0-9]+}/events/{event_id:[0-9]+}/replay-webhook", mid.Use(as.CampaignEventReplayWebhook, mid.RequirePermission(models.PermissionCreateCampaigns)))
+	router.HandleFunc("/admin/campaigns/queued", mid.Use(as.AdminQueuedCampaigns, mid.RequirePermission(models.PermissionModifySystem)))
+	router.HandleFunc("/admin/halt-sending", mid.Use(as.HaltSending, mid.RequirePermission(models.PermissionModifySystem)))
+	router.HandleFunc("/admin/csrf-key", mid.Use(as.CSRFKey, mid.RequirePermission(models.PermissionModifySystem)))
 	router.HandleFunc("/groups/", as.Groups)
 	router.HandleFunc("/groups/summary", as.GroupsSummary)
+	router.HandleFunc("/groups/autopilot/orphans", as.AutopilotGroupOrphans)
 	router.HandleFunc("/groups/{id:[0-9]+}", as.Group)
 	router.HandleFunc("/groups/{id:[0-9]+}/summary", as.GroupSummary)
-	router.HandleFunc("/templates/", as.Templates)
-	router.HandleFunc("/templates/{id:[0-9]+}", as.Template)
-	router.HandleFunc("/pages/", as.Pages)
-	router.HandleFunc("/pages/{id:[0-9]+}", as.Page)
+	router.HandleFunc("/templates/", mid.Use(as.Templates, mid.RequireWritePermission(models.PermissionManageTemplates)))
+	router.HandleFunc("/templates/{id:[0-9]+}", mid.Use(as.Template, mid.RequireWritePermission(models.PermissionManageTemplates)))
+	router.HandleFunc("/templates/validate", mid.Use(as.ValidateTemplate, mid.RequirePermission(models.PermissionManageTemplates)))
+	router.HandleFunc("/templates/{name}/render-check", mid.Use(as.TemplateRenderCheck, mid.RequirePermission(models.PermissionManageTemplates)))
+	router.HandleFunc("/pages/", mid.Use(as.Pages, mid.RequireWritePermission(models.PermissionManageTemplates)))
+	router.HandleFunc("/pages/validate", mid.Use(as.ValidatePage, mid.RequirePermission(models.PermissionManageTemplates)))
+	router.HandleFunc("/pages/{id:[0-9]+}", mid.Use(as.Page, mid.RequireWritePermission(models.PermissionManageTemplates)))
 	router.HandleFunc("/smtp/", as.SendingProfiles)
 	router.HandleFunc("/smtp/{id:[0-9]+}", as.SendingProfile)
 	router.HandleFunc("/users/", mid.Use(as.Users, mid.RequirePermission(models.PermissionModifySystem)))
 	router.HandleFunc("/users/{id:[0-9]+}", mid.Use(as.User))
+	router.HandleFunc("/users/{id:[0-9]+}/activity", mid.Use(as.UserActivity, mid.RequirePermission(models.PermissionModifySystem)))
 	router.HandleFunc("/util/send_test_email", as.SendTestEmail)
+	router.HandleFunc("/flashes", as.Flashes)
+	router.HandleFunc("/util/resend_test_email", as.ResendTestEmail)
 	router.HandleFunc("/import/group", as.ImportGroup)
+	router.HandleFunc("/import/group/validate", as.ValidateImportGroup)
+	router.HandleFunc("/import/campaign", mid.Use(as.ImportCampaign, mid.RequirePermission(models.PermissionCreateCampaigns)))
 	router.HandleFunc("/import/email", as.ImportEmail)
 	router.HandleFunc("/import/site", as.ImportSite)
 	router.HandleFunc("/webhooks/", mid.Use(as.Webhooks, mid.RequirePermission(models.PermissionModifySystem)))
 	router.HandleFunc("/webhooks/{id:[0-9]+}/validate", mid.Use(as.ValidateWebhook, mid.RequirePermission(models.PermissionModifySystem)))
 	router.HandleFunc("/webhooks/{id:[0-9]+}", mid.Use(as.Webhook, mid.RequirePermission(models.PermissionModifySystem)))
+	router.HandleFunc("/settings/quiet-hours", mid.Use(as.QuietHours, mid.RequirePermission(models.PermissionModifySystem)))
+	router.HandleFunc("/settings/logging", mid.Use(as.LogSettings, mid.RequirePermission(models.PermissionModifySystem)))
+	router.HandleFunc("/settings/rate-limit", mid.Use(as.RateLimit, mid.RequirePermission(models.PermissionModifySystem)))
 
 	// Email authorization routes (admin-only)
 	router.HandleFunc("/email-authorization/emails", mid.Use(as.EmailAuthorizationEmails, mid.RequirePermission(models.PermissionModifySystem)))
+	router.HandleFunc("/email-authorization/emails.csv", mid.Use(as.EmailAuthorizationEmailsCSV, mid.RequirePermission(models.PermissionModifySystem)))
 	router.HandleFunc("/email-authorization/emails/bulk", mid.Use(as.EmailAuthorizationEmailsBulk, mid.RequirePermission(models.PermissionModifySystem)))
 	router.HandleFunc("/email-authorization/emails/{id:[0-9]+}", mid.Use(as.EmailAuthorizationEmail, mid.RequirePermission(models.PermissionModifySystem)))
 	router.HandleFunc("/email-authorization/emails/{id:[0-9]+}/status", mid.Use(as.EmailAuthorizationEmailStatus, mid.RequirePermission(models.PermissionModifySystem)))
+	router.HandleFunc("/email-authorization/domains", mid.Use(as.EmailAuthorizationDomains, mid.RequirePermission(models.PermissionModifySystem)))
+	router.HandleFunc("/email-authorization/domains/{id:[0-9]+}", mid.Use(as.EmailAuthorizationDomain, mid.RequirePermission(models.PermissionModifySystem)))
 	router.HandleFunc("/email-authorization/check", mid.Use(as.EmailAuthorizationCheck, mid.RequirePermission(models.PermissionModifySystem)))
+	router.HandleFunc("/email-authorization/simulate", mid.Use(as.EmailAuthorizationSimulate, mid.RequirePermission(models.PermissionModifySystem)))
+	router.HandleFunc("/email-authorization/check-bulk", mid.Use(as.EmailAuthorizationCheckBulk, mid.RequirePermission(models.PermissionModifySystem)))
 	router.HandleFunc("/email-authorization/logs", mid.Use(as.EmailAuthorizationLogs, mid.RequirePermission(models.PermissionModifySystem)))
 
 	// Email accounts routes (admin-only)
 	router.HandleFunc("/email_accounts/", mid.Use(as.EmailAccounts, mid.RequirePermission(models.PermissionModifySystem)))
+	router.HandleFunc("/email_accounts/bulk-type", mid.Use(as.EmailAccountsBulkType, mid.RequirePermission(models.PermissionModifySystem)))
 	router.HandleFunc("/email_accounts/{id:[0-9]+}", mid.Use(as.EmailAccount, mid.RequirePermission(models.PermissionModifySystem)))
 	router.HandleFunc("/email_accounts/type/{type}", mid.Use(as.EmailAccountByType, mid.RequirePermission(models.PermissionModifySystem)))
 
@@ -113,9 +174,9 @@ func (as *Server) registerRoutes() {
 	router.HandleFunc("/email_types/{id:[0-9]+}", mid.Use(as.EmailType, mid.RequirePermission(models.PermissionModifySystem)))
 
 	// AI Workflow routes (used by both Copilot and Auto modes)
-	router.HandleFunc("/campaigns/ai-workflow/1", as.AutopilotAgent1)
-	router.HandleFunc("/campaigns/ai-workflow/2", as.AutopilotAgent2)
-	router.HandleFunc("/campaigns/ai-workflow/3", as.AutopilotAgent3)
+	router.HandleFunc("/campaigns/ai-workflow/1", as.autopilotLimiter.Limit(as.AutopilotAgent1))
+	router.HandleFunc("/campaigns/ai-workflow/2", as.autopilotLimiter.Limit(as.AutopilotAgent2))
+	router.HandleFunc("/campaigns/ai-workflow/3", as.autopilotLimiter.Limit(as.AutopilotAgent3))
 
 	// Use root router as handler to include both root routes (n8n callback) and subrouter routes (API endpoints)
 	as.handler = root
@@ -140,6 +201,20 @@ func (as *Server) EmailAuthorizationEmails(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// EmailAuthorizationEmailsCSV handles exporting and importing authorized
+// emails as CSV
+func (as *Server) EmailAuthorizationEmailsCSV(w http.ResponseWriter, r *http.Request) {
+	api := EmailAuthorizationAPI{}
+	switch r.Method {
+	case http.MethodGet:
+		api.ExportAuthorizedEmailsCSV(w, r)
+	case http.MethodPost:
+		api.ImportAuthorizedEmailsCSV(w, r)
+	default:
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
 // EmailAuthorizationEmailsBulk handles bulk operations for authorized emails
 func (as *Server) EmailAuthorizationEmailsBulk(w http.ResponseWriter, r *http.Request) {
 	api := EmailAuthorizationAPI{}
@@ -155,6 +230,8 @@ func (as *Server) EmailAuthorizationEmailsBulk(w http.ResponseWriter, r *http.Re
 func (as *Server) EmailAuthorizationEmail(w http.ResponseWriter, r *http.Request) {
 	api := EmailAuthorizationAPI{}
 	switch r.Method {
+	case http.MethodGet:
+		api.GetAuthorizedEmail(w, r)
 	case http.MethodPut:
 		api.UpdateAuthorizedEmail(w, r)
 	case http.MethodDelete:
@@ -175,6 +252,32 @@ func (as *Server) EmailAuthorizationEmailStatus(w http.ResponseWriter, r *http.R
 	}
 }
 
+// EmailAuthorizationDomains handles listing and adding authorized domains
+func (as *Server) EmailAuthorizationDomains(w http.ResponseWriter, r *http.Request) {
+	api := EmailAuthorizationAPI{}
+	switch r.Method {
+	case http.MethodGet:
+		api.GetAuthorizedDomains(w, r)
+	case http.MethodPost:
+		api.AddAuthorizedDomain(w, r)
+	default:
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
+// EmailAuthorizationDomain handles operations for individual authorized domains
+func (as *Server) EmailAuthorizationDomain(w http.ResponseWriter, r *http.Request) {
+	api := EmailAuthorizationAPI{}
+	switch r.Method {
+	case http.MethodPut:
+		api.UpdateAuthorizedDomain(w, r)
+	case http.MethodDelete:
+		api.DeleteAuthorizedDomain(w, r)
+	default:
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
 // EmailAuthorizationCheck handles email authorization checks
 func (as *Server) EmailAuthorizationCheck(w http.ResponseWriter, r *http.Request) {
 	api := EmailAuthorizationAPI{}
@@ -186,6 +289,28 @@ func (as *Server) EmailAuthorizationCheck(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// EmailAuthorizationSimulate handles simulated email authorization checks
+func (as *Server) EmailAuthorizationSimulate(w http.ResponseWriter, r *http.Request) {
+	api := EmailAuthorizationAPI{}
+	switch r.Method {
+	case http.MethodGet:
+		api.SimulateEmailAuthorization(w, r)
+	default:
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
+// EmailAuthorizationCheckBulk handles bulk email authorization checks
+func (as *Server) EmailAuthorizationCheckBulk(w http.ResponseWriter, r *http.Request) {
+	api := EmailAuthorizationAPI{}
+	switch r.Method {
+	case http.MethodPost:
+		api.CheckEmailAuthorizationBulk(w, r)
+	default:
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
 // EmailAuthorizationLogs handles authorization audit log retrieval
 func (as *Server) EmailAuthorizationLogs(w http.ResponseWriter, r *http.Request) {
 	api := EmailAuthorizationAPI{}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	ctx "github.com/gophish/gophish/context"
+	log "github.com/gophish/gophish/logger"
+	mid "github.com/gophish/gophish/middleware"
+	"github.com/gophish/gophish/models"
+	"github.com/sirupsen/logrus"
+)
+
+// QuietHours returns or updates the organization-wide quiet hours during
+// which no email is sent.
+func (as *Server) QuietHours(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "GET":
+		qh, err := models.GetQuietHours()
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Error fetching quiet hours"}, http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, qh, http.StatusOK)
+
+	case r.Method == "PUT":
+		qh := models.QuietHours{}
+		err := json.NewDecoder(r.Body).Decode(&qh)
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Invalid JSON structure"}, http.StatusBadRequest)
+			return
+		}
+		err = models.PutQuietHours(&qh)
+		if err != nil {
+			log.Error(err)
+			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		JSONResponse(w, qh, http.StatusOK)
+
+	default:
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
+// RateLimit returns the effective interval between campaign emails, where it
+// was configured and whether it is enforced, along with the interval of each
+// email account.
+func (as *Server) RateLimit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	settings, err := models.GetSendIntervalSettings()
+	if err != nil {
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: "Error fetching send interval settings"}, http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, settings, http.StatusOK)
+}
+
+// HaltSendingRequest is the request to halt all sending.
+type HaltSendingRequest struct {
+	Reason string `json:"reason"`
+}
+
+// HaltSending returns, activates or clears the organization-wide kill switch
+// which stops all campaign email from being sent. Activating it cancels every
+// pending send.
+func (as *Server) HaltSending(w http.ResponseWriter, r *http.Request) {
+	user := ctx.Get(r, "user").(models.User)
+	switch {
+	case r.Method == "GET":
+		h, err := models.GetSendingHalt()
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Error fetching sending halt"}, http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, h, http.StatusOK)
+
+	case r.Method == "POST":
+		req := HaltSendingRequest{}
+		// The reason is optional, so an empty body is allowed
+		if r.ContentLength != 0 {
+			err := json.NewDecoder(r.Body).Decode(&req)
+			if err != nil {
+				JSONResponse(w, models.Response{Success: false, Message: "Invalid JSON structure"}, http.StatusBadRequest)
+				return
+			}
+		}
+		report, err := models.HaltSending(user, req.Reason)
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Error halting sending"}, http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, report, http.StatusOK)
+
+	case r.Method == "DELETE":
+		h, err := models.ResumeSending(user)
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Error resuming sending"}, http.StatusInternalServerError)
+			return
+		}
+		JSONResponse(w, h, http.StatusOK)
+
+	default:
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
+// CSRFKeyRequest is the request to rotate the admin server's CSRF key. A
+// random key is generated if none is given. The new key is stored, so that it
+// lasts across restarts, and the previous key is accepted for the grace
+// period, which defaults to the lifetime of the CSRF cookie.
+type CSRFKeyRequest struct {
+	Key          string `json:"key"`
+	GraceMinutes int    `json:"grace_minutes"`
+}
+
+// CSRFKey returns the details of the last rotation of the admin server's CSRF
+// key, or rotates it. Forms served before the rotation keep working for the
+// grace period.
+func (as *Server) CSRFKey(w http.ResponseWriter, r *http.Request) {
+	if as.csrfKeys == nil {
+		JSONResponse(w, models.Response{Success: false, Message: "CSRF key rotation isn't available"}, http.StatusNotFound)
+		return
+	}
+	switch {
+	case r.Method == "GET":
+		JSONResponse(w, as.csrfKeys.Rotation(), http.StatusOK)
+
+	case r.Method == "POST":
+		req := CSRFKeyRequest{}
+		// The key is optional, so an empty body is allowed
+		if r.ContentLength != 0 {
+			err := json.NewDecoder(r.Body).Decode(&req)
+			if err != nil {
+				JSONResponse(w, models.Response{Success: false, Message: "Invalid JSON structure"}, http.StatusBadRequest)
+				return
+			}
+		}
+		if req.GraceMinutes < 0 {
+			JSONResponse(w, models.Response{Success: false, Message: "Grace period can't be negative"}, http.StatusBadRequest)
+			return
+		}
+		grace := mid.DefaultCSRFKeyGracePeriod
+		if req.GraceMinutes > 0 {
+			grace = time.Duration(req.GraceMinutes) * time.Minute
+		}
+		rotation, err := as.csrfKeys.Rotate([]byte(req.Key), grace)
+		if err == mid.ErrCSRFKeyTooShort || err == mid.ErrCSRFKeyUnchanged {
+			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Error storing the CSRF key"}, http.StatusInternalServerError)
+			return
+		}
+		user := ctx.Get(r, "user").(models.User)
+		log.WithFields(logrus.Fields{
+			"user_id":             user.Id,
+			"username":            user.Username,
+			"generated":           req.Key == "",
+			"previous_key_expiry": rotation.PreviousKeyExpiry,
+		}).Warn("CSRF key rotated")
+		JSONResponse(w, rotation, http.StatusOK)
+
+	default:
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
+
+// LogSettings returns or changes the logging level and format. Changes take
+// effect immediately and last until Gophish is restarted.
+func (as *Server) LogSettings(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "GET":
+		JSONResponse(w, log.GetSettings(), http.StatusOK)
+
+	case r.Method == "PUT":
+		s := log.Settings{}
+		err := json.NewDecoder(r.Body).Decode(&s)
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: "Invalid JSON structure"}, http.StatusBadRequest)
+			return
+		}
+		err = log.ApplySettings(s)
+		if err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+			return
+		}
+		user := ctx.Get(r, "user").(models.User)
+		current := log.GetSettings()
+		log.WithFields(logrus.Fields{
+			"user_id":  user.Id,
+			"username": user.Username,
+			"level":    current.Level,
+			"format":   current.Format,
+		}).Warn("Logging settings changed")
+		JSONResponse(w, current, http.StatusOK)
+
+	default:
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+	}
+}
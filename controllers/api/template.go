@@ -95,3 +95,77 @@ func (as *Server) Template(w http.ResponseWriter, r *http.Request) {
 		JSONResponse(w, t, http.StatusOK)
 	}
 }
+
+// TemplateRenderCheck renders the template named in the path for every target
+// in the group given by the group query parameter, reporting the recipients
+// and fields it fails to render for. Nothing is sent.
+//
+// If the preview query parameter is "faithful" or "annotated", the template
+// rendered for the group's first target is returned too. Annotated previews
+// wrap the tracked links, untracked links and tracking pixel in marker spans.
+func (as *Server) TemplateRenderCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	group := r.URL.Query().Get("group")
+	if group == "" {
+		JSONResponse(w, models.Response{Success: false, Message: "A group is required"}, http.StatusBadRequest)
+		return
+	}
+	preview := r.URL.Query().Get("preview")
+	if preview != "" {
+		if err := models.ValidatePreviewMode(preview); err != nil {
+			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	uid := ctx.Get(r, "user_id").(int64)
+	rc, err := models.CheckTemplateRender(mux.Vars(r)["name"], group, uid)
+	if err == gorm.ErrRecordNotFound {
+		JSONResponse(w, models.Response{Success: false, Message: "Template or group not found"}, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: "Error checking template"}, http.StatusInternalServerError)
+		return
+	}
+	if preview != "" {
+		// Templates which fail to render are already reported in the errors
+		p, err := models.PreviewTemplateRender(mux.Vars(r)["name"], group, uid, preview)
+		if err == nil {
+			rc.Preview = &p
+		}
+	}
+	JSONResponse(w, rc, http.StatusOK)
+}
+
+// TemplateValidation is the outcome of validating a template's content.
+type TemplateValidation struct {
+	Valid            bool                          `json:"valid"`
+	Errors           []models.TemplateContentError `json:"errors"`
+	AllowedFunctions []string                      `json:"allowed_functions"`
+}
+
+// ValidateTemplate checks that the subject, preheader and bodies of the
+// template sent in the request are valid templates which only call allowed
+// functions, without saving it.
+func (as *Server) ValidateTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	t := models.Template{}
+	err := json.NewDecoder(r.Body).Decode(&t)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid request"}, http.StatusBadRequest)
+		return
+	}
+	errs := t.ValidateContent()
+	JSONResponse(w, TemplateValidation{
+		Valid:            len(errs) == 0,
+		Errors:           errs,
+		AllowedFunctions: models.AllowedTemplateFuncs(),
+	}, http.StatusOK)
+}
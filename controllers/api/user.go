@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gophish/gophish/auth"
 	ctx "github.com/gophish/gophish/context"
@@ -128,6 +129,7 @@ func (as *Server) Users(w http.ResponseWriter, r *http.Request) {
 			AccountLocked:          ur.AccountLocked,
 			OAuthProvider:          ur.OAuthProvider,
 			OAuthID:                "", // Will be set on first OAuth login
+			SSOManaged:             ur.OAuthProvider != "",
 		}
 		err = models.PutUser(&user)
 		if err != nil {
@@ -211,6 +213,12 @@ func (as *Server) User(w http.ResponseWriter, r *http.Request) {
 		}
 		existingUser.Role = role
 		existingUser.RoleID = role.ID
+		// Admins set an existing account up for single sign-on by giving it
+		// a provider, so that it's linked on its next SSO sign-in
+		if hasSystem && ur.OAuthProvider != "" && ur.OAuthProvider != existingUser.OAuthProvider {
+			existingUser.OAuthProvider = ur.OAuthProvider
+			existingUser.OAuthID = ""
+		}
 		// We don't force the password to be provided, since it may be an admin
 		// managing the user's account, and making a simple change like
 		// updating the username or role. However, if it _is_ provided, we'll
@@ -222,6 +230,10 @@ func (as *Server) User(w http.ResponseWriter, r *http.Request) {
 		// authenticated access to the account.
 		existingUser.PasswordChangeRequired = ur.PasswordChangeRequired
 		if ur.Password != "" {
+			if !existingUser.LocalPasswordAllowed() {
+				JSONResponse(w, models.Response{Success: false, Message: models.ErrSSOManagedPassword.Error()}, http.StatusBadRequest)
+				return
+			}
 			err = auth.CheckPasswordPolicy(ur.Password)
 			if err != nil {
 				JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
@@ -243,3 +255,70 @@ func (as *Server) User(w http.ResponseWriter, r *http.Request) {
 		JSONResponse(w, existingUser, http.StatusOK)
 	}
 }
+
+// parseActivityDate parses a date given to filter a user's activity, either
+// as an RFC 3339 timestamp or as a date. Dates given for the end of a range
+// include the whole day.
+func parseActivityDate(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return t, err
+	}
+	if end {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// UserActivity returns the authorization log entries and campaign lifecycle
+// events of the requested user, most recent first, optionally filtered by the
+// "start" and "end" dates.
+// GET /api/users/{id}/activity
+func (as *Server) UserActivity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusMethodNotAllowed)
+		return
+	}
+	vars := mux.Vars(r)
+	id, _ := strconv.ParseInt(vars["id"], 0, 64)
+	q := r.URL.Query()
+	f := models.UserActivityFilter{}
+	var err error
+	f.Start, err = parseActivityDate(q.Get("start"), false)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid start date"}, http.StatusBadRequest)
+		return
+	}
+	f.End, err = parseActivityDate(q.Get("end"), true)
+	if err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid end date"}, http.StatusBadRequest)
+		return
+	}
+	if l := q.Get("limit"); l != "" {
+		f.Limit, err = strconv.Atoi(l)
+		if err != nil || f.Limit < 1 {
+			JSONResponse(w, models.Response{Success: false, Message: "Invalid limit"}, http.StatusBadRequest)
+			return
+		}
+	}
+	activity, err := models.GetUserActivity(id, f)
+	switch {
+	case err == models.ErrInvalidActivityRange:
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	case err == gorm.ErrRecordNotFound:
+		JSONResponse(w, models.Response{Success: false, Message: "User not found"}, http.StatusNotFound)
+		return
+	case err != nil:
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: "Error loading user activity"}, http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, activity, http.StatusOK)
+}
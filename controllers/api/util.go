@@ -15,6 +15,7 @@ import (
 
 	"github.com/gophish/gomail"
 	ctx "github.com/gophish/gophish/context"
+	"github.com/gophish/gophish/dialer"
 	log "github.com/gophish/gophish/logger"
 	"github.com/gophish/gophish/models"
 	"github.com/jinzhu/gorm"
@@ -131,6 +132,61 @@ func (as *Server) SendTestEmail(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	err = deliverTestEmail(s)
+	if err != nil {
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	JSONResponse(w, models.Response{Success: true, Message: "Test email sent successfully via n8n"}, http.StatusOK)
+}
+
+// ResendTestEmail sends the user's last test email again, to the same
+// recipient with the same settings, rendering the current version of its
+// template.
+func (as *Server) ResendTestEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		JSONResponse(w, models.Response{Success: false, Message: "Method not allowed"}, http.StatusBadRequest)
+		return
+	}
+	s, err := models.GetLastEmailRequest(ctx.Get(r, "user_id").(int64))
+	if err == gorm.ErrRecordNotFound {
+		JSONResponse(w, models.Response{Success: false, Message: "No test email has been sent yet"}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	s.ErrorChan = make(chan error)
+	if _, err = models.GetEmailTypeByValue(s.EmailType); err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: "Invalid email type"}, http.StatusBadRequest)
+		return
+	}
+	if err = s.Validate(); err != nil {
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
+		return
+	}
+	// Store the request again so that it's sent with a new rid
+	s.Id = 0
+	err = models.PostEmailRequest(&s)
+	if err != nil {
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	err = deliverTestEmail(&s)
+	if err != nil {
+		log.Error(err)
+		JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	JSONResponse(w, models.Response{Success: true, Message: "Test email sent successfully via n8n"}, http.StatusOK)
+}
+
+// deliverTestEmail generates the test email and sends it via n8n.
+func deliverTestEmail(s *models.EmailRequest) error {
 	// Generate the email message using the template
 	msg := gomail.NewMessage()
 	// Set a placeholder From address for gomail to generate the message
@@ -140,11 +196,9 @@ func (as *Server) SendTestEmail(w http.ResponseWriter, r *http.Request) {
 		"from": "test@fyphish.local",
 	}).Info("Set From header before Generate()")
 
-	err = s.Generate(msg)
+	err := s.Generate(msg)
 	if err != nil {
-		log.Error(err)
-		JSONResponse(w, models.Response{Success: false, Message: fmt.Sprintf("Error generating email: %v", err)}, http.StatusInternalServerError)
-		return
+		return fmt.Errorf("Error generating email: %v", err)
 	}
 
 	log.Info("Generate() completed successfully")
@@ -154,27 +208,20 @@ func (as *Server) SendTestEmail(w http.ResponseWriter, r *http.Request) {
 	log.Info("About to call msg.WriteTo()")
 	_, err = msg.WriteTo(buf)
 	if err != nil {
-		log.Error(err)
-		JSONResponse(w, models.Response{Success: false, Message: fmt.Sprintf("Error writing message: %v", err)}, http.StatusInternalServerError)
-		return
+		return fmt.Errorf("Error writing message: %v", err)
 	}
 
 	subject, htmlBody, err := parseEmailMessage(buf.String())
 	if err != nil {
-		log.Error(err)
-		JSONResponse(w, models.Response{Success: false, Message: fmt.Sprintf("Error parsing message: %v", err)}, http.StatusInternalServerError)
-		return
+		return fmt.Errorf("Error parsing message: %v", err)
 	}
 
 	// Send via n8n webhook
 	err = sendTestEmailToN8N(s.EmailType, s.Email, subject, htmlBody)
 	if err != nil {
-		log.Error(err)
-		JSONResponse(w, models.Response{Success: false, Message: fmt.Sprintf("Error sending test email: %v", err)}, http.StatusInternalServerError)
-		return
+		return fmt.Errorf("Error sending test email: %v", err)
 	}
-
-	JSONResponse(w, models.Response{Success: true, Message: "Test email sent successfully via n8n"}, http.StatusOK)
+	return nil
 }
 
 // sendTestEmailToN8N sends a test email via n8n webhook
@@ -226,8 +273,13 @@ func sendTestEmailToN8N(emailType, recipient, subject, htmlBody string) error {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	// Send request
-	client := &http.Client{Timeout: 30 * time.Second}
+	// Send request, waiting for a free slot if n8n is busy
+	release, err := models.GetN8NLimiter().Acquire(req.Context())
+	if err != nil {
+		return err
+	}
+	defer release()
+	client := &http.Client{Timeout: 30 * time.Second, Transport: dialer.Transport()}
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %v", err)
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	ctx "github.com/gophish/gophish/context"
+	"github.com/gophish/gophish/dialer"
+	"github.com/gophish/gophish/models"
+)
+
+func TestResendTestEmail(t *testing.T) {
+	testCtx := setupTest(t)
+	sent := []map[string]interface{}{}
+	n8n := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		sent = append(sent, payload)
+	}))
+	defer n8n.Close()
+	dialer.SetAllowedHosts([]string{"127.0.0.1/32"})
+	defer dialer.SetAllowedHosts(nil)
+	os.Setenv("N8N_SEND_EMAIL", n8n.URL)
+	defer os.Unsetenv("N8N_SEND_EMAIL")
+	os.Setenv("JWT_SECRET", "secret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	resend := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/util/resend_test_email", nil)
+		r = ctx.Set(r, "user_id", testCtx.admin.Id)
+		w := httptest.NewRecorder()
+		testCtx.apiServer.ResendTestEmail(w, r)
+		return w
+	}
+	if w := resend(); w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status resending without a previous test email: got %d expected %d", w.Code, http.StatusNotFound)
+	}
+
+	et := models.EmailType{Value: "noreply", DisplayName: "No Reply"}
+	if err := models.PostEmailType(&et); err != nil {
+		t.Fatalf("error creating email type: %v", err)
+	}
+	template := models.Template{Name: "Iterated Template", Subject: "Subject", HTML: "Version 1", UserId: 1}
+	if err := models.PostTemplate(&template); err != nil {
+		t.Fatalf("error creating template: %v", err)
+	}
+	req := &models.EmailRequest{
+		Template:      template,
+		TemplateId:    template.Id,
+		EmailType:     et.Value,
+		UserId:        1,
+		BaseRecipient: models.BaseRecipient{Email: "tester@example.com"},
+	}
+	if err := models.PostEmailRequest(req); err != nil {
+		t.Fatalf("error storing test email request: %v", err)
+	}
+	template.HTML = "Version 2"
+	if err := models.PutTemplate(&template); err != nil {
+		t.Fatalf("error updating template: %v", err)
+	}
+
+	if w := resend(); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status resending test email: got %d expected %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if len(sent) != 1 {
+		t.Fatalf("unexpected number of test emails sent: got %d expected 1", len(sent))
+	}
+	recipients, _ := sent[0]["recipients"].([]interface{})
+	if len(recipients) != 1 || recipients[0] != "tester@example.com" {
+		t.Fatalf("unexpected recipients: got %v expected [tester@example.com]", recipients)
+	}
+	if sent[0]["email_type"] != et.Value {
+		t.Fatalf("unexpected email type: got %v expected %s", sent[0]["email_type"], et.Value)
+	}
+	message, _ := sent[0]["message"].(string)
+	if !strings.Contains(message, "Version 2") {
+		t.Fatalf("unexpected message: got %q expected the current template", message)
+	}
+}
@@ -80,6 +80,8 @@ func (as *Server) Webhook(w http.ResponseWriter, r *http.Request) {
 }
 
 // ValidateWebhook makes an HTTP request to a specified remote url to ensure that it's valid.
+// The webhook is returned on success, including the signature header and
+// algorithm the request was signed with.
 func (as *Server) ValidateWebhook(w http.ResponseWriter, r *http.Request) {
 	type validationEvent struct {
 		Success bool `json:"success"`
@@ -95,7 +97,7 @@ func (as *Server) ValidateWebhook(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		payload := validationEvent{Success: true}
-		err = webhook.Send(webhook.EndPoint{URL: wh.URL, Secret: wh.Secret}, payload)
+		err = webhook.Send(wh.EndPoint(), payload)
 		if err != nil {
 			JSONResponse(w, models.Response{Success: false, Message: err.Error()}, http.StatusBadRequest)
 			return
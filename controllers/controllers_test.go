@@ -56,6 +56,12 @@ func setupTest(t *testing.T) *testContext {
 	if err != nil {
 		t.Fatalf("error creating new user: %v", err)
 	}
+	// Create a user managed by SSO to test that local logins are refused
+	u3 := models.User{Username: "sso.user@example.com", Hash: hash, OAuthProvider: "microsoft", SSOManaged: true}
+	err = models.PutUser(&u3)
+	if err != nil {
+		t.Fatalf("error creating new user: %v", err)
+	}
 
 	ctx.apiKey = u.ApiKey
 	// Start the phishing server
@@ -15,6 +15,7 @@ import (
 	ctx "github.com/gophish/gophish/context"
 	"github.com/gophish/gophish/controllers/api"
 	log "github.com/gophish/gophish/logger"
+	mid "github.com/gophish/gophish/middleware"
 	"github.com/gophish/gophish/models"
 	"github.com/gophish/gophish/util"
 	"github.com/gorilla/handlers"
@@ -49,9 +50,10 @@ type PhishingServerOption func(*PhishingServer)
 // PhishingServer is an HTTP server that implements the campaign event
 // handlers, such as email open tracking, click tracking, and more.
 type PhishingServer struct {
-	server         *http.Server
-	config         config.PhishServer
-	contactAddress string
+	server             *http.Server
+	config             config.PhishServer
+	contactAddress     string
+	trackingHealthPath string
 }
 
 // NewPhishingServer returns a new instance of the phishing server with
@@ -81,6 +83,14 @@ func WithContactAddress(addr string) PhishingServerOption {
 	}
 }
 
+// WithTrackingHealthCheck answers the tracking domain health check on the
+// given path. The check isn't answered unless this is set.
+func WithTrackingHealthCheck(path string) PhishingServerOption {
+	return func(ps *PhishingServer) {
+		ps.trackingHealthPath = path
+	}
+}
+
 // Start launches the phishing server, listening on the configured address.
 func (ps *PhishingServer) Start() {
 	if ps.config.UseTLS {
@@ -112,6 +122,9 @@ func (ps *PhishingServer) registerRoutes() {
 	router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", fileServer))
 	router.HandleFunc("/track", ps.TrackHandler)
 	router.HandleFunc("/robots.txt", ps.RobotsHandler)
+	if ps.trackingHealthPath != "" {
+		router.HandleFunc(ps.trackingHealthPath, ps.TrackingHealthHandler)
+	}
 	router.HandleFunc("/{path:.*}/track", ps.TrackHandler)
 	router.HandleFunc("/{path:.*}/report", ps.ReportHandler)
 	router.HandleFunc("/report", ps.ReportHandler)
@@ -119,14 +132,14 @@ func (ps *PhishingServer) registerRoutes() {
 
 	// Setup GZIP compression
 	gzipWrapper, _ := gziphandler.NewGzipLevelHandler(gzip.BestCompression)
-	phishHandler := gzipWrapper(router)
+	phishHandler := gzipWrapper(mid.Use(router.ServeHTTP, mid.SecurityHeaders(ps.config.GetSecurityHeaders())))
 
 	// Respect X-Forwarded-For and X-Real-IP headers in case we're behind a
 	// reverse proxy.
 	phishHandler = handlers.ProxyHeaders(phishHandler)
 
 	// Setup logging
-	phishHandler = handlers.CombinedLoggingHandler(log.Writer(), phishHandler)
+	phishHandler = handlers.CombinedLoggingHandler(log.AccessWriter(), phishHandler)
 	ps.server.Handler = phishHandler
 }
 
@@ -239,7 +252,7 @@ func (ps *PhishingServer) PhishHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p, err := models.GetPage(c.PageId, c.UserId)
+	p, err := models.GetPage(c.PageIdForResult(&rs), c.UserId)
 	if err != nil {
 		log.Error(err)
 		http.NotFound(w, r)
@@ -252,7 +265,7 @@ func (ps *PhishingServer) PhishHandler(w http.ResponseWriter, r *http.Request) {
 			log.Error(err)
 		}
 	case r.Method == "POST":
-		err = rs.HandleFormSubmit(d)
+		err = rs.HandleFormSubmit(c.SubmittedDetails(d))
 		if err != nil {
 			log.Error(err)
 		}
@@ -261,6 +274,7 @@ func (ps *PhishingServer) PhishHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Error(err)
 		http.NotFound(w, r)
+		return
 	}
 	renderPhishResponse(w, r, ptx, p)
 }
@@ -269,6 +283,8 @@ func (ps *PhishingServer) PhishHandler(w http.ResponseWriter, r *http.Request) {
 // connection. This usually involves writing out the page HTML or redirecting
 // the user to the correct URL.
 func renderPhishResponse(w http.ResponseWriter, r *http.Request, ptx models.PhishingTemplateContext, p models.Page) {
+	// Only the recipient details configured for landing pages are exposed
+	ptx = ptx.ForLandingPage()
 	// If the request was a form submit and a redirect URL was specified, we
 	// should send the user to that URL
 	if r.Method == "POST" {
@@ -284,7 +300,7 @@ func renderPhishResponse(w http.ResponseWriter, r *http.Request, ptx models.Phis
 		}
 	}
 	// Otherwise, we just need to write out the templated HTML
-	html, err := models.ExecuteTemplate(p.HTML, ptx)
+	html, err := models.ExecuteTemplate(p.HTML, ptx.HTMLEscaped())
 	if err != nil {
 		log.Error(err)
 		http.NotFound(w, r)
@@ -293,6 +309,18 @@ func renderPhishResponse(w http.ResponseWriter, r *http.Request, ptx models.Phis
 	w.Write([]byte(html))
 }
 
+// TrackingHealthHandler answers the tracking domain health check made before
+// a campaign is launched, proving the request reached this instance.
+func (ps *PhishingServer) TrackingHealthHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, models.TrackingHealthResponse(token))
+}
+
 // RobotsHandler prevents search engines, etc. from indexing phishing materials
 func (ps *PhishingServer) RobotsHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, "User-agent: *\nDisallow: /")
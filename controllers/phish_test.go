@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/gophish/gophish/config"
@@ -289,6 +291,30 @@ func TestCompletedCampaignClick(t *testing.T) {
 	}
 }
 
+func TestPersonalizedLandingPage(t *testing.T) {
+	ctx := setupTest(t)
+	defer tearDown(t, ctx)
+	campaign := getFirstCampaign(t)
+	result := campaign.Results[0]
+	page := campaign.Page
+	page.HTML = "<html>Hello {{.FirstName}} ({{.Email}})</html>"
+	err := models.PutPage(&page)
+	if err != nil {
+		t.Fatalf("error updating landing page: %v", err)
+	}
+	expected := fmt.Sprintf("<html>Hello %s (%s)</html>", result.FirstName, result.Email)
+	clickLink(t, ctx, result.RId, expected)
+
+	// Only the configured recipient details are rendered
+	ctx.config.LandingPageContext = &config.LandingPageContext{Fields: []string{"first_name"}}
+	defer func() { ctx.config.LandingPageContext = nil }()
+	expected = fmt.Sprintf("<html>Hello %s ()</html>", result.FirstName)
+	clickLink(t, ctx, result.RId, expected)
+
+	// Unknown recipients are never shown the page
+	clickLink404(t, ctx, "XXXXXXXXXX")
+}
+
 func TestRobotsHandler(t *testing.T) {
 	ctx := setupTest(t)
 	defer tearDown(t, ctx)
@@ -312,6 +338,40 @@ func TestRobotsHandler(t *testing.T) {
 	}
 }
 
+func TestTrackingHealthHandler(t *testing.T) {
+	ctx := setupTest(t)
+	defer tearDown(t, ctx)
+	// The default phishing server doesn't answer the check
+	resp, err := http.Get(fmt.Sprintf("%s/health-test?token=abc", ctx.phishServer.URL))
+	if err != nil {
+		t.Fatalf("error requesting health check endpoint: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("invalid status code received for health check endpoint. expected %d got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	ps := NewPhishingServer(ctx.config.PhishConf, WithTrackingHealthCheck("/health-test"))
+	ts := httptest.NewServer(ps.server.Handler)
+	defer ts.Close()
+	resp, err = http.Get(fmt.Sprintf("%s/health-test?token=abc", ts.URL))
+	if err != nil {
+		t.Fatalf("error requesting health check endpoint: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("invalid status code received for health check endpoint. expected %d got %d", http.StatusOK, resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("error reading response body from health check endpoint: %v", err)
+	}
+	expected := models.TrackingHealthResponse("abc")
+	if got := strings.TrimSpace(string(body)); got != expected {
+		t.Fatalf("invalid health check response received. expected %s got %s", expected, got)
+	}
+}
+
 func TestInvalidPreviewID(t *testing.T) {
 	ctx := setupTest(t)
 	defer tearDown(t, ctx)
@@ -414,3 +474,28 @@ func TestRedirectTemplating(t *testing.T) {
 		t.Fatalf("invalid redirect received. expected %s got %s", expectedURL, gotURL)
 	}
 }
+
+func TestClickedPhishingLinkWithTrackingParams(t *testing.T) {
+	ctx := setupTest(t)
+	defer tearDown(t, ctx)
+	campaign := getFirstCampaign(t)
+	result := campaign.Results[0]
+
+	// Extra tracking parameters don't stop the recipient from being found
+	resp, err := http.Get(fmt.Sprintf("%s/?utm_source=phish&%s=%s&utm_medium=email", ctx.phishServer.URL, models.RecipientParameter, result.RId))
+	if err != nil {
+		t.Fatalf("error requesting / endpoint: %v", err)
+	}
+	defer resp.Body.Close()
+	got, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("error reading payload from / endpoint response: %v", err)
+	}
+	if !bytes.Equal(got, []byte(campaign.Page.HTML)) {
+		t.Fatalf("invalid response received from / endpoint. expected %s got %s", campaign.Page.HTML, got)
+	}
+	campaign = getFirstCampaign(t)
+	if campaign.Results[0].Status != models.EventClicked {
+		t.Fatalf("unexpected result status received. expected %s got %s", models.EventClicked, campaign.Results[0].Status)
+	}
+}
@@ -45,24 +45,7 @@ type AdminServer struct {
 
 // buildOAuthRedirectURL constructs the OAuth callback URL based on server configuration
 func buildOAuthRedirectURL(cfg *config.Config, r *http.Request) string {
-	// Determine protocol with multiple detection methods
-	protocol := "http"
-
-	// Method 1: Check proxy headers (load balancer/reverse proxy)
-	if forwardedProto := r.Header.Get("X-Forwarded-Proto"); forwardedProto == "https" {
-		protocol = "https"
-	} else if r.Header.Get("X-Forwarded-Scheme") == "https" {
-		protocol = "https"
-	} else if strings.Contains(r.Host, "azurecontainerapps.io") {
-		// Method 2: Azure Container Apps always use HTTPS externally
-		protocol = "https"
-	} else if r.Header.Get("X-Forwarded-For") != "" {
-		// Method 3: Behind proxy, likely HTTPS in production
-		protocol = "https"
-	} else if cfg.AdminConf.UseTLS {
-		// Method 4: Config-based detection (for local HTTPS)
-		protocol = "https"
-	}
+	protocol := mid.ResolveScheme(cfg.AdminConf, r)
 
 	// Get host from request or config
 	host := r.Host
@@ -188,29 +171,36 @@ func (as *AdminServer) registerRoutes() {
 	router.HandleFunc("/users", mid.Use(as.UserManagement, mid.RequirePermission(models.PermissionModifySystem), mid.RequireLogin))
 	router.HandleFunc("/webhooks", mid.Use(as.Webhooks, mid.RequirePermission(models.PermissionModifySystem), mid.RequireLogin))
 	router.HandleFunc("/impersonate", mid.Use(as.Impersonate, mid.RequirePermission(models.PermissionModifySystem), mid.RequireLogin))
+	// Setup CSRF Protection. A random key is generated if none is
+	// configured. The key can be rotated through the API, in which case the
+	// new key is stored and the previous key is still accepted for a grace
+	// period.
+	// Debug: Print trusted origins
+	log.Infof("Loading CSRF with trusted origins: %v", as.config.TrustedOrigins)
+	csrfKeys := mid.NewCSRFKeyRing(router,
+		[]byte(as.config.CSRFKey),
+		[]byte(as.config.CSRFPreviousKey),
+		as.config.GetCSRFKeyGracePeriod(),
+		mid.CSRFOptions{
+			FieldName:      "csrf_token",
+			Secure:         as.config.UseTLS,
+			TrustedOrigins: as.config.TrustedOrigins,
+			Persist:        true,
+		})
+
 	// Create the API routes
 	api := api.NewServer(
 		api.WithWorker(as.worker),
 		api.WithLimiter(as.limiter),
+		api.WithCSRFKeys(csrfKeys),
 	)
 	router.PathPrefix("/api/").Handler(api)
 
 	// Setup static file serving
 	router.PathPrefix("/").Handler(http.FileServer(unindexed.Dir("./static/")))
 
-	// Setup CSRF Protection
-	csrfKey := []byte(as.config.CSRFKey)
-	if len(csrfKey) == 0 {
-		csrfKey = []byte(auth.GenerateSecureKey(auth.APIKeyLength))
-	}
-	// Debug: Print trusted origins
-	log.Infof("Loading CSRF with trusted origins: %v", as.config.TrustedOrigins)
-	csrfHandler := csrf.Protect(csrfKey,
-		csrf.FieldName("csrf_token"),
-		csrf.Secure(as.config.UseTLS),
-		csrf.TrustedOrigins(as.config.TrustedOrigins))
-	adminHandler := csrfHandler(router)
-	adminHandler = mid.Use(adminHandler.ServeHTTP, mid.CSRFExceptions, mid.GetContext, mid.ApplySecurityHeaders)
+	var adminHandler http.Handler = csrfKeys
+	adminHandler = mid.Use(adminHandler.ServeHTTP, mid.CSRFExceptions, mid.GetContext, mid.SecurityHeaders(as.config.GetSecurityHeaders()))
 
 	// Setup GZIP compression
 	gzipWrapper, _ := gziphandler.NewGzipLevelHandler(gzip.BestCompression)
@@ -219,9 +209,12 @@ func (as *AdminServer) registerRoutes() {
 	// Respect X-Forwarded-For and X-Real-IP headers in case we're behind a
 	// reverse proxy.
 	adminHandler = handlers.ProxyHeaders(adminHandler)
+	// Keep track of the connecting address so that forwarded headers are
+	// only trusted from the configured proxies.
+	adminHandler = mid.RecordPeerAddr(adminHandler)
 
 	// Setup logging
-	adminHandler = handlers.CombinedLoggingHandler(log.Writer(), adminHandler)
+	adminHandler = handlers.CombinedLoggingHandler(log.AccessWriter(), adminHandler)
 	as.server.Handler = adminHandler
 }
 
@@ -332,9 +325,15 @@ func (as *AdminServer) Settings(w http.ResponseWriter, r *http.Request) {
 		currentPw := r.FormValue("current_password")
 		newPassword := r.FormValue("new_password")
 		confirmPassword := r.FormValue("confirm_new_password")
+		msg := models.Response{Success: true, Message: "Settings Updated Successfully"}
+		if !u.LocalPasswordAllowed() {
+			msg.Message = models.ErrSSOManagedPassword.Error()
+			msg.Success = false
+			api.JSONResponse(w, msg, http.StatusBadRequest)
+			return
+		}
 		// Check the current password
 		err := auth.ValidatePassword(currentPw, u.Hash)
-		msg := models.Response{Success: true, Message: "Settings Updated Successfully"}
 		if err != nil {
 			msg.Message = err.Error()
 			msg.Success = false
@@ -383,11 +382,12 @@ func (as *AdminServer) handleInvalidLogin(w http.ResponseWriter, r *http.Request
 	session := ctx.Get(r, "session").(*sessions.Session)
 	Flash(w, r, "danger", message)
 	params := struct {
-		User    models.User
-		Title   string
-		Flashes []interface{}
-		Token   string
-	}{Title: "Login", Token: csrf.Token(r)}
+		User      models.User
+		Title     string
+		Flashes   []interface{}
+		Token     string
+		Challenge *mid.LoginChallengeWidget
+	}{Title: "Login", Token: csrf.Token(r), Challenge: mid.GetLoginChallengeWidget(r)}
 	params.Flashes = session.Flashes()
 	session.Save(r, w)
 	templates := template.New("template")
@@ -452,6 +452,7 @@ func (as *AdminServer) Login(w http.ResponseWriter, r *http.Request) {
 		EmergencyAccess     bool
 		EmergencyMode       bool
 		MicrosoftEnabled    bool
+		Challenge           *mid.LoginChallengeWidget
 	}{
 		Title:            "Login",
 		Token:            csrf.Token(r),
@@ -461,6 +462,7 @@ func (as *AdminServer) Login(w http.ResponseWriter, r *http.Request) {
 		EmergencyAccess:  true,  // Will be determined by config
 		EmergencyMode:    false,
 		MicrosoftEnabled: false,
+		Challenge:        mid.GetLoginChallengeWidget(r),
 	}
 
 	// Load SSO configuration to determine login options
@@ -476,21 +478,7 @@ func (as *AdminServer) Login(w http.ResponseWriter, r *http.Request) {
 
 	switch {
 	case r.Method == "GET":
-		// Preserve OAuth session data before calling Flashes() which can clear session data
-		var oauthData = make(map[string]interface{})
-		for _, key := range []string{"oauth_state", "oauth_code_verifier", "oauth_provider", "oauth_timestamp", "oauth_nonce", "oauth_next"} {
-			if value, exists := session.Values[key]; exists {
-				oauthData[key] = value
-			}
-		}
-
-		params.Flashes = session.Flashes()
-
-		// Restore OAuth session data after Flashes()
-		for key, value := range oauthData {
-			session.Values[key] = value
-		}
-
+		params.Flashes = mid.PopFlashes(session)
 		session.Save(r, w)
 		templates := template.New("template")
 		_, err := templates.ParseFiles("templates/login.html", "templates/flashes.html")
@@ -512,10 +500,30 @@ func (as *AdminServer) Login(w http.ResponseWriter, r *http.Request) {
 		// If emergency access is disabled, block emergency login attempts
 		if isEmergencyLogin && cfg != nil && !cfg.IsEmergencyAccessEnabled() {
 			log.Warnf("Emergency login attempt blocked - emergency access disabled")
+			sendLoginAlert(r, models.LoginAlertFailed, r.FormValue("username"), true, "Emergency access is disabled")
 			as.handleInvalidLogin(w, r, "Emergency access is not available")
 			return
 		}
 
+		// Escalate repeated failures from a single IP, regardless of which
+		// accounts were targeted, to slow down credential stuffing.
+		clientIP := models.ExtractIPFromRequest(r)
+		switch mid.CheckLoginEscalation(clientIP) {
+		case mid.LoginBlocked:
+			log.Warnf("Login attempt from blocked IP: %s", clientIP)
+			sendLoginAlert(r, models.LoginAlertAccountLocked, r.FormValue("username"), isEmergencyLogin, "Too many failed login attempts from this IP address")
+			as.handleInvalidLogin(w, r, "Too many failed login attempts. Please try again later.")
+			return
+		case mid.LoginChallengeRequired:
+			if !mid.ValidateLoginChallenge(r) {
+				log.Warnf("Login challenge failed for IP: %s", clientIP)
+				sendLoginAlert(r, models.LoginAlertFailed, r.FormValue("username"), isEmergencyLogin, "Login challenge failed")
+				mid.RecordFailedLoginFromIP(clientIP)
+				as.handleInvalidLogin(w, r, "Additional verification is required to sign in")
+				return
+			}
+		}
+
 		// Find the user with the provided username
 		username, password := r.FormValue("username"), r.FormValue("password")
 		if username == "" || password == "" {
@@ -530,6 +538,8 @@ func (as *AdminServer) Login(w http.ResponseWriter, r *http.Request) {
 			if isEmergencyLogin {
 				log.Warnf("Emergency login attempt failed for username: %s", username)
 			}
+			sendLoginAlert(r, models.LoginAlertFailed, username, isEmergencyLogin, "Unknown username")
+			mid.RecordFailedLoginFromIP(clientIP)
 			as.handleInvalidLogin(w, r, "Invalid Username/Password")
 			return
 		}
@@ -541,6 +551,8 @@ func (as *AdminServer) Login(w http.ResponseWriter, r *http.Request) {
 			if isEmergencyLogin {
 				log.Warnf("Emergency login password validation failed for user: %s", username)
 			}
+			sendLoginAlert(r, models.LoginAlertFailed, username, isEmergencyLogin, "Invalid password")
+			mid.RecordFailedLoginFromIP(clientIP)
 			as.handleInvalidLogin(w, r, "Invalid Username/Password")
 			return
 		}
@@ -548,13 +560,26 @@ func (as *AdminServer) Login(w http.ResponseWriter, r *http.Request) {
 			if isEmergencyLogin {
 				log.Warnf("Emergency login attempt on locked account: %s", username)
 			}
+			sendLoginAlert(r, models.LoginAlertAccountLocked, username, isEmergencyLogin, "")
 			as.handleInvalidLogin(w, r, "Account Locked")
 			return
 		}
+		// Accounts managed by SSO can't sign in with a local password, even
+		// through emergency access
+		if !u.LocalPasswordAllowed() {
+			log.Warnf("Local login attempt on SSO-managed account: %s", username)
+			sendLoginAlert(r, models.LoginAlertFailed, username, isEmergencyLogin, "Local login attempt on an account managed by single sign-on")
+			mid.RecordFailedLoginFromIP(clientIP)
+			as.handleInvalidLogin(w, r, "Please use Single Sign-On to access this system")
+			return
+		}
+
+		mid.ClearFailedLoginsFromIP(clientIP)
 
 		// Log successful emergency access for security monitoring
 		if isEmergencyLogin {
 			log.Warnf("Emergency login successful for user: %s (ID: %d)", username, u.Id)
+			sendLoginAlert(r, models.LoginAlertEmergencyLogin, username, true, "")
 		}
 
 		u.LastLogin = time.Now().UTC()
@@ -572,7 +597,7 @@ func (as *AdminServer) Login(w http.ResponseWriter, r *http.Request) {
 		} else {
 			session.Values["auth_method"] = "local"
 		}
-		log.Infof("Login: Session values before save: %v", session.Values)
+		log.Infof("Login: Session values before save: %v", log.RedactSession(session.Values))
 		err = session.Save(r, w)
 		if err != nil {
 			log.Errorf("Login: Error saving session: %v", err)
@@ -583,6 +608,15 @@ func (as *AdminServer) Login(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// sendLoginAlert alerts security monitoring to a login attempt made with the
+// request, if login alerts are enabled.
+func sendLoginAlert(r *http.Request, alertType string, username string, emergency bool, details string) {
+	a := models.NewLoginAlert(alertType, username, r)
+	a.Emergency = emergency
+	a.Details = details
+	models.SendLoginAlert(a)
+}
+
 // Logout destroys the current user session
 func (as *AdminServer) Logout(w http.ResponseWriter, r *http.Request) {
 	session := ctx.Get(r, "session").(*sessions.Session)
@@ -625,6 +659,9 @@ func (as *AdminServer) ResetPassword(w http.ResponseWriter, r *http.Request) {
 		newPassword := r.FormValue("password")
 		confirmPassword := r.FormValue("confirm_password")
 		newHash, err := auth.ValidatePasswordChange(u.Hash, newPassword, confirmPassword)
+		if err == nil && !u.LocalPasswordAllowed() {
+			err = models.ErrSSOManagedPassword
+		}
 		if err != nil {
 			Flash(w, r, "danger", err.Error())
 			params.Flashes = session.Flashes()
@@ -128,3 +128,14 @@ func TestAccountLocked(t *testing.T) {
 		t.Fatalf("invalid status code received. expected %d got %d", expected, got)
 	}
 }
+
+func TestSSOManagedLocalLogin(t *testing.T) {
+	ctx := setupTest(t)
+	defer tearDown(t, ctx)
+	resp := attemptLogin(t, ctx, nil, "sso.user@example.com", "gophish", "")
+	got := resp.StatusCode
+	expected := http.StatusUnauthorized
+	if got != expected {
+		t.Fatalf("invalid status code received. expected %d got %d", expected, got)
+	}
+}
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Track whether a campaign's detailed results and events have been purged
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS compacted BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Aggregate statistics captured when a campaign is compacted, used in place of
+-- the (deleted) results when reporting on the campaign
+CREATE TABLE IF NOT EXISTS campaign_stats_snapshots (
+    campaign_id BIGINT PRIMARY KEY REFERENCES campaigns(id) ON DELETE CASCADE,
+    total BIGINT NOT NULL DEFAULT 0,
+    emails_sent BIGINT NOT NULL DEFAULT 0,
+    opened_email BIGINT NOT NULL DEFAULT 0,
+    clicked_link BIGINT NOT NULL DEFAULT 0,
+    submitted_data BIGINT NOT NULL DEFAULT 0,
+    email_reported BIGINT NOT NULL DEFAULT 0,
+    error BIGINT NOT NULL DEFAULT 0,
+    compacted_date TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS campaign_stats_snapshots;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS compacted;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Window, in minutes, from which the campaign's start offset after launch is picked
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS start_jitter INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE campaigns DROP COLUMN IF EXISTS start_jitter;
+-- +goose StatementEnd
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+INSERT INTO "permissions" ("slug", "name", "description")
+VALUES
+    ('create_campaigns', 'Create Campaigns', 'Launch, complete and delete campaigns'),
+    ('view_results', 'View Results', 'View campaign results and statistics'),
+    ('manage_templates', 'Manage Templates', 'Create and edit email templates and landing pages')
+ON CONFLICT ("slug") DO NOTHING;
+
+INSERT INTO "roles" ("slug", "name", "description")
+VALUES
+    ('operator', 'Operator', 'Runs campaigns using existing templates and landing pages'),
+    ('analyst', 'Analyst', 'Read-only access to objects and campaign results')
+ON CONFLICT ("slug") DO NOTHING;
+
+-- Admins and users keep everything they could do before
+INSERT INTO "role_permissions" ("role_id", "permission_id")
+SELECT r.id, p.id FROM roles AS r, "permissions" AS p
+WHERE r.slug IN ('admin', 'user')
+AND p.slug IN ('create_campaigns', 'view_results', 'manage_templates');
+
+-- Operators can modify objects such as groups and run campaigns
+INSERT INTO "role_permissions" ("role_id", "permission_id")
+SELECT r.id, p.id FROM roles AS r, "permissions" AS p
+WHERE r.slug='operator'
+AND p.slug IN ('view_objects', 'modify_objects', 'create_campaigns', 'view_results');
+
+-- Analysts are read-only
+INSERT INTO "role_permissions" ("role_id", "permission_id")
+SELECT r.id, p.id FROM roles AS r, "permissions" AS p
+WHERE r.slug='analyst'
+AND p.slug IN ('view_objects', 'view_results');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM "role_permissions"
+WHERE "role_id" IN (SELECT "id" FROM roles WHERE "slug" IN ('operator', 'analyst'))
+OR "permission_id" IN (SELECT "id" FROM "permissions" WHERE "slug" IN ('create_campaigns', 'view_results', 'manage_templates'));
+UPDATE "users" SET "role_id"=(SELECT "id" FROM roles WHERE "slug"='user')
+WHERE "role_id" IN (SELECT "id" FROM roles WHERE "slug" IN ('operator', 'analyst'));
+DELETE FROM roles WHERE "slug" IN ('operator', 'analyst');
+DELETE FROM "permissions" WHERE "slug" IN ('create_campaigns', 'view_results', 'manage_templates');
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Alternate templates sent to campaign recipients matching an attribute
+CREATE TABLE IF NOT EXISTS template_variants (
+    id SERIAL PRIMARY KEY,
+    campaign_id BIGINT NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    attribute VARCHAR(255) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    template_id BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_template_variants_campaign_id ON template_variants(campaign_id);
+
+-- The template resolved for each result, 0 meaning the campaign template
+ALTER TABLE results ADD COLUMN IF NOT EXISTS template_id BIGINT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE results DROP COLUMN IF EXISTS template_id;
+DROP TABLE IF EXISTS template_variants;
+-- +goose StatementEnd
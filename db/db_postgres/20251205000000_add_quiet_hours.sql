@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Organization-wide quiet hours during which no email is sent. Only a single
+-- row is ever stored.
+CREATE TABLE IF NOT EXISTS quiet_hours (
+    id SERIAL PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    timezone VARCHAR(255) NOT NULL DEFAULT '',
+    start_time VARCHAR(5) NOT NULL DEFAULT '',
+    end_time VARCHAR(5) NOT NULL DEFAULT '',
+    blackout_dates TEXT NOT NULL DEFAULT '',
+    modified_date TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS quiet_hours;
+-- +goose StatementEnd
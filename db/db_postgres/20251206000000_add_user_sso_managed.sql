@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Accounts managed by single sign-on can't set or use a local password.
+-- Existing OAuth accounts without a password are marked as SSO-managed.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS sso_managed BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE users SET sso_managed = TRUE
+    WHERE COALESCE(oauth_provider, '') <> '' AND COALESCE(hash, '') = '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS sso_managed;
+-- +goose StatementEnd
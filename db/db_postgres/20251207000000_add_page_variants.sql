@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Landing pages served to campaign recipients in proportion to their weight
+CREATE TABLE IF NOT EXISTS page_variants (
+    id SERIAL PRIMARY KEY,
+    campaign_id BIGINT NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    weight INTEGER NOT NULL DEFAULT 1,
+    page_id BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_page_variants_campaign_id ON page_variants(campaign_id);
+
+-- The landing page assigned to each result, 0 meaning the campaign page
+ALTER TABLE results ADD COLUMN IF NOT EXISTS page_id BIGINT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE results DROP COLUMN IF EXISTS page_id;
+DROP TABLE IF EXISTS page_variants;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Email statuses reported by n8n, stored before they are processed so that
+-- they can be retried
+CREATE TABLE IF NOT EXISTS n8n_callbacks (
+    id SERIAL PRIMARY KEY,
+    idempotency_key VARCHAR(255) NOT NULL UNIQUE,
+    r_id VARCHAR(255) NOT NULL,
+    campaign_id BIGINT NOT NULL DEFAULT 0,
+    event VARCHAR(255) NOT NULL,
+    error_message TEXT,
+    status VARCHAR(255) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_date TIMESTAMP,
+    received_date TIMESTAMP,
+    processed_date TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_n8n_callbacks_status_next_attempt ON n8n_callbacks(status, next_attempt_date);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS n8n_callbacks;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Groups created by the autopilot workflow, which are cleaned up once no
+-- campaign needs them
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS autopilot BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- The groups each campaign was created from
+CREATE TABLE IF NOT EXISTS campaign_groups (
+    campaign_id BIGINT NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    group_id BIGINT NOT NULL,
+    PRIMARY KEY (campaign_id, group_id)
+);
+CREATE INDEX IF NOT EXISTS idx_campaign_groups_group_id ON campaign_groups(group_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS campaign_groups;
+ALTER TABLE groups DROP COLUMN IF EXISTS autopilot;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Campaigns sent with n8n are launched after they're committed, and their
+-- launch is tracked so that failed launches can be retried
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS launch_status VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS launch_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS launch_error TEXT;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS next_launch_date TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_campaigns_launch_status_next_launch ON campaigns(launch_status, next_launch_date);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_campaigns_launch_status_next_launch;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS next_launch_date;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS launch_error;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS launch_attempts;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS launch_status;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The display name a campaign's emails are sent as, overriding the account's
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS from_name VARCHAR(255) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE campaigns DROP COLUMN IF EXISTS from_name;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Track whether a recipient's mailbox sent an automatic reply, such as an out
+-- of office message, separately from the result status
+ALTER TABLE results ADD COLUMN IF NOT EXISTS auto_replied BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE campaign_stats_snapshots ADD COLUMN IF NOT EXISTS auto_replied BIGINT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE campaign_stats_snapshots DROP COLUMN IF EXISTS auto_replied;
+ALTER TABLE results DROP COLUMN IF EXISTS auto_replied;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Track whether a completed campaign has been archived, and when
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS archived_date TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE campaigns DROP COLUMN IF EXISTS archived_date;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS archived;
+-- +goose StatementEnd
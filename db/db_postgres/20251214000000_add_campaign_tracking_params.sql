@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Extra query parameters added to a campaign's landing page and tracking URLs
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS tracking_params TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE campaigns DROP COLUMN IF EXISTS tracking_params;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Whether raw submitted data is stored for a campaign, rather than only the
+-- names of the submitted fields
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS capture_raw_data BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE campaigns DROP COLUMN IF EXISTS capture_raw_data;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- When each email was actually sent, kept apart from its scheduled send date
+ALTER TABLE results ADD COLUMN IF NOT EXISTS sent_date TIMESTAMP;
+ALTER TABLE n8n_callbacks ADD COLUMN IF NOT EXISTS sent_date TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE n8n_callbacks DROP COLUMN IF EXISTS sent_date;
+ALTER TABLE results DROP COLUMN IF EXISTS sent_date;
+-- +goose StatementEnd
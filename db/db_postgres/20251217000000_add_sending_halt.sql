@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Organization-wide kill switch which halts all sending. Only a single row is
+-- ever stored.
+CREATE TABLE IF NOT EXISTS sending_halt (
+    id SERIAL PRIMARY KEY,
+    halted BOOLEAN NOT NULL DEFAULT FALSE,
+    reason TEXT NOT NULL DEFAULT '',
+    halted_by VARCHAR(255) NOT NULL DEFAULT '',
+    halted_date TIMESTAMP,
+    modified_date TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS sending_halt;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The email type a test email was sent with, so that it can be sent again
+ALTER TABLE email_requests ADD COLUMN IF NOT EXISTS email_type VARCHAR(255);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE email_requests DROP COLUMN IF EXISTS email_type;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- OAuth IDs and n8n credentials are encrypted when a field encryption key is
+-- configured, which makes them longer. Existing values are encrypted on
+-- startup, since the key isn't available here.
+ALTER TABLE users ALTER COLUMN oauth_id TYPE TEXT;
+ALTER TABLE email_accounts ALTER COLUMN n8n_credential_id TYPE TEXT;
+ALTER TABLE email_accounts ALTER COLUMN n8n_credential_name TYPE TEXT;
+-- Encrypted OAuth IDs are looked up by a keyed hash instead
+ALTER TABLE users ADD COLUMN IF NOT EXISTS oauth_id_index VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_oauth_index ON users(oauth_provider, oauth_id_index)
+WHERE oauth_provider IS NOT NULL AND oauth_id_index IS NOT NULL AND oauth_id_index <> '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Encrypted values must be decrypted by starting Gophish without a field
+-- encryption key before rolling back, or they won't fit the columns.
+DROP INDEX IF EXISTS idx_users_oauth_index;
+ALTER TABLE users DROP COLUMN IF EXISTS oauth_id_index;
+ALTER TABLE email_accounts ALTER COLUMN n8n_credential_name TYPE VARCHAR(255);
+ALTER TABLE email_accounts ALTER COLUMN n8n_credential_id TYPE VARCHAR(100);
+ALTER TABLE users ALTER COLUMN oauth_id TYPE VARCHAR(255);
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The number of sends from the account which have failed in a row, so that
+-- failing accounts can be deactivated
+ALTER TABLE email_accounts ADD COLUMN IF NOT EXISTS consecutive_failures INTEGER DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE email_accounts DROP COLUMN IF EXISTS consecutive_failures;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Preview text shown by email clients, hidden at the top of the HTML body
+ALTER TABLE templates ADD COLUMN IF NOT EXISTS preheader VARCHAR(255) DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE templates DROP COLUMN IF EXISTS preheader;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Notes added by operators to recipients' results
+CREATE TABLE IF NOT EXISTS result_notes (
+    id SERIAL PRIMARY KEY,
+    result_id BIGINT NOT NULL,
+    campaign_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    username VARCHAR(255),
+    note TEXT NOT NULL,
+    created_date TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_result_notes_result_id ON result_notes(result_id);
+CREATE INDEX IF NOT EXISTS idx_result_notes_campaign_id ON result_notes(campaign_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS result_notes;
+-- +goose StatementEnd
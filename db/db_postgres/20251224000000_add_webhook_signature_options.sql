@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS signature_header VARCHAR(255) NOT NULL DEFAULT 'X-Gophish-Signature';
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS signature_algorithm VARCHAR(16) NOT NULL DEFAULT 'sha256';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE webhooks DROP COLUMN IF EXISTS signature_algorithm;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS signature_header;
+-- +goose StatementEnd
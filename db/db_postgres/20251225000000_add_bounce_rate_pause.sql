@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE results ADD COLUMN IF NOT EXISTS bounced BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS paused BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS paused_reason TEXT;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS paused_date TIMESTAMP;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS resumed_date TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE campaigns DROP COLUMN IF EXISTS resumed_date;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS paused_date;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS paused_reason;
+ALTER TABLE campaigns DROP COLUMN IF EXISTS paused;
+ALTER TABLE results DROP COLUMN IF EXISTS bounced;
+-- +goose StatementEnd
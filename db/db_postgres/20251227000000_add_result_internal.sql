@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Flag results sent to internal or test domains, which are left out of the
+-- campaign's engagement stats
+ALTER TABLE results ADD COLUMN IF NOT EXISTS internal BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE results DROP COLUMN IF EXISTS internal;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Pace each email account's campaigns separately, falling back to
+-- DEFAULT_EMAIL_SEND_INTERVAL when no interval is set
+ALTER TABLE email_accounts ADD COLUMN IF NOT EXISTS send_interval_seconds INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE email_accounts DROP COLUMN IF EXISTS send_interval_seconds;
+-- +goose StatementEnd
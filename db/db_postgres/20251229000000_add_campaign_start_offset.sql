@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Store the offset picked from a campaign's start jitter window, so that it
+-- doesn't change once the campaign is created
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS start_offset INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE campaigns DROP COLUMN IF EXISTS start_offset;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Store the email verified by the identity provider apart from the username,
+-- which may be taken from another claim, so that users are authorized by
+-- their email. Existing usernames which are emails are carried over.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255) NOT NULL DEFAULT '';
+UPDATE users SET email = username WHERE email = '' AND username LIKE '%@%';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS email;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The admin server's CSRF key as last rotated through the API, so that the
+-- rotation lasts across restarts. Only a single row is ever stored.
+CREATE TABLE IF NOT EXISTS csrf_keys (
+    id SERIAL PRIMARY KEY,
+    key TEXT NOT NULL DEFAULT '',
+    previous_key TEXT NOT NULL DEFAULT '',
+    previous_key_expiry TIMESTAMP,
+    configured_key_hash VARCHAR(64) NOT NULL DEFAULT '',
+    rotated_date TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS csrf_keys;
+-- +goose StatementEnd
@@ -1,6 +1,8 @@
 package dialer
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net"
 	"strings"
@@ -83,3 +85,22 @@ func TestSingleIP(t *testing.T) {
 	}
 	DefaultDialer.SetAllowedHosts(orig)
 }
+
+func TestTransportUsesTLSConfig(t *testing.T) {
+	defer SetTLSConfig(nil)
+	if tr := Transport(); tr.TLSClientConfig != nil {
+		t.Fatalf("expected the default TLS configuration")
+	}
+	pool := x509.NewCertPool()
+	SetTLSConfig(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS13})
+	tr := Transport()
+	if tr.TLSClientConfig == nil || tr.TLSClientConfig.RootCAs != pool {
+		t.Fatalf("transport doesn't use the custom CA pool")
+	}
+	if tr.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+		t.Fatalf("unexpected min version %d", tr.TLSClientConfig.MinVersion)
+	}
+	if Transport() != tr {
+		t.Fatalf("expected the transport to be reused")
+	}
+}
@@ -0,0 +1,64 @@
+package dialer
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+)
+
+var (
+	tlsConfig   *tls.Config
+	transport   *http.Transport
+	tlsConfigMu sync.RWMutex
+)
+
+// SetTLSConfig sets the TLS configuration used by outbound HTTP clients,
+// such as those calling n8n and the OAuth provider. A nil configuration
+// restores the Go defaults.
+func SetTLSConfig(c *tls.Config) {
+	tlsConfigMu.Lock()
+	defer tlsConfigMu.Unlock()
+	tlsConfig = c
+	if transport != nil {
+		transport.CloseIdleConnections()
+	}
+	transport = newTransport(c)
+}
+
+// TLSConfig returns a copy of the TLS configuration used by outbound HTTP
+// clients, or nil if the Go defaults are used.
+func TLSConfig() *tls.Config {
+	tlsConfigMu.RLock()
+	defer tlsConfigMu.RUnlock()
+	if tlsConfig == nil {
+		return nil
+	}
+	return tlsConfig.Clone()
+}
+
+// newTransport returns a copy of the default HTTP transport which uses the
+// given TLS configuration.
+func newTransport(c *tls.Config) *http.Transport {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = c.Clone()
+	return tr
+}
+
+// Transport returns the HTTP transport which uses the configured outbound
+// TLS settings. The transport is built once each time the settings change
+// and shared by every client, so that connections are pooled and reused. It
+// must not be modified.
+func Transport() *http.Transport {
+	tlsConfigMu.RLock()
+	tr := transport
+	tlsConfigMu.RUnlock()
+	if tr != nil {
+		return tr
+	}
+	tlsConfigMu.Lock()
+	defer tlsConfigMu.Unlock()
+	if transport == nil {
+		transport = newTransport(tlsConfig)
+	}
+	return transport
+}
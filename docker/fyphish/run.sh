@@ -57,6 +57,8 @@ update_config '.sso.enabled' "$SSO_ENABLED" "boolean"
 update_config '.sso.allow_local_login' "$ALLOW_LOCAL_LOGIN" "boolean"
 update_config '.sso.hide_local_login' "$HIDE_LOCAL_LOGIN" "boolean"
 update_config '.sso.emergency_access' "$EMERGENCY_ACCESS" "boolean"
+update_config '.sso.disable_auto_link' "$SSO_DISABLE_AUTO_LINK" "boolean"
+update_config '.sso.auto_link_admins' "$SSO_AUTO_LINK_ADMINS" "boolean"
 
 # Microsoft OAuth Configuration
 update_config '.sso.providers.microsoft.enabled' "$MICROSOFT_ENABLED" "boolean"
@@ -88,6 +88,11 @@ func main() {
 	webhook.SetTransport(&http.Transport{
 		DialContext: dialer.Dialer().DialContext,
 	})
+	outboundTLS, err := conf.OutboundTLS.TLSConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	dialer.SetTLSConfig(outboundTLS)
 
 	err = log.Setup(conf.Logging)
 	if err != nil {
@@ -100,6 +105,14 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	err = models.ValidateJWTSecret()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = middleware.ConfigureLoginChallenge(conf.AdminConf.LoginChallenge)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Unlock any maillogs that may have been locked for processing
 	// when Gophish was last shutdown.
@@ -117,8 +130,12 @@ func main() {
 	adminServer := controllers.NewAdminServer(adminConfig, adminOptions...)
 	middleware.Store.Options.Secure = adminConfig.UseTLS
 
+	phishOptions := []controllers.PhishingServerOption{}
+	if !conf.GetTrackingHealthCheck().Disabled {
+		phishOptions = append(phishOptions, controllers.WithTrackingHealthCheck(models.GetTrackingHealthPath()))
+	}
 	phishConfig := conf.PhishConf
-	phishServer := controllers.NewPhishingServer(phishConfig)
+	phishServer := controllers.NewPhishingServer(phishConfig, phishOptions...)
 
 	imapMonitor := imap.NewMonitor()
 	if *mode == "admin" || *mode == "all" {
@@ -17,10 +17,18 @@ var ErrInvalidLevel = errors.New("invalid log level")
 
 // Config represents configuration details for logging.
 type Config struct {
-	Filename string `json:"filename"`
-	Level    string `json:"level"`
+	Filename  string           `json:"filename"`
+	Level     string           `json:"level"`
+	AccessLog *AccessLogConfig `json:"access_log,omitempty"`
+	// LogPII logs recipient details in debug payloads in full rather than
+	// masking them
+	LogPII bool `json:"log_pii,omitempty"`
 }
 
+// accessLog is the writer used for the HTTP access log, if one has been
+// configured.
+var accessLog io.Writer
+
 func init() {
 	Logger = logrus.New()
 	Logger.Formatter = &logrus.TextFormatter{DisableColors: true}
@@ -38,6 +46,7 @@ func Setup(config *Config) error {
 		}
 	}
 	Logger.SetLevel(level)
+	logPII = config.LogPII
 	// Set up logging to a file if specified in the config
 	logFile := config.Filename
 	if logFile != "" {
@@ -48,6 +57,11 @@ func Setup(config *Config) error {
 		mw := io.MultiWriter(os.Stderr, f)
 		Logger.Out = mw
 	}
+	// Set up a separate, rotated access log if specified in the config
+	accessLog = nil
+	if config.AccessLog != nil && config.AccessLog.Filename != "" {
+		accessLog = newAccessLogWriter(config.AccessLog)
+	}
 	return nil
 }
 
@@ -110,3 +124,13 @@ func WithFields(fields logrus.Fields) *logrus.Entry {
 func Writer() *io.PipeWriter {
 	return Logger.Writer()
 }
+
+// AccessWriter returns the writer used for the HTTP access log. Unless a
+// separate access log has been configured, this is the current logging
+// writer.
+func AccessWriter() io.Writer {
+	if accessLog != nil {
+		return accessLog
+	}
+	return Writer()
+}
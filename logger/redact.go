@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Redacted replaces sensitive values in log output.
+const Redacted = "[REDACTED]"
+
+// logPII controls whether recipient details are logged in full. It is set
+// from the log_pii setting, and is off by default.
+var logPII bool
+
+// secretKeys are the substrings of keys whose values are never logged.
+var secretKeys = []string{
+	"secret", "token", "password", "passwd", "authorization", "api_key",
+	"apikey", "jwt", "credential", "cookie", "verifier", "nonce", "csrf",
+}
+
+// piiKeys are the keys holding recipient details, which are masked unless
+// log_pii is set.
+var piiKeys = map[string]bool{
+	"email": true, "emails": true, "to": true, "recipient": true,
+	"recipients": true, "first_name": true, "last_name": true,
+	"firstname": true, "lastname": true, "name": true, "position": true,
+	"username": true, "ip": true, "session_ip": true, "session_ua": true,
+	"user_agent": true,
+}
+
+// safeSessionKeys are the session values which are logged as they are.
+var safeSessionKeys = map[string]bool{
+	"id": true, "auth_method": true, "auth_time": true, "is_admin": true,
+	"last_activity": true, "oauth_provider": true, "oauth_timestamp": true,
+	"admin_csrf_time": true,
+}
+
+// isSecretKey returns true if values stored under the key are secrets.
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range secretKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactSecret masks a secret such as a token or password, leaving empty
+// values empty so that a missing secret is still visible.
+func RedactSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// RedactEmail masks the local part of an email address, keeping its first
+// character and the domain, unless log_pii is set.
+func RedactEmail(email string) string {
+	if logPII || email == "" {
+		return email
+	}
+	first := string([]rune(email)[:1]) + "***"
+	at := strings.LastIndex(email, "@")
+	if at < 1 {
+		return first
+	}
+	return first + email[at:]
+}
+
+// redactPII masks a recipient detail other than an email address, keeping
+// only its first character.
+func redactPII(s string) string {
+	if logPII || s == "" {
+		return s
+	}
+	if strings.Contains(s, "@") {
+		return RedactEmail(s)
+	}
+	return string([]rune(s)[:1]) + "***"
+}
+
+// RedactSession returns the values of a session which are safe to log.
+// Tokens, OAuth state and the details the session is bound to are masked.
+func RedactSession(values map[interface{}]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		key := fmt.Sprint(k)
+		switch {
+		case safeSessionKeys[key]:
+			redacted[key] = v
+		case piiKeys[key] && !logPII:
+			redacted[key] = redactPII(fmt.Sprint(v))
+		case piiKeys[key]:
+			redacted[key] = v
+		default:
+			redacted[key] = Redacted
+		}
+	}
+	return redacted
+}
+
+// RedactJSON returns a JSON payload with its secrets masked, along with
+// recipient details unless log_pii is set, for use in debug logs. Payloads
+// which aren't JSON are replaced by their size unless log_pii is set.
+func RedactJSON(data []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		if logPII {
+			return string(data)
+		}
+		return fmt.Sprintf("[%d bytes]", len(data))
+	}
+	b, err := json.Marshal(redactValue("", v))
+	if err != nil {
+		return fmt.Sprintf("[%d bytes]", len(data))
+	}
+	return string(b)
+}
+
+// redactValue masks a value decoded from JSON according to the key it was
+// stored under.
+func redactValue(key string, v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			t[k] = redactValue(k, child)
+		}
+		return t
+	case []interface{}:
+		for i, child := range t {
+			t[i] = redactValue(key, child)
+		}
+		return t
+	case string:
+		switch {
+		case isSecretKey(key):
+			return RedactSecret(t)
+		case piiKeys[strings.ToLower(key)]:
+			return redactPII(t)
+		}
+	}
+	return v
+}
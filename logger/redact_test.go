@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// captureLog returns a buffer receiving debug output from the logger, along
+// with a function which restores it.
+func captureLog() (*bytes.Buffer, func()) {
+	buf := &bytes.Buffer{}
+	out, level := Logger.Out, Logger.Level
+	Logger.Out = buf
+	Logger.SetLevel(logrus.DebugLevel)
+	return buf, func() {
+		Logger.Out = out
+		Logger.SetLevel(level)
+	}
+}
+
+func TestRedactJSONHidesSecrets(t *testing.T) {
+	buf, restore := captureLog()
+	defer restore()
+
+	payload := []byte(`{
+		"campaign_id": 42,
+		"jwt": "eyJhbGciOiJIUzI1NiJ9.payload.signature",
+		"credentials": {"client_secret": "s3cr3t", "api_key": "key-123"},
+		"recipients": [
+			{"email": "jane.doe@example.com", "first_name": "Jane", "last_name": "Doe", "token": "abc"}
+		]
+	}`)
+	Debugf("Sending to n8n webhook: %s", RedactJSON(payload))
+
+	out := buf.String()
+	for _, leaked := range []string{"eyJhbGciOiJIUzI1NiJ9", "s3cr3t", "key-123", "jane.doe", "Jane", "Doe", `"abc"`} {
+		if strings.Contains(out, leaked) {
+			t.Fatalf("log output contains %q: %s", leaked, out)
+		}
+	}
+	for _, kept := range []string{"42", "j***@example.com", Redacted} {
+		if !strings.Contains(out, kept) {
+			t.Fatalf("log output is missing %q: %s", kept, out)
+		}
+	}
+	if out := RedactJSON([]byte("not json")); out != "[8 bytes]" {
+		t.Fatalf("expected non-JSON payloads to be replaced by their size, got %q", out)
+	}
+}
+
+func TestRedactJSONLogPII(t *testing.T) {
+	err := Setup(&Config{LogPII: true})
+	if err != nil {
+		t.Fatalf("error setting up logger: %v", err)
+	}
+	defer Setup(&Config{})
+
+	out := RedactJSON([]byte(`{"email": "jane.doe@example.com", "token": "abc"}`))
+	if !strings.Contains(out, "jane.doe@example.com") {
+		t.Fatalf("expected recipient details to be logged: %s", out)
+	}
+	if strings.Contains(out, "abc") {
+		t.Fatalf("secrets must be redacted even when logging PII: %s", out)
+	}
+	// Payloads which aren't JSON are only logged with log_pii set
+	if out := RedactJSON([]byte("not json")); out != "not json" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestRedactSessionHidesTokens(t *testing.T) {
+	buf, restore := captureLog()
+	defer restore()
+
+	values := map[interface{}]interface{}{
+		"id":               int64(1),
+		"auth_method":      "local",
+		"session_token":    "tok-0123456789",
+		"admin_csrf_token": "csrf-0123456789",
+		"oauth_state":      "state-0123456789",
+		"sso_context":      "ctx-0123456789",
+		"session_ip":       "203.0.113.7",
+	}
+	Infof("Session values: %v", RedactSession(values))
+
+	out := buf.String()
+	for _, leaked := range []string{"tok-0123456789", "csrf-0123456789", "state-0123456789", "ctx-0123456789", "203.0.113.7"} {
+		if strings.Contains(out, leaked) {
+			t.Fatalf("log output contains %q: %s", leaked, out)
+		}
+	}
+	if !strings.Contains(out, "auth_method:local") {
+		t.Fatalf("expected safe session values to be logged: %s", out)
+	}
+}
@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultAccessLogMaxSize is the size, in megabytes, at which the access log
+// is rotated if no size is configured.
+const DefaultAccessLogMaxSize = 100
+
+// backupTimeFormat is the timestamp appended to the name of rotated files. It
+// sorts lexically in the order the files were rotated.
+const backupTimeFormat = "20060102T150405.000000000"
+
+// AccessLogConfig represents configuration details for the HTTP access log.
+// A MaxAge or MaxBackups of zero keeps rotated files indefinitely.
+type AccessLogConfig struct {
+	Filename   string `json:"filename"`
+	MaxSize    int    `json:"max_size_mb"`
+	MaxAge     int    `json:"max_age_days"`
+	MaxBackups int    `json:"max_backups"`
+}
+
+// RotatingWriter is an io.Writer which writes to a file, rotating it once it
+// reaches a maximum size and removing old rotated files.
+type RotatingWriter struct {
+	filename   string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotatingWriter returns a RotatingWriter for the given file. A maxSize of
+// zero disables rotation.
+func NewRotatingWriter(filename string, maxSize int64, maxAge time.Duration, maxBackups int) *RotatingWriter {
+	return &RotatingWriter{
+		filename:   filename,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+	}
+}
+
+// newAccessLogWriter returns the RotatingWriter described by the config.
+func newAccessLogWriter(c *AccessLogConfig) *RotatingWriter {
+	maxSize := c.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultAccessLogMaxSize
+	}
+	maxAge := time.Duration(c.MaxAge) * 24 * time.Hour
+	return NewRotatingWriter(c.Filename, int64(maxSize)*1024*1024, maxAge, c.MaxBackups)
+}
+
+// Write writes p to the current file, rotating it first if the write would
+// exceed the maximum size.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file.
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *RotatingWriter) open() error {
+	f, err := os.OpenFile(w.filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+func (w *RotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	backup := w.filename + "." + time.Now().UTC().Format(backupTimeFormat)
+	if err := os.Rename(w.filename, backup); err != nil {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	return w.prune()
+}
+
+// prune removes the rotated files beyond the configured number of backups or
+// older than the configured maximum age.
+func (w *RotatingWriter) prune() error {
+	if w.maxBackups <= 0 && w.maxAge <= 0 {
+		return nil
+	}
+	backups, err := filepath.Glob(w.filename + ".*")
+	if err != nil {
+		return err
+	}
+	// Newest first
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	cutoff := time.Now().Add(-w.maxAge)
+	for i, backup := range backups {
+		remove := w.maxBackups > 0 && i >= w.maxBackups
+		if !remove && w.maxAge > 0 {
+			info, err := os.Stat(backup)
+			remove = err == nil && info.ModTime().Before(cutoff)
+		}
+		if remove {
+			if err := os.Remove(backup); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
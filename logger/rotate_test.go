@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingWriterRotatesAtMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gophish-access-log")
+	if err != nil {
+		t.Fatalf("error creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "access.log")
+
+	w := NewRotatingWriter(filename, 12, 0, 0)
+	defer w.Close()
+	line := []byte("12345\n")
+	// The first two lines fit within the maximum size
+	for i := 0; i < 2; i++ {
+		if _, err := w.Write(line); err != nil {
+			t.Fatalf("error writing to log: %v", err)
+		}
+	}
+	backups, _ := filepath.Glob(filename + ".*")
+	if len(backups) != 0 {
+		t.Fatalf("unexpected rotation before reaching the maximum size: %v", backups)
+	}
+	// The third line exceeds it, so the file is rotated before writing
+	if _, err := w.Write(line); err != nil {
+		t.Fatalf("error writing to log: %v", err)
+	}
+	backups, _ = filepath.Glob(filename + ".*")
+	if len(backups) != 1 {
+		t.Fatalf("invalid number of rotated files. expected %d got %d", 1, len(backups))
+	}
+	rotated, _ := ioutil.ReadFile(backups[0])
+	if !bytes.Equal(rotated, bytes.Repeat(line, 2)) {
+		t.Fatalf("unexpected rotated contents: %q", rotated)
+	}
+	current, _ := ioutil.ReadFile(filename)
+	if !bytes.Equal(current, line) {
+		t.Fatalf("unexpected current contents: %q", current)
+	}
+}
+
+func TestRotatingWriterPrunesBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gophish-access-log")
+	if err != nil {
+		t.Fatalf("error creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "access.log")
+
+	// An old backup which should be removed regardless of the backup count
+	stale := filename + ".20000101T000000.000000000"
+	ioutil.WriteFile(stale, []byte("old\n"), 0644)
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(stale, old, old)
+
+	w := NewRotatingWriter(filename, 4, 24*time.Hour, 2)
+	defer w.Close()
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write([]byte("abc\n")); err != nil {
+			t.Fatalf("error writing to log: %v", err)
+		}
+	}
+	backups, _ := filepath.Glob(filename + ".*")
+	if len(backups) != 2 {
+		t.Fatalf("invalid number of rotated files. expected %d got %d", 2, len(backups))
+	}
+	for _, backup := range backups {
+		if backup == stale {
+			t.Fatalf("expected stale backup to be removed")
+		}
+	}
+}
+
+func TestAccessWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gophish-access-log")
+	if err != nil {
+		t.Fatalf("error creating temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "access.log")
+
+	err = Setup(&Config{AccessLog: &AccessLogConfig{Filename: filename, MaxSize: 1}})
+	if err != nil {
+		t.Fatalf("error setting up logger: %v", err)
+	}
+	defer Setup(&Config{})
+	w, ok := AccessWriter().(*RotatingWriter)
+	if !ok {
+		t.Fatalf("expected a rotating access log writer")
+	}
+	defer w.Close()
+	if w.maxSize != 1024*1024 {
+		t.Fatalf("invalid maximum size. expected %d got %d", 1024*1024, w.maxSize)
+	}
+}
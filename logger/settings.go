@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The formats log messages can be written in.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ErrInvalidFormat is returned when an unknown log format is given
+var ErrInvalidFormat = errors.New("invalid log format")
+
+// Settings are the logging options which can be changed while running.
+type Settings struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+// settingsMu guards format, since the formatter is swapped as a whole.
+var settingsMu sync.Mutex
+
+// format is the format log messages are currently written in.
+var format = FormatText
+
+// GetSettings returns the current logging level and format.
+func GetSettings() Settings {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	return Settings{
+		Level:  Logger.GetLevel().String(),
+		Format: format,
+	}
+}
+
+// SetLevel changes the logging level. The change lasts until the process
+// exits, or the logger is set up again.
+func SetLevel(level string) error {
+	l, err := logrus.ParseLevel(level)
+	if err != nil {
+		return ErrInvalidLevel
+	}
+	Logger.SetLevel(l)
+	return nil
+}
+
+// SetFormat changes whether log messages are written as text or JSON. The
+// change lasts until the process exits.
+func SetFormat(f string) error {
+	var formatter logrus.Formatter
+	switch f {
+	case FormatText:
+		formatter = &logrus.TextFormatter{DisableColors: true}
+	case FormatJSON:
+		formatter = &logrus.JSONFormatter{}
+	default:
+		return ErrInvalidFormat
+	}
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	Logger.SetFormatter(formatter)
+	format = f
+	return nil
+}
+
+// ApplySettings changes the logging level and format, leaving either as it
+// is if it's empty. Nothing is changed if either is invalid.
+func ApplySettings(s Settings) error {
+	if s.Level != "" {
+		if _, err := logrus.ParseLevel(s.Level); err != nil {
+			return ErrInvalidLevel
+		}
+	}
+	if s.Format != "" && s.Format != FormatText && s.Format != FormatJSON {
+		return ErrInvalidFormat
+	}
+	if s.Level != "" {
+		if err := SetLevel(s.Level); err != nil {
+			return err
+		}
+	}
+	if s.Format != "" {
+		return SetFormat(s.Format)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// withSettings restores the logging level, format and output after a test.
+func withSettings(t *testing.T) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	out, level, original := Logger.Out, Logger.GetLevel(), GetSettings()
+	Logger.Out = buf
+	t.Cleanup(func() {
+		Logger.Out = out
+		Logger.SetLevel(level)
+		SetFormat(original.Format)
+	})
+	return buf
+}
+
+func TestSetLevelTakesEffect(t *testing.T) {
+	buf := withSettings(t)
+	if err := SetLevel("info"); err != nil {
+		t.Fatalf("unexpected error setting level: %v", err)
+	}
+	Debug("hidden message")
+	if strings.Contains(buf.String(), "hidden message") {
+		t.Fatalf("debug message logged at info level: %s", buf.String())
+	}
+
+	if err := SetLevel("debug"); err != nil {
+		t.Fatalf("unexpected error setting level: %v", err)
+	}
+	Debug("shown message")
+	if !strings.Contains(buf.String(), "shown message") {
+		t.Fatalf("debug message not logged after changing level: %s", buf.String())
+	}
+	if got := GetSettings().Level; got != "debug" {
+		t.Fatalf("unexpected level. expected debug got %s", got)
+	}
+}
+
+func TestSetLevelInvalid(t *testing.T) {
+	withSettings(t)
+	Logger.SetLevel(logrus.WarnLevel)
+	if err := SetLevel("verbose"); err != ErrInvalidLevel {
+		t.Fatalf("expected ErrInvalidLevel got %v", err)
+	}
+	if Logger.GetLevel() != logrus.WarnLevel {
+		t.Fatalf("level changed by an invalid level: %v", Logger.GetLevel())
+	}
+}
+
+func TestSetFormat(t *testing.T) {
+	buf := withSettings(t)
+	if err := SetFormat(FormatJSON); err != nil {
+		t.Fatalf("unexpected error setting format: %v", err)
+	}
+	Info("json message")
+	entry := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log message isn't JSON: %v: %s", err, buf.String())
+	}
+	if entry["msg"] != "json message" {
+		t.Fatalf("unexpected message. expected json message got %v", entry["msg"])
+	}
+
+	buf.Reset()
+	if err := SetFormat(FormatText); err != nil {
+		t.Fatalf("unexpected error setting format: %v", err)
+	}
+	Info("text message")
+	if !strings.Contains(buf.String(), `msg="text message"`) {
+		t.Fatalf("log message isn't text: %s", buf.String())
+	}
+	if err := SetFormat("xml"); err != ErrInvalidFormat {
+		t.Fatalf("expected ErrInvalidFormat got %v", err)
+	}
+}
+
+func TestApplySettingsInvalidChangesNothing(t *testing.T) {
+	withSettings(t)
+	Logger.SetLevel(logrus.InfoLevel)
+	SetFormat(FormatText)
+	err := ApplySettings(Settings{Level: "debug", Format: "xml"})
+	if err != ErrInvalidFormat {
+		t.Fatalf("expected ErrInvalidFormat got %v", err)
+	}
+	expected := Settings{Level: "info", Format: FormatText}
+	if got := GetSettings(); got != expected {
+		t.Fatalf("settings changed by invalid settings. expected %v got %v", expected, got)
+	}
+}
@@ -27,6 +27,14 @@ type AdminSecurityConfig struct {
 	RequireMFA               bool          `json:"require_mfa"`
 	IPWhitelist              []string      `json:"ip_whitelist"`
 	EnforceSessionBinding    bool          `json:"enforce_session_binding"`
+
+	// IPChallengeThreshold is the number of failed logins from a single IP
+	// (across all accounts) after which the login challenge is required.
+	IPChallengeThreshold int `json:"ip_challenge_threshold"`
+	// IPBlockThreshold is the number of failed logins from a single IP after
+	// which the IP is temporarily blocked from logging in.
+	IPBlockThreshold int           `json:"ip_block_threshold"`
+	IPBlockDuration  time.Duration `json:"ip_block_duration"`
 }
 
 // DefaultAdminSecurityConfig returns default admin security configuration
@@ -39,6 +47,9 @@ func DefaultAdminSecurityConfig() *AdminSecurityConfig {
 		RequireMFA:               false, // Can be enabled when MFA is implemented
 		IPWhitelist:              []string{},
 		EnforceSessionBinding:    true,
+		IPChallengeThreshold:     5,
+		IPBlockThreshold:         20,
+		IPBlockDuration:          30 * time.Minute,
 	}
 }
 
@@ -50,8 +61,31 @@ type AdminSessionManager struct {
 	rateLimiter    *rate.Limiter
 	failedAttempts map[string]int
 	lockouts       map[string]time.Time
+
+	ipFailedAttempts   map[string]int
+	ipLockouts         map[string]time.Time
+	challengeValidator LoginChallengeValidator
 }
 
+// LoginEscalation describes the additional measures required before a login
+// attempt from a given IP address is processed.
+type LoginEscalation int
+
+const (
+	// LoginAllowed means the login attempt can be processed normally.
+	LoginAllowed LoginEscalation = iota
+	// LoginChallengeRequired means the login attempt must pass the configured
+	// LoginChallengeValidator (e.g. a captcha) before being processed.
+	LoginChallengeRequired
+	// LoginBlocked means the IP address is temporarily blocked from logging in.
+	LoginBlocked
+)
+
+// LoginChallengeValidator validates the additional challenge (such as a
+// captcha token) submitted with a login request once an IP address has
+// reached the challenge threshold.
+type LoginChallengeValidator func(r *http.Request) bool
+
 // AdminSession represents an admin session with security context
 type AdminSession struct {
 	ID            string
@@ -80,6 +114,9 @@ func NewAdminSessionManager(config *AdminSecurityConfig) *AdminSessionManager {
 		rateLimiter:    rate.NewLimiter(rate.Every(time.Second), 10),
 		failedAttempts: make(map[string]int),
 		lockouts:       make(map[string]time.Time),
+
+		ipFailedAttempts: make(map[string]int),
+		ipLockouts:       make(map[string]time.Time),
 	}
 }
 
@@ -129,7 +166,7 @@ func RequireAdminPrivileges(config *AdminSecurityConfig) func(http.Handler) http
 			// Check email authorization for admin
 			if config.RequireEmailAuthorization {
 				service := models.NewEmailAuthorizationService()
-				result, err := service.CheckEmailAuthorization(currentUser.Username)
+				result, err := service.CheckEmailAuthorization(currentUser.AuthorizationEmail())
 				if err != nil || !result.Authorized || result.GetRole() != "admin" {
 					log.Errorf("Admin email authorization failed for %s", currentUser.Username)
 					logAdminSecurityEvent(currentUser.Id, "admin_email_auth_failed", currentUser.Username)
@@ -302,6 +339,12 @@ func cleanupExpiredSessions() {
 			delete(adminSessionManager.failedAttempts, email)
 		}
 	}
+	for ip, lockoutTime := range adminSessionManager.ipLockouts {
+		if now.After(lockoutTime) {
+			delete(adminSessionManager.ipLockouts, ip)
+			delete(adminSessionManager.ipFailedAttempts, ip)
+		}
+	}
 }
 
 // RecordFailedAdminAttempt records a failed admin authentication attempt
@@ -341,6 +384,107 @@ func ClearFailedAttempts(email string) {
 	delete(adminSessionManager.lockouts, email)
 }
 
+// SetChallengeValidator sets the hook used to validate the login challenge.
+// If no validator is set, IPs are never asked for a challenge and are only
+// blocked once they reach the block threshold.
+func (m *AdminSessionManager) SetChallengeValidator(v LoginChallengeValidator) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.challengeValidator = v
+}
+
+// escalationLocked returns the escalation level for the IP. The caller must
+// hold the lock.
+func (m *AdminSessionManager) escalationLocked(ip string) LoginEscalation {
+	if lockoutTime, exists := m.ipLockouts[ip]; exists {
+		if time.Now().Before(lockoutTime) {
+			return LoginBlocked
+		}
+		// Block expired, start counting from scratch
+		delete(m.ipLockouts, ip)
+		delete(m.ipFailedAttempts, ip)
+	}
+	if m.challengeValidator != nil && m.config.IPChallengeThreshold > 0 &&
+		m.ipFailedAttempts[ip] >= m.config.IPChallengeThreshold {
+		return LoginChallengeRequired
+	}
+	return LoginAllowed
+}
+
+// CheckLoginEscalation returns the escalation level currently applied to
+// login attempts from the given IP address.
+func (m *AdminSessionManager) CheckLoginEscalation(ip string) LoginEscalation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.escalationLocked(ip)
+}
+
+// ValidateLoginChallenge runs the configured challenge validator against the
+// request.
+func (m *AdminSessionManager) ValidateLoginChallenge(r *http.Request) bool {
+	m.mu.RLock()
+	v := m.challengeValidator
+	m.mu.RUnlock()
+	return v != nil && v(r)
+}
+
+// RecordFailedLoginFromIP records a failed login from the given IP address,
+// regardless of the account targeted, and returns the resulting escalation
+// level.
+func (m *AdminSessionManager) RecordFailedLoginFromIP(ip string) LoginEscalation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.escalationLocked(ip) == LoginBlocked {
+		return LoginBlocked
+	}
+	m.ipFailedAttempts[ip]++
+	if m.config.IPBlockThreshold > 0 && m.ipFailedAttempts[ip] >= m.config.IPBlockThreshold {
+		m.ipLockouts[ip] = time.Now().Add(m.config.IPBlockDuration)
+		log.Warnf("Login from IP %s blocked due to %d failed attempts", ip, m.ipFailedAttempts[ip])
+		return LoginBlocked
+	}
+	return m.escalationLocked(ip)
+}
+
+// ClearFailedLoginsFromIP resets the failed login counter for an IP address
+// after a successful login.
+func (m *AdminSessionManager) ClearFailedLoginsFromIP(ip string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.ipFailedAttempts, ip)
+	delete(m.ipLockouts, ip)
+}
+
+// SetLoginChallengeValidator sets the hook used to validate the login
+// challenge (e.g. a captcha token) for IPs with repeated failed logins.
+func SetLoginChallengeValidator(v LoginChallengeValidator) {
+	adminSessionManager.SetChallengeValidator(v)
+}
+
+// CheckLoginEscalation returns the escalation level for login attempts from
+// the given IP address.
+func CheckLoginEscalation(ip string) LoginEscalation {
+	return adminSessionManager.CheckLoginEscalation(ip)
+}
+
+// ValidateLoginChallenge validates the login challenge submitted with the
+// request.
+func ValidateLoginChallenge(r *http.Request) bool {
+	return adminSessionManager.ValidateLoginChallenge(r)
+}
+
+// RecordFailedLoginFromIP records a failed login from the given IP address.
+func RecordFailedLoginFromIP(ip string) LoginEscalation {
+	return adminSessionManager.RecordFailedLoginFromIP(ip)
+}
+
+// ClearFailedLoginsFromIP clears the failed logins recorded for an IP address.
+func ClearFailedLoginsFromIP(ip string) {
+	adminSessionManager.ClearFailedLoginsFromIP(ip)
+}
+
 // isIPWhitelisted checks if an IP is in the whitelist
 func isIPWhitelisted(ip string, whitelist []string) bool {
 	for _, allowedIP := range whitelist {
@@ -407,7 +551,7 @@ func EnforceAdminAPIKey(next http.Handler) http.Handler {
 
 		// Additional validation for admin API operations
 		service := models.NewEmailAuthorizationService()
-		result, err := service.CheckEmailAuthorization(currentUser.Username)
+		result, err := service.CheckEmailAuthorization(currentUser.AuthorizationEmail())
 		if err != nil || !result.Authorized || result.GetRole() != "admin" {
 			log.Errorf("Admin API email authorization failed for %s", currentUser.Username)
 			JSONError(w, http.StatusForbidden, "Admin email authorization required for API access")
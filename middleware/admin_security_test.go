@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newEscalationManager(validator LoginChallengeValidator) *AdminSessionManager {
+	config := DefaultAdminSecurityConfig()
+	config.IPChallengeThreshold = 2
+	config.IPBlockThreshold = 4
+	config.IPBlockDuration = time.Minute
+	m := NewAdminSessionManager(config)
+	m.SetChallengeValidator(validator)
+	return m
+}
+
+func TestLoginEscalationThresholds(t *testing.T) {
+	m := newEscalationManager(func(r *http.Request) bool {
+		return r.FormValue("captcha_token") == "valid"
+	})
+	ip := "203.0.113.10"
+
+	expected := []LoginEscalation{LoginAllowed, LoginChallengeRequired, LoginChallengeRequired, LoginBlocked}
+	for i, want := range expected {
+		got := m.RecordFailedLoginFromIP(ip)
+		if got != want {
+			t.Fatalf("attempt %d: expected escalation %d got %d", i+1, want, got)
+		}
+	}
+	if got := m.CheckLoginEscalation(ip); got != LoginBlocked {
+		t.Fatalf("expected IP to remain blocked, got %d", got)
+	}
+	// Other IPs are unaffected
+	if got := m.CheckLoginEscalation("203.0.113.11"); got != LoginAllowed {
+		t.Fatalf("expected other IP to be allowed, got %d", got)
+	}
+}
+
+func TestLoginEscalationReset(t *testing.T) {
+	m := newEscalationManager(func(r *http.Request) bool { return true })
+	ip := "203.0.113.20"
+
+	m.RecordFailedLoginFromIP(ip)
+	m.RecordFailedLoginFromIP(ip)
+	if got := m.CheckLoginEscalation(ip); got != LoginChallengeRequired {
+		t.Fatalf("expected challenge to be required, got %d", got)
+	}
+	m.ClearFailedLoginsFromIP(ip)
+	if got := m.CheckLoginEscalation(ip); got != LoginAllowed {
+		t.Fatalf("expected escalation to be reset, got %d", got)
+	}
+
+	// Blocks expire after the configured duration
+	for i := 0; i < 4; i++ {
+		m.RecordFailedLoginFromIP(ip)
+	}
+	m.ipLockouts[ip] = time.Now().Add(-time.Second)
+	if got := m.CheckLoginEscalation(ip); got != LoginAllowed {
+		t.Fatalf("expected expired block to be lifted, got %d", got)
+	}
+}
+
+func TestLoginEscalationWithoutValidator(t *testing.T) {
+	m := newEscalationManager(nil)
+	ip := "203.0.113.30"
+
+	for i := 0; i < 3; i++ {
+		if got := m.RecordFailedLoginFromIP(ip); got != LoginAllowed {
+			t.Fatalf("attempt %d: expected no challenge without a validator, got %d", i+1, got)
+		}
+	}
+	if got := m.RecordFailedLoginFromIP(ip); got != LoginBlocked {
+		t.Fatalf("expected IP to be blocked, got %d", got)
+	}
+}
+
+func TestValidateLoginChallenge(t *testing.T) {
+	m := newEscalationManager(func(r *http.Request) bool {
+		return r.FormValue("captcha_token") == "valid"
+	})
+	r := httptest.NewRequest(http.MethodPost, "/login?captcha_token=valid", nil)
+	if !m.ValidateLoginChallenge(r) {
+		t.Fatalf("expected valid challenge to pass")
+	}
+	r = httptest.NewRequest(http.MethodPost, "/login?captcha_token=nope", nil)
+	if m.ValidateLoginChallenge(r) {
+		t.Fatalf("expected invalid challenge to fail")
+	}
+}
@@ -0,0 +1,263 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gophish/gophish/auth"
+	log "github.com/gophish/gophish/logger"
+	"github.com/gophish/gophish/models"
+	"github.com/gorilla/csrf"
+	"github.com/gorilla/securecookie"
+)
+
+// csrfCookieName is the name of the cookie gorilla/csrf stores the CSRF token
+// in.
+const csrfCookieName = "_gorilla_csrf"
+
+// csrfCookieMaxAge is the lifetime of the CSRF cookie in seconds, matching
+// the gorilla/csrf default.
+const csrfCookieMaxAge = 12 * 60 * 60
+
+// csrfMinKeyLength is the shortest CSRF key accepted when rotating the key.
+const csrfMinKeyLength = 32
+
+// DefaultCSRFKeyGracePeriod is how long the previous CSRF key is still
+// accepted after the key is rotated. It matches the lifetime of the CSRF
+// cookie, so that every form served before the rotation can be submitted.
+const DefaultCSRFKeyGracePeriod = csrfCookieMaxAge * time.Second
+
+// ErrCSRFKeyTooShort is thrown when rotating to a CSRF key which is too short
+var ErrCSRFKeyTooShort = errors.New("CSRF key must be at least 32 bytes")
+
+// ErrCSRFKeyUnchanged is thrown when rotating to the CSRF key already in use
+var ErrCSRFKeyUnchanged = errors.New("CSRF key is already in use")
+
+// CSRFOptions are the options used to protect the admin server against CSRF.
+// When Persist is set, rotated keys are stored in the database so that they
+// last across restarts.
+type CSRFOptions struct {
+	FieldName      string
+	Secure         bool
+	TrustedOrigins []string
+	Persist        bool
+}
+
+// CSRFKeyRotation describes the last rotation of the CSRF key, without
+// exposing the keys themselves.
+type CSRFKeyRotation struct {
+	RotatedDate         time.Time `json:"rotated_date,omitempty"`
+	PreviousKeyAccepted bool      `json:"previous_key_accepted"`
+	PreviousKeyExpiry   time.Time `json:"previous_key_expiry,omitempty"`
+}
+
+// CSRFKeyRing protects the handler it wraps against CSRF, and lets the key
+// used be rotated while the server is running. After a rotation the previous
+// key is still accepted for a grace period. CSRF cookies signed with the
+// previous key are re-signed with the new one when they're seen, so the CSRF
+// token doesn't change and forms served before the rotation keep working.
+type CSRFKeyRing struct {
+	mu             sync.RWMutex
+	next           http.Handler
+	opts           CSRFOptions
+	current        *securecookie.SecureCookie
+	previous       *securecookie.SecureCookie
+	previousExpiry time.Time
+	rotatedDate    time.Time
+	protected      http.Handler
+	key            []byte
+	// configuredKeyHash is the hash of the configured key, stored along
+	// with rotated keys so that a key configured since takes over.
+	configuredKeyHash string
+}
+
+// NewCSRFKeyRing returns a CSRFKeyRing protecting the handler with the key.
+// If a previous key is given, it's accepted for the grace period. A random
+// key is generated if none is given.
+//
+// If the keys are persisted and the key was rotated since the configured key
+// was set, the rotated key and the key it replaced are used instead.
+func NewCSRFKeyRing(next http.Handler, key, previousKey []byte, grace time.Duration, opts CSRFOptions) *CSRFKeyRing {
+	kr := &CSRFKeyRing{next: next, opts: opts, configuredKeyHash: csrfKeyHash(key)}
+	if opts.Persist && kr.loadStoredKeys(key) {
+		return kr
+	}
+	if len(key) == 0 {
+		key = []byte(auth.GenerateSecureKey(auth.APIKeyLength))
+	}
+	kr.setKey(key)
+	if len(previousKey) > 0 {
+		kr.previous = newCSRFCodec(previousKey)
+		kr.previousExpiry = time.Now().UTC().Add(grace)
+	}
+	return kr
+}
+
+// csrfKeyHash returns the hex encoded SHA-256 hash of the key.
+func csrfKeyHash(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadStoredKeys uses the stored keys if the key was rotated while the
+// configured key was in use, returning whether they were used.
+func (kr *CSRFKeyRing) loadStoredKeys(configuredKey []byte) bool {
+	stored, err := models.GetCSRFKey()
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	if stored.Key == "" {
+		return false
+	}
+	if stored.Key != string(configuredKey) && stored.ConfiguredKeyHash != kr.configuredKeyHash {
+		log.Info("Using the configured CSRF key, which was changed since the key was last rotated")
+		return false
+	}
+	kr.setKey([]byte(stored.Key))
+	kr.rotatedDate = stored.RotatedDate
+	if stored.PreviousKey != "" {
+		kr.previous = newCSRFCodec([]byte(stored.PreviousKey))
+		kr.previousExpiry = stored.PreviousKeyExpiry
+	}
+	return true
+}
+
+// newCSRFCodec returns the codec gorilla/csrf signs its cookie with when
+// using the key.
+func newCSRFCodec(key []byte) *securecookie.SecureCookie {
+	sc := securecookie.New(key, nil)
+	sc.SetSerializer(securecookie.JSONEncoder{})
+	sc.MaxAge(csrfCookieMaxAge)
+	return sc
+}
+
+// setKey makes the key the one used to sign and check CSRF tokens. The
+// caller must hold the lock.
+func (kr *CSRFKeyRing) setKey(key []byte) {
+	kr.key = key
+	kr.current = newCSRFCodec(key)
+	kr.protected = csrf.Protect(key,
+		csrf.FieldName(kr.opts.FieldName),
+		csrf.Secure(kr.opts.Secure),
+		csrf.TrustedOrigins(kr.opts.TrustedOrigins))(kr.next)
+}
+
+// Rotate makes the key the one used to sign and check CSRF tokens, accepting
+// the previous key for the grace period. A random key is generated if none
+// is given. If the keys are persisted, the rotation is stored before it takes
+// effect.
+func (kr *CSRFKeyRing) Rotate(key []byte, grace time.Duration) (CSRFKeyRotation, error) {
+	if len(key) == 0 {
+		key = []byte(auth.GenerateSecureKey(auth.APIKeyLength))
+	}
+	if len(key) < csrfMinKeyLength {
+		return CSRFKeyRotation{}, ErrCSRFKeyTooShort
+	}
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if string(key) == string(kr.key) {
+		return kr.rotation(), ErrCSRFKeyUnchanged
+	}
+	rotatedDate := time.Now().UTC()
+	if kr.opts.Persist {
+		err := models.PutCSRFKey(models.CSRFKey{
+			Key:               string(key),
+			PreviousKey:       string(kr.key),
+			PreviousKeyExpiry: rotatedDate.Add(grace),
+			ConfiguredKeyHash: kr.configuredKeyHash,
+			RotatedDate:       rotatedDate,
+		})
+		if err != nil {
+			return kr.rotation(), err
+		}
+	}
+	kr.previous = kr.current
+	kr.rotatedDate = rotatedDate
+	kr.previousExpiry = kr.rotatedDate.Add(grace)
+	kr.setKey(key)
+	return kr.rotation(), nil
+}
+
+// Rotation returns the details of the last rotation of the CSRF key.
+func (kr *CSRFKeyRing) Rotation() CSRFKeyRotation {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.rotation()
+}
+
+// rotation returns the details of the last rotation. The caller must hold
+// the lock.
+func (kr *CSRFKeyRing) rotation() CSRFKeyRotation {
+	r := CSRFKeyRotation{RotatedDate: kr.rotatedDate}
+	if kr.previous != nil && time.Now().Before(kr.previousExpiry) {
+		r.PreviousKeyAccepted = true
+		r.PreviousKeyExpiry = kr.previousExpiry
+	}
+	return r
+}
+
+// ServeHTTP checks the request's CSRF token with the current key, after
+// re-signing a cookie signed with the previous key during the grace period.
+func (kr *CSRFKeyRing) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	kr.mu.RLock()
+	protected := kr.protected
+	current := kr.current
+	previous := kr.previous
+	if previous != nil && !time.Now().Before(kr.previousExpiry) {
+		previous = nil
+	}
+	kr.mu.RUnlock()
+	if previous != nil {
+		r = kr.resignCookie(w, r, current, previous)
+	}
+	protected.ServeHTTP(w, r)
+}
+
+// resignCookie re-signs the request's CSRF cookie with the current key if
+// it was signed with the previous one. The re-signed cookie replaces the
+// original in the request, and is sent back so that the browser uses it from
+// then on.
+func (kr *CSRFKeyRing) resignCookie(w http.ResponseWriter, r *http.Request, current, previous *securecookie.SecureCookie) *http.Request {
+	cookie, err := r.Cookie(csrfCookieName)
+	if err != nil {
+		return r
+	}
+	token := []byte{}
+	if current.Decode(csrfCookieName, cookie.Value, &token) == nil {
+		return r
+	}
+	if previous.Decode(csrfCookieName, cookie.Value, &token) != nil {
+		return r
+	}
+	encoded, err := current.Encode(csrfCookieName, token)
+	if err != nil {
+		log.Error(err)
+		return r
+	}
+	cookies := r.Cookies()
+	r = r.Clone(r.Context())
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name == csrfCookieName {
+			c.Value = encoded
+		}
+		r.AddCookie(c)
+	}
+	// The cookie is sent with the same attributes gorilla/csrf uses, so that
+	// it replaces the original
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    encoded,
+		MaxAge:   csrfCookieMaxAge,
+		Expires:  time.Now().Add(csrfCookieMaxAge * time.Second),
+		HttpOnly: true,
+		Secure:   kr.opts.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return r
+}
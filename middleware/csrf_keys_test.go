@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/csrf"
+)
+
+var (
+	oldCSRFKey = []byte(strings.Repeat("o", 32))
+	newCSRFKey = []byte(strings.Repeat("n", 32))
+)
+
+// newTestCSRFKeyRing returns a key ring protecting a handler which responds
+// with the request's CSRF token.
+func newTestCSRFKeyRing(key, previousKey []byte, grace time.Duration) *CSRFKeyRing {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, csrf.Token(r))
+	})
+	return NewCSRFKeyRing(next, key, previousKey, grace, CSRFOptions{FieldName: "csrf_token"})
+}
+
+// getCSRFToken loads a form, returning its CSRF token and cookie.
+func getCSRFToken(t *testing.T, kr *CSRFKeyRing) (string, *http.Cookie) {
+	w := httptest.NewRecorder()
+	kr.ServeHTTP(w, httptest.NewRequest("GET", "/settings", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != csrfCookieName {
+		t.Fatalf("expected the CSRF cookie to be set, got %v", cookies)
+	}
+	return w.Body.String(), cookies[0]
+}
+
+// postCSRFForm submits a form with the CSRF token and cookie.
+func postCSRFForm(kr *CSRFKeyRing, token string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/settings", nil)
+	r.Header.Set("X-CSRF-Token", token)
+	r.Header.Set("Referer", "https://example.com/settings")
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	kr.ServeHTTP(w, r)
+	return w
+}
+
+func TestCSRFKeyRotationGracePeriod(t *testing.T) {
+	kr := newTestCSRFKeyRing(oldCSRFKey, nil, 0)
+	token, cookie := getCSRFToken(t, kr)
+
+	_, err := kr.Rotate(newCSRFKey, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error rotating CSRF key: %v", err)
+	}
+
+	// Forms served before the rotation are accepted, and the cookie is
+	// re-signed with the new key
+	w := postCSRFForm(kr, token, cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected form served with the previous key to be accepted, got %d", w.Code)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != csrfCookieName || cookies[0].Value == cookie.Value {
+		t.Fatalf("expected the CSRF cookie to be re-signed, got %v", cookies)
+	}
+	resigned := cookies[0]
+
+	// The re-signed cookie works with the same token without the previous
+	// key
+	kr = newTestCSRFKeyRing(newCSRFKey, nil, 0)
+	w = postCSRFForm(kr, token, resigned)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected re-signed cookie to be accepted, got %d", w.Code)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Fatalf("expected cookie signed with the current key not to be re-signed")
+	}
+}
+
+func TestCSRFKeyRotationGracePeriodExpired(t *testing.T) {
+	kr := newTestCSRFKeyRing(oldCSRFKey, nil, 0)
+	token, cookie := getCSRFToken(t, kr)
+
+	rotation, err := kr.Rotate(newCSRFKey, 0)
+	if err != nil {
+		t.Fatalf("unexpected error rotating CSRF key: %v", err)
+	}
+	if rotation.PreviousKeyAccepted {
+		t.Fatalf("expected previous key not to be accepted without a grace period")
+	}
+	w := postCSRFForm(kr, token, cookie)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected form served with the expired key to be rejected, got %d", w.Code)
+	}
+}
+
+func TestCSRFPreviousKeyConfigured(t *testing.T) {
+	token, cookie := getCSRFToken(t, newTestCSRFKeyRing(oldCSRFKey, nil, 0))
+
+	// The key was changed across a restart
+	kr := newTestCSRFKeyRing(newCSRFKey, oldCSRFKey, time.Hour)
+	if !kr.Rotation().PreviousKeyAccepted {
+		t.Fatalf("expected the configured previous key to be accepted")
+	}
+	w := postCSRFForm(kr, token, cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected form served with the previous key to be accepted, got %d", w.Code)
+	}
+
+	// Tokens which don't match the cookie are still rejected
+	w = postCSRFForm(kr, "invalid-token", cookie)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected invalid token to be rejected, got %d", w.Code)
+	}
+}
+
+func TestCSRFKeyRotationInvalid(t *testing.T) {
+	kr := newTestCSRFKeyRing(oldCSRFKey, nil, 0)
+	if _, err := kr.Rotate([]byte("short"), time.Hour); err != ErrCSRFKeyTooShort {
+		t.Fatalf("expected ErrCSRFKeyTooShort, got %v", err)
+	}
+	if _, err := kr.Rotate(oldCSRFKey, time.Hour); err != ErrCSRFKeyUnchanged {
+		t.Fatalf("expected ErrCSRFKeyUnchanged, got %v", err)
+	}
+	// A key is generated if none is given
+	rotation, err := kr.Rotate(nil, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error rotating to a generated key: %v", err)
+	}
+	if !rotation.PreviousKeyAccepted || rotation.RotatedDate.IsZero() {
+		t.Fatalf("unexpected rotation %+v", rotation)
+	}
+}
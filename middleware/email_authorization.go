@@ -118,7 +118,7 @@ func checkEmailAuthorization(r *http.Request, user *models.User) error {
 	service := models.NewEmailAuthorizationService()
 
 	// Check email authorization
-	result, err := service.CheckEmailAuthorization(user.Username)
+	result, err := service.CheckEmailAuthorization(user.AuthorizationEmail())
 	if err != nil {
 		return fmt.Errorf("authorization check failed: %w", err)
 	}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophish/gophish/config"
+	log "github.com/gophish/gophish/logger"
+	"github.com/gophish/gophish/models"
+)
+
+// captchaProvider describes how a captcha provider's widget is rendered on
+// the login page and how the token it submits is verified.
+type captchaProvider struct {
+	scriptURL   string
+	widgetClass string
+	tokenField  string
+	verifyURL   string
+}
+
+// captchaProviders are the captcha providers which can be used for the login
+// challenge. They share the same siteverify API.
+var captchaProviders = map[string]captchaProvider{
+	"recaptcha": {
+		scriptURL:   "https://www.google.com/recaptcha/api.js",
+		widgetClass: "g-recaptcha",
+		tokenField:  "g-recaptcha-response",
+		verifyURL:   "https://www.google.com/recaptcha/api/siteverify",
+	},
+	"hcaptcha": {
+		scriptURL:   "https://js.hcaptcha.com/1/api.js",
+		widgetClass: "h-captcha",
+		tokenField:  "h-captcha-response",
+		verifyURL:   "https://api.hcaptcha.com/siteverify",
+	},
+	"turnstile": {
+		scriptURL:   "https://challenges.cloudflare.com/turnstile/v0/api.js",
+		widgetClass: "cf-turnstile",
+		tokenField:  "cf-turnstile-response",
+		verifyURL:   "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+	},
+}
+
+// LoginChallengeWidget is the captcha rendered on the login page for IP
+// addresses which must pass the login challenge.
+type LoginChallengeWidget struct {
+	ScriptURL   string
+	WidgetClass string
+	SiteKey     string
+}
+
+var (
+	loginChallengeMu     sync.RWMutex
+	loginChallengeWidget *LoginChallengeWidget
+)
+
+// ConfigureLoginChallenge sets up the captcha required of IP addresses with
+// repeated failed logins. Without a configured provider, no challenge is
+// required and those IP addresses are only blocked once they reach the block
+// threshold.
+func ConfigureLoginChallenge(c config.LoginChallenge) error {
+	if c.Provider == "" {
+		return nil
+	}
+	p, ok := captchaProviders[strings.ToLower(c.Provider)]
+	if !ok {
+		return fmt.Errorf("unknown login challenge provider %q", c.Provider)
+	}
+	if c.SiteKey == "" || c.Secret == "" {
+		return fmt.Errorf("the %s login challenge requires a site key and secret", c.Provider)
+	}
+	loginChallengeMu.Lock()
+	loginChallengeWidget = &LoginChallengeWidget{
+		ScriptURL:   p.scriptURL,
+		WidgetClass: p.widgetClass,
+		SiteKey:     c.SiteKey,
+	}
+	loginChallengeMu.Unlock()
+	client := &http.Client{Timeout: 5 * time.Second}
+	SetLoginChallengeValidator(newCaptchaValidator(p.verifyURL, p.tokenField, c.Secret, client))
+	log.Infof("Requiring a %s login challenge after repeated failed logins", c.Provider)
+	return nil
+}
+
+// GetLoginChallengeWidget returns the captcha to render on the login page if
+// the client must pass the login challenge, or nil otherwise.
+func GetLoginChallengeWidget(r *http.Request) *LoginChallengeWidget {
+	loginChallengeMu.RLock()
+	w := loginChallengeWidget
+	loginChallengeMu.RUnlock()
+	if w == nil || CheckLoginEscalation(models.ExtractIPFromRequest(r)) != LoginChallengeRequired {
+		return nil
+	}
+	return w
+}
+
+// newCaptchaValidator returns a LoginChallengeValidator which verifies the
+// captcha token submitted in the given form field with the provider's
+// siteverify endpoint.
+func newCaptchaValidator(verifyURL, tokenField, secret string, client *http.Client) LoginChallengeValidator {
+	return func(r *http.Request) bool {
+		token := r.FormValue(tokenField)
+		if token == "" {
+			return false
+		}
+		resp, err := client.PostForm(verifyURL, url.Values{
+			"secret":   {secret},
+			"response": {token},
+			"remoteip": {models.ExtractIPFromRequest(r)},
+		})
+		if err != nil {
+			log.Errorf("error verifying login challenge: %v", err)
+			return false
+		}
+		defer resp.Body.Close()
+		result := struct {
+			Success bool `json:"success"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			log.Errorf("error decoding login challenge verification: %v", err)
+			return false
+		}
+		return result.Success
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gophish/gophish/config"
+)
+
+func TestCaptchaValidator(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("secret") != "secret" {
+			t.Fatalf("expected the secret to be sent, got %q", r.PostForm.Get("secret"))
+		}
+		if r.PostForm.Get("response") == "valid" {
+			w.Write([]byte(`{"success": true}`))
+			return
+		}
+		w.Write([]byte(`{"success": false}`))
+	}))
+	defer ts.Close()
+
+	validate := newCaptchaValidator(ts.URL, "cf-turnstile-response", "secret", &http.Client{Timeout: time.Second})
+	for token, expected := range map[string]bool{"valid": true, "invalid": false, "": false} {
+		form := url.Values{"cf-turnstile-response": {token}}
+		r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if got := validate(r); got != expected {
+			t.Fatalf("expected token %q to validate as %v, got %v", token, expected, got)
+		}
+	}
+}
+
+func TestConfigureLoginChallenge(t *testing.T) {
+	if err := ConfigureLoginChallenge(config.LoginChallenge{}); err != nil {
+		t.Fatalf("expected no challenge to be configured, got %v", err)
+	}
+	if err := ConfigureLoginChallenge(config.LoginChallenge{Provider: "unknown", SiteKey: "key", Secret: "secret"}); err == nil {
+		t.Fatal("expected an unknown provider to be rejected")
+	}
+	if err := ConfigureLoginChallenge(config.LoginChallenge{Provider: "turnstile"}); err == nil {
+		t.Fatal("expected a challenge without a secret to be rejected")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/gophish/gophish/config"
 	ctx "github.com/gophish/gophish/context"
 	log "github.com/gophish/gophish/logger"
 	"github.com/gophish/gophish/models"
@@ -55,7 +56,7 @@ func GetContext(handler http.Handler) http.HandlerFunc {
 		// Set the session
 		session, err := Store.Get(r, "gophish")
 		log.Infof("GetContext: Session error: %v", err)
-		log.Infof("GetContext: Session values: %v", session.Values)
+		log.Infof("GetContext: Session values: %v", log.RedactSession(session.Values))
 
 		// Validate SSO session if present
 		if !ValidateSession(session) {
@@ -176,7 +177,7 @@ func EnforceViewOnly(next http.Handler) http.Handler {
 		// If the request is for any non-GET HTTP method, e.g. POST, PUT,
 		// or DELETE, we need to ensure the user has the appropriate
 		// permission.
-		if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+		if isWriteRequest(r) {
 			user := ctx.Get(r, "user").(models.User)
 			access, err := user.HasPermission(models.PermissionModifyObjects)
 			if err != nil {
@@ -213,14 +214,44 @@ func RequirePermission(perm string) func(http.Handler) http.HandlerFunc {
 	}
 }
 
+// RequireWritePermission checks to see if the user has the requested
+// permission before executing a handler which modifies objects. Read-only
+// requests are passed through unchecked.
+func RequireWritePermission(perm string) func(http.Handler) http.HandlerFunc {
+	return func(next http.Handler) http.HandlerFunc {
+		check := RequirePermission(perm)(next)
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !isWriteRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			check.ServeHTTP(w, r)
+		}
+	}
+}
+
+// isWriteRequest returns true if the request uses an HTTP method which may
+// modify objects, e.g. POST, PUT or DELETE.
+func isWriteRequest(r *http.Request) bool {
+	return r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions
+}
+
 // ApplySecurityHeaders applies various security headers according to best-
 // practices.
 func ApplySecurityHeaders(next http.Handler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		csp := "frame-ancestors 'none';"
-		w.Header().Set("Content-Security-Policy", csp)
-		w.Header().Set("X-Frame-Options", "DENY")
-		next.ServeHTTP(w, r)
+	return SecurityHeaders(config.DefaultAdminSecurityHeaders())(next)
+}
+
+// SecurityHeaders returns a middleware which sets the given headers on every
+// response, such as those configured for the admin or phishing server.
+func SecurityHeaders(headers map[string]string) func(http.Handler) http.HandlerFunc {
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range headers {
+				w.Header().Set(name, value)
+			}
+			next.ServeHTTP(w, r)
+		}
 	}
 }
 
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gophish/gophish/config"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	csp := "default-src 'self'; img-src https://assets.example.com"
+	conf := config.PhishServer{SecurityHeaders: map[string]string{
+		"Content-Security-Policy": csp,
+	}}
+	handler := Use(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}, SecurityHeaders(conf.GetSecurityHeaders()))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if got := w.Header().Get("Content-Security-Policy"); got != csp {
+		t.Fatalf("unexpected Content-Security-Policy. expected %q got %q", csp, got)
+	}
+	if got := w.Header().Get("Referrer-Policy"); got != "no-referrer" {
+		t.Fatalf("unexpected Referrer-Policy %q", got)
+	}
+
+	// The admin server keeps its strict defaults
+	w = httptest.NewRecorder()
+	ApplySecurityHeaders(http.NotFoundHandler()).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if got := w.Header().Get("Content-Security-Policy"); got != "frame-ancestors 'none';" {
+		t.Fatalf("unexpected admin Content-Security-Policy %q", got)
+	}
+	if got := w.Header().Get("X-Frame-Options"); got != "DENY" {
+		t.Fatalf("unexpected admin X-Frame-Options %q", got)
+	}
+}
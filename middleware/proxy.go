@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gophish/gophish/config"
+	ctx "github.com/gophish/gophish/context"
+	"github.com/gophish/gophish/models"
+)
+
+// RecordPeerAddr stores the connecting address of the request in the context
+// so that it can still be checked against the trusted proxies once
+// handlers.ProxyHeaders has rewritten RemoteAddr. It must wrap the proxy
+// header handler.
+func RecordPeerAddr(handler http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r = ctx.Set(r, models.PeerAddrKey, r.RemoteAddr)
+		handler.ServeHTTP(w, r)
+	}
+}
+
+// PeerAddr returns the address of the host which connected to us. If the
+// request wasn't passed through RecordPeerAddr, RemoteAddr is returned.
+func PeerAddr(r *http.Request) string {
+	return models.PeerAddr(r)
+}
+
+// forwardedScheme returns the scheme reported by a reverse proxy, if any.
+func forwardedScheme(r *http.Request) string {
+	for _, h := range []string{"X-Forwarded-Proto", "X-Forwarded-Scheme"} {
+		scheme := strings.ToLower(strings.TrimSpace(r.Header.Get(h)))
+		if scheme == "http" || scheme == "https" {
+			return scheme
+		}
+	}
+	return ""
+}
+
+// ResolveScheme returns the scheme ("http" or "https") that clients use to
+// reach the admin server.
+//
+// If an external scheme is configured, it is always used. If trusted proxies
+// are configured, the forwarded scheme headers are only honored when the
+// request came from one of them, otherwise the scheme is derived from use_tls.
+// Without either setting, the legacy heuristics are used.
+func ResolveScheme(conf config.AdminServer, r *http.Request) string {
+	switch scheme := strings.ToLower(strings.TrimSpace(conf.ExternalScheme)); scheme {
+	case "http", "https":
+		return scheme
+	}
+	tlsScheme := "http"
+	if conf.UseTLS {
+		tlsScheme = "https"
+	}
+	if len(conf.TrustedProxies) > 0 {
+		if conf.IsTrustedProxy(PeerAddr(r)) {
+			if scheme := forwardedScheme(r); scheme != "" {
+				return scheme
+			}
+		}
+		return tlsScheme
+	}
+	if forwardedScheme(r) == "https" {
+		return "https"
+	}
+	// Azure Container Apps always use HTTPS externally
+	if strings.Contains(r.Host, "azurecontainerapps.io") {
+		return "https"
+	}
+	// Behind a proxy, likely HTTPS in production
+	if r.Header.Get("X-Forwarded-For") != "" {
+		return "https"
+	}
+	return tlsScheme
+}
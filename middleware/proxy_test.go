@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gophish/gophish/config"
+	"github.com/gorilla/handlers"
+)
+
+func TestResolveScheme(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "192.0.2.1"}
+	testCases := []struct {
+		name     string
+		conf     config.AdminServer
+		peer     string
+		headers  map[string]string
+		expected string
+	}{
+		{
+			name:     "no proxy, no TLS",
+			peer:     "198.51.100.1:1234",
+			expected: "http",
+		},
+		{
+			name:     "no proxy, TLS",
+			conf:     config.AdminServer{UseTLS: true},
+			peer:     "198.51.100.1:1234",
+			expected: "https",
+		},
+		{
+			name:     "legacy forwarded proto",
+			peer:     "198.51.100.1:1234",
+			headers:  map[string]string{"X-Forwarded-Proto": "https"},
+			expected: "https",
+		},
+		{
+			name:     "legacy forwarded for guess",
+			peer:     "198.51.100.1:1234",
+			headers:  map[string]string{"X-Forwarded-For": "203.0.113.5"},
+			expected: "https",
+		},
+		{
+			name:     "trusted proxy forwarding http",
+			conf:     config.AdminServer{TrustedProxies: trusted},
+			peer:     "10.1.2.3:1234",
+			headers:  map[string]string{"X-Forwarded-For": "203.0.113.5", "X-Forwarded-Proto": "http"},
+			expected: "http",
+		},
+		{
+			name:     "trusted proxy forwarding https",
+			conf:     config.AdminServer{TrustedProxies: trusted},
+			peer:     "192.0.2.1:1234",
+			headers:  map[string]string{"X-Forwarded-Scheme": "https"},
+			expected: "https",
+		},
+		{
+			name:     "trusted proxy without forwarded scheme",
+			conf:     config.AdminServer{TrustedProxies: trusted},
+			peer:     "10.1.2.3:1234",
+			headers:  map[string]string{"X-Forwarded-For": "203.0.113.5"},
+			expected: "http",
+		},
+		{
+			name:     "untrusted peer forwarding https",
+			conf:     config.AdminServer{TrustedProxies: trusted},
+			peer:     "198.51.100.1:1234",
+			headers:  map[string]string{"X-Forwarded-Proto": "https"},
+			expected: "http",
+		},
+		{
+			name:     "untrusted peer with TLS",
+			conf:     config.AdminServer{TrustedProxies: trusted, UseTLS: true},
+			peer:     "198.51.100.1:1234",
+			headers:  map[string]string{"X-Forwarded-Proto": "http"},
+			expected: "https",
+		},
+		{
+			name:     "external scheme overrides headers",
+			conf:     config.AdminServer{TrustedProxies: trusted, ExternalScheme: "https"},
+			peer:     "10.1.2.3:1234",
+			headers:  map[string]string{"X-Forwarded-Proto": "http"},
+			expected: "https",
+		},
+		{
+			name:     "external scheme overrides legacy guess",
+			conf:     config.AdminServer{ExternalScheme: "HTTP"},
+			peer:     "198.51.100.1:1234",
+			headers:  map[string]string{"X-Forwarded-For": "203.0.113.5"},
+			expected: "http",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			handler := handlers.ProxyHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ResolveScheme(tc.conf, r)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/auth/microsoft", nil)
+			r.RemoteAddr = tc.peer
+			for k, v := range tc.headers {
+				r.Header.Set(k, v)
+			}
+			RecordPeerAddr(handler).ServeHTTP(httptest.NewRecorder(), r)
+			if got != tc.expected {
+				t.Fatalf("expected scheme %s got %s", tc.expected, got)
+			}
+		})
+	}
+}
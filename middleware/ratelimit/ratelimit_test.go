@@ -4,6 +4,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	ctx "github.com/gophish/gophish/context"
 )
 
 var successHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -57,3 +60,58 @@ func TestRateLimitCleanup(t *testing.T) {
 	}
 	reachLimit(t, handler, expectedLimit)
 }
+
+func userRequest(uid int64) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/campaigns/ai-workflow/1", nil)
+	return ctx.Set(r, "user_id", uid)
+}
+
+func TestUserRateLimit(t *testing.T) {
+	expectedLimit := 3
+	limiter := NewUserLimiter(expectedLimit)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+	handler := limiter.Limit(successHandler)
+
+	for i := 0; i < expectedLimit; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, userRequest(1))
+		if w.Code != http.StatusOK {
+			t.Fatalf("no 200 on req %d got %d", i, w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, userRequest(1))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("no 429 got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "20" {
+		t.Fatalf("unexpected Retry-After %q", w.Header().Get("Retry-After"))
+	}
+
+	// Other users have their own limit
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, userRequest(2))
+	if w.Code != http.StatusOK {
+		t.Fatalf("no 200 for another user got %d", w.Code)
+	}
+
+	// The limit resets once the user has waited
+	now = now.Add(20 * time.Second)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, userRequest(1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("no 200 after waiting got %d", w.Code)
+	}
+}
+
+func TestUserRateLimitDisabled(t *testing.T) {
+	handler := NewUserLimiter(0).Limit(successHandler)
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, userRequest(1))
+		if w.Code != http.StatusOK {
+			t.Fatalf("no 200 on req %d got %d", i, w.Code)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	ctx "github.com/gophish/gophish/context"
+	log "github.com/gophish/gophish/logger"
+	"golang.org/x/time/rate"
+)
+
+// UserLimiter is a rate limiting middleware which allows each authenticated
+// user n requests per minute, regardless of the request method or the
+// address they're made from.
+type UserLimiter struct {
+	users        map[int64]*bucket
+	requestLimit int
+	expiry       time.Duration
+	// now returns the current time, and is replaced in tests
+	now func() time.Time
+	sync.Mutex
+}
+
+// NewUserLimiter returns a new instance of a UserLimiter which allows each
+// user requestLimit requests per minute. A limit of zero or less disables
+// rate limiting.
+func NewUserLimiter(requestLimit int) *UserLimiter {
+	limiter := &UserLimiter{
+		users:        make(map[int64]*bucket),
+		requestLimit: requestLimit,
+		expiry:       DefaultExpiry,
+		now:          time.Now,
+	}
+	if requestLimit > 0 {
+		go limiter.pollCleanup()
+	}
+	return limiter
+}
+
+func (limiter *UserLimiter) pollCleanup() {
+	ticker := time.NewTicker(DefaultCleanupInterval)
+	for range ticker.C {
+		limiter.Cleanup()
+	}
+}
+
+// Cleanup removes any buckets that were last seen past the configured expiry.
+func (limiter *UserLimiter) Cleanup() {
+	limiter.Lock()
+	defer limiter.Unlock()
+	now := limiter.now()
+	for uid, bucket := range limiter.users {
+		if now.Sub(bucket.lastSeen) >= limiter.expiry {
+			delete(limiter.users, uid)
+		}
+	}
+}
+
+// allow reports whether the user may make another request. If not, it also
+// returns how long the user has to wait before they may.
+func (limiter *UserLimiter) allow(uid int64) (bool, time.Duration) {
+	limiter.Lock()
+	defer limiter.Unlock()
+	now := limiter.now()
+	b, exists := limiter.users[uid]
+	if !exists {
+		b = &bucket{
+			limiter: rate.NewLimiter(rate.Every(time.Minute/time.Duration(limiter.requestLimit)), limiter.requestLimit),
+		}
+		limiter.users[uid] = b
+	}
+	b.lastSeen = now
+	reservation := b.limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay == 0 {
+		return true, 0
+	}
+	reservation.CancelAt(now)
+	return false, delay
+}
+
+// Limit enforces the configured rate limit for the user making the request.
+// Requests over the limit get a 429 response with a Retry-After header.
+func (limiter *UserLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uid, ok := ctx.Get(r, "user_id").(int64)
+		if limiter.requestLimit <= 0 || !ok {
+			next(w, r)
+			return
+		}
+		allowed, delay := limiter.allow(uid)
+		if !allowed {
+			retryAfter := int(math.Ceil(delay.Seconds()))
+			log.Warnf("rate limit exceeded for user %d on %s", uid, r.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("Too many requests, try again in %d seconds", retryAfter),
+			})
+			return
+		}
+		next(w, r)
+	}
+}
@@ -187,3 +187,24 @@ func GetAuthMethod(session *sessions.Session) string {
 	}
 	return "local"
 }
+
+// oauthSessionKeys are the session values which carry an OAuth sign-in in
+// progress across the redirect to and from the provider.
+var oauthSessionKeys = []string{"oauth_state", "oauth_code_verifier", "oauth_provider", "oauth_timestamp", "oauth_nonce", "oauth_next"}
+
+// PopFlashes returns the flash messages queued in the session and removes
+// them, keeping any OAuth sign-in in progress. The session must be saved
+// afterwards for the flashes to be cleared.
+func PopFlashes(session *sessions.Session) []interface{} {
+	oauthData := make(map[string]interface{})
+	for _, key := range oauthSessionKeys {
+		if value, exists := session.Values[key]; exists {
+			oauthData[key] = value
+		}
+	}
+	flashes := session.Flashes()
+	for key, value := range oauthData {
+		session.Values[key] = value
+	}
+	return flashes
+}
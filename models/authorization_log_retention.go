@@ -0,0 +1,105 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// authorizationLogPruneBatchSize is the number of authorization logs deleted
+// at a time, so that pruning a large backlog doesn't hold long locks.
+const authorizationLogPruneBatchSize = 1000
+
+// GetAuthorizationLogRetention returns how long authorization logs are kept
+// before they're pruned, configured in days by
+// AUTHORIZATION_LOG_RETENTION_DAYS. Logs are kept forever unless a positive
+// number of days is set.
+func GetAuthorizationLogRetention() time.Duration {
+	s := os.Getenv("AUTHORIZATION_LOG_RETENTION_DAYS")
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		log.Warnf("Invalid AUTHORIZATION_LOG_RETENTION_DAYS value '%s', authorization logs won't be pruned", s)
+		return 0
+	}
+	return time.Duration(v) * 24 * time.Hour
+}
+
+// GetAuthorizationLogArchiveDir returns the directory pruned authorization
+// logs are archived to as CSV, configured by AUTHORIZATION_LOG_ARCHIVE_DIR.
+// Pruned logs aren't archived unless it's set.
+func GetAuthorizationLogArchiveDir() string {
+	return os.Getenv("AUTHORIZATION_LOG_ARCHIVE_DIR")
+}
+
+// PruneAuthorizationLogs deletes the authorization logs created up to the
+// cutoff, returning the number deleted. If an archive directory is given,
+// the logs are first exported to a CSV file in it, and nothing is deleted if
+// the export fails. Logs are deleted in batches.
+func PruneAuthorizationLogs(cutoff time.Time, archiveDir string) (int, error) {
+	var count int
+	err := db.Model(&EmailAuthorizationLog{}).Where("created_at <= ?", cutoff).Count(&count).Error
+	if err != nil || count == 0 {
+		return 0, err
+	}
+	if archiveDir != "" {
+		err = archiveAuthorizationLogs(cutoff, archiveDir)
+		if err != nil {
+			return 0, err
+		}
+	}
+	pruned := 0
+	for {
+		ids := []int64{}
+		err = db.Model(&EmailAuthorizationLog{}).
+			Where("created_at <= ?", cutoff).
+			Order("id asc").Limit(authorizationLogPruneBatchSize).Pluck("id", &ids).Error
+		if err != nil {
+			return pruned, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		err = db.Where("id IN (?)", ids).Delete(&EmailAuthorizationLog{}).Error
+		if err != nil {
+			return pruned, err
+		}
+		pruned += len(ids)
+		if len(ids) < authorizationLogPruneBatchSize {
+			break
+		}
+	}
+	log.WithFields(logrus.Fields{
+		"pruned": pruned,
+		"cutoff": cutoff,
+	}).Info("Pruned old authorization logs")
+	return pruned, nil
+}
+
+// archiveAuthorizationLogs exports the authorization logs created up to the
+// cutoff to a CSV file in the directory.
+func archiveAuthorizationLogs(cutoff time.Time, dir string) error {
+	name := fmt.Sprintf("authorization-logs-%s.csv", cutoff.UTC().Format("20060102T150405Z"))
+	path := filepath.Join(dir, name)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	err = WriteAuthorizationLogsCSV(f, AuthorizationLogFilter{End: cutoff})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	log.Infof("Archived authorization logs to %s", path)
+	return nil
+}
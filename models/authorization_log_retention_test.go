@@ -0,0 +1,86 @@
+package models
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+// createAgedAuthorizationLogs creates an authorization log for each email,
+// logged the given number of days ago.
+func createAgedAuthorizationLogs(c *check.C, now time.Time, ages map[string]int) {
+	for email, days := range ages {
+		l := EmailAuthorizationLog{
+			Email:           email,
+			NormalizedEmail: email,
+			Action:          "login",
+			Result:          "success",
+			CreatedAt:       now.Add(-time.Duration(days) * 24 * time.Hour),
+		}
+		c.Assert(db.Create(&l).Error, check.IsNil)
+	}
+}
+
+func (s *EmailAuthorizationSuite) TestPruneAuthorizationLogs(c *check.C) {
+	now := time.Now().UTC()
+	createAgedAuthorizationLogs(c, now, map[string]int{
+		"old1@example.com":   120,
+		"old2@example.com":   91,
+		"recent@example.com": 10,
+		"today@example.com":  0,
+	})
+
+	pruned, err := PruneAuthorizationLogs(now.Add(-90*24*time.Hour), "")
+	c.Assert(err, check.IsNil)
+	c.Assert(pruned, check.Equals, 2)
+
+	logs, err := GetAuthorizationLogs("", "", "", 0, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(len(logs), check.Equals, 2)
+	for _, l := range logs {
+		c.Assert(l.CreatedAt.After(now.Add(-90*24*time.Hour)), check.Equals, true)
+	}
+
+	// Nothing is left to prune
+	pruned, err = PruneAuthorizationLogs(now.Add(-90*24*time.Hour), "")
+	c.Assert(err, check.IsNil)
+	c.Assert(pruned, check.Equals, 0)
+}
+
+func (s *EmailAuthorizationSuite) TestPruneAuthorizationLogsArchive(c *check.C) {
+	dir, err := ioutil.TempDir("", "gophish-authorization-logs")
+	c.Assert(err, check.IsNil)
+	defer os.RemoveAll(dir)
+
+	now := time.Now().UTC()
+	createAgedAuthorizationLogs(c, now, map[string]int{
+		"old@example.com":    120,
+		"recent@example.com": 10,
+	})
+
+	cutoff := now.Add(-90 * 24 * time.Hour)
+	pruned, err := PruneAuthorizationLogs(cutoff, dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(pruned, check.Equals, 1)
+
+	f, err := os.Open(filepath.Join(dir, "authorization-logs-"+cutoff.Format("20060102T150405Z")+".csv"))
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	c.Assert(err, check.IsNil)
+	c.Assert(len(records), check.Equals, 2)
+	c.Assert(records[0], check.DeepEquals, AuthorizationLogCSVHeader)
+	c.Assert(records[1][2], check.Equals, "old@example.com")
+
+	// Nothing is deleted if the archive can't be written
+	pruned, err = PruneAuthorizationLogs(now, filepath.Join(dir, "missing"))
+	c.Assert(err, check.NotNil)
+	c.Assert(pruned, check.Equals, 0)
+	logs, err := GetAuthorizationLogs("", "", "", 0, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(len(logs), check.Equals, 1)
+}
@@ -7,6 +7,12 @@ import (
 	"net"
 	"net/http"
 	"context"
+	"regexp"
+	"errors"
+
+	"github.com/gophish/gophish/config"
+	ctx "github.com/gophish/gophish/context"
+	"github.com/jinzhu/gorm"
 )
 
 // AuthorizedEmail represents an email authorized to access the system
@@ -162,6 +168,144 @@ func (s *EmailAuthorizationService) CheckEmailAuthorization(email string) (*Emai
 	}, nil
 }
 
+// MaxBulkAuthorizationChecks is the largest number of emails which can be
+// checked in a single bulk authorization check.
+const MaxBulkAuthorizationChecks = 100
+
+// ErrTooManyAuthorizationChecks is returned when a bulk authorization check
+// contains more than MaxBulkAuthorizationChecks emails.
+var ErrTooManyAuthorizationChecks = fmt.Errorf("Maximum %d emails allowed per request", MaxBulkAuthorizationChecks)
+
+// BulkAuthorizationCheck is the result of checking a single email as part of
+// a bulk authorization check.
+type BulkAuthorizationCheck struct {
+	Email      string `json:"email"`
+	Authorized bool   `json:"authorized"`
+	Reason     string `json:"reason,omitempty"`
+	AuthMethod string `json:"auth_method,omitempty"`
+	Role       string `json:"role,omitempty"`
+}
+
+// CheckEmailAuthorizationBulk runs CheckEmailAuthorization against each of the
+// given emails without modifying the list of authorized emails.
+func (s *EmailAuthorizationService) CheckEmailAuthorizationBulk(emails []string) ([]BulkAuthorizationCheck, error) {
+	if len(emails) > MaxBulkAuthorizationChecks {
+		return nil, ErrTooManyAuthorizationChecks
+	}
+	checks := make([]BulkAuthorizationCheck, 0, len(emails))
+	for _, email := range emails {
+		result, err := s.CheckEmailAuthorization(email)
+		if err != nil {
+			return nil, err
+		}
+		check := BulkAuthorizationCheck{
+			Email:      email,
+			Authorized: result.Authorized,
+			Reason:     result.Reason,
+		}
+		if result.Authorized {
+			check.AuthMethod = result.AuthMethod
+			check.Role = result.GetRole()
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// AuthorizationRule describes an email or domain rule which applies to an
+// email, and whether it decided the outcome. Rules which exist but didn't
+// decide the outcome, such as a revoked email rule or a domain rule shadowed
+// by an email rule, are reported with a note explaining why.
+type AuthorizationRule struct {
+	Type    string `json:"type"` // "email" or "domain"
+	Id      int64  `json:"id"`
+	Value   string `json:"value"`
+	Status  string `json:"status"`
+	Role    string `json:"role"`
+	Matched bool   `json:"matched"`
+	Note    string `json:"note,omitempty"`
+}
+
+// AuthorizationSimulation is the decision CheckEmailAuthorization makes for
+// an email, along with the rules which were considered in reaching it.
+type AuthorizationSimulation struct {
+	Email      string              `json:"email"`
+	Authorized bool                `json:"authorized"`
+	Reason     string              `json:"reason,omitempty"`
+	AuthMethod string              `json:"auth_method,omitempty"`
+	Role       string              `json:"role,omitempty"`
+	Rules      []AuthorizationRule `json:"rules"`
+}
+
+// SimulateEmailAuthorization reports whether the email would be authorized
+// and with what role, and which rules were considered, without logging the
+// check or updating when the email was last used. Email rules take
+// precedence over domain rules.
+func (s *EmailAuthorizationService) SimulateEmailAuthorization(email string) (*AuthorizationSimulation, error) {
+	result, err := s.CheckEmailAuthorization(email)
+	if err != nil {
+		return nil, err
+	}
+	sim := &AuthorizationSimulation{
+		Email:      email,
+		Authorized: result.Authorized,
+		Reason:     result.Reason,
+		Rules:      []AuthorizationRule{},
+	}
+	if result.Reason == "invalid_format" {
+		return sim, nil
+	}
+	if result.Authorized {
+		sim.AuthMethod = result.AuthMethod
+		sim.Role = result.GetRole()
+	}
+	normalized := s.NormalizeEmail(email)
+	ae := AuthorizedEmail{}
+	err = db.Preload("Role").Where("normalized_email = ?", normalized).First(&ae).Error
+	if err == nil {
+		rule := AuthorizationRule{
+			Type:    "email",
+			Id:      ae.Id,
+			Value:   ae.Email,
+			Status:  ae.Status,
+			Role:    (&EmailAuthorizationResult{AuthorizedEmail: &ae}).GetRole(),
+			Matched: result.AuthMethod == "email",
+		}
+		switch {
+		case ae.Status != "active":
+			rule.Note = "email rule is " + ae.Status
+		case ae.ExpiresAt != nil && !ae.ExpiresAt.After(time.Now()):
+			rule.Note = "email rule expired"
+		}
+		sim.Rules = append(sim.Rules, rule)
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	domain := normalized[strings.LastIndex(normalized, "@")+1:]
+	ad := AuthorizedDomain{}
+	err = db.Where("domain = ?", domain).First(&ad).Error
+	if err == nil {
+		rule := AuthorizationRule{
+			Type:    "domain",
+			Id:      ad.Id,
+			Value:   ad.Domain,
+			Status:  ad.Status,
+			Role:    ad.DefaultRole,
+			Matched: result.AuthMethod == "domain",
+		}
+		switch {
+		case ad.Status != "active":
+			rule.Note = "domain rule is " + ad.Status
+		case result.AuthMethod == "email":
+			rule.Note = "shadowed by email rule"
+		}
+		sim.Rules = append(sim.Rules, rule)
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return sim, nil
+}
+
 // LogAuthorizationAttempt logs an email authorization attempt
 func (s *EmailAuthorizationService) LogAuthorizationAttempt(ctx context.Context, email, action, result string, userID *int64, details string) error {
 	// Extract IP and User-Agent from context if available
@@ -245,6 +389,14 @@ func GetAuthorizedEmails(status string, limit, offset int) ([]AuthorizedEmail, e
 	return emails, err
 }
 
+// GetAuthorizedEmail returns the authorized email with the given ID, along
+// with its role and the user who created it
+func GetAuthorizedEmail(id int64) (AuthorizedEmail, error) {
+	email := AuthorizedEmail{}
+	err := db.Preload("Role").Preload("CreatedByUser").Where("id = ?", id).First(&email).Error
+	return email, err
+}
+
 // AddAuthorizedEmail adds a new authorized email
 func AddAuthorizedEmail(email string, roleID *int64, defaultRole string, createdBy *int64, expiresAt *time.Time, notes string) (*AuthorizedEmail, error) {
 	service := NewEmailAuthorizationService()
@@ -289,60 +441,294 @@ func DeleteAuthorizedEmail(id int64) error {
 	return db.Delete(&AuthorizedEmail{}, id).Error
 }
 
-// GetAuthorizationLogs returns authorization logs with optional filtering
-func GetAuthorizationLogs(email, action, result string, limit, offset int) ([]EmailAuthorizationLog, error) {
-	var logs []EmailAuthorizationLog
-	query := db.Preload("User")
+// domainRegex matches a fully qualified domain name such as "example.com"
+var domainRegex = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
 
-	if email != "" {
-		service := NewEmailAuthorizationService()
-		normalizedEmail := service.NormalizeEmail(email)
-		query = query.Where("normalized_email = ?", normalizedEmail)
+// validAuthorizationStatuses are the statuses an authorized email or domain
+// may have
+var validAuthorizationStatuses = map[string]bool{
+	"active":    true,
+	"suspended": true,
+	"revoked":   true,
+}
+
+// ErrInvalidAuthorizationStatus indicates that a status other than active,
+// suspended or revoked was given
+var ErrInvalidAuthorizationStatus = errors.New("invalid status")
+
+// ErrInvalidDefaultRole indicates that a default role doesn't match the slug
+// of an existing role
+var ErrInvalidDefaultRole = errors.New("invalid default role")
+
+// validateDefaultRole checks that the given role slug names an existing role,
+// so that users signing in from a domain aren't created with a role that
+// doesn't exist.
+func validateDefaultRole(slug string) error {
+	_, err := GetRoleBySlug(slug)
+	if err == gorm.ErrRecordNotFound {
+		return ErrInvalidDefaultRole
 	}
+	return err
+}
+
+// NormalizeDomain normalizes a domain for consistent storage and lookup
+func (s *EmailAuthorizationService) NormalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimPrefix(domain, "@")
+	return strings.TrimSuffix(domain, ".")
+}
 
-	if action != "" {
-		query = query.Where("action = ?", action)
+// ValidateDomainFormat checks that a domain is a valid fully qualified
+// domain name
+func (s *EmailAuthorizationService) ValidateDomainFormat(domain string) error {
+	domain = s.NormalizeDomain(domain)
+	if domain == "" {
+		return fmt.Errorf("domain cannot be empty")
 	}
+	if len(domain) > 253 {
+		return fmt.Errorf("domain too long")
+	}
+	if !domainRegex.MatchString(domain) {
+		return fmt.Errorf("invalid domain format")
+	}
+	return nil
+}
 
-	if result != "" {
-		query = query.Where("result = ?", result)
+// GetAuthorizedDomains returns all authorized domains with optional filtering
+func GetAuthorizedDomains(status string, limit, offset int) ([]AuthorizedDomain, error) {
+	var domains []AuthorizedDomain
+	query := db.Preload("CreatedByUser")
+
+	if status != "" {
+		query = query.Where("status = ?", status)
 	}
 
 	if limit > 0 {
 		query = query.Limit(limit).Offset(offset)
 	}
 
+	err := query.Order("domain asc").Find(&domains).Error
+	return domains, err
+}
+
+// GetAuthorizedDomain returns the authorized domain with the given id
+func GetAuthorizedDomain(id int64) (*AuthorizedDomain, error) {
+	var domain AuthorizedDomain
+	err := db.Where("id = ?", id).First(&domain).Error
+	if err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
+
+// AddAuthorizedDomain adds a new authorized domain
+func AddAuthorizedDomain(domain string, defaultRole string, createdBy *int64, notes string) (*AuthorizedDomain, error) {
+	service := NewEmailAuthorizationService()
+
+	if err := service.ValidateDomainFormat(domain); err != nil {
+		return nil, err
+	}
+	if defaultRole == "" {
+		defaultRole = RoleUser
+	}
+	if err := validateDefaultRole(defaultRole); err != nil {
+		return nil, err
+	}
+
+	authorizedDomain := AuthorizedDomain{
+		Domain:      service.NormalizeDomain(domain),
+		Status:      "active",
+		DefaultRole: defaultRole,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now(),
+		Notes:       notes,
+	}
+
+	err := db.Create(&authorizedDomain).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorizedDomain, nil
+}
+
+// UpdateAuthorizedDomain updates the status, default role and notes of an
+// authorized domain. Empty status and default role values are left unchanged.
+func UpdateAuthorizedDomain(id int64, status, defaultRole, notes string) error {
+	updates := map[string]interface{}{
+		"notes": notes,
+	}
+	if status != "" {
+		if !validAuthorizationStatuses[status] {
+			return ErrInvalidAuthorizationStatus
+		}
+		updates["status"] = status
+	}
+	if defaultRole != "" {
+		if err := validateDefaultRole(defaultRole); err != nil {
+			return err
+		}
+		updates["default_role"] = defaultRole
+	}
+
+	return db.Model(&AuthorizedDomain{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// DeleteAuthorizedDomain removes an authorized domain
+func DeleteAuthorizedDomain(id int64) error {
+	return db.Delete(&AuthorizedDomain{}, id).Error
+}
+
+// AuthorizationLogFilter restricts the authorization logs returned to those
+// matching every field which is set. IPAddress matches exactly, or as a
+// prefix if it ends with "*". UserAgent matches any part of the user agent,
+// ignoring case. Start and End bound when the entries were logged.
+type AuthorizationLogFilter struct {
+	Email     string
+	Action    string
+	Result    string
+	IPAddress string
+	UserAgent string
+	Start     time.Time
+	End       time.Time
+	Limit     int
+	Offset    int
+}
+
+// Validate checks that the filter's range is in order.
+func (f *AuthorizationLogFilter) Validate() error {
+	if !f.Start.IsZero() && !f.End.IsZero() && f.End.Before(f.Start) {
+		return ErrInvalidActivityRange
+	}
+	return nil
+}
+
+// likeEscaper escapes the characters which are special in LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// GetAuthorizationLogs returns authorization logs with optional filtering
+func GetAuthorizationLogs(email, action, result string, limit, offset int) ([]EmailAuthorizationLog, error) {
+	return GetFilteredAuthorizationLogs(AuthorizationLogFilter{
+		Email:  email,
+		Action: action,
+		Result: result,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// GetFilteredAuthorizationLogs returns the authorization logs matching the
+// filter, most recent first.
+func GetFilteredAuthorizationLogs(f AuthorizationLogFilter) ([]EmailAuthorizationLog, error) {
+	var logs []EmailAuthorizationLog
+	if err := f.Validate(); err != nil {
+		return logs, err
+	}
+	query := f.query(db.Preload("User"))
+
+	if f.Limit > 0 {
+		query = query.Limit(f.Limit).Offset(f.Offset)
+	}
+
 	query = query.Order("created_at DESC")
 	err := query.Find(&logs).Error
 	return logs, err
 }
 
-// ExtractIPFromRequest safely extracts IP address from request
-func ExtractIPFromRequest(r *http.Request) string {
-	// Check X-Forwarded-For header (proxy/load balancer)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP in the chain
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			ip := strings.TrimSpace(ips[0])
-			if net.ParseIP(ip) != nil {
-				return ip
-			}
-		}
+// query restricts the query to the authorization logs matching the filter.
+func (f *AuthorizationLogFilter) query(query *gorm.DB) *gorm.DB {
+	if f.Email != "" {
+		service := NewEmailAuthorizationService()
+		normalizedEmail := service.NormalizeEmail(f.Email)
+		query = query.Where("normalized_email = ?", normalizedEmail)
+	}
+
+	if f.Action != "" {
+		query = query.Where("action = ?", f.Action)
 	}
 
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		if net.ParseIP(xri) != nil {
-			return xri
+	if f.Result != "" {
+		query = query.Where("result = ?", f.Result)
+	}
+
+	if f.IPAddress != "" {
+		if prefix := strings.TrimSuffix(f.IPAddress, "*"); prefix != f.IPAddress {
+			query = query.Where(`ip_address LIKE ? ESCAPE '\'`, likeEscaper.Replace(prefix)+"%")
+		} else {
+			query = query.Where("ip_address = ?", f.IPAddress)
 		}
 	}
 
-	// Fall back to RemoteAddr
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr // Return as-is if parsing fails
+	if f.UserAgent != "" {
+		query = query.Where(`LOWER(user_agent) LIKE ? ESCAPE '\'`,
+			"%"+likeEscaper.Replace(strings.ToLower(f.UserAgent))+"%")
+	}
+
+	if !f.Start.IsZero() {
+		query = query.Where("created_at >= ?", f.Start)
+	}
+
+	if !f.End.IsZero() {
+		query = query.Where("created_at <= ?", f.End)
+	}
+	return query
+}
+
+// PeerAddrKey is the request context key holding the address of the host
+// which actually connected to the admin server, before any proxy headers
+// were applied to the request.
+const PeerAddrKey = "peer_addr"
+
+// PeerAddr returns the address of the host which connected to the admin
+// server. If it wasn't recorded, RemoteAddr is returned.
+func PeerAddr(r *http.Request) string {
+	if addr, ok := ctx.Get(r, PeerAddrKey).(string); ok {
+		return addr
 	}
+	return r.RemoteAddr
+}
 
+// ExtractIPFromRequest returns the IP address of the client which made the
+// request. The X-Forwarded-For and X-Real-IP headers can be set by anyone,
+// so they're only honoured when the request came from one of the admin
+// server's trusted proxies.
+func ExtractIPFromRequest(r *http.Request) string {
+	var proxies config.AdminServer
+	if conf != nil {
+		proxies = conf.AdminConf
+	}
+	return extractClientIP(r, proxies)
+}
+
+// extractClientIP returns the IP address of the client which made the
+// request, trusting the forwarded headers only from the given proxies. Since
+// each proxy appends to X-Forwarded-For, the client is the last address in
+// the chain which isn't itself a trusted proxy.
+func extractClientIP(r *http.Request, proxies config.AdminServer) string {
+	peer := PeerAddr(r)
+	ip, _, err := net.SplitHostPort(peer)
+	if err != nil {
+		ip = peer // Use as-is if parsing fails
+	}
+	if !proxies.IsTrustedProxy(ip) {
+		return ip
+	}
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			ip = hop
+			if !proxies.IsTrustedProxy(hop) {
+				break
+			}
+		}
+		return ip
+	}
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(xri) != nil {
+		return xri
+	}
 	return ip
-}
\ No newline at end of file
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	mysql "github.com/go-sql-driver/mysql"
+	log "github.com/gophish/gophish/logger"
+	"github.com/lib/pq"
+)
+
+// DefaultBulkAddRetries is the default number of times adding an email in a
+// bulk add is retried after a transient failure.
+const DefaultBulkAddRetries = 2
+
+// bulkAddRetryDelay is the delay before the first retry of a transient
+// failure. The delay grows with each retry.
+var bulkAddRetryDelay = 100 * time.Millisecond
+
+// ErrAuthorizedEmailExists is thrown when adding an email which is already
+// authorized
+var ErrAuthorizedEmailExists = errors.New("Email already authorized")
+
+// addAuthorizedEmail adds each email in a bulk add. It is a variable so that
+// tests can simulate failures.
+var addAuthorizedEmail = AddAuthorizedEmail
+
+// BulkAddResult is the outcome of adding a single email in a bulk add.
+type BulkAddResult struct {
+	Email    string `json:"email"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+	Attempts int    `json:"attempts"`
+}
+
+// GetBulkAddRetries returns the number of times a transient failure is
+// retried in a bulk add, configured by AUTHORIZED_EMAIL_BULK_RETRIES.
+func GetBulkAddRetries() int {
+	s := os.Getenv("AUTHORIZED_EMAIL_BULK_RETRIES")
+	if s == "" {
+		return DefaultBulkAddRetries
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		log.Warnf("Invalid AUTHORIZED_EMAIL_BULK_RETRIES value '%s', using default %d", s, DefaultBulkAddRetries)
+		return DefaultBulkAddRetries
+	}
+	return v
+}
+
+// isDuplicateAuthorizedEmail returns true if the error is from adding an
+// email which is already authorized.
+func isDuplicateAuthorizedEmail(err error) bool {
+	msg := err.Error()
+	return errors.Is(err, ErrAuthorizedEmailExists) ||
+		strings.Contains(msg, "UNIQUE constraint") ||
+		strings.Contains(msg, "duplicate")
+}
+
+// transientPostgresErrors are the PostgreSQL error codes of failures which
+// may succeed if retried, such as serialization failures and deadlocks.
+var transientPostgresErrors = map[pq.ErrorCode]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"55P03": true, // lock_not_available
+	"57P01": true, // admin_shutdown
+	"08000": true, // connection_exception
+	"08003": true, // connection_does_not_exist
+	"08006": true, // connection_failure
+}
+
+// transientMySQLErrors are the MySQL error numbers of failures which may
+// succeed if retried.
+var transientMySQLErrors = map[uint16]bool{
+	1205: true, // ER_LOCK_WAIT_TIMEOUT
+	1213: true, // ER_LOCK_DEADLOCK
+}
+
+// transientDBMessages are the messages of failures which may succeed if
+// retried, for drivers which don't return typed errors, such as SQLite.
+var transientDBMessages = []string{
+	"database is locked",
+	"database table is locked",
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"bad connection",
+	"i/o timeout",
+}
+
+// isTransientDBError returns true if the error is from a lost connection, a
+// timeout or a conflict with another transaction, rather than from the email
+// itself, so that adding it again may succeed.
+func isTransientDBError(err error) bool {
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return transientPostgresErrors[pqErr.Code]
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return transientMySQLErrors[mysqlErr.Number]
+	}
+	msg := strings.ToLower(err.Error())
+	for _, m := range transientDBMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// BulkAddAuthorizedEmails authorizes each of the emails, returning the
+// outcome for each. Transient failures, such as a locked database or a lost
+// connection, are retried before the email is reported as failed, while
+// any other failure is reported immediately.
+func BulkAddAuthorizedEmails(emails []string, roleID *int64, defaultRole string, createdBy *int64, expiresAt *time.Time, notes string) []BulkAddResult {
+	service := NewEmailAuthorizationService()
+	retries := GetBulkAddRetries()
+	results := make([]BulkAddResult, 0, len(emails))
+	for _, email := range emails {
+		result := BulkAddResult{Email: email}
+		if err := service.ValidateEmailFormat(email); err != nil {
+			result.Error = "Invalid email format: " + err.Error()
+			results = append(results, result)
+			continue
+		}
+		for {
+			result.Attempts++
+			_, err := addAuthorizedEmail(email, roleID, defaultRole, createdBy, expiresAt, notes)
+			if err == nil {
+				result.Success = true
+				break
+			}
+			if isDuplicateAuthorizedEmail(err) {
+				result.Error = ErrAuthorizedEmailExists.Error()
+				break
+			}
+			if !isTransientDBError(err) {
+				log.Errorf("Failed to add authorized email %s: %v", email, err)
+				result.Error = "Failed to add email"
+				break
+			}
+			if result.Attempts > retries {
+				log.Errorf("Failed to add authorized email %s after %d attempts: %v", email, result.Attempts, err)
+				result.Error = "Failed to add email"
+				break
+			}
+			log.Warnf("Failed to add authorized email %s, retrying: %v", email, err)
+			time.Sleep(bulkAddRetryDelay * time.Duration(result.Attempts))
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net"
+	"os"
+	"time"
+
+	mysql "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	check "gopkg.in/check.v1"
+)
+
+// stubAddAuthorizedEmail replaces adding authorized emails with add, which
+// is given the number of the attempt for each email, returning a function
+// which restores the original behaviour.
+func stubAddAuthorizedEmail(add func(email string, attempt int) error) func() {
+	original, delay := addAuthorizedEmail, bulkAddRetryDelay
+	attempts := map[string]int{}
+	addAuthorizedEmail = func(email string, roleID *int64, defaultRole string, createdBy *int64, expiresAt *time.Time, notes string) (*AuthorizedEmail, error) {
+		attempts[email]++
+		if err := add(email, attempts[email]); err != nil {
+			return nil, err
+		}
+		return &AuthorizedEmail{Email: email}, nil
+	}
+	bulkAddRetryDelay = 0
+	return func() { addAuthorizedEmail, bulkAddRetryDelay = original, delay }
+}
+
+func (s *ModelsSuite) TestBulkAddAuthorizedEmailsRetriesTransientFailures(ch *check.C) {
+	defer stubAddAuthorizedEmail(func(email string, attempt int) error {
+		switch {
+		case email == "flaky@example.com" && attempt < 3:
+			return errors.New("database is locked")
+		case email == "exists@example.com":
+			return errors.New("pq: duplicate key value violates unique constraint")
+		case email == "down@example.com":
+			return errors.New("database is locked")
+		case email == "invalid@example.com":
+			return errors.New("pq: null value in column \"email\" violates not-null constraint")
+		}
+		return nil
+	})()
+
+	results := BulkAddAuthorizedEmails([]string{
+		"ok@example.com",
+		"flaky@example.com",
+		"exists@example.com",
+		"not-an-email",
+		"down@example.com",
+		"invalid@example.com",
+	}, nil, RoleUser, nil, nil, "")
+	ch.Assert(results, check.DeepEquals, []BulkAddResult{
+		{Email: "ok@example.com", Success: true, Attempts: 1},
+		{Email: "flaky@example.com", Success: true, Attempts: 3},
+		{Email: "exists@example.com", Error: ErrAuthorizedEmailExists.Error(), Attempts: 1},
+		{Email: "not-an-email", Error: results[3].Error},
+		{Email: "down@example.com", Error: "Failed to add email", Attempts: DefaultBulkAddRetries + 1},
+		{Email: "invalid@example.com", Error: "Failed to add email", Attempts: 1},
+	})
+	ch.Assert(results[3].Error, check.Matches, "Invalid email format: .*")
+}
+
+func (s *ModelsSuite) TestBulkAddAuthorizedEmailsRetriesDisabled(ch *check.C) {
+	os.Setenv("AUTHORIZED_EMAIL_BULK_RETRIES", "0")
+	defer os.Unsetenv("AUTHORIZED_EMAIL_BULK_RETRIES")
+	defer stubAddAuthorizedEmail(func(email string, attempt int) error {
+		if attempt < 2 {
+			return errors.New("database is locked")
+		}
+		return nil
+	})()
+
+	results := BulkAddAuthorizedEmails([]string{"flaky@example.com"}, nil, RoleUser, nil, nil, "")
+	ch.Assert(results[0].Success, check.Equals, false)
+	ch.Assert(results[0].Attempts, check.Equals, 1)
+}
+
+func (s *ModelsSuite) TestIsTransientDBError(ch *check.C) {
+	for _, err := range []error{
+		errors.New("database is locked"),
+		driver.ErrBadConn,
+		&pq.Error{Code: "40001"},
+		&pq.Error{Code: "40P01"},
+		&mysql.MySQLError{Number: 1213},
+		&net.OpError{Op: "dial", Err: errors.New("connection refused")},
+	} {
+		ch.Assert(isTransientDBError(err), check.Equals, true, check.Commentf("%v", err))
+	}
+	for _, err := range []error{
+		errors.New("no such table: authorized_emails"),
+		&pq.Error{Code: "23502"},
+		&mysql.MySQLError{Number: 1406},
+	} {
+		ch.Assert(isTransientDBError(err), check.Equals, false, check.Commentf("%v", err))
+	}
+}
+
+func (s *ModelsSuite) TestGetBulkAddRetries(ch *check.C) {
+	defer os.Unsetenv("AUTHORIZED_EMAIL_BULK_RETRIES")
+	os.Unsetenv("AUTHORIZED_EMAIL_BULK_RETRIES")
+	ch.Assert(GetBulkAddRetries(), check.Equals, DefaultBulkAddRetries)
+	os.Setenv("AUTHORIZED_EMAIL_BULK_RETRIES", "5")
+	ch.Assert(GetBulkAddRetries(), check.Equals, 5)
+	os.Setenv("AUTHORIZED_EMAIL_BULK_RETRIES", "-1")
+	ch.Assert(GetBulkAddRetries(), check.Equals, DefaultBulkAddRetries)
+}
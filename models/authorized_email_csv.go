@@ -0,0 +1,275 @@
+package models
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+)
+
+// AuthorizedEmailCSVHeader is the header row of an authorized email CSV
+// export. Imports accept the same columns in any order, and only the email
+// column is required.
+var AuthorizedEmailCSVHeader = []string{
+	"email", "status", "role", "default_role", "expires_at", "notes",
+	"created_by", "created_at", "last_used_at",
+}
+
+// ErrCSVEmailColumnMissing indicates that an authorized email CSV import has
+// no email column
+var ErrCSVEmailColumnMissing = errors.New("CSV must have an email column")
+
+// AuthorizedEmailImportError describes a row of an authorized email CSV
+// import which couldn't be imported.
+type AuthorizedEmailImportError struct {
+	Line   int    `json:"line"`
+	Email  string `json:"email"`
+	Reason string `json:"reason"`
+}
+
+// AuthorizedEmailImportReport summarizes an authorized email CSV import.
+type AuthorizedEmailImportReport struct {
+	Total    int                          `json:"total"`
+	Imported int                          `json:"imported"`
+	Skipped  int                          `json:"skipped"`
+	Errors   []AuthorizedEmailImportError `json:"errors"`
+}
+
+// formatCSVTime formats an optional time for a CSV export, leaving unset
+// times empty.
+func formatCSVTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// authorizedEmailCSVRow is an authorized email along with the slug of its role
+// and the username of the user who created it, as joined for a CSV export.
+type authorizedEmailCSVRow struct {
+	AuthorizedEmail
+	RoleSlug          string
+	CreatedByUsername string
+}
+
+// WriteAuthorizedEmailsCSV writes every authorized email, optionally filtered
+// by status, to w as CSV. Rows are streamed from the database so that large
+// allow-lists aren't held in memory.
+func WriteAuthorizedEmailsCSV(w io.Writer, status string) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(AuthorizedEmailCSVHeader)
+	if err != nil {
+		return err
+	}
+	// Roles and users are joined rather than looked up per row, since the
+	// database can't be queried again while the rows are open
+	query := db.Table("authorized_emails").
+		Select("authorized_emails.*, COALESCE(roles.slug, '') AS role_slug, COALESCE(users.username, '') AS created_by_username").
+		Joins("LEFT JOIN roles ON roles.id = authorized_emails.role_id").
+		Joins("LEFT JOIN users ON users.id = authorized_emails.created_by").
+		Order("authorized_emails.email asc")
+	if status != "" {
+		query = query.Where("authorized_emails.status = ?", status)
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		ae := authorizedEmailCSVRow{}
+		err = db.ScanRows(rows, &ae)
+		if err != nil {
+			return err
+		}
+		err = cw.Write([]string{
+			ae.Email,
+			ae.Status,
+			ae.RoleSlug,
+			ae.DefaultRole,
+			formatCSVTime(ae.ExpiresAt),
+			ae.Notes,
+			ae.CreatedByUsername,
+			formatCSVTime(&ae.CreatedAt),
+			formatCSVTime(ae.LastUsedAt),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportAuthorizedEmailsCSV adds the authorized emails in a CSV such as one
+// written by WriteAuthorizedEmailsCSV. Emails which are already authorized
+// are skipped rather than overwritten, and rows which can't be imported are
+// reported without stopping the import.
+func ImportAuthorizedEmailsCSV(r io.Reader, createdBy *int64) (AuthorizedEmailImportReport, error) {
+	report := AuthorizedEmailImportReport{Errors: []AuthorizedEmailImportError{}}
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return report, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return report, ErrCSVEmailColumnMissing
+	}
+	service := NewEmailAuthorizationService()
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		email := field("email")
+		report.Total++
+		fail := func(reason string) {
+			report.Errors = append(report.Errors, AuthorizedEmailImportError{
+				Line:   line,
+				Email:  email,
+				Reason: reason,
+			})
+		}
+		if err := service.ValidateEmailFormat(email); err != nil {
+			fail(err.Error())
+			continue
+		}
+		status := field("status")
+		if status == "" {
+			status = "active"
+		}
+		if !validAuthorizationStatuses[status] {
+			fail(ErrInvalidAuthorizationStatus.Error())
+			continue
+		}
+		var roleID *int64
+		if slug := field("role"); slug != "" {
+			role, err := GetRoleBySlug(slug)
+			if err != nil {
+				fail("unknown role " + strconv.Quote(slug))
+				continue
+			}
+			roleID = &role.ID
+		}
+		defaultRole := field("default_role")
+		if defaultRole == "" {
+			defaultRole = "user"
+		}
+		var expiresAt *time.Time
+		if s := field("expires_at"); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				fail("expires_at must be an RFC 3339 timestamp")
+				continue
+			}
+			expiresAt = &t
+		}
+		if _, err := getAuthorizedEmailByNormalized(service.NormalizeEmail(email)); err == nil {
+			report.Skipped++
+			continue
+		}
+		ae, err := AddAuthorizedEmail(email, roleID, defaultRole, createdBy, expiresAt, field("notes"))
+		if err != nil {
+			log.Errorf("Failed to import authorized email %s: %v", email, err)
+			fail("failed to add email")
+			continue
+		}
+		if status != ae.Status {
+			err = UpdateAuthorizedEmailStatus(ae.Id, status, createdBy)
+			if err != nil {
+				log.Errorf("Failed to set status of imported email %s: %v", email, err)
+				fail("failed to set status")
+				continue
+			}
+		}
+		report.Imported++
+	}
+	return report, nil
+}
+
+// getAuthorizedEmailByNormalized returns the authorized email with the given
+// normalized address, regardless of its status.
+func getAuthorizedEmailByNormalized(normalized string) (AuthorizedEmail, error) {
+	ae := AuthorizedEmail{}
+	err := db.Where("normalized_email = ?", normalized).First(&ae).Error
+	return ae, err
+}
+
+// AuthorizationLogCSVHeader is the header row of an authorization log CSV
+// export.
+var AuthorizationLogCSVHeader = []string{
+	"id", "created_at", "email", "action", "result", "ip_address",
+	"user_agent", "user_id", "details",
+}
+
+// WriteAuthorizationLogsCSV writes the authorization logs matching the
+// filter, oldest first, to w as CSV. Rows are streamed from the database so
+// that large logs aren't held in memory. The filter's limit and offset are
+// ignored.
+func WriteAuthorizationLogsCSV(w io.Writer, f AuthorizationLogFilter) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	err := cw.Write(AuthorizationLogCSVHeader)
+	if err != nil {
+		return err
+	}
+	rows, err := f.query(db.Model(&EmailAuthorizationLog{})).Order("id asc").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		l := EmailAuthorizationLog{}
+		err = db.ScanRows(rows, &l)
+		if err != nil {
+			return err
+		}
+		userID := ""
+		if l.UserID != nil {
+			userID = strconv.FormatInt(*l.UserID, 10)
+		}
+		err = cw.Write([]string{
+			strconv.FormatInt(l.Id, 10),
+			formatCSVTime(&l.CreatedAt),
+			l.Email,
+			l.Action,
+			l.Result,
+			l.IPAddress,
+			l.UserAgent,
+			userID,
+			l.Details,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
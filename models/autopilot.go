@@ -0,0 +1,78 @@
+package models
+
+import (
+	"os"
+	"strconv"
+
+	log "github.com/gophish/gophish/logger"
+)
+
+// DefaultAutopilotMinConfidence is the lowest confidence, out of 100, at
+// which an autopilot result is applied without asking, unless
+// AUTOPILOT_MIN_CONFIDENCE is set.
+const DefaultAutopilotMinConfidence = 70
+
+// DefaultAutopilotRateLimit is the number of autopilot requests each user may
+// make per minute, unless AUTOPILOT_RATE_LIMIT_PER_MINUTE is set.
+const DefaultAutopilotRateLimit = 20
+
+// Decisions made on whether to apply an autopilot result
+const (
+	AutopilotAutoApplied = "auto_applied"
+	AutopilotSuggestion  = "suggestion"
+)
+
+// AutopilotGate is the decision on whether an autopilot result is applied
+// automatically or offered as a suggestion which has to be confirmed.
+type AutopilotGate struct {
+	MinConfidence        int    `json:"min_confidence"`
+	RequiresConfirmation bool   `json:"requires_confirmation"`
+	GateDecision         string `json:"gate_decision"`
+}
+
+// GetAutopilotMinConfidence returns the lowest confidence at which autopilot
+// results are applied automatically, configured by AUTOPILOT_MIN_CONFIDENCE.
+// A value of 0 applies every result.
+func GetAutopilotMinConfidence() int {
+	s := os.Getenv("AUTOPILOT_MIN_CONFIDENCE")
+	if s == "" {
+		return DefaultAutopilotMinConfidence
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 || v > 100 {
+		log.Warnf("Invalid AUTOPILOT_MIN_CONFIDENCE value '%s', using default %d", s, DefaultAutopilotMinConfidence)
+		return DefaultAutopilotMinConfidence
+	}
+	return v
+}
+
+// GetAutopilotRateLimit returns the number of autopilot requests each user
+// may make per minute, configured by AUTOPILOT_RATE_LIMIT_PER_MINUTE. A value
+// of 0 disables the limit.
+func GetAutopilotRateLimit() int {
+	s := os.Getenv("AUTOPILOT_RATE_LIMIT_PER_MINUTE")
+	if s == "" {
+		return DefaultAutopilotRateLimit
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		log.Warnf("Invalid AUTOPILOT_RATE_LIMIT_PER_MINUTE value '%s', using default %d", s, DefaultAutopilotRateLimit)
+		return DefaultAutopilotRateLimit
+	}
+	return v
+}
+
+// GateAutopilotResult decides whether a result with the given confidence is
+// applied automatically. Results below the minimum confidence, including
+// those the workflow didn't score, have to be confirmed.
+func GateAutopilotResult(confidence int) AutopilotGate {
+	g := AutopilotGate{
+		MinConfidence: GetAutopilotMinConfidence(),
+		GateDecision:  AutopilotAutoApplied,
+	}
+	if confidence < g.MinConfidence {
+		g.RequiresConfirmation = true
+		g.GateDecision = AutopilotSuggestion
+	}
+	return g
+}
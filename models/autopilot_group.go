@@ -0,0 +1,169 @@
+package models
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultAutopilotGroupRetention is how long an autopilot group is kept once
+// no campaign needs it, unless AUTOPILOT_GROUP_RETENTION_HOURS is set.
+const DefaultAutopilotGroupRetention = 7 * 24 * time.Hour
+
+// CampaignGroup records a group which a campaign was created from.
+type CampaignGroup struct {
+	CampaignId int64 `json:"campaign_id"`
+	GroupId    int64 `json:"group_id"`
+}
+
+// campaignGroupRef is a campaign which was created from a group, used to
+// decide whether the group is still needed.
+type campaignGroupRef struct {
+	GroupId       int64
+	CampaignId    int64
+	Status        string
+	CompletedDate time.Time
+}
+
+// GetAutopilotGroupRetention returns the grace period an orphaned autopilot
+// group is kept for, configured in hours by AUTOPILOT_GROUP_RETENTION_HOURS.
+// A retention of 0 disables the automatic cleanup.
+func GetAutopilotGroupRetention() time.Duration {
+	s := os.Getenv("AUTOPILOT_GROUP_RETENTION_HOURS")
+	if s == "" {
+		return DefaultAutopilotGroupRetention
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		log.Warnf("Invalid AUTOPILOT_GROUP_RETENTION_HOURS value '%s', using default %s", s, DefaultAutopilotGroupRetention)
+		return DefaultAutopilotGroupRetention
+	}
+	return time.Duration(v) * time.Hour
+}
+
+// MarkAutopilotGroup tags the group as created by the autopilot workflow, so
+// that it's cleaned up once no campaign needs it.
+func MarkAutopilotGroup(id int64, uid int64) error {
+	return db.Model(&Group{}).Where("id = ? AND user_id = ?", id, uid).
+		UpdateColumn("autopilot", true).Error
+}
+
+// isOrphanedAutopilotGroup returns true if the autopilot group is no longer
+// needed by any campaign and has been idle since before the cutoff. Groups
+// used by a campaign which hasn't completed are always needed, and groups
+// used by completed campaigns are kept until the grace period has passed
+// since the last of them completed.
+func isOrphanedAutopilotGroup(g Group, refs []campaignGroupRef, cutoff time.Time) bool {
+	if !g.Autopilot {
+		return false
+	}
+	lastUsed := g.ModifiedDate
+	for _, ref := range refs {
+		if ref.Status != CampaignComplete {
+			return false
+		}
+		if ref.CompletedDate.After(lastUsed) {
+			lastUsed = ref.CompletedDate
+		}
+	}
+	return lastUsed.Before(cutoff)
+}
+
+// getCampaignGroupRefs returns the campaigns created from each of the given
+// groups.
+func getCampaignGroupRefs(gids []int64) (map[int64][]campaignGroupRef, error) {
+	refs := map[int64][]campaignGroupRef{}
+	if len(gids) == 0 {
+		return refs, nil
+	}
+	rows := []campaignGroupRef{}
+	err := db.Table("campaign_groups").
+		Select("campaign_groups.group_id, campaigns.id AS campaign_id, campaigns.status, campaigns.completed_date").
+		Joins("JOIN campaigns ON campaigns.id = campaign_groups.campaign_id").
+		Where("campaign_groups.group_id IN (?)", gids).
+		Scan(&rows).Error
+	if err != nil {
+		return refs, err
+	}
+	for _, r := range rows {
+		refs[r.GroupId] = append(refs[r.GroupId], r)
+	}
+	return refs, nil
+}
+
+// GetOrphanedAutopilotGroups returns the summaries of the autopilot groups
+// which no campaign needs and which have been idle since before the cutoff.
+// Groups owned by every user are returned if uid is 0.
+func GetOrphanedAutopilotGroups(uid int64, cutoff time.Time) ([]GroupSummary, error) {
+	orphans := []GroupSummary{}
+	query := db.Where("autopilot = ?", true)
+	if uid != 0 {
+		query = query.Where("user_id = ?", uid)
+	}
+	gs := []Group{}
+	err := query.Order("id asc").Find(&gs).Error
+	if err != nil {
+		return orphans, err
+	}
+	gids := make([]int64, len(gs))
+	for i, g := range gs {
+		gids[i] = g.Id
+	}
+	refs, err := getCampaignGroupRefs(gids)
+	if err != nil {
+		return orphans, err
+	}
+	for _, g := range gs {
+		if !isOrphanedAutopilotGroup(g, refs[g.Id], cutoff) {
+			continue
+		}
+		gsum := GroupSummary{Id: g.Id, Name: g.Name, ModifiedDate: g.ModifiedDate, Autopilot: true}
+		err = db.Table("group_targets").Where("group_id=?", g.Id).Count(&gsum.NumTargets).Error
+		if err != nil {
+			return orphans, err
+		}
+		orphans = append(orphans, gsum)
+	}
+	return orphans, nil
+}
+
+// PurgeOrphanedAutopilotGroups deletes the autopilot groups which no campaign
+// needs and which have been idle since before the cutoff, returning the
+// groups deleted. Groups owned by every user are purged if uid is 0. Each
+// group is checked again before it's deleted, in case a campaign was created
+// from it in the meantime.
+func PurgeOrphanedAutopilotGroups(uid int64, cutoff time.Time) ([]GroupSummary, error) {
+	purged := []GroupSummary{}
+	orphans, err := GetOrphanedAutopilotGroups(uid, cutoff)
+	if err != nil {
+		return purged, err
+	}
+	for _, o := range orphans {
+		g := Group{}
+		err = db.Where("id = ?", o.Id).First(&g).Error
+		if err != nil {
+			continue
+		}
+		refs, err := getCampaignGroupRefs([]int64{g.Id})
+		if err != nil {
+			return purged, err
+		}
+		if !isOrphanedAutopilotGroup(g, refs[g.Id], cutoff) {
+			continue
+		}
+		err = DeleteGroup(&g)
+		if err != nil {
+			return purged, err
+		}
+		log.WithFields(logrus.Fields{
+			"group_id": g.Id,
+			"user_id":  g.UserId,
+			"name":     g.Name,
+		}).Info("Deleted orphaned autopilot group")
+		purged = append(purged, o)
+	}
+	return purged, nil
+}
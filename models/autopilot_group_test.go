@@ -0,0 +1,85 @@
+package models
+
+import (
+	"os"
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestIsOrphanedAutopilotGroup(ch *check.C) {
+	now := time.Now().UTC()
+	cutoff := now.Add(-DefaultAutopilotGroupRetention)
+	old := now.Add(-2 * DefaultAutopilotGroupRetention)
+	g := Group{Autopilot: true, ModifiedDate: old}
+
+	// Groups not created by autopilot are never cleaned up
+	ch.Assert(isOrphanedAutopilotGroup(Group{ModifiedDate: old}, nil, cutoff), check.Equals, false)
+	// Unused groups are kept until they've been idle for the grace period
+	ch.Assert(isOrphanedAutopilotGroup(g, nil, cutoff), check.Equals, true)
+	ch.Assert(isOrphanedAutopilotGroup(Group{Autopilot: true, ModifiedDate: now}, nil, cutoff), check.Equals, false)
+
+	// Groups used by a campaign which hasn't completed are always kept
+	for _, status := range []string{CampaignQueued, CampaignInProgress, CampaignEmailsSent, CampaignCreated} {
+		refs := []campaignGroupRef{
+			{Status: CampaignComplete, CompletedDate: old},
+			{Status: status},
+		}
+		ch.Assert(isOrphanedAutopilotGroup(g, refs, cutoff), check.Equals, false, check.Commentf("status %s", status))
+	}
+
+	// Groups used by completed campaigns are kept until the grace period has
+	// passed since the last one completed
+	refs := []campaignGroupRef{{Status: CampaignComplete, CompletedDate: old}}
+	ch.Assert(isOrphanedAutopilotGroup(g, refs, cutoff), check.Equals, true)
+	refs = append(refs, campaignGroupRef{Status: CampaignComplete, CompletedDate: now.Add(-time.Hour)})
+	ch.Assert(isOrphanedAutopilotGroup(g, refs, cutoff), check.Equals, false)
+}
+
+func (s *ModelsSuite) TestGetAutopilotGroupRetention(ch *check.C) {
+	defer os.Unsetenv("AUTOPILOT_GROUP_RETENTION_HOURS")
+	os.Unsetenv("AUTOPILOT_GROUP_RETENTION_HOURS")
+	ch.Assert(GetAutopilotGroupRetention(), check.Equals, DefaultAutopilotGroupRetention)
+	os.Setenv("AUTOPILOT_GROUP_RETENTION_HOURS", "24")
+	ch.Assert(GetAutopilotGroupRetention(), check.Equals, 24*time.Hour)
+	os.Setenv("AUTOPILOT_GROUP_RETENTION_HOURS", "0")
+	ch.Assert(GetAutopilotGroupRetention(), check.Equals, time.Duration(0))
+	os.Setenv("AUTOPILOT_GROUP_RETENTION_HOURS", "-1")
+	ch.Assert(GetAutopilotGroupRetention(), check.Equals, DefaultAutopilotGroupRetention)
+}
+
+func (s *ModelsSuite) TestPurgeOrphanedAutopilotGroups(ch *check.C) {
+	campaign := s.createCampaignDependencies(ch)
+	used := campaign.Groups[0]
+	ch.Assert(MarkAutopilotGroup(used.Id, 1), check.Equals, nil)
+	ch.Assert(PostCampaign(&campaign, campaign.UserId), check.Equals, nil)
+
+	unused := Group{Name: "Autopilot Group", UserId: 1, Targets: []Target{
+		{BaseRecipient: BaseRecipient{Email: "autopilot@example.com"}},
+	}}
+	ch.Assert(PostGroup(&unused), check.Equals, nil)
+	ch.Assert(MarkAutopilotGroup(unused.Id, 1), check.Equals, nil)
+
+	// Only the unused group is orphaned, even once both are past the cutoff
+	cutoff := time.Now().UTC().Add(time.Hour)
+	orphans, err := GetOrphanedAutopilotGroups(1, cutoff)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(orphans), check.Equals, 1)
+	ch.Assert(orphans[0].Id, check.Equals, unused.Id)
+	ch.Assert(orphans[0].NumTargets, check.Equals, int64(1))
+
+	purged, err := PurgeOrphanedAutopilotGroups(0, cutoff)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(purged), check.Equals, 1)
+	_, err = GetGroup(unused.Id, 1)
+	ch.Assert(err, check.NotNil)
+	_, err = GetGroup(used.Id, 1)
+	ch.Assert(err, check.Equals, nil)
+
+	// Once the campaign is completed, the group can be cleaned up too
+	ch.Assert(CompleteCampaign(campaign.Id, campaign.UserId), check.Equals, nil)
+	purged, err = PurgeOrphanedAutopilotGroups(1, cutoff)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(purged), check.Equals, 1)
+	ch.Assert(purged[0].Id, check.Equals, used.Id)
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"os"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestGateAutopilotResult(ch *check.C) {
+	defer os.Unsetenv("AUTOPILOT_MIN_CONFIDENCE")
+	os.Unsetenv("AUTOPILOT_MIN_CONFIDENCE")
+
+	// Results at or above the threshold are applied
+	g := GateAutopilotResult(DefaultAutopilotMinConfidence)
+	ch.Assert(g, check.DeepEquals, AutopilotGate{
+		MinConfidence: DefaultAutopilotMinConfidence,
+		GateDecision:  AutopilotAutoApplied,
+	})
+	ch.Assert(GateAutopilotResult(95).RequiresConfirmation, check.Equals, false)
+
+	// Results below it, including unscored ones, are only suggested
+	g = GateAutopilotResult(DefaultAutopilotMinConfidence - 1)
+	ch.Assert(g, check.DeepEquals, AutopilotGate{
+		MinConfidence:        DefaultAutopilotMinConfidence,
+		RequiresConfirmation: true,
+		GateDecision:         AutopilotSuggestion,
+	})
+	ch.Assert(GateAutopilotResult(0).GateDecision, check.Equals, AutopilotSuggestion)
+
+	os.Setenv("AUTOPILOT_MIN_CONFIDENCE", "90")
+	ch.Assert(GateAutopilotResult(85).RequiresConfirmation, check.Equals, true)
+	ch.Assert(GateAutopilotResult(90).RequiresConfirmation, check.Equals, false)
+
+	// A threshold of 0 applies everything
+	os.Setenv("AUTOPILOT_MIN_CONFIDENCE", "0")
+	ch.Assert(GateAutopilotResult(0).GateDecision, check.Equals, AutopilotAutoApplied)
+
+	// Invalid thresholds fall back to the default
+	for _, v := range []string{"high", "-5", "101"} {
+		os.Setenv("AUTOPILOT_MIN_CONFIDENCE", v)
+		ch.Assert(GetAutopilotMinConfidence(), check.Equals, DefaultAutopilotMinConfidence)
+	}
+}
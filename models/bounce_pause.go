@@ -0,0 +1,229 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/gophish/gophish/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultBounceRateThreshold is the default percentage of a campaign's
+// delivery attempts which may bounce before the campaign is paused.
+const DefaultBounceRateThreshold = 10
+
+// DefaultBounceRateMinSample is the default number of delivery attempts a
+// campaign must have before its bounce rate is acted on, so that a bounce
+// among the first few sends doesn't pause it.
+const DefaultBounceRateMinSample = 20
+
+// CampaignPausedNotificationName is the event name of the webhook sent when a
+// campaign is paused because too many of its emails bounced.
+const CampaignPausedNotificationName = "campaign_paused"
+
+// ErrCampaignPaused is returned when generating an email for a campaign which
+// is paused
+var ErrCampaignPaused = errors.New("Campaign is paused")
+
+// ErrCampaignNotPaused is thrown when resuming a campaign which isn't paused
+var ErrCampaignNotPaused = errors.New("Campaign isn't paused")
+
+// ErrResumeNotConfirmed is thrown when resuming a campaign without confirming
+// that the cause of the pause has been looked into
+var ErrResumeNotConfirmed = errors.New("Resuming a paused campaign must be confirmed")
+
+// CampaignPausedNotification is the webhook payload sent to admins when a
+// campaign is paused because too many of its emails bounced.
+type CampaignPausedNotification struct {
+	Event      string    `json:"event"`
+	CampaignId int64     `json:"campaign_id"`
+	Name       string    `json:"name"`
+	Reason     string    `json:"reason"`
+	Delivered  int       `json:"delivered"`
+	Bounced    int       `json:"bounced"`
+	BounceRate float64   `json:"bounce_rate"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// GetBounceRateThreshold returns the percentage of a campaign's delivery
+// attempts which may bounce before it's paused, configured by
+// BOUNCE_RATE_PAUSE_THRESHOLD. A threshold of 0 never pauses campaigns.
+func GetBounceRateThreshold() int {
+	s := os.Getenv("BOUNCE_RATE_PAUSE_THRESHOLD")
+	if s == "" {
+		return DefaultBounceRateThreshold
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 || v > 100 {
+		log.Warnf("Invalid BOUNCE_RATE_PAUSE_THRESHOLD value '%s', using default %d", s, DefaultBounceRateThreshold)
+		return DefaultBounceRateThreshold
+	}
+	return v
+}
+
+// GetBounceRateMinSample returns the number of delivery attempts a campaign
+// must have before its bounce rate is acted on, configured by
+// BOUNCE_RATE_MIN_SAMPLE.
+func GetBounceRateMinSample() int {
+	s := os.Getenv("BOUNCE_RATE_MIN_SAMPLE")
+	if s == "" {
+		return DefaultBounceRateMinSample
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 1 {
+		log.Warnf("Invalid BOUNCE_RATE_MIN_SAMPLE value '%s', using default %d", s, DefaultBounceRateMinSample)
+		return DefaultBounceRateMinSample
+	}
+	return v
+}
+
+// HandleEmailBounce updates a Result to indicate that the email bounced. It's
+// recorded as a sending error, and marked as a bounce so that it counts
+// towards the campaign's bounce rate.
+func (r *Result) HandleEmailBounce(err error) error {
+	event, err := r.createEvent(EventSendingError, EventError{Error: err.Error()})
+	if err != nil {
+		return err
+	}
+	r.Status = Error
+	r.Bounced = true
+	r.ModifiedDate = event.Time
+	return db.Save(r).Error
+}
+
+// getCampaignDeliveries returns the number of the campaign's emails which
+// were delivered and which bounced since the given time.
+func getCampaignDeliveries(cid int64, since time.Time) (int, int, error) {
+	delivered := 0
+	err := db.Model(&Result{}).
+		Where("campaign_id = ? AND status IN (?) AND sent_date >= ?", cid,
+			[]string{EventSent, EventOpened, EventClicked, EventDataSubmit}, since).
+		Count(&delivered).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	bounced := 0
+	err = db.Model(&Result{}).
+		Where("campaign_id = ? AND bounced = ? AND modified_date >= ?", cid, true, since).
+		Count(&bounced).Error
+	return delivered, bounced, err
+}
+
+// checkCampaignBounceRate pauses the campaign if enough of its emails have
+// bounced since it was launched, or last resumed, to cross the threshold.
+func checkCampaignBounceRate(cid int64) error {
+	threshold := GetBounceRateThreshold()
+	if threshold == 0 {
+		return nil
+	}
+	c := Campaign{}
+	err := db.Select("id, name, paused, resumed_date").Where("id = ?", cid).First(&c).Error
+	if err != nil {
+		return err
+	}
+	if c.Paused {
+		return nil
+	}
+	delivered, bounced, err := getCampaignDeliveries(cid, c.ResumedDate)
+	if err != nil {
+		return err
+	}
+	attempts := delivered + bounced
+	if attempts < GetBounceRateMinSample() || bounced*100 < threshold*attempts {
+		return nil
+	}
+	rate := float64(bounced) * 100 / float64(attempts)
+	reason := fmt.Sprintf("%.1f%% of emails bounced (%d of %d), above the %d%% threshold", rate, bounced, attempts, threshold)
+	paused, err := pauseCampaign(cid, reason)
+	if err != nil || !paused {
+		return err
+	}
+	log.WithFields(logrus.Fields{
+		"campaign_id": cid,
+		"delivered":   delivered,
+		"bounced":     bounced,
+		"threshold":   threshold,
+	}).Warn("Paused campaign after its bounce rate crossed the threshold")
+	notifyCampaignPaused(CampaignPausedNotification{
+		Event:      CampaignPausedNotificationName,
+		CampaignId: cid,
+		Name:       c.Name,
+		Reason:     reason,
+		Delivered:  delivered,
+		Bounced:    bounced,
+		BounceRate: rate,
+		Timestamp:  time.Now().UTC(),
+	})
+	return nil
+}
+
+// pauseCampaign pauses the campaign, returning false if it was already
+// paused. While a campaign is paused, none of its emails are sent by the
+// worker and it isn't launched with n8n. Emails already handed to n8n are
+// held back too, since n8n checks with CheckN8NSend before sending each one.
+func pauseCampaign(cid int64, reason string) (bool, error) {
+	pause := db.Model(&Campaign{}).Where("id = ? AND paused = ?", cid, false).
+		UpdateColumns(map[string]interface{}{
+			"paused":        true,
+			"paused_reason": reason,
+			"paused_date":   time.Now().UTC(),
+		})
+	return pause.RowsAffected > 0, pause.Error
+}
+
+// notifyCampaignPaused notifies admins through every active webhook that the
+// campaign was paused.
+func notifyCampaignPaused(n CampaignPausedNotification) {
+	whEndPoints, err := getActiveWebhookEndPoints()
+	if err != nil {
+		log.Errorf("error getting active webhooks: %v", err)
+		return
+	}
+	webhook.SendAll(whEndPoints, n)
+}
+
+// ResumeCampaign resumes the user's paused campaign. Since the campaign was
+// paused to protect the sending domain's reputation, resuming it must be
+// confirmed. Only bounces after the campaign is resumed count towards
+// pausing it again.
+func ResumeCampaign(cid int64, u User, confirm bool) (Campaign, error) {
+	c := Campaign{}
+	err := db.Where("id = ? AND user_id = ?", cid, u.Id).First(&c).Error
+	if err != nil {
+		return c, err
+	}
+	if !c.Paused {
+		return c, ErrCampaignNotPaused
+	}
+	if !confirm {
+		return c, ErrResumeNotConfirmed
+	}
+	c.Paused = false
+	c.ResumedDate = time.Now().UTC()
+	err = db.Model(&Campaign{}).Where("id = ?", cid).UpdateColumns(map[string]interface{}{
+		"paused":       false,
+		"resumed_date": c.ResumedDate,
+	}).Error
+	if err != nil {
+		log.Error(err)
+		return c, err
+	}
+	log.WithFields(logrus.Fields{
+		"campaign_id":   cid,
+		"user_id":       u.Id,
+		"username":      u.Username,
+		"paused_reason": c.PausedReason,
+	}).Warn("Paused campaign resumed")
+	return c, nil
+}
+
+// isCampaignPaused returns true if the campaign is paused.
+func isCampaignPaused(cid int64) (bool, error) {
+	count := 0
+	err := db.Model(&Campaign{}).Where("id = ? AND paused = ?", cid, true).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+// setBounceRateEnv sets the bounce rate threshold and minimum sample,
+// returning a function which restores the original values.
+func setBounceRateEnv(threshold, minSample string) func() {
+	origThreshold, hadThreshold := os.LookupEnv("BOUNCE_RATE_PAUSE_THRESHOLD")
+	origMinSample, hadMinSample := os.LookupEnv("BOUNCE_RATE_MIN_SAMPLE")
+	os.Setenv("BOUNCE_RATE_PAUSE_THRESHOLD", threshold)
+	os.Setenv("BOUNCE_RATE_MIN_SAMPLE", minSample)
+	return func() {
+		os.Unsetenv("BOUNCE_RATE_PAUSE_THRESHOLD")
+		os.Unsetenv("BOUNCE_RATE_MIN_SAMPLE")
+		if hadThreshold {
+			os.Setenv("BOUNCE_RATE_PAUSE_THRESHOLD", origThreshold)
+		}
+		if hadMinSample {
+			os.Setenv("BOUNCE_RATE_MIN_SAMPLE", origMinSample)
+		}
+	}
+}
+
+// sendN8NCallback processes a callback from n8n reporting the event for the
+// result.
+func sendN8NCallback(ch *check.C, r Result, event string) {
+	cb := N8NCallback{
+		IdempotencyKey: fmt.Sprintf("%s-%s", r.RId, event),
+		RId:            r.RId,
+		CampaignId:     r.CampaignId,
+		Event:          event,
+		SentDate:       time.Now().UTC(),
+	}
+	_, err := EnqueueN8NCallback(&cb)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(ProcessN8NCallback(cb.Id), check.Equals, nil)
+}
+
+// queuedCampaignMailLogs returns the number of the campaign's maillogs the
+// worker would pick up.
+func queuedCampaignMailLogs(ch *check.C, cid int64) int {
+	ms, err := GetQueuedMailLogs(time.Now().UTC().Add(24 * time.Hour))
+	ch.Assert(err, check.Equals, nil)
+	count := 0
+	for _, m := range ms {
+		if m.CampaignId == cid {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *ModelsSuite) TestBounceRatePausesCampaign(ch *check.C) {
+	defer setBounceRateEnv("50", "4")()
+	campaign := s.createCampaign(ch)
+	ch.Assert(len(campaign.Results), check.Equals, 4)
+	queued := queuedCampaignMailLogs(ch, campaign.Id)
+
+	sendN8NCallback(ch, campaign.Results[0], "sent")
+	sendN8NCallback(ch, campaign.Results[1], "sent")
+	sendN8NCallback(ch, campaign.Results[2], "bounce")
+
+	// Too few emails have been sent to act on the bounce rate
+	c, err := GetCampaign(campaign.Id, campaign.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(c.Paused, check.Equals, false)
+
+	sendN8NCallback(ch, campaign.Results[3], "bounce")
+	c, err = GetCampaign(campaign.Id, campaign.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(c.Paused, check.Equals, true)
+	ch.Assert(c.PausedReason, check.Not(check.Equals), "")
+	ch.Assert(c.PausedDate.IsZero(), check.Equals, false)
+
+	bounced, err := GetResult(campaign.Results[3].RId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(bounced.Bounced, check.Equals, true)
+	ch.Assert(bounced.Status, check.Equals, Error)
+
+	// The worker doesn't send emails for paused campaigns
+	ch.Assert(queuedCampaignMailLogs(ch, campaign.Id), check.Equals, 0)
+
+	u, err := GetUser(campaign.UserId)
+	ch.Assert(err, check.Equals, nil)
+	_, err = ResumeCampaign(campaign.Id, u, false)
+	ch.Assert(err, check.Equals, ErrResumeNotConfirmed)
+	c, err = ResumeCampaign(campaign.Id, u, true)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(c.Paused, check.Equals, false)
+	ch.Assert(queuedCampaignMailLogs(ch, campaign.Id), check.Equals, queued)
+
+	// Bounces from before the campaign was resumed don't pause it again
+	ch.Assert(checkCampaignBounceRate(campaign.Id), check.Equals, nil)
+	c, err = GetCampaign(campaign.Id, campaign.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(c.Paused, check.Equals, false)
+	_, err = ResumeCampaign(campaign.Id, u, true)
+	ch.Assert(err, check.Equals, ErrCampaignNotPaused)
+}
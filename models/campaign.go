@@ -1,13 +1,19 @@
 package models
 
 import (
+	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"math/big"
+	"net/mail"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gophish/gophish/config"
 	log "github.com/gophish/gophish/logger"
 	"github.com/gophish/gophish/webhook"
 	"github.com/jinzhu/gorm"
@@ -21,6 +27,7 @@ type Campaign struct {
 	Name          string    `json:"name" sql:"not null"`
 	CreatedDate   time.Time `json:"created_date"`
 	LaunchDate    time.Time `json:"launch_date"`
+	LaunchAt      string    `json:"launch_at,omitempty" gorm:"-"` // Relative launch, resolved to LaunchDate when the campaign is created
 	SendByDate    time.Time `json:"send_by_date"`
 	CompletedDate time.Time `json:"completed_date"`
 	TemplateId    int64     `json:"-"`
@@ -35,6 +42,43 @@ type Campaign struct {
 	EmailAccount   EmailAccount `json:"email_account"`
 	EmailType      string       `json:"email_type" gorm:"-"` // Transient field for frontend, not stored in DB
 	URL            string       `json:"url"`
+	FromName       string       `json:"from_name,omitempty"`
+	TrackingParams string       `json:"tracking_params,omitempty"`
+	CaptureRawData bool         `json:"capture_raw_data"`
+	CooldownDays   int          `json:"cooldown_days,omitempty" gorm:"-"` // Skip targets sent a campaign this recently
+	Exclusions     []string     `json:"exclusions,omitempty" gorm:"-"`    // Addresses and "@domain"s never sent to
+	Compacted      bool         `json:"compacted"`
+	Archived       bool         `json:"archived"`
+	ArchivedDate   time.Time    `json:"archived_date"`
+	StartJitter    *int         `json:"start_jitter" gorm:"default:0"`
+	StartOffset    int          `json:"start_offset"` // Seconds after the launch date that sending starts, picked from the start jitter window
+	LaunchStatus   string       `json:"launch_status,omitempty"`
+	LaunchAttempts int          `json:"launch_attempts,omitempty"`
+	LaunchError    string       `json:"launch_error,omitempty"`
+	NextLaunchDate time.Time    `json:"-"`
+	Paused         bool         `json:"paused"`
+	PausedReason   string       `json:"paused_reason,omitempty"`
+	PausedDate     time.Time    `json:"paused_date"`
+	ResumedDate    time.Time    `json:"-"`
+	Transport      string       `json:"transport,omitempty"`
+
+	TemplateVariants []TemplateVariant `json:"template_variants,omitempty"`
+	PageVariants     []PageVariant     `json:"page_variants,omitempty"`
+
+	// SuppressClickersFrom is a prior campaign whose recipients who clicked
+	// the link or submitted data are skipped, so only non-clickers are
+	// re-targeted
+	SuppressClickersFrom int64 `json:"suppress_clickers_from,omitempty" gorm:"-"`
+	// Skipped are the group members the campaign wasn't sent to, given when
+	// it's created
+	Skipped       []SkippedRecipient `json:"skipped,omitempty" gorm:"-"`
+	// RateLimitWarning is given when the campaign is created with a send-by
+	// date which sends emails faster than the default interval
+	RateLimitWarning *RateLimitWarning `json:"rate_limit_warning,omitempty" gorm:"-"`
+	// EnforceSendInterval rejects the campaign if it would be created with a
+	// rate limit warning, as though ENFORCE_EMAIL_SEND_INTERVAL were set
+	EnforceSendInterval bool `json:"-" gorm:"-"`
+	priorClickers map[string]bool
 }
 
 // CampaignResults is a struct representing the results from a campaign
@@ -50,6 +94,9 @@ type CampaignResults struct {
 type CampaignSummaries struct {
 	Total     int64             `json:"total"`
 	Campaigns []CampaignSummary `json:"campaigns"`
+	// AutoArchive is true if completed campaigns are archived automatically,
+	// in which case they're listed as active until they've been archived.
+	AutoArchive bool `json:"auto_archive"`
 }
 
 // CampaignSummary is a struct representing the overview of a single camaign
@@ -61,6 +108,8 @@ type CampaignSummary struct {
 	CompletedDate time.Time     `json:"completed_date"`
 	Status        string        `json:"status"`
 	Name          string        `json:"name"`
+	Compacted     bool          `json:"compacted"`
+	Archived      bool          `json:"archived"`
 	Stats         CampaignStats `json:"stats"`
 }
 
@@ -72,6 +121,7 @@ type CampaignStats struct {
 	ClickedLink   int64 `json:"clicked"`
 	SubmittedData int64 `json:"submitted_data"`
 	EmailReported int64 `json:"email_reported"`
+	AutoReplied   int64 `json:"auto_replied"`
 	Error         int64 `json:"error"`
 }
 
@@ -126,10 +176,22 @@ var ErrPageNotFound = errors.New("Page not found")
 // ErrEmailAccountNotFound indicates an email account specified by the user does not exist in the database
 var ErrEmailAccountNotFound = errors.New("Email account not found")
 
+// ErrEmailAccountTypeMismatch indicates that the email account specified by
+// the user isn't of the campaign's email type
+var ErrEmailAccountTypeMismatch = errors.New("Email account type doesn't match the campaign's email type")
+
 // ErrInvalidSendByDate indicates that the user specified a send by date that occurs before the
 // launch date
 var ErrInvalidSendByDate = errors.New("The launch date must be before the \"send emails by\" date")
 
+// ErrInvalidStartJitter indicates that the start jitter is outside of the
+// allowed range
+var ErrInvalidStartJitter = fmt.Errorf("The start jitter must be between 0 and %d minutes", MaxStartJitter)
+
+// MaxStartJitter is the largest start jitter window, in minutes, that can be
+// set on a campaign.
+const MaxStartJitter = 60
+
 // RecipientParameter is the URL parameter that points to the result ID for a recipient.
 const RecipientParameter = "rid"
 
@@ -148,6 +210,31 @@ func (c *Campaign) Validate() error {
 		return ErrEmailAccountNotSpecified
 	case !c.SendByDate.IsZero() && !c.LaunchDate.IsZero() && c.SendByDate.Before(c.LaunchDate):
 		return ErrInvalidSendByDate
+	case c.StartJitter != nil && (*c.StartJitter < 0 || *c.StartJitter > MaxStartJitter):
+		return ErrInvalidStartJitter
+	}
+	if err := validateName("campaign", c.Name); err != nil {
+		return err
+	}
+	c.FromName = strings.TrimSpace(c.FromName)
+	if err := validateFromName(c.FromName); err != nil {
+		return err
+	}
+	if err := c.normalizeTrackingParams(); err != nil {
+		return err
+	}
+	if err := c.validateRecipientFilters(); err != nil {
+		return err
+	}
+	for i := range c.TemplateVariants {
+		if err := c.TemplateVariants[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range c.PageVariants {
+		if err := c.PageVariants[i].Validate(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -163,21 +250,32 @@ func AddEvent(e *Event, campaignID int64) error {
 	e.CampaignId = campaignID
 	e.Time = time.Now().UTC()
 
-	whs, err := GetActiveWebhooks()
+	whEndPoints, err := getActiveWebhookEndPoints()
 	if err == nil {
-		whEndPoints := []webhook.EndPoint{}
-		for _, wh := range whs {
-			whEndPoints = append(whEndPoints, webhook.EndPoint{
-				URL:    wh.URL,
-				Secret: wh.Secret,
-			})
-		}
 		webhook.SendAll(whEndPoints, e)
 	} else {
 		log.Errorf("error getting active webhooks: %v", err)
 	}
 
-	return db.Save(e).Error
+	err = db.Save(e).Error
+	if err != nil {
+		return err
+	}
+	notifyCampaignProgress(campaignID)
+	return nil
+}
+
+// getActiveWebhookEndPoints returns the endpoints for all active webhooks.
+func getActiveWebhookEndPoints() ([]webhook.EndPoint, error) {
+	whs, err := GetActiveWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	whEndPoints := []webhook.EndPoint{}
+	for _, wh := range whs {
+		whEndPoints = append(whEndPoints, wh.EndPoint())
+	}
+	return whEndPoints, nil
 }
 
 // getDetails retrieves the related attributes of the campaign
@@ -190,11 +288,17 @@ func (c *Campaign) getDetails() error {
 		log.Warnf("%s: results not found for campaign", err)
 		return err
 	}
+	err = attachResultNotes(c.Results)
+	if err != nil {
+		log.Warnf("%s: notes not found for results", err)
+		return err
+	}
 	err = db.Model(c).Related(&c.Events).Error
 	if err != nil {
 		log.Warnf("%s: events not found for campaign", err)
 		return err
 	}
+	countResultEngagement(c.Results, c.Events)
 	err = db.Table("templates").Where("id=?", c.TemplateId).Find(&c.Template).Error
 	if err != nil {
 		if err != gorm.ErrRecordNotFound {
@@ -208,6 +312,11 @@ func (c *Campaign) getDetails() error {
 		log.Warn(err)
 		return err
 	}
+	err = c.getTemplateVariants()
+	if err != nil {
+		log.Warn(err)
+		return err
+	}
 	err = db.Table("pages").Where("id=?", c.PageId).Find(&c.Page).Error
 	if err != nil {
 		if err != gorm.ErrRecordNotFound {
@@ -216,6 +325,11 @@ func (c *Campaign) getDetails() error {
 		c.Page = Page{Name: "[Deleted]"}
 		log.Warnf("%s: page not found for campaign", err)
 	}
+	err = c.getPageVariants()
+	if err != nil {
+		log.Warn(err)
+		return err
+	}
 	err = db.Table("email_accounts").Where("id=?", c.EmailAccountId).Find(&c.EmailAccount).Error
 	if err != nil {
 		// Check if the EmailAccount was deleted
@@ -234,70 +348,213 @@ func (c *Campaign) getBaseURL() string {
 	return c.URL
 }
 
-// getFromAddress returns the Campaign's configured email account address.
+// getFromAddress returns the Campaign's configured email account address,
+// along with the campaign's display name if one is set.
 // This is used to implement the TemplateContext interface.
 func (c *Campaign) getFromAddress() string {
-	return c.EmailAccount.Email
+	if c.FromName == "" {
+		return c.EmailAccount.Email
+	}
+	return (&mail.Address{Name: c.FromName, Address: c.EmailAccount.Email}).String()
+}
+
+// pickStartOffset picks how long after the launch date the campaign starts
+// sending from its start jitter window, so that campaigns launched at the
+// same time are spread out. The offset is stored with the campaign, so that
+// it never changes once the campaign is created.
+func (c *Campaign) pickStartOffset() error {
+	c.StartOffset = 0
+	if c.StartJitter == nil || *c.StartJitter <= 0 {
+		return nil
+	}
+	offset, err := rand.Int(rand.Reader, big.NewInt(int64(*c.StartJitter)*60+1))
+	if err != nil {
+		return err
+	}
+	c.StartOffset = int(offset.Int64())
+	return nil
+}
+
+// startOffset returns how long after the launch date the campaign starts
+// sending.
+func (c *Campaign) startOffset() time.Duration {
+	return time.Duration(c.StartOffset) * time.Second
 }
 
 // generateSendDate creates a sendDate
-func (c *Campaign) generateSendDate(idx int, totalRecipients int) time.Time {
-	// If no send date is specified, just return the launch date
-	if c.SendByDate.IsZero() || c.SendByDate.Equal(c.LaunchDate) {
+func (c *Campaign) generateSendDate(idx int, totalRecipients int, interval time.Duration) time.Time {
+	return c.generateBaseSendDate(idx, totalRecipients, interval).Add(c.startOffset())
+}
+
+// generateBaseSendDate spreads the recipients evenly between the launch date
+// and the send by date.
+func (c *Campaign) generateBaseSendDate(idx int, totalRecipients int, interval time.Duration) time.Time {
+	sendByDate := c.SendByDate
+	// Campaigns without a send by date drip at the given interval rather
+	// than sending to everyone at once, unless AUTO_DRIP_CAMPAIGNS is off
+	if (sendByDate.IsZero() || sendByDate.Equal(c.LaunchDate)) && IsAutoDripEnabled() && totalRecipients > 0 {
+		sendByDate = CalculateMinimumSendByDate(c.LaunchDate, totalRecipients, interval)
+	}
+	// If there's still no send date, just return the launch date
+	if sendByDate.IsZero() || !sendByDate.After(c.LaunchDate) || totalRecipients < 1 {
 		return c.LaunchDate
 	}
-	// Otherwise, we can calculate the range of minutes to send emails
-	// (since we only poll once per minute)
-	totalMinutes := c.SendByDate.Sub(c.LaunchDate).Minutes()
-
-	// Next, we can determine how many minutes should elapse between emails
-	minutesPerEmail := totalMinutes / float64(totalRecipients)
+	// Otherwise, we can determine how long should elapse between emails.
+	// The offset is kept to the second rather than rounded down to the
+	// minute, so that short intervals don't send emails in bursts.
+	perEmail := float64(sendByDate.Sub(c.LaunchDate)) / float64(totalRecipients)
 
 	// Then, we can calculate the offset for this particular email
-	offset := int(minutesPerEmail * float64(idx))
+	offset := time.Duration(perEmail * float64(idx)).Truncate(time.Second)
 
 	// Finally, we can just add this offset to the launch date to determine
 	// when the email should be sent
-	return c.LaunchDate.Add(time.Duration(offset) * time.Minute)
+	return c.LaunchDate.Add(offset)
 }
 
-// getCampaignStats returns a CampaignStats object for the campaign with the given campaign ID.
-// It also backfills numbers as appropriate with a running total, so that the values are aggregated.
-func getCampaignStats(cid int64) (CampaignStats, error) {
-	s := CampaignStats{}
-	query := db.Table("results").Where("campaign_id = ?", cid)
-	err := query.Count(&s.Total).Error
+// ErrSendByDateInPast indicates that a campaign's send schedule was
+// recalculated with a "send emails by" date which has already passed
+var ErrSendByDateInPast = errors.New("The \"send emails by\" date must be in the future")
+
+// ErrCampaignNotScheduling indicates that a campaign's send schedule can't be
+// recalculated because the campaign has completed
+var ErrCampaignNotScheduling = errors.New("Campaign has completed, so its schedule can't be changed")
+
+// ErrN8NScheduleFixed indicates that a campaign's send schedule can't be
+// recalculated because n8n was given the schedule when it was launched
+var ErrN8NScheduleFixed = errors.New("Campaign is sent with n8n, so its schedule can't be changed")
+
+// ErrNoSendsToReschedule indicates that a campaign's send schedule can't be
+// recalculated because none of its emails are still scheduled
+var ErrNoSendsToReschedule = errors.New("Campaign has no scheduled emails to reschedule")
+
+// RecalculateSendSchedule moves the campaign's "send emails by" date,
+// spreading the emails still scheduled to be sent evenly between now, or the
+// launch date if the campaign hasn't launched yet, and the new date. Results
+// which have already been sent, or are being sent, are left alone. The
+// number of emails rescheduled is returned. Campaigns sent with n8n can't be
+// rescheduled, since n8n schedules their emails itself.
+func RecalculateSendSchedule(campaignID, uid int64, newSendBy time.Time) (int, error) {
+	now := time.Now().UTC()
+	newSendBy = newSendBy.UTC()
+	if !newSendBy.After(now) {
+		return 0, ErrSendByDateInPast
+	}
+	c := Campaign{}
+	err := db.Select("id, status, launch_date, launch_status").Where("id = ? and user_id = ?", campaignID, uid).First(&c).Error
 	if err != nil {
-		return s, err
+		return 0, err
+	}
+	if c.Status == CampaignComplete {
+		return 0, ErrCampaignNotScheduling
+	}
+	// n8n campaigns have no maillogs, since n8n schedules their emails
+	if c.LaunchStatus != "" {
+		return 0, ErrN8NScheduleFixed
 	}
-	query.Where("status=?", EventDataSubmit).Count(&s.SubmittedData)
+	start := now
+	if c.LaunchDate.After(start) {
+		start = c.LaunchDate
+	}
+	if !newSendBy.After(start) {
+		return 0, ErrInvalidSendByDate
+	}
+
+	tx := db.Begin()
+	// Maillogs locked by the worker are being sent, so they keep their
+	// send date
+	ms := []MailLog{}
+	err = tx.Table("mail_logs").
+		Select("mail_logs.*").
+		Joins("JOIN results ON results.r_id = mail_logs.r_id").
+		Where("mail_logs.campaign_id = ? AND mail_logs.processing = ? AND results.status = ?", campaignID, false, StatusScheduled).
+		Order("mail_logs.send_date asc, mail_logs.id asc").
+		Find(&ms).Error
 	if err != nil {
-		return s, err
+		tx.Rollback()
+		return 0, err
 	}
-	query.Where("status=?", EventClicked).Count(&s.ClickedLink)
+	if len(ms) == 0 {
+		tx.Rollback()
+		return 0, ErrNoSendsToReschedule
+	}
+	perEmail := float64(newSendBy.Sub(start)) / float64(len(ms))
+	for i, m := range ms {
+		sendDate := start.Add(time.Duration(perEmail * float64(i)).Truncate(time.Second))
+		err = tx.Model(&MailLog{}).Where("id = ?", m.Id).Update("send_date", sendDate).Error
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		err = tx.Model(&Result{}).Where("r_id = ?", m.RId).Update("send_date", sendDate).Error
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	err = tx.Model(&Campaign{}).Where("id = ?", campaignID).Update("send_by_date", newSendBy).Error
 	if err != nil {
-		return s, err
+		tx.Rollback()
+		return 0, err
 	}
-	query.Where("reported=?", true).Count(&s.EmailReported)
+	err = tx.Commit().Error
 	if err != nil {
-		return s, err
+		return 0, err
 	}
-	// Every submitted data event implies they clicked the link
-	s.ClickedLink += s.SubmittedData
-	err = query.Where("status=?", EventOpened).Count(&s.OpenedEmail).Error
+	log.WithFields(logrus.Fields{
+		"campaign_id":  campaignID,
+		"send_by_date": newSendBy,
+		"rescheduled":  len(ms),
+	}).Info("Recalculated campaign send schedule")
+	return len(ms), nil
+}
+
+// getCampaignStats returns a CampaignStats object for the campaign with the given campaign ID.
+// It also backfills numbers as appropriate with a running total, so that the values are aggregated.
+func getCampaignStats(cid int64) (CampaignStats, error) {
+	s := CampaignStats{}
+	// The statistics are counted in a single pass over the results, since
+	// they're loaded for every campaign on the dashboard. Internal results
+	// are only counted as sent, so that test accounts don't count towards
+	// the campaign's engagement.
+	bind := db.Dialect().BindVar
+	query := fmt.Sprintf(`SELECT COUNT(*),
+		COALESCE(SUM(CASE WHEN status = %s AND internal = %s THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN status = %s AND internal = %s THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN reported = %s AND internal = %s THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN status = %s AND internal = %s THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN status = %s OR (internal = %s AND status IN (%s, %s, %s)) THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN status = %s THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN auto_replied = %s THEN 1 ELSE 0 END), 0)
+		FROM results WHERE campaign_id = %s`,
+		bind(1), bind(2), bind(3), bind(4), bind(5), bind(6), bind(7), bind(8),
+		bind(9), bind(10), bind(11), bind(12), bind(13), bind(14), bind(15), bind(16))
+	ctx, cancel := queryContext()
+	defer cancel()
+	err := db.DB().QueryRowContext(ctx, query,
+		EventDataSubmit, false, EventClicked, false, true, false, EventOpened, false,
+		EventSent, true, EventOpened, EventClicked, EventDataSubmit, Error, true, cid,
+	).Scan(&s.Total, &s.SubmittedData, &s.ClickedLink, &s.EmailReported,
+		&s.OpenedEmail, &s.EmailsSent, &s.Error, &s.AutoReplied)
 	if err != nil {
 		return s, err
 	}
+	// Every submitted data event implies they clicked the link
+	s.ClickedLink += s.SubmittedData
 	// Every clicked link event implies they opened the email
 	s.OpenedEmail += s.ClickedLink
-	err = query.Where("status=?", EventSent).Count(&s.EmailsSent).Error
-	if err != nil {
-		return s, err
-	}
 	// Every opened email event implies the email was sent
 	s.EmailsSent += s.OpenedEmail
-	err = query.Where("status=?", Error).Count(&s.Error).Error
-	return s, err
+	return s, nil
+}
+
+// getStats returns the statistics for the summarized campaign, using the
+// stored snapshot if the campaign has been compacted.
+func (cs *CampaignSummary) getStats() (CampaignStats, error) {
+	if cs.Compacted {
+		return getCompactedCampaignStats(cs.Id)
+	}
+	return getCampaignStats(cs.Id)
 }
 
 // GetCampaigns returns the campaigns owned by the given user.
@@ -320,39 +577,50 @@ func GetCampaigns(uid int64) ([]Campaign, error) {
 // owned by the current user
 func GetCampaignSummaries(uid int64) (CampaignSummaries, error) {
 	overview := CampaignSummaries{}
-	cs := []CampaignSummary{}
 	// Get the basic campaign information
 	query := db.Table("campaigns").Where("user_id = ?", uid)
-	query = query.Select("id, name, created_date, launch_date, send_by_date, completed_date, status")
+	cs, err := scanCampaignSummaries(query)
+	if err != nil {
+		return overview, err
+	}
+	overview.Total = int64(len(cs))
+	overview.Campaigns = cs
+	overview.AutoArchive = GetAutoArchiveAge() > 0
+	return overview, nil
+}
+
+// scanCampaignSummaries returns the summaries of the campaigns selected by
+// the query, along with their statistics.
+func scanCampaignSummaries(query *gorm.DB) ([]CampaignSummary, error) {
+	cs := []CampaignSummary{}
+	query = query.Select("id, name, created_date, launch_date, send_by_date, completed_date, status, compacted, archived")
 	err := query.Scan(&cs).Error
 	if err != nil {
 		log.Error(err)
-		return overview, err
+		return cs, err
 	}
 	for i := range cs {
-		s, err := getCampaignStats(cs[i].Id)
+		s, err := cs[i].getStats()
 		if err != nil {
 			log.Error(err)
-			return overview, err
+			return cs, err
 		}
 		cs[i].Stats = s
 	}
-	overview.Total = int64(len(cs))
-	overview.Campaigns = cs
-	return overview, nil
+	return cs, nil
 }
 
 // GetCampaignSummary gets the summary object for a campaign specified by the campaign ID
 func GetCampaignSummary(id int64, uid int64) (CampaignSummary, error) {
 	cs := CampaignSummary{}
 	query := db.Table("campaigns").Where("user_id = ? AND id = ?", uid, id)
-	query = query.Select("id, name, created_date, launch_date, send_by_date, completed_date, status")
+	query = query.Select("id, name, created_date, launch_date, send_by_date, completed_date, status, compacted, archived")
 	err := query.Scan(&cs).Error
 	if err != nil {
 		log.Error(err)
 		return cs, err
 	}
-	s, err := getCampaignStats(cs.Id)
+	s, err := cs.getStats()
 	if err != nil {
 		log.Error(err)
 		return cs, err
@@ -374,19 +642,61 @@ func GetCampaignMailContext(id int64, uid int64) (Campaign, error) {
 	if err != nil {
 		return c, err
 	}
-	err = db.Table("email_accounts").Where("id=?", c.EmailAccountId).Find(&c.EmailAccount).Error
+	err = c.getMailContext()
+	return c, err
+}
+
+// getMailContext loads the email account, template and template variants
+// needed to send the campaign's emails.
+func (c *Campaign) getMailContext() error {
+	err := db.Table("email_accounts").Where("id=?", c.EmailAccountId).Find(&c.EmailAccount).Error
 	if err != nil {
-		return c, err
+		return err
 	}
 	err = db.Table("templates").Where("id=?", c.TemplateId).Find(&c.Template).Error
 	if err != nil {
-		return c, err
+		return err
 	}
 	err = db.Where("template_id=?", c.Template.Id).Find(&c.Template.Attachments).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
-		return c, err
+		return err
 	}
-	return c, nil
+	return c.getTemplateVariants()
+}
+
+// GetQueuedMailContexts returns the mail context of each campaign the given
+// maillogs belong to, keyed by campaign id.
+//
+// Campaigns are loaded in launch date order, using the configured number of
+// workers, and any which can't be loaded within the budget are left out so
+// that their maillogs are sent on the next poll.
+func GetQueuedMailContexts(ms []*MailLog) (map[int64]Campaign, error) {
+	contexts := map[int64]Campaign{}
+	ids := []int64{}
+	for _, m := range ms {
+		if _, ok := contexts[m.CampaignId]; !ok {
+			contexts[m.CampaignId] = Campaign{}
+			ids = append(ids, m.CampaignId)
+		}
+	}
+	if len(ids) == 0 {
+		return contexts, nil
+	}
+	cs := []Campaign{}
+	err := db.Where("id IN (?)", ids).Order("launch_date asc, id asc").Find(&cs).Error
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	cs, err = loadQueuedCampaigns(cs, (*Campaign).getMailContext)
+	if err != nil {
+		return nil, err
+	}
+	contexts = make(map[int64]Campaign, len(cs))
+	for _, c := range cs {
+		contexts[c.Id] = c
+	}
+	return contexts, nil
 }
 
 // GetCampaign returns the campaign, if it exists, specified by the given id and user_id.
@@ -417,34 +727,141 @@ func GetCampaignResults(id int64, uid int64) (CampaignResults, error) {
 		log.Errorf("%s: results not found for campaign", err)
 		return cr, err
 	}
+	err = attachResultNotes(cr.Results)
+	if err != nil {
+		log.Errorf("%s: notes not found for results", err)
+		return cr, err
+	}
 	err = db.Table("events").Where("campaign_id=?", cr.Id).Find(&cr.Events).Error
 	if err != nil {
 		log.Errorf("%s: events not found for campaign", err)
 		return cr, err
 	}
+	countResultEngagement(cr.Results, cr.Events)
 	return cr, err
 }
 
 // GetQueuedCampaigns returns the campaigns that are queued up for this given minute
+//
+// Campaigns are returned in launch date order. Their details are loaded
+// concurrently, and any campaigns which can't be loaded within the configured
+// budget are left out so that they're picked up on the next poll.
 func GetQueuedCampaigns(t time.Time) ([]Campaign, error) {
 	cs := []Campaign{}
 	err := db.Where("launch_date <= ?", t).
-		Where("status = ?", CampaignQueued).Find(&cs).Error
+		Where("status = ?", CampaignQueued).
+		Order("launch_date asc, id asc").Find(&cs).Error
 	if err != nil {
 		log.Error(err)
+		return cs, err
 	}
 	log.Infof("Found %d Campaigns to run\n", len(cs))
+	return loadQueuedCampaigns(cs, (*Campaign).getDetails)
+}
+
+// loadQueuedCampaigns runs load against each campaign using the configured
+// number of workers and budget.
+func loadQueuedCampaigns(cs []Campaign, load func(*Campaign) error) ([]Campaign, error) {
+	qc := (&config.Config{}).GetQueuedCampaigns()
+	if conf != nil {
+		qc = conf.GetQueuedCampaigns()
+	}
+	budget := time.Duration(qc.BudgetSeconds) * time.Second
+	return loadCampaignDetails(cs, qc.Workers, budget, load)
+}
+
+// loadedCampaign is the outcome of loading a single queued campaign.
+type loadedCampaign struct {
+	index    int
+	campaign Campaign
+	err      error
+}
+
+// loadCampaignDetails runs load against each campaign using at most the given
+// number of workers. It returns the campaigns which finished loading within
+// the budget, keeping their original order, along with the last error seen.
+//
+// Each worker loads a copy of the campaign, so a slow load which outlives the
+// budget never touches the returned slice.
+func loadCampaignDetails(cs []Campaign, workers int, budget time.Duration, load func(*Campaign) error) ([]Campaign, error) {
+	if len(cs) == 0 {
+		return cs, nil
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > len(cs) {
+		workers = len(cs)
+	}
+	jobs := make(chan int, len(cs))
 	for i := range cs {
-		err = cs[i].getDetails()
-		if err != nil {
-			log.Error(err)
+		jobs <- i
+	}
+	close(jobs)
+	// The results channel is buffered so that workers finishing after the
+	// budget has expired never block.
+	results := make(chan loadedCampaign, len(cs))
+	done := make(chan struct{})
+	defer close(done)
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				c := cs[i]
+				err := load(&c)
+				results <- loadedCampaign{index: i, campaign: c, err: err}
+			}
+		}()
+	}
+	loaded := make([]*loadedCampaign, len(cs))
+	timeout := time.NewTimer(budget)
+	defer timeout.Stop()
+	var err error
+	received := 0
+collect:
+	for received < len(cs) {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				log.Error(r.err)
+				err = r.err
+			}
+			loaded[r.index] = &r
+			received++
+		case <-timeout.C:
+			log.Warnf("Loaded %d of %d queued campaigns within %s, deferring the rest to the next poll", received, len(cs), budget)
+			break collect
 		}
 	}
-	return cs, err
+	ready := []Campaign{}
+	for _, r := range loaded {
+		if r != nil {
+			ready = append(ready, r.campaign)
+		}
+	}
+	return ready, err
 }
 
-// PostCampaign inserts a campaign and all associated records into the database.
-func PostCampaign(c *Campaign, uid int64) error {
+// prepare resolves and validates everything the campaign is created from,
+// without writing to the database. It returns the number of recipients in
+// the campaign's groups, counting duplicates, and the rate limit warning for
+// its send-by date, if any.
+func (c *Campaign) prepare(uid int64) (int, *RateLimitWarning, error) {
+	// Make sure there's an account to send from up front, rather than only
+	// finding out when the campaign is sent
+	if c.EmailType != "" {
+		err := ValidateEmailTypeHasActiveAccount(c.EmailType)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"email_type": c.EmailType,
+			}).Error(err)
+			return 0, nil, err
+		}
+	}
 	// If EmailType is provided, look up the EmailAccount before validation
 	if c.EmailType != "" && c.EmailAccount.Email == "" {
 		ea, err := GetEmailAccountByType(c.EmailType)
@@ -452,18 +869,47 @@ func PostCampaign(c *Campaign, uid int64) error {
 			log.WithFields(logrus.Fields{
 				"email_type": c.EmailType,
 			}).Error("Email account with this type does not exist")
-			return errors.New("Email account not found for type: " + c.EmailType)
+			return 0, nil, errors.New("Email account not found for type: " + c.EmailType)
 		} else if err != nil {
 			log.Error(err)
-			return err
+			return 0, nil, err
 		}
 		c.EmailAccount = ea
 		c.EmailAccountId = ea.Id
 	}
 
-	err := c.Validate()
+	// Fall back to the default start jitter if none was requested. A start
+	// jitter of 0 turns jitter off for the campaign.
+	if c.StartJitter == nil {
+		jitter := 0
+		if conf != nil {
+			jitter = conf.StartJitterMinutes
+		}
+		c.StartJitter = &jitter
+	}
+	err := c.resolveLaunchAt(time.Now().UTC())
 	if err != nil {
-		return err
+		return 0, nil, err
+	}
+	err = c.Validate()
+	if err != nil {
+		return 0, nil, err
+	}
+	err = c.pickStartOffset()
+	if err != nil {
+		return 0, nil, err
+	}
+	err = c.checkTrackingHost()
+	if err != nil {
+		return 0, nil, err
+	}
+	err = c.checkTrackingDomain()
+	if err != nil {
+		return 0, nil, err
+	}
+	err = c.ensureUniqueName(uid)
+	if err != nil {
+		return 0, nil, err
 	}
 	// Fill in the details
 	c.UserId = uid
@@ -484,27 +930,13 @@ func PostCampaign(c *Campaign, uid int64) error {
 	// Check to make sure all the groups already exist
 	// Also, later we'll need to know the total number of recipients (counting
 	// duplicates is ok for now), so we'll do that here to save a loop.
-	totalRecipients := 0
-	for i, g := range c.Groups {
-		c.Groups[i], err = GetGroupByName(g.Name, uid)
-		if err == gorm.ErrRecordNotFound {
-			log.WithFields(logrus.Fields{
-				"group": g.Name,
-			}).Error("Group does not exist")
-			return ErrGroupNotFound
-		} else if err != nil {
-			log.Error(err)
-			return err
-		}
-		totalRecipients += len(c.Groups[i].Targets)
+	totalRecipients, err := c.loadGroups(uid)
+	if err != nil {
+		return 0, nil, err
 	}
-
-	// Auto-calculate send-by date if not provided (rate limiting)
-	// This ensures emails are spaced out safely to avoid spam filters and account lockouts
-	if c.SendByDate.IsZero() && totalRecipients > 0 {
-		c.SendByDate = CalculateMinimumSendByDate(c.LaunchDate, totalRecipients)
-		log.Infof("Auto-calculated send-by date for campaign: %v (launch: %v, recipients: %d, interval: %v)",
-			c.SendByDate, c.LaunchDate, totalRecipients, GetDefaultSendInterval())
+	err = c.loadPriorClickers(uid)
+	if err != nil {
+		return 0, nil, err
 	}
 
 	// Check to make sure the template exists
@@ -513,26 +945,36 @@ func PostCampaign(c *Campaign, uid int64) error {
 		log.WithFields(logrus.Fields{
 			"template": c.Template.Name,
 		}).Error("Template does not exist")
-		return ErrTemplateNotFound
+		return 0, nil, ErrTemplateNotFound
 	} else if err != nil {
 		log.Error(err)
-		return err
+		return 0, nil, err
 	}
 	c.Template = t
 	c.TemplateId = t.Id
+	// Check to make sure the templates used by any variants exist
+	err = c.resolveTemplateVariants(uid)
+	if err != nil {
+		return 0, nil, err
+	}
 	// Check to make sure the page exists
 	p, err := GetPageByName(c.Page.Name, uid)
 	if err == gorm.ErrRecordNotFound {
 		log.WithFields(logrus.Fields{
 			"page": c.Page.Name,
 		}).Error("Page does not exist")
-		return ErrPageNotFound
+		return 0, nil, ErrPageNotFound
 	} else if err != nil {
 		log.Error(err)
-		return err
+		return 0, nil, err
 	}
 	c.Page = p
 	c.PageId = p.Id
+	// Check to make sure the pages used by any variants exist
+	err = c.resolvePageVariants(uid)
+	if err != nil {
+		return 0, nil, err
+	}
 	// Check to make sure the email account exists
 	// Note: Campaigns should reference EmailAccount by ID, Email, or EmailType
 	if c.EmailAccountId == 0 && c.EmailAccount.Email != "" {
@@ -542,10 +984,10 @@ func PostCampaign(c *Campaign, uid int64) error {
 			log.WithFields(logrus.Fields{
 				"email": c.EmailAccount.Email,
 			}).Error("Email account does not exist")
-			return ErrEmailAccountNotFound
+			return 0, nil, ErrEmailAccountNotFound
 		} else if err != nil {
 			log.Error(err)
-			return err
+			return 0, nil, err
 		}
 		c.EmailAccount = ea
 		c.EmailAccountId = ea.Id
@@ -556,18 +998,70 @@ func PostCampaign(c *Campaign, uid int64) error {
 			log.WithFields(logrus.Fields{
 				"email_type": c.EmailType,
 			}).Error("Email account with this type does not exist")
-			return ErrEmailAccountNotFound
+			return 0, nil, ErrEmailAccountNotFound
 		} else if err != nil {
 			log.Error(err)
-			return err
+			return 0, nil, err
 		}
 		c.EmailAccount = ea
 		c.EmailAccountId = ea.Id
 	}
+	err = c.checkEmailAccountType()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Check the send-by date as requested before it's filled in, so that
+	// admins can be told about campaigns created with an aggressive rate.
+	// Campaigns are paced by their email account's send interval.
+	interval := c.EmailAccount.SendInterval()
+	rateLimitWarning := ValidateCampaignRateLimit(c.LaunchDate, c.SendByDate, totalRecipients, interval)
+	if rateLimitWarning != nil && (c.EnforceSendInterval || IsSendIntervalEnforced()) {
+		return 0, nil, fmt.Errorf("%w: %s", ErrSendIntervalTooShort, rateLimitWarning.WarningMessage)
+	}
+
+	// Auto-calculate send-by date if not provided (rate limiting)
+	// This ensures emails are spaced out safely to avoid spam filters and account lockouts.
+	// A send-by date equal to the launch date is treated as not provided.
+	if (c.SendByDate.IsZero() || c.SendByDate.Equal(c.LaunchDate)) && totalRecipients > 0 && IsAutoDripEnabled() {
+		c.SendByDate = CalculateMinimumSendByDate(c.LaunchDate, totalRecipients, interval)
+		log.Infof("Auto-calculated send-by date for campaign: %v (launch: %v, recipients: %d, interval: %v)",
+			c.SendByDate, c.LaunchDate, totalRecipients, interval)
+	}
+	c.Transport, err = c.selectTransport(totalRecipients)
+	if err != nil {
+		return 0, nil, err
+	}
+	return totalRecipients, rateLimitWarning, nil
+}
+
+// PostCampaign inserts a campaign and all associated records into the database.
+func PostCampaign(c *Campaign, uid int64) error {
+	return PostCampaignContext(context.Background(), c, uid)
+}
+
+// PostCampaignContext is PostCampaign, giving up on launching the campaign
+// with n8n once the context is done. A launch which is given up on is retried
+// by the worker.
+func PostCampaignContext(ctx context.Context, c *Campaign, uid int64) error {
+	totalRecipients, rateLimitWarning, err := c.prepare(uid)
+	if err != nil {
+		return err
+	}
+	c.RateLimitWarning = rateLimitWarning
 	// Start transaction BEFORE saving campaign to ensure atomicity
 	// If any error occurs during campaign/results creation, everything will be rolled back
 	tx := db.Begin()
 
+	// n8n campaigns are launched once the transaction commits. If the launch
+	// is interrupted, it's picked up by RetryN8NLaunches once it's due.
+	if ShouldUseN8NBatchLaunch(c) {
+		_, interval := n8nLaunchSettings()
+		c.LaunchStatus = N8NLaunchPending
+		c.LaunchAttempts = 0
+		c.NextLaunchDate = c.CreatedDate.Add(interval)
+	}
+
 	// Insert campaign into the DB (using transaction)
 	err = tx.Save(c).Error
 	if err != nil {
@@ -588,101 +1082,118 @@ func PostCampaign(c *Campaign, uid int64) error {
 		// Continue despite event save failure - this is non-critical
 	}
 
-	// Insert all the results (in same transaction)
-	resultMap := make(map[string]bool)
-	targetIDs := []int64{} // Track target IDs for last_campaign_date update
-	recipientIndex := 0
+	// Raw submitted data capture has to be a deliberate choice, so record
+	// who made it
+	err = c.auditRawCapture(tx)
+	if err != nil {
+		log.Error(err)
+		tx.Rollback()
+		return err
+	}
+
+	// Record the groups the campaign was created from, so that they're kept
+	// while the campaign needs them
 	for _, g := range c.Groups {
-		// Insert a result for each target in the group
-		for _, t := range g.Targets {
-			// Remove duplicate results - we should only
-			// send emails to unique email addresses.
-			if _, ok := resultMap[t.Email]; ok {
-				continue
-			}
-			resultMap[t.Email] = true
-			targetIDs = append(targetIDs, t.Id) // Collect target ID for date tracking
-			sendDate := c.generateSendDate(recipientIndex, totalRecipients)
-			r := &Result{
-				BaseRecipient: BaseRecipient{
-					Email:     t.Email,
-					Position:  t.Position,
-					FirstName: t.FirstName,
-					LastName:  t.LastName,
-				},
-				Status:       StatusScheduled,
-				CampaignId:   c.Id,
-				UserId:       c.UserId,
-				SendDate:     sendDate,
-				Reported:     false,
-				ModifiedDate: c.CreatedDate,
-			}
-			err = r.GenerateId(tx)
-			if err != nil {
-				log.Error(err)
-				tx.Rollback()
-				return err
-			}
-			processing := false
-			if r.SendDate.Before(c.CreatedDate) || r.SendDate.Equal(c.CreatedDate) {
-				r.Status = StatusSending
-				processing = true
+		err = tx.Save(&CampaignGroup{CampaignId: c.Id, GroupId: g.Id}).Error
+		if err != nil {
+			log.Error(err)
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// Insert a result for each recipient (in same transaction). Duplicates,
+	// excluded, fatigued and previously clicked targets have already been
+	// skipped.
+	recipients := c.resolveRecipients()
+	c.Skipped = recipients.Skipped
+	targetIDs := recipients.TargetIds // Track target IDs for last_campaign_date update
+	internalDomains := GetInternalTrackingDomains()
+	interval := c.EmailAccount.SendInterval()
+	for recipientIndex, t := range recipients.Recipients {
+		sendDate := c.generateSendDate(recipientIndex, totalRecipients, interval)
+		r := &Result{
+			BaseRecipient: BaseRecipient{
+				Email:     t.Email,
+				Position:  t.Position,
+				FirstName: t.FirstName,
+				LastName:  t.LastName,
+			},
+			Status:       StatusScheduled,
+			TemplateId:   c.selectTemplateVariant(t.BaseRecipient),
+			CampaignId:   c.Id,
+			UserId:       c.UserId,
+			SendDate:     sendDate,
+			Reported:     false,
+			Internal:     isInternalEmail(t.Email, internalDomains),
+			ModifiedDate: c.CreatedDate,
+		}
+		err = r.GenerateId(tx)
+		if err != nil {
+			log.Error(err)
+			tx.Rollback()
+			return err
+		}
+		r.PageId = c.selectPageVariant(r.RId)
+		processing := false
+		if r.SendDate.Before(c.CreatedDate) || r.SendDate.Equal(c.CreatedDate) {
+			r.Status = StatusSending
+			processing = true
+		}
+		err = tx.Save(r).Error
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"email": t.Email,
+			}).Errorf("error creating result: %v", err)
+			tx.Rollback()
+			return err
+		}
+		c.Results = append(c.Results, *r)
+
+		// Skip maillog creation for n8n campaigns (true batch sending)
+		// n8n will handle scheduling via Wait nodes and send callbacks
+		if !ShouldUseN8NBatchLaunch(c) {
+			log.WithFields(logrus.Fields{
+				"email":     r.Email,
+				"send_date": sendDate,
+			}).Debug("creating maillog")
+			m := &MailLog{
+				UserId:     c.UserId,
+				CampaignId: c.Id,
+				RId:        r.RId,
+				SendDate:   sendDate,
+				Processing: processing,
 			}
-			err = tx.Save(r).Error
+			err = tx.Save(m).Error
 			if err != nil {
 				log.WithFields(logrus.Fields{
 					"email": t.Email,
-				}).Errorf("error creating result: %v", err)
+				}).Errorf("error creating maillog entry: %v", err)
 				tx.Rollback()
 				return err
 			}
-			c.Results = append(c.Results, *r)
-
-			// Skip maillog creation for n8n campaigns (true batch sending)
-			// n8n will handle scheduling via Wait nodes and send callbacks
-			if !ShouldUseN8NBatchLaunch(c) {
-				log.WithFields(logrus.Fields{
-					"email":     r.Email,
-					"send_date": sendDate,
-				}).Debug("creating maillog")
-				m := &MailLog{
-					UserId:     c.UserId,
-					CampaignId: c.Id,
-					RId:        r.RId,
-					SendDate:   sendDate,
-					Processing: processing,
-				}
-				err = tx.Save(m).Error
-				if err != nil {
-					log.WithFields(logrus.Fields{
-						"email": t.Email,
-					}).Errorf("error creating maillog entry: %v", err)
-					tx.Rollback()
-					return err
-				}
-			}
-			recipientIndex++
 		}
 	}
 
-	// For n8n campaigns, launch the webhook BEFORE committing transaction
-	// This ensures atomicity - if n8n fails, campaign is not created
-	if ShouldUseN8NBatchLaunch(c) {
-		log.Infof("Launching n8n batch campaign %d (before commit)", c.Id)
-		err = LaunchN8NBatchCampaign(c)
-		if err != nil {
-			log.Errorf("Failed to launch n8n batch campaign %d: %v", c.Id, err)
-			tx.Rollback() // Rollback everything if n8n webhook fails
-			return fmt.Errorf("n8n webhook failed: %v", err)
-		}
-	}
-
-	// Commit the transaction (only reached if n8n succeeded or not needed)
+	// Commit the transaction before launching with n8n, so that a slow or
+	// failing webhook doesn't hold the transaction open or roll back the
+	// campaign
 	err = tx.Commit().Error
 	if err != nil {
 		return err
 	}
 
+	// Launch n8n campaigns now that they're committed. A failed launch is
+	// recorded on the campaign and retried by the worker.
+	if ShouldUseN8NBatchLaunch(c) {
+		err = c.attemptN8NLaunch(ctx)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"campaign_id": c.Id,
+			}).Errorf("Error recording n8n launch: %v", err)
+		}
+	}
+
 	// Update last_campaign_date for all targets in this campaign
 	// This helps track cybersecurity fatigue and prevent over-targeting
 	if len(targetIDs) > 0 {
@@ -701,13 +1212,11 @@ func PostCampaign(c *Campaign, uid int64) error {
 	if err == nil && len(whs) > 0 {
 		whEndPoints := []webhook.EndPoint{}
 		for _, wh := range whs {
-			whEndPoints = append(whEndPoints, webhook.EndPoint{
-				URL:    wh.URL,
-				Secret: wh.Secret,
-			})
+			whEndPoints = append(whEndPoints, wh.EndPoint())
 		}
 		webhook.SendAll(whEndPoints, event)
 	}
+	c.sendCreatedNotification(rateLimitWarning)
 
 	return nil
 }
@@ -723,6 +1232,11 @@ func DeleteCampaign(id int64) error {
 		log.Error(err)
 		return err
 	}
+	err = db.Where("campaign_id=?", id).Delete(&ResultNote{}).Error
+	if err != nil {
+		log.Error(err)
+		return err
+	}
 	err = db.Where("campaign_id=?", id).Delete(&Event{}).Error
 	if err != nil {
 		log.Error(err)
@@ -733,6 +1247,11 @@ func DeleteCampaign(id int64) error {
 		log.Error(err)
 		return err
 	}
+	err = db.Where("campaign_id=?", id).Delete(&CampaignGroup{}).Error
+	if err != nil {
+		log.Error(err)
+		return err
+	}
 	// Delete the campaign
 	err = db.Delete(&Campaign{Id: id}).Error
 	if err != nil {
@@ -761,15 +1280,126 @@ func CompleteCampaign(id int64, uid int64) error {
 	if c.Status == CampaignComplete {
 		return nil
 	}
-	// Mark the campaign as complete
+	// Mark the campaign as complete. The status is checked again as part of
+	// the update so that only one caller completes the campaign if several
+	// race to do so.
 	c.CompletedDate = time.Now().UTC()
 	c.Status = CampaignComplete
-	err = db.Model(&Campaign{}).Where("id=? and user_id=?", id, uid).
-		Select([]string{"completed_date", "status"}).UpdateColumns(&c).Error
+	query := db.Model(&Campaign{}).Where("id=? and user_id=? and status<>?", id, uid, CampaignComplete).
+		Select([]string{"completed_date", "status"}).UpdateColumns(&c)
+	if query.Error != nil {
+		log.Error(query.Error)
+		return query.Error
+	}
+	if query.RowsAffected == 0 {
+		return nil
+	}
+	notifyCampaignProgress(id)
+	c.sendCompletedWebhook()
+	return nil
+}
+
+// CampaignCompletedEventName is the event name sent in the webhook fired when
+// a campaign is completed.
+const CampaignCompletedEventName = "campaign_completed"
+
+// CampaignCompletedEvent is the webhook payload sent once when a campaign is
+// completed, containing the final statistics for the campaign.
+type CampaignCompletedEvent struct {
+	Event           string        `json:"event"`
+	CampaignId      int64         `json:"campaign_id"`
+	Name            string        `json:"name"`
+	CreatedDate     time.Time     `json:"created_date"`
+	LaunchDate      time.Time     `json:"launch_date"`
+	SendByDate      time.Time     `json:"send_by_date"`
+	CompletedDate   time.Time     `json:"completed_date"`
+	DurationSeconds int64         `json:"duration_seconds"`
+	Stats           CampaignStats `json:"stats"`
+}
+
+// sendCompletedWebhook sends the final statistics of a newly completed
+// campaign to the active webhooks.
+func (c *Campaign) sendCompletedWebhook() {
+	if conf != nil && conf.DisableCompletionWebhook {
+		return
+	}
+	stats, err := getCampaignStats(c.Id)
 	if err != nil {
-		log.Error(err)
+		log.Errorf("error getting stats for completed campaign: %v", err)
+		return
 	}
-	return err
+	whEndPoints, err := getActiveWebhookEndPoints()
+	if err != nil {
+		log.Errorf("error getting active webhooks: %v", err)
+		return
+	}
+	webhook.SendAll(whEndPoints, CampaignCompletedEvent{
+		Event:           CampaignCompletedEventName,
+		CampaignId:      c.Id,
+		Name:            c.Name,
+		CreatedDate:     c.CreatedDate,
+		LaunchDate:      c.LaunchDate,
+		SendByDate:      c.SendByDate,
+		CompletedDate:   c.CompletedDate,
+		DurationSeconds: int64(c.CompletedDate.Sub(c.LaunchDate).Seconds()),
+		Stats:           stats,
+	})
+}
+
+// CampaignCreatedNotificationName is the event name sent in the notification
+// fired when a campaign is created.
+const CampaignCreatedNotificationName = "campaign_created_notification"
+
+// CampaignCreatedNotification is the webhook payload sent when any user
+// creates a campaign, giving admins visibility into sending activity.
+type CampaignCreatedNotification struct {
+	Event            string            `json:"event"`
+	CampaignId       int64             `json:"campaign_id"`
+	Name             string            `json:"name"`
+	UserId           int64             `json:"user_id"`
+	Username         string            `json:"username"`
+	CreatedDate      time.Time         `json:"created_date"`
+	LaunchDate       time.Time         `json:"launch_date"`
+	SendByDate       time.Time         `json:"send_by_date"`
+	TargetCount      int               `json:"target_count"`
+	RateLimitWarning *RateLimitWarning `json:"rate_limit_warning"`
+}
+
+// sendCreatedNotification notifies admins that the campaign was created, if
+// enabled. The warning is the rate limit warning for the send-by date the
+// campaign was created with, if any.
+func (c *Campaign) sendCreatedNotification(warning *RateLimitWarning) {
+	if conf == nil || conf.CampaignCreatedNotify == nil || !conf.CampaignCreatedNotify.Enabled {
+		return
+	}
+	notify := conf.CampaignCreatedNotify
+	var whEndPoints []webhook.EndPoint
+	if notify.URL != "" {
+		whEndPoints = []webhook.EndPoint{{URL: notify.URL, Secret: notify.Secret}}
+	} else {
+		var err error
+		whEndPoints, err = getActiveWebhookEndPoints()
+		if err != nil {
+			log.Errorf("error getting active webhooks: %v", err)
+			return
+		}
+	}
+	n := CampaignCreatedNotification{
+		Event:            CampaignCreatedNotificationName,
+		CampaignId:       c.Id,
+		Name:             c.Name,
+		UserId:           c.UserId,
+		CreatedDate:      c.CreatedDate,
+		LaunchDate:       c.LaunchDate,
+		SendByDate:       c.SendByDate,
+		TargetCount:      len(c.Results),
+		RateLimitWarning: warning,
+	}
+	u, err := GetUser(c.UserId)
+	if err == nil {
+		n.Username = u.Username
+	}
+	webhook.SendAll(whEndPoints, n)
 }
 
 // RateLimitWarning contains information about rate limiting warnings
@@ -784,44 +1414,195 @@ type RateLimitWarning struct {
 	WarningMessage       string    `json:"warning_message"`
 }
 
-// GetDefaultSendInterval returns the default interval between emails in seconds
-// from environment variable DEFAULT_EMAIL_SEND_INTERVAL, defaulting to 120 seconds (2 minutes)
-func GetDefaultSendInterval() time.Duration {
+// DefaultSendInterval is the interval between emails used when
+// DEFAULT_EMAIL_SEND_INTERVAL isn't set to a valid value.
+const DefaultSendInterval = 120 * time.Second
+
+// Sources of the effective send interval. Email accounts with an interval
+// of their own have the account as their source.
+const (
+	SendIntervalSourceEnv     = "env"
+	SendIntervalSourceDefault = "default"
+	SendIntervalSourceAccount = "account"
+)
+
+// ErrSendIntervalTooShort is thrown when a campaign's send-by date would send
+// emails faster than the default interval while ENFORCE_EMAIL_SEND_INTERVAL
+// is set.
+var ErrSendIntervalTooShort = errors.New("Send-by date is too soon for the number of recipients")
+
+// SendIntervalSettings describes the effective rate limit applied to
+// campaigns. If DEFAULT_EMAIL_SEND_INTERVAL is set to an invalid value, the
+// default is used and the rejected value is reported in EnvValue and Warning.
+type SendIntervalSettings struct {
+	IntervalSeconds float64 `json:"interval_seconds"`
+	Source          string  `json:"source"`
+	EnvValue        string  `json:"env_value,omitempty"`
+	Warning         string  `json:"warning,omitempty"`
+	Enforced        bool    `json:"enforced"`
+	AutoDrip        bool    `json:"auto_drip"`
+	// Accounts are the intervals of the active email accounts, which
+	// campaigns sent from them are paced by
+	Accounts []AccountSendInterval `json:"accounts"`
+}
+
+// AccountSendInterval is the interval between the emails sent from an email
+// account, and whether it's the account's own or the default.
+type AccountSendInterval struct {
+	EmailAccountId  int64   `json:"email_account_id"`
+	Email           string  `json:"email"`
+	EmailType       string  `json:"email_type"`
+	IntervalSeconds float64 `json:"interval_seconds"`
+	Source          string  `json:"source"`
+}
+
+// resolveSendInterval returns the interval between emails along with where
+// it came from and, if DEFAULT_EMAIL_SEND_INTERVAL was ignored, why.
+func resolveSendInterval() (time.Duration, string, string) {
 	intervalStr := os.Getenv("DEFAULT_EMAIL_SEND_INTERVAL")
 	if intervalStr == "" {
-		return 120 * time.Second // Default: 2 minutes
+		return DefaultSendInterval, SendIntervalSourceDefault, ""
 	}
 
 	interval, err := strconv.ParseInt(intervalStr, 10, 64)
 	if err != nil {
-		log.Warnf("Invalid DEFAULT_EMAIL_SEND_INTERVAL value '%s', using default 120 seconds", intervalStr)
-		return 120 * time.Second
+		return DefaultSendInterval, SendIntervalSourceDefault,
+			fmt.Sprintf("Invalid DEFAULT_EMAIL_SEND_INTERVAL value '%s', using default 120 seconds", intervalStr)
 	}
 
 	if interval < 1 {
-		log.Warnf("DEFAULT_EMAIL_SEND_INTERVAL too small (%d), using default 120 seconds", interval)
-		return 120 * time.Second
+		return DefaultSendInterval, SendIntervalSourceDefault,
+			fmt.Sprintf("DEFAULT_EMAIL_SEND_INTERVAL too small (%d), using default 120 seconds", interval)
+	}
+
+	return time.Duration(interval) * time.Second, SendIntervalSourceEnv, ""
+}
+
+// GetDefaultSendInterval returns the default interval between emails in seconds
+// from environment variable DEFAULT_EMAIL_SEND_INTERVAL, defaulting to 120 seconds (2 minutes)
+func GetDefaultSendInterval() time.Duration {
+	interval, _, warning := resolveSendInterval()
+	if warning != "" {
+		log.Warn(warning)
 	}
+	return interval
+}
 
-	return time.Duration(interval) * time.Second
+// IsSendIntervalEnforced returns true if ENFORCE_EMAIL_SEND_INTERVAL is set,
+// in which case campaigns whose send-by date would send emails faster than
+// the default interval are rejected rather than only warned about.
+func IsSendIntervalEnforced() bool {
+	enforced, _ := strconv.ParseBool(os.Getenv("ENFORCE_EMAIL_SEND_INTERVAL"))
+	return enforced
+}
+
+// IsEmailAccountTypeEnforced returns true unless ENFORCE_EMAIL_ACCOUNT_TYPE
+// is set to false.
+func IsEmailAccountTypeEnforced() bool {
+	s := os.Getenv("ENFORCE_EMAIL_ACCOUNT_TYPE")
+	if s == "" {
+		return true
+	}
+	enforced, err := strconv.ParseBool(s)
+	if err != nil {
+		log.Warnf("Invalid ENFORCE_EMAIL_ACCOUNT_TYPE value '%s', using default true", s)
+		return true
+	}
+	return enforced
+}
+
+// checkEmailAccountType confirms that the campaign's email account is of the
+// campaign's email type, when both are given. Mismatches are only logged if
+// ENFORCE_EMAIL_ACCOUNT_TYPE is set to false.
+func (c *Campaign) checkEmailAccountType() error {
+	if c.EmailType == "" || c.EmailAccount.EmailType == "" {
+		return nil
+	}
+	if strings.EqualFold(c.EmailType, c.EmailAccount.EmailType) {
+		return nil
+	}
+	log.WithFields(logrus.Fields{
+		"campaign":           c.Name,
+		"email_account":      c.EmailAccount.Email,
+		"email_account_type": c.EmailAccount.EmailType,
+		"email_type":         c.EmailType,
+	}).Warn(ErrEmailAccountTypeMismatch)
+	if IsEmailAccountTypeEnforced() {
+		return fmt.Errorf("%w: %s is a %s account, not %s", ErrEmailAccountTypeMismatch,
+			c.EmailAccount.Email, c.EmailAccount.EmailType, c.EmailType)
+	}
+	return nil
+}
+
+// IsAutoDripEnabled returns true unless AUTO_DRIP_CAMPAIGNS is set to false.
+// While it's enabled, campaigns without a send-by date, including those
+// launched immediately, send at the default interval rather than all at once.
+func IsAutoDripEnabled() bool {
+	s := os.Getenv("AUTO_DRIP_CAMPAIGNS")
+	if s == "" {
+		return true
+	}
+	enabled, err := strconv.ParseBool(s)
+	if err != nil {
+		log.Warnf("Invalid AUTO_DRIP_CAMPAIGNS value '%s', using default true", s)
+		return true
+	}
+	return enabled
+}
+
+// GetSendIntervalSettings returns the effective send interval and how it was
+// resolved, so that a misconfigured DEFAULT_EMAIL_SEND_INTERVAL is visible,
+// along with the interval of each active email account.
+func GetSendIntervalSettings() (SendIntervalSettings, error) {
+	interval, source, warning := resolveSendInterval()
+	s := SendIntervalSettings{
+		IntervalSeconds: interval.Seconds(),
+		Source:          source,
+		Warning:         warning,
+		Enforced:        IsSendIntervalEnforced(),
+		AutoDrip:        IsAutoDripEnabled(),
+		Accounts:        []AccountSendInterval{},
+	}
+	if warning != "" {
+		s.EnvValue = os.Getenv("DEFAULT_EMAIL_SEND_INTERVAL")
+	}
+	eas := []EmailAccount{}
+	err := db.Where("is_active = ?", true).Order("id asc").Find(&eas).Error
+	if err != nil {
+		return s, err
+	}
+	for _, ea := range eas {
+		a := AccountSendInterval{
+			EmailAccountId:  ea.Id,
+			Email:           ea.Email,
+			EmailType:       ea.EmailType,
+			IntervalSeconds: interval.Seconds(),
+			Source:          source,
+		}
+		if ea.SendIntervalSeconds > 0 {
+			a.IntervalSeconds = float64(ea.SendIntervalSeconds)
+			a.Source = SendIntervalSourceAccount
+		}
+		s.Accounts = append(s.Accounts, a)
+	}
+	return s, nil
 }
 
-// CalculateMinimumSendByDate calculates the minimum send-by date based on launch date and recipient count
-func CalculateMinimumSendByDate(launchDate time.Time, recipientCount int) time.Time {
-	interval := GetDefaultSendInterval()
+// CalculateMinimumSendByDate calculates the minimum send-by date based on launch date, recipient count
+// and the interval between emails
+func CalculateMinimumSendByDate(launchDate time.Time, recipientCount int, interval time.Duration) time.Time {
 	totalDuration := time.Duration(recipientCount) * interval
 	return launchDate.Add(totalDuration)
 }
 
-// ValidateCampaignRateLimit checks if a campaign's send-by date is too aggressive
+// ValidateCampaignRateLimit checks if a campaign's send-by date is too aggressive for the given interval
 // Returns a RateLimitWarning with details if the rate is too fast
-func ValidateCampaignRateLimit(launchDate, sendByDate time.Time, recipientCount int) *RateLimitWarning {
+func ValidateCampaignRateLimit(launchDate, sendByDate time.Time, recipientCount int, minimumInterval time.Duration) *RateLimitWarning {
 	if recipientCount == 0 {
 		return nil // No recipients, no warning needed
 	}
 
-	minimumInterval := GetDefaultSendInterval()
-	minimumSendByDate := CalculateMinimumSendByDate(launchDate, recipientCount)
+	minimumSendByDate := CalculateMinimumSendByDate(launchDate, recipientCount, minimumInterval)
 
 	// If send-by date is zero (not provided), it's not aggressive - will be auto-set
 	if sendByDate.IsZero() {
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// GetAutoArchiveAge returns how long after completing a campaign is archived
+// automatically, configured in days by AUTO_ARCHIVE_COMPLETED_DAYS. Automatic
+// archival is disabled unless a positive number of days is set.
+func GetAutoArchiveAge() time.Duration {
+	s := os.Getenv("AUTO_ARCHIVE_COMPLETED_DAYS")
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		log.Warnf("Invalid AUTO_ARCHIVE_COMPLETED_DAYS value '%s', automatic archival is disabled", s)
+		return 0
+	}
+	return time.Duration(v) * 24 * time.Hour
+}
+
+// ArchiveCompletedCampaigns archives the campaigns which were completed
+// before the cutoff, returning the number archived. Campaigns which haven't
+// completed or have already been archived are left alone.
+func ArchiveCompletedCampaigns(cutoff time.Time) (int, error) {
+	cs := []Campaign{}
+	err := db.Select("id, user_id, name, completed_date").
+		Where("status = ? AND archived = ? AND completed_date < ?", CampaignComplete, false, cutoff).
+		Find(&cs).Error
+	if err != nil {
+		return 0, err
+	}
+	archived := 0
+	now := time.Now().UTC()
+	for _, c := range cs {
+		// The status is checked again in case the campaign changed since it
+		// was selected
+		update := db.Model(&Campaign{}).
+			Where("id = ? AND status = ? AND archived = ?", c.Id, CampaignComplete, false).
+			Updates(map[string]interface{}{"archived": true, "archived_date": now})
+		if update.Error != nil {
+			log.Errorf("Error archiving campaign %d: %v", c.Id, update.Error)
+			continue
+		}
+		if update.RowsAffected == 0 {
+			continue
+		}
+		archived++
+		log.WithFields(logrus.Fields{
+			"campaign_id":    c.Id,
+			"user_id":        c.UserId,
+			"name":           c.Name,
+			"completed_date": c.CompletedDate,
+		}).Info("Automatically archived completed campaign")
+	}
+	return archived, nil
+}
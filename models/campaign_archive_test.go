@@ -0,0 +1,46 @@
+package models
+
+import (
+	"os"
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestGetAutoArchiveAge(c *check.C) {
+	defer os.Unsetenv("AUTO_ARCHIVE_COMPLETED_DAYS")
+	c.Assert(GetAutoArchiveAge(), check.Equals, time.Duration(0))
+	os.Setenv("AUTO_ARCHIVE_COMPLETED_DAYS", "30")
+	c.Assert(GetAutoArchiveAge(), check.Equals, 30*24*time.Hour)
+	os.Setenv("AUTO_ARCHIVE_COMPLETED_DAYS", "soon")
+	c.Assert(GetAutoArchiveAge(), check.Equals, time.Duration(0))
+}
+
+func (s *ModelsSuite) TestArchiveCompletedCampaigns(ch *check.C) {
+	now := time.Now().UTC()
+	old := s.createCampaign(ch)
+	ch.Assert(CompleteCampaign(old.Id, old.UserId), check.Equals, nil)
+	err := db.Model(&Campaign{}).Where("id=?", old.Id).
+		UpdateColumn("completed_date", now.Add(-100*24*time.Hour)).Error
+	ch.Assert(err, check.Equals, nil)
+
+	recent := s.createCampaign(ch)
+	ch.Assert(CompleteCampaign(recent.Id, recent.UserId), check.Equals, nil)
+
+	running := s.createCampaign(ch)
+
+	archived, err := ArchiveCompletedCampaigns(now.Add(-90 * 24 * time.Hour))
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(archived, check.Equals, 1)
+
+	for id, expected := range map[int64]bool{old.Id: true, recent.Id: false, running.Id: false} {
+		c := Campaign{}
+		ch.Assert(db.Where("id=?", id).First(&c).Error, check.Equals, nil)
+		ch.Assert(c.Archived, check.Equals, expected)
+	}
+
+	// Campaigns which are already archived aren't archived again
+	archived, err = ArchiveCompletedCampaigns(time.Now().UTC().Add(time.Minute))
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(archived, check.Equals, 1)
+}
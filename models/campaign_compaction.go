@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCampaignNotComplete is thrown when attempting to compact a campaign
+// which is still running.
+var ErrCampaignNotComplete = errors.New("Only completed campaigns can be compacted")
+
+// ErrCampaignAlreadyCompacted is thrown when attempting to compact a campaign
+// which has already been compacted.
+var ErrCampaignAlreadyCompacted = errors.New("Campaign has already been compacted")
+
+// CampaignStatsSnapshot holds the statistics of a campaign captured when its
+// detailed results and events were purged.
+type CampaignStatsSnapshot struct {
+	CampaignId int64 `json:"campaign_id" gorm:"primary_key;auto_increment:false"`
+	CampaignStats
+	CompactedDate time.Time `json:"compacted_date"`
+}
+
+// getCampaignStatsSnapshot returns the stored statistics snapshot for the
+// campaign, or gorm.ErrRecordNotFound if it hasn't been compacted.
+func getCampaignStatsSnapshot(cid int64) (CampaignStatsSnapshot, error) {
+	s := CampaignStatsSnapshot{}
+	err := db.Where("campaign_id=?", cid).First(&s).Error
+	return s, err
+}
+
+// CompactCampaign snapshots the statistics of a completed campaign, then
+// deletes its results, events and any remaining maillogs. The campaign itself
+// is kept and reports the snapshot statistics from then on.
+func CompactCampaign(id int64, uid int64) (CampaignStatsSnapshot, error) {
+	snapshot := CampaignStatsSnapshot{CampaignId: id}
+	c := Campaign{}
+	err := db.Where("id=? and user_id=?", id, uid).Find(&c).Error
+	if err != nil {
+		return snapshot, err
+	}
+	if c.Compacted {
+		return snapshot, ErrCampaignAlreadyCompacted
+	}
+	if c.Status != CampaignComplete {
+		return snapshot, ErrCampaignNotComplete
+	}
+	snapshot.CampaignStats, err = getCampaignStats(c.Id)
+	if err != nil {
+		log.Error(err)
+		return snapshot, err
+	}
+	snapshot.CompactedDate = time.Now().UTC()
+	log.WithFields(logrus.Fields{
+		"campaign_id": id,
+	}).Info("Compacting campaign")
+	tx := db.Begin()
+	err = tx.Create(&snapshot).Error
+	if err != nil {
+		tx.Rollback()
+		log.Error(err)
+		return snapshot, err
+	}
+	for _, model := range []interface{}{&Result{}, &Event{}, &MailLog{}} {
+		err = tx.Where("campaign_id=?", c.Id).Delete(model).Error
+		if err != nil {
+			tx.Rollback()
+			log.Error(err)
+			return snapshot, err
+		}
+	}
+	err = tx.Model(&Campaign{}).Where("id=?", c.Id).UpdateColumn("compacted", true).Error
+	if err != nil {
+		tx.Rollback()
+		log.Error(err)
+		return snapshot, err
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		log.Error(err)
+	}
+	return snapshot, err
+}
+
+// getCompactedCampaignStats returns the snapshot statistics for a compacted
+// campaign. A missing snapshot is reported as empty statistics rather than an
+// error.
+func getCompactedCampaignStats(cid int64) (CampaignStats, error) {
+	s, err := getCampaignStatsSnapshot(cid)
+	if err == gorm.ErrRecordNotFound {
+		return CampaignStats{}, nil
+	}
+	return s.CampaignStats, err
+}
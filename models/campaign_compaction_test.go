@@ -0,0 +1,107 @@
+package models
+
+import (
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestCompactCampaignRequiresCompletion(c *check.C) {
+	campaign := s.createCampaign(c)
+	_, err := CompactCampaign(campaign.Id, campaign.UserId)
+	c.Assert(err, check.Equals, ErrCampaignNotComplete)
+}
+
+func (s *ModelsSuite) TestCompactCampaignKeepsStats(c *check.C) {
+	campaign := s.createCampaign(c)
+	statuses := []string{EventSent, EventOpened, EventClicked, EventDataSubmit}
+	for i, r := range campaign.Results {
+		err := db.Model(&Result{}).Where("id=?", r.Id).Update("status", statuses[i]).Error
+		c.Assert(err, check.Equals, nil)
+	}
+	err := db.Model(&Result{}).Where("id=?", campaign.Results[0].Id).Update("reported", true).Error
+	c.Assert(err, check.Equals, nil)
+	c.Assert(CompleteCampaign(campaign.Id, campaign.UserId), check.Equals, nil)
+
+	before, err := GetCampaignSummary(campaign.Id, campaign.UserId)
+	c.Assert(err, check.Equals, nil)
+
+	snapshot, err := CompactCampaign(campaign.Id, campaign.UserId)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(snapshot.CampaignStats, check.DeepEquals, before.Stats)
+
+	after, err := GetCampaignSummary(campaign.Id, campaign.UserId)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(after.Compacted, check.Equals, true)
+	c.Assert(after.Stats, check.DeepEquals, before.Stats)
+
+	summaries, err := GetCampaignSummaries(campaign.UserId)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(summaries.Campaigns[0].Stats, check.DeepEquals, before.Stats)
+
+	results, err := GetCampaignResults(campaign.Id, campaign.UserId)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(len(results.Results), check.Equals, 0)
+	c.Assert(len(results.Events), check.Equals, 0)
+
+	_, err = CompactCampaign(campaign.Id, campaign.UserId)
+	c.Assert(err, check.Equals, ErrCampaignAlreadyCompacted)
+}
+
+func (s *ModelsSuite) TestRecomputeCampaignStatsFixesSnapshot(c *check.C) {
+	campaign := s.createCampaign(c)
+	statuses := []string{EventSent, EventOpened, EventClicked, EventDataSubmit}
+	for i, r := range campaign.Results {
+		err := db.Model(&Result{}).Where("id=?", r.Id).Update("status", statuses[i]).Error
+		c.Assert(err, check.Equals, nil)
+	}
+	c.Assert(CompleteCampaign(campaign.Id, campaign.UserId), check.Equals, nil)
+	expected, err := getCampaignStats(campaign.Id)
+	c.Assert(err, check.Equals, nil)
+
+	// Store a snapshot which has drifted from the results
+	corrupted := CampaignStats{Total: 1, EmailsSent: 7, Error: 3}
+	err = db.Create(&CampaignStatsSnapshot{CampaignId: campaign.Id, CampaignStats: corrupted}).Error
+	c.Assert(err, check.Equals, nil)
+	err = db.Model(&Campaign{}).Where("id=?", campaign.Id).UpdateColumn("compacted", true).Error
+	c.Assert(err, check.Equals, nil)
+
+	rc, err := RecomputeCampaignStats(campaign.Id)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(rc.Before, check.DeepEquals, corrupted)
+	c.Assert(rc.After, check.DeepEquals, expected)
+	c.Assert(rc.SnapshotUpdated, check.Equals, true)
+
+	summary, err := GetCampaignSummary(campaign.Id, campaign.UserId)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(summary.Stats, check.DeepEquals, expected)
+}
+
+func (s *ModelsSuite) TestRecomputeCampaignStatsFromEvents(c *check.C) {
+	campaign := s.createCampaign(c)
+	r := campaign.Results[0]
+	err := db.Model(&Result{}).Where("id=?", r.Id).Update("status", EventSent).Error
+	c.Assert(err, check.Equals, nil)
+	// Backfill events which weren't reflected in the result
+	for _, message := range []string{EventClicked, EventReported} {
+		err = db.Save(&Event{CampaignId: campaign.Id, Email: r.Email, Message: message}).Error
+		c.Assert(err, check.Equals, nil)
+	}
+
+	rc, err := RecomputeCampaignStats(campaign.Id)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(rc.Before.ClickedLink, check.Equals, int64(0))
+	c.Assert(rc.After.ClickedLink, check.Equals, int64(1))
+	c.Assert(rc.After.OpenedEmail, check.Equals, int64(1))
+	c.Assert(rc.After.EmailsSent, check.Equals, int64(1))
+	c.Assert(rc.After.EmailReported, check.Equals, int64(1))
+	c.Assert(rc.After.Total, check.Equals, int64(len(campaign.Results)))
+	c.Assert(rc.SnapshotUpdated, check.Equals, false)
+}
+
+func (s *ModelsSuite) TestRecomputeCampaignStatsPurged(c *check.C) {
+	campaign := s.createCampaign(c)
+	c.Assert(CompleteCampaign(campaign.Id, campaign.UserId), check.Equals, nil)
+	_, err := CompactCampaign(campaign.Id, campaign.UserId)
+	c.Assert(err, check.Equals, nil)
+	_, err = RecomputeCampaignStats(campaign.Id)
+	c.Assert(err, check.Equals, ErrCampaignResultsPurged)
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestCompleteCampaignSendsSingleWebhook(c *check.C) {
+	received := make(chan CampaignCompletedEvent, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, check.Equals, nil)
+		e := CampaignCompletedEvent{}
+		// Per-event webhooks are also sent to this endpoint, so only
+		// completion events are recorded
+		if json.Unmarshal(body, &e) == nil && e.Event == CampaignCompletedEventName {
+			received <- e
+		}
+	}))
+	defer ts.Close()
+
+	campaign := s.createCampaign(c)
+	wh := Webhook{Name: "Reporting", URL: ts.URL, Secret: "secret", IsActive: true}
+	c.Assert(PostWebhook(&wh), check.Equals, nil)
+
+	statuses := []string{EventSent, EventOpened, EventClicked, EventDataSubmit}
+	for i, r := range campaign.Results {
+		err := db.Model(&Result{}).Where("id=?", r.Id).Update("status", statuses[i]).Error
+		c.Assert(err, check.Equals, nil)
+	}
+	expected, err := getCampaignStats(campaign.Id)
+	c.Assert(err, check.Equals, nil)
+
+	c.Assert(CompleteCampaign(campaign.Id, campaign.UserId), check.Equals, nil)
+	// Completing the campaign again must not send another webhook
+	c.Assert(CompleteCampaign(campaign.Id, campaign.UserId), check.Equals, nil)
+
+	select {
+	case e := <-received:
+		c.Assert(e.CampaignId, check.Equals, campaign.Id)
+		c.Assert(e.Name, check.Equals, campaign.Name)
+		c.Assert(e.Stats, check.DeepEquals, expected)
+		c.Assert(e.CompletedDate.IsZero(), check.Equals, false)
+	case <-time.After(5 * time.Second):
+		c.Fatalf("timed out waiting for the completion webhook")
+	}
+	select {
+	case <-received:
+		c.Fatalf("received more than one completion webhook")
+	case <-time.After(500 * time.Millisecond):
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"os"
+
+	check "gopkg.in/check.v1"
+)
+
+// createTypedCampaignDependencies returns a campaign sent from a support
+// account with the given email type.
+func (s *ModelsSuite) createTypedCampaignDependencies(ch *check.C, emailType string) Campaign {
+	c := s.createCampaignDependencies(ch)
+	ea := EmailAccount{Email: "help@example.com", EmailType: "support", IsActive: true}
+	ch.Assert(PostEmailAccount(&ea), check.Equals, nil)
+	c.EmailAccount = EmailAccount{Email: ea.Email}
+	c.EmailType = emailType
+	return c
+}
+
+func (s *ModelsSuite) TestPostCampaignEmailAccountTypeMatches(ch *check.C) {
+	c := s.createTypedCampaignDependencies(ch, "support")
+	ch.Assert(PostCampaign(&c, 1), check.Equals, nil)
+	ch.Assert(c.EmailAccount.EmailType, check.Equals, "support")
+}
+
+func (s *ModelsSuite) TestPostCampaignEmailAccountTypeMismatch(ch *check.C) {
+	c := s.createTypedCampaignDependencies(ch, "marketing")
+	marketing := EmailAccount{Email: "news@example.com", EmailType: "marketing", IsActive: true}
+	ch.Assert(PostEmailAccount(&marketing), check.Equals, nil)
+	err := PostCampaign(&c, 1)
+	ch.Assert(errors.Is(err, ErrEmailAccountTypeMismatch), check.Equals, true)
+
+	var count int
+	db.Model(&Campaign{}).Count(&count)
+	ch.Assert(count, check.Equals, 0)
+}
+
+func (s *ModelsSuite) TestPostCampaignEmailAccountTypeNotEnforced(ch *check.C) {
+	os.Setenv("ENFORCE_EMAIL_ACCOUNT_TYPE", "false")
+	defer os.Unsetenv("ENFORCE_EMAIL_ACCOUNT_TYPE")
+
+	c := s.createTypedCampaignDependencies(ch, "marketing")
+	marketing := EmailAccount{Email: "news@example.com", EmailType: "marketing", IsActive: true}
+	ch.Assert(PostEmailAccount(&marketing), check.Equals, nil)
+	ch.Assert(PostCampaign(&c, 1), check.Equals, nil)
+	ch.Assert(c.EmailAccount.Email, check.Equals, "help@example.com")
+}
+
+func (s *ModelsSuite) TestValidateEmailTypeHasActiveAccount(ch *check.C) {
+	support := EmailAccount{Email: "help@example.com", EmailType: "support", IsActive: true}
+	ch.Assert(PostEmailAccount(&support), check.Equals, nil)
+	ch.Assert(ValidateEmailTypeHasActiveAccount("support"), check.Equals, nil)
+
+	// Inactive accounts can't be sent from
+	marketing := EmailAccount{Email: "news@example.com", EmailType: "marketing", IsActive: true}
+	ch.Assert(PostEmailAccount(&marketing), check.Equals, nil)
+	ch.Assert(db.Model(&marketing).UpdateColumn("is_active", false).Error, check.Equals, nil)
+	err := ValidateEmailTypeHasActiveAccount("marketing")
+	ch.Assert(errors.Is(err, ErrNoActiveEmailAccount), check.Equals, true)
+
+	ch.Assert(ValidateEmailTypeHasActiveAccount("notification"), check.NotNil)
+	ch.Assert(ValidateEmailTypeHasActiveAccount("not-a-type"), check.NotNil)
+	ch.Assert(ValidateEmailTypeHasActiveAccount(""), check.Equals, ErrEmailTypeNotSpecified)
+}
+
+func (s *ModelsSuite) TestPostCampaignEmailTypeWithoutAccount(ch *check.C) {
+	c := s.createCampaignDependencies(ch)
+	c.EmailType = "marketing"
+	err := PostCampaign(&c, 1)
+	ch.Assert(errors.Is(err, ErrNoActiveEmailAccount), check.Equals, true)
+
+	// The dry run catches it too
+	_, err = ValidateCampaign(&c, 1)
+	ch.Assert(errors.Is(err, ErrNoActiveEmailAccount), check.Equals, true)
+}
@@ -0,0 +1,203 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNoImportedResults indicates that a campaign import didn't include any
+// recipients
+var ErrNoImportedResults = errors.New("No results specified")
+
+// ErrInvalidImportedStatus indicates that an imported result has a status
+// which isn't one of the known result statuses
+var ErrInvalidImportedStatus = errors.New("Result status must be one of: Email Sent, Email Opened, Clicked Link, Submitted Data, Error")
+
+// ErrDuplicateImportedResult indicates that a recipient appears more than once
+// in a campaign import
+var ErrDuplicateImportedResult = errors.New("Recipient appears more than once")
+
+// importedEventProgression is the timeline of events implied by each
+// imported status. A recipient who submitted data must also have been sent,
+// opened and clicked the email.
+var importedEventProgression = map[string][]string{
+	EventSent:       {EventSent},
+	EventOpened:     {EventSent, EventOpened},
+	EventClicked:    {EventSent, EventOpened, EventClicked},
+	EventDataSubmit: {EventSent, EventOpened, EventClicked, EventDataSubmit},
+	Error:           {EventSendingError},
+}
+
+// ImportedResult is the known outcome for a single recipient of a campaign
+// run in another tool.
+type ImportedResult struct {
+	BaseRecipient
+	Status       string    `json:"status"`
+	Reported     bool      `json:"reported"`
+	SendDate     time.Time `json:"send_date"`
+	ModifiedDate time.Time `json:"modified_date"`
+}
+
+// CampaignImport describes a previously run campaign and the outcome for each
+// of its recipients. Imported campaigns are created as completed, so nothing
+// is sent.
+type CampaignImport struct {
+	Name          string           `json:"name"`
+	LaunchDate    time.Time        `json:"launch_date"`
+	CompletedDate time.Time        `json:"completed_date"`
+	Results       []ImportedResult `json:"results"`
+}
+
+// importedEventDetails marks events which were synthesized from an import
+// rather than recorded by Gophish.
+type importedEventDetails struct {
+	Imported bool `json:"imported"`
+}
+
+// Validate checks to make sure the import can be turned into a campaign.
+func (ci *CampaignImport) Validate() error {
+	if ci.Name == "" {
+		return ErrCampaignNameNotSpecified
+	}
+	if err := validateName("campaign", ci.Name); err != nil {
+		return err
+	}
+	if len(ci.Results) == 0 {
+		return ErrNoImportedResults
+	}
+	seen := make(map[string]bool)
+	for _, r := range ci.Results {
+		if r.Email == "" {
+			return ErrEmailNotSpecified
+		}
+		email := strings.ToLower(strings.TrimSpace(r.Email))
+		if seen[email] {
+			return fmt.Errorf("%s: %w", r.Email, ErrDuplicateImportedResult)
+		}
+		seen[email] = true
+		if _, ok := importedEventProgression[r.Status]; !ok {
+			return fmt.Errorf("%s: %w", r.Email, ErrInvalidImportedStatus)
+		}
+	}
+	return nil
+}
+
+// ImportCampaignResults creates a completed campaign pre-seeded with the given
+// results, along with a synthetic timeline of events matching each result's
+// status so that statistics and reports reflect the imported outcomes.
+func ImportCampaignResults(ci *CampaignImport, uid int64) (Campaign, error) {
+	c := Campaign{}
+	err := ci.Validate()
+	if err != nil {
+		return c, err
+	}
+	now := time.Now().UTC()
+	c = Campaign{
+		Name:          ci.Name,
+		UserId:        uid,
+		CreatedDate:   now,
+		LaunchDate:    ci.LaunchDate.UTC(),
+		CompletedDate: ci.CompletedDate.UTC(),
+		Status:        CampaignComplete,
+	}
+	if c.LaunchDate.IsZero() {
+		c.LaunchDate = now
+	}
+	if c.CompletedDate.IsZero() {
+		c.CompletedDate = now
+	}
+	details, err := json.Marshal(importedEventDetails{Imported: true})
+	if err != nil {
+		return c, err
+	}
+	tx := db.Begin()
+	err = tx.Save(&c).Error
+	if err != nil {
+		log.Error(err)
+		tx.Rollback()
+		return c, err
+	}
+	err = tx.Save(&Event{CampaignId: c.Id, Time: c.LaunchDate, Message: "Campaign Created"}).Error
+	if err != nil {
+		log.Error(err)
+		tx.Rollback()
+		return c, err
+	}
+	internalDomains := GetInternalTrackingDomains()
+	for _, ir := range ci.Results {
+		sendDate := ir.SendDate.UTC()
+		if sendDate.IsZero() {
+			sendDate = c.LaunchDate
+		}
+		modifiedDate := ir.ModifiedDate.UTC()
+		if modifiedDate.IsZero() {
+			modifiedDate = sendDate
+		}
+		r := &Result{
+			BaseRecipient: ir.BaseRecipient,
+			Status:        ir.Status,
+			CampaignId:    c.Id,
+			UserId:        uid,
+			SendDate:      sendDate,
+			Reported:      ir.Reported,
+			Internal:      isInternalEmail(ir.Email, internalDomains),
+			ModifiedDate:  modifiedDate,
+		}
+		err = r.GenerateId(tx)
+		if err != nil {
+			log.Error(err)
+			tx.Rollback()
+			return c, err
+		}
+		err = tx.Save(r).Error
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"email": r.Email,
+			}).Errorf("error importing result: %v", err)
+			tx.Rollback()
+			return c, err
+		}
+		events := importedEventProgression[ir.Status]
+		if ir.Reported {
+			events = append(events[:len(events):len(events)], EventReported)
+		}
+		for i, message := range events {
+			// The send is recorded at the send date and everything
+			// afterwards at the time of the last known activity.
+			eventTime := modifiedDate
+			if i == 0 {
+				eventTime = sendDate
+			}
+			e := &Event{
+				CampaignId: c.Id,
+				Email:      r.Email,
+				Time:       eventTime,
+				Message:    message,
+				Details:    string(details),
+			}
+			err = tx.Save(e).Error
+			if err != nil {
+				log.Error(err)
+				tx.Rollback()
+				return c, err
+			}
+		}
+		c.Results = append(c.Results, *r)
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		log.Error(err)
+		return c, err
+	}
+	log.WithFields(logrus.Fields{
+		"campaign_id": c.Id,
+		"results":     len(c.Results),
+	}).Info("Imported campaign results")
+	return c, nil
+}
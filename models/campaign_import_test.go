@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func importedResult(email, status string) ImportedResult {
+	return ImportedResult{BaseRecipient: BaseRecipient{Email: email}, Status: status}
+}
+
+func (s *ModelsSuite) TestCampaignImportValidate(ch *check.C) {
+	ci := CampaignImport{Results: []ImportedResult{importedResult("a@example.com", EventSent)}}
+	ch.Assert(ci.Validate(), check.Equals, ErrCampaignNameNotSpecified)
+
+	ci = CampaignImport{Name: "Imported"}
+	ch.Assert(ci.Validate(), check.Equals, ErrNoImportedResults)
+
+	ci.Results = []ImportedResult{importedResult("", EventSent)}
+	ch.Assert(ci.Validate(), check.Equals, ErrEmailNotSpecified)
+
+	ci.Results = []ImportedResult{importedResult("a@example.com", StatusScheduled)}
+	ch.Assert(ci.Validate(), check.ErrorMatches, ".*Result status must be one of.*")
+
+	ci.Results = []ImportedResult{
+		importedResult("a@example.com", EventSent),
+		importedResult("A@example.com", EventOpened),
+	}
+	ch.Assert(ci.Validate(), check.ErrorMatches, ".*appears more than once")
+
+	ci.Results = ci.Results[:1]
+	ch.Assert(ci.Validate(), check.Equals, nil)
+}
+
+func (s *ModelsSuite) TestImportCampaignResults(ch *check.C) {
+	launch := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	reported := importedResult("reporter@example.com", EventOpened)
+	reported.Reported = true
+	ci := CampaignImport{
+		Name:          "Imported Campaign",
+		LaunchDate:    launch,
+		CompletedDate: launch.Add(48 * time.Hour),
+		Results: []ImportedResult{
+			importedResult("sent@example.com", EventSent),
+			reported,
+			importedResult("clicked@example.com", EventClicked),
+			importedResult("submitted@example.com", EventDataSubmit),
+			importedResult("error@example.com", Error),
+		},
+	}
+	c, err := ImportCampaignResults(&ci, 1)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(c.Status, check.Equals, CampaignComplete)
+	ch.Assert(len(c.Results), check.Equals, 5)
+
+	stats, err := getCampaignStats(c.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(stats.Total, check.Equals, int64(5))
+	ch.Assert(stats.EmailsSent, check.Equals, int64(4))
+	ch.Assert(stats.OpenedEmail, check.Equals, int64(3))
+	ch.Assert(stats.ClickedLink, check.Equals, int64(2))
+	ch.Assert(stats.SubmittedData, check.Equals, int64(1))
+	ch.Assert(stats.EmailReported, check.Equals, int64(1))
+	ch.Assert(stats.Error, check.Equals, int64(1))
+
+	// The timeline should include the creation event, each implied step of
+	// every result, and the report.
+	events := []Event{}
+	err = db.Where("campaign_id=?", c.Id).Find(&events).Error
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(events), check.Equals, 1+1+3+3+4+1)
+
+	// Nothing should be queued for sending
+	ms, err := GetMailLogsByCampaign(c.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(ms), check.Equals, 0)
+}
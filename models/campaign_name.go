@@ -0,0 +1,74 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gophish/gophish/config"
+)
+
+// ErrCampaignNameExists is thrown when unique campaign names are enforced and
+// the user already has a campaign with the same name
+var ErrCampaignNameExists = errors.New("A campaign with this name already exists")
+
+// getUniqueCampaignNames returns how duplicate campaign names are handled,
+// allowing them if the package config hasn't been set up.
+func getUniqueCampaignNames() string {
+	if conf == nil {
+		return ""
+	}
+	return conf.GetUniqueCampaignNames()
+}
+
+// containsName returns true if the name is in the list, ignoring case.
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// suffixName returns the first of "name (2)", "name (3)" and so on which
+// isn't in the list of existing names, ignoring case. The name is shortened
+// if needed so that the suffixed name fits within max characters.
+func suffixName(name string, existing []string, max int) string {
+	for i := 2; ; i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		base := name
+		if n := max - utf8.RuneCountInString(suffix); utf8.RuneCountInString(base) > n && n > 0 {
+			base = strings.TrimSpace(string([]rune(base)[:n]))
+		}
+		candidate := base + suffix
+		if !containsName(existing, candidate) {
+			return candidate
+		}
+	}
+}
+
+// ensureUniqueName applies the configured handling of duplicate campaign
+// names. If the user already has a campaign with the same name, ignoring
+// case, the campaign is either rejected with ErrCampaignNameExists or
+// renamed with a numbered suffix.
+func (c *Campaign) ensureUniqueName(uid int64) error {
+	mode := getUniqueCampaignNames()
+	if mode == "" {
+		return nil
+	}
+	names := []string{}
+	err := db.Model(&Campaign{}).Where("user_id = ?", uid).Pluck("name", &names).Error
+	if err != nil {
+		return err
+	}
+	if !containsName(names, c.Name) {
+		return nil
+	}
+	if mode == config.UniqueNamesReject {
+		return fmt.Errorf("%w: %s", ErrCampaignNameExists, c.Name)
+	}
+	c.Name = suffixName(c.Name, names, getMaxNameLength())
+	return nil
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gophish/gophish/config"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestSuffixName(ch *check.C) {
+	existing := []string{"Quarterly Test", "quarterly test (2)"}
+	ch.Assert(suffixName("Quarterly Test", existing, 255), check.Equals, "Quarterly Test (3)")
+	ch.Assert(suffixName("Other", existing, 255), check.Equals, "Other (2)")
+	// Suffixed names are kept within the maximum length
+	ch.Assert(suffixName("abcdefghij", nil, 10), check.Equals, "abcdef (2)")
+}
+
+func (s *ModelsSuite) TestDuplicateCampaignNameAllowedByDefault(ch *check.C) {
+	c := s.createCampaign(ch)
+	duplicate := s.createCampaignDependencies(ch)
+	duplicate.Name = strings.ToUpper(c.Name)
+	ch.Assert(PostCampaign(&duplicate, c.UserId), check.Equals, nil)
+	ch.Assert(duplicate.Name, check.Equals, strings.ToUpper(c.Name))
+}
+
+func (s *ModelsSuite) TestDuplicateCampaignNameRejected(ch *check.C) {
+	original := conf.UniqueCampaignNames
+	defer func() { conf.UniqueCampaignNames = original }()
+	conf.UniqueCampaignNames = config.UniqueNamesReject
+
+	c := s.createCampaign(ch)
+	duplicate := s.createCampaignDependencies(ch)
+	duplicate.Name = strings.ToUpper(c.Name)
+	err := PostCampaign(&duplicate, c.UserId)
+	ch.Assert(errors.Is(err, ErrCampaignNameExists), check.Equals, true)
+	ch.Assert(duplicate.Id, check.Equals, int64(0))
+
+	// Names only need to be unique for each user
+	other := s.createCampaignDependencies(ch)
+	other.Name = c.Name
+	err = PostCampaign(&other, 2)
+	ch.Assert(errors.Is(err, ErrCampaignNameExists), check.Equals, false)
+}
+
+func (s *ModelsSuite) TestDuplicateCampaignNameSuffixed(ch *check.C) {
+	original := conf.UniqueCampaignNames
+	defer func() { conf.UniqueCampaignNames = original }()
+	conf.UniqueCampaignNames = config.UniqueNamesSuffix
+
+	c := s.createCampaign(ch)
+	second := s.createCampaignDependencies(ch)
+	ch.Assert(PostCampaign(&second, c.UserId), check.Equals, nil)
+	ch.Assert(second.Name, check.Equals, c.Name+" (2)")
+
+	third := s.createCampaignDependencies(ch)
+	third.Name = strings.ToLower(c.Name)
+	ch.Assert(PostCampaign(&third, c.UserId), check.Equals, nil)
+	ch.Assert(third.Name, check.Equals, strings.ToLower(c.Name)+" (3)")
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gophish/gophish/config"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestPostCampaignSendsCreatedNotification(c *check.C) {
+	received := make(chan CampaignCreatedNotification, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, check.Equals, nil)
+		n := CampaignCreatedNotification{}
+		if json.Unmarshal(body, &n) == nil && n.Event == CampaignCreatedNotificationName {
+			received <- n
+		}
+	}))
+	defer ts.Close()
+
+	conf.CampaignCreatedNotify = &config.CampaignCreatedNotify{Enabled: true, URL: ts.URL, Secret: "secret"}
+	defer func() { conf.CampaignCreatedNotify = nil }()
+
+	campaign := s.createCampaignDependencies(c)
+	// Sending every recipient within a minute is too aggressive
+	campaign.LaunchDate = time.Now().UTC()
+	campaign.SendByDate = campaign.LaunchDate.Add(time.Minute)
+	c.Assert(PostCampaign(&campaign, campaign.UserId), check.Equals, nil)
+
+	select {
+	case n := <-received:
+		c.Assert(n.CampaignId, check.Equals, campaign.Id)
+		c.Assert(n.Name, check.Equals, campaign.Name)
+		c.Assert(n.UserId, check.Equals, int64(1))
+		c.Assert(n.Username, check.Equals, "admin")
+		c.Assert(n.TargetCount, check.Equals, len(campaign.Results))
+		c.Assert(n.RateLimitWarning, check.NotNil)
+		c.Assert(n.RateLimitWarning.IsAggressive, check.Equals, true)
+		c.Assert(n.RateLimitWarning.TotalRecipients, check.Equals, len(campaign.Results))
+	case <-time.After(5 * time.Second):
+		c.Fatalf("timed out waiting for the campaign created notification")
+	}
+}
+
+func (s *ModelsSuite) TestPostCampaignCreatedNotificationDisabled(c *check.C) {
+	received := make(chan struct{}, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer ts.Close()
+
+	conf.CampaignCreatedNotify = &config.CampaignCreatedNotify{URL: ts.URL}
+	defer func() { conf.CampaignCreatedNotify = nil }()
+
+	s.createCampaign(c)
+	select {
+	case <-received:
+		c.Fatalf("received a notification while notifications are disabled")
+	case <-time.After(500 * time.Millisecond):
+	}
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"sync"
+)
+
+// CampaignProgress holds the number of results of a campaign at each stage of
+// sending, used to report live progress. As with CampaignStats, each count
+// includes the results which have progressed further, so every opened email
+// is also counted as sent.
+type CampaignProgress struct {
+	CampaignId    int64  `json:"campaign_id"`
+	Status        string `json:"status"`
+	Total         int64  `json:"total"`
+	Scheduled     int64  `json:"scheduled"`
+	Sending       int64  `json:"sending"`
+	Sent          int64  `json:"sent"`
+	Opened        int64  `json:"opened"`
+	Clicked       int64  `json:"clicked"`
+	SubmittedData int64  `json:"submitted_data"`
+	Reported      int64  `json:"reported"`
+	AutoReplied   int64  `json:"auto_replied"`
+	Error         int64  `json:"error"`
+}
+
+// GetCampaignProgress returns the current progress of the campaign owned by
+// the given user.
+func GetCampaignProgress(id int64, uid int64) (CampaignProgress, error) {
+	p := CampaignProgress{CampaignId: id}
+	c := Campaign{}
+	err := db.Select("id, status, compacted").Where("id=? and user_id=?", id, uid).First(&c).Error
+	if err != nil {
+		return p, err
+	}
+	p.Status = c.Status
+	var s CampaignStats
+	if c.Compacted {
+		s, err = getCompactedCampaignStats(id)
+	} else {
+		s, err = getCampaignStats(id)
+	}
+	if err != nil {
+		return p, err
+	}
+	p.Total = s.Total
+	p.Sent = s.EmailsSent
+	p.Opened = s.OpenedEmail
+	p.Clicked = s.ClickedLink
+	p.SubmittedData = s.SubmittedData
+	p.Reported = s.EmailReported
+	p.AutoReplied = s.AutoReplied
+	p.Error = s.Error
+	query := db.Table("results").Where("campaign_id = ?", id)
+	err = query.Where("status IN (?)", []string{StatusScheduled, StatusQueued, StatusRetry}).Count(&p.Scheduled).Error
+	if err != nil {
+		return p, err
+	}
+	err = query.Where("status = ?", StatusSending).Count(&p.Sending).Error
+	return p, err
+}
+
+// progressSubscribers holds the channels notified when a campaign's progress
+// may have changed, keyed by campaign ID.
+var progressSubscribers = struct {
+	sync.Mutex
+	m map[int64]map[chan struct{}]bool
+}{m: map[int64]map[chan struct{}]bool{}}
+
+// SubscribeCampaignProgress returns a channel which receives a value whenever
+// an event is added to the campaign or it is completed, along with a function
+// which must be called to unsubscribe. Notifications are coalesced, so a slow
+// subscriber receives one value for any number of changes rather than
+// blocking the caller of AddEvent.
+func SubscribeCampaignProgress(cid int64) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	progressSubscribers.Lock()
+	if progressSubscribers.m[cid] == nil {
+		progressSubscribers.m[cid] = map[chan struct{}]bool{}
+	}
+	progressSubscribers.m[cid][ch] = true
+	progressSubscribers.Unlock()
+	unsubscribe := func() {
+		progressSubscribers.Lock()
+		defer progressSubscribers.Unlock()
+		delete(progressSubscribers.m[cid], ch)
+		if len(progressSubscribers.m[cid]) == 0 {
+			delete(progressSubscribers.m, cid)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// notifyCampaignProgress notifies any subscribers that the campaign's
+// progress may have changed.
+func notifyCampaignProgress(cid int64) {
+	progressSubscribers.Lock()
+	defer progressSubscribers.Unlock()
+	for ch := range progressSubscribers.m[cid] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestCampaignProgressNotificationsCoalesce(ch *check.C) {
+	notify, unsubscribe := SubscribeCampaignProgress(42)
+	other, unsubscribeOther := SubscribeCampaignProgress(43)
+	defer unsubscribeOther()
+
+	// Several changes before the subscriber reads are delivered as one
+	notifyCampaignProgress(42)
+	notifyCampaignProgress(42)
+	select {
+	case <-notify:
+	case <-time.After(time.Second):
+		ch.Fatal("expected a progress notification")
+	}
+	select {
+	case <-notify:
+		ch.Fatal("expected notifications to be coalesced")
+	default:
+	}
+	// Subscribers of other campaigns aren't notified
+	select {
+	case <-other:
+		ch.Fatal("unexpected notification for another campaign")
+	default:
+	}
+
+	unsubscribe()
+	notifyCampaignProgress(42)
+	select {
+	case <-notify:
+		ch.Fatal("unexpected notification after unsubscribing")
+	default:
+	}
+}
+
+func (s *ModelsSuite) TestCampaignProgressFollowsEvents(ch *check.C) {
+	campaign := s.createCampaign(ch)
+	notify, unsubscribe := SubscribeCampaignProgress(campaign.Id)
+	defer unsubscribe()
+
+	p, err := GetCampaignProgress(campaign.Id, campaign.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(p.Total, check.Equals, int64(len(campaign.Results)))
+	ch.Assert(p.Sent, check.Equals, int64(0))
+
+	result := campaign.Results[0]
+	ch.Assert(result.HandleEmailSent(), check.Equals, nil)
+	select {
+	case <-notify:
+	case <-time.After(time.Second):
+		ch.Fatal("expected AddEvent to notify progress subscribers")
+	}
+	p, err = GetCampaignProgress(campaign.Id, campaign.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(p.Sent, check.Equals, int64(1))
+
+	ch.Assert(CompleteCampaign(campaign.Id, campaign.UserId), check.Equals, nil)
+	select {
+	case <-notify:
+	case <-time.After(time.Second):
+		ch.Fatal("expected completing the campaign to notify progress subscribers")
+	}
+	p, err = GetCampaignProgress(campaign.Id, campaign.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(p.Status, check.Equals, CampaignComplete)
+
+	_, err = GetCampaignProgress(campaign.Id, campaign.UserId+1)
+	ch.Assert(err, check.NotNil)
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestGetQueuedCampaigns(ch *check.C) {
+	now := time.Now().UTC()
+	campaigns := []Campaign{
+		{Name: "Later", LaunchDate: now.Add(-time.Minute), Status: CampaignQueued},
+		{Name: "Earlier", LaunchDate: now.Add(-time.Hour), Status: CampaignQueued},
+		{Name: "Future", LaunchDate: now.Add(time.Hour), Status: CampaignQueued},
+		{Name: "Running", LaunchDate: now.Add(-time.Hour), Status: CampaignInProgress},
+	}
+	for i := range campaigns {
+		ch.Assert(db.Save(&campaigns[i]).Error, check.Equals, nil)
+	}
+	cs, err := GetQueuedCampaigns(now)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(cs), check.Equals, 2)
+	ch.Assert(cs[0].Name, check.Equals, "Earlier")
+	ch.Assert(cs[1].Name, check.Equals, "Later")
+}
+
+func (s *ModelsSuite) TestGetQueuedMailContexts(ch *check.C) {
+	first := s.createCampaign(ch)
+	second := s.createCampaign(ch)
+	ms, err := GetMailLogsByCampaign(first.Id)
+	ch.Assert(err, check.Equals, nil)
+	others, err := GetMailLogsByCampaign(second.Id)
+	ch.Assert(err, check.Equals, nil)
+	ms = append(ms, others...)
+
+	contexts, err := GetQueuedMailContexts(ms)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(contexts), check.Equals, 2)
+	for _, c := range []Campaign{first, second} {
+		mc, ok := contexts[c.Id]
+		ch.Assert(ok, check.Equals, true)
+		ch.Assert(mc.Template.Id, check.Equals, c.Template.Id)
+	}
+
+	contexts, err = GetQueuedMailContexts([]*MailLog{})
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(contexts), check.Equals, 0)
+}
+
+func (s *ModelsSuite) TestLoadCampaignDetailsBounded(ch *check.C) {
+	cs := make([]Campaign, 12)
+	for i := range cs {
+		cs[i].Id = int64(i + 1)
+	}
+	var running, peak int32
+	loadErr := errors.New("failed to load")
+	load := func(c *Campaign) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		c.Name = "loaded"
+		if c.Id == 5 {
+			return loadErr
+		}
+		return nil
+	}
+	loaded, err := loadCampaignDetails(cs, 3, time.Minute, load)
+	ch.Assert(err, check.Equals, loadErr)
+	ch.Assert(len(loaded), check.Equals, len(cs))
+	for i, c := range loaded {
+		ch.Assert(c.Id, check.Equals, int64(i+1))
+		ch.Assert(c.Name, check.Equals, "loaded")
+	}
+	ch.Assert(atomic.LoadInt32(&peak) <= 3, check.Equals, true)
+}
+
+func (s *ModelsSuite) TestLoadCampaignDetailsBudget(ch *check.C) {
+	cs := []Campaign{{Id: 1}, {Id: 2}, {Id: 3}}
+	release := make(chan struct{})
+	defer close(release)
+	load := func(c *Campaign) error {
+		if c.Id == 2 {
+			<-release
+		}
+		return nil
+	}
+	start := time.Now()
+	loaded, err := loadCampaignDetails(cs, 2, 50*time.Millisecond, load)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(time.Since(start) < time.Second, check.Equals, true)
+	ch.Assert(len(loaded), check.Equals, 2)
+	ch.Assert(loaded[0].Id, check.Equals, int64(1))
+	ch.Assert(loaded[1].Id, check.Equals, int64(3))
+}
+
+func (s *ModelsSuite) TestGetScheduledCampaigns(ch *check.C) {
+	other := User{Username: "scheduler", ApiKey: "scheduler-api-key", RoleID: 1}
+	ch.Assert(db.Save(&other).Error, check.Equals, nil)
+
+	now := time.Now().UTC()
+	campaigns := []Campaign{
+		{Name: "Admin queued", UserId: 1, LaunchDate: now.Add(time.Hour), Status: CampaignQueued},
+		{Name: "Other running", UserId: other.Id, LaunchDate: now.Add(-time.Hour), Status: CampaignInProgress},
+		{Name: "Other complete", UserId: other.Id, LaunchDate: now.Add(-2 * time.Hour), Status: CampaignComplete},
+	}
+	for i := range campaigns {
+		ch.Assert(db.Save(&campaigns[i]).Error, check.Equals, nil)
+	}
+	for _, email := range []string{"first@example.com", "second@example.com"} {
+		r := Result{CampaignId: campaigns[1].Id, UserId: other.Id, BaseRecipient: BaseRecipient{Email: email}}
+		ch.Assert(db.Save(&r).Error, check.Equals, nil)
+	}
+
+	cs, err := GetScheduledCampaigns()
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(cs), check.Equals, 2)
+	ch.Assert(cs[0].Name, check.Equals, "Other running")
+	ch.Assert(cs[0].Username, check.Equals, "scheduler")
+	ch.Assert(cs[0].Recipients, check.Equals, int64(2))
+	ch.Assert(cs[1].Name, check.Equals, "Admin queued")
+	ch.Assert(cs[1].Username, check.Equals, "admin")
+	ch.Assert(cs[1].Recipients, check.Equals, int64(0))
+}
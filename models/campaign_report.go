@@ -0,0 +1,174 @@
+package models
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// MaxReportClickers is the number of recipients listed as top clickers in a
+// campaign report.
+const MaxReportClickers = 10
+
+// reportMilestones are the events whose first occurrence is highlighted on a
+// campaign report's timeline, in funnel order.
+var reportMilestones = []string{
+	CampaignCreated,
+	EventSent,
+	EventOpened,
+	EventClicked,
+	EventDataSubmit,
+	EventReported,
+}
+
+// CampaignReport is the summary of a campaign used for stakeholder readouts.
+// It holds the campaign's statistics funnel, the first time each stage of
+// the funnel was reached and the recipients who clicked most often.
+type CampaignReport struct {
+	Id            int64           `json:"id"`
+	Name          string          `json:"name"`
+	Status        string          `json:"status"`
+	CreatedDate   time.Time       `json:"created_date"`
+	LaunchDate    time.Time       `json:"launch_date"`
+	CompletedDate time.Time       `json:"completed_date"`
+	Stats         CampaignStats   `json:"stats"`
+	Highlights    []Event         `json:"highlights"`
+	TopClickers   []ReportClicker `json:"top_clickers"`
+	Results       []Result        `json:"-"`
+	Events        []Event         `json:"-"`
+}
+
+// ReportClicker is a recipient who clicked the link in a campaign, along with
+// the number of times they clicked it.
+type ReportClicker struct {
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Status    string    `json:"status"`
+	Clicks    int       `json:"clicks"`
+	LastClick time.Time `json:"last_click"`
+}
+
+// GetCampaignReport returns the report of the campaign with the given ID,
+// assembled from the campaign's results and statistics.
+func GetCampaignReport(id int64, uid int64) (CampaignReport, error) {
+	cr, err := GetCampaignResults(id, uid)
+	if err != nil {
+		return CampaignReport{}, err
+	}
+	c := Campaign{}
+	err = db.Where("id = ? and user_id = ?", id, uid).First(&c).Error
+	if err != nil {
+		return CampaignReport{}, err
+	}
+	stats, err := getCampaignStats(id)
+	if err != nil {
+		return CampaignReport{}, err
+	}
+	return newCampaignReport(c, cr, stats), nil
+}
+
+// newCampaignReport builds the report of a campaign from its results.
+func newCampaignReport(c Campaign, cr CampaignResults, stats CampaignStats) CampaignReport {
+	report := CampaignReport{
+		Id:            c.Id,
+		Name:          c.Name,
+		Status:        c.Status,
+		CreatedDate:   c.CreatedDate,
+		LaunchDate:    c.LaunchDate,
+		CompletedDate: c.CompletedDate,
+		Stats:         stats,
+		Results:       cr.Results,
+		Events:        cr.Events,
+		Highlights:    []Event{},
+		TopClickers:   []ReportClicker{},
+	}
+	events := make([]Event, len(cr.Events))
+	copy(events, cr.Events)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+
+	first := map[string]Event{}
+	clickers := map[string]*ReportClicker{}
+	for _, e := range events {
+		if _, ok := first[e.Message]; !ok {
+			first[e.Message] = e
+		}
+		if e.Message != EventClicked {
+			continue
+		}
+		rc, ok := clickers[e.Email]
+		if !ok {
+			rc = &ReportClicker{Email: e.Email}
+			clickers[e.Email] = rc
+		}
+		rc.Clicks++
+		rc.LastClick = e.Time
+	}
+	for _, m := range reportMilestones {
+		if e, ok := first[m]; ok {
+			report.Highlights = append(report.Highlights, e)
+		}
+	}
+
+	for _, r := range cr.Results {
+		if rc, ok := clickers[r.Email]; ok {
+			rc.FirstName = r.FirstName
+			rc.LastName = r.LastName
+			rc.Status = r.Status
+		}
+	}
+	for _, rc := range clickers {
+		report.TopClickers = append(report.TopClickers, *rc)
+	}
+	sort.Slice(report.TopClickers, func(i, j int) bool {
+		a, b := report.TopClickers[i], report.TopClickers[j]
+		if a.Clicks != b.Clicks {
+			return a.Clicks > b.Clicks
+		}
+		return a.Email < b.Email
+	})
+	if len(report.TopClickers) > MaxReportClickers {
+		report.TopClickers = report.TopClickers[:MaxReportClickers]
+	}
+	return report
+}
+
+// WriteCSV writes the report's results out as CSV, with a row for each
+// recipient and the number of times they clicked the link.
+func (r CampaignReport) WriteCSV(w io.Writer) error {
+	clicks := map[string]int{}
+	for _, e := range r.Events {
+		if e.Message == EventClicked {
+			clicks[e.Email]++
+		}
+	}
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"email", "first_name", "last_name", "position", "status", "reported", "clicks", "send_date", "sent_date", "modified_date", "ip"})
+	if err != nil {
+		return err
+	}
+	for _, res := range r.Results {
+		err = cw.Write([]string{
+			res.Email,
+			res.FirstName,
+			res.LastName,
+			res.Position,
+			res.Status,
+			fmt.Sprint(res.Reported),
+			fmt.Sprint(clicks[res.Email]),
+			formatCSVTime(&res.SendDate),
+			formatCSVTime(&res.SentDate),
+			formatCSVTime(&res.ModifiedDate),
+			res.IP,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestNewCampaignReport(ch *check.C) {
+	start := time.Date(2025, 12, 1, 9, 0, 0, 0, time.UTC)
+	cr := CampaignResults{
+		Results: []Result{
+			{Status: EventClicked, BaseRecipient: BaseRecipient{Email: "a@example.com", FirstName: "Ann"}},
+			{Status: EventDataSubmit, BaseRecipient: BaseRecipient{Email: "b@example.com", FirstName: "Bob"}},
+			{Status: EventSent, BaseRecipient: BaseRecipient{Email: "c@example.com"}},
+		},
+		// Events are listed out of order to check they're sorted by time
+		Events: []Event{
+			{Email: "b@example.com", Time: start.Add(3 * time.Hour), Message: EventDataSubmit},
+			{Email: "a@example.com", Time: start.Add(2 * time.Hour), Message: EventClicked},
+			{Time: start, Message: CampaignCreated},
+			{Email: "b@example.com", Time: start.Add(time.Hour), Message: EventClicked},
+			{Email: "a@example.com", Time: start.Add(4 * time.Hour), Message: EventClicked},
+			{Email: "a@example.com", Time: start.Add(time.Minute), Message: EventSent},
+		},
+	}
+	report := newCampaignReport(Campaign{Id: 1, Name: "Report"}, cr, CampaignStats{Total: 3})
+
+	messages := []string{}
+	for _, e := range report.Highlights {
+		messages = append(messages, e.Message)
+	}
+	ch.Assert(messages, check.DeepEquals, []string{CampaignCreated, EventSent, EventClicked, EventDataSubmit})
+	ch.Assert(report.Highlights[2].Email, check.Equals, "b@example.com")
+
+	ch.Assert(len(report.TopClickers), check.Equals, 2)
+	ch.Assert(report.TopClickers[0], check.DeepEquals, ReportClicker{
+		Email: "a@example.com", FirstName: "Ann", Status: EventClicked, Clicks: 2, LastClick: start.Add(4 * time.Hour),
+	})
+	ch.Assert(report.TopClickers[1].Email, check.Equals, "b@example.com")
+	ch.Assert(report.TopClickers[1].Clicks, check.Equals, 1)
+
+	// The CSV export is built from the same report
+	buf := &bytes.Buffer{}
+	ch.Assert(report.WriteCSV(buf), check.Equals, nil)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	ch.Assert(len(lines), check.Equals, 4)
+	ch.Assert(strings.HasPrefix(lines[1], "a@example.com,Ann,,,Clicked Link,false,2,"), check.Equals, true)
+	ch.Assert(strings.HasPrefix(lines[3], "c@example.com,,,,Email Sent,false,0,"), check.Equals, true)
+}
+
+func (s *ModelsSuite) TestCampaignReportTopClickersLimit(ch *check.C) {
+	cr := CampaignResults{}
+	for i := 0; i < MaxReportClickers+5; i++ {
+		cr.Events = append(cr.Events, Event{Email: fmt.Sprintf("%02d@example.com", i), Message: EventClicked})
+	}
+	report := newCampaignReport(Campaign{}, cr, CampaignStats{})
+	ch.Assert(len(report.TopClickers), check.Equals, MaxReportClickers)
+	ch.Assert(report.TopClickers[0].Email, check.Equals, "00@example.com")
+}
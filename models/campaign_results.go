@@ -0,0 +1,175 @@
+package models
+
+import (
+	"errors"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// ResultStatusReported is the filter name used to select results which have
+// reported the email. Reporting is tracked separately from the result status.
+const ResultStatusReported = "reported"
+
+// ResultStatusAutoReplied is the filter name used to select results whose
+// mailbox sent an automatic reply, such as an out of office message.
+const ResultStatusAutoReplied = "auto_replied"
+
+// resultStatusFilters maps the status names accepted by the results API to
+// the status values stored in the results table.
+var resultStatusFilters = map[string]string{
+	"scheduled": StatusScheduled,
+	"queued":    StatusQueued,
+	"sending":   StatusSending,
+	"retrying":  StatusRetry,
+	"sent":      EventSent,
+	"opened":    EventOpened,
+	"clicked":   EventClicked,
+	"submitted": EventDataSubmit,
+	"error":     Error,
+}
+
+// MaxResultsPageSize is the largest number of results returned in a single
+// page of filtered campaign results.
+const MaxResultsPageSize = 1000
+
+// ErrInvalidResultStatus is thrown when an unknown status is used to filter
+// campaign results.
+var ErrInvalidResultStatus = errors.New("Invalid result status filter")
+
+// ErrInvalidPagination is thrown when a negative limit or offset is provided.
+var ErrInvalidPagination = errors.New("Limit and offset must not be negative")
+
+// ResultsFilter contains the options used to filter and paginate the results
+// of a campaign.
+type ResultsFilter struct {
+	Status string
+	Limit  int
+	Offset int
+}
+
+// Validate ensures the filter uses a known status and sane pagination values.
+func (f *ResultsFilter) Validate() error {
+	if f.Status != "" && f.Status != ResultStatusReported && f.Status != ResultStatusAutoReplied {
+		if _, ok := resultStatusFilters[f.Status]; !ok {
+			return ErrInvalidResultStatus
+		}
+	}
+	if f.Limit < 0 || f.Offset < 0 {
+		return ErrInvalidPagination
+	}
+	if f.Limit == 0 || f.Limit > MaxResultsPageSize {
+		f.Limit = MaxResultsPageSize
+	}
+	return nil
+}
+
+// FilteredCampaignResults is a page of campaign results matching a
+// ResultsFilter, along with the number of results in each status.
+type FilteredCampaignResults struct {
+	CampaignResults
+	Total        int64            `json:"total"`
+	Limit        int              `json:"limit"`
+	Offset       int              `json:"offset"`
+	StatusCounts map[string]int64 `json:"status_counts"`
+}
+
+// GetFilteredCampaignResults returns the results of a campaign matching the
+// given filter. Only the timeline events belonging to the returned results are
+// included.
+func GetFilteredCampaignResults(id int64, uid int64, f ResultsFilter) (FilteredCampaignResults, error) {
+	fr := FilteredCampaignResults{}
+	if err := f.Validate(); err != nil {
+		return fr, err
+	}
+	fr.Limit = f.Limit
+	fr.Offset = f.Offset
+	err := db.Table("campaigns").Where("id=? and user_id=?", id, uid).Find(&fr.CampaignResults).Error
+	if err != nil {
+		log.WithFields(logrus.Fields{
+			"campaign_id": id,
+			"error":       err,
+		}).Error(err)
+		return fr, err
+	}
+	fr.StatusCounts, err = getResultStatusCounts(fr.Id)
+	if err != nil {
+		log.Errorf("%s: unable to count results for campaign", err)
+		return fr, err
+	}
+	query := db.Table("results").Where("campaign_id=? and user_id=?", fr.Id, uid)
+	switch {
+	case f.Status == ResultStatusReported:
+		query = query.Where("reported=?", true)
+	case f.Status == ResultStatusAutoReplied:
+		query = query.Where("auto_replied=?", true)
+	case f.Status != "":
+		query = query.Where("status=?", resultStatusFilters[f.Status])
+	}
+	err = query.Count(&fr.Total).Error
+	if err != nil {
+		log.Errorf("%s: unable to count results for campaign", err)
+		return fr, err
+	}
+	err = query.Order("id asc").Limit(f.Limit).Offset(f.Offset).Find(&fr.Results).Error
+	if err != nil {
+		log.Errorf("%s: results not found for campaign", err)
+		return fr, err
+	}
+	if len(fr.Results) == 0 {
+		return fr, nil
+	}
+	err = attachResultNotes(fr.Results)
+	if err != nil {
+		log.Errorf("%s: notes not found for results", err)
+		return fr, err
+	}
+	emails := make([]string, len(fr.Results))
+	for i, r := range fr.Results {
+		emails[i] = r.Email
+	}
+	err = db.Table("events").Where("campaign_id=? and email in (?)", fr.Id, emails).Find(&fr.Events).Error
+	if err != nil {
+		log.Errorf("%s: events not found for campaign", err)
+		return fr, err
+	}
+	countResultEngagement(fr.Results, fr.Events)
+	return fr, nil
+}
+
+// getResultStatusCounts returns the number of results in each status for the
+// given campaign, keyed by the status names accepted by ResultsFilter.
+func getResultStatusCounts(cid int64) (map[string]int64, error) {
+	counts := make(map[string]int64, len(resultStatusFilters)+2)
+	for name := range resultStatusFilters {
+		counts[name] = 0
+	}
+	rows, err := db.Table("results").Select("status, count(*)").
+		Where("campaign_id=?", cid).Group("status").Rows()
+	if err != nil {
+		return counts, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			return counts, err
+		}
+		for name, value := range resultStatusFilters {
+			if value == status {
+				counts[name] += count
+			}
+		}
+	}
+	var reported int64
+	err = db.Table("results").Where("campaign_id=? and reported=?", cid, true).Count(&reported).Error
+	counts[ResultStatusReported] = reported
+	if err != nil {
+		return counts, err
+	}
+	var autoReplied int64
+	err = db.Table("results").Where("campaign_id=? and auto_replied=?", cid, true).Count(&autoReplied).Error
+	counts[ResultStatusAutoReplied] = autoReplied
+	return counts, err
+}
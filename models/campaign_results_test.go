@@ -0,0 +1,79 @@
+package models
+
+import (
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestResultsFilterValidate(c *check.C) {
+	f := ResultsFilter{Status: "clicked"}
+	c.Assert(f.Validate(), check.Equals, nil)
+	c.Assert(f.Limit, check.Equals, MaxResultsPageSize)
+
+	f = ResultsFilter{Status: ResultStatusReported, Limit: 10}
+	c.Assert(f.Validate(), check.Equals, nil)
+	c.Assert(f.Limit, check.Equals, 10)
+
+	f = ResultsFilter{Status: "bogus"}
+	c.Assert(f.Validate(), check.Equals, ErrInvalidResultStatus)
+
+	f = ResultsFilter{Offset: -1}
+	c.Assert(f.Validate(), check.Equals, ErrInvalidPagination)
+}
+
+func (s *ModelsSuite) TestGetFilteredCampaignResultsByStatus(c *check.C) {
+	campaign := s.createCampaign(c)
+	c.Assert(len(campaign.Results), check.Equals, 4)
+
+	// Give each result a different outcome
+	statuses := []string{EventSent, EventOpened, EventClicked, EventDataSubmit}
+	for i, r := range campaign.Results {
+		err := db.Model(&Result{}).Where("id=?", r.Id).Update("status", statuses[i]).Error
+		c.Assert(err, check.Equals, nil)
+	}
+	err := db.Model(&Result{}).Where("id=?", campaign.Results[0].Id).Update("reported", true).Error
+	c.Assert(err, check.Equals, nil)
+
+	for name, status := range map[string]string{
+		"sent":      EventSent,
+		"opened":    EventOpened,
+		"clicked":   EventClicked,
+		"submitted": EventDataSubmit,
+	} {
+		fr, err := GetFilteredCampaignResults(campaign.Id, campaign.UserId, ResultsFilter{Status: name})
+		c.Assert(err, check.Equals, nil)
+		c.Assert(fr.Total, check.Equals, int64(1))
+		c.Assert(len(fr.Results), check.Equals, 1)
+		c.Assert(fr.Results[0].Status, check.Equals, status)
+		c.Assert(fr.StatusCounts[name], check.Equals, int64(1))
+		for _, e := range fr.Events {
+			c.Assert(e.Email, check.Equals, fr.Results[0].Email)
+		}
+	}
+
+	for _, name := range []string{"scheduled", "queued", "sending", "retrying", "error"} {
+		fr, err := GetFilteredCampaignResults(campaign.Id, campaign.UserId, ResultsFilter{Status: name})
+		c.Assert(err, check.Equals, nil)
+		c.Assert(fr.Total, check.Equals, int64(0))
+		c.Assert(len(fr.Results), check.Equals, 0)
+	}
+
+	fr, err := GetFilteredCampaignResults(campaign.Id, campaign.UserId, ResultsFilter{Status: ResultStatusReported})
+	c.Assert(err, check.Equals, nil)
+	c.Assert(fr.Total, check.Equals, int64(1))
+	c.Assert(fr.Results[0].Id, check.Equals, campaign.Results[0].Id)
+	c.Assert(fr.StatusCounts[ResultStatusReported], check.Equals, int64(1))
+}
+
+func (s *ModelsSuite) TestGetFilteredCampaignResultsPagination(c *check.C) {
+	campaign := s.createCampaign(c)
+
+	fr, err := GetFilteredCampaignResults(campaign.Id, campaign.UserId, ResultsFilter{Limit: 3})
+	c.Assert(err, check.Equals, nil)
+	c.Assert(fr.Total, check.Equals, int64(4))
+	c.Assert(len(fr.Results), check.Equals, 3)
+
+	fr, err = GetFilteredCampaignResults(campaign.Id, campaign.UserId, ResultsFilter{Limit: 3, Offset: 3})
+	c.Assert(err, check.Equals, nil)
+	c.Assert(fr.Total, check.Equals, int64(4))
+	c.Assert(len(fr.Results), check.Equals, 1)
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestRecalculateSendSchedule(ch *check.C) {
+	c := s.createCampaign(ch)
+	ms, err := GetMailLogsByCampaign(c.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(ms) > 2, check.Equals, true)
+
+	// One email has already been sent, and another is being sent
+	sent := ms[0]
+	sentDate := sent.SendDate
+	ch.Assert(db.Model(&Result{}).Where("r_id = ?", sent.RId).Update("status", EventSent).Error, check.Equals, nil)
+	ch.Assert(db.Delete(sent).Error, check.Equals, nil)
+	sending := ms[1]
+	ch.Assert(db.Model(&MailLog{}).Where("id = ?", sending.Id).Update("processing", true).Error, check.Equals, nil)
+
+	start := time.Now().UTC()
+	sendBy := start.Add(2 * time.Hour)
+	n, err := RecalculateSendSchedule(c.Id, c.UserId, sendBy)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(n, check.Equals, len(ms)-2)
+
+	rescheduled, err := GetMailLogsByCampaign(c.Id)
+	ch.Assert(err, check.Equals, nil)
+	for _, m := range rescheduled {
+		r, err := GetResult(m.RId)
+		ch.Assert(err, check.Equals, nil)
+		if m.Id == sending.Id {
+			ch.Assert(m.SendDate.Equal(sending.SendDate), check.Equals, true)
+			continue
+		}
+		ch.Assert(m.SendDate.Before(start.Add(-time.Second)), check.Equals, false)
+		ch.Assert(m.SendDate.Before(sendBy), check.Equals, true)
+		ch.Assert(r.SendDate.Equal(m.SendDate), check.Equals, true)
+	}
+	r, err := GetResult(sent.RId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(r.Status, check.Equals, EventSent)
+	ch.Assert(r.SendDate.Equal(sentDate), check.Equals, true)
+
+	c, err = GetCampaign(c.Id, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(c.SendByDate.Unix(), check.Equals, sendBy.Unix())
+}
+
+func (s *ModelsSuite) TestRecalculateSendScheduleRejectsPastDate(ch *check.C) {
+	c := s.createCampaign(ch)
+	_, err := RecalculateSendSchedule(c.Id, c.UserId, time.Now().UTC().Add(-time.Minute))
+	ch.Assert(err, check.Equals, ErrSendByDateInPast)
+
+	_, err = RecalculateSendSchedule(c.Id, c.UserId+1, time.Now().UTC().Add(time.Hour))
+	ch.Assert(err, check.NotNil)
+}
+
+func (s *ModelsSuite) TestRecalculateSendScheduleWithoutScheduledSends(ch *check.C) {
+	c := s.createCampaign(ch)
+	ch.Assert(db.Where("campaign_id = ?", c.Id).Delete(&MailLog{}).Error, check.Equals, nil)
+	sendBy := time.Now().UTC().Add(time.Hour)
+	_, err := RecalculateSendSchedule(c.Id, c.UserId, sendBy)
+	ch.Assert(err, check.Equals, ErrNoSendsToReschedule)
+
+	// The campaign's schedule is left alone
+	got, err := GetCampaign(c.Id, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.SendByDate.Unix() == sendBy.Unix(), check.Equals, false)
+}
+
+func (s *ModelsSuite) TestRecalculateSendScheduleRejectsN8NCampaigns(ch *check.C) {
+	c := s.createLaunchedN8NCampaign(ch)
+	_, err := RecalculateSendSchedule(c.Id, c.UserId, time.Now().UTC().Add(time.Hour))
+	ch.Assert(err, check.Equals, ErrN8NScheduleFixed)
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+)
+
+// ScheduledCampaign is an overview of a queued or in progress campaign, used
+// by admins to review the upcoming sends of every user.
+type ScheduledCampaign struct {
+	Id          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Status      string    `json:"status"`
+	UserId      int64     `json:"user_id"`
+	Username    string    `json:"username"`
+	CreatedDate time.Time `json:"created_date"`
+	LaunchDate  time.Time `json:"launch_date"`
+	SendByDate  time.Time `json:"send_by_date"`
+	Recipients  int64     `json:"recipients"`
+}
+
+// GetScheduledCampaigns returns the queued and in progress campaigns of every
+// user, along with their owner and number of recipients, in the order they
+// launch.
+func GetScheduledCampaigns() ([]ScheduledCampaign, error) {
+	cs := []ScheduledCampaign{}
+	err := db.Table("campaigns").
+		Select(`campaigns.id, campaigns.name, campaigns.status, campaigns.user_id,
+			users.username, campaigns.created_date, campaigns.launch_date, campaigns.send_by_date,
+			(SELECT COUNT(*) FROM results WHERE results.campaign_id = campaigns.id) AS recipients`).
+		Joins("LEFT JOIN users ON users.id = campaigns.user_id").
+		Where("campaigns.status IN (?)", []string{CampaignQueued, CampaignInProgress}).
+		Order("campaigns.launch_date asc, campaigns.id asc").
+		Scan(&cs).Error
+	if err != nil {
+		log.Error(err)
+	}
+	return cs, err
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCampaignResultsPurged is thrown when attempting to recompute the
+// statistics of a compacted campaign whose results have been purged.
+var ErrCampaignResultsPurged = errors.New("Campaign results have been purged, so its statistics can't be recomputed")
+
+// eventProgress ranks the events which move a recipient through a campaign,
+// so that each recipient is counted at the furthest point they reached.
+var eventProgress = map[string]int{
+	EventSent:       1,
+	EventOpened:     2,
+	EventClicked:    3,
+	EventDataSubmit: 4,
+}
+
+// CampaignStatsRecompute holds the statistics of a campaign before and after
+// they were recomputed from its results and events.
+type CampaignStatsRecompute struct {
+	CampaignId      int64         `json:"campaign_id"`
+	Before          CampaignStats `json:"before"`
+	After           CampaignStats `json:"after"`
+	SnapshotUpdated bool          `json:"snapshot_updated"`
+}
+
+// recipientProgress is the furthest point a recipient reached in a campaign.
+type recipientProgress struct {
+	progress    int
+	reported    bool
+	autoReplied bool
+	errored     bool
+	internal    bool
+}
+
+// RecomputeCampaignStats recounts the statistics of a campaign from its
+// results and events, which corrects any drift after events have been
+// backfilled. If a statistics snapshot has been stored for the campaign, it
+// is rewritten with the recomputed statistics.
+func RecomputeCampaignStats(id int64) (CampaignStatsRecompute, error) {
+	rc := CampaignStatsRecompute{CampaignId: id}
+	c := Campaign{}
+	err := db.Where("id=?", id).Find(&c).Error
+	if err != nil {
+		return rc, err
+	}
+	if c.Compacted {
+		rc.Before, err = getCompactedCampaignStats(c.Id)
+	} else {
+		rc.Before, err = getCampaignStats(c.Id)
+	}
+	if err != nil {
+		log.Error(err)
+		return rc, err
+	}
+
+	results := []Result{}
+	err = db.Where("campaign_id=?", c.Id).Find(&results).Error
+	if err != nil {
+		log.Error(err)
+		return rc, err
+	}
+	if c.Compacted && len(results) == 0 {
+		return rc, ErrCampaignResultsPurged
+	}
+	events := []Event{}
+	err = db.Where("campaign_id=? and email <> ''", c.Id).Find(&events).Error
+	if err != nil {
+		log.Error(err)
+		return rc, err
+	}
+	rc.After = countCampaignStats(results, events)
+
+	snapshot, err := getCampaignStatsSnapshot(c.Id)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+	case err != nil:
+		log.Error(err)
+		return rc, err
+	default:
+		snapshot.CampaignStats = rc.After
+		err = db.Save(&snapshot).Error
+		if err != nil {
+			log.Error(err)
+			return rc, err
+		}
+		rc.SnapshotUpdated = true
+	}
+	log.WithFields(logrus.Fields{
+		"campaign_id":      c.Id,
+		"snapshot_updated": rc.SnapshotUpdated,
+	}).Info("Recomputed campaign statistics")
+	return rc, nil
+}
+
+// countCampaignStats counts the statistics of a campaign from its results and
+// events. Each recipient is counted at the furthest point recorded by either
+// their result or their events, and reaching a point implies reaching every
+// point before it. Internal recipients are counted no further than sent.
+func countCampaignStats(results []Result, events []Event) CampaignStats {
+	recipients := make([]recipientProgress, len(results))
+	byEmail := make(map[string][]int, len(results))
+	for i, r := range results {
+		recipients[i] = recipientProgress{
+			progress:    eventProgress[r.Status],
+			reported:    r.Reported,
+			autoReplied: r.AutoReplied,
+			errored:     r.Status == Error,
+			internal:    r.Internal,
+		}
+		email := strings.ToLower(r.Email)
+		byEmail[email] = append(byEmail[email], i)
+	}
+	for _, e := range events {
+		for _, i := range byEmail[strings.ToLower(e.Email)] {
+			p := &recipients[i]
+			switch e.Message {
+			case EventReported:
+				p.reported = true
+			case EventAutoReply:
+				p.autoReplied = true
+			case EventSendingError:
+				p.errored = true
+			default:
+				if progress := eventProgress[e.Message]; progress > p.progress {
+					p.progress = progress
+				}
+			}
+		}
+	}
+	s := CampaignStats{Total: int64(len(results))}
+	for _, p := range recipients {
+		if p.reported && !p.internal {
+			s.EmailReported++
+		}
+		if p.autoReplied {
+			s.AutoReplied++
+		}
+		// Internal recipients are only counted as sent
+		if p.internal && p.progress > eventProgress[EventSent] {
+			p.progress = eventProgress[EventSent]
+		}
+		switch {
+		case p.progress >= eventProgress[EventDataSubmit]:
+			s.SubmittedData++
+			fallthrough
+		case p.progress >= eventProgress[EventClicked]:
+			s.ClickedLink++
+			fallthrough
+		case p.progress >= eventProgress[EventOpened]:
+			s.OpenedEmail++
+			fallthrough
+		case p.progress >= eventProgress[EventSent]:
+			s.EmailsSent++
+		case p.errored:
+			// Recipients who were sent their email after a failed attempt
+			// aren't counted as errors
+			s.Error++
+		}
+	}
+	return s
+}
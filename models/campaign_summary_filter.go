@@ -0,0 +1,92 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxCampaignSummariesPageSize is the largest number of campaign summaries
+// returned in a single page.
+const MaxCampaignSummariesPageSize = 1000
+
+// ErrInvalidDateRange is thrown when a date range filter ends before it
+// starts.
+var ErrInvalidDateRange = errors.New("The start of a date range must be before its end")
+
+// CampaignSummaryFilter contains the options used to filter and paginate
+// campaign summaries. The "after" dates are inclusive and the "before" dates
+// are exclusive, so that consecutive ranges such as quarters don't overlap.
+// Filtering by completion date only matches completed campaigns.
+type CampaignSummaryFilter struct {
+	LaunchedAfter   time.Time
+	LaunchedBefore  time.Time
+	CompletedAfter  time.Time
+	CompletedBefore time.Time
+	// Archived, if set, matches only archived or only unarchived campaigns
+	Archived *bool
+	Limit    int
+	Offset   int
+}
+
+// Validate ensures the date ranges don't end before they start and the
+// pagination values are sane.
+func (f *CampaignSummaryFilter) Validate() error {
+	if !f.LaunchedAfter.IsZero() && !f.LaunchedBefore.IsZero() && !f.LaunchedAfter.Before(f.LaunchedBefore) {
+		return ErrInvalidDateRange
+	}
+	if !f.CompletedAfter.IsZero() && !f.CompletedBefore.IsZero() && !f.CompletedAfter.Before(f.CompletedBefore) {
+		return ErrInvalidDateRange
+	}
+	if f.Limit < 0 || f.Offset < 0 {
+		return ErrInvalidPagination
+	}
+	if f.Limit == 0 || f.Limit > MaxCampaignSummariesPageSize {
+		f.Limit = MaxCampaignSummariesPageSize
+	}
+	return nil
+}
+
+// FilteredCampaignSummaries is a page of the summaries of campaigns matching
+// a CampaignSummaryFilter. Total is the number of campaigns matching the
+// filter, rather than the number in the page.
+type FilteredCampaignSummaries struct {
+	CampaignSummaries
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// GetFilteredCampaignSummaries returns the summaries of the user's campaigns
+// matching the given filter, most recently launched first.
+func GetFilteredCampaignSummaries(uid int64, f CampaignSummaryFilter) (FilteredCampaignSummaries, error) {
+	fs := FilteredCampaignSummaries{}
+	if err := f.Validate(); err != nil {
+		return fs, err
+	}
+	fs.Limit = f.Limit
+	fs.Offset = f.Offset
+	query := db.Table("campaigns").Where("user_id = ?", uid)
+	if !f.LaunchedAfter.IsZero() {
+		query = query.Where("launch_date >= ?", f.LaunchedAfter.UTC())
+	}
+	if !f.LaunchedBefore.IsZero() {
+		query = query.Where("launch_date < ?", f.LaunchedBefore.UTC())
+	}
+	if !f.CompletedAfter.IsZero() || !f.CompletedBefore.IsZero() {
+		query = query.Where("status = ?", CampaignComplete)
+	}
+	if !f.CompletedAfter.IsZero() {
+		query = query.Where("completed_date >= ?", f.CompletedAfter.UTC())
+	}
+	if !f.CompletedBefore.IsZero() {
+		query = query.Where("completed_date < ?", f.CompletedBefore.UTC())
+	}
+	if f.Archived != nil {
+		query = query.Where("archived = ?", *f.Archived)
+	}
+	err := query.Count(&fs.Total).Error
+	if err != nil {
+		return fs, err
+	}
+	fs.Campaigns, err = scanCampaignSummaries(query.Order("launch_date desc, id desc").Limit(f.Limit).Offset(f.Offset))
+	return fs, err
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+// createDatedCampaign creates a campaign launched at the given date. If the
+// completed date isn't zero, the campaign is marked as completed then.
+func (s *ModelsSuite) createDatedCampaign(ch *check.C, name string, launched, completed time.Time) Campaign {
+	c := s.createCampaignDependencies(ch)
+	c.Name = name
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	updates := map[string]interface{}{"launch_date": launched}
+	if !completed.IsZero() {
+		updates["status"] = CampaignComplete
+		updates["completed_date"] = completed
+	}
+	ch.Assert(db.Model(&Campaign{}).Where("id = ?", c.Id).UpdateColumns(updates).Error, check.Equals, nil)
+	return c
+}
+
+// filteredCampaignNames returns the names of the summaries matching the
+// filter.
+func filteredCampaignNames(ch *check.C, f CampaignSummaryFilter) []string {
+	fs, err := GetFilteredCampaignSummaries(1, f)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(fs.Total, check.Equals, int64(len(fs.Campaigns)))
+	names := []string{}
+	for _, c := range fs.Campaigns {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func (s *ModelsSuite) TestGetFilteredCampaignSummariesByDate(ch *check.C) {
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2025, month, day, 12, 0, 0, 0, time.UTC)
+	}
+	s.createDatedCampaign(ch, "December", date(time.December, 15), date(time.December, 20))
+	s.createDatedCampaign(ch, "January", date(time.January, 10), date(time.April, 2))
+	s.createDatedCampaign(ch, "March", date(time.March, 31), date(time.March, 31).Add(time.Hour))
+	s.createDatedCampaign(ch, "April", date(time.April, 1), time.Time{})
+
+	q1Start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	q2Start := time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	// Most recently launched first
+	ch.Assert(filteredCampaignNames(ch, CampaignSummaryFilter{LaunchedAfter: q1Start}),
+		check.DeepEquals, []string{"December", "April", "March", "January"})
+	ch.Assert(filteredCampaignNames(ch, CampaignSummaryFilter{LaunchedBefore: q2Start}),
+		check.DeepEquals, []string{"March", "January"})
+	ch.Assert(filteredCampaignNames(ch, CampaignSummaryFilter{LaunchedAfter: q1Start, LaunchedBefore: q2Start}),
+		check.DeepEquals, []string{"March", "January"})
+
+	// Only completed campaigns match completion dates
+	ch.Assert(filteredCampaignNames(ch, CampaignSummaryFilter{CompletedAfter: q2Start}),
+		check.DeepEquals, []string{"December", "January"})
+	ch.Assert(filteredCampaignNames(ch, CampaignSummaryFilter{CompletedBefore: q2Start}),
+		check.DeepEquals, []string{"March"})
+	ch.Assert(filteredCampaignNames(ch, CampaignSummaryFilter{CompletedAfter: q1Start, CompletedBefore: q2Start}),
+		check.DeepEquals, []string{"March"})
+}
+
+func (s *ModelsSuite) TestGetFilteredCampaignSummariesArchived(ch *check.C) {
+	launched := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	s.createDatedCampaign(ch, "Running", launched, time.Time{})
+	s.createDatedCampaign(ch, "Recent", launched.Add(time.Hour), launched.Add(2*time.Hour))
+	old := s.createDatedCampaign(ch, "Old", launched.Add(2*time.Hour), launched.Add(3*time.Hour))
+	ch.Assert(db.Model(&Campaign{}).Where("id = ?", old.Id).UpdateColumn("archived", true).Error, check.Equals, nil)
+
+	archived, active := true, false
+	ch.Assert(filteredCampaignNames(ch, CampaignSummaryFilter{Archived: &archived}),
+		check.DeepEquals, []string{"Old"})
+	ch.Assert(filteredCampaignNames(ch, CampaignSummaryFilter{Archived: &active}),
+		check.DeepEquals, []string{"Recent", "Running"})
+	ch.Assert(filteredCampaignNames(ch, CampaignSummaryFilter{}),
+		check.DeepEquals, []string{"Old", "Recent", "Running"})
+}
+
+func (s *ModelsSuite) TestGetFilteredCampaignSummariesPagination(ch *check.C) {
+	launched := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"First", "Second", "Third"} {
+		s.createDatedCampaign(ch, name, launched.Add(time.Duration(i)*time.Hour), time.Time{})
+	}
+	fs, err := GetFilteredCampaignSummaries(1, CampaignSummaryFilter{LaunchedAfter: launched, Limit: 1, Offset: 1})
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(fs.Total, check.Equals, int64(3))
+	ch.Assert(len(fs.Campaigns), check.Equals, 1)
+	ch.Assert(fs.Campaigns[0].Name, check.Equals, "Second")
+	ch.Assert(fs.Limit, check.Equals, 1)
+	ch.Assert(fs.Offset, check.Equals, 1)
+}
+
+func (s *ModelsSuite) TestGetFilteredCampaignSummariesInvalid(ch *check.C) {
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	_, err := GetFilteredCampaignSummaries(1, CampaignSummaryFilter{LaunchedAfter: start, LaunchedBefore: start})
+	ch.Assert(err, check.Equals, ErrInvalidDateRange)
+	_, err = GetFilteredCampaignSummaries(1, CampaignSummaryFilter{CompletedAfter: start.Add(time.Hour), CompletedBefore: start})
+	ch.Assert(err, check.Equals, ErrInvalidDateRange)
+	_, err = GetFilteredCampaignSummaries(1, CampaignSummaryFilter{Offset: -1})
+	ch.Assert(err, check.Equals, ErrInvalidPagination)
+}
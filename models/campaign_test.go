@@ -335,3 +335,71 @@ func BenchmarkGetCampaign10000(b *testing.B) {
 	}
 	tearDownBenchmark(b)
 }
+
+func (s *ModelsSuite) TestCampaignStartJitter(ch *check.C) {
+	launch := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	jitter := 15
+	a := Campaign{LaunchDate: launch, StartJitter: &jitter}
+	ch.Assert(a.pickStartOffset(), check.Equals, nil)
+	offsetA := a.generateSendDate(0, 1, DefaultSendInterval).Sub(launch)
+	ch.Assert(offsetA >= 0, check.Equals, true)
+	ch.Assert(offsetA <= 15*time.Minute, check.Equals, true)
+
+	// The offset is stored, so it doesn't change once the campaign is saved
+	a.Id = 1
+	ch.Assert(a.generateSendDate(0, 1, DefaultSendInterval).Sub(launch), check.Equals, offsetA)
+
+	// Every recipient is shifted by the same offset
+	a.SendByDate = launch.Add(time.Hour)
+	ch.Assert(a.generateSendDate(2, 4, DefaultSendInterval), check.Equals, launch.Add(30*time.Minute+offsetA))
+
+	// No jitter leaves the launch date untouched
+	c := Campaign{LaunchDate: launch, StartOffset: 60}
+	ch.Assert(c.pickStartOffset(), check.Equals, nil)
+	ch.Assert(c.generateSendDate(0, 1, DefaultSendInterval), check.Equals, launch)
+}
+
+func (s *ModelsSuite) TestPostCampaignStoresStartOffset(ch *check.C) {
+	c := s.createCampaignDependencies(ch)
+	jitter := 30
+	c.StartJitter = &jitter
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	ch.Assert(c.StartOffset <= 30*60, check.Equals, true)
+
+	saved, err := GetCampaign(c.Id, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(saved.StartOffset, check.Equals, c.StartOffset)
+	ch.Assert(saved.startOffset(), check.Equals, time.Duration(c.StartOffset)*time.Second)
+}
+
+func (s *ModelsSuite) TestCampaignStartJitterValidation(ch *check.C) {
+	c := s.createCampaignDependencies(ch)
+	jitter := MaxStartJitter + 1
+	c.StartJitter = &jitter
+	ch.Assert(c.Validate(), check.Equals, ErrInvalidStartJitter)
+	jitter = -1
+	ch.Assert(c.Validate(), check.Equals, ErrInvalidStartJitter)
+}
+
+func (s *ModelsSuite) TestPostCampaignDefaultStartJitter(ch *check.C) {
+	original := conf.StartJitterMinutes
+	defer func() { conf.StartJitterMinutes = original }()
+	conf.StartJitterMinutes = 30
+
+	// Campaigns which don't ask for a start jitter get the default
+	c := s.createCampaignDependencies(ch)
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	ch.Assert(*c.StartJitter, check.Equals, 30)
+
+	// while an explicit 0 turns jitter off
+	c = s.createCampaignDependencies(ch)
+	jitter := 0
+	c.StartJitter = &jitter
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	ch.Assert(*c.StartJitter, check.Equals, 0)
+	ch.Assert(c.StartOffset, check.Equals, 0)
+
+	saved, err := GetCampaign(c.Id, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(*saved.StartJitter, check.Equals, 0)
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// TimelineEvent is an event in a campaign's timeline with its details parsed,
+// as it's exported for log analytics.
+type TimelineEvent struct {
+	Id         int64                 `json:"id"`
+	CampaignId int64                 `json:"campaign_id"`
+	Email      string                `json:"email"`
+	Time       time.Time             `json:"time"`
+	Message    string                `json:"message"`
+	Details    *TimelineEventDetails `json:"details,omitempty"`
+	// RawDetails holds details which couldn't be parsed
+	RawDetails string `json:"raw_details,omitempty"`
+}
+
+// TimelineEventDetails are the details recorded with any of the events in a
+// campaign's timeline. Only the fields recorded with the event are set, and
+// any other details, such as those of audit events, are kept in Extra.
+type TimelineEventDetails struct {
+	Payload  url.Values        `json:"payload,omitempty"`
+	Browser  map[string]string `json:"browser,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	SentAt   *time.Time        `json:"sent_at,omitempty"`
+	Imported bool              `json:"imported,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// timelineEventDetails has the fields of TimelineEventDetails without its
+// JSON methods.
+type timelineEventDetails TimelineEventDetails
+
+// UnmarshalJSON parses the details, keeping those without a field of their
+// own in Extra.
+func (d *TimelineEventDetails) UnmarshalJSON(b []byte) error {
+	known := timelineEventDetails{}
+	if err := json.Unmarshal(b, &known); err != nil {
+		return err
+	}
+	extra := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &extra); err != nil {
+		return err
+	}
+	for _, key := range []string{"payload", "browser", "error", "sent_at", "imported"} {
+		delete(extra, key)
+	}
+	if len(extra) > 0 {
+		known.Extra = extra
+	}
+	*d = TimelineEventDetails(known)
+	return nil
+}
+
+// MarshalJSON writes out the details along with those kept in Extra.
+func (d TimelineEventDetails) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(timelineEventDetails(d))
+	if err != nil || len(d.Extra) == 0 {
+		return b, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range d.Extra {
+		if _, ok := fields[key]; !ok {
+			fields[key] = value
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// newTimelineEvent returns the event with its details parsed.
+func newTimelineEvent(e Event) TimelineEvent {
+	te := TimelineEvent{
+		Id:         e.Id,
+		CampaignId: e.CampaignId,
+		Email:      e.Email,
+		Time:       e.Time,
+		Message:    e.Message,
+	}
+	if e.Details == "" {
+		return te
+	}
+	details := &TimelineEventDetails{}
+	if err := json.Unmarshal([]byte(e.Details), details); err != nil {
+		te.RawDetails = e.Details
+		return te
+	}
+	te.Details = details
+	return te
+}
+
+// CampaignTimeline is the timeline of a campaign which has been checked to
+// belong to the user exporting it.
+type CampaignTimeline struct {
+	CampaignId int64
+}
+
+// GetCampaignTimeline returns the timeline of the campaign, or
+// gorm.ErrRecordNotFound if the campaign doesn't belong to the user. The
+// events aren't loaded until the timeline is written.
+func GetCampaignTimeline(cid int64, uid int64) (CampaignTimeline, error) {
+	ct := CampaignTimeline{CampaignId: cid}
+	count := 0
+	err := db.Model(&Campaign{}).Where("id = ? AND user_id = ?", cid, uid).Count(&count).Error
+	if err != nil {
+		return ct, err
+	}
+	if count == 0 {
+		return ct, gorm.ErrRecordNotFound
+	}
+	return ct, nil
+}
+
+// timelineBatchSize is the number of events read from the database at a time
+// when writing a timeline.
+var timelineBatchSize = 500
+
+// WriteJSONL writes each event in the timeline to w as a line of JSON, oldest
+// first. Events are read from the database in batches so that the timelines
+// of large campaigns aren't held in memory, and each batch is read in full
+// before it's written so that a slow reader doesn't hold a connection.
+func (ct CampaignTimeline) WriteJSONL(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	var last *Event
+	for {
+		query := db.Where("campaign_id = ?", ct.CampaignId)
+		if last != nil {
+			query = query.Where("time > ? OR (time = ? AND id > ?)", last.Time, last.Time, last.Id)
+		}
+		events := []Event{}
+		err := query.Order("time asc, id asc").Limit(timelineBatchSize).Find(&events).Error
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			err = enc.Encode(newTimelineEvent(e))
+			if err != nil {
+				return err
+			}
+		}
+		if len(events) < timelineBatchSize {
+			return nil
+		}
+		last = &events[len(events)-1]
+	}
+}
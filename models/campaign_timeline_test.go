@@ -0,0 +1,74 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestCampaignTimelineJSONL(ch *check.C) {
+	campaign := s.createCampaign(ch)
+	result := campaign.Results[0]
+	ch.Assert(result.HandleEmailSent(), check.Equals, nil)
+	ch.Assert(result.HandleEmailOpened(EventDetails{Browser: map[string]string{"address": "127.0.0.1"}}), check.Equals, nil)
+	ch.Assert(campaign.Results[1].HandleEmailError(errors.New("mailbox unavailable")), check.Equals, nil)
+
+	ct, err := GetCampaignTimeline(campaign.Id, campaign.UserId)
+	ch.Assert(err, check.Equals, nil)
+	buf := &bytes.Buffer{}
+	ch.Assert(ct.WriteJSONL(buf), check.Equals, nil)
+
+	// Every line is a complete JSON object, oldest first
+	events := []TimelineEvent{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		e := TimelineEvent{}
+		ch.Assert(json.Unmarshal(scanner.Bytes(), &e), check.Equals, nil)
+		events = append(events, e)
+	}
+	ch.Assert(scanner.Err(), check.Equals, nil)
+	ch.Assert(len(events), check.Equals, 4)
+	for i := 1; i < len(events); i++ {
+		ch.Assert(events[i].Time.Before(events[i-1].Time), check.Equals, false)
+	}
+	ch.Assert(events[0].Message, check.Equals, "Campaign Created")
+	ch.Assert(events[1].Message, check.Equals, EventSent)
+	ch.Assert(events[2].Message, check.Equals, EventOpened)
+	ch.Assert(events[2].Details.Browser["address"], check.Equals, "127.0.0.1")
+	ch.Assert(events[3].Message, check.Equals, EventSendingError)
+	ch.Assert(events[3].Details.Error, check.Equals, "mailbox unavailable")
+
+	// Reading the timeline in batches gives the same events
+	defer func(size int) { timelineBatchSize = size }(timelineBatchSize)
+	timelineBatchSize = 3
+	batched := &bytes.Buffer{}
+	ch.Assert(ct.WriteJSONL(batched), check.Equals, nil)
+	all := &bytes.Buffer{}
+	timelineBatchSize = 100
+	ch.Assert(ct.WriteJSONL(all), check.Equals, nil)
+	ch.Assert(batched.String(), check.Equals, all.String())
+	ch.Assert(bytes.Count(batched.Bytes(), []byte("\n")), check.Equals, 4)
+
+	_, err = GetCampaignTimeline(campaign.Id, campaign.UserId+1)
+	ch.Assert(err, check.Equals, gorm.ErrRecordNotFound)
+}
+
+func (s *ModelsSuite) TestTimelineEventDetailsKeepsUnknownDetails(ch *check.C) {
+	e := newTimelineEvent(Event{Message: EventRawCaptureEnabled, Details: `{"user_id":1,"username":"admin","error":"x"}`})
+	ch.Assert(e.RawDetails, check.Equals, "")
+	ch.Assert(e.Details.Error, check.Equals, "x")
+	ch.Assert(string(e.Details.Extra["username"]), check.Equals, `"admin"`)
+	ch.Assert(string(e.Details.Extra["user_id"]), check.Equals, "1")
+
+	b, err := json.Marshal(e.Details)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(string(b), check.Equals, `{"error":"x","user_id":1,"username":"admin"}`)
+
+	b, err = json.Marshal(TimelineEventDetails{Error: "x"})
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(string(b), check.Equals, `{"error":"x"}`)
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	check "gopkg.in/check.v1"
+)
+
+// countCampaignMailLogs returns the number of maillogs queued for the
+// campaign.
+func countCampaignMailLogs(ch *check.C, cid int64) int {
+	var count int
+	ch.Assert(db.Model(&MailLog{}).Where("campaign_id = ?", cid).Count(&count).Error, check.Equals, nil)
+	return count
+}
+
+func (s *ModelsSuite) TestCampaignTransportBelowThreshold(ch *check.C) {
+	defer stubN8NLaunches(func(c *Campaign) error { return nil })()
+	os.Setenv("N8N_BATCH_MIN_RECIPIENTS", "10")
+	defer os.Unsetenv("N8N_BATCH_MIN_RECIPIENTS")
+
+	c := s.createN8NCampaignDependencies(ch)
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	ch.Assert(c.Transport, check.Equals, CampaignTransportDirect)
+	ch.Assert(ShouldUseN8NBatchLaunch(&c), check.Equals, false)
+	// Direct campaigns are sent by the worker from their maillogs
+	ch.Assert(countCampaignMailLogs(ch, c.Id), check.Equals, len(c.Results))
+
+	c, err := GetCampaign(c.Id, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(c.Transport, check.Equals, CampaignTransportDirect)
+}
+
+func (s *ModelsSuite) TestCampaignTransportAboveThreshold(ch *check.C) {
+	defer stubN8NLaunches(func(c *Campaign) error { return nil })()
+	os.Setenv("N8N_BATCH_MIN_RECIPIENTS", "3")
+	defer os.Unsetenv("N8N_BATCH_MIN_RECIPIENTS")
+
+	c := s.createN8NCampaignDependencies(ch)
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	ch.Assert(c.Transport, check.Equals, CampaignTransportN8N)
+	ch.Assert(ShouldUseN8NBatchLaunch(&c), check.Equals, true)
+	ch.Assert(countCampaignMailLogs(ch, c.Id), check.Equals, 0)
+}
+
+func (s *ModelsSuite) TestCampaignTransportOverride(ch *check.C) {
+	defer stubN8NLaunches(func(c *Campaign) error { return nil })()
+	os.Setenv("N8N_BATCH_MIN_RECIPIENTS", "10")
+	defer os.Unsetenv("N8N_BATCH_MIN_RECIPIENTS")
+
+	// The campaign's transport takes precedence over the threshold
+	c := s.createN8NCampaignDependencies(ch)
+	c2 := c
+	c2.Groups = append([]Group{}, c.Groups...)
+	c.Transport = CampaignTransportN8N
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	ch.Assert(c.Transport, check.Equals, CampaignTransportN8N)
+
+	// but n8n can't be used without a credential
+	noN8N := EmailAccount{Email: "support@example.com", EmailType: "support", IsActive: true}
+	ch.Assert(PostEmailAccount(&noN8N), check.Equals, nil)
+	c2.Name = "Without n8n"
+	c2.EmailAccount = noN8N
+	c2.EmailAccountId = noN8N.Id
+	c2.Transport = CampaignTransportN8N
+	ch.Assert(PostCampaign(&c2, c2.UserId), check.Equals, ErrN8NTransportUnavailable)
+
+	c2.Transport = "smtp"
+	ch.Assert(PostCampaign(&c2, c2.UserId), check.Equals, ErrInvalidTransport)
+}
+
+func (s *ModelsSuite) TestDirectCampaignSendsFromMailLogs(ch *check.C) {
+	defer stubN8NLaunches(func(c *Campaign) error { return nil })()
+	c := s.createN8NCampaignDependencies(ch)
+	c.Transport = CampaignTransportDirect
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+
+	received := make(chan N8NWebhookPayload, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := N8NWebhookPayload{}
+		ch.Assert(json.NewDecoder(r.Body).Decode(&payload), check.Equals, nil)
+		received <- payload
+	}))
+	defer ts.Close()
+
+	// The worker sends with the campaign's mail context, which doesn't carry
+	// its results
+	mc, err := GetCampaignMailContext(c.Id, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(mc.Results), check.Equals, 0)
+	sender := &N8NSender{
+		webhookURL: ts.URL,
+		jwtSecret:  "secret",
+		emailType:  "test",
+		campaign:   &mc,
+		client:     ts.Client(),
+	}
+	result := c.Results[0]
+	err = sender.Send("from@example.com", []string{result.Email}, &mockWriterTo{campaign: &mc})
+	ch.Assert(err, check.Equals, nil)
+	payload := <-received
+	ch.Assert(len(payload.Recipients), check.Equals, 1)
+	ch.Assert(payload.Recipients[0].RId, check.Equals, result.RId)
+
+	err = sender.Send("from@example.com", []string{"unknown@example.com"}, &mockWriterTo{campaign: &mc})
+	ch.Assert(err, check.NotNil)
+}
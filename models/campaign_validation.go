@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// CampaignValidation is the outcome of validating a campaign without creating
+// it. TotalRecipients counts every target in the campaign's groups, while
+// UniqueRecipients are those who would be sent an email once duplicates,
+// exclusions, fatigued targets and prior clickers are skipped. Emails are
+// spread over the whole total, so IntervalSeconds is the time between sends.
+type CampaignValidation struct {
+	LaunchDate       time.Time          `json:"launch_date"`
+	SendByDate       time.Time          `json:"send_by_date"`
+	IntervalSeconds  float64            `json:"interval_seconds"`
+	TotalRecipients  int                `json:"total_recipients"`
+	UniqueRecipients int                `json:"unique_recipients"`
+	Skipped          []SkippedRecipient `json:"skipped"`
+	Transport        string             `json:"transport"`
+	Warning          *RateLimitWarning  `json:"warning,omitempty"`
+}
+
+// ValidateCampaign resolves the campaign's groups, templates, pages and email
+// account the same way as PostCampaign, returning the send schedule it would
+// be created with. Nothing is written to the database.
+func ValidateCampaign(c *Campaign, uid int64) (CampaignValidation, error) {
+	v := CampaignValidation{Skipped: []SkippedRecipient{}}
+	total, warning, err := c.prepare(uid)
+	if err != nil {
+		return v, err
+	}
+	rr := c.resolveRecipients()
+	v.LaunchDate = c.LaunchDate
+	v.SendByDate = c.SendByDate
+	v.TotalRecipients = total
+	v.UniqueRecipients = len(rr.Recipients)
+	v.Skipped = append(v.Skipped, rr.Skipped...)
+	v.Transport = c.Transport
+	v.Warning = warning
+	if total > 0 && c.SendByDate.After(c.LaunchDate) {
+		v.IntervalSeconds = c.SendByDate.Sub(c.LaunchDate).Seconds() / float64(total)
+	}
+	return v, nil
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+// createValidationCampaign returns a campaign sent to the default group and
+// a second group repeating one of its targets.
+func (s *ModelsSuite) createValidationCampaign(ch *check.C) Campaign {
+	c := s.createCampaignDependencies(ch)
+	g := Group{Name: "Second Group", UserId: 1, Targets: []Target{
+		{BaseRecipient: BaseRecipient{Email: "test1@example.com", FirstName: "First", LastName: "Example"}},
+	}}
+	ch.Assert(PostGroup(&g), check.Equals, nil)
+	c.Groups = append(c.Groups, g)
+	return c
+}
+
+func (s *ModelsSuite) TestValidateCampaign(ch *check.C) {
+	c := s.createValidationCampaign(ch)
+	c.LaunchDate = time.Now().UTC()
+	c.SendByDate = c.LaunchDate.Add(time.Hour)
+
+	v, err := ValidateCampaign(&c, 1)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(v.TotalRecipients, check.Equals, 5)
+	ch.Assert(v.UniqueRecipients, check.Equals, 4)
+	ch.Assert(len(v.Skipped), check.Equals, 1)
+	ch.Assert(v.SendByDate.Equal(c.SendByDate), check.Equals, true)
+	ch.Assert(v.IntervalSeconds, check.Equals, 720.0)
+	ch.Assert(v.Warning, check.IsNil)
+
+	// Nothing is created
+	var count int
+	db.Model(&Campaign{}).Count(&count)
+	ch.Assert(count, check.Equals, 0)
+	db.Model(&Result{}).Count(&count)
+	ch.Assert(count, check.Equals, 0)
+}
+
+func (s *ModelsSuite) TestValidateCampaignRateLimitWarning(ch *check.C) {
+	c := s.createValidationCampaign(ch)
+	c.LaunchDate = time.Now().UTC()
+	c.SendByDate = c.LaunchDate.Add(time.Minute)
+
+	v, err := ValidateCampaign(&c, 1)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(v.Warning, check.NotNil)
+	ch.Assert(v.Warning.TotalRecipients, check.Equals, 5)
+}
+
+func (s *ModelsSuite) TestValidateCampaignErrors(ch *check.C) {
+	c := s.createValidationCampaign(ch)
+	early := c
+	early.LaunchDate = time.Now().UTC()
+	early.SendByDate = early.LaunchDate.Add(-time.Hour)
+	_, err := ValidateCampaign(&early, 1)
+	ch.Assert(err, check.Equals, ErrInvalidSendByDate)
+
+	missing := c
+	missing.Groups = []Group{{Name: "No Such Group"}}
+	_, err = ValidateCampaign(&missing, 1)
+	ch.Assert(err, check.Equals, ErrGroupNotFound)
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gophish/gophish/config"
+)
+
+// ErrContentTooLarge is thrown when a template or landing page body exceeds
+// the configured maximum size.
+var ErrContentTooLarge = errors.New("Content exceeds the maximum allowed size")
+
+// getMaxContentSize returns the configured maximum body size, falling back
+// to the default if the package config hasn't been set up.
+func getMaxContentSize() int {
+	if conf == nil {
+		return config.DefaultMaxContentSize
+	}
+	return conf.GetMaxContentSize()
+}
+
+// validateContentSize ensures that each of the provided bodies is within the
+// configured maximum size. The returned error wraps ErrContentTooLarge.
+func validateContentSize(bodies ...string) error {
+	max := getMaxContentSize()
+	for _, b := range bodies {
+		if len(b) > max {
+			return fmt.Errorf("%w (%d bytes, the limit is %d bytes)", ErrContentTooLarge, len(b), max)
+		}
+	}
+	return nil
+}
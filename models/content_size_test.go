@@ -0,0 +1,38 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestTemplateContentSizeBoundary(ch *check.C) {
+	original := conf.MaxContentSize
+	defer func() { conf.MaxContentSize = original }()
+	conf.MaxContentSize = 64
+
+	t := Template{Name: "Size Test", HTML: strings.Repeat("a", 64)}
+	ch.Assert(t.Validate(), check.Equals, nil)
+
+	t.HTML = strings.Repeat("a", 65)
+	ch.Assert(errors.Is(t.Validate(), ErrContentTooLarge), check.Equals, true)
+
+	t = Template{Name: "Size Test", Text: strings.Repeat("a", 65)}
+	ch.Assert(errors.Is(t.Validate(), ErrContentTooLarge), check.Equals, true)
+}
+
+func (s *ModelsSuite) TestPageContentSizeBoundary(ch *check.C) {
+	original := conf.MaxContentSize
+	defer func() { conf.MaxContentSize = original }()
+	conf.MaxContentSize = 64
+
+	html := "<html><body>"
+	html += strings.Repeat("a", 64-len(html)-len("</body></html>")) + "</body></html>"
+	p := Page{Name: "Size Test", HTML: html}
+	ch.Assert(len(p.HTML), check.Equals, 64)
+	ch.Assert(p.Validate(), check.Equals, nil)
+
+	p = Page{Name: "Size Test", HTML: html + "a"}
+	ch.Assert(errors.Is(p.Validate(), ErrContentTooLarge), check.Equals, true)
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/jinzhu/gorm"
+)
+
+// csrfKeyId is the ID of the single row holding the CSRF keys
+const csrfKeyId = 1
+
+// CSRFKey is the admin server's CSRF key as last rotated through the API,
+// stored so that the rotation lasts across restarts. The key it replaced is
+// kept until PreviousKeyExpiry, so that forms served before the rotation
+// keep working. ConfiguredKeyHash is the hash of the key which was configured
+// when the key was rotated, so that a key configured since takes over.
+//
+// The keys are encrypted with the field encryption key, if one is
+// configured.
+type CSRFKey struct {
+	Id                int64
+	Key               string
+	PreviousKey       string
+	PreviousKeyExpiry time.Time
+	ConfiguredKeyHash string
+	RotatedDate       time.Time
+}
+
+// TableName specifies the database tablename for Gorm to use
+func (k CSRFKey) TableName() string {
+	return "csrf_keys"
+}
+
+// GetCSRFKey returns the stored CSRF key. If the key has never been rotated,
+// an empty key is returned.
+func GetCSRFKey() (CSRFKey, error) {
+	k := CSRFKey{}
+	err := db.Where("id=?", csrfKeyId).First(&k).Error
+	if err == gorm.ErrRecordNotFound {
+		return CSRFKey{}, nil
+	} else if err != nil {
+		log.Error(err)
+		return k, err
+	}
+	k.Key, err = decryptField(k.Key)
+	if err != nil {
+		return k, err
+	}
+	k.PreviousKey, err = decryptField(k.PreviousKey)
+	return k, err
+}
+
+// PutCSRFKey stores the CSRF key, replacing the one stored before.
+func PutCSRFKey(k CSRFKey) error {
+	k.Id = csrfKeyId
+	var err error
+	k.Key, err = encryptField(k.Key)
+	if err != nil {
+		return err
+	}
+	k.PreviousKey, err = encryptField(k.PreviousKey)
+	if err != nil {
+		return err
+	}
+	err = db.Save(&k).Error
+	if err != nil {
+		log.Error(err)
+	}
+	return err
+}
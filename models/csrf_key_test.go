@@ -0,0 +1,39 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestCSRFKeyRoundTrip(ch *check.C) {
+	defer withFieldKeys(fieldKey('a'))()
+	defer db.Delete(CSRFKey{})
+
+	k, err := GetCSRFKey()
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(k.Key, check.Equals, "")
+
+	expiry := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	stored := CSRFKey{
+		Key:               strings.Repeat("n", 32),
+		PreviousKey:       strings.Repeat("o", 32),
+		PreviousKeyExpiry: expiry,
+		ConfiguredKeyHash: "hash",
+	}
+	ch.Assert(PutCSRFKey(stored), check.Equals, nil)
+
+	// The keys aren't stored in the clear
+	raw := CSRFKey{}
+	ch.Assert(db.Where("id=?", csrfKeyId).First(&raw).Error, check.Equals, nil)
+	ch.Assert(isEncryptedField(raw.Key), check.Equals, true)
+	ch.Assert(isEncryptedField(raw.PreviousKey), check.Equals, true)
+
+	k, err = GetCSRFKey()
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(k.Key, check.Equals, stored.Key)
+	ch.Assert(k.PreviousKey, check.Equals, stored.PreviousKey)
+	ch.Assert(k.PreviousKeyExpiry.Equal(expiry), check.Equals, true)
+	ch.Assert(k.ConfiguredKeyHash, check.Equals, "hash")
+}
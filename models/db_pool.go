@@ -0,0 +1,29 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/gophish/gophish/config"
+)
+
+// applyDBPool applies the configured connection pool settings to the
+// database.
+func applyDBPool(sqlDB *sql.DB, c *config.Config) {
+	p := c.GetDBPool()
+	sqlDB.SetMaxOpenConns(p.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(p.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(time.Duration(p.ConnMaxLifetime) * time.Second)
+}
+
+// queryContext returns a context which cancels a heavy query once the
+// configured query timeout has passed.
+func queryContext() (context.Context, context.CancelFunc) {
+	c := conf
+	if c == nil {
+		c = &config.Config{}
+	}
+	timeout := time.Duration(c.GetDBPool().QueryTimeout) * time.Second
+	return context.WithTimeout(context.Background(), timeout)
+}
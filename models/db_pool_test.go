@@ -0,0 +1,51 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/gophish/gophish/config"
+	check "gopkg.in/check.v1"
+)
+
+// poolTestDriver is a driver which is never connected to, used to check the
+// pool settings applied to a sql.DB.
+type poolTestDriver struct{}
+
+func (poolTestDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("not implemented")
+}
+
+func init() {
+	sql.Register("pooltest", poolTestDriver{})
+}
+
+func (s *ModelsSuite) TestApplyDBPool(c *check.C) {
+	sqlDB, err := sql.Open("pooltest", "")
+	c.Assert(err, check.Equals, nil)
+	defer sqlDB.Close()
+
+	applyDBPool(sqlDB, &config.Config{})
+	c.Assert(sqlDB.Stats().MaxOpenConnections, check.Equals, config.DefaultDBMaxOpenConns)
+
+	applyDBPool(sqlDB, &config.Config{DBPool: &config.DBPool{
+		MaxOpenConns:    25,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: 300,
+	}})
+	c.Assert(sqlDB.Stats().MaxOpenConnections, check.Equals, 25)
+}
+
+func (s *ModelsSuite) TestQueryContextTimeout(c *check.C) {
+	orig := conf
+	defer func() { conf = orig }()
+	conf = &config.Config{DBPool: &config.DBPool{QueryTimeout: 5}}
+	ctx, cancel := queryContext()
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	c.Assert(ok, check.Equals, true)
+	c.Assert(time.Until(deadline) <= 5*time.Second, check.Equals, true)
+	c.Assert(time.Until(deadline) > 4*time.Second, check.Equals, true)
+}
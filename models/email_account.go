@@ -11,8 +11,10 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/gophish/gophish/dialer"
 	log "github.com/gophish/gophish/logger"
 	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
 )
 
 // EmailAccount represents an email sender account used for campaigns
@@ -28,6 +30,14 @@ type EmailAccount struct {
 	IsActive          bool      `json:"is_active" gorm:"column:is_active; default:true"`
 	CreatedAt         time.Time `json:"created_at" gorm:"column:created_at"`
 	UpdatedAt         time.Time `json:"updated_at" gorm:"column:updated_at"`
+
+	// ConsecutiveFailures is the number of sends which have failed in a row
+	ConsecutiveFailures int `json:"consecutive_failures" gorm:"column:consecutive_failures; default:0"`
+
+	// SendIntervalSeconds paces campaigns sent from the account, so that it
+	// can send slower or faster than DEFAULT_EMAIL_SEND_INTERVAL. 0 uses the
+	// default interval.
+	SendIntervalSeconds int `json:"send_interval_seconds" gorm:"column:send_interval_seconds; default:0"`
 }
 
 // TableName specifies the table name for EmailAccount
@@ -43,6 +53,9 @@ func (ea *EmailAccount) Validate() error {
 	if ea.EmailType == "" {
 		return errors.New("email type is required")
 	}
+	if ea.SendIntervalSeconds < 0 {
+		return errors.New("send interval must be at least 1 second")
+	}
 
 	// Validate type exists in database and is active
 	if err := ValidateEmailType(ea.EmailType); err != nil {
@@ -52,6 +65,15 @@ func (ea *EmailAccount) Validate() error {
 	return nil
 }
 
+// SendInterval returns the interval between emails sent from the account,
+// falling back to the default send interval if the account doesn't set one.
+func (ea *EmailAccount) SendInterval() time.Duration {
+	if ea.SendIntervalSeconds > 0 {
+		return time.Duration(ea.SendIntervalSeconds) * time.Second
+	}
+	return GetDefaultSendInterval()
+}
+
 // GetEmailAccounts returns all email accounts from the database
 func GetEmailAccounts() ([]EmailAccount, error) {
 	accounts := []EmailAccount{}
@@ -175,6 +197,85 @@ func DeleteEmailAccount(id int64) error {
 	return nil
 }
 
+// ErrNoEmailAccountsSpecified is thrown when a bulk update doesn't name any
+// email accounts
+var ErrNoEmailAccountsSpecified = errors.New("No email accounts specified")
+
+// EmailAccountTypeChange is the outcome of changing the type of one of the
+// email accounts in a bulk update.
+type EmailAccountTypeChange struct {
+	Id      int64  `json:"id"`
+	Email   string `json:"email,omitempty"`
+	OldType string `json:"old_type,omitempty"`
+	NewType string `json:"new_type,omitempty"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// BulkUpdateEmailAccountType changes the type of the given email accounts in
+// a single transaction. The type is validated once up front, and accounts
+// aren't re-validated as their email addresses don't change. Accounts which
+// don't exist are reported as failed without stopping the others from being
+// updated, while a database error rolls back the whole update.
+func BulkUpdateEmailAccountType(ids []int64, emailType string, uid int64) ([]EmailAccountTypeChange, error) {
+	changes := []EmailAccountTypeChange{}
+	if len(ids) == 0 {
+		return changes, ErrNoEmailAccountsSpecified
+	}
+	if err := ValidateEmailType(emailType); err != nil {
+		return changes, err
+	}
+	now := time.Now().UTC()
+	seen := map[int64]bool{}
+	tx := db.Begin()
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		change := EmailAccountTypeChange{Id: id, NewType: emailType}
+		account := EmailAccount{}
+		err := tx.Where("id = ?", id).First(&account).Error
+		if err == gorm.ErrRecordNotFound {
+			change.Error = "email account not found"
+			changes = append(changes, change)
+			continue
+		} else if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		change.Email = account.Email
+		change.OldType = account.EmailType
+		if account.EmailType != emailType {
+			err = tx.Model(&EmailAccount{}).Where("id = ?", id).
+				Updates(map[string]interface{}{"email_type": emailType, "updated_at": now}).Error
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+		change.Success = true
+		changes = append(changes, change)
+	}
+	err := tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		if !change.Success || change.OldType == change.NewType {
+			continue
+		}
+		log.WithFields(logrus.Fields{
+			"user_id":          uid,
+			"email_account_id": change.Id,
+			"email":            change.Email,
+			"old_type":         change.OldType,
+			"new_type":         change.NewType,
+		}).Info("Changed email account type")
+	}
+	return changes, nil
+}
+
 // IncrementUsageCount increments the usage counter and updates last_used timestamp
 func (ea *EmailAccount) IncrementUsageCount() error {
 	ea.UsageCount++
@@ -277,8 +378,14 @@ func (ea *EmailAccount) CreateN8NCredential(credentialName string) (string, stri
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-N8N-API-KEY", n8nAPIKey)
 
-	// Execute the request
-	client := &http.Client{}
+	// Execute the request, waiting for a free slot if n8n is busy
+	release, err := GetN8NLimiter().Acquire(req.Context())
+	if err != nil {
+		log.Error(err)
+		return "", "", err
+	}
+	defer release()
+	client := &http.Client{Transport: dialer.Transport()}
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Error(err)
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/gophish/gophish/webhook"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultEmailAccountFailureThreshold is the default number of sends from an
+// email account which may fail in a row before it's deactivated.
+const DefaultEmailAccountFailureThreshold = 5
+
+// EmailAccountDeactivatedNotificationName is the event name of the webhook
+// sent when an email account is deactivated after repeated failures.
+const EmailAccountDeactivatedNotificationName = "email_account_deactivated"
+
+// EmailAccountDeactivatedNotification is the webhook payload sent to admins
+// when an email account is deactivated after repeated failures.
+type EmailAccountDeactivatedNotification struct {
+	Event               string    `json:"event"`
+	EmailAccountId      int64     `json:"email_account_id"`
+	Email               string    `json:"email"`
+	EmailType           string    `json:"email_type"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastError           string    `json:"last_error"`
+	Timestamp           time.Time `json:"timestamp"`
+}
+
+// GetEmailAccountFailureThreshold returns the number of sends from an email
+// account which may fail in a row before it's deactivated, configured by
+// EMAIL_ACCOUNT_FAILURE_THRESHOLD. A threshold of 0 never deactivates
+// accounts.
+func GetEmailAccountFailureThreshold() int {
+	s := os.Getenv("EMAIL_ACCOUNT_FAILURE_THRESHOLD")
+	if s == "" {
+		return DefaultEmailAccountFailureThreshold
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		log.Warnf("Invalid EMAIL_ACCOUNT_FAILURE_THRESHOLD value '%s', using default %d", s, DefaultEmailAccountFailureThreshold)
+		return DefaultEmailAccountFailureThreshold
+	}
+	return v
+}
+
+// campaignEmailAccountId returns the ID of the email account the campaign
+// sends from, or 0 if it doesn't have one.
+func campaignEmailAccountId(cid int64) (int64, error) {
+	ids := []int64{}
+	err := db.Model(&Campaign{}).Where("id = ?", cid).Pluck("email_account_id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// RecordEmailAccountSuccess resets the number of failed sends in a row from
+// the email account.
+func RecordEmailAccountSuccess(id int64) error {
+	return db.Model(&EmailAccount{}).Where("id = ? AND consecutive_failures <> ?", id, 0).
+		UpdateColumn("consecutive_failures", 0).Error
+}
+
+// RecordEmailAccountFailure counts a failed send from the email account. Once
+// the number of failures in a row reaches the threshold, the account is
+// deactivated so that it's no longer selected for campaigns, and admins are
+// notified.
+func RecordEmailAccountFailure(id int64, reason string) error {
+	err := db.Model(&EmailAccount{}).Where("id = ?", id).
+		UpdateColumn("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error
+	if err != nil {
+		return err
+	}
+	threshold := GetEmailAccountFailureThreshold()
+	if threshold == 0 {
+		return nil
+	}
+	ea, err := GetEmailAccount(id)
+	if err != nil {
+		return err
+	}
+	if !ea.IsActive || ea.ConsecutiveFailures < threshold {
+		return nil
+	}
+	// Only the failure which deactivates the account notifies admins
+	deactivate := db.Model(&EmailAccount{}).Where("id = ? AND is_active = ?", id, true).
+		UpdateColumns(map[string]interface{}{
+			"is_active":  false,
+			"updated_at": time.Now().UTC(),
+		})
+	if deactivate.Error != nil {
+		return deactivate.Error
+	}
+	if deactivate.RowsAffected == 0 {
+		return nil
+	}
+	log.WithFields(logrus.Fields{
+		"email_account_id":     ea.Id,
+		"email":                ea.Email,
+		"email_type":           ea.EmailType,
+		"consecutive_failures": ea.ConsecutiveFailures,
+		"last_error":           reason,
+	}).Warn("Deactivated email account after repeated send failures")
+	notifyEmailAccountDeactivated(ea, reason)
+	return nil
+}
+
+// notifyEmailAccountDeactivated notifies admins through every active webhook
+// that the email account was deactivated.
+func notifyEmailAccountDeactivated(ea EmailAccount, reason string) {
+	whEndPoints, err := getActiveWebhookEndPoints()
+	if err != nil {
+		log.Errorf("error getting active webhooks: %v", err)
+		return
+	}
+	webhook.SendAll(whEndPoints, EmailAccountDeactivatedNotification{
+		Event:               EmailAccountDeactivatedNotificationName,
+		EmailAccountId:      ea.Id,
+		Email:               ea.Email,
+		EmailType:           ea.EmailType,
+		ConsecutiveFailures: ea.ConsecutiveFailures,
+		LastError:           reason,
+		Timestamp:           time.Now().UTC(),
+	})
+}
+
+// recordCampaignSend records whether a send from the campaign's email account
+// succeeded. Failures to record it are logged rather than returned, since the
+// send itself has already been recorded.
+func recordCampaignSend(cid int64, sendErr error) {
+	id, err := campaignEmailAccountId(cid)
+	if err != nil || id == 0 {
+		if err != nil {
+			log.Errorf("Error finding the email account for campaign %d: %v", cid, err)
+		}
+		return
+	}
+	if sendErr == nil {
+		err = RecordEmailAccountSuccess(id)
+	} else {
+		err = RecordEmailAccountFailure(id, sendErr.Error())
+	}
+	if err != nil {
+		log.Errorf("Error recording send from email account %d: %v", id, err)
+	}
+}
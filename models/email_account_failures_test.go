@@ -0,0 +1,79 @@
+package models
+
+import (
+	"os"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestEmailAccountDeactivatedAfterFailures(ch *check.C) {
+	os.Setenv("EMAIL_ACCOUNT_FAILURE_THRESHOLD", "3")
+	defer os.Unsetenv("EMAIL_ACCOUNT_FAILURE_THRESHOLD")
+	ea := EmailAccount{Email: "noreply@example.com", EmailType: "noreply", IsActive: true}
+	ch.Assert(PostEmailAccount(&ea), check.Equals, nil)
+
+	for i := 1; i <= 2; i++ {
+		ch.Assert(RecordEmailAccountFailure(ea.Id, "credential expired"), check.Equals, nil)
+		got, err := GetEmailAccount(ea.Id)
+		ch.Assert(err, check.Equals, nil)
+		ch.Assert(got.ConsecutiveFailures, check.Equals, i)
+		ch.Assert(got.IsActive, check.Equals, true)
+	}
+
+	ch.Assert(RecordEmailAccountFailure(ea.Id, "credential expired"), check.Equals, nil)
+	got, err := GetEmailAccount(ea.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.IsActive, check.Equals, false)
+
+	// The deactivated account is no longer selected
+	_, err = GetEmailAccountByType("noreply")
+	ch.Assert(err, check.NotNil)
+}
+
+func (s *ModelsSuite) TestEmailAccountFailuresResetBySuccess(ch *check.C) {
+	os.Setenv("EMAIL_ACCOUNT_FAILURE_THRESHOLD", "3")
+	defer os.Unsetenv("EMAIL_ACCOUNT_FAILURE_THRESHOLD")
+	ea := EmailAccount{Email: "noreply@example.com", EmailType: "noreply", IsActive: true}
+	ch.Assert(PostEmailAccount(&ea), check.Equals, nil)
+
+	ch.Assert(RecordEmailAccountFailure(ea.Id, "timeout"), check.Equals, nil)
+	ch.Assert(RecordEmailAccountFailure(ea.Id, "timeout"), check.Equals, nil)
+	ch.Assert(RecordEmailAccountSuccess(ea.Id), check.Equals, nil)
+	ch.Assert(RecordEmailAccountFailure(ea.Id, "timeout"), check.Equals, nil)
+	ch.Assert(RecordEmailAccountFailure(ea.Id, "timeout"), check.Equals, nil)
+
+	got, err := GetEmailAccount(ea.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.ConsecutiveFailures, check.Equals, 2)
+	ch.Assert(got.IsActive, check.Equals, true)
+}
+
+func (s *ModelsSuite) TestEmailAccountFailuresFromN8NCallbacks(ch *check.C) {
+	os.Setenv("EMAIL_ACCOUNT_FAILURE_THRESHOLD", "2")
+	defer os.Unsetenv("EMAIL_ACCOUNT_FAILURE_THRESHOLD")
+	defer stubN8NLaunches(func(c *Campaign) error { return nil })()
+	c := s.createN8NCampaignDependencies(ch)
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	ch.Assert(len(c.Results) >= 3, check.Equals, true)
+
+	process := func(rid string, event string) {
+		cb := N8NCallback{IdempotencyKey: rid + event, RId: rid, CampaignId: c.Id, Event: event}
+		_, err := EnqueueN8NCallback(&cb)
+		ch.Assert(err, check.Equals, nil)
+		ch.Assert(ProcessN8NCallback(cb.Id), check.Equals, nil)
+	}
+	account := func() EmailAccount {
+		ea, err := GetEmailAccount(c.EmailAccountId)
+		ch.Assert(err, check.Equals, nil)
+		return ea
+	}
+
+	// Bounces don't count against the account
+	process(c.Results[0].RId, "bounce")
+	ch.Assert(account().ConsecutiveFailures, check.Equals, 0)
+
+	process(c.Results[1].RId, "error")
+	ch.Assert(account().ConsecutiveFailures, check.Equals, 1)
+	process(c.Results[2].RId, "sent")
+	ch.Assert(account().ConsecutiveFailures, check.Equals, 0)
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestBulkUpdateEmailAccountType(ch *check.C) {
+	ids := []int64{}
+	for _, email := range []string{"alerts@example.com", "updates@example.com", "help@example.com"} {
+		accountType := "notification"
+		if email == "help@example.com" {
+			accountType = "support"
+		}
+		a := EmailAccount{Email: email, EmailType: accountType, IsActive: true}
+		ch.Assert(PostEmailAccount(&a), check.Equals, nil)
+		ids = append(ids, a.Id)
+	}
+
+	changes, err := BulkUpdateEmailAccountType(append(ids, 9999), "support", 1)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(changes), check.Equals, 4)
+	for _, change := range changes[:3] {
+		ch.Assert(change.Success, check.Equals, true)
+		ch.Assert(change.NewType, check.Equals, "support")
+		a, err := GetEmailAccount(change.Id)
+		ch.Assert(err, check.Equals, nil)
+		ch.Assert(a.EmailType, check.Equals, "support")
+	}
+	ch.Assert(changes[0].OldType, check.Equals, "notification")
+	ch.Assert(changes[2].OldType, check.Equals, "support")
+	ch.Assert(changes[3].Success, check.Equals, false)
+	ch.Assert(changes[3].Error, check.Equals, "email account not found")
+}
+
+func (s *ModelsSuite) TestBulkUpdateEmailAccountTypeValidation(ch *check.C) {
+	a := EmailAccount{Email: "alerts@example.com", EmailType: "notification", IsActive: true}
+	ch.Assert(PostEmailAccount(&a), check.Equals, nil)
+
+	_, err := BulkUpdateEmailAccountType([]int64{}, "support", 1)
+	ch.Assert(err, check.Equals, ErrNoEmailAccountsSpecified)
+
+	_, err = BulkUpdateEmailAccountType([]int64{a.Id}, "not-a-type", 1)
+	ch.Assert(err, check.NotNil)
+	a, err = GetEmailAccount(a.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(a.EmailType, check.Equals, "notification")
+}
@@ -1,10 +1,16 @@
 package models
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"time"
 
 	"github.com/gophish/gophish/config"
+	ctx "github.com/gophish/gophish/context"
 	"gopkg.in/check.v1"
 )
 
@@ -103,6 +109,46 @@ func (s *EmailAuthorizationSuite) TestCheckEmailAuthorization(c *check.C) {
 	c.Assert(result.Reason, check.Equals, "not_authorized")
 }
 
+func (s *EmailAuthorizationSuite) TestCheckEmailAuthorizationBulk(c *check.C) {
+	_, err := AddAuthorizedEmail("analyst@example.com", nil, "analyst", nil, nil, "")
+	c.Assert(err, check.IsNil)
+	err = db.Create(&AuthorizedDomain{
+		Domain:      "partner.com",
+		Status:      "active",
+		DefaultRole: "user",
+		CreatedAt:   time.Now(),
+	}).Error
+	c.Assert(err, check.IsNil)
+
+	emails := []string{"analyst@example.com", "someone@partner.com", "stranger@example.com", "invalid"}
+	results, err := s.service.CheckEmailAuthorizationBulk(emails)
+	c.Assert(err, check.IsNil)
+	c.Assert(len(results), check.Equals, len(emails))
+
+	c.Assert(results[0].Authorized, check.Equals, true)
+	c.Assert(results[0].AuthMethod, check.Equals, "email")
+	c.Assert(results[0].Role, check.Equals, "analyst")
+
+	c.Assert(results[1].Authorized, check.Equals, true)
+	c.Assert(results[1].AuthMethod, check.Equals, "domain")
+	c.Assert(results[1].Role, check.Equals, "user")
+
+	c.Assert(results[2].Authorized, check.Equals, false)
+	c.Assert(results[2].Reason, check.Equals, "not_authorized")
+	c.Assert(results[2].Role, check.Equals, "")
+
+	c.Assert(results[3].Authorized, check.Equals, false)
+	c.Assert(results[3].Reason, check.Equals, "invalid_format")
+
+	// Checking emails must not authorize them
+	_, err = s.service.IsEmailAuthorized("stranger@example.com")
+	c.Assert(err, check.NotNil)
+
+	tooMany := make([]string, MaxBulkAuthorizationChecks+1)
+	_, err = s.service.CheckEmailAuthorizationBulk(tooMany)
+	c.Assert(err, check.Equals, ErrTooManyAuthorizationChecks)
+}
+
 func (s *EmailAuthorizationSuite) TestLogAuthorizationAttempt(c *check.C) {
 	email := "test@example.com"
 	action := "login_attempt"
@@ -208,6 +254,220 @@ func (s *EmailAuthorizationSuite) TestDeleteAuthorizedEmail(c *check.C) {
 	}
 }
 
+func (s *EmailAuthorizationSuite) TestValidateDomainFormat(c *check.C) {
+	validDomains := []string{"example.com", "Mail.Example.co.uk", "@partner.org", "example.com."}
+	for _, domain := range validDomains {
+		c.Assert(s.service.ValidateDomainFormat(domain), check.IsNil, check.Commentf("domain: %s", domain))
+	}
+
+	invalidDomains := []string{"", "localhost", "user@example.com", "-bad.com", "bad-.com", "exa mple.com", "example..com"}
+	for _, domain := range invalidDomains {
+		c.Assert(s.service.ValidateDomainFormat(domain), check.NotNil, check.Commentf("domain: %s", domain))
+	}
+}
+
+func (s *EmailAuthorizationSuite) TestAuthorizedDomainLifecycle(c *check.C) {
+	domain, err := AddAuthorizedDomain("Partner.COM", "user", nil, "Partner staff")
+	c.Assert(err, check.IsNil)
+	c.Assert(domain.Domain, check.Equals, "partner.com")
+	c.Assert(domain.Status, check.Equals, "active")
+
+	_, err = AddAuthorizedDomain("partner.com", "user", nil, "")
+	c.Assert(err, check.NotNil)
+
+	_, err = AddAuthorizedDomain("not a domain", "user", nil, "")
+	c.Assert(err, check.NotNil)
+
+	domains, err := GetAuthorizedDomains("", 0, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(len(domains), check.Equals, 1)
+	c.Assert(domains[0].Id, check.Equals, domain.Id)
+
+	// Domain authorization should now apply to addresses on the domain
+	_, err = s.service.IsEmailAuthorizedByDomain("someone@partner.com")
+	c.Assert(err, check.IsNil)
+
+	err = UpdateAuthorizedDomain(domain.Id, "suspended", "", "Paused")
+	c.Assert(err, check.IsNil)
+	updated, err := GetAuthorizedDomain(domain.Id)
+	c.Assert(err, check.IsNil)
+	c.Assert(updated.Status, check.Equals, "suspended")
+	c.Assert(updated.DefaultRole, check.Equals, "user")
+	c.Assert(updated.Notes, check.Equals, "Paused")
+
+	domains, err = GetAuthorizedDomains("active", 0, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(len(domains), check.Equals, 0)
+
+	err = UpdateAuthorizedDomain(domain.Id, "bogus", "", "")
+	c.Assert(err, check.Equals, ErrInvalidAuthorizationStatus)
+
+	err = UpdateAuthorizedDomain(domain.Id, "", "superuser", "")
+	c.Assert(err, check.Equals, ErrInvalidDefaultRole)
+	err = UpdateAuthorizedDomain(domain.Id, "", RoleAdmin, "")
+	c.Assert(err, check.IsNil)
+	updated, err = GetAuthorizedDomain(domain.Id)
+	c.Assert(err, check.IsNil)
+	c.Assert(updated.DefaultRole, check.Equals, RoleAdmin)
+
+	_, err = AddAuthorizedDomain("other.com", "superuser", nil, "")
+	c.Assert(err, check.Equals, ErrInvalidDefaultRole)
+
+	err = DeleteAuthorizedDomain(domain.Id)
+	c.Assert(err, check.IsNil)
+	domains, err = GetAuthorizedDomains("", 0, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(len(domains), check.Equals, 0)
+	_, err = s.service.IsEmailAuthorizedByDomain("someone@partner.com")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *EmailAuthorizationSuite) TestWriteAuthorizedEmailsCSV(c *check.C) {
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	_, err := AddAuthorizedEmail("b@example.com", nil, "user", nil, &expires, "Contractor, temporary")
+	c.Assert(err, check.IsNil)
+	suspended, err := AddAuthorizedEmail("a@example.com", nil, "admin", nil, nil, "")
+	c.Assert(err, check.IsNil)
+	c.Assert(UpdateAuthorizedEmailStatus(suspended.Id, "suspended", nil), check.IsNil)
+
+	buf := &bytes.Buffer{}
+	c.Assert(WriteAuthorizedEmailsCSV(buf, ""), check.IsNil)
+	records, err := csv.NewReader(buf).ReadAll()
+	c.Assert(err, check.IsNil)
+	c.Assert(len(records), check.Equals, 3)
+	c.Assert(records[0], check.DeepEquals, AuthorizedEmailCSVHeader)
+	c.Assert(records[1][0], check.Equals, "a@example.com")
+	c.Assert(records[1][1], check.Equals, "suspended")
+	c.Assert(records[1][3], check.Equals, "admin")
+	c.Assert(records[2][0], check.Equals, "b@example.com")
+	c.Assert(records[2][4], check.Equals, "2030-01-02T03:04:05Z")
+	c.Assert(records[2][5], check.Equals, "Contractor, temporary")
+
+	// The status filter matches the JSON listing
+	buf.Reset()
+	c.Assert(WriteAuthorizedEmailsCSV(buf, "active"), check.IsNil)
+	records, err = csv.NewReader(buf).ReadAll()
+	c.Assert(err, check.IsNil)
+	c.Assert(len(records), check.Equals, 2)
+	c.Assert(records[1][0], check.Equals, "b@example.com")
+}
+
+func (s *EmailAuthorizationSuite) TestWriteAuthorizedEmailsCSVRoleAndCreator(c *check.C) {
+	role, err := GetRoleBySlug(RoleAdmin)
+	c.Assert(err, check.IsNil)
+	creator, err := GetUser(1)
+	c.Assert(err, check.IsNil)
+	_, err = AddAuthorizedEmail("a@example.com", &role.ID, "user", &creator.Id, nil, "")
+	c.Assert(err, check.IsNil)
+	_, err = AddAuthorizedEmail("b@example.com", &role.ID, "user", &creator.Id, nil, "")
+	c.Assert(err, check.IsNil)
+	_, err = AddAuthorizedEmail("c@example.com", nil, "user", nil, nil, "")
+	c.Assert(err, check.IsNil)
+
+	buf := &bytes.Buffer{}
+	c.Assert(WriteAuthorizedEmailsCSV(buf, ""), check.IsNil)
+	records, err := csv.NewReader(buf).ReadAll()
+	c.Assert(err, check.IsNil)
+	c.Assert(len(records), check.Equals, 4)
+	for _, record := range records[1:3] {
+		c.Assert(record[2], check.Equals, RoleAdmin)
+		c.Assert(record[6], check.Equals, creator.Username)
+	}
+	c.Assert(records[3][2], check.Equals, "")
+	c.Assert(records[3][6], check.Equals, "")
+}
+
+func (s *EmailAuthorizationSuite) TestImportAuthorizedEmailsCSV(c *check.C) {
+	_, err := AddAuthorizedEmail("existing@example.com", nil, "user", nil, nil, "")
+	c.Assert(err, check.IsNil)
+
+	input := strings.Join([]string{
+		"notes,email,status,expires_at",
+		"Imported,new@example.com,active,2030-01-02T03:04:05Z",
+		",paused@example.com,suspended,",
+		",existing@example.com,,",
+		",not-an-email,,",
+		",bad-status@example.com,deleted,",
+		",bad-expiry@example.com,,tomorrow",
+	}, "\n")
+	report, err := ImportAuthorizedEmailsCSV(strings.NewReader(input), nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(report.Total, check.Equals, 6)
+	c.Assert(report.Imported, check.Equals, 2)
+	c.Assert(report.Skipped, check.Equals, 1)
+	c.Assert(len(report.Errors), check.Equals, 3)
+	c.Assert(report.Errors[0].Line, check.Equals, 5)
+
+	emails, err := GetAuthorizedEmails("suspended", 0, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(len(emails), check.Equals, 1)
+	c.Assert(emails[0].Email, check.Equals, "paused@example.com")
+
+	_, err = ImportAuthorizedEmailsCSV(strings.NewReader("name,status\nfoo,active"), nil)
+	c.Assert(err, check.Equals, ErrCSVEmailColumnMissing)
+}
+
+func (s *EmailAuthorizationSuite) TestSimulateEmailAuthorizationEmailMatch(c *check.C) {
+	_, err := AddAuthorizedEmail("alice@partner.com", nil, "admin", nil, nil, "")
+	c.Assert(err, check.IsNil)
+	_, err = AddAuthorizedDomain("partner.com", "user", nil, "")
+	c.Assert(err, check.IsNil)
+
+	sim, err := s.service.SimulateEmailAuthorization("Alice@Partner.com")
+	c.Assert(err, check.IsNil)
+	c.Assert(sim.Authorized, check.Equals, true)
+	c.Assert(sim.AuthMethod, check.Equals, "email")
+	c.Assert(sim.Role, check.Equals, "admin")
+	c.Assert(len(sim.Rules), check.Equals, 2)
+	c.Assert(sim.Rules[0].Type, check.Equals, "email")
+	c.Assert(sim.Rules[0].Matched, check.Equals, true)
+	// The email rule takes precedence over the domain rule
+	c.Assert(sim.Rules[1].Type, check.Equals, "domain")
+	c.Assert(sim.Rules[1].Matched, check.Equals, false)
+	c.Assert(sim.Rules[1].Note, check.Equals, "shadowed by email rule")
+
+	// Simulations aren't logged
+	logs, err := GetAuthorizationLogs("", "", "", 0, 0)
+	c.Assert(err, check.IsNil)
+	c.Assert(len(logs), check.Equals, 0)
+}
+
+func (s *EmailAuthorizationSuite) TestSimulateEmailAuthorizationDomainMatch(c *check.C) {
+	ae, err := AddAuthorizedEmail("bob@partner.com", nil, "admin", nil, nil, "")
+	c.Assert(err, check.IsNil)
+	c.Assert(UpdateAuthorizedEmailStatus(ae.Id, "revoked", nil), check.IsNil)
+	_, err = AddAuthorizedDomain("partner.com", "user", nil, "")
+	c.Assert(err, check.IsNil)
+
+	sim, err := s.service.SimulateEmailAuthorization("bob@partner.com")
+	c.Assert(err, check.IsNil)
+	c.Assert(sim.Authorized, check.Equals, true)
+	c.Assert(sim.AuthMethod, check.Equals, "domain")
+	c.Assert(sim.Role, check.Equals, "user")
+	c.Assert(len(sim.Rules), check.Equals, 2)
+	c.Assert(sim.Rules[0].Matched, check.Equals, false)
+	c.Assert(sim.Rules[0].Note, check.Equals, "email rule is revoked")
+	c.Assert(sim.Rules[1].Value, check.Equals, "partner.com")
+	c.Assert(sim.Rules[1].Matched, check.Equals, true)
+}
+
+func (s *EmailAuthorizationSuite) TestSimulateEmailAuthorizationNoMatch(c *check.C) {
+	_, err := AddAuthorizedDomain("partner.com", "user", nil, "")
+	c.Assert(err, check.IsNil)
+
+	sim, err := s.service.SimulateEmailAuthorization("carol@example.com")
+	c.Assert(err, check.IsNil)
+	c.Assert(sim.Authorized, check.Equals, false)
+	c.Assert(sim.Reason, check.Equals, "not_authorized")
+	c.Assert(sim.Role, check.Equals, "")
+	c.Assert(len(sim.Rules), check.Equals, 0)
+
+	sim, err = s.service.SimulateEmailAuthorization("not-an-email")
+	c.Assert(err, check.IsNil)
+	c.Assert(sim.Authorized, check.Equals, false)
+	c.Assert(sim.Reason, check.Equals, "invalid_format")
+}
+
 func (s *EmailAuthorizationSuite) TestGetAuthorizationLogs(c *check.C) {
 	ctx := context.Background()
 
@@ -243,6 +503,54 @@ func (s *EmailAuthorizationSuite) TestGetAuthorizationLogs(c *check.C) {
 	c.Assert(len(logs), check.Equals, 3)
 }
 
+func (s *EmailAuthorizationSuite) TestGetAuthorizationLogsBySource(c *check.C) {
+	now := time.Now()
+	testLogs := []EmailAuthorizationLog{
+		{Email: "user1@example.com", Action: "login", Result: "failed", IPAddress: "203.0.113.5", UserAgent: "Mozilla/5.0 (Windows NT 10.0)", CreatedAt: now.Add(-2 * time.Hour)},
+		{Email: "user2@example.com", Action: "login", Result: "failed", IPAddress: "203.0.113.50", UserAgent: "python-requests/2.31", CreatedAt: now.Add(-time.Hour)},
+		{Email: "user2@example.com", Action: "login", Result: "success", IPAddress: "198.51.100.7", UserAgent: "Mozilla/5.0 (Macintosh)", CreatedAt: now},
+	}
+	for _, l := range testLogs {
+		l.NormalizedEmail = s.service.NormalizeEmail(l.Email)
+		c.Assert(db.Create(&l).Error, check.IsNil)
+	}
+
+	// Filter by exact IP
+	logs, err := GetFilteredAuthorizationLogs(AuthorizationLogFilter{IPAddress: "203.0.113.5"})
+	c.Assert(err, check.IsNil)
+	c.Assert(len(logs), check.Equals, 1)
+	c.Assert(logs[0].Email, check.Equals, "user1@example.com")
+
+	// Filter by IP prefix
+	logs, err = GetFilteredAuthorizationLogs(AuthorizationLogFilter{IPAddress: "203.0.113.*"})
+	c.Assert(err, check.IsNil)
+	c.Assert(len(logs), check.Equals, 2)
+
+	// Filter by user agent substring, ignoring case
+	logs, err = GetFilteredAuthorizationLogs(AuthorizationLogFilter{UserAgent: "mozilla"})
+	c.Assert(err, check.IsNil)
+	c.Assert(len(logs), check.Equals, 2)
+
+	// Wildcards in the user agent are matched literally
+	logs, err = GetFilteredAuthorizationLogs(AuthorizationLogFilter{UserAgent: "%"})
+	c.Assert(err, check.IsNil)
+	c.Assert(len(logs), check.Equals, 0)
+
+	// Source filters combine with the others and the date range
+	logs, err = GetFilteredAuthorizationLogs(AuthorizationLogFilter{
+		IPAddress: "203.0.113.*",
+		Result:    "failed",
+		Start:     now.Add(-90 * time.Minute),
+		End:       now,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(len(logs), check.Equals, 1)
+	c.Assert(logs[0].Email, check.Equals, "user2@example.com")
+
+	_, err = GetFilteredAuthorizationLogs(AuthorizationLogFilter{Start: now, End: now.Add(-time.Hour)})
+	c.Assert(err, check.Equals, ErrInvalidActivityRange)
+}
+
 func (s *EmailAuthorizationSuite) TestEmailAuthorizationPerformance(c *check.C) {
 	// Add test email for performance testing
 	_, err := AddAuthorizedEmail("performance@example.com", nil, "user", nil, nil, "Performance test")
@@ -289,4 +597,30 @@ func (s *EmailAuthorizationSuite) TestEmailAuthorizationWithUnicodeEmails(c *che
 		normalized := s.service.NormalizeEmail(email)
 		c.Assert(normalized, check.Equals, strings.ToLower(email))
 	}
-}
\ No newline at end of file
+}
+
+func (s *EmailAuthorizationSuite) TestExtractIPFromRequestTrustedProxies(c *check.C) {
+	proxies := config.AdminServer{TrustedProxies: []string{"10.0.0.0/8"}}
+	testCases := []struct {
+		peer     string
+		headers  map[string]string
+		expected string
+	}{
+		// Headers from untrusted clients are ignored
+		{"198.51.100.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.5"}, "198.51.100.1"},
+		{"198.51.100.1:1234", map[string]string{"X-Real-IP": "203.0.113.5"}, "198.51.100.1"},
+		// The client is the last address which isn't a trusted proxy
+		{"10.0.0.2:1234", map[string]string{"X-Forwarded-For": "1.2.3.4, 203.0.113.5, 10.0.0.3"}, "203.0.113.5"},
+		{"10.0.0.2:1234", map[string]string{"X-Real-IP": "203.0.113.5"}, "203.0.113.5"},
+		{"10.0.0.2:1234", nil, "10.0.0.2"},
+	}
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodGet, "/login", nil)
+		r.RemoteAddr = "192.0.2.99:1234"
+		r = ctx.Set(r, PeerAddrKey, tc.peer)
+		for k, v := range tc.headers {
+			r.Header.Set(k, v)
+		}
+		c.Assert(extractClientIP(r, proxies), check.Equals, tc.expected)
+	}
+}
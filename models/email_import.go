@@ -0,0 +1,206 @@
+package models
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxEmailImportDepth is the deepest nesting of multipart bodies followed
+// when importing an email.
+const maxEmailImportDepth = 10
+
+// ErrEmailTooDeeplyNested is thrown when an imported email nests multipart
+// bodies deeper than is followed
+var ErrEmailTooDeeplyNested = errors.New("Email has too many nested parts")
+
+// inlineImageExtensions are the extensions given to inline images which are
+// imported without a filename, so that they're embedded when sent.
+var inlineImageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// cidRegex matches a cid: reference in HTML, up to the quote, whitespace or
+// bracket which ends it.
+var cidRegex = regexp.MustCompile(`cid:([^"'\s()<>]+)`)
+
+// emailImport holds the state of an email being imported as a template.
+type emailImport struct {
+	template *Template
+	// cids maps the Content-ID of each inline part to the name of the
+	// attachment it was imported as
+	cids  map[string]string
+	names map[string]bool
+}
+
+// ImportEmailTemplate parses a full email, such as a saved .eml file, into a
+// template. The subject, the text and HTML bodies, and any attachments and
+// inline images are extracted. Inline images are referenced by their
+// attachment name, which is how they're embedded when the template is sent,
+// so the HTML's cid: references are rewritten to match.
+func ImportEmailTemplate(r io.Reader) (Template, error) {
+	t := Template{Attachments: []Attachment{}}
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return t, err
+	}
+	t.Subject = decodeHeader(msg.Header.Get("Subject"))
+	ei := &emailImport{
+		template: &t,
+		cids:     map[string]string{},
+		names:    map[string]bool{},
+	}
+	err = ei.walk(textproto.MIMEHeader(msg.Header), msg.Body, 0)
+	if err != nil {
+		return t, err
+	}
+	t.HTML = ei.rewriteCIDs(t.HTML)
+	return t, nil
+}
+
+// rewriteCIDs replaces each cid: reference to an imported inline part with
+// the part's attachment name. References are matched whole and replaced in
+// a single pass, so a Content-ID which is a prefix of another, or of a
+// rewritten name, is left alone.
+func (ei *emailImport) rewriteCIDs(html string) string {
+	return cidRegex.ReplaceAllStringFunc(html, func(ref string) string {
+		if name, ok := ei.cids[strings.TrimPrefix(ref, "cid:")]; ok {
+			return "cid:" + name
+		}
+		return ref
+	})
+}
+
+// decodeHeader decodes any RFC 2047 encoded words in a header value.
+func decodeHeader(v string) string {
+	decoded, err := (&mime.WordDecoder{}).DecodeHeader(v)
+	if err != nil {
+		return v
+	}
+	return decoded
+}
+
+// walk imports the part with the given header and body, recursing into
+// multipart bodies.
+func (ei *emailImport) walk(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	if depth > maxEmailImportDepth {
+		return ErrEmailTooDeeplyNested
+	}
+	ct := header.Get("Content-Type")
+	if ct == "" {
+		ct = "text/plain"
+	}
+	mediaType, params, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return fmt.Errorf("invalid Content-Type %q: %v", ct, err)
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			err = ei.walk(p.Header, p, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body)
+	if err != nil {
+		return err
+	}
+	disposition, dparams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dparams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+	cid := strings.Trim(strings.TrimSpace(header.Get("Content-ID")), "<>")
+
+	t := ei.template
+	isBody := disposition != "attachment" && cid == "" && filename == ""
+	switch {
+	case isBody && mediaType == "text/html" && t.HTML == "":
+		t.HTML = string(content)
+		return nil
+	case isBody && mediaType == "text/plain" && t.Text == "":
+		t.Text = string(content)
+		return nil
+	}
+
+	name := ei.attachmentName(filename, cid, mediaType)
+	if cid != "" {
+		ei.cids[cid] = name
+	}
+	t.Attachments = append(t.Attachments, Attachment{
+		Name:    name,
+		Type:    mediaType,
+		Content: base64.StdEncoding.EncodeToString(content),
+	})
+	return nil
+}
+
+// attachmentName returns a unique name for an imported attachment. Parts
+// without a filename are named after their Content-ID, and inline images are
+// given an extension which causes them to be embedded when sent.
+func (ei *emailImport) attachmentName(filename string, cid string, mediaType string) string {
+	name := filepath.Base(strings.Replace(filename, "\\", "/", -1))
+	if filename == "" || name == "." || name == "/" {
+		name = "attachment"
+		if cid != "" {
+			name = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(`/\:*?"<>|`, r) {
+					return '_'
+				}
+				return r
+			}, cid)
+		}
+	}
+	if ext, ok := inlineImageExtensions[mediaType]; ok && cid != "" && !shouldEmbedAttachment(name) {
+		name += ext
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	unique := name
+	for i := 2; ei.names[strings.ToLower(unique)]; i++ {
+		unique = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	ei.names[strings.ToLower(unique)] = true
+	return unique
+}
+
+// decodeTransferEncoding returns the content of a part, decoded from its
+// Content-Transfer-Encoding.
+func decodeTransferEncoding(encoding string, body io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		// Some mail clients wrap base64 content with stray whitespace
+		raw, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		raw = bytes.Join(bytes.Fields(raw), nil)
+		return base64.StdEncoding.DecodeString(string(raw))
+	case "quoted-printable":
+		return ioutil.ReadAll(quotedprintable.NewReader(body))
+	}
+	return ioutil.ReadAll(body)
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"encoding/base64"
+	"strings"
+
+	check "gopkg.in/check.v1"
+)
+
+// multipartEML is a saved email with a text and HTML body, an inline image
+// referenced by its Content-ID and a PDF attachment.
+const multipartEML = "From: \"IT Support\" <it@example.com>\r\n" +
+	"To: jane@example.com\r\n" +
+	"Subject: =?UTF-8?Q?Password_expiry_=E2=80=93_action_required?=\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"mixed\"\r\n" +
+	"\r\n" +
+	"--mixed\r\n" +
+	"Content-Type: multipart/related; boundary=\"related\"\r\n" +
+	"\r\n" +
+	"--related\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"alt\"\r\n" +
+	"\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/plain; charset=UTF-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Your password expires today =E2=80=93 reset it now.\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/html; charset=UTF-8\r\n" +
+	"\r\n" +
+	"<html><body><img src=\"cid:logo@example.com\"><a href=\"https://example.com/reset\">Reset</a></body></html>\r\n" +
+	"--alt--\r\n" +
+	"--related\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"Content-ID: <logo@example.com>\r\n" +
+	"\r\n" +
+	"iVBORw0KGgo=\r\n" +
+	"--related--\r\n" +
+	"--mixed\r\n" +
+	"Content-Type: application/pdf; name=\"policy.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"policy.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQK\r\n" +
+	"--mixed--\r\n"
+
+func (s *ModelsSuite) TestImportEmailTemplate(c *check.C) {
+	t, err := ImportEmailTemplate(strings.NewReader(multipartEML))
+	c.Assert(err, check.Equals, nil)
+	c.Assert(t.Subject, check.Equals, "Password expiry – action required")
+	c.Assert(strings.TrimSpace(t.Text), check.Equals, "Your password expires today – reset it now.")
+	c.Assert(len(t.Attachments), check.Equals, 2)
+
+	// Inline images are named so that they're embedded, and the HTML refers
+	// to them by that name
+	image := t.Attachments[0]
+	c.Assert(image.Name, check.Equals, "logo@example.com.png")
+	c.Assert(image.Type, check.Equals, "image/png")
+	decoded, err := base64.StdEncoding.DecodeString(image.Content)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(string(decoded), check.Equals, "\x89PNG\r\n\x1a\n")
+	c.Assert(shouldEmbedAttachment(image.Name), check.Equals, true)
+	c.Assert(strings.Contains(t.HTML, `src="cid:logo@example.com.png"`), check.Equals, true)
+
+	pdf := t.Attachments[1]
+	c.Assert(pdf.Name, check.Equals, "policy.pdf")
+	c.Assert(pdf.Type, check.Equals, "application/pdf")
+	c.Assert(pdf.Content, check.Equals, "JVBERi0xLjQK")
+}
+
+func (s *ModelsSuite) TestImportEmailTemplateSinglePart(c *check.C) {
+	t, err := ImportEmailTemplate(strings.NewReader("Subject: Hello\r\nContent-Type: text/html\r\n\r\n<p>Hi</p>"))
+	c.Assert(err, check.Equals, nil)
+	c.Assert(t.Subject, check.Equals, "Hello")
+	c.Assert(t.HTML, check.Equals, "<p>Hi</p>")
+	c.Assert(t.Text, check.Equals, "")
+	c.Assert(len(t.Attachments), check.Equals, 0)
+}
+
+func (s *ModelsSuite) TestImportEmailTemplateDuplicateNames(c *check.C) {
+	ei := &emailImport{template: &Template{}, cids: map[string]string{}, names: map[string]bool{}}
+	c.Assert(ei.attachmentName("report.pdf", "", "application/pdf"), check.Equals, "report.pdf")
+	c.Assert(ei.attachmentName("Report.pdf", "", "application/pdf"), check.Equals, "Report-2.pdf")
+	c.Assert(ei.attachmentName("..\\..\\evil.exe", "", "application/octet-stream"), check.Equals, "evil.exe")
+	c.Assert(ei.attachmentName("", "", "application/octet-stream"), check.Equals, "attachment")
+	c.Assert(ei.attachmentName("photo.jpg", "photo", "image/jpeg"), check.Equals, "photo.jpg")
+}
+
+func (s *ModelsSuite) TestImportEmailTemplateCIDPrefixes(c *check.C) {
+	ei := &emailImport{template: &Template{}, cids: map[string]string{
+		"img1":  "banner.png",
+		"img10": "footer.png",
+		"a":     "a.png",
+	}, names: map[string]bool{}}
+	html := `<img src="cid:img1"><img src='cid:img10'><img src=cid:a><img src="cid:unknown">`
+	c.Assert(ei.rewriteCIDs(html), check.Equals,
+		`<img src="cid:banner.png"><img src='cid:footer.png'><img src=cid:a.png><img src="cid:unknown">`)
+}
@@ -18,6 +18,10 @@ const PreviewPrefix = "preview-"
 // ErrEmailTypeNotSpecified is returned when no email type is provided
 var ErrEmailTypeNotSpecified = errors.New("No email type specified")
 
+// ErrTestRecipientNotAllowed is returned when a test email is requested for
+// an address outside of the configured test recipient allow-list
+var ErrTestRecipientNotAllowed = errors.New("Test emails may not be sent to this address")
+
 // EmailRequest is the structure of a request
 // to send a test email to test an SMTP connection.
 // This type implements the mailer.Mail interface.
@@ -55,6 +59,8 @@ func (s *EmailRequest) Validate() error {
 		return ErrEmailNotSpecified
 	case s.EmailType == "":
 		return ErrEmailTypeNotSpecified
+	case conf != nil && !conf.IsTestRecipientAllowed(s.Email):
+		return ErrTestRecipientNotAllowed
 	}
 	return nil
 }
@@ -98,6 +104,28 @@ func GetEmailRequestByResultId(id string) (EmailRequest, error) {
 	return s, err
 }
 
+// GetLastEmailRequest returns the last test email request the user sent, with
+// the current version of its template and landing page, so that it can be
+// sent again after the template is edited.
+func GetLastEmailRequest(uid int64) (EmailRequest, error) {
+	s := EmailRequest{}
+	err := db.Where("user_id=?", uid).Order("id desc").First(&s).Error
+	if err != nil {
+		return s, err
+	}
+	s.Template, err = GetTemplate(s.TemplateId, uid)
+	if err != nil {
+		return s, err
+	}
+	if s.PageId != 0 {
+		s.Page, err = GetPage(s.PageId, uid)
+		if err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
 // Generate fills in the details of a gomail.Message with the contents
 // from the SendTestEmailRequest.
 func (s *EmailRequest) Generate(msg *gomail.Message) error {
@@ -155,6 +183,9 @@ func (s *EmailRequest) Generate(msg *gomail.Message) error {
 	}
 
 	log.Info("To header set successfully")
+	if err := validateContentSize(s.Template.Text, s.Template.HTML); err != nil {
+		return err
+	}
 	if s.Template.Text != "" {
 		text, err := ExecuteTemplate(s.Template.Text, ptx)
 		if err != nil {
@@ -163,10 +194,15 @@ func (s *EmailRequest) Generate(msg *gomail.Message) error {
 		msg.SetBody("text/plain", text)
 	}
 	if s.Template.HTML != "" {
-		html, err := ExecuteTemplate(s.Template.HTML, ptx)
+		html, err := ExecuteTemplate(s.Template.HTML, ptx.HTMLEscaped())
+		if err != nil {
+			log.Error(err)
+		}
+		preheader, err := renderPreheader(s.Template, ptx)
 		if err != nil {
 			log.Error(err)
 		}
+		html = injectPreheader(html, preheader)
 		if s.Template.Text == "" {
 			msg.SetBody("text/html", html)
 		} else {
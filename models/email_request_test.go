@@ -7,6 +7,7 @@ import (
 
 	"github.com/gophish/gomail"
 	"github.com/gophish/gophish/config"
+	"github.com/jinzhu/gorm"
 	"github.com/jordan-wright/email"
 	check "gopkg.in/check.v1"
 )
@@ -256,3 +257,51 @@ func (s *ModelsSuite) TestPostSendTestEmailRequest(ch *check.C) {
 	ch.Assert(got.RId, check.Equals, req.RId)
 	ch.Assert(got.Email, check.Equals, req.Email)
 }
+
+func (s *ModelsSuite) TestEmailRequestTestRecipientAllowList(ch *check.C) {
+	original := conf.TestRecipients
+	defer func() { conf.TestRecipients = original }()
+	conf.TestRecipients = &config.TestRecipients{AllowedDomains: []string{"example.com"}}
+
+	req := &EmailRequest{EmailType: "gmail"}
+	req.Email = "allowed@example.com"
+	ch.Assert(req.Validate(), check.Equals, nil)
+
+	req.Email = "target@elsewhere.com"
+	ch.Assert(req.Validate(), check.Equals, ErrTestRecipientNotAllowed)
+}
+
+func (s *ModelsSuite) TestGetLastEmailRequest(ch *check.C) {
+	_, err := GetLastEmailRequest(1)
+	ch.Assert(err, check.Equals, gorm.ErrRecordNotFound)
+
+	template := Template{Name: "Iterated Template", Text: "Version 1", UserId: 1}
+	ch.Assert(PostTemplate(&template), check.Equals, nil)
+	for _, email := range []string{"first@example.com", "last@example.com"} {
+		req := &EmailRequest{
+			Template:   template,
+			TemplateId: template.Id,
+			EmailType:  "noreply",
+			UserId:     1,
+			BaseRecipient: BaseRecipient{
+				Email:     email,
+				FirstName: "Test",
+			},
+		}
+		ch.Assert(PostEmailRequest(req), check.Equals, nil)
+	}
+	template.Text = "Version 2"
+	ch.Assert(PutTemplate(&template), check.Equals, nil)
+
+	got, err := GetLastEmailRequest(1)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.Email, check.Equals, "last@example.com")
+	ch.Assert(got.FirstName, check.Equals, "Test")
+	ch.Assert(got.EmailType, check.Equals, "noreply")
+	// The current version of the template is used
+	ch.Assert(got.Template.Text, check.Equals, "Version 2")
+
+	// Other users' requests aren't recalled
+	_, err = GetLastEmailRequest(2)
+	ch.Assert(err, check.Equals, gorm.ErrRecordNotFound)
+}
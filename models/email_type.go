@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	log "github.com/gophish/gophish/logger"
@@ -183,3 +184,25 @@ func DeleteEmailType(id int64) error {
 
 	return nil
 }
+
+// ErrNoActiveEmailAccount is returned when an email type has no active email
+// account to send from
+var ErrNoActiveEmailAccount = errors.New("No active email account for this email type")
+
+// ValidateEmailTypeHasActiveAccount checks that the email type exists, is
+// active and has an active email account to send from, so that campaigns of
+// the type don't fail only once they're sent.
+func ValidateEmailTypeHasActiveAccount(typeValue string) error {
+	if typeValue == "" {
+		return ErrEmailTypeNotSpecified
+	}
+	err := ValidateEmailType(typeValue)
+	if err != nil {
+		return fmt.Errorf("email type %q: %w", typeValue, err)
+	}
+	_, err = GetEmailAccountByType(typeValue)
+	if err == gorm.ErrRecordNotFound {
+		return fmt.Errorf("%w: add a %q email account or activate an existing one", ErrNoActiveEmailAccount, typeValue)
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"errors"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/gophish/gophish/webhook"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrEventNotFound indicates the requested event doesn't exist or doesn't
+// belong to the given campaign.
+var ErrEventNotFound = errors.New("Event not found")
+
+// ErrNoActiveWebhooks indicates there are no active webhooks to deliver an
+// event to.
+var ErrNoActiveWebhooks = errors.New("No active webhooks configured")
+
+// ReplayedEvent is the payload sent when an event is replayed. Unlike live
+// events, it includes the event's id so that consumers can recognise events
+// they've already received.
+type ReplayedEvent struct {
+	Id int64 `json:"id"`
+	Event
+}
+
+// WebhookReplayResult contains the outcome of re-sending an event to the
+// active webhooks.
+type WebhookReplayResult struct {
+	Event     ReplayedEvent `json:"event"`
+	Delivered int           `json:"delivered"`
+	Failed    []string      `json:"failed"`
+}
+
+// GetEvent returns the event with the given id, ensuring it belongs to the
+// given campaign owned by the given user.
+func GetEvent(id int64, cid int64, uid int64) (Event, error) {
+	e := Event{}
+	c := Campaign{}
+	err := db.Where("id=? and user_id=?", cid, uid).First(&c).Error
+	if err != nil {
+		return e, err
+	}
+	err = db.Where("id=? and campaign_id=?", id, c.Id).First(&e).Error
+	if err != nil {
+		return e, ErrEventNotFound
+	}
+	return e, nil
+}
+
+// ReplayEventWebhook re-sends a past campaign event to the currently active
+// webhooks. Unlike AddEvent, delivery is synchronous so that the caller can
+// report which endpoints failed.
+func ReplayEventWebhook(id int64, cid int64, uid int64) (WebhookReplayResult, error) {
+	e, err := GetEvent(id, cid, uid)
+	if err != nil {
+		return WebhookReplayResult{}, err
+	}
+	eps, err := getActiveWebhookEndPoints()
+	if err != nil {
+		return WebhookReplayResult{Event: newReplayedEvent(e)}, err
+	}
+	if len(eps) == 0 {
+		return WebhookReplayResult{Event: newReplayedEvent(e)}, ErrNoActiveWebhooks
+	}
+	return replayEvent(e, eps), nil
+}
+
+// newReplayedEvent returns the replay payload for the event.
+func newReplayedEvent(e Event) ReplayedEvent {
+	return ReplayedEvent{Id: e.Id, Event: e}
+}
+
+// replayEvent delivers the event to each of the endpoints, recording which
+// deliveries failed.
+func replayEvent(e Event, eps []webhook.EndPoint) WebhookReplayResult {
+	wr := WebhookReplayResult{Event: newReplayedEvent(e), Failed: []string{}}
+	for _, ep := range eps {
+		err := webhook.Send(ep, wr.Event)
+		if err != nil {
+			log.WithFields(logrus.Fields{
+				"event_id":    e.Id,
+				"campaign_id": e.CampaignId,
+				"url":         ep.URL,
+			}).Error("error replaying webhook event")
+			wr.Failed = append(wr.Failed, ep.URL)
+			continue
+		}
+		wr.Delivered++
+	}
+	return wr
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gophish/gophish/webhook"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestReplayEventSignsPayload(c *check.C) {
+	secret := "replay-secret"
+	e := Event{
+		Id:         42,
+		CampaignId: 7,
+		Email:      "foo@example.com",
+		Time:       time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Message:    EventClicked,
+	}
+	received := make(chan ReplayedEvent, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, check.Equals, nil)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		expected := webhook.Sha256Prefix + "=" + hex.EncodeToString(mac.Sum(nil))
+		c.Assert(r.Header.Get(webhook.SignatureHeader), check.Equals, expected)
+
+		got := ReplayedEvent{}
+		c.Assert(json.Unmarshal(body, &got), check.Equals, nil)
+		received <- got
+	}))
+	defer ts.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	wr := replayEvent(e, []webhook.EndPoint{
+		{URL: ts.URL, Secret: secret},
+		{URL: failing.URL, Secret: secret},
+	})
+	c.Assert(wr.Delivered, check.Equals, 1)
+	c.Assert(wr.Failed, check.DeepEquals, []string{failing.URL})
+
+	got := <-received
+	c.Assert(got.Id, check.Equals, e.Id)
+	c.Assert(wr.Event.Id, check.Equals, e.Id)
+	c.Assert(got.Message, check.Equals, e.Message)
+	c.Assert(got.Email, check.Equals, e.Email)
+}
+
+func (s *ModelsSuite) TestReplayEventWebhookOwnership(c *check.C) {
+	campaign := s.createCampaign(c)
+	c.Assert(len(campaign.Events), check.Not(check.Equals), 0)
+	e := campaign.Events[0]
+
+	_, err := ReplayEventWebhook(e.Id, campaign.Id, campaign.UserId+1)
+	c.Assert(err, check.NotNil)
+
+	_, err = ReplayEventWebhook(e.Id+1000, campaign.Id, campaign.UserId)
+	c.Assert(err, check.Equals, ErrEventNotFound)
+
+	_, err = ReplayEventWebhook(e.Id, campaign.Id, campaign.UserId)
+	c.Assert(err, check.Equals, ErrNoActiveWebhooks)
+}
+
+func (s *ModelsSuite) TestLiveEventOmitsId(c *check.C) {
+	// Live events are sent to webhooks before they're saved, so their id
+	// is left out rather than sent while it's still being set
+	b, err := json.Marshal(Event{Id: 42, Message: EventClicked})
+	c.Assert(err, check.Equals, nil)
+	fields := map[string]interface{}{}
+	c.Assert(json.Unmarshal(b, &fields), check.Equals, nil)
+	_, ok := fields["id"]
+	c.Assert(ok, check.Equals, false)
+}
@@ -0,0 +1,322 @@
+package models
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// encryptedFieldPrefix marks a field value as encrypted. It's followed by the
+// ID of the key which encrypted the value and the base64 encoded nonce and
+// ciphertext, separated by colons.
+const encryptedFieldPrefix = "enc:v1:"
+
+// ErrFieldKeyNotFound is returned when decrypting a field which was encrypted
+// with a key which isn't configured
+var ErrFieldKeyNotFound = errors.New("The key this field was encrypted with isn't configured")
+
+// ErrInvalidEncryptedField is returned when an encrypted field can't be
+// decrypted
+var ErrInvalidEncryptedField = errors.New("Invalid encrypted field")
+
+// fieldKeyID returns the ID stored with values encrypted by the key, so that
+// the right key is used to decrypt them once there are several.
+func fieldKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// fieldEncryptionKeys returns the configured field encryption keys, the first
+// of which encrypts new values.
+func fieldEncryptionKeys() ([][]byte, error) {
+	if conf == nil {
+		return nil, nil
+	}
+	return conf.GetFieldEncryptionKeys()
+}
+
+// isEncryptedField returns true if the value was encrypted by encryptField.
+func isEncryptedField(value string) bool {
+	return strings.HasPrefix(value, encryptedFieldPrefix)
+}
+
+// encryptField encrypts the value with the current field encryption key. If
+// no key is configured, or the value is empty or already encrypted, it's
+// returned as it is.
+func encryptField(value string) (string, error) {
+	if value == "" || isEncryptedField(value) {
+		return value, nil
+	}
+	keys, err := fieldEncryptionKeys()
+	if err != nil || len(keys) == 0 {
+		return value, err
+	}
+	gcm, err := newFieldCipher(keys[0])
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedFieldPrefix + fieldKeyID(keys[0]) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptField decrypts a value encrypted by encryptField with any of the
+// configured keys. Values which aren't encrypted are returned as they are.
+func decryptField(value string) (string, error) {
+	if !isEncryptedField(value) {
+		return value, nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(value, encryptedFieldPrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", ErrInvalidEncryptedField
+	}
+	keys, err := fieldEncryptionKeys()
+	if err != nil {
+		return "", err
+	}
+	var key []byte
+	for _, k := range keys {
+		if fieldKeyID(k) == parts[0] {
+			key = k
+			break
+		}
+	}
+	if key == nil {
+		return "", ErrFieldKeyNotFound
+	}
+	sealed, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrInvalidEncryptedField
+	}
+	gcm, err := newFieldCipher(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", ErrInvalidEncryptedField
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrInvalidEncryptedField
+	}
+	return string(plain), nil
+}
+
+// fieldEncryptedWithCurrentKey returns true if the value is encrypted with the
+// current key, or there's no key and it isn't encrypted.
+func fieldEncryptedWithCurrentKey(value string, keys [][]byte) bool {
+	if len(keys) == 0 {
+		return !isEncryptedField(value)
+	}
+	return strings.HasPrefix(value, encryptedFieldPrefix+fieldKeyID(keys[0])+":")
+}
+
+func newFieldCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// fieldIndex returns a keyed hash of the value, which is stored alongside an
+// encrypted field so that it can still be looked up. The key is derived from
+// the encryption key, so it changes when the keys are rotated.
+func fieldIndex(key []byte, value string) string {
+	derive := hmac.New(sha256.New, key)
+	derive.Write([]byte("gophish field index"))
+	mac := hmac.New(sha256.New, derive.Sum(nil))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// fieldIndexes returns the index of the value under each of the configured
+// keys, so that values indexed before the keys were rotated are found.
+func fieldIndexes(value string) ([]string, error) {
+	keys, err := fieldEncryptionKeys()
+	if err != nil {
+		return nil, err
+	}
+	indexes := make([]string, 0, len(keys))
+	for _, k := range keys {
+		indexes = append(indexes, fieldIndex(k, value))
+	}
+	return indexes, nil
+}
+
+// currentFieldIndex returns the index of the value under the current key, or
+// an empty string if the value is empty or there's no key.
+func currentFieldIndex(value string) (string, error) {
+	keys, err := fieldEncryptionKeys()
+	if err != nil || len(keys) == 0 || value == "" {
+		return "", err
+	}
+	return fieldIndex(keys[0], value), nil
+}
+
+// encryptedUser holds the encrypted fields of a user as they're stored.
+type encryptedUser struct {
+	Id           int64
+	OAuthID      string `gorm:"column:oauth_id"`
+	OAuthIDIndex string `gorm:"column:oauth_id_index"`
+}
+
+// encryptedEmailAccount holds the encrypted fields of an email account as
+// they're stored.
+type encryptedEmailAccount struct {
+	Id                int64
+	N8NCredentialID   string `gorm:"column:n8n_credential_id"`
+	N8NCredentialName string `gorm:"column:n8n_credential_name"`
+}
+
+// MigrateEncryptedFields encrypts any sensitive fields stored unencrypted or
+// with an earlier key using the current field encryption key. If no key is
+// configured, encrypted fields are decrypted. It's run on startup, so keys are
+// rotated by adding the new key first and restarting, after which the old key
+// can be removed.
+func MigrateEncryptedFields() error {
+	keys, err := fieldEncryptionKeys()
+	if err != nil {
+		return err
+	}
+	users := []encryptedUser{}
+	err = db.Table("users").Select("id, oauth_id, oauth_id_index").
+		Where("oauth_id IS NOT NULL AND oauth_id <> ''").Scan(&users).Error
+	if err != nil {
+		return err
+	}
+	migratedUsers := 0
+	for _, u := range users {
+		plain, err := decryptField(u.OAuthID)
+		if err != nil {
+			return err
+		}
+		index, err := currentFieldIndex(plain)
+		if err != nil {
+			return err
+		}
+		if fieldEncryptedWithCurrentKey(u.OAuthID, keys) && u.OAuthIDIndex == index {
+			continue
+		}
+		encrypted, err := encryptField(plain)
+		if err != nil {
+			return err
+		}
+		err = db.Table("users").Where("id=?", u.Id).UpdateColumns(map[string]interface{}{
+			"oauth_id":       encrypted,
+			"oauth_id_index": index,
+		}).Error
+		if err != nil {
+			return err
+		}
+		migratedUsers++
+	}
+
+	accounts := []encryptedEmailAccount{}
+	if !db.HasTable("email_accounts") {
+		return nil
+	}
+	err = db.Table("email_accounts").Select("id, n8n_credential_id, n8n_credential_name").Scan(&accounts).Error
+	if err != nil {
+		return err
+	}
+	migratedAccounts := 0
+	for _, a := range accounts {
+		current := (a.N8NCredentialID == "" || fieldEncryptedWithCurrentKey(a.N8NCredentialID, keys)) &&
+			(a.N8NCredentialName == "" || fieldEncryptedWithCurrentKey(a.N8NCredentialName, keys))
+		if current {
+			continue
+		}
+		updates := map[string]interface{}{}
+		for column, value := range map[string]string{
+			"n8n_credential_id":   a.N8NCredentialID,
+			"n8n_credential_name": a.N8NCredentialName,
+		} {
+			plain, err := decryptField(value)
+			if err != nil {
+				return err
+			}
+			updates[column], err = encryptField(plain)
+			if err != nil {
+				return err
+			}
+		}
+		err = db.Table("email_accounts").Where("id=?", a.Id).UpdateColumns(updates).Error
+		if err != nil {
+			return err
+		}
+		migratedAccounts++
+	}
+	if migratedUsers > 0 || migratedAccounts > 0 {
+		log.WithFields(logrus.Fields{
+			"users":          migratedUsers,
+			"email_accounts": migratedAccounts,
+			"encrypted":      len(keys) > 0,
+		}).Info("Migrated sensitive fields to the current field encryption key")
+	}
+	return nil
+}
+
+// BeforeSave encrypts the user's OAuth ID before it's stored, along with the
+// index it's looked up by.
+func (u *User) BeforeSave() error {
+	var err error
+	u.OAuthIDIndex, err = currentFieldIndex(u.OAuthID)
+	if err != nil {
+		return err
+	}
+	u.OAuthID, err = encryptField(u.OAuthID)
+	return err
+}
+
+// AfterSave decrypts the user's OAuth ID once it's stored.
+func (u *User) AfterSave() error {
+	return u.AfterFind()
+}
+
+// AfterFind decrypts the user's OAuth ID.
+func (u *User) AfterFind() error {
+	var err error
+	u.OAuthID, err = decryptField(u.OAuthID)
+	return err
+}
+
+// BeforeSave encrypts the account's n8n credential before it's stored.
+func (ea *EmailAccount) BeforeSave() error {
+	var err error
+	ea.N8NCredentialID, err = encryptField(ea.N8NCredentialID)
+	if err != nil {
+		return err
+	}
+	ea.N8NCredentialName, err = encryptField(ea.N8NCredentialName)
+	return err
+}
+
+// AfterSave decrypts the account's n8n credential once it's stored.
+func (ea *EmailAccount) AfterSave() error {
+	return ea.AfterFind()
+}
+
+// AfterFind decrypts the account's n8n credential.
+func (ea *EmailAccount) AfterFind() error {
+	var err error
+	ea.N8NCredentialID, err = decryptField(ea.N8NCredentialID)
+	if err != nil {
+		return err
+	}
+	ea.N8NCredentialName, err = decryptField(ea.N8NCredentialName)
+	return err
+}
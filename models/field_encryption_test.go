@@ -0,0 +1,141 @@
+package models
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/gophish/gophish/config"
+	check "gopkg.in/check.v1"
+)
+
+// fieldKey returns a base64 encoded field encryption key made of the byte.
+func fieldKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), config.FieldEncryptionKeySize)))
+}
+
+// withFieldKeys configures the field encryption keys, returning a function
+// which restores the config.
+func withFieldKeys(keys ...string) func() {
+	orig := conf
+	c := *orig
+	c.FieldEncryptionKeys = keys
+	conf = &c
+	return func() { conf = orig }
+}
+
+func (s *ModelsSuite) TestEncryptFieldRoundTrip(ch *check.C) {
+	defer withFieldKeys(fieldKey('a'))()
+	encrypted, err := encryptField("cred-1234")
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(isEncryptedField(encrypted), check.Equals, true)
+	ch.Assert(strings.Contains(encrypted, "cred-1234"), check.Equals, false)
+
+	// Each value is encrypted with its own nonce
+	again, err := encryptField("cred-1234")
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(again, check.Not(check.Equals), encrypted)
+
+	plain, err := decryptField(encrypted)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(plain, check.Equals, "cred-1234")
+}
+
+func (s *ModelsSuite) TestEncryptFieldWithoutKey(ch *check.C) {
+	defer withFieldKeys()()
+	encrypted, err := encryptField("cred-1234")
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(encrypted, check.Equals, "cred-1234")
+}
+
+func (s *ModelsSuite) TestDecryptFieldRotatedKeys(ch *check.C) {
+	restore := withFieldKeys(fieldKey('a'))
+	encrypted, err := encryptField("cred-1234")
+	restore()
+	ch.Assert(err, check.Equals, nil)
+
+	// The old key still decrypts values while the keys are rotated
+	restore = withFieldKeys(fieldKey('b'), fieldKey('a'))
+	plain, err := decryptField(encrypted)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(plain, check.Equals, "cred-1234")
+	ch.Assert(fieldEncryptedWithCurrentKey(encrypted, [][]byte{[]byte(strings.Repeat("b", 32))}), check.Equals, false)
+	restore()
+
+	defer withFieldKeys(fieldKey('b'))()
+	_, err = decryptField(encrypted)
+	ch.Assert(err, check.Equals, ErrFieldKeyNotFound)
+}
+
+func (s *ModelsSuite) TestEmailAccountCredentialEncryptedAtRest(ch *check.C) {
+	defer withFieldKeys(fieldKey('a'))()
+	a := EmailAccount{
+		Email:             "alerts@example.com",
+		EmailType:         "notification",
+		N8NCredentialID:   "cred-1234",
+		N8NCredentialName: "notification-1",
+		IsActive:          true,
+	}
+	ch.Assert(PostEmailAccount(&a), check.Equals, nil)
+	ch.Assert(a.N8NCredentialID, check.Equals, "cred-1234")
+
+	stored := encryptedEmailAccount{}
+	ch.Assert(db.Table("email_accounts").Where("id=?", a.Id).Scan(&stored).Error, check.Equals, nil)
+	ch.Assert(isEncryptedField(stored.N8NCredentialID), check.Equals, true)
+	ch.Assert(isEncryptedField(stored.N8NCredentialName), check.Equals, true)
+
+	got, err := GetEmailAccount(a.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.N8NCredentialID, check.Equals, "cred-1234")
+	ch.Assert(got.N8NCredentialName, check.Equals, "notification-1")
+}
+
+func (s *ModelsSuite) TestUserOAuthIDEncryptedAtRest(ch *check.C) {
+	defer withFieldKeys(fieldKey('a'))()
+	u, err := GetUser(1)
+	ch.Assert(err, check.Equals, nil)
+	u.OAuthProvider = "microsoft"
+	u.OAuthID = "oauth-subject"
+	ch.Assert(PutUser(&u), check.Equals, nil)
+	ch.Assert(u.OAuthID, check.Equals, "oauth-subject")
+
+	stored := encryptedUser{}
+	ch.Assert(db.Table("users").Where("id=?", u.Id).Scan(&stored).Error, check.Equals, nil)
+	ch.Assert(isEncryptedField(stored.OAuthID), check.Equals, true)
+	ch.Assert(stored.OAuthIDIndex, check.Not(check.Equals), "")
+
+	got, err := GetUserByOAuthID("microsoft", "oauth-subject")
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.Id, check.Equals, u.Id)
+	ch.Assert(got.OAuthID, check.Equals, "oauth-subject")
+}
+
+func (s *ModelsSuite) TestMigrateEncryptedFields(ch *check.C) {
+	// Values stored before a key was configured are encrypted on startup
+	restore := withFieldKeys()
+	u, err := GetUser(1)
+	ch.Assert(err, check.Equals, nil)
+	u.OAuthProvider = "microsoft"
+	u.OAuthID = "oauth-subject"
+	ch.Assert(PutUser(&u), check.Equals, nil)
+	restore()
+
+	restore = withFieldKeys(fieldKey('a'))
+	ch.Assert(MigrateEncryptedFields(), check.Equals, nil)
+	stored := encryptedUser{}
+	ch.Assert(db.Table("users").Where("id=?", u.Id).Scan(&stored).Error, check.Equals, nil)
+	ch.Assert(isEncryptedField(stored.OAuthID), check.Equals, true)
+	restore()
+
+	// Rotating the key re-encrypts them with the new key
+	defer withFieldKeys(fieldKey('b'), fieldKey('a'))()
+	ch.Assert(MigrateEncryptedFields(), check.Equals, nil)
+	rotated := encryptedUser{}
+	ch.Assert(db.Table("users").Where("id=?", u.Id).Scan(&rotated).Error, check.Equals, nil)
+	ch.Assert(rotated.OAuthID, check.Not(check.Equals), stored.OAuthID)
+	ch.Assert(rotated.OAuthIDIndex, check.Not(check.Equals), stored.OAuthIDIndex)
+
+	conf.FieldEncryptionKeys = []string{fieldKey('b')}
+	got, err := GetUserByOAuthID("microsoft", "oauth-subject")
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.OAuthID, check.Equals, "oauth-subject")
+}
@@ -18,6 +18,7 @@ type Group struct {
 	UserId       int64     `json:"-"`
 	Name         string    `json:"name"`
 	ModifiedDate time.Time `json:"modified_date"`
+	Autopilot    bool      `json:"autopilot"`
 	Targets      []Target  `json:"targets" sql:"-"`
 }
 
@@ -34,6 +35,7 @@ type GroupSummary struct {
 	Id           int64     `json:"id"`
 	Name         string    `json:"name"`
 	ModifiedDate time.Time `json:"modified_date"`
+	Autopilot    bool      `json:"autopilot"`
 	NumTargets   int64     `json:"num_targets"`
 }
 
@@ -103,7 +105,15 @@ func (g *Group) Validate() error {
 	case len(g.Targets) == 0:
 		return ErrNoTargetsSpecified
 	}
-	return nil
+	return validateName("group", g.Name)
+}
+
+// normalizeTargets strips template syntax and control characters from the
+// name and position fields of every target in the group.
+func (g *Group) normalizeTargets() {
+	for i := range g.Targets {
+		g.Targets[i].Normalize()
+	}
 }
 
 // GetGroups returns the groups owned by the given user.
@@ -128,7 +138,7 @@ func GetGroups(uid int64) ([]Group, error) {
 func GetGroupSummaries(uid int64) (GroupSummaries, error) {
 	gs := GroupSummaries{}
 	query := db.Table("groups").Where("user_id=?", uid)
-	err := query.Select("id, name, modified_date").Scan(&gs.Groups).Error
+	err := query.Select("id, name, modified_date, autopilot").Scan(&gs.Groups).Error
 	if err != nil {
 		log.Error(err)
 		return gs, err
@@ -163,7 +173,7 @@ func GetGroup(id int64, uid int64) (Group, error) {
 func GetGroupSummary(id int64, uid int64) (GroupSummary, error) {
 	g := GroupSummary{}
 	query := db.Table("groups").Where("user_id=? and id=?", uid, id)
-	err := query.Select("id, name, modified_date").Scan(&g).Error
+	err := query.Select("id, name, modified_date, autopilot").Scan(&g).Error
 	if err != nil {
 		log.Error(err)
 		return g, err
@@ -196,6 +206,7 @@ func PostGroup(g *Group) error {
 	if err := g.Validate(); err != nil {
 		return err
 	}
+	g.normalizeTargets()
 	// Insert the group into the DB
 	tx := db.Begin()
 	err := tx.Save(g).Error
@@ -226,6 +237,7 @@ func PutGroup(g *Group) error {
 	if err := g.Validate(); err != nil {
 		return err
 	}
+	g.normalizeTargets()
 	// Fetch group's existing targets from database.
 	ts, err := GetTargets(g.Id)
 	if err != nil {
@@ -304,6 +316,12 @@ func DeleteGroup(g *Group) error {
 		log.Error(err)
 		return err
 	}
+	// Forget which campaigns were created from it
+	err = db.Where("group_id=?", g.Id).Delete(&CampaignGroup{}).Error
+	if err != nil {
+		log.Error(err)
+		return err
+	}
 	// Delete the group itself
 	err = db.Delete(g).Error
 	if err != nil {
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"os"
+	"strings"
+)
+
+// GetInternalTrackingDomains returns the domains whose recipients are
+// internal or test accounts, configured as a comma separated list by
+// INTERNAL_TRACKING_DOMAINS. Their emails are still sent, but their results
+// are flagged as internal and left out of the campaign's engagement stats.
+func GetInternalTrackingDomains() []string {
+	domains := []string{}
+	for _, d := range strings.Split(os.Getenv("INTERNAL_TRACKING_DOMAINS"), ",") {
+		d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "@")
+		if d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+// isInternalEmail returns whether the email address belongs to one of the
+// given domains or their subdomains.
+func isInternalEmail(email string, domains []string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	for _, d := range domains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"os"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestGetInternalTrackingDomains(ch *check.C) {
+	os.Setenv("INTERNAL_TRACKING_DOMAINS", " Example.com, @test.example.org,, ")
+	defer os.Unsetenv("INTERNAL_TRACKING_DOMAINS")
+	domains := GetInternalTrackingDomains()
+	ch.Assert(domains, check.DeepEquals, []string{"example.com", "test.example.org"})
+
+	ch.Assert(isInternalEmail("qa@EXAMPLE.com", domains), check.Equals, true)
+	ch.Assert(isInternalEmail("qa@mail.test.example.org", domains), check.Equals, true)
+	ch.Assert(isInternalEmail("qa@notexample.com", domains), check.Equals, false)
+	ch.Assert(isInternalEmail("qa@example.org", domains), check.Equals, false)
+	ch.Assert(isInternalEmail("not-an-email", domains), check.Equals, false)
+
+	os.Unsetenv("INTERNAL_TRACKING_DOMAINS")
+	ch.Assert(len(GetInternalTrackingDomains()), check.Equals, 0)
+}
+
+func (s *ModelsSuite) TestInternalResultsExcludedFromStats(ch *check.C) {
+	os.Setenv("INTERNAL_TRACKING_DOMAINS", "example.org")
+	defer os.Unsetenv("INTERNAL_TRACKING_DOMAINS")
+
+	c := s.createCampaignDependencies(ch)
+	g := Group{Name: "Internal Group", UserId: 1, Targets: []Target{
+		{BaseRecipient: BaseRecipient{Email: "qa1@example.org"}},
+		{BaseRecipient: BaseRecipient{Email: "qa2@corp.example.org"}},
+	}}
+	ch.Assert(PostGroup(&g), check.Equals, nil)
+	c.Groups = append(c.Groups, g)
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+
+	// Internal recipients are still sent their email
+	ch.Assert(len(c.Results), check.Equals, 6)
+	ch.Assert(countCampaignMailLogs(ch, c.Id), check.Equals, 6)
+
+	internal := 0
+	for _, r := range c.Results {
+		status := EventClicked
+		if r.Internal {
+			internal++
+			status = EventDataSubmit
+		}
+		err := db.Model(&Result{}).Where("id=?", r.Id).
+			Updates(map[string]interface{}{"status": status, "reported": r.Internal}).Error
+		ch.Assert(err, check.Equals, nil)
+	}
+	ch.Assert(internal, check.Equals, 2)
+
+	stats, err := getCampaignStats(c.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(stats.Total, check.Equals, int64(6))
+	ch.Assert(stats.EmailsSent, check.Equals, int64(6))
+	ch.Assert(stats.OpenedEmail, check.Equals, int64(4))
+	ch.Assert(stats.ClickedLink, check.Equals, int64(4))
+	ch.Assert(stats.SubmittedData, check.Equals, int64(0))
+	ch.Assert(stats.EmailReported, check.Equals, int64(0))
+
+	// Recomputing the stats excludes them the same way
+	rc, err := RecomputeCampaignStats(c.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(rc.After, check.DeepEquals, stats)
+}
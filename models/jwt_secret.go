@@ -0,0 +1,128 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	log "github.com/gophish/gophish/logger"
+)
+
+// DefaultJWTSecretMinLength is the shortest JWT_SECRET considered strong,
+// unless JWT_SECRET_MIN_LENGTH is set.
+const DefaultJWTSecretMinLength = 32
+
+// DefaultJWTSecretMinEntropy is the lowest estimated entropy, in bits, of a
+// JWT_SECRET considered strong, unless JWT_SECRET_MIN_ENTROPY is set.
+const DefaultJWTSecretMinEntropy = 96
+
+// ErrWeakJWTSecret is thrown at startup in strict mode when JWT_SECRET is
+// too short or too predictable
+var ErrWeakJWTSecret = errors.New("JWT_SECRET is too weak to sign n8n requests")
+
+// JWTSecretCheck is the result of checking the strength of JWT_SECRET. It
+// never includes the secret itself.
+type JWTSecretCheck struct {
+	Configured     bool     `json:"configured"`
+	Length         int      `json:"length"`
+	EntropyBits    int      `json:"entropy_bits"`
+	MinLength      int      `json:"min_length"`
+	MinEntropyBits int      `json:"min_entropy_bits"`
+	Strong         bool     `json:"strong"`
+	Strict         bool     `json:"strict"`
+	Issues         []string `json:"issues,omitempty"`
+}
+
+// jwtSecretSettings returns the minimum length and entropy of JWT_SECRET,
+// configured by JWT_SECRET_MIN_LENGTH and JWT_SECRET_MIN_ENTROPY, and whether
+// a weak secret stops Gophish from starting, configured by JWT_SECRET_STRICT.
+func jwtSecretSettings() (int, int, bool) {
+	minLength := DefaultJWTSecretMinLength
+	if s := os.Getenv("JWT_SECRET_MIN_LENGTH"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			log.Warnf("Invalid JWT_SECRET_MIN_LENGTH value '%s', using default %d", s, DefaultJWTSecretMinLength)
+		} else {
+			minLength = v
+		}
+	}
+	minEntropy := DefaultJWTSecretMinEntropy
+	if s := os.Getenv("JWT_SECRET_MIN_ENTROPY"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			log.Warnf("Invalid JWT_SECRET_MIN_ENTROPY value '%s', using default %d", s, DefaultJWTSecretMinEntropy)
+		} else {
+			minEntropy = v
+		}
+	}
+	strict, _ := strconv.ParseBool(os.Getenv("JWT_SECRET_STRICT"))
+	return minLength, minEntropy, strict
+}
+
+// estimateEntropyBits estimates the entropy of a secret from how often each
+// of its characters occurs. It's an upper bound for secrets built from words
+// or patterns, but reliably flags short, repetitive or single character
+// class secrets.
+func estimateEntropyBits(secret string) float64 {
+	runes := []rune(secret)
+	if len(runes) == 0 {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, r := range runes {
+		counts[r]++
+	}
+	perChar := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(len(runes))
+		perChar -= p * math.Log2(p)
+	}
+	return perChar * float64(len(runes))
+}
+
+// CheckJWTSecret checks the strength of the given secret against the
+// configured minimums.
+func CheckJWTSecret(secret string) JWTSecretCheck {
+	minLength, minEntropy, strict := jwtSecretSettings()
+	c := JWTSecretCheck{
+		Configured:     secret != "",
+		Length:         len([]rune(secret)),
+		EntropyBits:    int(estimateEntropyBits(secret)),
+		MinLength:      minLength,
+		MinEntropyBits: minEntropy,
+		Strict:         strict,
+	}
+	if !c.Configured {
+		c.Issues = append(c.Issues, "JWT_SECRET is not set")
+		return c
+	}
+	if c.Length < minLength {
+		c.Issues = append(c.Issues, fmt.Sprintf("JWT_SECRET is %d characters, shorter than the minimum of %d", c.Length, minLength))
+	}
+	if c.EntropyBits < minEntropy {
+		c.Issues = append(c.Issues, fmt.Sprintf("JWT_SECRET has an estimated %d bits of entropy, below the minimum of %d", c.EntropyBits, minEntropy))
+	}
+	c.Strong = len(c.Issues) == 0
+	return c
+}
+
+// ValidateJWTSecret checks the strength of JWT_SECRET at startup, logging a
+// warning for each problem found. In strict mode, ErrWeakJWTSecret is
+// returned for a weak secret so that Gophish refuses to start. Nothing is
+// checked if no secret is set, since n8n isn't in use.
+func ValidateJWTSecret() error {
+	c := CheckJWTSecret(os.Getenv("JWT_SECRET"))
+	if !c.Configured || c.Strong {
+		return nil
+	}
+	for _, issue := range c.Issues {
+		log.Warn(issue)
+	}
+	log.Warn("Generate a strong JWT_SECRET with: openssl rand -hex 32")
+	if c.Strict {
+		return ErrWeakJWTSecret
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"os"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestCheckJWTSecret(c *check.C) {
+	for _, secret := range []string{
+		"secret",
+		"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		"abababababababababababababababababababab",
+		"0123456789abcdef",
+	} {
+		weak := CheckJWTSecret(secret)
+		c.Assert(weak.Configured, check.Equals, true)
+		c.Assert(weak.Strong, check.Equals, false, check.Commentf("secret %q", secret))
+		c.Assert(len(weak.Issues) > 0, check.Equals, true)
+	}
+
+	// The output of openssl rand -hex 32
+	strong := CheckJWTSecret("3f6c1e0a9b7d24c58e1f0a6b3d9c7e25a4b8f0c1d2e3a4b5c6d7e8f9a0b1c2d3")
+	c.Assert(strong.Strong, check.Equals, true)
+	c.Assert(strong.Length, check.Equals, 64)
+	c.Assert(len(strong.Issues), check.Equals, 0)
+
+	missing := CheckJWTSecret("")
+	c.Assert(missing.Configured, check.Equals, false)
+	c.Assert(missing.Strong, check.Equals, false)
+}
+
+func (s *ModelsSuite) TestValidateJWTSecret(c *check.C) {
+	defer os.Unsetenv("JWT_SECRET")
+	defer os.Unsetenv("JWT_SECRET_STRICT")
+	os.Setenv("JWT_SECRET", "short")
+
+	// Weak secrets only log a warning unless strict mode is enabled
+	c.Assert(ValidateJWTSecret(), check.Equals, nil)
+	os.Setenv("JWT_SECRET_STRICT", "true")
+	c.Assert(ValidateJWTSecret(), check.Equals, ErrWeakJWTSecret)
+
+	os.Setenv("JWT_SECRET", "3f6c1e0a9b7d24c58e1f0a6b3d9c7e25a4b8f0c1d2e3a4b5c6d7e8f9a0b1c2d3")
+	c.Assert(ValidateJWTSecret(), check.Equals, nil)
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrInvalidLaunchSpec indicates that a relative launch isn't in the
+// supported form
+var ErrInvalidLaunchSpec = errors.New("Relative launch must be in the form \"next <weekday> <HH:MM> <timezone>\", such as \"next Monday 09:00 America/New_York\"")
+
+// ErrAmbiguousLaunchTimezone indicates that a relative launch names a
+// timezone by an abbreviation, such as EST, rather than its IANA name
+var ErrAmbiguousLaunchTimezone = errors.New("Relative launch timezone must be an IANA name, such as America/New_York, or UTC")
+
+// ErrAmbiguousLaunchTime indicates that a relative launch falls on a time
+// which is skipped or repeated by a daylight saving transition
+var ErrAmbiguousLaunchTime = errors.New("Relative launch time is skipped or repeated by a daylight saving change on that day")
+
+// ErrLaunchSpecConflict indicates that both a launch date and a relative
+// launch were given
+var ErrLaunchSpecConflict = errors.New("Specify either a launch date or a relative launch, not both")
+
+// launchSpecWeekdays maps the weekday names accepted in a relative launch,
+// in full or abbreviated, to their weekdays
+var launchSpecWeekdays = map[string]time.Weekday{}
+
+func init() {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		launchSpecWeekdays[name] = d
+		launchSpecWeekdays[name[:3]] = d
+	}
+}
+
+// ResolveLaunchSpec resolves a relative launch, such as
+// "next Monday 09:00 America/New_York", to an absolute time in UTC. The time
+// of day is on the 24 hour clock in the given timezone. "Next" is always the
+// first matching day after the current day in that timezone, so a launch
+// requested on a Monday for "next Monday" is a week later.
+func ResolveLaunchSpec(spec string, now time.Time) (time.Time, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 4 || !strings.EqualFold(fields[0], "next") {
+		return time.Time{}, ErrInvalidLaunchSpec
+	}
+	weekday, ok := launchSpecWeekdays[strings.ToLower(fields[1])]
+	if !ok {
+		return time.Time{}, ErrInvalidLaunchSpec
+	}
+	clock, err := ParseClock(fields[2])
+	if err != nil {
+		return time.Time{}, ErrInvalidLaunchSpec
+	}
+	// Abbreviations such as EST or CST are shared by several timezones, and
+	// "Local" depends on the server, so only IANA names are accepted
+	name := fields[3]
+	if name != "UTC" && !strings.Contains(name, "/") {
+		return time.Time{}, ErrAmbiguousLaunchTimezone
+	}
+	loc, err := LoadScheduleLocation(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	local := now.In(loc)
+	days := (int(weekday) - int(local.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	day := time.Date(local.Year(), local.Month(), local.Day()+days, 0, 0, 0, 0, loc)
+	t := atLocalClock(day, clock)
+	if t.Hour() != int(clock/time.Hour) || t.Minute() != int((clock%time.Hour)/time.Minute) {
+		return time.Time{}, ErrAmbiguousLaunchTime
+	}
+	// The same wall clock time occurs twice when the clocks go back
+	for _, d := range []time.Duration{-time.Hour, -30 * time.Minute, 30 * time.Minute, time.Hour} {
+		other := t.Add(d).In(loc)
+		if other.Day() == t.Day() && other.Hour() == t.Hour() && other.Minute() == t.Minute() {
+			return time.Time{}, ErrAmbiguousLaunchTime
+		}
+	}
+	return t.UTC(), nil
+}
+
+// resolveLaunchAt sets the campaign's launch date from its relative launch,
+// if one was given.
+func (c *Campaign) resolveLaunchAt(now time.Time) error {
+	if c.LaunchAt == "" {
+		return nil
+	}
+	if !c.LaunchDate.IsZero() {
+		return ErrLaunchSpecConflict
+	}
+	launch, err := ResolveLaunchSpec(c.LaunchAt, now)
+	if err != nil {
+		return err
+	}
+	c.LaunchDate = launch
+	return nil
+}
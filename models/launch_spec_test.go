@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestResolveLaunchSpec(c *check.C) {
+	// Wednesday, before daylight saving time starts in New York on Sunday
+	now := time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)
+	launch, err := ResolveLaunchSpec("next Monday 09:00 America/New_York", now)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(launch, check.Equals, time.Date(2024, 3, 11, 13, 0, 0, 0, time.UTC))
+
+	launch, err = ResolveLaunchSpec("NEXT fri 17:30 UTC", now)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(launch, check.Equals, time.Date(2024, 3, 8, 17, 30, 0, 0, time.UTC))
+
+	// Early on a Monday in New York, "next Monday" is the following week
+	now = time.Date(2024, 11, 4, 10, 0, 0, 0, time.UTC)
+	launch, err = ResolveLaunchSpec("next Monday 09:00 America/New_York", now)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(launch, check.Equals, time.Date(2024, 11, 11, 14, 0, 0, 0, time.UTC))
+}
+
+func (s *ModelsSuite) TestResolveLaunchSpecRejectsAmbiguous(c *check.C) {
+	now := time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)
+	for spec, expected := range map[string]error{
+		"":                                    ErrInvalidLaunchSpec,
+		"Monday 09:00 America/New_York":       ErrInvalidLaunchSpec,
+		"next Monday 9am America/New_York":    ErrInvalidLaunchSpec,
+		"next Someday 09:00 America/New_York": ErrInvalidLaunchSpec,
+		"next Monday 09:00":                   ErrInvalidLaunchSpec,
+		"next Monday 09:00 EST":               ErrAmbiguousLaunchTimezone,
+		"next Monday 09:00 Local":             ErrAmbiguousLaunchTimezone,
+		"next Monday 09:00 Mars/Olympus":      ErrInvalidTimezone,
+		// The clocks go forward from 02:00 to 03:00 on March 10th
+		"next Sunday 02:30 America/New_York": ErrAmbiguousLaunchTime,
+	} {
+		_, err := ResolveLaunchSpec(spec, now)
+		c.Assert(err, check.Equals, expected, check.Commentf("spec %q", spec))
+	}
+	// The clocks go back from 02:00 to 01:00 on November 3rd
+	now = time.Date(2024, 10, 30, 12, 0, 0, 0, time.UTC)
+	_, err := ResolveLaunchSpec("next Sunday 01:30 America/New_York", now)
+	c.Assert(err, check.Equals, ErrAmbiguousLaunchTime)
+}
+
+func (s *ModelsSuite) TestPostCampaignResolvesLaunchAt(ch *check.C) {
+	c := s.createCampaignDependencies(ch)
+	c.LaunchAt = "next Monday 09:00 America/New_York"
+	ch.Assert(PostCampaign(&c, 1), check.Equals, nil)
+	ch.Assert(c.Status, check.Equals, CampaignQueued)
+	loc, _ := time.LoadLocation("America/New_York")
+	local := c.LaunchDate.In(loc)
+	ch.Assert(local.Weekday(), check.Equals, time.Monday)
+	ch.Assert(local.Hour(), check.Equals, 9)
+	ch.Assert(c.LaunchDate.Location(), check.Equals, time.UTC)
+
+	conflict := s.createCampaignDependencies(ch)
+	conflict.LaunchAt = "next Monday 09:00 America/New_York"
+	conflict.LaunchDate = time.Now().UTC()
+	ch.Assert(PostCampaign(&conflict, 1), check.Equals, ErrLaunchSpecConflict)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"net/http"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/gophish/gophish/webhook"
+)
+
+// LoginAlertNotificationName is the event name of the webhook sent for
+// authentication failures and emergency logins.
+const LoginAlertNotificationName = "login_alert"
+
+// Types of login alert
+const (
+	LoginAlertFailed                = "login_failed"
+	LoginAlertAccountLocked         = "account_locked"
+	LoginAlertEmergencyLogin        = "emergency_login_success"
+	LoginAlertAdminValidationFailed = "admin_validation_failed"
+)
+
+// LoginAlert is the webhook payload sent to security monitoring when a login
+// fails, a locked account or address is refused, an admin fails validation
+// or someone signs in through emergency access.
+type LoginAlert struct {
+	Event     string    `json:"event"`
+	Type      string    `json:"type"`
+	Username  string    `json:"username"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	Emergency bool      `json:"emergency"`
+	Details   string    `json:"details,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewLoginAlert returns an alert of the given type for a login attempt made
+// with the request.
+func NewLoginAlert(alertType string, username string, r *http.Request) LoginAlert {
+	return LoginAlert{
+		Event:     LoginAlertNotificationName,
+		Type:      alertType,
+		Username:  username,
+		IPAddress: ExtractIPFromRequest(r),
+		UserAgent: r.UserAgent(),
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+// SendLoginAlert sends the alert to the configured endpoint, or to every
+// active webhook if none is configured. Nothing is sent unless login alerts
+// are enabled.
+func SendLoginAlert(a LoginAlert) {
+	if conf == nil || conf.LoginAlertNotify == nil || !conf.LoginAlertNotify.Enabled {
+		return
+	}
+	notify := conf.LoginAlertNotify
+	var whEndPoints []webhook.EndPoint
+	if notify.URL != "" {
+		whEndPoints = []webhook.EndPoint{{URL: notify.URL, Secret: notify.Secret}}
+	} else {
+		var err error
+		whEndPoints, err = getActiveWebhookEndPoints()
+		if err != nil {
+			log.Errorf("error getting active webhooks: %v", err)
+			return
+		}
+	}
+	webhook.SendAll(whEndPoints, a)
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gophish/gophish/config"
+	ctx "github.com/gophish/gophish/context"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestSendLoginAlert(c *check.C) {
+	received := make(chan LoginAlert, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, check.Equals, nil)
+		a := LoginAlert{}
+		if json.Unmarshal(body, &a) == nil && a.Event == LoginAlertNotificationName {
+			received <- a
+		}
+	}))
+	defer ts.Close()
+
+	conf.LoginAlertNotify = &config.LoginAlertNotify{Enabled: true, URL: ts.URL, Secret: "secret"}
+	defer func() { conf.LoginAlertNotify = nil }()
+
+	for _, alertType := range []string{
+		LoginAlertFailed,
+		LoginAlertAccountLocked,
+		LoginAlertEmergencyLogin,
+		LoginAlertAdminValidationFailed,
+	} {
+		r := httptest.NewRequest("POST", "/login", nil)
+		r.RemoteAddr = "192.0.2.10:1234"
+		r.Header.Set("User-Agent", "test-agent")
+		a := NewLoginAlert(alertType, "admin", r)
+		a.Emergency = alertType == LoginAlertEmergencyLogin
+		SendLoginAlert(a)
+
+		select {
+		case got := <-received:
+			c.Assert(got.Type, check.Equals, alertType)
+			c.Assert(got.Username, check.Equals, "admin")
+			c.Assert(got.IPAddress, check.Equals, "192.0.2.10")
+			c.Assert(got.UserAgent, check.Equals, "test-agent")
+			c.Assert(got.Emergency, check.Equals, alertType == LoginAlertEmergencyLogin)
+			c.Assert(got.Timestamp.IsZero(), check.Equals, false)
+		case <-time.After(5 * time.Second):
+			c.Fatalf("timed out waiting for the %s alert", alertType)
+		}
+	}
+}
+
+func (s *ModelsSuite) TestSendLoginAlertDisabled(c *check.C) {
+	received := make(chan struct{}, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer ts.Close()
+
+	conf.LoginAlertNotify = &config.LoginAlertNotify{URL: ts.URL}
+	defer func() { conf.LoginAlertNotify = nil }()
+
+	r := httptest.NewRequest("POST", "/login", nil)
+	SendLoginAlert(NewLoginAlert(LoginAlertFailed, "admin", r))
+	select {
+	case <-received:
+		c.Fatalf("received an alert while login alerts are disabled")
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func (s *ModelsSuite) TestLoginAlertIgnoresSpoofedForwardedFor(c *check.C) {
+	oldProxies := conf.AdminConf.TrustedProxies
+	conf.AdminConf.TrustedProxies = []string{"10.0.0.0/8"}
+	defer func() { conf.AdminConf.TrustedProxies = oldProxies }()
+
+	// The proxy headers middleware has already rewritten the remote address
+	// from the spoofed header, so the alert must use the recorded peer
+	r := httptest.NewRequest("POST", "/login", nil)
+	r.Header.Set("X-Forwarded-For", "203.0.113.66")
+	r.RemoteAddr = "203.0.113.66"
+	r = ctx.Set(r, PeerAddrKey, "198.51.100.7:1234")
+	a := NewLoginAlert(LoginAlertFailed, "admin", r)
+	c.Assert(a.IPAddress, check.Equals, "198.51.100.7")
+
+	// Behind a trusted proxy, the forwarded client is reported
+	r = httptest.NewRequest("POST", "/login", nil)
+	r.Header.Set("X-Forwarded-For", "203.0.113.66")
+	r = ctx.Set(r, PeerAddrKey, "10.0.0.2:1234")
+	a = NewLoginAlert(LoginAlertFailed, "admin", r)
+	c.Assert(a.IPAddress, check.Equals, "203.0.113.66")
+}
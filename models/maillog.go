@@ -102,6 +102,19 @@ func (m *MailLog) Lock() error {
 // maillog refers to. Since MailLog errors are permanent,
 // this action also deletes the maillog.
 func (m *MailLog) Error(e error) error {
+	// Cancelled sends have already been recorded, so the maillog just needs
+	// to be removed
+	if e == ErrResultCancelled {
+		return db.Delete(m).Error
+	}
+	// Sends stopped by the kill switch are cancelled rather than failed
+	if e == ErrSendingHalted {
+		return m.cancel()
+	}
+	// Sends for paused campaigns are kept until the campaign is resumed
+	if e == ErrCampaignPaused {
+		return m.Unlock()
+	}
 	r, err := GetResult(m.RId)
 	if err != nil {
 		log.Warn(err)
@@ -170,10 +183,27 @@ func (m *MailLog) GetSmtpFrom() (string, error) {
 // the maillog. We accept the gomail.Message as an argument so that the caller
 // can choose to re-use the message across recipients.
 func (m *MailLog) Generate(msg *gomail.Message) error {
+	halted, err := IsSendingHalted()
+	if err != nil {
+		return err
+	}
+	if halted {
+		return ErrSendingHalted
+	}
+	paused, err := isCampaignPaused(m.CampaignId)
+	if err != nil {
+		return err
+	}
+	if paused {
+		return ErrCampaignPaused
+	}
 	r, err := GetResult(m.RId)
 	if err != nil {
 		return err
 	}
+	if r.Status == StatusCancelled {
+		return ErrResultCancelled
+	}
 	c := m.cachedCampaign
 	if c == nil {
 		campaign, err := GetCampaignMailContext(m.CampaignId, m.UserId)
@@ -182,8 +212,10 @@ func (m *MailLog) Generate(msg *gomail.Message) error {
 		}
 		c = &campaign
 	}
+	// Recipients matching a template variant receive that template instead
+	t := c.templateForResult(&r)
 
-	f, err := mail.ParseAddress(c.Template.EnvelopeSender)
+	f, err := mail.ParseAddress(t.EnvelopeSender)
 	if err != nil {
 		// Fallback to email account address
 		f = &mail.Address{
@@ -191,6 +223,10 @@ func (m *MailLog) Generate(msg *gomail.Message) error {
 			Name:    "", // Email account doesn't have a display name field
 		}
 	}
+	// The campaign's send-as name takes precedence over the template's
+	if c.FromName != "" {
+		f.Name = c.FromName
+	}
 	msg.SetAddressHeader("From", f.Address, f.Name)
 
 	ptx, err := NewPhishingTemplateContext(c, r.BaseRecipient, r.RId)
@@ -217,7 +253,7 @@ func (m *MailLog) Generate(msg *gomail.Message) error {
 	// if email header customization is required.
 
 	// Parse remaining templates
-	subject, err := ExecuteTemplate(c.Template.Subject, ptx)
+	subject, err := ExecuteTemplate(t.Subject, ptx)
 
 	if err != nil {
 		log.Warn(err)
@@ -228,26 +264,34 @@ func (m *MailLog) Generate(msg *gomail.Message) error {
 	}
 
 	msg.SetHeader("To", r.FormatAddress())
-	if c.Template.Text != "" {
-		text, err := ExecuteTemplate(c.Template.Text, ptx)
+	if err := validateContentSize(t.Text, t.HTML); err != nil {
+		return err
+	}
+	if t.Text != "" {
+		text, err := ExecuteTemplate(t.Text, ptx)
 		if err != nil {
 			log.Warn(err)
 		}
 		msg.SetBody("text/plain", text)
 	}
-	if c.Template.HTML != "" {
-		html, err := ExecuteTemplate(c.Template.HTML, ptx)
+	if t.HTML != "" {
+		html, err := ExecuteTemplate(t.HTML, ptx.HTMLEscaped())
+		if err != nil {
+			log.Warn(err)
+		}
+		preheader, err := renderPreheader(t, ptx)
 		if err != nil {
 			log.Warn(err)
 		}
-		if c.Template.Text == "" {
+		html = injectPreheader(html, preheader)
+		if t.Text == "" {
 			msg.SetBody("text/html", html)
 		} else {
 			msg.AddAlternative("text/html", html)
 		}
 	}
 	// Attach the files
-	for _, a := range c.Template.Attachments {
+	for _, a := range t.Attachments {
 		addAttachment(msg, a, ptx)
 	}
 
@@ -258,6 +302,7 @@ func (m *MailLog) Generate(msg *gomail.Message) error {
 func GetQueuedMailLogs(t time.Time) ([]*MailLog, error) {
 	ms := []*MailLog{}
 	err := db.Where("send_date <= ? AND processing = ?", t, false).
+		Where("campaign_id NOT IN (SELECT id FROM campaigns WHERE paused = ?)", true).
 		Find(&ms).Error
 	if err != nil {
 		log.Warn(err)
@@ -51,6 +51,7 @@ const (
 	EventClicked       string = "Clicked Link"
 	EventDataSubmit    string = "Submitted Data"
 	EventReported      string = "Email Reported"
+	EventAutoReply     string = "Auto Reply Received"
 	EventProxyRequest  string = "Proxied request"
 	StatusSuccess      string = "Success"
 	StatusQueued       string = "Queued"
@@ -58,13 +59,15 @@ const (
 	StatusUnknown      string = "Unknown"
 	StatusScheduled    string = "Scheduled"
 	StatusRetry        string = "Retrying"
+	StatusCancelled    string = "Cancelled"
+	EventCancelled     string = "Email Cancelled"
 	Error              string = "Error"
 )
 
 // Flash is used to hold flash information for use in templates.
 type Flash struct {
-	Type    string
-	Message string
+	Type    string `json:"type"`
+	Message string `json:"message"`
 }
 
 // Response contains the attributes found in an API response
@@ -181,7 +184,7 @@ func Setup(c *config.Config) error {
 	}
 	db.LogMode(false)
 	db.SetLogger(log.Logger)
-	db.DB().SetMaxOpenConns(1)
+	applyDBPool(db.DB(), conf)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -192,6 +195,12 @@ func Setup(c *config.Config) error {
 		log.Error(err)
 		return err
 	}
+	// Encrypt sensitive fields with the current field encryption key
+	err = MigrateEncryptedFields()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
 	// Create the admin user if it doesn't exist
 	var userCount int64
 	var adminUser User
@@ -201,6 +210,13 @@ func Setup(c *config.Config) error {
 		log.Error(err)
 		return err
 	}
+	// If SSO is the only way to sign in, there would otherwise be no way for
+	// anyone to become an admin
+	err = BootstrapAdminEmails(conf)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
 	if userCount == 0 {
 		adminUser := User{
 			Username:               DefaultAdminUsername,
@@ -38,8 +38,14 @@ func (s *ModelsSuite) TearDownTest(c *check.C) {
 	db.Delete(SMTP{})
 	db.Delete(Page{})
 	db.Delete(Result{})
+	db.Delete(ResultNote{})
 	db.Delete(MailLog{})
 	db.Delete(Campaign{})
+	db.Delete(CampaignGroup{})
+	db.Delete(CampaignStatsSnapshot{})
+	db.Delete(Event{})
+	db.Delete(&EmailAccount{})
+	db.Delete(Webhook{})
 
 	// Reset users table to default state.
 	db.Not("id", 1).Delete(User{})
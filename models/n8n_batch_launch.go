@@ -2,15 +2,36 @@ package models
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"strconv"
+	"time"
 
 	log "github.com/gophish/gophish/logger"
+	"github.com/sirupsen/logrus"
 )
 
+// Launch statuses of a campaign sent with n8n
+const (
+	N8NLaunchPending  = "pending"
+	N8NLaunchLaunched = "launched"
+	N8NLaunchFailed   = "failed"
+)
+
+// DefaultN8NLaunchMaxAttempts is the default number of times a campaign's
+// launch is attempted before it's marked as failed.
+const DefaultN8NLaunchMaxAttempts = 5
+
+// DefaultN8NLaunchRetryInterval is the default delay before a failed launch
+// is retried. The delay grows with each attempt.
+const DefaultN8NLaunchRetryInterval = time.Minute
+
 // LaunchN8NBatchCampaign sends a single batch webhook to n8n with all recipients
 // This bypasses the maillog system entirely and lets n8n handle scheduling and callbacks
-func LaunchN8NBatchCampaign(c *Campaign) error {
+func LaunchN8NBatchCampaign(ctx context.Context, c *Campaign) error {
 	log.Infof("Launching n8n batch campaign: CampaignId=%d, Recipients=%d", c.Id, len(c.Results))
 
 	// Get n8n dialer with campaign context
@@ -25,6 +46,10 @@ func LaunchN8NBatchCampaign(c *Campaign) error {
 		return fmt.Errorf("failed to create n8n sender: %v", err)
 	}
 	defer sender.Close()
+	n8nSender, ok := sender.(*N8NSender)
+	if !ok {
+		return fmt.Errorf("unexpected n8n sender %T", sender)
+	}
 
 	// Build recipient list from Results
 	recipients := make([]string, 0, len(c.Results))
@@ -43,7 +68,7 @@ func LaunchN8NBatchCampaign(c *Campaign) error {
 	}
 
 	// Send batch to n8n (single webhook call with all recipients)
-	err = sender.Send(c.EmailAccount.Email, recipients, msg)
+	err = n8nSender.SendContext(ctx, c.EmailAccount.Email, recipients, msg)
 	if err != nil {
 		log.Errorf("Failed to send batch to n8n for campaign %d: %v", c.Id, err)
 		return fmt.Errorf("failed to send batch to n8n: %v", err)
@@ -68,27 +93,102 @@ func (m *mockWriterTo) WriteTo(w io.Writer) (int64, error) {
 	buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
 	buf.WriteString("\r\n")
 
-	// Write HTML body
-	buf.WriteString(m.campaign.Template.HTML)
+	// Write HTML body. The preheader is left as a template, like the body, for
+	// n8n to personalize.
+	preheader := escapePreheaderTemplate(m.campaign.Template.Preheader)
+	buf.WriteString(injectPreheader(m.campaign.Template.HTML, preheader))
 
 	// Write to provided writer
 	n, err := w.Write(buf.Bytes())
 	return int64(n), err
 }
 
-// ShouldUseN8NBatchLaunch determines if a campaign should use true batch sending
-// For now, ALL campaigns use n8n, so always return true
-// In the future, this could check email account configuration
-func ShouldUseN8NBatchLaunch(c *Campaign) bool {
-	// Check if email account has n8n credential configured
+// The transports a campaign can be sent with. n8n campaigns are handed to
+// n8n's batch pipeline in a single webhook call when they launch, while
+// direct campaigns are sent one email at a time by the worker through the
+// email account's dialer.
+const (
+	CampaignTransportN8N    = "n8n"
+	CampaignTransportDirect = "direct"
+)
+
+// ErrInvalidTransport is thrown when a campaign asks for a transport which
+// doesn't exist
+var ErrInvalidTransport = errors.New("Transport must be \"n8n\" or \"direct\"")
+
+// ErrN8NTransportUnavailable is thrown when a campaign asks to be sent with
+// n8n from an email account without an n8n credential
+var ErrN8NTransportUnavailable = errors.New("Email account has no n8n credential, so the campaign can't be sent with n8n")
+
+// GetN8NBatchMinRecipients returns the number of recipients a campaign needs
+// before it's sent with n8n's batch pipeline rather than directly,
+// configured by N8N_BATCH_MIN_RECIPIENTS. Every campaign which can use n8n
+// does if it's 0.
+func GetN8NBatchMinRecipients() int {
+	s := os.Getenv("N8N_BATCH_MIN_RECIPIENTS")
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		log.Warnf("Invalid N8N_BATCH_MIN_RECIPIENTS value '%s', using default 0", s)
+		return 0
+	}
+	return v
+}
+
+// hasN8NCredential returns true if the campaign's email account can send
+// with n8n.
+func (c *Campaign) hasN8NCredential() bool {
 	return c.EmailAccount.N8NCredentialID != "" || c.EmailAccount.N8NCredentialName != ""
 }
 
+// selectTransport returns the transport the campaign is sent with, in order
+// of precedence:
+//
+//  1. The transport requested for the campaign, if any. n8n can only be
+//     requested if the email account has an n8n credential.
+//  2. Direct, if the email account has no n8n credential.
+//  3. Direct, if the campaign has fewer recipients than
+//     N8N_BATCH_MIN_RECIPIENTS.
+//  4. n8n otherwise.
+func (c *Campaign) selectTransport(recipients int) (string, error) {
+	switch c.Transport {
+	case CampaignTransportN8N:
+		if !c.hasN8NCredential() {
+			return "", ErrN8NTransportUnavailable
+		}
+		return CampaignTransportN8N, nil
+	case CampaignTransportDirect:
+		return CampaignTransportDirect, nil
+	case "":
+	default:
+		return "", ErrInvalidTransport
+	}
+	if !c.hasN8NCredential() {
+		return CampaignTransportDirect, nil
+	}
+	if recipients < GetN8NBatchMinRecipients() {
+		return CampaignTransportDirect, nil
+	}
+	return CampaignTransportN8N, nil
+}
+
+// ShouldUseN8NBatchLaunch determines if a campaign is sent with n8n's batch
+// pipeline. Campaigns created before the transport was recorded use n8n if
+// their email account has an n8n credential.
+func ShouldUseN8NBatchLaunch(c *Campaign) bool {
+	if c.Transport != "" {
+		return c.Transport == CampaignTransportN8N
+	}
+	return c.hasN8NCredential()
+}
+
 // LaunchCampaignWithN8N is a wrapper that decides between batch and traditional launch
-func LaunchCampaignWithN8N(c *Campaign) error {
+func LaunchCampaignWithN8N(ctx context.Context, c *Campaign) error {
 	if ShouldUseN8NBatchLaunch(c) {
 		log.Infof("Using n8n batch launch for campaign %d", c.Id)
-		return LaunchN8NBatchCampaign(c)
+		return LaunchN8NBatchCampaign(ctx, c)
 	}
 
 	// Fallback to traditional maillog system (for SMTP-based campaigns)
@@ -98,3 +198,125 @@ func LaunchCampaignWithN8N(c *Campaign) error {
 	return fmt.Errorf("traditional SMTP launch not implemented")
 }
 
+// n8nLaunchSettings returns the maximum number of launch attempts and the
+// retry interval, configured by N8N_LAUNCH_MAX_ATTEMPTS and
+// N8N_LAUNCH_RETRY_SECONDS.
+func n8nLaunchSettings() (int, time.Duration) {
+	max := DefaultN8NLaunchMaxAttempts
+	if s := os.Getenv("N8N_LAUNCH_MAX_ATTEMPTS"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 {
+			log.Warnf("Invalid N8N_LAUNCH_MAX_ATTEMPTS value '%s', using default %d", s, DefaultN8NLaunchMaxAttempts)
+		} else {
+			max = v
+		}
+	}
+	interval := DefaultN8NLaunchRetryInterval
+	if s := os.Getenv("N8N_LAUNCH_RETRY_SECONDS"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 {
+			log.Warnf("Invalid N8N_LAUNCH_RETRY_SECONDS value '%s', using default %s", s, DefaultN8NLaunchRetryInterval)
+		} else {
+			interval = time.Duration(v) * time.Second
+		}
+	}
+	return max, interval
+}
+
+// launchN8NBatch sends the campaign's batch webhook to n8n. It is a variable
+// so that tests can simulate failures.
+var launchN8NBatch = LaunchN8NBatchCampaign
+
+// attemptN8NLaunch makes an attempt at launching the committed campaign with
+// n8n, recording the outcome on the campaign. The attempt is claimed first,
+// so that the campaign isn't launched twice when the worker and the request
+// which created it race. Claiming the attempt schedules the next one, so a
+// launch which is interrupted is picked up again once it's due. While sending
+// is halted, the launch fails without being attempted. The request to n8n is
+// given up on once the context is done.
+func (c *Campaign) attemptN8NLaunch(ctx context.Context) error {
+	halted, err := IsSendingHalted()
+	if err != nil {
+		return err
+	}
+	if halted {
+		log.WithFields(logrus.Fields{
+			"campaign_id": c.Id,
+		}).Warn("Not launching n8n batch campaign while sending is halted")
+		c.LaunchStatus = N8NLaunchFailed
+		c.LaunchError = ErrSendingHalted.Error()
+		return db.Model(&Campaign{}).Where("id = ? AND launch_status = ?", c.Id, N8NLaunchPending).
+			Updates(map[string]interface{}{
+				"launch_status": c.LaunchStatus,
+				"launch_error":  c.LaunchError,
+			}).Error
+	}
+	maxAttempts, interval := n8nLaunchSettings()
+	now := time.Now().UTC()
+	attempts := c.LaunchAttempts + 1
+	next := now.Add(interval * time.Duration(attempts))
+	claim := db.Model(&Campaign{}).
+		Where("id = ? AND launch_status = ? AND launch_attempts = ?", c.Id, N8NLaunchPending, c.LaunchAttempts).
+		Updates(map[string]interface{}{
+			"launch_attempts":  attempts,
+			"next_launch_date": next,
+		})
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil
+	}
+	c.LaunchAttempts = attempts
+	c.NextLaunchDate = next
+
+	fields := logrus.Fields{
+		"campaign_id": c.Id,
+		"attempts":    attempts,
+	}
+	log.WithFields(fields).Info("Launching n8n batch campaign")
+	err = launchN8NBatch(ctx, c)
+	switch {
+	case err == nil:
+		c.LaunchStatus = N8NLaunchLaunched
+		c.LaunchError = ""
+	case attempts >= maxAttempts:
+		log.WithFields(fields).Errorf("Giving up on launching n8n batch campaign: %v", err)
+		c.LaunchStatus = N8NLaunchFailed
+		c.LaunchError = err.Error()
+	default:
+		log.WithFields(fields).Warnf("Failed to launch n8n batch campaign, retrying at %s: %v", next, err)
+		c.LaunchError = err.Error()
+	}
+	return db.Model(&Campaign{}).Where("id = ?", c.Id).
+		Updates(map[string]interface{}{
+			"launch_status": c.LaunchStatus,
+			"launch_error":  c.LaunchError,
+		}).Error
+}
+
+// RetryN8NLaunches retries the launches of the n8n campaigns which are due at
+// the given time. Campaigns which were completed before they launched are
+// left alone, and paused campaigns wait until they're resumed.
+func RetryN8NLaunches(ctx context.Context, t time.Time) error {
+	cs := []Campaign{}
+	err := db.Select("id, user_id").
+		Where("launch_status = ? AND next_launch_date <= ? AND status <> ?", N8NLaunchPending, t, CampaignComplete).
+		Where("paused = ?", false).
+		Order("id asc").Find(&cs).Error
+	if err != nil {
+		return err
+	}
+	for _, pending := range cs {
+		c, err := GetCampaign(pending.Id, pending.UserId)
+		if err != nil {
+			log.Errorf("Error loading campaign %d to launch with n8n: %v", pending.Id, err)
+			continue
+		}
+		err = c.attemptN8NLaunch(ctx)
+		if err != nil {
+			log.Errorf("Error recording n8n launch of campaign %d: %v", c.Id, err)
+		}
+	}
+	return nil
+}
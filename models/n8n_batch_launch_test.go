@@ -0,0 +1,137 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+// stubN8NLaunches replaces the n8n batch launch with the given function,
+// returning a function which restores the original behaviour.
+func stubN8NLaunches(launch func(c *Campaign) error) func() {
+	original := launchN8NBatch
+	launchN8NBatch = func(ctx context.Context, c *Campaign) error { return launch(c) }
+	return func() { launchN8NBatch = original }
+}
+
+// failN8NLaunches makes the next n launches fail as though n8n was
+// unavailable, and the rest succeed.
+func failN8NLaunches(n int) func() {
+	return stubN8NLaunches(func(c *Campaign) error {
+		if n > 0 {
+			n--
+			return errors.New("n8n webhook returned error (status 503)")
+		}
+		return nil
+	})
+}
+
+// createN8NCampaignDependencies returns a campaign sent from an email account
+// which is set up for n8n.
+func (s *ModelsSuite) createN8NCampaignDependencies(ch *check.C) Campaign {
+	c := s.createCampaignDependencies(ch)
+	ea := EmailAccount{Email: "noreply@example.com", EmailType: "noreply", N8NCredentialID: "1", IsActive: true}
+	ch.Assert(PostEmailAccount(&ea), check.Equals, nil)
+	c.EmailAccount = ea
+	c.EmailAccountId = ea.Id
+	return c
+}
+
+// makeN8NLaunchDue moves the next launch attempt of the campaign into the
+// past.
+func makeN8NLaunchDue(ch *check.C, id int64) {
+	err := db.Model(&Campaign{}).Where("id = ?", id).
+		Update("next_launch_date", time.Now().UTC().Add(-time.Second)).Error
+	ch.Assert(err, check.Equals, nil)
+}
+
+func (s *ModelsSuite) TestN8NLaunchAfterCommit(ch *check.C) {
+	var committed int
+	var results int
+	defer stubN8NLaunches(func(c *Campaign) error {
+		// The campaign and its results are visible outside the transaction
+		// by the time n8n is called
+		db.Model(&Campaign{}).Where("id = ?", c.Id).Count(&committed)
+		db.Model(&Result{}).Where("campaign_id = ?", c.Id).Count(&results)
+		return nil
+	})()
+	c := s.createN8NCampaignDependencies(ch)
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	ch.Assert(committed, check.Equals, 1)
+	ch.Assert(results, check.Equals, len(c.Results))
+	ch.Assert(c.LaunchStatus, check.Equals, N8NLaunchLaunched)
+
+	got, err := GetCampaign(c.Id, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.LaunchStatus, check.Equals, N8NLaunchLaunched)
+	ch.Assert(got.LaunchAttempts, check.Equals, 1)
+	ch.Assert(got.LaunchError, check.Equals, "")
+}
+
+func (s *ModelsSuite) TestN8NLaunchRetriedAfterFailure(ch *check.C) {
+	defer failN8NLaunches(1)()
+	c := s.createN8NCampaignDependencies(ch)
+
+	// A failed launch no longer rolls back the campaign
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	got, err := GetCampaign(c.Id, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(got.Results), check.Equals, 4)
+	ch.Assert(got.LaunchStatus, check.Equals, N8NLaunchPending)
+	ch.Assert(got.LaunchAttempts, check.Equals, 1)
+	ch.Assert(got.LaunchError, check.Equals, "n8n webhook returned error (status 503)")
+	ch.Assert(got.NextLaunchDate.After(time.Now().UTC()), check.Equals, true)
+
+	// Launches aren't retried before they're due
+	ch.Assert(RetryN8NLaunches(context.Background(), time.Now().UTC()), check.Equals, nil)
+	got, err = GetCampaign(c.Id, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.LaunchAttempts, check.Equals, 1)
+
+	makeN8NLaunchDue(ch, c.Id)
+	ch.Assert(RetryN8NLaunches(context.Background(), time.Now().UTC()), check.Equals, nil)
+	got, err = GetCampaign(c.Id, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.LaunchStatus, check.Equals, N8NLaunchLaunched)
+	ch.Assert(got.LaunchAttempts, check.Equals, 2)
+	ch.Assert(got.LaunchError, check.Equals, "")
+}
+
+func (s *ModelsSuite) TestN8NLaunchFailsAfterMaxAttempts(ch *check.C) {
+	os.Setenv("N8N_LAUNCH_MAX_ATTEMPTS", "2")
+	defer os.Unsetenv("N8N_LAUNCH_MAX_ATTEMPTS")
+	defer failN8NLaunches(2)()
+	c := s.createN8NCampaignDependencies(ch)
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+
+	makeN8NLaunchDue(ch, c.Id)
+	ch.Assert(RetryN8NLaunches(context.Background(), time.Now().UTC()), check.Equals, nil)
+	got, err := GetCampaign(c.Id, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.LaunchStatus, check.Equals, N8NLaunchFailed)
+	ch.Assert(got.LaunchAttempts, check.Equals, 2)
+
+	// Failed launches are left for inspection
+	makeN8NLaunchDue(ch, c.Id)
+	ch.Assert(RetryN8NLaunches(context.Background(), time.Now().UTC()), check.Equals, nil)
+	got, err = GetCampaign(c.Id, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.LaunchAttempts, check.Equals, 2)
+}
+
+func (s *ModelsSuite) TestN8NLaunchSkipsCompletedCampaigns(ch *check.C) {
+	defer failN8NLaunches(1)()
+	c := s.createN8NCampaignDependencies(ch)
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	ch.Assert(CompleteCampaign(c.Id, c.UserId), check.Equals, nil)
+
+	makeN8NLaunchDue(ch, c.Id)
+	ch.Assert(RetryN8NLaunches(context.Background(), time.Now().UTC()), check.Equals, nil)
+	got, err := GetCampaign(c.Id, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.LaunchStatus, check.Equals, N8NLaunchPending)
+	ch.Assert(got.LaunchAttempts, check.Equals, 1)
+}
@@ -0,0 +1,289 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gophish/gophish/auth"
+	log "github.com/gophish/gophish/logger"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// Statuses of a queued n8n status callback
+const (
+	N8NCallbackPending   = "pending"
+	N8NCallbackProcessed = "processed"
+	N8NCallbackDead      = "dead"
+)
+
+// DefaultN8NCallbackMaxAttempts is the default number of times a callback is
+// processed before it is dead-lettered.
+const DefaultN8NCallbackMaxAttempts = 5
+
+// DefaultN8NCallbackRetryInterval is the default delay before a failed
+// callback is retried. The delay grows with each attempt.
+const DefaultN8NCallbackRetryInterval = 30 * time.Second
+
+// N8NCallbackRetention is how long processed callbacks are kept, and so how
+// long a repeated delivery of the same callback is recognized.
+const N8NCallbackRetention = 7 * 24 * time.Hour
+
+// n8nCallbackBatchSize is the most callbacks processed in a single poll.
+const n8nCallbackBatchSize = 500
+
+// ErrUnknownN8NEvent is thrown when n8n reports an event which isn't handled
+var ErrUnknownN8NEvent = errors.New("Unknown event type")
+
+// ErrN8NCampaignMismatch is thrown when a callback names a campaign other than
+// the one the recipient belongs to
+var ErrN8NCampaignMismatch = errors.New("Campaign ID mismatch")
+
+// N8NCallback is an email status reported by n8n. Callbacks are stored as
+// soon as they are received and processed afterwards, so that a database
+// error while updating the result doesn't lose the status. Failed callbacks
+// are retried until they have been attempted N8N_CALLBACK_MAX_ATTEMPTS
+// times, after which they are dead-lettered and left for inspection.
+type N8NCallback struct {
+	Id              int64     `json:"id"`
+	IdempotencyKey  string    `json:"idempotency_key"`
+	RId             string    `json:"rid"`
+	CampaignId      int64     `json:"campaign_id"`
+	Event           string    `json:"event"`
+	ErrorMessage    string    `json:"error_message,omitempty"`
+	SentDate        time.Time `json:"sent_date"`
+	Status          string    `json:"status"`
+	Attempts        int       `json:"attempts"`
+	LastError       string    `json:"last_error,omitempty"`
+	NextAttemptDate time.Time `json:"next_attempt_date"`
+	ReceivedDate    time.Time `json:"received_date"`
+	ProcessedDate   time.Time `json:"processed_date"`
+}
+
+// TableName specifies the database table for Gorm to use
+func (cb N8NCallback) TableName() string {
+	return "n8n_callbacks"
+}
+
+// ValidN8NEvent returns true if the event is one reported by n8n.
+func ValidN8NEvent(event string) bool {
+	switch event {
+	case "sent", "error", "bounce", "failed", "opened", "clicked", "auto_reply":
+		return true
+	}
+	return false
+}
+
+// N8NCallbackKey returns the idempotency key used for a callback which n8n
+// didn't supply one for. Callbacks without a timestamp can't be told apart
+// from a legitimate repeat, so they are given a unique key.
+func N8NCallbackKey(rid string, event string, timestamp time.Time) string {
+	if timestamp.IsZero() {
+		return auth.GenerateSecureKey(32)
+	}
+	h := sha256.Sum256([]byte(rid + "|" + event + "|" + timestamp.UTC().Format(time.RFC3339Nano)))
+	return hex.EncodeToString(h[:])
+}
+
+// n8nCallbackSettings returns the maximum number of attempts and the retry
+// interval, configured by N8N_CALLBACK_MAX_ATTEMPTS and
+// N8N_CALLBACK_RETRY_SECONDS.
+func n8nCallbackSettings() (int, time.Duration) {
+	max := DefaultN8NCallbackMaxAttempts
+	if s := os.Getenv("N8N_CALLBACK_MAX_ATTEMPTS"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 {
+			log.Warnf("Invalid N8N_CALLBACK_MAX_ATTEMPTS value '%s', using default %d", s, DefaultN8NCallbackMaxAttempts)
+		} else {
+			max = v
+		}
+	}
+	interval := DefaultN8NCallbackRetryInterval
+	if s := os.Getenv("N8N_CALLBACK_RETRY_SECONDS"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 {
+			log.Warnf("Invalid N8N_CALLBACK_RETRY_SECONDS value '%s', using default %s", s, DefaultN8NCallbackRetryInterval)
+		} else {
+			interval = time.Duration(v) * time.Second
+		}
+	}
+	return max, interval
+}
+
+// EnqueueN8NCallback stores a callback to be processed. If a callback with the
+// same idempotency key has already been received, nothing is stored and true
+// is returned.
+func EnqueueN8NCallback(cb *N8NCallback) (bool, error) {
+	existing := N8NCallback{}
+	err := db.Where("idempotency_key = ?", cb.IdempotencyKey).First(&existing).Error
+	if err == nil {
+		*cb = existing
+		return true, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return false, err
+	}
+	now := time.Now().UTC()
+	cb.Status = N8NCallbackPending
+	cb.Attempts = 0
+	cb.ReceivedDate = now
+	cb.NextAttemptDate = now
+	err = db.Create(cb).Error
+	if err != nil {
+		// The same callback may have been delivered concurrently
+		if db.Where("idempotency_key = ?", cb.IdempotencyKey).First(&existing).Error == nil {
+			*cb = existing
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// applyN8NCallback updates the recipient's result for the callback. It is a
+// variable so that tests can simulate failures.
+var applyN8NCallback = func(cb *N8NCallback) error {
+	return cb.apply()
+}
+
+// apply records the reported event against the recipient's result.
+func (cb *N8NCallback) apply() error {
+	result, err := GetResult(cb.RId)
+	if err != nil {
+		return err
+	}
+	if cb.CampaignId != 0 && result.CampaignId != cb.CampaignId {
+		return ErrN8NCampaignMismatch
+	}
+	// Sends which were cancelled after being handed to n8n are ignored
+	if result.Status == StatusCancelled {
+		log.Infof("Ignoring n8n %s event for cancelled RId %s", cb.Event, cb.RId)
+		return nil
+	}
+	switch cb.Event {
+	case "sent":
+		err = result.HandleEmailSentAt(cb.SentDate)
+		if err == nil {
+			recordCampaignSend(result.CampaignId, nil)
+		}
+		return err
+	case "error", "bounce", "failed":
+		msg := cb.ErrorMessage
+		if msg == "" {
+			msg = fmt.Sprintf("Email %s", cb.Event)
+		}
+		// Bounces are down to the recipient rather than the account the
+		// email was sent from, so they don't count towards deactivating it.
+		// They count towards the campaign's bounce rate instead.
+		if cb.Event == "bounce" {
+			err = result.HandleEmailBounce(errors.New(msg))
+			if err != nil {
+				return err
+			}
+			if err := checkCampaignBounceRate(result.CampaignId); err != nil {
+				log.Errorf("error checking bounce rate of campaign %d: %v", result.CampaignId, err)
+			}
+			return nil
+		}
+		err = result.HandleEmailError(errors.New(msg))
+		if err == nil {
+			recordCampaignSend(result.CampaignId, errors.New(msg))
+		}
+		return err
+	case "opened":
+		return result.HandleEmailOpened(EventDetails{})
+	case "clicked":
+		return result.HandleClickedLink(EventDetails{})
+	case "auto_reply":
+		return result.HandleAutoReply(EventDetails{})
+	}
+	return ErrUnknownN8NEvent
+}
+
+// isPermanentN8NCallbackError returns true for errors which retrying the
+// callback won't fix.
+func isPermanentN8NCallbackError(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound) ||
+		errors.Is(err, ErrN8NCampaignMismatch) ||
+		errors.Is(err, ErrUnknownN8NEvent)
+}
+
+// ProcessN8NCallback makes an attempt at processing the callback with the
+// given ID, if it is due. The callback is claimed before it is processed, so
+// that it isn't processed twice when the worker and the callback handler race.
+func ProcessN8NCallback(id int64) error {
+	maxAttempts, interval := n8nCallbackSettings()
+	cb := N8NCallback{}
+	err := db.Where("id = ?", id).First(&cb).Error
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if cb.Status != N8NCallbackPending || cb.NextAttemptDate.After(now) {
+		return nil
+	}
+	// Claiming the callback schedules the next attempt, so a callback whose
+	// processing is interrupted is picked up again once it's due
+	cb.Attempts++
+	cb.NextAttemptDate = now.Add(interval * time.Duration(cb.Attempts))
+	claim := db.Model(&N8NCallback{}).
+		Where("id = ? AND status = ? AND attempts = ?", cb.Id, N8NCallbackPending, cb.Attempts-1).
+		Updates(map[string]interface{}{
+			"attempts":          cb.Attempts,
+			"next_attempt_date": cb.NextAttemptDate,
+		})
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil
+	}
+
+	fields := logrus.Fields{
+		"rid":      cb.RId,
+		"event":    cb.Event,
+		"attempts": cb.Attempts,
+	}
+	err = applyN8NCallback(&cb)
+	updates := map[string]interface{}{}
+	switch {
+	case err == nil:
+		updates["status"] = N8NCallbackProcessed
+		updates["processed_date"] = time.Now().UTC()
+		updates["last_error"] = ""
+	case isPermanentN8NCallbackError(err) || cb.Attempts >= maxAttempts:
+		log.WithFields(fields).Errorf("Dead-lettering n8n callback: %v", err)
+		updates["status"] = N8NCallbackDead
+		updates["last_error"] = err.Error()
+	default:
+		log.WithFields(fields).Warnf("Failed to process n8n callback, retrying at %s: %v", cb.NextAttemptDate, err)
+		updates["last_error"] = err.Error()
+	}
+	return db.Model(&N8NCallback{}).Where("id = ?", cb.Id).Updates(updates).Error
+}
+
+// ProcessN8NCallbacks processes the callbacks which are due at the given
+// time, and removes processed callbacks once they are past the retention
+// period.
+func ProcessN8NCallbacks(t time.Time) error {
+	ids := []int64{}
+	err := db.Model(&N8NCallback{}).
+		Where("status = ? AND next_attempt_date <= ?", N8NCallbackPending, t).
+		Order("id asc").Limit(n8nCallbackBatchSize).Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		err = ProcessN8NCallback(id)
+		if err != nil {
+			log.Errorf("Error processing n8n callback %d: %v", id, err)
+		}
+	}
+	return db.Where("status = ? AND processed_date < ?", N8NCallbackProcessed, t.Add(-N8NCallbackRetention)).
+		Delete(&N8NCallback{}).Error
+}
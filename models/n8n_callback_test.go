@@ -0,0 +1,204 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+// failN8NCallbacks makes the next n attempts at applying a callback fail as
+// though the database was unavailable, returning a function which restores
+// the original behaviour.
+func failN8NCallbacks(n int) func() {
+	original := applyN8NCallback
+	applyN8NCallback = func(cb *N8NCallback) error {
+		if n > 0 {
+			n--
+			return errors.New("database is locked")
+		}
+		return original(cb)
+	}
+	return func() { applyN8NCallback = original }
+}
+
+// makeN8NCallbackDue moves the next attempt of the callback into the past.
+func makeN8NCallbackDue(ch *check.C, id int64) {
+	err := db.Model(&N8NCallback{}).Where("id = ?", id).
+		Update("next_attempt_date", time.Now().UTC().Add(-time.Second)).Error
+	ch.Assert(err, check.Equals, nil)
+}
+
+func (s *ModelsSuite) TestN8NCallbackRetriedAfterFailure(ch *check.C) {
+	defer failN8NCallbacks(1)()
+	campaign := s.createCampaign(ch)
+	result := campaign.Results[0]
+
+	cb := N8NCallback{IdempotencyKey: "retry", RId: result.RId, CampaignId: campaign.Id, Event: "sent"}
+	duplicate, err := EnqueueN8NCallback(&cb)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(duplicate, check.Equals, false)
+
+	// The first attempt fails and is scheduled to be retried
+	ch.Assert(ProcessN8NCallback(cb.Id), check.Equals, nil)
+	got := N8NCallback{}
+	ch.Assert(db.Where("id = ?", cb.Id).First(&got).Error, check.Equals, nil)
+	ch.Assert(got.Status, check.Equals, N8NCallbackPending)
+	ch.Assert(got.Attempts, check.Equals, 1)
+	ch.Assert(got.LastError, check.Equals, "database is locked")
+	ch.Assert(got.NextAttemptDate.After(time.Now().UTC()), check.Equals, true)
+
+	// Callbacks aren't retried before they're due
+	ch.Assert(ProcessN8NCallbacks(time.Now().UTC()), check.Equals, nil)
+	ch.Assert(db.Where("id = ?", cb.Id).First(&got).Error, check.Equals, nil)
+	ch.Assert(got.Attempts, check.Equals, 1)
+
+	makeN8NCallbackDue(ch, cb.Id)
+	ch.Assert(ProcessN8NCallbacks(time.Now().UTC()), check.Equals, nil)
+	ch.Assert(db.Where("id = ?", cb.Id).First(&got).Error, check.Equals, nil)
+	ch.Assert(got.Status, check.Equals, N8NCallbackProcessed)
+	ch.Assert(got.Attempts, check.Equals, 2)
+
+	r, err := GetResult(result.RId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(r.Status, check.Equals, EventSent)
+}
+
+func (s *ModelsSuite) TestN8NCallbackDeadLettered(ch *check.C) {
+	os.Setenv("N8N_CALLBACK_MAX_ATTEMPTS", "2")
+	defer os.Unsetenv("N8N_CALLBACK_MAX_ATTEMPTS")
+	defer failN8NCallbacks(2)()
+	campaign := s.createCampaign(ch)
+
+	cb := N8NCallback{IdempotencyKey: "dead", RId: campaign.Results[0].RId, Event: "sent"}
+	_, err := EnqueueN8NCallback(&cb)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(ProcessN8NCallback(cb.Id), check.Equals, nil)
+	makeN8NCallbackDue(ch, cb.Id)
+	ch.Assert(ProcessN8NCallback(cb.Id), check.Equals, nil)
+
+	got := N8NCallback{}
+	ch.Assert(db.Where("id = ?", cb.Id).First(&got).Error, check.Equals, nil)
+	ch.Assert(got.Status, check.Equals, N8NCallbackDead)
+	ch.Assert(got.Attempts, check.Equals, 2)
+
+	// Callbacks for unknown recipients are dead-lettered without retrying
+	cb = N8NCallback{IdempotencyKey: "unknown", RId: "missing", Event: "sent"}
+	_, err = EnqueueN8NCallback(&cb)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(ProcessN8NCallback(cb.Id), check.Equals, nil)
+	ch.Assert(db.Where("id = ?", cb.Id).First(&got).Error, check.Equals, nil)
+	ch.Assert(got.Status, check.Equals, N8NCallbackDead)
+	ch.Assert(got.Attempts, check.Equals, 1)
+}
+
+func (s *ModelsSuite) TestN8NCallbackIdempotency(ch *check.C) {
+	campaign := s.createCampaign(ch)
+	rid := campaign.Results[0].RId
+	ts := time.Date(2025, 12, 1, 9, 0, 0, 0, time.UTC)
+	key := N8NCallbackKey(rid, "sent", ts)
+	ch.Assert(N8NCallbackKey(rid, "sent", ts), check.Equals, key)
+	ch.Assert(N8NCallbackKey(rid, "error", ts), check.Not(check.Equals), key)
+
+	first := N8NCallback{IdempotencyKey: key, RId: rid, Event: "sent"}
+	duplicate, err := EnqueueN8NCallback(&first)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(duplicate, check.Equals, false)
+	ch.Assert(ProcessN8NCallback(first.Id), check.Equals, nil)
+
+	second := N8NCallback{IdempotencyKey: key, RId: rid, Event: "sent"}
+	duplicate, err = EnqueueN8NCallback(&second)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(duplicate, check.Equals, true)
+	ch.Assert(second.Id, check.Equals, first.Id)
+
+	var count int64
+	ch.Assert(db.Model(&N8NCallback{}).Where("idempotency_key = ?", key).Count(&count).Error, check.Equals, nil)
+	ch.Assert(count, check.Equals, int64(1))
+	// Processing a callback again has no effect
+	ch.Assert(ProcessN8NCallback(first.Id), check.Equals, nil)
+	got := N8NCallback{}
+	ch.Assert(db.Where("id = ?", first.Id).First(&got).Error, check.Equals, nil)
+	ch.Assert(got.Attempts, check.Equals, 1)
+}
+
+func (s *ModelsSuite) TestN8NCallbackAutoReply(ch *check.C) {
+	ch.Assert(ValidN8NEvent("auto_reply"), check.Equals, true)
+	campaign := s.createCampaign(ch)
+	result := campaign.Results[0]
+
+	for _, event := range []string{"sent", "auto_reply"} {
+		cb := N8NCallback{IdempotencyKey: "auto-reply-" + event, RId: result.RId, CampaignId: campaign.Id, Event: event}
+		_, err := EnqueueN8NCallback(&cb)
+		ch.Assert(err, check.Equals, nil)
+		ch.Assert(ProcessN8NCallback(cb.Id), check.Equals, nil)
+	}
+
+	// The auto-reply is recorded on the timeline without changing the status
+	got, err := GetResult(result.RId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.AutoReplied, check.Equals, true)
+	ch.Assert(got.Status, check.Equals, EventSent)
+	event := Event{}
+	err = db.Where("campaign_id=? and email=? and message=?", campaign.Id, result.Email, EventAutoReply).First(&event).Error
+	ch.Assert(err, check.Equals, nil)
+
+	// Auto-replies are counted separately from engagement
+	stats, err := getCampaignStats(campaign.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(stats.AutoReplied, check.Equals, int64(1))
+	ch.Assert(stats.EmailsSent, check.Equals, int64(1))
+	ch.Assert(stats.OpenedEmail, check.Equals, int64(0))
+
+	results, err := GetFilteredCampaignResults(campaign.Id, campaign.UserId, ResultsFilter{Status: ResultStatusAutoReplied})
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(results.Total, check.Equals, int64(1))
+	ch.Assert(results.StatusCounts[ResultStatusAutoReplied], check.Equals, int64(1))
+}
+
+func (s *ModelsSuite) TestN8NCallbackSentDate(ch *check.C) {
+	campaign := s.createCampaign(ch)
+	result, err := GetResult(campaign.Results[0].RId)
+	ch.Assert(err, check.Equals, nil)
+	scheduled := result.SendDate
+
+	// n8n reports the email was accepted some time after it was scheduled
+	sentAt := scheduled.Add(7 * time.Minute).Truncate(time.Second)
+	cb := N8NCallback{IdempotencyKey: "sent-date", RId: result.RId, CampaignId: campaign.Id, Event: "sent", SentDate: sentAt}
+	_, err = EnqueueN8NCallback(&cb)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(ProcessN8NCallback(cb.Id), check.Equals, nil)
+
+	got, err := GetResult(result.RId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.Status, check.Equals, EventSent)
+	ch.Assert(got.SentDate.Equal(sentAt), check.Equals, true)
+	ch.Assert(got.SendDate.Equal(scheduled), check.Equals, true)
+	ch.Assert(got.SentDate.Equal(got.SendDate), check.Equals, false)
+
+	event := Event{}
+	err = db.Where("campaign_id=? and email=? and message=?", campaign.Id, result.Email, EventSent).First(&event).Error
+	ch.Assert(err, check.Equals, nil)
+	details := EventSentDetails{}
+	ch.Assert(json.Unmarshal([]byte(event.Details), &details), check.Equals, nil)
+	ch.Assert(details.SentAt.Equal(sentAt), check.Equals, true)
+}
+
+func (s *ModelsSuite) TestN8NCallbackSentDateDefault(ch *check.C) {
+	campaign := s.createCampaign(ch)
+	result := campaign.Results[0]
+
+	// Without an actual send time, the time the email was reported sent is
+	// used
+	before := time.Now().UTC().Add(-time.Second)
+	cb := N8NCallback{IdempotencyKey: "sent-date-default", RId: result.RId, CampaignId: campaign.Id, Event: "sent"}
+	_, err := EnqueueN8NCallback(&cb)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(ProcessN8NCallback(cb.Id), check.Equals, nil)
+
+	got, err := GetResult(result.RId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.SentDate.After(before), check.Equals, true)
+}
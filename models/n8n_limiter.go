@@ -0,0 +1,107 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+)
+
+// DefaultN8NMaxInFlight is the default maximum number of concurrent requests
+// made to n8n.
+const DefaultN8NMaxInFlight = 10
+
+// DefaultN8NInFlightWait is the default amount of time a request waits for a
+// free slot before giving up.
+const DefaultN8NInFlightWait = 5 * time.Second
+
+// ErrN8NSaturated is returned when no slot for a request to n8n became free
+// in time
+var ErrN8NSaturated = errors.New("Too many requests to n8n in flight, try again shortly")
+
+// N8NLimiter bounds the number of concurrent requests made to n8n so that
+// campaign launches, test emails, autopilot and credential creation can't
+// overwhelm a single n8n instance between them.
+type N8NLimiter struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// NewN8NLimiter returns a limiter allowing at most max requests in flight.
+// Callers wait up to the given duration for a slot, and fail immediately when
+// saturated if wait is 0.
+func NewN8NLimiter(max int, wait time.Duration) *N8NLimiter {
+	if max < 1 {
+		max = 1
+	}
+	return &N8NLimiter{
+		slots: make(chan struct{}, max),
+		wait:  wait,
+	}
+}
+
+// Acquire reserves a slot for a request, returning a function which releases
+// it. ErrN8NSaturated is returned if no slot becomes free in time.
+func (l *N8NLimiter) Acquire(ctx context.Context) (func(), error) {
+	release := func() { <-l.slots }
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if l.wait <= 0 {
+		return nil, ErrN8NSaturated
+	}
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, ErrN8NSaturated
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InFlight returns the number of requests currently holding a slot.
+func (l *N8NLimiter) InFlight() int {
+	return len(l.slots)
+}
+
+var (
+	n8nLimiter     *N8NLimiter
+	n8nLimiterOnce sync.Once
+)
+
+// GetN8NLimiter returns the limiter shared by every request made to n8n. It is
+// configured from N8N_MAX_IN_FLIGHT, the maximum number of concurrent
+// requests, and N8N_IN_FLIGHT_WAIT_SECONDS, how long to wait for a free slot.
+func GetN8NLimiter() *N8NLimiter {
+	n8nLimiterOnce.Do(func() {
+		max := DefaultN8NMaxInFlight
+		if s := os.Getenv("N8N_MAX_IN_FLIGHT"); s != "" {
+			v, err := strconv.Atoi(s)
+			if err != nil || v < 1 {
+				log.Warnf("Invalid N8N_MAX_IN_FLIGHT value '%s', using default %d", s, DefaultN8NMaxInFlight)
+			} else {
+				max = v
+			}
+		}
+		wait := DefaultN8NInFlightWait
+		if s := os.Getenv("N8N_IN_FLIGHT_WAIT_SECONDS"); s != "" {
+			v, err := strconv.Atoi(s)
+			if err != nil || v < 0 {
+				log.Warnf("Invalid N8N_IN_FLIGHT_WAIT_SECONDS value '%s', using default %s", s, DefaultN8NInFlightWait)
+			} else {
+				wait = time.Duration(v) * time.Second
+			}
+		}
+		n8nLimiter = NewN8NLimiter(max, wait)
+	})
+	return n8nLimiter
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestN8NLimiterBoundsConcurrency(ch *check.C) {
+	l := NewN8NLimiter(3, time.Minute)
+	var running, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.Acquire(context.Background())
+			if err != nil {
+				ch.Error(err)
+				return
+			}
+			defer release()
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+	wg.Wait()
+	ch.Assert(atomic.LoadInt32(&peak) <= 3, check.Equals, true)
+	ch.Assert(l.InFlight(), check.Equals, 0)
+}
+
+func (s *ModelsSuite) TestN8NLimiterSaturated(ch *check.C) {
+	// Without a wait, saturated callers fail immediately
+	l := NewN8NLimiter(1, 0)
+	release, err := l.Acquire(context.Background())
+	ch.Assert(err, check.Equals, nil)
+	_, err = l.Acquire(context.Background())
+	ch.Assert(err, check.Equals, ErrN8NSaturated)
+	release()
+	release, err = l.Acquire(context.Background())
+	ch.Assert(err, check.Equals, nil)
+	release()
+
+	// With a wait, callers block briefly before giving up
+	l = NewN8NLimiter(1, 20*time.Millisecond)
+	release, err = l.Acquire(context.Background())
+	ch.Assert(err, check.Equals, nil)
+	start := time.Now()
+	_, err = l.Acquire(context.Background())
+	ch.Assert(err, check.Equals, ErrN8NSaturated)
+	ch.Assert(time.Since(start) >= 20*time.Millisecond, check.Equals, true)
+
+	// and are let through as soon as a slot is released
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		release()
+	}()
+	l = &N8NLimiter{slots: l.slots, wait: time.Minute}
+	release, err = l.Acquire(context.Background())
+	ch.Assert(err, check.Equals, nil)
+	release()
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	log "github.com/gophish/gophish/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// N8NSendCheck is the answer given to n8n when it asks whether a scheduled
+// email may still be sent. Since n8n schedules the emails of a campaign
+// itself once it's launched, it must ask right before each send, so that
+// emails aren't sent once they've been stopped in Gophish.
+type N8NSendCheck struct {
+	RId    string `json:"rid"`
+	Send   bool   `json:"send"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// CheckN8NSend returns whether n8n may send the email to the recipient with
+// the given rid. Emails aren't sent while sending is halted or the campaign
+// is paused, or once the recipient has been cancelled or their campaign
+// completed.
+func CheckN8NSend(rid string) (N8NSendCheck, error) {
+	check := N8NSendCheck{RId: rid}
+	r, err := GetResult(rid)
+	if err != nil {
+		return check, err
+	}
+	halted, err := IsSendingHalted()
+	if err != nil {
+		return check, err
+	}
+	if halted {
+		check.Reason = ErrSendingHalted.Error()
+		return check, nil
+	}
+	if r.Status == StatusCancelled {
+		check.Reason = ErrResultCancelled.Error()
+		return check, nil
+	}
+	c := Campaign{}
+	err = db.Select("id, status, paused").Where("id = ?", r.CampaignId).First(&c).Error
+	if err != nil {
+		return check, err
+	}
+	if c.Paused {
+		check.Reason = ErrCampaignPaused.Error()
+		return check, nil
+	}
+	if c.Status == CampaignComplete {
+		check.Reason = "Campaign is complete"
+		return check, nil
+	}
+	check.Send = true
+	return check, nil
+}
+
+// cancelScheduledN8NResults cancels the recipients of n8n campaigns who
+// haven't been sent their email yet, returning the number cancelled. These
+// recipients have no maillogs, since n8n schedules their emails, so they're
+// marked as cancelled for n8n to find when it checks before sending.
+func cancelScheduledN8NResults() (int, error) {
+	statuses := []string{}
+	for status := range cancellableStatuses {
+		statuses = append(statuses, status)
+	}
+	rs := []Result{}
+	err := db.Where("status IN (?)", statuses).
+		Where("campaign_id IN (?)", db.Table("campaigns").Select("id").
+			Where("launch_status <> ? AND status <> ?", "", CampaignComplete).QueryExpr()).
+		Find(&rs).Error
+	if err != nil {
+		log.Error(err)
+		return 0, err
+	}
+	for i := range rs {
+		r := &rs[i]
+		event, err := r.createEvent(EventCancelled, nil)
+		if err != nil {
+			return i, err
+		}
+		r.Status = StatusCancelled
+		r.ModifiedDate = event.Time
+		err = db.Save(r).Error
+		if err != nil {
+			return i, err
+		}
+	}
+	if len(rs) > 0 {
+		log.WithFields(logrus.Fields{
+			"cancelled": len(rs),
+		}).Info("Cancelled scheduled n8n sends")
+	}
+	return len(rs), nil
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	check "gopkg.in/check.v1"
+)
+
+// createLaunchedN8NCampaign returns a campaign which has been launched with
+// n8n, without calling n8n.
+func (s *ModelsSuite) createLaunchedN8NCampaign(ch *check.C) Campaign {
+	defer stubN8NLaunches(func(c *Campaign) error { return nil })()
+	c := s.createN8NCampaignDependencies(ch)
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	ch.Assert(c.LaunchStatus, check.Equals, N8NLaunchLaunched)
+	return c
+}
+
+func (s *ModelsSuite) TestCheckN8NSend(ch *check.C) {
+	c := s.createLaunchedN8NCampaign(ch)
+	rid := c.Results[0].RId
+	sc, err := CheckN8NSend(rid)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(sc.Send, check.Equals, true)
+
+	// Cancelled recipients aren't sent to
+	report, err := CancelScheduledResults(c.Id, c.UserId, []string{rid})
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(report.Cancelled), check.Equals, 1)
+	sc, err = CheckN8NSend(rid)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(sc.Send, check.Equals, false)
+	ch.Assert(sc.Reason, check.Equals, ErrResultCancelled.Error())
+
+	// Nor are the recipients of completed campaigns
+	ch.Assert(CompleteCampaign(c.Id, c.UserId), check.Equals, nil)
+	sc, err = CheckN8NSend(c.Results[1].RId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(sc.Send, check.Equals, false)
+
+	_, err = CheckN8NSend("missing")
+	ch.Assert(err, check.NotNil)
+}
+
+func (s *ModelsSuite) TestHaltSendingStopsN8NSends(ch *check.C) {
+	c := s.createLaunchedN8NCampaign(ch)
+	report := s.haltSending(ch)
+	ch.Assert(report.CancelledN8NSends, check.Equals, len(c.Results))
+
+	results := []Result{}
+	ch.Assert(db.Where("campaign_id = ?", c.Id).Find(&results).Error, check.Equals, nil)
+	for _, r := range results {
+		ch.Assert(r.Status, check.Equals, StatusCancelled)
+		sc, err := CheckN8NSend(r.RId)
+		ch.Assert(err, check.Equals, nil)
+		ch.Assert(sc.Send, check.Equals, false)
+		ch.Assert(sc.Reason, check.Equals, ErrSendingHalted.Error())
+	}
+}
+
+func (s *ModelsSuite) TestCheckN8NSendPaused(ch *check.C) {
+	c := s.createLaunchedN8NCampaign(ch)
+	rid := c.Results[0].RId
+	paused, err := pauseCampaign(c.Id, "too many bounces")
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(paused, check.Equals, true)
+	sc, err := CheckN8NSend(rid)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(sc.Send, check.Equals, false)
+	ch.Assert(sc.Reason, check.Equals, ErrCampaignPaused.Error())
+
+	// Sending picks up again once the campaign is resumed
+	u, err := GetUser(c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	_, err = ResumeCampaign(c.Id, u, true)
+	ch.Assert(err, check.Equals, nil)
+	sc, err = CheckN8NSend(rid)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(sc.Send, check.Equals, true)
+}
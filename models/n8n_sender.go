@@ -13,11 +13,16 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/gophish/gophish/dialer"
 	log "github.com/gophish/gophish/logger"
 	"github.com/gophish/gophish/mailer"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
 )
 
 // N8NSender implements the mailer.Sender interface for sending emails via n8n webhook
@@ -37,12 +42,17 @@ type N8NWebhookPayload struct {
 	LaunchDate      time.Time             `json:"launch_date"`
 	SendByDate      time.Time             `json:"send_by_date"`
 	TotalRecipients int                   `json:"total_recipients"`
+	FromName        string                `json:"from_name,omitempty"` // Send-as display name, overrides the account's default
 	Recipients      []RecipientWithTiming `json:"recipients"` // Enhanced with tracking info
 	Subject         string                `json:"subject"`
+	Preheader       string                `json:"preheader,omitempty"` // Raw preview text template, also hidden at the top of the message
 	Message         string                `json:"message"` // Raw template with {{.FirstName}}, {{.Email}}, {{.URL}} placeholders
+	IdempotencyKey  string                `json:"idempotency_key"` // Identifies the request, so that n8n can drop repeats of one it accepted
 }
 
-// RecipientWithTiming contains recipient email, result ID, calculated send time, and personalization data
+// RecipientWithTiming contains recipient email, result ID, calculated send time, and personalization data.
+// The personalization fields are sent unescaped, since n8n also uses them in subjects and plain text, so
+// n8n must escape them wherever they're rendered into HTML.
 type RecipientWithTiming struct {
 	Email       string    `json:"email"`
 	FirstName   string    `json:"first_name"`   // For {{.FirstName}} template placeholder
@@ -52,6 +62,10 @@ type RecipientWithTiming struct {
 	SendAt      time.Time `json:"send_at"`      // Pre-calculated send time
 	PhishingURL string    `json:"phishing_url"` // Phishing landing page URL for {{.URL}} placeholder (click tracking)
 	TrackingURL string    `json:"tracking_url"` // Tracking pixel URL for {{.Tracker}} placeholder (open tracking)
+	TemplateId  int64     `json:"template_id,omitempty"` // Set when the recipient matched a template variant
+	Subject     string    `json:"subject,omitempty"`     // Variant subject, overrides the payload subject
+	Preheader   string    `json:"preheader,omitempty"`   // Variant preheader, overrides the payload preheader
+	Message     string    `json:"message,omitempty"`     // Variant raw template, overrides the payload message
 }
 
 // N8NDialer implements the mailer.Dialer interface for n8n webhook
@@ -80,6 +94,7 @@ func (d *N8NDialer) Dial() (mailer.Sender, error) {
 			Timeout:   2 * time.Second, // DNS resolution + connection timeout
 			KeepAlive: 30 * time.Second,
 		}).DialContext,
+		TLSClientConfig:       dialer.TLSConfig(),
 		TLSHandshakeTimeout:   2 * time.Second,
 		ResponseHeaderTimeout: 3 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
@@ -99,6 +114,12 @@ func (d *N8NDialer) Dial() (mailer.Sender, error) {
 
 // Send sends an email via n8n webhook to multiple recipients in a single call
 func (s *N8NSender) Send(from string, to []string, msg io.WriterTo) error {
+	return s.SendContext(context.Background(), from, to, msg)
+}
+
+// SendContext is Send, giving up on the request to n8n once the context is
+// done.
+func (s *N8NSender) SendContext(ctx context.Context, from string, to []string, msg io.WriterTo) error {
 	if len(to) == 0 {
 		return errors.New("no recipients specified")
 	}
@@ -120,43 +141,63 @@ func (s *N8NSender) Send(from string, to []string, msg io.WriterTo) error {
 		return fmt.Errorf("failed to parse message: %v", err)
 	}
 
+	// Refuse to ship oversized bodies to n8n
+	if err := validateContentSize(htmlBody); err != nil {
+		return err
+	}
+
+	// Sends are deferred past the organization-wide quiet hours here, since
+	// n8n schedules them itself rather than going through the worker
+	qh, err := GetQuietHours()
+	if err != nil {
+		return err
+	}
+
 	// Build recipients with tracking information and calculated send times
 	recipientsWithTiming := make([]RecipientWithTiming, 0, len(to))
 	totalRecipients := len(to)
+	interval := s.campaign.EmailAccount.SendInterval()
 
 	for idx, email := range to {
-		// Look up Result from campaign's in-memory results (CRITICAL: don't query database during transaction!)
-		var result *Result
-		for i := range s.campaign.Results {
-			if s.campaign.Results[i].Email == email {
-				result = &s.campaign.Results[i]
-				break
-			}
-		}
-
-		if result == nil {
-			log.Warnf("Failed to find result for %s in campaign results, skipping", email)
+		result, err := s.resultFor(email)
+		if err != nil {
+			log.Warnf("Failed to find result for %s in campaign results, skipping: %v", email, err)
 			continue
 		}
 
 		// Calculate send time using campaign's timing logic
-		sendAt := s.campaign.generateSendDate(idx, totalRecipients)
+		sendAt := qh.NextAllowed(s.campaign.generateSendDate(idx, totalRecipients, interval))
 
 		// Build personalized URLs using public base URL
 		// GetPublicBaseURL prioritizes: 1) PUBLIC_BASE_URL env var, 2) Campaign URL (if not localhost)
 		phishingURL := GetPublicTrackingURL(nil, s.campaign.URL, result.RId)        // Landing page URL (click tracking)
 		trackingPixelURL := GetPublicTrackingPixelURL(nil, s.campaign.URL, result.RId) // /track endpoint (open tracking)
+		phishingURL = appendTrackingParams(phishingURL, s.campaign.getTrackingParams())
+		trackingPixelURL = appendTrackingParams(trackingPixelURL, s.campaign.getTrackingParams())
 
-		recipientsWithTiming = append(recipientsWithTiming, RecipientWithTiming{
+		recipient := RecipientWithTiming{
 			Email:       email,
-			FirstName:   result.FirstName,
-			LastName:    result.LastName,
-			Position:    result.Position,
+			FirstName:   NormalizeRecipientField(result.FirstName),
+			LastName:    NormalizeRecipientField(result.LastName),
+			Position:    NormalizeRecipientField(result.Position),
 			RId:         result.RId,
 			SendAt:      sendAt,
 			PhishingURL: phishingURL,
 			TrackingURL: trackingPixelURL,
-		})
+		}
+
+		// Carry the variant template for recipients which matched one
+		if t := s.campaign.templateForResult(result); t.Id != s.campaign.Template.Id {
+			if err := validateContentSize(t.HTML); err != nil {
+				return err
+			}
+			recipient.TemplateId = t.Id
+			recipient.Subject = t.Subject
+			recipient.Preheader = t.Preheader
+			recipient.Message = injectPreheader(t.HTML, escapePreheaderTemplate(t.Preheader))
+		}
+
+		recipientsWithTiming = append(recipientsWithTiming, recipient)
 	}
 
 	if len(recipientsWithTiming) == 0 {
@@ -174,12 +215,15 @@ func (s *N8NSender) Send(from string, to []string, msg io.WriterTo) error {
 		LaunchDate:      s.campaign.LaunchDate,
 		SendByDate:      s.campaign.SendByDate,
 		TotalRecipients: len(recipientsWithTiming),
+		FromName:        s.campaign.FromName,
 		Recipients:      recipientsWithTiming,
 		Subject:         subject,
+		Preheader:       s.campaign.Template.Preheader,
 		Message:         htmlBody,
+		IdempotencyKey:  s.idempotencyKey(recipientsWithTiming),
 	}
 
-	err = s.sendToN8N(payload)
+	err = s.sendToN8N(ctx, payload)
 	if err != nil {
 		log.Errorf("Failed to send email via n8n to %d recipients: %v", len(recipientsWithTiming), err)
 		return err
@@ -190,8 +234,77 @@ func (s *N8NSender) Send(from string, to []string, msg io.WriterTo) error {
 	return nil
 }
 
-// sendToN8N sends the payload to n8n webhook with JWT authentication
-func (s *N8NSender) sendToN8N(payload N8NWebhookPayload) error {
+// resultFor returns the campaign's result for the recipient with the given
+// email. Batch launches carry the campaign's results with them, while the
+// worker sends from maillogs with a campaign whose results aren't loaded, so
+// their results are looked up in the database.
+func (s *N8NSender) resultFor(email string) (*Result, error) {
+	for i := range s.campaign.Results {
+		if s.campaign.Results[i].Email == email {
+			return &s.campaign.Results[i], nil
+		}
+	}
+	if len(s.campaign.Results) > 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	r := Result{}
+	err := db.Where("campaign_id = ? AND email = ?", s.campaign.Id, email).First(&r).Error
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// idempotencyKey returns the key n8n uses to recognize a request it has
+// already accepted. Batch launches are identified by the campaign and launch
+// attempt, and the worker's sends by the campaign and recipients, so that
+// retries of the same request share a key.
+func (s *N8NSender) idempotencyKey(recipients []RecipientWithTiming) string {
+	if s.campaign.LaunchAttempts > 0 {
+		return fmt.Sprintf("campaign-%d-launch-%d", s.campaign.Id, s.campaign.LaunchAttempts)
+	}
+	rids := make([]string, len(recipients))
+	for i, r := range recipients {
+		rids[i] = r.RId
+	}
+	return fmt.Sprintf("campaign-%d-rid-%s", s.campaign.Id, strings.Join(rids, ","))
+}
+
+// DefaultN8NMaxRetries is the default number of times a request to the n8n
+// webhook is retried when it couldn't be delivered or n8n returned a server
+// error.
+const DefaultN8NMaxRetries = 3
+
+// n8nAttemptTimeout bounds each attempt at a request to the n8n webhook, so
+// that a slow n8n can't block a launch indefinitely.
+const n8nAttemptTimeout = 3 * time.Second
+
+// n8nRetryDelay is the delay before the first retry of a request to the n8n
+// webhook, doubling with each retry after it. It is a variable so that tests
+// don't have to wait.
+var n8nRetryDelay = 500 * time.Millisecond
+
+// GetN8NMaxRetries returns the number of times a request to the n8n webhook
+// is retried, configured by N8N_MAX_RETRIES. 0 disables retrying.
+func GetN8NMaxRetries() int {
+	s := os.Getenv("N8N_MAX_RETRIES")
+	if s == "" {
+		return DefaultN8NMaxRetries
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		log.Warnf("Invalid N8N_MAX_RETRIES value '%s', using default %d", s, DefaultN8NMaxRetries)
+		return DefaultN8NMaxRetries
+	}
+	return v
+}
+
+// sendToN8N sends the payload to n8n webhook with JWT authentication.
+// Requests which never reached n8n and server errors are retried with
+// exponential backoff until the context is done. Other errors, including
+// timeouts, are returned straight away, since n8n may have accepted the
+// payload.
+func (s *N8NSender) sendToN8N(ctx context.Context, payload N8NWebhookPayload) error {
 	// Generate JWT token
 	token, err := s.generateJWT()
 	if err != nil {
@@ -204,42 +317,92 @@ func (s *N8NSender) sendToN8N(payload N8NWebhookPayload) error {
 		return fmt.Errorf("failed to marshal payload: %v", err)
 	}
 
-	log.Debugf("Sending to n8n webhook: %s", string(payloadBytes))
+	log.Debugf("Sending to n8n webhook: %s", log.RedactJSON(payloadBytes))
 
-	// Create context with absolute 3-second deadline
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Wait for a free slot. The slot is held while the request is retried.
+	release, err := GetN8NLimiter().Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	retries := GetN8NMaxRetries()
+	delay := n8nRetryDelay
+	for attempt := 1; ; attempt++ {
+		retryable, err := s.postToN8N(ctx, token, payloadBytes)
+		if err == nil || !retryable || attempt > retries {
+			return err
+		}
+		// Give up rather than wait past the deadline
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+		log.WithFields(logrus.Fields{
+			"attempt": attempt,
+			"retries": retries,
+			"delay":   delay,
+		}).Warnf("n8n webhook request failed, retrying: %v", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// postToN8N makes a single attempt at sending the payload to the n8n webhook,
+// returning whether a failed attempt is worth retrying.
+func (s *N8NSender) postToN8N(ctx context.Context, token string, payloadBytes []byte) (bool, error) {
+	// Create context with absolute deadline for this attempt
+	ctx, cancel := context.WithTimeout(ctx, n8nAttemptTimeout)
 	defer cancel()
 
 	// Create HTTP request with context
-	req, err := http.NewRequestWithContext(ctx, "POST", s.webhookURL, bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.webhookURL, bytes.NewReader(payloadBytes))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %v", err)
+		return false, fmt.Errorf("failed to create request: %v", err)
 	}
 
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	// Send request (will be cancelled after 3 seconds no matter what)
+	// Send request (will be cancelled at the deadline no matter what)
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %v", err)
+		return n8nRequestNotDelivered(err), fmt.Errorf("failed to send request: %v", err)
 	}
 	defer resp.Body.Close()
 
-	// Read response
+	// Read response. n8n has the payload by now, so this isn't retried.
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read response: %v", err)
+		return false, fmt.Errorf("failed to read response: %v", err)
 	}
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("n8n webhook returned error (status %d): %s", resp.StatusCode, string(body))
+		return resp.StatusCode >= 500, fmt.Errorf("n8n webhook returned error (status %d): %s", resp.StatusCode, string(body))
 	}
 
-	log.Debugf("n8n webhook response: %s", string(body))
-	return nil
+	log.Debugf("n8n webhook response: %s", log.RedactJSON(body))
+	return false, nil
+}
+
+// n8nRequestNotDelivered returns true if a request failed before it could
+// reach n8n, because the connection to n8n couldn't be made. Only these are
+// safe to retry without sending n8n the same payload twice.
+func n8nRequestNotDelivered(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
 }
 
 // generateJWT generates an HS256 JWT token for n8n webhook authentication
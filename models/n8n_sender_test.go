@@ -0,0 +1,220 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+// newRetryTestSender returns a sender to a webhook which responds with each
+// of the given statuses in turn, then 200, along with the number of requests
+// the webhook has received.
+func newRetryTestSender(statuses ...int) (*N8NSender, *int32, func()) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&requests, 1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+		}
+	}))
+	sender := &N8NSender{
+		webhookURL: ts.URL,
+		jwtSecret:  "secret",
+		emailType:  "test",
+		client:     ts.Client(),
+	}
+	origDelay := n8nRetryDelay
+	n8nRetryDelay = time.Millisecond
+	return sender, &requests, func() {
+		n8nRetryDelay = origDelay
+		ts.Close()
+	}
+}
+
+func (s *ModelsSuite) TestN8NMaxRetries(ch *check.C) {
+	defer os.Unsetenv("N8N_MAX_RETRIES")
+	os.Unsetenv("N8N_MAX_RETRIES")
+	ch.Assert(GetN8NMaxRetries(), check.Equals, DefaultN8NMaxRetries)
+	os.Setenv("N8N_MAX_RETRIES", "0")
+	ch.Assert(GetN8NMaxRetries(), check.Equals, 0)
+	os.Setenv("N8N_MAX_RETRIES", "-1")
+	ch.Assert(GetN8NMaxRetries(), check.Equals, DefaultN8NMaxRetries)
+}
+
+func (s *ModelsSuite) TestSendToN8NRetriesServerErrors(ch *check.C) {
+	sender, requests, done := newRetryTestSender(http.StatusBadGateway, http.StatusServiceUnavailable)
+	defer done()
+	ch.Assert(sender.sendToN8N(context.Background(), N8NWebhookPayload{}), check.Equals, nil)
+	ch.Assert(atomic.LoadInt32(requests), check.Equals, int32(3))
+}
+
+func (s *ModelsSuite) TestSendToN8NDoesNotRetryClientErrors(ch *check.C) {
+	sender, requests, done := newRetryTestSender(http.StatusUnauthorized)
+	defer done()
+	ch.Assert(sender.sendToN8N(context.Background(), N8NWebhookPayload{}), check.NotNil)
+	ch.Assert(atomic.LoadInt32(requests), check.Equals, int32(1))
+}
+
+func (s *ModelsSuite) TestSendToN8NGivesUpAfterMaxRetries(ch *check.C) {
+	os.Setenv("N8N_MAX_RETRIES", "1")
+	defer os.Unsetenv("N8N_MAX_RETRIES")
+	sender, requests, done := newRetryTestSender(http.StatusInternalServerError,
+		http.StatusInternalServerError, http.StatusInternalServerError)
+	defer done()
+	ch.Assert(sender.sendToN8N(context.Background(), N8NWebhookPayload{}), check.NotNil)
+	ch.Assert(atomic.LoadInt32(requests), check.Equals, int32(2))
+}
+
+// failingTransport fails every request with the given error, or as though
+// n8n couldn't be reached if there's none.
+type failingTransport struct {
+	requests int32
+	err      error
+}
+
+func (t *failingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.requests, 1)
+	if t.err != nil {
+		return nil, t.err
+	}
+	return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+}
+
+func (s *ModelsSuite) TestSendToN8NRetriesConnectionErrors(ch *check.C) {
+	sender, _, done := newRetryTestSender()
+	defer done()
+	transport := &failingTransport{}
+	sender.client = &http.Client{Transport: transport}
+	ch.Assert(sender.sendToN8N(context.Background(), N8NWebhookPayload{}), check.NotNil)
+	ch.Assert(atomic.LoadInt32(&transport.requests), check.Equals, int32(DefaultN8NMaxRetries+1))
+}
+
+func (s *ModelsSuite) TestSendToN8NDoesNotRetryDeliveredRequests(ch *check.C) {
+	// n8n may have accepted the payload of requests which timed out or
+	// whose response couldn't be read, so they aren't sent again
+	for _, err := range []error{
+		context.DeadlineExceeded,
+		&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")},
+	} {
+		sender, _, done := newRetryTestSender()
+		transport := &failingTransport{err: err}
+		sender.client = &http.Client{Transport: transport}
+		ch.Assert(sender.sendToN8N(context.Background(), N8NWebhookPayload{}), check.NotNil)
+		ch.Assert(atomic.LoadInt32(&transport.requests), check.Equals, int32(1))
+		done()
+	}
+}
+
+func (s *ModelsSuite) TestSendToN8NStopsWithContext(ch *check.C) {
+	sender, _, done := newRetryTestSender()
+	defer done()
+	transport := &failingTransport{}
+	sender.client = &http.Client{Transport: transport}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ch.Assert(sender.sendToN8N(ctx, N8NWebhookPayload{}), check.NotNil)
+	ch.Assert(atomic.LoadInt32(&transport.requests) <= 1, check.Equals, true)
+}
+
+func (s *ModelsSuite) TestN8NPayloadIdempotencyKey(ch *check.C) {
+	received := make(chan N8NWebhookPayload, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := N8NWebhookPayload{}
+		ch.Assert(json.NewDecoder(r.Body).Decode(&payload), check.Equals, nil)
+		received <- payload
+	}))
+	defer ts.Close()
+
+	campaign := newVariantCampaign()
+	campaign.Results = []Result{
+		{RId: "abc123", BaseRecipient: BaseRecipient{Email: "sean@example.com"}},
+	}
+	sender := &N8NSender{
+		webhookURL: ts.URL,
+		jwtSecret:  "secret",
+		emailType:  "test",
+		campaign:   campaign,
+		client:     ts.Client(),
+	}
+	// Batch launches are keyed by their launch attempt
+	campaign.LaunchAttempts = 2
+	err := sender.Send("from@example.com", []string{"sean@example.com"}, &mockWriterTo{campaign: campaign})
+	ch.Assert(err, check.Equals, nil)
+	payload := <-received
+	ch.Assert(payload.IdempotencyKey, check.Equals, "campaign-1-launch-2")
+
+	// and the worker's sends by their recipients
+	campaign.LaunchAttempts = 0
+	err = sender.Send("from@example.com", []string{"sean@example.com"}, &mockWriterTo{campaign: campaign})
+	ch.Assert(err, check.Equals, nil)
+	payload = <-received
+	ch.Assert(payload.IdempotencyKey, check.Equals, "campaign-1-rid-abc123")
+}
+
+func (s *ModelsSuite) TestN8NPayloadRecipientFieldsUnescaped(ch *check.C) {
+	received := make(chan N8NWebhookPayload, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := N8NWebhookPayload{}
+		ch.Assert(json.NewDecoder(r.Body).Decode(&payload), check.Equals, nil)
+		received <- payload
+	}))
+	defer ts.Close()
+
+	campaign := newVariantCampaign()
+	campaign.Results = []Result{
+		{RId: "abc123", BaseRecipient: BaseRecipient{Email: "sean@example.com",
+			FirstName: "Seán", LastName: "O'Brien", Position: "R&D"}},
+	}
+	sender := &N8NSender{
+		webhookURL: ts.URL,
+		jwtSecret:  "secret",
+		emailType:  "test",
+		campaign:   campaign,
+		client:     ts.Client(),
+	}
+	err := sender.Send("from@example.com", []string{"sean@example.com"}, &mockWriterTo{campaign: campaign})
+	ch.Assert(err, check.Equals, nil)
+	payload := <-received
+	ch.Assert(payload.Recipients[0].LastName, check.Equals, "O'Brien")
+	ch.Assert(payload.Recipients[0].Position, check.Equals, "R&D")
+}
+
+func (s *ModelsSuite) TestN8NPayloadRespectsQuietHours(ch *check.C) {
+	launch := time.Date(2025, 7, 4, 12, 0, 0, 0, time.UTC)
+	q := QuietHours{Enabled: true, BlackoutDates: []string{"2025-07-04"}}
+	ch.Assert(PutQuietHours(&q), check.Equals, nil)
+
+	received := make(chan N8NWebhookPayload, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := N8NWebhookPayload{}
+		ch.Assert(json.NewDecoder(r.Body).Decode(&payload), check.Equals, nil)
+		received <- payload
+	}))
+	defer ts.Close()
+
+	campaign := newVariantCampaign()
+	campaign.LaunchDate = launch
+	campaign.Results = []Result{
+		{RId: "abc123", BaseRecipient: BaseRecipient{Email: "sean@example.com"}},
+	}
+	sender := &N8NSender{
+		webhookURL: ts.URL,
+		jwtSecret:  "secret",
+		emailType:  "test",
+		campaign:   campaign,
+		client:     ts.Client(),
+	}
+	err := sender.Send("from@example.com", []string{"sean@example.com"}, &mockWriterTo{campaign: campaign})
+	ch.Assert(err, check.Equals, nil)
+	payload := <-received
+	ch.Assert(payload.Recipients[0].SendAt.Equal(time.Date(2025, 7, 5, 0, 0, 0, 0, time.UTC)), check.Equals, true)
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gophish/gophish/config"
+)
+
+// ErrNameTooLong is thrown when the name of a campaign, template, landing
+// page or group exceeds the configured maximum length.
+var ErrNameTooLong = errors.New("Name is too long")
+
+// ErrNameInvalidCharacters is thrown when the name of a campaign, template,
+// landing page or group contains control characters or invalid UTF-8.
+var ErrNameInvalidCharacters = errors.New("Name contains invalid characters")
+
+// MaxFromNameLength is the maximum length, in characters, of the display name
+// a campaign's emails are sent as.
+const MaxFromNameLength = 64
+
+// ErrInvalidFromName is thrown when a campaign's send-as display name is too
+// long or contains characters which can't be used in a From header.
+var ErrInvalidFromName = errors.New("Invalid send-as name")
+
+// getMaxNameLength returns the configured maximum name length, falling back
+// to the default if the package config hasn't been set up.
+func getMaxNameLength() int {
+	if conf == nil {
+		return config.DefaultMaxNameLength
+	}
+	return conf.GetMaxNameLength()
+}
+
+// validateName ensures that a name is within the configured maximum length
+// and doesn't contain control characters, such as newlines, which break
+// exports and the UI. kind describes what is being named for the error
+// message, and the returned error wraps ErrNameTooLong or
+// ErrNameInvalidCharacters.
+func validateName(kind string, name string) error {
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("%w (%s name isn't valid UTF-8)", ErrNameInvalidCharacters, kind)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%w (%s name contains the control character %U)", ErrNameInvalidCharacters, kind, r)
+		}
+	}
+	max := getMaxNameLength()
+	if n := utf8.RuneCountInString(name); n > max {
+		return fmt.Errorf("%w (%s name is %d characters, the limit is %d)", ErrNameTooLong, kind, n, max)
+	}
+	return nil
+}
+
+// validateFromName ensures that a send-as display name fits in a From header.
+// Control characters, which could inject headers, and the quotes and angle
+// brackets used to delimit the address aren't allowed.
+func validateFromName(name string) error {
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("%w (the name isn't valid UTF-8)", ErrInvalidFromName)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) || strings.ContainsRune(`<>"\`, r) {
+			return fmt.Errorf("%w (the name contains the character %U)", ErrInvalidFromName, r)
+		}
+	}
+	if n := utf8.RuneCountInString(name); n > MaxFromNameLength {
+		return fmt.Errorf("%w (the name is %d characters, the limit is %d)", ErrInvalidFromName, n, MaxFromNameLength)
+	}
+	return nil
+}
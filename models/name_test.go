@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestValidateNameLength(ch *check.C) {
+	original := conf.MaxNameLength
+	defer func() { conf.MaxNameLength = original }()
+	conf.MaxNameLength = 16
+
+	ch.Assert(validateName("campaign", strings.Repeat("a", 16)), check.Equals, nil)
+	// The limit is in characters rather than bytes
+	ch.Assert(validateName("campaign", strings.Repeat("é", 16)), check.Equals, nil)
+
+	err := validateName("campaign", strings.Repeat("a", 17))
+	ch.Assert(errors.Is(err, ErrNameTooLong), check.Equals, true)
+}
+
+func (s *ModelsSuite) TestValidateNameCharacters(ch *check.C) {
+	ch.Assert(validateName("campaign", "Q3 Payroll – Finance 🎯"), check.Equals, nil)
+	for _, name := range []string{"Line\nBreak", "Tab\there", "Null\x00", "Escape\x1b[31m", "Bad\xffUTF-8"} {
+		err := validateName("campaign", name)
+		ch.Assert(errors.Is(err, ErrNameInvalidCharacters), check.Equals, true, check.Commentf("name %q", name))
+	}
+}
+
+func (s *ModelsSuite) TestValidateNamesOnModels(ch *check.C) {
+	original := conf.MaxNameLength
+	defer func() { conf.MaxNameLength = original }()
+	conf.MaxNameLength = 16
+	long := strings.Repeat("a", 17)
+
+	c := Campaign{
+		Name:         long,
+		Groups:       []Group{{Name: "Group"}},
+		Template:     Template{Name: "Template"},
+		Page:         Page{Name: "Page"},
+		EmailAccount: EmailAccount{Email: "sender@example.com"},
+	}
+	ch.Assert(errors.Is(c.Validate(), ErrNameTooLong), check.Equals, true)
+	c.Name = "Bad\nName"
+	ch.Assert(errors.Is(c.Validate(), ErrNameInvalidCharacters), check.Equals, true)
+
+	t := Template{Name: long, Text: "Hello"}
+	ch.Assert(errors.Is(t.Validate(), ErrNameTooLong), check.Equals, true)
+
+	p := Page{Name: "Bad\x00Name", HTML: "<html></html>"}
+	ch.Assert(errors.Is(p.Validate(), ErrNameInvalidCharacters), check.Equals, true)
+
+	g := Group{Name: long, Targets: []Target{{BaseRecipient: BaseRecipient{Email: "test@example.com"}}}}
+	ch.Assert(errors.Is(g.Validate(), ErrNameTooLong), check.Equals, true)
+}
+
+func (s *ModelsSuite) TestValidateFromName(ch *check.C) {
+	ch.Assert(validateFromName(""), check.Equals, nil)
+	ch.Assert(validateFromName("CEO Office"), check.Equals, nil)
+	ch.Assert(validateFromName("Zoë from Finance, Inc."), check.Equals, nil)
+	for _, name := range []string{
+		"Line\nBreak",
+		"Bcc: victim@example.com\r\n",
+		"Spoof <ceo@example.com>",
+		"\"Quoted\"",
+		"Bad\xffUTF-8",
+		strings.Repeat("a", MaxFromNameLength+1),
+	} {
+		err := validateFromName(name)
+		ch.Assert(errors.Is(err, ErrInvalidFromName), check.Equals, true, check.Commentf("name %q", name))
+	}
+
+	c := Campaign{
+		Name:         "Campaign",
+		Groups:       []Group{{Name: "Group"}},
+		Template:     Template{Name: "Template"},
+		Page:         Page{Name: "Page"},
+		EmailAccount: EmailAccount{Email: "sender@example.com"},
+		FromName:     "  CEO Office ",
+	}
+	ch.Assert(c.Validate(), check.Equals, nil)
+	ch.Assert(c.FromName, check.Equals, "CEO Office")
+	ch.Assert(c.getFromAddress(), check.Equals, `"CEO Office" <sender@example.com>`)
+	c.FromName = "Spoof <ceo@example.com>"
+	ch.Assert(errors.Is(c.Validate(), ErrInvalidFromName), check.Equals, true)
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gophish/gophish/config"
+	log "github.com/gophish/gophish/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrOAuthLinkRequired is thrown when an SSO sign-in matches an existing
+// account which may only be linked to single sign-on by an administrator.
+var ErrOAuthLinkRequired = errors.New("This account must be set up for single sign-on by an administrator before it can sign in with SSO")
+
+// The action and results recorded in the authorization log when an SSO
+// sign-in is linked to an existing account.
+const (
+	OAuthLinkAction = "oauth_link"
+	OAuthLinkLinked = "linked"
+	OAuthLinkDenied = "denied"
+)
+
+// oauthLinkAllowed returns whether an SSO sign-in with the provider may be
+// linked to the existing account with the same email, along with the reason.
+// Accounts set up for SSO by an administrator, either as SSO managed or with
+// the provider but no linked identity yet, are always linked. Otherwise the
+// account is linked automatically unless auto-linking is disabled, or it's an
+// admin account and auto-linking admins hasn't been enabled.
+func oauthLinkAllowed(u User, provider string) (bool, string) {
+	if u.OAuthID == "" && (u.SSOManaged || u.OAuthProvider == provider) {
+		return true, "account was set up for single sign-on"
+	}
+	sso := (&config.Config{}).GetSSOConfig()
+	if conf != nil {
+		sso = conf.GetSSOConfig()
+	}
+	switch {
+	case sso.DisableAutoLink:
+		return false, "auto-linking is disabled"
+	case u.Role.Slug == RoleAdmin && !sso.AutoLinkAdmins:
+		return false, "auto-linking admin accounts is disabled"
+	}
+	return true, "auto-linked by email"
+}
+
+// auditOAuthLink records an attempt to link an SSO sign-in to an existing
+// account in the authorization log.
+func auditOAuthLink(u User, provider, oauthID, result, reason string) {
+	fields := logrus.Fields{
+		"user_id":  u.Id,
+		"username": u.Username,
+		"provider": provider,
+		"reason":   reason,
+	}
+	if result == OAuthLinkLinked {
+		log.WithFields(fields).Warn("Linked SSO identity to existing account")
+	} else {
+		log.WithFields(fields).Warn("Refused to link SSO identity to existing account")
+	}
+	details := fmt.Sprintf("provider=%s oauth_id=%s: %s", provider, oauthID, reason)
+	err := NewEmailAuthorizationService().LogAuthorizationAttempt(context.Background(), u.Username, OAuthLinkAction, result, &u.Id, details)
+	if err != nil {
+		log.Errorf("Failed to record SSO link in the authorization log: %v", err)
+	}
+}
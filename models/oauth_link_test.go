@@ -0,0 +1,151 @@
+package models
+
+import (
+	"github.com/gophish/gophish/config"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) createLinkTestAdmin(c *check.C, username string) User {
+	adminRole, err := GetRoleBySlug(RoleAdmin)
+	c.Assert(err, check.Equals, nil)
+	u := User{
+		Username: username,
+		Hash:     "local-password-hash",
+		ApiKey:   username + "-api-key",
+		Role:     adminRole,
+		RoleID:   adminRole.ID,
+	}
+	c.Assert(db.Save(&u).Error, check.Equals, nil)
+	return u
+}
+
+func (s *ModelsSuite) TestOAuthLinkDeniedForExistingAdmin(c *check.C) {
+	email := "local.admin@example.com"
+	admin := s.createLinkTestAdmin(c, email)
+	defer db.Where("normalized_email = ?", email).Delete(&EmailAuthorizationLog{})
+
+	_, err := FindOrCreateOAuthUser("microsoft", "attacker-oauth-id", email)
+	c.Assert(err, check.Equals, ErrOAuthLinkRequired)
+
+	u, err := GetUser(admin.Id)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(u.OAuthProvider, check.Equals, "")
+	c.Assert(u.OAuthID, check.Equals, "")
+
+	logs, err := GetAuthorizationLogs(email, OAuthLinkAction, OAuthLinkDenied, 0, 0)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(len(logs), check.Equals, 1)
+	c.Assert(*logs[0].UserID, check.Equals, admin.Id)
+}
+
+func (s *ModelsSuite) TestOAuthLinkAllowedForExistingAdmin(c *check.C) {
+	original := conf.SSO
+	defer func() { conf.SSO = original }()
+	conf.SSO = &config.SSOConfig{AutoLinkAdmins: true}
+
+	email := "linked.admin@example.com"
+	admin := s.createLinkTestAdmin(c, email)
+	defer db.Where("normalized_email = ?", email).Delete(&EmailAuthorizationLog{})
+
+	u, err := FindOrCreateOAuthUser("microsoft", "admin-oauth-id", email)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(u.Id, check.Equals, admin.Id)
+	c.Assert(u.OAuthID, check.Equals, "admin-oauth-id")
+
+	logs, err := GetAuthorizationLogs(email, OAuthLinkAction, OAuthLinkLinked, 0, 0)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(len(logs), check.Equals, 1)
+}
+
+func (s *ModelsSuite) TestOAuthLinkExplicitlySetUpAdmin(c *check.C) {
+	email := "setup.admin@example.com"
+	admin := s.createLinkTestAdmin(c, email)
+	defer db.Where("normalized_email = ?", email).Delete(&EmailAuthorizationLog{})
+
+	// An admin set the account up for this provider, so it's linked even
+	// though admin accounts aren't auto-linked
+	admin.OAuthProvider = "microsoft"
+	c.Assert(PutUser(&admin), check.Equals, nil)
+	_, err := FindOrCreateOAuthUser("google", "google-oauth-id", email)
+	c.Assert(err, check.Equals, ErrOAuthLinkRequired)
+	u, err := FindOrCreateOAuthUser("microsoft", "admin-oauth-id", email)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(u.Id, check.Equals, admin.Id)
+}
+
+func (s *ModelsSuite) TestOAuthLinkAllowed(c *check.C) {
+	original := conf.SSO
+	defer func() { conf.SSO = original }()
+
+	user := User{Role: Role{Slug: RoleUser}}
+	admin := User{Role: Role{Slug: RoleAdmin}}
+	managed := User{Role: Role{Slug: RoleAdmin}, SSOManaged: true}
+
+	conf.SSO = nil
+	allowed, _ := oauthLinkAllowed(user, "microsoft")
+	c.Assert(allowed, check.Equals, true)
+	allowed, _ = oauthLinkAllowed(admin, "microsoft")
+	c.Assert(allowed, check.Equals, false)
+	allowed, _ = oauthLinkAllowed(managed, "microsoft")
+	c.Assert(allowed, check.Equals, true)
+
+	conf.SSO = &config.SSOConfig{DisableAutoLink: true}
+	allowed, _ = oauthLinkAllowed(user, "microsoft")
+	c.Assert(allowed, check.Equals, false)
+	allowed, _ = oauthLinkAllowed(managed, "microsoft")
+	c.Assert(allowed, check.Equals, true)
+}
+
+func (s *ModelsSuite) TestOAuthEmailAsUsername(c *check.C) {
+	email := "email.username@example.com"
+	user := s.createLinkTestAdmin(c, email)
+	user.OAuthProvider = "microsoft"
+	c.Assert(PutUser(&user), check.Equals, nil)
+	defer db.Where("normalized_email = ?", email).Delete(&EmailAuthorizationLog{})
+
+	u, err := FindOrCreateOAuthUserAs("microsoft", "email-oauth-id", email, "")
+	c.Assert(err, check.Equals, nil)
+	c.Assert(u.Id, check.Equals, user.Id)
+	c.Assert(u.Username, check.Equals, email)
+}
+
+func (s *ModelsSuite) TestOAuthClaimAsUsername(c *check.C) {
+	email := "claim.username@example.com"
+	user := s.createLinkTestAdmin(c, email)
+	user.OAuthProvider = "microsoft"
+	c.Assert(PutUser(&user), check.Equals, nil)
+	defer db.Where("normalized_email = ?", email).Delete(&EmailAuthorizationLog{})
+
+	// The user is found by their email and takes their preferred username
+	u, err := FindOrCreateOAuthUserAs("microsoft", "claim-oauth-id", email, "cusername")
+	c.Assert(err, check.Equals, nil)
+	c.Assert(u.Id, check.Equals, user.Id)
+	c.Assert(u.Username, check.Equals, "cusername")
+	// They're still authorized by the email from the provider
+	c.Assert(u.Email, check.Equals, email)
+	c.Assert(u.AuthorizationEmail(), check.Equals, email)
+	got, err := GetUser(user.Id)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(got.AuthorizationEmail(), check.Equals, email)
+
+	// Later sign-ins find them by their OAuth ID, keeping the username
+	u, err = FindOrCreateOAuthUserAs("microsoft", "claim-oauth-id", email, "cusername")
+	c.Assert(err, check.Equals, nil)
+	c.Assert(u.Id, check.Equals, user.Id)
+	c.Assert(u.Username, check.Equals, "cusername")
+
+	// A preferred username which belongs to someone else falls back to the
+	// email
+	s.createLinkTestAdmin(c, "taken")
+	u, err = FindOrCreateOAuthUserAs("microsoft", "claim-oauth-id", email, "taken")
+	c.Assert(err, check.Equals, nil)
+	c.Assert(u.Id, check.Equals, user.Id)
+	c.Assert(u.Username, check.Equals, email)
+}
+
+func (s *ModelsSuite) TestUserAuthorizationEmail(c *check.C) {
+	u := User{Username: "local@example.com"}
+	c.Assert(u.AuthorizationEmail(), check.Equals, "local@example.com")
+	u = User{Username: "jdoe", Email: "jane.doe@example.com"}
+	c.Assert(u.AuthorizationEmail(), check.Equals, "jane.doe@example.com")
+}
@@ -74,11 +74,17 @@ func (p *Page) Validate() error {
 	if p.Name == "" {
 		return ErrPageNameNotSpecified
 	}
+	if err := validateName("page", p.Name); err != nil {
+		return err
+	}
 	// If the user specifies to capture passwords,
 	// we automatically capture credentials
 	if p.CapturePasswords && !p.CaptureCredentials {
 		p.CaptureCredentials = true
 	}
+	if err := validateContentSize(p.HTML); err != nil {
+		return err
+	}
 	if err := ValidateTemplate(p.HTML); err != nil {
 		return err
 	}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Severities of the issues found when linting a landing page
+const (
+	PageIssueError   = "error"
+	PageIssueWarning = "warning"
+)
+
+// PageFormIssue is a problem with one of a landing page's forms. Form is the
+// index of the form on the page, or -1 for problems with the page as a whole.
+// Issues with an error severity stop submissions from being recorded.
+type PageFormIssue struct {
+	Form     int    `json:"form"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// PageLintReport is the result of checking that a landing page's forms will
+// post submissions back to Gophish.
+type PageLintReport struct {
+	Forms  int             `json:"forms"`
+	Valid  bool            `json:"valid"`
+	Issues []PageFormIssue `json:"issues"`
+}
+
+// submitSelector matches the elements which submit a form without needing
+// any JavaScript.
+const submitSelector = "input[type=submit], input[type=image], button:not([type]), button[type=submit]"
+
+// LintForms checks that the forms on the page will record submissions once
+// it is saved. Forms are submitted to the landing page URL, which carries the
+// recipient's rid, and recorded as submitted data when they are POSTed.
+//
+// The form action is rewritten when the page is saved, so a form posting
+// elsewhere is only a warning. Anything the rewrite can't fix, such as a form
+// submitted with GET, is an error.
+func (p *Page) LintForms() (PageLintReport, error) {
+	report := PageLintReport{Issues: []PageFormIssue{}}
+	d, err := goquery.NewDocumentFromReader(strings.NewReader(p.HTML))
+	if err != nil {
+		return report, err
+	}
+	issue := func(form int, severity string, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, PageFormIssue{
+			Form:     form,
+			Severity: severity,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+	forms := d.Find("form")
+	report.Forms = forms.Length()
+	if report.Forms == 0 && (p.CaptureCredentials || p.CapturePasswords) {
+		issue(-1, PageIssueError, "Page captures submitted data but has no forms")
+	}
+	forms.Each(func(i int, f *goquery.Selection) {
+		if action, _ := f.Attr("action"); strings.TrimSpace(action) != "" {
+			issue(i, PageIssueWarning, "Form action %q will be rewritten to post to the landing page", action)
+		}
+		method, _ := f.Attr("method")
+		if !strings.EqualFold(strings.TrimSpace(method), "post") {
+			issue(i, PageIssueError, "Form is submitted with GET, which drops the %s parameter so submissions aren't recorded; set method=\"post\"", RecipientParameter)
+		}
+		if enctype, _ := f.Attr("enctype"); strings.EqualFold(strings.TrimSpace(enctype), "multipart/form-data") {
+			issue(i, PageIssueWarning, "Multipart form data isn't captured; remove enctype=\"multipart/form-data\"")
+		}
+		fields := f.Find("input[name], select[name], textarea[name]")
+		fields.Each(func(j int, field *goquery.Selection) {
+			if name, _ := field.Attr("name"); name == RecipientParameter {
+				issue(i, PageIssueError, "Form has a field named %q, which replaces the recipient's %s so submissions can't be attributed", name, RecipientParameter)
+			}
+		})
+		if fields.Length() == 0 {
+			issue(i, PageIssueWarning, "Form has no named fields, so submissions are recorded without any data")
+		} else if !p.CaptureCredentials && !p.CapturePasswords {
+			issue(i, PageIssueWarning, "Page doesn't capture submitted data, so submissions are recorded without any data")
+		}
+		if f.Find(submitSelector).Length() == 0 {
+			issue(i, PageIssueWarning, "Form has no submit button, so it can only be submitted by JavaScript")
+		}
+	})
+	report.Valid = true
+	for _, is := range report.Issues {
+		if is.Severity == PageIssueError {
+			report.Valid = false
+			break
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestLintFormsWiredCorrectly(c *check.C) {
+	p := Page{
+		HTML: `<html><body><form method="POST" action="">
+				<input name="username"/>
+				<input name="password" type="password"/>
+				<button>Sign in</button>
+			</form></body></html>`,
+		CaptureCredentials: true,
+	}
+	report, err := p.LintForms()
+	c.Assert(err, check.Equals, nil)
+	c.Assert(report.Forms, check.Equals, 1)
+	c.Assert(report.Valid, check.Equals, true)
+	c.Assert(len(report.Issues), check.Equals, 0)
+}
+
+func (s *ModelsSuite) TestLintFormsRewritableAction(c *check.C) {
+	p := Page{
+		HTML: `<form method="post" action="https://login.example.com/auth">
+				<input name="username"/>
+				<input type="submit" value="Sign in"/>
+			</form>`,
+		CaptureCredentials: true,
+	}
+	report, err := p.LintForms()
+	c.Assert(err, check.Equals, nil)
+	c.Assert(report.Valid, check.Equals, true)
+	c.Assert(len(report.Issues), check.Equals, 1)
+	c.Assert(report.Issues[0].Severity, check.Equals, PageIssueWarning)
+}
+
+func (s *ModelsSuite) TestLintFormsBroken(c *check.C) {
+	p := Page{
+		HTML: `<form action="https://login.example.com/auth">
+				<input name="username"/>
+				<input type="hidden" name="rid" value="1234567"/>
+			</form>`,
+		CaptureCredentials: true,
+	}
+	report, err := p.LintForms()
+	c.Assert(err, check.Equals, nil)
+	c.Assert(report.Valid, check.Equals, false)
+	errors := 0
+	for _, issue := range report.Issues {
+		c.Assert(issue.Form, check.Equals, 0)
+		if issue.Severity == PageIssueError {
+			errors++
+		}
+	}
+	// Submitted with GET and overrides the rid
+	c.Assert(errors, check.Equals, 2)
+}
+
+func (s *ModelsSuite) TestLintFormsNoForms(c *check.C) {
+	p := Page{HTML: `<html><body>Nothing to see here</body></html>`, CaptureCredentials: true}
+	report, err := p.LintForms()
+	c.Assert(err, check.Equals, nil)
+	c.Assert(report.Valid, check.Equals, false)
+	c.Assert(report.Issues[0].Form, check.Equals, -1)
+
+	p.CaptureCredentials = false
+	report, err = p.LintForms()
+	c.Assert(err, check.Equals, nil)
+	c.Assert(report.Valid, check.Equals, true)
+}
@@ -0,0 +1,199 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrVariantPageNotSpecified indicates that a page variant doesn't name the
+// landing page to serve
+var ErrVariantPageNotSpecified = errors.New("Page variant page not specified")
+
+// ErrInvalidPageWeight indicates that a page variant has a weight below one
+var ErrInvalidPageWeight = errors.New("Page variant weight must be at least 1")
+
+// PageVariant is one of several landing pages served to the recipients of a
+// campaign. Each recipient is assigned a page in proportion to its weight,
+// so a page with a weight of 3 is served to three times as many recipients
+// as a page with a weight of 1. Campaigns without variants serve the
+// campaign page to everyone.
+type PageVariant struct {
+	Id         int64 `json:"-"`
+	CampaignId int64 `json:"-"`
+	Weight     int   `json:"weight"`
+	PageId     int64 `json:"-"`
+	Page       Page  `json:"page" gorm:"-"`
+}
+
+// PageStats holds the conversion of one of the landing pages served by a
+// campaign. SubmitRate is the share of recipients who clicked through to the
+// page that went on to submit data. As with CampaignStats, every recipient
+// who submitted data is also counted as having clicked.
+type PageStats struct {
+	PageId        int64   `json:"page_id"`
+	Name          string  `json:"name"`
+	Weight        int     `json:"weight"`
+	Total         int64   `json:"total"`
+	ClickedLink   int64   `json:"clicked"`
+	SubmittedData int64   `json:"submitted_data"`
+	SubmitRate    float64 `json:"submit_rate"`
+}
+
+// Validate checks to make sure the variant names a page and has a weight.
+func (v *PageVariant) Validate() error {
+	switch {
+	case v.Page.Name == "":
+		return ErrVariantPageNotSpecified
+	case v.Weight < 1:
+		return ErrInvalidPageWeight
+	}
+	return nil
+}
+
+// selectPageVariant returns the ID of the landing page to serve to the
+// recipient with the given rid, or 0 if the campaign page should be used.
+// The rid is hashed rather than picked at random, so that a recipient is
+// always assigned the same page.
+func (c *Campaign) selectPageVariant(rid string) int64 {
+	total := 0
+	for _, v := range c.PageVariants {
+		total += v.Weight
+	}
+	if total == 0 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(rid))
+	n := int(h.Sum32() % uint32(total))
+	for _, v := range c.PageVariants {
+		if n < v.Weight {
+			return v.PageId
+		}
+		n -= v.Weight
+	}
+	return 0
+}
+
+// PageIdForResult returns the ID of the landing page assigned to the result,
+// falling back to the campaign page for results without one.
+func (c *Campaign) PageIdForResult(r *Result) int64 {
+	if r.PageId != 0 {
+		return r.PageId
+	}
+	return c.PageId
+}
+
+// resolvePageVariants looks up the landing pages named by the campaign's
+// variants.
+func (c *Campaign) resolvePageVariants(uid int64) error {
+	for i, v := range c.PageVariants {
+		p, err := GetPageByName(v.Page.Name, uid)
+		if err == gorm.ErrRecordNotFound {
+			log.WithFields(logrus.Fields{
+				"page": v.Page.Name,
+			}).Error("Page does not exist")
+			return ErrPageNotFound
+		} else if err != nil {
+			log.Error(err)
+			return err
+		}
+		c.PageVariants[i].Page = p
+		c.PageVariants[i].PageId = p.Id
+	}
+	return nil
+}
+
+// getPageVariants loads the page variants of the campaign, along with their
+// landing pages.
+func (c *Campaign) getPageVariants() error {
+	c.PageVariants = []PageVariant{}
+	err := db.Where("campaign_id=?", c.Id).Order("id asc").Find(&c.PageVariants).Error
+	if err != nil {
+		return err
+	}
+	for i, v := range c.PageVariants {
+		p, err := GetPage(v.PageId, c.UserId)
+		if err == gorm.ErrRecordNotFound {
+			c.PageVariants[i].Page = Page{Name: "[Deleted]"}
+			log.Warnf("%s: variant page not found for campaign", err)
+			continue
+		} else if err != nil {
+			return err
+		}
+		c.PageVariants[i].Page = p
+	}
+	return nil
+}
+
+// GetCampaignPageStats returns the conversion of each landing page served by
+// the campaign owned by the given user. Campaigns without page variants
+// report only the campaign page.
+func GetCampaignPageStats(id int64, uid int64) ([]PageStats, error) {
+	stats := []PageStats{}
+	c := Campaign{}
+	err := db.Where("id = ? and user_id = ?", id, uid).First(&c).Error
+	if err != nil {
+		return stats, err
+	}
+	err = c.getPageVariants()
+	if err != nil {
+		return stats, err
+	}
+	// Results without an assigned page were served the campaign page
+	index := map[int64]int{}
+	if len(c.PageVariants) == 0 {
+		p := PageStats{PageId: c.PageId, Name: "[Deleted]", Weight: 1}
+		if page, err := GetPage(c.PageId, uid); err == nil {
+			p.Name = page.Name
+		}
+		index[0] = 0
+		stats = append(stats, p)
+	}
+	for _, v := range c.PageVariants {
+		index[v.PageId] = len(stats)
+		stats = append(stats, PageStats{PageId: v.PageId, Name: v.Page.Name, Weight: v.Weight})
+	}
+
+	bind := db.Dialect().BindVar
+	query := fmt.Sprintf(`SELECT page_id, COUNT(*),
+		COALESCE(SUM(CASE WHEN status = %s THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN status = %s THEN 1 ELSE 0 END), 0)
+		FROM results WHERE campaign_id = %s GROUP BY page_id`,
+		bind(1), bind(2), bind(3))
+	ctx, cancel := queryContext()
+	defer cancel()
+	rows, err := db.DB().QueryContext(ctx, query, EventClicked, EventDataSubmit, id)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pageID, total, clicked, submitted int64
+		err = rows.Scan(&pageID, &total, &clicked, &submitted)
+		if err != nil {
+			return stats, err
+		}
+		i, ok := index[pageID]
+		if !ok {
+			continue
+		}
+		stats[i].Total += total
+		// Every submitted data event implies they clicked the link
+		stats[i].ClickedLink += clicked + submitted
+		stats[i].SubmittedData += submitted
+	}
+	if err = rows.Err(); err != nil {
+		return stats, err
+	}
+	for i := range stats {
+		if stats[i].ClickedLink > 0 {
+			stats[i].SubmitRate = float64(stats[i].SubmittedData) / float64(stats[i].ClickedLink)
+		}
+	}
+	return stats, nil
+}
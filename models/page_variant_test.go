@@ -0,0 +1,95 @@
+package models
+
+import (
+	"fmt"
+
+	check "gopkg.in/check.v1"
+)
+
+func newPageVariantCampaign() *Campaign {
+	return &Campaign{
+		Id:     1,
+		PageId: 1,
+		PageVariants: []PageVariant{
+			{PageId: 2, Weight: 3, Page: Page{Id: 2, Name: "Login"}},
+			{PageId: 3, Weight: 1, Page: Page{Id: 3, Name: "Survey"}},
+		},
+	}
+}
+
+func (s *ModelsSuite) TestPageVariantSelectionIsDeterministic(c *check.C) {
+	campaign := newPageVariantCampaign()
+	counts := map[int64]int{}
+	for i := 0; i < 4000; i++ {
+		rid := fmt.Sprintf("rid%d", i)
+		page := campaign.selectPageVariant(rid)
+		// The same recipient is always assigned the same page
+		c.Assert(campaign.selectPageVariant(rid), check.Equals, page)
+		counts[page]++
+	}
+	c.Assert(counts[0], check.Equals, 0)
+	// Pages are assigned roughly in proportion to their weight
+	c.Assert(counts[2] > 2700 && counts[2] < 3300, check.Equals, true, check.Commentf("counts %v", counts))
+	c.Assert(counts[3] > 700 && counts[3] < 1300, check.Equals, true, check.Commentf("counts %v", counts))
+
+	// Campaigns without variants serve the campaign page
+	campaign.PageVariants = nil
+	c.Assert(campaign.selectPageVariant("rid0"), check.Equals, int64(0))
+	c.Assert(campaign.PageIdForResult(&Result{}), check.Equals, int64(1))
+	c.Assert(campaign.PageIdForResult(&Result{PageId: 3}), check.Equals, int64(3))
+}
+
+func (s *ModelsSuite) TestPageVariantValidate(c *check.C) {
+	v := PageVariant{Weight: 1}
+	c.Assert(v.Validate(), check.Equals, ErrVariantPageNotSpecified)
+	v = PageVariant{Page: Page{Name: "Login"}}
+	c.Assert(v.Validate(), check.Equals, ErrInvalidPageWeight)
+	v = PageVariant{Page: Page{Name: "Login"}, Weight: 2}
+	c.Assert(v.Validate(), check.Equals, nil)
+}
+
+func (s *ModelsSuite) TestCampaignPageStats(c *check.C) {
+	campaign := s.createCampaignDependencies(c)
+	survey := Page{Name: "Survey Page", HTML: "<html>Survey</html>", UserId: 1}
+	c.Assert(PostPage(&survey), check.Equals, nil)
+	campaign.PageVariants = []PageVariant{
+		{Page: Page{Name: campaign.Page.Name}, Weight: 1},
+		{Page: Page{Name: survey.Name}, Weight: 1},
+	}
+	c.Assert(PostCampaign(&campaign, campaign.UserId), check.Equals, nil)
+
+	// One recipient of each page submits data and the rest only click
+	byPage := map[int64][]Result{}
+	for _, r := range campaign.Results {
+		c.Assert(r.PageId, check.Equals, campaign.selectPageVariant(r.RId))
+		byPage[r.PageId] = append(byPage[r.PageId], r)
+	}
+	expected := map[int64][2]int64{}
+	for pageID, results := range byPage {
+		clicked, submitted := int64(0), int64(0)
+		for i, r := range results {
+			status := EventDataSubmit
+			if i > 0 {
+				status = EventClicked
+			} else {
+				submitted++
+			}
+			clicked++
+			c.Assert(db.Model(&Result{}).Where("id=?", r.Id).Update("status", status).Error, check.Equals, nil)
+		}
+		expected[pageID] = [2]int64{clicked, submitted}
+	}
+
+	stats, err := GetCampaignPageStats(campaign.Id, campaign.UserId)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(len(stats), check.Equals, 2)
+	for _, ps := range stats {
+		e := expected[ps.PageId]
+		c.Assert(ps.Total, check.Equals, int64(len(byPage[ps.PageId])))
+		c.Assert(ps.ClickedLink, check.Equals, e[0])
+		c.Assert(ps.SubmittedData, check.Equals, e[1])
+		if e[0] > 0 {
+			c.Assert(ps.SubmitRate, check.Equals, float64(e[1])/float64(e[0]))
+		}
+	}
+}
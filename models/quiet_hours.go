@@ -0,0 +1,195 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// quietHoursId is the ID of the single row holding the quiet hours settings
+const quietHoursId = 1
+
+// blackoutDateFormat is the format of quiet hours blackout dates
+const blackoutDateFormat = "2006-01-02"
+
+// maxQuietHoursDays bounds how far ahead the next allowed send time is
+// searched for, in case every upcoming day is blacked out.
+const maxQuietHoursDays = 366
+
+// ErrQuietHoursWindowNotSpecified indicates that only one of the start and
+// end of the quiet hours window was given
+var ErrQuietHoursWindowNotSpecified = errors.New("Quiet hours need both a start and an end time")
+
+// ErrInvalidBlackoutDate indicates that a blackout date isn't in YYYY-MM-DD
+// format
+var ErrInvalidBlackoutDate = errors.New("Blackout dates must be in YYYY-MM-DD format")
+
+// QuietHours is an organization-wide block on sending email. While enabled,
+// no email is sent during the daily quiet window or on any of the blackout
+// dates, regardless of how the campaign was scheduled. Sends falling within
+// quiet hours are deferred until the next allowed time.
+//
+// The window is given in local HH:MM time in Timezone and may span midnight,
+// such as 20:00 to 07:00.
+type QuietHours struct {
+	Id            int64     `json:"-"`
+	Enabled       bool      `json:"enabled"`
+	Timezone      string    `json:"timezone"`
+	Start         string    `json:"start" gorm:"column:start_time"`
+	End           string    `json:"end" gorm:"column:end_time"`
+	BlackoutDates []string  `json:"blackout_dates" gorm:"-"`
+	Blackouts     string    `json:"-" gorm:"column:blackout_dates"`
+	ModifiedDate  time.Time `json:"modified_date"`
+}
+
+// TableName specifies the database tablename for Gorm to use
+func (q QuietHours) TableName() string {
+	return "quiet_hours"
+}
+
+// Validate checks to make sure the quiet hours can be applied.
+func (q *QuietHours) Validate() error {
+	if _, err := LoadScheduleLocation(q.Timezone); err != nil {
+		return err
+	}
+	if (q.Start == "") != (q.End == "") {
+		return ErrQuietHoursWindowNotSpecified
+	}
+	if q.Start != "" {
+		if _, err := ParseClock(q.Start); err != nil {
+			return err
+		}
+		if _, err := ParseClock(q.End); err != nil {
+			return err
+		}
+	}
+	for _, d := range q.BlackoutDates {
+		if _, err := time.Parse(blackoutDateFormat, d); err != nil {
+			return ErrInvalidBlackoutDate
+		}
+	}
+	return nil
+}
+
+// isBlackout returns true if the local date of t is a blackout date.
+func (q *QuietHours) isBlackout(t time.Time) bool {
+	date := t.Format(blackoutDateFormat)
+	for _, d := range q.BlackoutDates {
+		if d == date {
+			return true
+		}
+	}
+	return false
+}
+
+// NextAllowed returns t if email may be sent at t, otherwise the next time
+// after t at which it may.
+func (q *QuietHours) NextAllowed(t time.Time) time.Time {
+	if !q.Enabled {
+		return t
+	}
+	loc, err := LoadScheduleLocation(q.Timezone)
+	if err != nil {
+		log.Warn(err)
+		return t
+	}
+	var start, end time.Duration
+	window := q.Start != "" && q.Start != q.End
+	if window {
+		start, _ = ParseClock(q.Start)
+		end, _ = ParseClock(q.End)
+	}
+	next := t.In(loc)
+	for i := 0; i < maxQuietHoursDays*2; i++ {
+		if q.isBlackout(next) {
+			next = atLocalClock(AddLocalDays(next, 1, loc), 0)
+			continue
+		}
+		if !window {
+			break
+		}
+		clock := atLocalClock(next, start)
+		switch {
+		case start < end:
+			// A window within the day, such as 12:00 to 13:00
+			if !next.Before(clock) && next.Before(atLocalClock(next, end)) {
+				next = atLocalClock(next, end)
+				continue
+			}
+		case !next.Before(clock):
+			// The evening part of a window spanning midnight
+			next = atLocalClock(AddLocalDays(next, 1, loc), end)
+			continue
+		case next.Before(atLocalClock(next, end)):
+			// The morning part of a window spanning midnight
+			next = atLocalClock(next, end)
+			continue
+		}
+		break
+	}
+	if next.Equal(t) {
+		return t
+	}
+	return next.UTC()
+}
+
+// GetQuietHours returns the quiet hours settings. If none have been saved,
+// disabled quiet hours are returned.
+func GetQuietHours() (QuietHours, error) {
+	q := QuietHours{}
+	err := db.Where("id=?", quietHoursId).First(&q).Error
+	if err == gorm.ErrRecordNotFound {
+		return QuietHours{BlackoutDates: []string{}}, nil
+	} else if err != nil {
+		log.Error(err)
+		return q, err
+	}
+	q.BlackoutDates = []string{}
+	if q.Blackouts != "" {
+		q.BlackoutDates = strings.Split(q.Blackouts, ",")
+	}
+	return q, nil
+}
+
+// PutQuietHours validates and saves the quiet hours settings.
+func PutQuietHours(q *QuietHours) error {
+	for i, d := range q.BlackoutDates {
+		q.BlackoutDates[i] = strings.TrimSpace(d)
+	}
+	err := q.Validate()
+	if err != nil {
+		return err
+	}
+	q.Id = quietHoursId
+	q.Blackouts = strings.Join(q.BlackoutDates, ",")
+	q.ModifiedDate = time.Now().UTC()
+	err = db.Save(q).Error
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	log.WithFields(logrus.Fields{
+		"enabled":  q.Enabled,
+		"timezone": q.Timezone,
+		"start":    q.Start,
+		"end":      q.End,
+	}).Info("Quiet hours updated")
+	return nil
+}
+
+// Defer moves the maillog to the given send date and unlocks it so that it's
+// picked up again then. Unlike Backoff, this doesn't count as a failed send
+// attempt.
+func (m *MailLog) Defer(sendDate time.Time) error {
+	m.SendDate = sendDate
+	m.Processing = false
+	err := db.Save(m).Error
+	if err != nil {
+		return err
+	}
+	return db.Model(&Result{}).Where("r_id=?", m.RId).Update("send_date", sendDate).Error
+}
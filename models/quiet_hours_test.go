@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestQuietHoursValidate(ch *check.C) {
+	q := QuietHours{Enabled: true, Timezone: "Europe/London", Start: "20:00", End: "07:00", BlackoutDates: []string{"2025-12-25"}}
+	ch.Assert(q.Validate(), check.Equals, nil)
+
+	q.Timezone = "Not/A_Zone"
+	ch.Assert(q.Validate(), check.Equals, ErrInvalidTimezone)
+	q.Timezone = ""
+
+	q.End = ""
+	ch.Assert(q.Validate(), check.Equals, ErrQuietHoursWindowNotSpecified)
+	q.End = "7am"
+	ch.Assert(q.Validate(), check.Equals, ErrInvalidClock)
+	q.End = "07:00"
+
+	q.BlackoutDates = []string{"25/12/2025"}
+	ch.Assert(q.Validate(), check.Equals, ErrInvalidBlackoutDate)
+}
+
+func (s *ModelsSuite) TestQuietHoursNextAllowed(ch *check.C) {
+	loc, err := LoadScheduleLocation("America/New_York")
+	ch.Assert(err, check.Equals, nil)
+	q := QuietHours{
+		Enabled:       true,
+		Timezone:      "America/New_York",
+		Start:         "20:00",
+		End:           "07:00",
+		BlackoutDates: []string{"2025-07-04"},
+	}
+	testCases := []struct {
+		due      time.Time
+		expected time.Time
+	}{
+		// Outside quiet hours, sends aren't touched
+		{time.Date(2025, 7, 2, 12, 0, 0, 0, loc), time.Date(2025, 7, 2, 12, 0, 0, 0, loc)},
+		// Late evening is deferred to the next morning
+		{time.Date(2025, 7, 2, 22, 30, 0, 0, loc), time.Date(2025, 7, 3, 7, 0, 0, 0, loc)},
+		// Early morning is deferred to later that morning
+		{time.Date(2025, 7, 3, 5, 0, 0, 0, loc), time.Date(2025, 7, 3, 7, 0, 0, 0, loc)},
+		// Blackout dates defer the whole day
+		{time.Date(2025, 7, 4, 12, 0, 0, 0, loc), time.Date(2025, 7, 5, 7, 0, 0, 0, loc)},
+		// An evening deferral landing on a blackout date skips it
+		{time.Date(2025, 7, 3, 21, 0, 0, 0, loc), time.Date(2025, 7, 5, 7, 0, 0, 0, loc)},
+		// The morning end of the window stays at 07:00 across the DST change
+		{time.Date(2025, 3, 8, 23, 0, 0, 0, loc), time.Date(2025, 3, 9, 7, 0, 0, 0, loc)},
+	}
+	for _, tc := range testCases {
+		got := q.NextAllowed(tc.due.UTC())
+		ch.Assert(got.Equal(tc.expected), check.Equals, true, check.Commentf("due %s: expected %s got %s", tc.due, tc.expected, got))
+	}
+
+	// A window within the day is deferred to its end
+	q = QuietHours{Enabled: true, Start: "12:00", End: "13:00"}
+	due := time.Date(2025, 7, 2, 12, 15, 0, 0, time.UTC)
+	ch.Assert(q.NextAllowed(due), check.Equals, time.Date(2025, 7, 2, 13, 0, 0, 0, time.UTC))
+	ch.Assert(q.NextAllowed(due.Add(time.Hour)), check.Equals, due.Add(time.Hour))
+
+	// Disabled quiet hours never defer sends
+	q.Enabled = false
+	ch.Assert(q.NextAllowed(due), check.Equals, due)
+}
+
+func (s *ModelsSuite) TestQuietHoursDefersSend(ch *check.C) {
+	q := QuietHours{Enabled: true, Start: "00:00", End: "00:00", BlackoutDates: []string{time.Now().UTC().Format(blackoutDateFormat)}}
+	ch.Assert(PutQuietHours(&q), check.Equals, nil)
+
+	saved, err := GetQuietHours()
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(saved.Enabled, check.Equals, true)
+	ch.Assert(saved.BlackoutDates, check.DeepEquals, q.BlackoutDates)
+
+	campaign := s.createCampaign(ch)
+	ms, err := GetMailLogsByCampaign(campaign.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(ms) > 0, check.Equals, true)
+
+	now := time.Now().UTC()
+	next := saved.NextAllowed(now)
+	ch.Assert(next.After(now), check.Equals, true)
+	m := ms[0]
+	ch.Assert(m.Lock(), check.Equals, nil)
+	ch.Assert(m.Defer(next), check.Equals, nil)
+
+	queued, err := GetQueuedMailLogs(now)
+	ch.Assert(err, check.Equals, nil)
+	for _, q := range queued {
+		ch.Assert(q.Id, check.Not(check.Equals), m.Id)
+	}
+	r, err := GetResult(m.RId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(r.SendDate.Equal(next), check.Equals, true)
+	ch.Assert(m.Processing, check.Equals, false)
+	ch.Assert(m.SendAttempt, check.Equals, 0)
+}
@@ -6,11 +6,14 @@ Design:
 Gophish implements simple Role-Based-Access-Control (RBAC) to control access to
 certain resources.
 
-By default, Gophish has two separate roles, with each user being assigned to
+By default, Gophish has the following roles, with each user being assigned to
 a single role:
 
-* Admin  - Can modify all objects as well as system-level configuration
-* User   - Can modify all objects
+* Admin    - Can modify all objects as well as system-level configuration
+* User     - Can modify all objects
+* Operator - Can run campaigns and manage groups, but not templates, landing
+             pages or system-level configuration
+* Analyst  - Can view objects and campaign results, but not modify anything
 
 It's important to note that these are global roles. In the future, we'll likely
 add the concept of teams, which will include their own roles and permission
@@ -33,6 +36,13 @@ const (
 	// RoleUser is used for standard Gophish users. Users with this role can
 	// create, manage, and view Gophish objects and campaigns.
 	RoleUser = "user"
+	// RoleOperator is used for users who run campaigns. Users with this role
+	// can launch campaigns and view their results using the existing
+	// templates and landing pages.
+	RoleOperator = "operator"
+	// RoleAnalyst is used for read-only users. Users with this role can view
+	// objects and campaign results.
+	RoleAnalyst = "analyst"
 
 	// PermissionViewObjects determines if a role can view standard Gophish
 	// objects such as campaigns, groups, landing pages, etc.
@@ -43,6 +53,15 @@ const (
 	// PermissionModifySystem determines if a role can manage system-level
 	// configuration.
 	PermissionModifySystem = "modify_system"
+	// PermissionCreateCampaigns determines if a role can launch, complete
+	// and delete campaigns.
+	PermissionCreateCampaigns = "create_campaigns"
+	// PermissionViewResults determines if a role can view campaign results
+	// and statistics.
+	PermissionViewResults = "view_results"
+	// PermissionManageTemplates determines if a role can create and modify
+	// email templates and landing pages.
+	PermissionManageTemplates = "manage_templates"
 )
 
 // Role represents a user role within Gophish. Each user has a single role
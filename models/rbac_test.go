@@ -12,14 +12,36 @@ func (s *ModelsSuite) TestHasPermission(c *check.C) {
 
 	permissionTests := map[string]PermissionCheck{
 		RoleAdmin: PermissionCheck{
-			PermissionModifySystem:  true,
-			PermissionModifyObjects: true,
-			PermissionViewObjects:   true,
+			PermissionModifySystem:    true,
+			PermissionModifyObjects:   true,
+			PermissionViewObjects:     true,
+			PermissionCreateCampaigns: true,
+			PermissionViewResults:     true,
+			PermissionManageTemplates: true,
 		},
 		RoleUser: PermissionCheck{
-			PermissionModifySystem:  false,
-			PermissionModifyObjects: true,
-			PermissionViewObjects:   true,
+			PermissionModifySystem:    false,
+			PermissionModifyObjects:   true,
+			PermissionViewObjects:     true,
+			PermissionCreateCampaigns: true,
+			PermissionViewResults:     true,
+			PermissionManageTemplates: true,
+		},
+		RoleOperator: PermissionCheck{
+			PermissionModifySystem:    false,
+			PermissionModifyObjects:   true,
+			PermissionViewObjects:     true,
+			PermissionCreateCampaigns: true,
+			PermissionViewResults:     true,
+			PermissionManageTemplates: false,
+		},
+		RoleAnalyst: PermissionCheck{
+			PermissionModifySystem:    false,
+			PermissionModifyObjects:   false,
+			PermissionViewObjects:     true,
+			PermissionCreateCampaigns: false,
+			PermissionViewResults:     true,
+			PermissionManageTemplates: false,
 		},
 	}
 
@@ -46,7 +68,7 @@ func (s *ModelsSuite) TestHasPermission(c *check.C) {
 }
 
 func (s *ModelsSuite) TestGetRoleBySlug(c *check.C) {
-	roles := []string{RoleAdmin, RoleUser}
+	roles := []string{RoleAdmin, RoleUser, RoleOperator, RoleAnalyst}
 	for _, role := range roles {
 		got, err := GetRoleBySlug(role)
 		c.Assert(err, check.Equals, nil)
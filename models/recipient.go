@@ -0,0 +1,147 @@
+package models
+
+import (
+	"html/template"
+	"strings"
+	"unicode"
+
+	"github.com/gophish/gophish/config"
+)
+
+// templateDelimiters are the sequences which are stripped from recipient
+// attributes so that imported data can never be interpreted as template
+// actions, either by us or by the n8n workflow rendering the message.
+var templateDelimiters = strings.NewReplacer("{{", "", "}}", "")
+
+// getRecipientSanitization returns the configured recipient sanitization
+// settings, falling back to the safe defaults if the package config hasn't
+// been set up.
+func getRecipientSanitization() *config.RecipientSanitization {
+	if conf == nil {
+		return &config.RecipientSanitization{}
+	}
+	return conf.GetRecipientSanitization()
+}
+
+// NormalizeRecipientField removes template delimiters and control characters
+// from a recipient attribute such as a first name or position. Legitimate
+// values (including accented characters, apostrophes and hyphens) are
+// returned unchanged apart from surrounding whitespace.
+func NormalizeRecipientField(s string) string {
+	if getRecipientSanitization().Disabled {
+		return s
+	}
+	// Stripping a delimiter may join two halves of another one (e.g. "{{{{}}"),
+	// so we repeat until the value is stable.
+	for {
+		stripped := templateDelimiters.Replace(s)
+		if stripped == s {
+			break
+		}
+		s = stripped
+	}
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
+
+// Normalize applies NormalizeRecipientField to the name and position fields
+// of the recipient.
+func (r *BaseRecipient) Normalize() {
+	r.FirstName = NormalizeRecipientField(r.FirstName)
+	r.LastName = NormalizeRecipientField(r.LastName)
+	r.Position = NormalizeRecipientField(r.Position)
+}
+
+// gmailDomains are the domains on which Gmail ignores dots in the local part
+// of an address.
+var gmailDomains = map[string]bool{
+	"gmail.com":      true,
+	"googlemail.com": true,
+}
+
+// RecipientDedupKey returns the form of an email address used to detect
+// duplicate recipients. By default this is the address as given. If dedup
+// normalization is enabled, the address is lowercased and any plus-addressing
+// tag is removed (along with dots for Gmail addresses), so that different
+// spellings of the same mailbox are treated as one recipient. Only the
+// comparison is affected; email is always sent to the address as given.
+func RecipientDedupKey(email string) string {
+	if conf == nil || !conf.DedupNormalizeEmails {
+		return email
+	}
+	return normalizeMailbox(email)
+}
+
+// normalizeMailbox returns the canonical form of the mailbox an email
+// address delivers to.
+func normalizeMailbox(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+	if i := strings.Index(local, "+"); i > 0 {
+		local = local[:i]
+	}
+	if gmailDomains[domain] {
+		local = strings.ReplaceAll(local, ".", "")
+		domain = "gmail.com"
+	}
+	return local + "@" + domain
+}
+
+// escapeRecipientField HTML-escapes a recipient attribute unless HTML has
+// been explicitly allowed in the configuration.
+func escapeRecipientField(s string) string {
+	rs := getRecipientSanitization()
+	if rs.Disabled || rs.AllowHTML {
+		return s
+	}
+	return template.HTMLEscapeString(s)
+}
+
+// HTMLEscaped returns a copy of the template context with the recipient
+// attributes escaped for safe inclusion in HTML content such as the email
+// body or a landing page.
+func (ptx PhishingTemplateContext) HTMLEscaped() PhishingTemplateContext {
+	ptx.FirstName = escapeRecipientField(ptx.FirstName)
+	ptx.LastName = escapeRecipientField(ptx.LastName)
+	ptx.Position = escapeRecipientField(ptx.Position)
+	return ptx
+}
+
+// landingPageFieldAllowed returns true if the recipient attribute may be
+// rendered into landing pages, allowing every attribute if the package
+// config hasn't been set up.
+func landingPageFieldAllowed(field string) bool {
+	if conf == nil {
+		return true
+	}
+	return conf.LandingPageFieldAllowed(field)
+}
+
+// ForLandingPage returns a copy of the template context holding only the
+// recipient attributes which the configuration allows landing pages to use.
+// Anyone holding the link can load the page, so the remaining attributes
+// are blanked rather than exposed.
+func (ptx PhishingTemplateContext) ForLandingPage() PhishingTemplateContext {
+	if !landingPageFieldAllowed("first_name") {
+		ptx.FirstName = ""
+	}
+	if !landingPageFieldAllowed("last_name") {
+		ptx.LastName = ""
+	}
+	if !landingPageFieldAllowed("email") {
+		ptx.Email = ""
+	}
+	if !landingPageFieldAllowed("position") {
+		ptx.Position = ""
+	}
+	return ptx
+}
@@ -0,0 +1,260 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// The reasons a group member may be skipped when resolving the recipients of
+// a campaign.
+const (
+	SkipReasonDuplicate = "duplicate"
+	SkipReasonExcluded  = "excluded"
+	SkipReasonFatigued  = "fatigued"
+	SkipReasonClicked   = "clicked"
+)
+
+// ErrInvalidCooldownDays is thrown when a campaign's fatigue cooldown is
+// negative
+var ErrInvalidCooldownDays = errors.New("Cooldown days must not be negative")
+
+// ErrInvalidExclusion is thrown when an entry in a campaign's exclusion list
+// is neither an email address nor a domain starting with "@"
+var ErrInvalidExclusion = errors.New("Exclusions must be email addresses or domains starting with \"@\"")
+
+// ErrPriorCampaignNotFound is thrown when the campaign whose clickers should
+// be suppressed doesn't exist
+var ErrPriorCampaignNotFound = errors.New("Campaign to suppress clickers from not found")
+
+// SkippedRecipient is a group member who won't be sent to by a campaign.
+type SkippedRecipient struct {
+	Email  string `json:"email"`
+	Group  string `json:"group"`
+	Reason string `json:"reason"`
+}
+
+// RecipientResolution is the outcome of resolving a campaign's groups into
+// the recipients it will be sent to.
+type RecipientResolution struct {
+	Recipients []Target           `json:"-"`
+	Skipped    []SkippedRecipient `json:"skipped"`
+	// TargetIds are the targets whose last campaign date is updated once the
+	// campaign is created. Duplicates are included, since they were reached
+	// through another group.
+	TargetIds []int64 `json:"-"`
+}
+
+// RecipientPreview summarises who a campaign would be sent to, without
+// creating the campaign.
+type RecipientPreview struct {
+	Total      int                `json:"total"`
+	Recipients int                `json:"recipients"`
+	Duplicates int                `json:"duplicates"`
+	Excluded   int                `json:"excluded"`
+	Fatigued   int                `json:"fatigued"`
+	Clicked    int                `json:"clicked"`
+	Skipped    []SkippedRecipient `json:"skipped"`
+}
+
+// validateRecipientFilters checks the campaign's exclusion list and fatigue
+// cooldown, lowercasing the exclusions.
+func (c *Campaign) validateRecipientFilters() error {
+	if c.CooldownDays < 0 {
+		return ErrInvalidCooldownDays
+	}
+	for i, e := range c.Exclusions {
+		e = strings.ToLower(strings.TrimSpace(e))
+		at := strings.LastIndex(e, "@")
+		if at < 0 || at == len(e)-1 {
+			return ErrInvalidExclusion
+		}
+		c.Exclusions[i] = e
+	}
+	return nil
+}
+
+// isExcluded returns true if the email address matches the campaign's
+// exclusion list, either by address or by domain.
+func (c *Campaign) isExcluded(email string) bool {
+	if len(c.Exclusions) == 0 {
+		return false
+	}
+	key := strings.ToLower(RecipientDedupKey(email))
+	domain := key[strings.LastIndex(key, "@")+1:]
+	for _, e := range c.Exclusions {
+		if strings.HasPrefix(e, "@") {
+			if e[1:] == domain {
+				return true
+			}
+			continue
+		}
+		if strings.ToLower(RecipientDedupKey(e)) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// isFatigued returns true if the target was last sent a campaign within the
+// campaign's cooldown of its launch.
+func (c *Campaign) isFatigued(t Target) bool {
+	if c.CooldownDays == 0 || t.LastCampaignDate == nil {
+		return false
+	}
+	launch := c.LaunchDate
+	if launch.IsZero() {
+		launch = time.Now().UTC()
+	}
+	cooldown := time.Duration(c.CooldownDays) * 24 * time.Hour
+	return launch.Sub(*t.LastCampaignDate) < cooldown
+}
+
+// loadPriorClickers loads the recipients who clicked the link or submitted
+// data in the campaign whose clickers are suppressed, if there is one, keyed
+// by their normalized email address.
+func (c *Campaign) loadPriorClickers(uid int64) error {
+	c.priorClickers = nil
+	if c.SuppressClickersFrom == 0 {
+		return nil
+	}
+	prior := Campaign{}
+	err := db.Select("id").Where("id = ? and user_id = ?", c.SuppressClickersFrom, uid).First(&prior).Error
+	if err == gorm.ErrRecordNotFound {
+		return ErrPriorCampaignNotFound
+	} else if err != nil {
+		log.Error(err)
+		return err
+	}
+	emails := []string{}
+	err = db.Model(&Event{}).
+		Where("campaign_id = ? and message in (?)", prior.Id, []string{EventClicked, EventDataSubmit}).
+		Pluck("distinct email", &emails).Error
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	c.priorClickers = make(map[string]bool, len(emails))
+	for _, email := range emails {
+		c.priorClickers[strings.ToLower(RecipientDedupKey(email))] = true
+	}
+	return nil
+}
+
+// clickedBefore returns true if the email address clicked in the campaign
+// whose clickers are suppressed.
+func (c *Campaign) clickedBefore(email string) bool {
+	return c.priorClickers[strings.ToLower(RecipientDedupKey(email))]
+}
+
+// resolveRecipients resolves the campaign's groups into the recipients it
+// will be sent to. Members on the exclusion list, who were sent a campaign
+// within the cooldown, or who clicked in the campaign whose clickers are
+// suppressed, are skipped, as are members whose address was already resolved
+// through another group. The groups, and any prior clickers, must already be
+// loaded.
+func (c *Campaign) resolveRecipients() RecipientResolution {
+	rr := RecipientResolution{
+		Recipients: []Target{},
+		Skipped:    []SkippedRecipient{},
+		TargetIds:  []int64{},
+	}
+	seen := make(map[string]bool)
+	for _, g := range c.Groups {
+		for _, t := range g.Targets {
+			reason := ""
+			key := RecipientDedupKey(t.Email)
+			switch {
+			case c.isExcluded(t.Email):
+				reason = SkipReasonExcluded
+			case c.isFatigued(t):
+				reason = SkipReasonFatigued
+			case c.clickedBefore(t.Email):
+				reason = SkipReasonClicked
+			case seen[key]:
+				reason = SkipReasonDuplicate
+				rr.TargetIds = append(rr.TargetIds, t.Id)
+			}
+			if reason != "" {
+				rr.Skipped = append(rr.Skipped, SkippedRecipient{
+					Email:  t.Email,
+					Group:  g.Name,
+					Reason: reason,
+				})
+				continue
+			}
+			seen[key] = true
+			rr.Recipients = append(rr.Recipients, t)
+			rr.TargetIds = append(rr.TargetIds, t.Id)
+		}
+	}
+	return rr
+}
+
+// loadGroups replaces the campaign's groups, which are given by name, with
+// the user's groups and their targets. It returns the total number of
+// targets, counting duplicates.
+func (c *Campaign) loadGroups(uid int64) (int, error) {
+	total := 0
+	for i, g := range c.Groups {
+		var err error
+		c.Groups[i], err = GetGroupByName(g.Name, uid)
+		if err == gorm.ErrRecordNotFound {
+			log.WithFields(logrus.Fields{
+				"group": g.Name,
+			}).Error("Group does not exist")
+			return 0, ErrGroupNotFound
+		} else if err != nil {
+			log.Error(err)
+			return 0, err
+		}
+		total += len(c.Groups[i].Targets)
+	}
+	return total, nil
+}
+
+// PreviewCampaignRecipients resolves the recipients a campaign would be sent
+// to, using the same deduplication, exclusions, fatigue cooldown and clicker
+// suppression as when it's created. Nothing is persisted.
+func PreviewCampaignRecipients(c *Campaign, uid int64) (RecipientPreview, error) {
+	p := RecipientPreview{}
+	if len(c.Groups) == 0 {
+		return p, ErrGroupNotSpecified
+	}
+	err := c.resolveLaunchAt(time.Now().UTC())
+	if err != nil {
+		return p, err
+	}
+	err = c.validateRecipientFilters()
+	if err != nil {
+		return p, err
+	}
+	p.Total, err = c.loadGroups(uid)
+	if err != nil {
+		return p, err
+	}
+	err = c.loadPriorClickers(uid)
+	if err != nil {
+		return p, err
+	}
+	rr := c.resolveRecipients()
+	p.Recipients = len(rr.Recipients)
+	p.Skipped = rr.Skipped
+	for _, s := range rr.Skipped {
+		switch s.Reason {
+		case SkipReasonDuplicate:
+			p.Duplicates++
+		case SkipReasonExcluded:
+			p.Excluded++
+		case SkipReasonFatigued:
+			p.Fatigued++
+		case SkipReasonClicked:
+			p.Clicked++
+		}
+	}
+	return p, nil
+}
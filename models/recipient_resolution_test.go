@@ -0,0 +1,183 @@
+package models
+
+import (
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func recipientTarget(email string, lastCampaign *time.Time) Target {
+	return Target{
+		LastCampaignDate: lastCampaign,
+		BaseRecipient:    BaseRecipient{Email: email},
+	}
+}
+
+func (s *ModelsSuite) TestResolveRecipientsSkipReasons(ch *check.C) {
+	launch := time.Date(2025, 6, 10, 9, 0, 0, 0, time.UTC)
+	recent := launch.Add(-3 * 24 * time.Hour)
+	old := launch.Add(-30 * 24 * time.Hour)
+	c := Campaign{
+		LaunchDate:   launch,
+		CooldownDays: 7,
+		Exclusions:   []string{" CEO@example.com ", "@partner.com"},
+		Groups: []Group{
+			{Name: "Staff", Targets: []Target{
+				recipientTarget("alice@example.com", nil),
+				recipientTarget("ceo@example.com", nil),
+				recipientTarget("bob@partner.com", nil),
+				recipientTarget("carol@example.com", &recent),
+				recipientTarget("dave@example.com", &old),
+			}},
+			{Name: "Managers", Targets: []Target{
+				recipientTarget("alice@example.com", nil),
+				recipientTarget("erin@example.com", nil),
+			}},
+		},
+	}
+	ch.Assert(c.validateRecipientFilters(), check.Equals, nil)
+
+	rr := c.resolveRecipients()
+	emails := []string{}
+	for _, t := range rr.Recipients {
+		emails = append(emails, t.Email)
+	}
+	ch.Assert(emails, check.DeepEquals, []string{"alice@example.com", "dave@example.com", "erin@example.com"})
+	ch.Assert(rr.Skipped, check.DeepEquals, []SkippedRecipient{
+		{Email: "ceo@example.com", Group: "Staff", Reason: SkipReasonExcluded},
+		{Email: "bob@partner.com", Group: "Staff", Reason: SkipReasonExcluded},
+		{Email: "carol@example.com", Group: "Staff", Reason: SkipReasonFatigued},
+		{Email: "alice@example.com", Group: "Managers", Reason: SkipReasonDuplicate},
+	})
+	// Duplicates have their last campaign date updated along with the
+	// recipients, but excluded and fatigued targets don't
+	ch.Assert(len(rr.TargetIds), check.Equals, 4)
+}
+
+func (s *ModelsSuite) TestResolveRecipientsSkipsPriorClickers(ch *check.C) {
+	c := Campaign{
+		SuppressClickersFrom: 1,
+		priorClickers:        map[string]bool{"alice@example.com": true},
+		Groups: []Group{{Name: "Staff", Targets: []Target{
+			recipientTarget("Alice@Example.com", nil),
+			recipientTarget("bob@example.com", nil),
+		}}},
+	}
+	rr := c.resolveRecipients()
+	ch.Assert(len(rr.Recipients), check.Equals, 1)
+	ch.Assert(rr.Recipients[0].Email, check.Equals, "bob@example.com")
+	ch.Assert(rr.Skipped, check.DeepEquals, []SkippedRecipient{
+		{Email: "Alice@Example.com", Group: "Staff", Reason: SkipReasonClicked},
+	})
+}
+
+func (s *ModelsSuite) TestResolveRecipientsNoFilters(ch *check.C) {
+	recent := time.Now().UTC()
+	c := Campaign{Groups: []Group{{Name: "Staff", Targets: []Target{
+		recipientTarget("alice@example.com", &recent),
+		recipientTarget("bob@example.com", nil),
+	}}}}
+	rr := c.resolveRecipients()
+	ch.Assert(len(rr.Recipients), check.Equals, 2)
+	ch.Assert(len(rr.Skipped), check.Equals, 0)
+}
+
+func (s *ModelsSuite) TestValidateRecipientFilters(ch *check.C) {
+	c := Campaign{CooldownDays: -1}
+	ch.Assert(c.validateRecipientFilters(), check.Equals, ErrInvalidCooldownDays)
+	for _, exclusion := range []string{"example.com", "user@", ""} {
+		c = Campaign{Exclusions: []string{exclusion}}
+		ch.Assert(c.validateRecipientFilters(), check.Equals, ErrInvalidExclusion, check.Commentf("exclusion: %q", exclusion))
+	}
+	c = Campaign{Exclusions: []string{"@Example.com", "User@Example.com"}}
+	ch.Assert(c.validateRecipientFilters(), check.Equals, nil)
+	ch.Assert(c.Exclusions, check.DeepEquals, []string{"@example.com", "user@example.com"})
+}
+
+func (s *ModelsSuite) TestPreviewCampaignRecipients(ch *check.C) {
+	c := s.createCampaignDependencies(ch)
+	recent := time.Now().UTC().Add(-time.Hour)
+	g := c.Groups[0]
+	g.Targets = append(g.Targets, Target{BaseRecipient: BaseRecipient{Email: "test1@example.com"}})
+	ch.Assert(PutGroup(&g), check.Equals, nil)
+	ch.Assert(db.Model(&Target{}).Where("email=?", "test2@example.com").Update("last_campaign_date", recent).Error, check.Equals, nil)
+
+	preview := Campaign{
+		Groups:       []Group{{Name: g.Name}},
+		CooldownDays: 1,
+		Exclusions:   []string{"test3@example.com"},
+	}
+	p, err := PreviewCampaignRecipients(&preview, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(p.Total, check.Equals, 5)
+	ch.Assert(p.Recipients, check.Equals, 2)
+	ch.Assert(p.Duplicates, check.Equals, 1)
+	ch.Assert(p.Excluded, check.Equals, 1)
+	ch.Assert(p.Fatigued, check.Equals, 1)
+	ch.Assert(len(p.Skipped), check.Equals, 3)
+
+	// Previewing doesn't create a campaign
+	var count int
+	ch.Assert(db.Model(&Campaign{}).Count(&count).Error, check.Equals, nil)
+	ch.Assert(count, check.Equals, 0)
+	ch.Assert(db.Model(&Result{}).Count(&count).Error, check.Equals, nil)
+	ch.Assert(count, check.Equals, 0)
+
+	_, err = PreviewCampaignRecipients(&Campaign{Groups: []Group{{Name: "Missing"}}}, c.UserId)
+	ch.Assert(err, check.Equals, ErrGroupNotFound)
+}
+
+func (s *ModelsSuite) TestPostCampaignSuppressesPriorClickers(ch *check.C) {
+	first := s.createCampaign(ch)
+	for _, r := range first.Results {
+		switch r.Email {
+		case "test1@example.com":
+			ch.Assert(r.HandleClickedLink(EventDetails{}), check.Equals, nil)
+		case "test2@example.com":
+			ch.Assert(r.HandleFormSubmit(EventDetails{}), check.Equals, nil)
+		}
+	}
+
+	followUp := Campaign{
+		Name:                 "Follow-up",
+		UserId:               first.UserId,
+		Template:             first.Template,
+		Page:                 first.Page,
+		EmailAccount:         first.EmailAccount,
+		Groups:               []Group{{Name: first.Groups[0].Name}},
+		SuppressClickersFrom: first.Id,
+	}
+	ch.Assert(PostCampaign(&followUp, followUp.UserId), check.Equals, nil)
+	emails := []string{}
+	for _, r := range followUp.Results {
+		emails = append(emails, r.Email)
+	}
+	ch.Assert(emails, check.DeepEquals, []string{"test3@example.com", "test4@example.com"})
+	ch.Assert(followUp.Skipped, check.DeepEquals, []SkippedRecipient{
+		{Email: "test1@example.com", Group: first.Groups[0].Name, Reason: SkipReasonClicked},
+		{Email: "test2@example.com", Group: first.Groups[0].Name, Reason: SkipReasonClicked},
+	})
+
+	// Without the option, everyone is targeted again
+	again := Campaign{
+		Name:         "Everyone",
+		UserId:       first.UserId,
+		Template:     first.Template,
+		Page:         first.Page,
+		EmailAccount: first.EmailAccount,
+		Groups:       []Group{{Name: first.Groups[0].Name}},
+	}
+	ch.Assert(PostCampaign(&again, again.UserId), check.Equals, nil)
+	ch.Assert(len(again.Results), check.Equals, 4)
+
+	missing := Campaign{
+		Name:                 "Missing prior",
+		UserId:               first.UserId,
+		Template:             first.Template,
+		Page:                 first.Page,
+		EmailAccount:         first.EmailAccount,
+		Groups:               []Group{{Name: first.Groups[0].Name}},
+		SuppressClickersFrom: first.Id + 100,
+	}
+	ch.Assert(PostCampaign(&missing, missing.UserId), check.Equals, ErrPriorCampaignNotFound)
+}
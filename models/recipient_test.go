@@ -0,0 +1,143 @@
+package models
+
+import (
+	"github.com/gophish/gophish/config"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestNormalizeRecipientField(ch *check.C) {
+	cases := map[string]string{
+		"Jane":                         "Jane",
+		"  José-María O'Neil ":         "José-María O'Neil",
+		"{{.URL}}":                     ".URL",
+		"Bob {{template \"x\"}}":       "Bob template \"x\"",
+		"{{{{}}.Tracker}}":             ".Tracker",
+		"Eve\r\nBcc: evil@example.com": "EveBcc: evil@example.com",
+		"<script>alert(1)</script>":    "<script>alert(1)</script>",
+	}
+	for input, expected := range cases {
+		ch.Assert(NormalizeRecipientField(input), check.Equals, expected)
+	}
+}
+
+func (s *ModelsSuite) TestTemplateContextStripsRecipientTemplateSyntax(ch *check.C) {
+	r := BaseRecipient{
+		FirstName: "{{.URL}}",
+		LastName:  "Smith",
+		Email:     "foo@bar.com",
+	}
+	ctx := mockTemplateContext{
+		URL:         "http://example.com",
+		FromAddress: "From Address <from@example.com>",
+	}
+	ptx, err := NewPhishingTemplateContext(ctx, r, "1234567")
+	ch.Assert(err, check.Equals, nil)
+	got, err := ExecuteTemplate("Hi {{.FirstName}} {{.LastName}}", ptx)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got, check.Equals, "Hi .URL Smith")
+}
+
+func (s *ModelsSuite) TestHTMLEscapedRecipient(ch *check.C) {
+	ptx := PhishingTemplateContext{
+		BaseRecipient: BaseRecipient{
+			FirstName: "<script>alert(1)</script>",
+			LastName:  "O'Brien",
+			Position:  "R&D",
+		},
+	}
+	got, err := ExecuteTemplate("<p>{{.FirstName}} {{.LastName}}, {{.Position}}</p>", ptx.HTMLEscaped())
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got, check.Equals, "<p>&lt;script&gt;alert(1)&lt;/script&gt; O&#39;Brien, R&amp;D</p>")
+
+	// The original context is left untouched for plaintext rendering
+	ch.Assert(ptx.FirstName, check.Equals, "<script>alert(1)</script>")
+}
+
+func (s *ModelsSuite) TestRecipientSanitizationConfig(ch *check.C) {
+	original := conf.RecipientSanitization
+	defer func() { conf.RecipientSanitization = original }()
+
+	conf.RecipientSanitization = &config.RecipientSanitization{AllowHTML: true}
+	ch.Assert(escapeRecipientField("<b>Bob</b>"), check.Equals, "<b>Bob</b>")
+	ch.Assert(NormalizeRecipientField("{{.URL}}"), check.Equals, ".URL")
+
+	conf.RecipientSanitization = &config.RecipientSanitization{Disabled: true}
+	ch.Assert(NormalizeRecipientField("{{.URL}}"), check.Equals, "{{.URL}}")
+	ch.Assert(escapeRecipientField("<b>Bob</b>"), check.Equals, "<b>Bob</b>")
+}
+
+func (s *ModelsSuite) TestLandingPageContext(ch *check.C) {
+	original := conf.LandingPageContext
+	defer func() { conf.LandingPageContext = original }()
+	ptx := PhishingTemplateContext{
+		RId: "1234567",
+		BaseRecipient: BaseRecipient{
+			FirstName: "Jane",
+			LastName:  "Doe",
+			Email:     "jane@example.com",
+			Position:  "CFO",
+		},
+	}
+	page := "{{.FirstName}} {{.LastName}} <{{.Email}}> {{.Position}} {{.RId}}"
+
+	// Every recipient attribute is available by default
+	conf.LandingPageContext = nil
+	got, err := ExecuteTemplate(page, ptx.ForLandingPage())
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got, check.Equals, "Jane Doe <jane@example.com> CFO 1234567")
+
+	conf.LandingPageContext = &config.LandingPageContext{Fields: []string{"first_name", "Email"}}
+	got, err = ExecuteTemplate(page, ptx.ForLandingPage())
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got, check.Equals, "Jane  <jane@example.com>  1234567")
+
+	conf.LandingPageContext = &config.LandingPageContext{Disabled: true, Fields: []string{"first_name"}}
+	got, err = ExecuteTemplate(page, ptx.ForLandingPage())
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got, check.Equals, "  <>  1234567")
+
+	// The original context is left untouched for rendering emails
+	ch.Assert(ptx.LastName, check.Equals, "Doe")
+}
+
+func (s *ModelsSuite) TestRecipientDedupKey(ch *check.C) {
+	original := conf.DedupNormalizeEmails
+	defer func() { conf.DedupNormalizeEmails = original }()
+
+	// By default addresses are compared exactly as given
+	conf.DedupNormalizeEmails = false
+	ch.Assert(RecipientDedupKey("User+Tag@Example.com"), check.Equals, "User+Tag@Example.com")
+
+	conf.DedupNormalizeEmails = true
+	cases := map[string]string{
+		"user@example.com":              "user@example.com",
+		"User+Tag@Example.com":          "user@example.com",
+		"user+a+b@example.com":          "user@example.com",
+		"first.last@example.com":        "first.last@example.com",
+		"First.Last+news@gmail.com":     "firstlast@gmail.com",
+		"f.i.r.s.t.last@googlemail.com": "firstlast@gmail.com",
+		"+tag@example.com":              "+tag@example.com",
+		"not-an-email":                  "not-an-email",
+	}
+	for input, expected := range cases {
+		ch.Assert(RecipientDedupKey(input), check.Equals, expected, check.Commentf("input: %s", input))
+	}
+}
+
+func (s *ModelsSuite) TestPostCampaignDedupNormalization(ch *check.C) {
+	original := conf.DedupNormalizeEmails
+	defer func() { conf.DedupNormalizeEmails = original }()
+	conf.DedupNormalizeEmails = true
+
+	c := s.createCampaignDependencies(ch)
+	g := c.Groups[0]
+	g.Targets = append(g.Targets, Target{BaseRecipient: BaseRecipient{Email: "test1+promo@example.com", FirstName: "Tagged"}})
+	ch.Assert(PutGroup(&g), check.Equals, nil)
+	c.Groups = []Group{g}
+
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	ch.Assert(len(c.Results), check.Equals, 4)
+	for _, r := range c.Results {
+		ch.Assert(r.Email, check.Not(check.Equals), "test1+promo@example.com")
+	}
+}
@@ -33,8 +33,20 @@ type Result struct {
 	Latitude     float64   `json:"latitude"`
 	Longitude    float64   `json:"longitude"`
 	SendDate     time.Time `json:"send_date"`
+	SentDate     time.Time `json:"sent_date"`
 	Reported     bool      `json:"reported" sql:"not null"`
+	AutoReplied  bool      `json:"auto_replied" sql:"not null"`
+	Bounced      bool      `json:"bounced" sql:"not null"`
+	Internal     bool      `json:"internal" sql:"not null"`
 	ModifiedDate time.Time `json:"modified_date"`
+	TemplateId   int64     `json:"template_id,omitempty"`
+	PageId       int64     `json:"page_id,omitempty"`
+	// Notes are added by operators, and aren't stored with the result
+	Notes []ResultNote `json:"notes,omitempty" gorm:"-"`
+	// OpenCount and ClickCount are the number of times the recipient opened
+	// the email and clicked the link, counted from the campaign's timeline
+	OpenCount  int `json:"open_count" gorm:"-"`
+	ClickCount int `json:"click_count" gorm:"-"`
 	BaseRecipient
 }
 
@@ -59,6 +71,35 @@ func (r *Result) HandleEmailSent() error {
 		return err
 	}
 	r.SendDate = event.Time
+	r.SentDate = event.Time
+	r.Status = EventSent
+	r.ModifiedDate = event.Time
+	return db.Save(r).Error
+}
+
+// EventSentDetails records when the email was actually accepted for
+// delivery, as reported by n8n.
+type EventSentDetails struct {
+	SentAt time.Time `json:"sent_at"`
+}
+
+// HandleEmailSentAt updates a Result to indicate that n8n sent the email at
+// the given time. Unlike HandleEmailSent, the scheduled send date is kept, so
+// that the planned and actual send times can be compared. If the time isn't
+// known, the time the email was reported as sent is used.
+func (r *Result) HandleEmailSentAt(sentAt time.Time) error {
+	var details interface{}
+	if !sentAt.IsZero() {
+		details = EventSentDetails{SentAt: sentAt.UTC()}
+	}
+	event, err := r.createEvent(EventSent, details)
+	if err != nil {
+		return err
+	}
+	if sentAt.IsZero() {
+		sentAt = event.Time
+	}
+	r.SentDate = sentAt.UTC()
 	r.Status = EventSent
 	r.ModifiedDate = event.Time
 	return db.Save(r).Error
@@ -147,6 +188,20 @@ func (r *Result) HandleEmailReport(details EventDetails) error {
 	return db.Save(r).Error
 }
 
+// HandleAutoReply updates a Result to indicate that the recipient's mailbox
+// sent an automatic reply, such as an out of office message. As with reports,
+// auto-replies are tracked separately from the result status, so they don't
+// count towards engagement.
+func (r *Result) HandleAutoReply(details EventDetails) error {
+	event, err := r.createEvent(EventAutoReply, details)
+	if err != nil {
+		return err
+	}
+	r.AutoReplied = true
+	r.ModifiedDate = event.Time
+	return db.Save(r).Error
+}
+
 // UpdateGeo updates the latitude and longitude of the result in
 // the database given an IP address
 func (r *Result) UpdateGeo(addr string) error {
@@ -208,3 +263,26 @@ func GetResult(rid string) (Result, error) {
 	err := db.Where("r_id=?", rid).First(&r).Error
 	return r, err
 }
+
+// countResultEngagement sets the number of times each recipient opened the
+// email and clicked the link from the campaign's events. Every open and click
+// is recorded as its own event, whereas the result's status and the campaign
+// statistics only count each recipient once.
+func countResultEngagement(rs []Result, events []Event) {
+	byEmail := make(map[string][]int, len(rs))
+	for i := range rs {
+		rs[i].OpenCount = 0
+		rs[i].ClickCount = 0
+		byEmail[rs[i].Email] = append(byEmail[rs[i].Email], i)
+	}
+	for _, e := range events {
+		for _, i := range byEmail[e.Email] {
+			switch e.Message {
+			case EventOpened:
+				rs[i].OpenCount++
+			case EventClicked:
+				rs[i].ClickCount++
+			}
+		}
+	}
+}
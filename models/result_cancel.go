@@ -0,0 +1,104 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNoRecipientsToCancel indicates that a request to cancel scheduled sends
+// didn't include any recipients
+var ErrNoRecipientsToCancel = errors.New("No recipients specified")
+
+// ErrResultCancelled is returned when generating an email for a recipient
+// whose send has been cancelled
+var ErrResultCancelled = errors.New("Send was cancelled for this recipient")
+
+// cancellableStatuses are the result statuses of recipients who haven't yet
+// been sent their email.
+var cancellableStatuses = map[string]bool{
+	StatusScheduled: true,
+	StatusQueued:    true,
+	StatusRetry:     true,
+	StatusSending:   true,
+}
+
+// CancelSkipped is a recipient whose send couldn't be cancelled.
+type CancelSkipped struct {
+	Recipient string `json:"recipient"`
+	Reason    string `json:"reason"`
+}
+
+// CancelReport is the outcome of cancelling the scheduled sends of some of
+// a campaign's recipients.
+type CancelReport struct {
+	Cancelled []string        `json:"cancelled"`
+	Skipped   []CancelSkipped `json:"skipped"`
+}
+
+// CancelScheduledResults stops the emails to the given recipients of a
+// campaign, identified by either their rid or email address, from being sent.
+// Their pending maillogs are removed and they are marked as cancelled, so
+// that any n8n callbacks for them are ignored. Recipients who have already
+// been sent their email, or whose email is being sent, are skipped.
+func CancelScheduledResults(cid int64, uid int64, recipients []string) (CancelReport, error) {
+	report := CancelReport{Cancelled: []string{}, Skipped: []CancelSkipped{}}
+	if len(recipients) == 0 {
+		return report, ErrNoRecipientsToCancel
+	}
+	c := Campaign{}
+	err := db.Select("id").Where("id = ? and user_id = ?", cid, uid).First(&c).Error
+	if err != nil {
+		return report, err
+	}
+	for _, recipient := range recipients {
+		recipient = strings.TrimSpace(recipient)
+		skip := func(reason string) {
+			report.Skipped = append(report.Skipped, CancelSkipped{Recipient: recipient, Reason: reason})
+		}
+		r := Result{}
+		err = db.Where("campaign_id = ? and (r_id = ? or lower(email) = ?)", cid, recipient, strings.ToLower(recipient)).
+			First(&r).Error
+		if err != nil {
+			skip("recipient not found")
+			continue
+		}
+		if !cancellableStatuses[r.Status] {
+			skip("email already sent")
+			continue
+		}
+		// Maillogs locked by the worker are being sent right now
+		var sending int64
+		err = db.Model(&MailLog{}).Where("r_id = ? and processing = ?", r.RId, true).Count(&sending).Error
+		if err != nil {
+			return report, err
+		}
+		if sending > 0 {
+			skip("email is being sent")
+			continue
+		}
+		err = db.Where("r_id = ? and processing = ?", r.RId, false).Delete(&MailLog{}).Error
+		if err != nil {
+			return report, err
+		}
+		event, err := r.createEvent(EventCancelled, nil)
+		if err != nil {
+			return report, err
+		}
+		r.Status = StatusCancelled
+		r.ModifiedDate = event.Time
+		err = db.Save(&r).Error
+		if err != nil {
+			return report, err
+		}
+		report.Cancelled = append(report.Cancelled, r.Email)
+	}
+	log.WithFields(logrus.Fields{
+		"campaign_id": cid,
+		"cancelled":   len(report.Cancelled),
+		"skipped":     len(report.Skipped),
+	}).Info("Cancelled scheduled sends")
+	return report, nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gophish/gomail"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestCancelScheduledResults(ch *check.C) {
+	campaign := s.createCampaign(ch)
+	cancelled := campaign.Results[0]
+	sent := campaign.Results[1]
+	ch.Assert(sent.HandleEmailSent(), check.Equals, nil)
+
+	report, err := CancelScheduledResults(campaign.Id, campaign.UserId, []string{
+		cancelled.Email, sent.RId, "nobody@example.com",
+	})
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(report.Cancelled, check.DeepEquals, []string{cancelled.Email})
+	ch.Assert(len(report.Skipped), check.Equals, 2)
+	ch.Assert(report.Skipped[0].Recipient, check.Equals, sent.RId)
+	ch.Assert(report.Skipped[1].Recipient, check.Equals, "nobody@example.com")
+
+	r, err := GetResult(cancelled.RId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(r.Status, check.Equals, StatusCancelled)
+	r, err = GetResult(sent.RId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(r.Status, check.Equals, EventSent)
+
+	// The cancelled recipient is never queued for sending
+	ms, err := GetQueuedMailLogs(time.Now().UTC().Add(365 * 24 * time.Hour))
+	ch.Assert(err, check.Equals, nil)
+	for _, m := range ms {
+		ch.Assert(m.RId, check.Not(check.Equals), cancelled.RId)
+	}
+	events := []Event{}
+	err = db.Where("campaign_id = ? and email = ? and message = ?", campaign.Id, cancelled.Email, EventCancelled).
+		Find(&events).Error
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(events), check.Equals, 1)
+
+	// A maillog picked up before it was cancelled doesn't send either
+	m := &MailLog{UserId: campaign.UserId, CampaignId: campaign.Id, RId: cancelled.RId, SendDate: time.Now().UTC()}
+	ch.Assert(db.Save(m).Error, check.Equals, nil)
+	ch.Assert(m.Generate(gomail.NewMessage()), check.Equals, ErrResultCancelled)
+	ch.Assert(m.Error(ErrResultCancelled), check.Equals, nil)
+	r, err = GetResult(cancelled.RId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(r.Status, check.Equals, StatusCancelled)
+
+	_, err = CancelScheduledResults(campaign.Id, campaign.UserId, nil)
+	ch.Assert(err, check.Equals, ErrNoRecipientsToCancel)
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxResultNoteLength is the most characters a note on a result may have.
+const MaxResultNoteLength = 2000
+
+// ErrResultNoteEmpty is thrown when adding a note with no text
+var ErrResultNoteEmpty = errors.New("Note can't be empty")
+
+// ErrResultNoteTooLong is thrown when adding a note longer than
+// MaxResultNoteLength
+var ErrResultNoteTooLong = fmt.Errorf("Note must be at most %d characters", MaxResultNoteLength)
+
+// The action and result recorded in the authorization log when a note is
+// added to a result.
+const (
+	ResultNoteAction = "result_note"
+	ResultNoteAdded  = "added"
+)
+
+// ResultNote is a free-form note an operator has added to a recipient's
+// result, such as how they reported the email.
+type ResultNote struct {
+	Id          int64     `json:"id"`
+	ResultId    int64     `json:"-"`
+	CampaignId  int64     `json:"-"`
+	UserId      int64     `json:"user_id"`
+	Username    string    `json:"username"`
+	Note        string    `json:"note"`
+	CreatedDate time.Time `json:"created_date"`
+}
+
+// TableName specifies the database table for Gorm to use
+func (n ResultNote) TableName() string {
+	return "result_notes"
+}
+
+// AddResultNote adds a note by the user to the result with the given rid in
+// the campaign, returning the result with all of its notes. The campaign must
+// belong to the user.
+func AddResultNote(cid int64, u User, rid string, note string) (Result, error) {
+	r := Result{}
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return r, ErrResultNoteEmpty
+	}
+	if utf8.RuneCountInString(note) > MaxResultNoteLength {
+		return r, ErrResultNoteTooLong
+	}
+	r, err := GetCampaignResult(cid, u.Id, rid)
+	if err != nil {
+		return r, err
+	}
+	n := ResultNote{
+		ResultId:    r.Id,
+		CampaignId:  cid,
+		UserId:      u.Id,
+		Username:    u.Username,
+		Note:        note,
+		CreatedDate: time.Now().UTC(),
+	}
+	// The note is audited in the authorization log along with being saved,
+	// so that who noted which result can be queried later
+	tx := db.Begin()
+	err = tx.Save(&n).Error
+	if err != nil {
+		tx.Rollback()
+		log.Error(err)
+		return r, err
+	}
+	err = tx.Create(&EmailAuthorizationLog{
+		Email:           u.Username,
+		NormalizedEmail: NewEmailAuthorizationService().NormalizeEmail(u.Username),
+		Action:          ResultNoteAction,
+		Result:          ResultNoteAdded,
+		UserID:          &u.Id,
+		CreatedAt:       time.Now(),
+		Details:         fmt.Sprintf("campaign_id=%d rid=%s note_id=%d", cid, rid, n.Id),
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		log.Error(err)
+		return r, err
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		log.Error(err)
+		return r, err
+	}
+	log.WithFields(logrus.Fields{
+		"campaign_id": cid,
+		"rid":         rid,
+		"user_id":     u.Id,
+		"username":    u.Username,
+	}).Info("Added note to result")
+	r.Notes = append(r.Notes, n)
+	return r, nil
+}
+
+// GetCampaignResult returns the result with the given rid in the campaign,
+// along with its notes and engagement counts. The campaign must belong to the user.
+func GetCampaignResult(cid int64, uid int64, rid string) (Result, error) {
+	r := Result{}
+	err := db.Where("campaign_id=? and user_id=? and r_id=?", cid, uid, rid).First(&r).Error
+	if err != nil {
+		return r, err
+	}
+	rs := []Result{r}
+	err = attachResultNotes(rs)
+	if err != nil {
+		return rs[0], err
+	}
+	events := []Event{}
+	err = db.Where("campaign_id=? and email=? and message in (?)", cid, r.Email,
+		[]string{EventOpened, EventClicked}).Find(&events).Error
+	if err != nil {
+		return rs[0], err
+	}
+	countResultEngagement(rs, events)
+	return rs[0], nil
+}
+
+// attachResultNotes loads the notes on each of the results, oldest first.
+func attachResultNotes(rs []Result) error {
+	if len(rs) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(rs))
+	byId := make(map[int64]*Result, len(rs))
+	for i := range rs {
+		ids[i] = rs[i].Id
+		byId[rs[i].Id] = &rs[i]
+	}
+	notes := []ResultNote{}
+	err := db.Where("result_id in (?)", ids).Order("created_date asc, id asc").Find(&notes).Error
+	if err != nil {
+		return err
+	}
+	for _, n := range notes {
+		if r, ok := byId[n.ResultId]; ok {
+			r.Notes = append(r.Notes, n)
+		}
+	}
+	return nil
+}
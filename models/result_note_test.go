@@ -0,0 +1,63 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestAddResultNote(ch *check.C) {
+	campaign := s.createCampaign(ch)
+	result := campaign.Results[0]
+	u, err := GetUser(campaign.UserId)
+	ch.Assert(err, check.Equals, nil)
+
+	r, err := AddResultNote(campaign.Id, u, result.RId, "  Reported to the help desk  ")
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(r.Notes), check.Equals, 1)
+	ch.Assert(r.Notes[0].Note, check.Equals, "Reported to the help desk")
+	ch.Assert(r.Notes[0].Username, check.Equals, u.Username)
+	ch.Assert(r.Notes[0].CreatedDate.IsZero(), check.Equals, false)
+
+	r, err = AddResultNote(campaign.Id, u, result.RId, "Called back to confirm")
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(r.Notes), check.Equals, 2)
+	ch.Assert(r.Notes[0].Note, check.Equals, "Reported to the help desk")
+	ch.Assert(r.Notes[1].Note, check.Equals, "Called back to confirm")
+
+	// Notes are returned with the campaign's results
+	cr, err := GetCampaignResults(campaign.Id, campaign.UserId)
+	ch.Assert(err, check.Equals, nil)
+	for _, cr := range cr.Results {
+		if cr.RId == result.RId {
+			ch.Assert(len(cr.Notes), check.Equals, 2)
+		} else {
+			ch.Assert(len(cr.Notes), check.Equals, 0)
+		}
+	}
+
+	// Each note is audited
+	logs, err := GetAuthorizationLogs(u.Username, ResultNoteAction, ResultNoteAdded, 0, 0)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(logs), check.Equals, 2)
+	for _, l := range logs {
+		ch.Assert(*l.UserID, check.Equals, u.Id)
+		ch.Assert(strings.Contains(l.Details, "rid="+result.RId), check.Equals, true)
+	}
+
+	_, err = AddResultNote(campaign.Id, u, result.RId, " ")
+	ch.Assert(err, check.Equals, ErrResultNoteEmpty)
+	_, err = AddResultNote(campaign.Id, u, result.RId, strings.Repeat("a", MaxResultNoteLength+1))
+	ch.Assert(err, check.Equals, ErrResultNoteTooLong)
+
+	// Results in other users' campaigns can't be noted
+	other := User{Id: u.Id + 1, Username: "other"}
+	_, err = AddResultNote(campaign.Id, other, result.RId, "Not mine")
+	ch.Assert(err, check.Equals, gorm.ErrRecordNotFound)
+	_, err = AddResultNote(campaign.Id+1, u, result.RId, "Wrong campaign")
+	ch.Assert(err, check.Equals, gorm.ErrRecordNotFound)
+	logs, err = GetAuthorizationLogs(u.Username, ResultNoteAction, "", 0, 0)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(logs), check.Equals, 2)
+}
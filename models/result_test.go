@@ -118,3 +118,39 @@ func (s *ModelsSuite) TestDuplicateResults(ch *check.C) {
 	ch.Assert(c.Results[0].Email, check.Equals, group.Targets[0].Email)
 	ch.Assert(c.Results[1].Email, check.Equals, group.Targets[2].Email)
 }
+
+func (s *ModelsSuite) TestResultEngagementCounts(ch *check.C) {
+	campaign := s.createCampaign(ch)
+	engaged, err := GetResult(campaign.Results[0].RId)
+	ch.Assert(err, check.Equals, nil)
+	for i := 0; i < 3; i++ {
+		ch.Assert(engaged.HandleEmailOpened(EventDetails{}), check.Equals, nil)
+	}
+	for i := 0; i < 2; i++ {
+		ch.Assert(engaged.HandleClickedLink(EventDetails{}), check.Equals, nil)
+	}
+	opened, err := GetResult(campaign.Results[1].RId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(opened.HandleEmailOpened(EventDetails{}), check.Equals, nil)
+
+	cr, err := GetCampaignResults(campaign.Id, campaign.UserId)
+	ch.Assert(err, check.Equals, nil)
+	counts := map[string][2]int{}
+	for _, r := range cr.Results {
+		counts[r.RId] = [2]int{r.OpenCount, r.ClickCount}
+	}
+	ch.Assert(counts[engaged.RId], check.Equals, [2]int{3, 2})
+	ch.Assert(counts[opened.RId], check.Equals, [2]int{1, 0})
+	ch.Assert(counts[campaign.Results[2].RId], check.Equals, [2]int{0, 0})
+
+	r, err := GetCampaignResult(campaign.Id, campaign.UserId, engaged.RId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(r.OpenCount, check.Equals, 3)
+	ch.Assert(r.ClickCount, check.Equals, 2)
+
+	// Repeated opens and clicks don't inflate the unique recipient funnel
+	stats, err := getCampaignStats(campaign.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(stats.OpenedEmail, check.Equals, int64(2))
+	ch.Assert(stats.ClickedLink, check.Equals, int64(1))
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrNoResultStatusesSpecified is thrown when creating a group from a
+// campaign's results without saying which results to include
+var ErrNoResultStatusesSpecified = errors.New("At least one result status must be specified")
+
+// ErrNoMatchingResults is thrown when no results in the campaign match the
+// statuses a group is being created from
+var ErrNoMatchingResults = errors.New("No results match the given statuses")
+
+// resultFunnel is the order recipients move through a campaign's statuses
+// once they've been sent their email. A recipient in one of these statuses
+// has also reached each of the statuses before it.
+var resultFunnel = []string{EventSent, EventOpened, EventClicked, EventDataSubmit}
+
+// funnelStatuses returns the stored statuses of the recipients who reached
+// the given status, following the funnel so that, for example, recipients
+// who clicked include those who went on to submit data.
+func funnelStatuses(status string) []string {
+	stored := resultStatusFilters[status]
+	for i, s := range resultFunnel {
+		if s == stored {
+			return resultFunnel[i:]
+		}
+	}
+	return []string{stored}
+}
+
+// ResultsGroupRequest is a request to create a group from the recipients of
+// a campaign whose results match any of the given statuses, such as everyone
+// who clicked, so that they can be targeted again. Statuses in the funnel
+// include the recipients who went further, so clicking includes submitting
+// data.
+type ResultsGroupRequest struct {
+	Name     string   `json:"name"`
+	Statuses []string `json:"statuses"`
+}
+
+// ResultsGroup is a group created from a campaign's results, along with the
+// matching recipients which were left out because their email address isn't
+// valid.
+type ResultsGroup struct {
+	Group   Group    `json:"group"`
+	Skipped []string `json:"skipped"`
+}
+
+// Validate ensures the group is named and each status is known.
+func (rg *ResultsGroupRequest) Validate() error {
+	if rg.Name == "" {
+		return ErrGroupNameNotSpecified
+	}
+	if len(rg.Statuses) == 0 {
+		return ErrNoResultStatusesSpecified
+	}
+	for i, status := range rg.Statuses {
+		rg.Statuses[i] = strings.ToLower(status)
+		if rg.Statuses[i] == "" {
+			return ErrInvalidResultStatus
+		}
+		f := ResultsFilter{Status: rg.Statuses[i]}
+		if err := f.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateGroupFromResults creates a group owned by the user from the
+// recipients of the campaign whose results match any of the requested
+// statuses. Recipients are copied with their details, each email address is
+// only added once, and invalid addresses are skipped.
+func CreateGroupFromResults(cid int64, uid int64, rg ResultsGroupRequest) (ResultsGroup, error) {
+	out := ResultsGroup{Skipped: []string{}}
+	if err := rg.Validate(); err != nil {
+		return out, err
+	}
+	count := 0
+	err := db.Model(&Campaign{}).Where("id = ? AND user_id = ?", cid, uid).Count(&count).Error
+	if err != nil {
+		return out, err
+	}
+	if count == 0 {
+		return out, gorm.ErrRecordNotFound
+	}
+
+	query := db.Table("results").Where("campaign_id = ? AND user_id = ?", cid, uid)
+	conditions := []string{}
+	args := []interface{}{}
+	statuses := []string{}
+	for _, status := range rg.Statuses {
+		switch status {
+		case ResultStatusReported:
+			conditions = append(conditions, "reported = ?")
+			args = append(args, true)
+		case ResultStatusAutoReplied:
+			conditions = append(conditions, "auto_replied = ?")
+			args = append(args, true)
+		default:
+			statuses = append(statuses, funnelStatuses(status)...)
+		}
+	}
+	if len(statuses) > 0 {
+		conditions = append(conditions, "status IN (?)")
+		args = append(args, statuses)
+	}
+	query = query.Where(strings.Join(conditions, " OR "), args...)
+	results := []Result{}
+	err = query.Order("id asc").Find(&results).Error
+	if err != nil {
+		return out, err
+	}
+
+	seen := map[string]bool{}
+	targets := []Target{}
+	for _, r := range results {
+		email := strings.TrimSpace(r.Email)
+		key := strings.ToLower(email)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, err := mail.ParseAddress(email); err != nil {
+			out.Skipped = append(out.Skipped, r.Email)
+			continue
+		}
+		t := Target{BaseRecipient: r.BaseRecipient}
+		t.Email = email
+		targets = append(targets, t)
+	}
+	if len(targets) == 0 {
+		return out, ErrNoMatchingResults
+	}
+	out.Group = Group{
+		Name:         rg.Name,
+		UserId:       uid,
+		ModifiedDate: time.Now().UTC(),
+		Targets:      targets,
+	}
+	err = PostGroup(&out.Group)
+	return out, err
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"github.com/jinzhu/gorm"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestCreateGroupFromResults(ch *check.C) {
+	campaign := s.createCampaign(ch)
+	clicked := campaign.Results[0]
+	submitted := campaign.Results[1]
+	ch.Assert(clicked.HandleClickedLink(EventDetails{}), check.Equals, nil)
+	ch.Assert(submitted.HandleFormSubmit(EventDetails{}), check.Equals, nil)
+	ch.Assert(campaign.Results[2].HandleEmailOpened(EventDetails{}), check.Equals, nil)
+	// A second result for the same recipient is only added once
+	duplicate := clicked
+	duplicate.Id = 0
+	duplicate.RId = "duplicate"
+	ch.Assert(db.Save(&duplicate).Error, check.Equals, nil)
+	ch.Assert(CompleteCampaign(campaign.Id, campaign.UserId), check.Equals, nil)
+
+	rg, err := CreateGroupFromResults(campaign.Id, campaign.UserId, ResultsGroupRequest{
+		Name:     "Clickers",
+		Statuses: []string{"Clicked", "submitted"},
+	})
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(rg.Skipped), check.Equals, 0)
+
+	g, err := GetGroup(rg.Group.Id, campaign.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(g.Name, check.Equals, "Clickers")
+	ch.Assert(len(g.Targets), check.Equals, 2)
+	emails := map[string]Target{}
+	for _, t := range g.Targets {
+		emails[t.Email] = t
+	}
+	ch.Assert(emails[clicked.Email].FirstName, check.Equals, clicked.FirstName)
+	ch.Assert(emails[clicked.Email].LastName, check.Equals, clicked.LastName)
+	_, ok := emails[submitted.Email]
+	ch.Assert(ok, check.Equals, true)
+
+	// Clicking includes submitting data, and opening includes both
+	rg, err = CreateGroupFromResults(campaign.Id, campaign.UserId, ResultsGroupRequest{
+		Name:     "Clicked",
+		Statuses: []string{"clicked"},
+	})
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(rg.Group.Targets), check.Equals, 2)
+	rg, err = CreateGroupFromResults(campaign.Id, campaign.UserId, ResultsGroupRequest{
+		Name:     "Opened",
+		Statuses: []string{"opened"},
+	})
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(rg.Group.Targets), check.Equals, 3)
+
+	_, err = CreateGroupFromResults(campaign.Id, campaign.UserId, ResultsGroupRequest{
+		Name:     "Reporters",
+		Statuses: []string{"reported"},
+	})
+	ch.Assert(err, check.Equals, ErrNoMatchingResults)
+	_, err = CreateGroupFromResults(campaign.Id, campaign.UserId, ResultsGroupRequest{
+		Name:     "Unknown",
+		Statuses: []string{"bounced"},
+	})
+	ch.Assert(err, check.Equals, ErrInvalidResultStatus)
+	_, err = CreateGroupFromResults(campaign.Id, campaign.UserId+1, ResultsGroupRequest{
+		Name:     "Not mine",
+		Statuses: []string{"clicked"},
+	})
+	ch.Assert(err, check.Equals, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	// Embed the timezone database so that schedules work on hosts without
+	// one installed
+	_ "time/tzdata"
+)
+
+// ErrInvalidTimezone indicates that a timezone is not a valid IANA name
+var ErrInvalidTimezone = errors.New("Invalid timezone")
+
+// ErrInvalidClock indicates that a time of day isn't in HH:MM format
+var ErrInvalidClock = errors.New("Time of day must be in HH:MM format")
+
+// LoadScheduleLocation returns the location with the given IANA name, such as
+// "Europe/London". An empty name returns UTC.
+func LoadScheduleLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+// atLocalClock returns the time on the same local day as t at the given
+// offset from midnight. The offset is applied to the wall clock, so 09:00 is
+// 09:00 regardless of whether the day is 23, 24 or 25 hours long.
+func atLocalClock(t time.Time, offset time.Duration) time.Time {
+	h := int(offset / time.Hour)
+	m := int((offset % time.Hour) / time.Minute)
+	s := int((offset % time.Minute) / time.Second)
+	return time.Date(t.Year(), t.Month(), t.Day(), h, m, s, 0, t.Location())
+}
+
+// ParseClock parses a time of day in HH:MM format, returning it as an offset
+// from midnight.
+func ParseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, ErrInvalidClock
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// AddLocalDays returns t moved by the given number of calendar days in loc,
+// keeping the same wall clock time.
+func AddLocalDays(t time.Time, days int, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day()+days,
+		local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), loc)
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestLoadScheduleLocation(ch *check.C) {
+	loc, err := LoadScheduleLocation("")
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(loc, check.Equals, time.UTC)
+
+	loc, err = LoadScheduleLocation("America/New_York")
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(loc.String(), check.Equals, "America/New_York")
+
+	_, err = LoadScheduleLocation("Not/A_Zone")
+	ch.Assert(err, check.Equals, ErrInvalidTimezone)
+}
+
+func (s *ModelsSuite) TestLocalClockAcrossSpringForward(ch *check.C) {
+	loc, err := LoadScheduleLocation("America/New_York")
+	ch.Assert(err, check.Equals, nil)
+
+	// Clocks in New York go forward at 02:00 on 9 March 2025, so adding a
+	// fixed 24 hours would be an hour late
+	start := time.Date(2025, 3, 8, 9, 0, 0, 0, loc)
+	naive := start.Add(24 * time.Hour)
+	ch.Assert(naive.In(loc).Hour(), check.Equals, 10)
+	next := AddLocalDays(start, 1, loc)
+	ch.Assert(next.In(loc).Hour(), check.Equals, 9)
+	ch.Assert(next.Equal(time.Date(2025, 3, 9, 13, 0, 0, 0, time.UTC)), check.Equals, true)
+
+	// The local clock is used regardless of the length of the day
+	at := atLocalClock(time.Date(2025, 3, 9, 1, 0, 0, 0, loc), 17*time.Hour)
+	ch.Assert(at.Equal(time.Date(2025, 3, 9, 21, 0, 0, 0, time.UTC)), check.Equals, true)
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+// setSendIntervalEnv sets the send interval environment variables for a test,
+// returning a function which restores them.
+func setSendIntervalEnv(interval, enforce string) func() {
+	origInterval, hadInterval := os.LookupEnv("DEFAULT_EMAIL_SEND_INTERVAL")
+	origEnforce, hadEnforce := os.LookupEnv("ENFORCE_EMAIL_SEND_INTERVAL")
+	os.Setenv("DEFAULT_EMAIL_SEND_INTERVAL", interval)
+	os.Setenv("ENFORCE_EMAIL_SEND_INTERVAL", enforce)
+	return func() {
+		os.Unsetenv("DEFAULT_EMAIL_SEND_INTERVAL")
+		os.Unsetenv("ENFORCE_EMAIL_SEND_INTERVAL")
+		if hadInterval {
+			os.Setenv("DEFAULT_EMAIL_SEND_INTERVAL", origInterval)
+		}
+		if hadEnforce {
+			os.Setenv("ENFORCE_EMAIL_SEND_INTERVAL", origEnforce)
+		}
+	}
+}
+
+func (s *ModelsSuite) TestSendIntervalSettingsDefault(ch *check.C) {
+	defer setSendIntervalEnv("", "")()
+	settings, err := GetSendIntervalSettings()
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(settings.IntervalSeconds, check.Equals, DefaultSendInterval.Seconds())
+	ch.Assert(settings.Source, check.Equals, SendIntervalSourceDefault)
+	ch.Assert(settings.Warning, check.Equals, "")
+	ch.Assert(settings.Enforced, check.Equals, false)
+}
+
+func (s *ModelsSuite) TestSendIntervalSettingsEnv(ch *check.C) {
+	defer setSendIntervalEnv("30", "true")()
+	settings, err := GetSendIntervalSettings()
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(settings.IntervalSeconds, check.Equals, float64(30))
+	ch.Assert(settings.Source, check.Equals, SendIntervalSourceEnv)
+	ch.Assert(settings.Enforced, check.Equals, true)
+	ch.Assert(GetDefaultSendInterval(), check.Equals, 30*time.Second)
+}
+
+func (s *ModelsSuite) TestSendIntervalSettingsAccounts(ch *check.C) {
+	defer setSendIntervalEnv("30", "")()
+	paced := EmailAccount{Email: "paced@example.com", EmailType: "marketing", IsActive: true, SendIntervalSeconds: 300}
+	ch.Assert(PostEmailAccount(&paced), check.Equals, nil)
+	other := EmailAccount{Email: "other@example.com", EmailType: "support", IsActive: true}
+	ch.Assert(PostEmailAccount(&other), check.Equals, nil)
+
+	settings, err := GetSendIntervalSettings()
+	ch.Assert(err, check.Equals, nil)
+	accounts := map[int64]AccountSendInterval{}
+	for _, a := range settings.Accounts {
+		accounts[a.EmailAccountId] = a
+	}
+	ch.Assert(accounts[paced.Id].IntervalSeconds, check.Equals, float64(300))
+	ch.Assert(accounts[paced.Id].Source, check.Equals, SendIntervalSourceAccount)
+	ch.Assert(accounts[paced.Id].EmailType, check.Equals, "marketing")
+	ch.Assert(accounts[other.Id].IntervalSeconds, check.Equals, float64(30))
+	ch.Assert(accounts[other.Id].Source, check.Equals, SendIntervalSourceEnv)
+}
+
+func (s *ModelsSuite) TestSendIntervalSettingsInvalidEnv(ch *check.C) {
+	defer setSendIntervalEnv("2m", "")()
+	settings, err := GetSendIntervalSettings()
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(settings.IntervalSeconds, check.Equals, DefaultSendInterval.Seconds())
+	ch.Assert(settings.Source, check.Equals, SendIntervalSourceDefault)
+	ch.Assert(settings.EnvValue, check.Equals, "2m")
+	ch.Assert(settings.Warning, check.Not(check.Equals), "")
+}
+
+func (s *ModelsSuite) TestPostCampaignEnforcedSendInterval(ch *check.C) {
+	defer setSendIntervalEnv("60", "true")()
+	c := s.createCampaignDependencies(ch)
+	c.LaunchDate = time.Now().UTC()
+	c.SendByDate = c.LaunchDate.Add(time.Second)
+	err := PostCampaign(&c, c.UserId)
+	ch.Assert(errors.Is(err, ErrSendIntervalTooShort), check.Equals, true)
+}
+
+func (s *ModelsSuite) TestPostCampaignRateLimitWarning(ch *check.C) {
+	defer setSendIntervalEnv("60", "")()
+	c := s.createCampaignDependencies(ch)
+	c.LaunchDate = time.Now().UTC()
+	c.SendByDate = c.LaunchDate.Add(time.Second)
+	strict := c
+	strict.Name = "Strict Campaign"
+	strict.Groups = append([]Group{}, c.Groups...)
+
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	ch.Assert(c.RateLimitWarning, check.NotNil)
+	ch.Assert(c.RateLimitWarning.IsAggressive, check.Equals, true)
+
+	// The campaign can ask to be refused rather than warned
+	strict.EnforceSendInterval = true
+	err := PostCampaign(&strict, strict.UserId)
+	ch.Assert(errors.Is(err, ErrSendIntervalTooShort), check.Equals, true)
+
+	relaxed := strict
+	relaxed.EnforceSendInterval = false
+	relaxed.SendByDate = relaxed.LaunchDate.Add(time.Hour)
+	ch.Assert(PostCampaign(&relaxed, relaxed.UserId), check.Equals, nil)
+	ch.Assert(relaxed.RateLimitWarning, check.IsNil)
+}
+
+func (s *ModelsSuite) TestEmailAccountSendInterval(ch *check.C) {
+	defer setSendIntervalEnv("", "")()
+	ea := EmailAccount{Email: "slow@example.com", EmailType: "marketing", SendIntervalSeconds: -1}
+	ch.Assert(PostEmailAccount(&ea), check.NotNil)
+	ea.SendIntervalSeconds = 10
+	ch.Assert(PostEmailAccount(&ea), check.Equals, nil)
+	ch.Assert(ea.SendInterval(), check.Equals, 10*time.Second)
+	ch.Assert((&EmailAccount{}).SendInterval(), check.Equals, DefaultSendInterval)
+
+	// Campaigns without a send-by date drip at the account's interval
+	c := s.createCampaignDependencies(ch)
+	c.EmailAccount = EmailAccount{Email: ea.Email}
+	c.LaunchDate = time.Now().UTC()
+	fast := c
+	fast.Name = "Fast Campaign"
+	fast.Groups = append([]Group{}, c.Groups...)
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	ch.Assert(c.SendByDate.Sub(c.LaunchDate), check.Equals, 40*time.Second)
+
+	// and send-by dates are only aggressive if they're faster than it
+	fast.SendByDate = fast.LaunchDate.Add(time.Minute)
+	ch.Assert(PostCampaign(&fast, fast.UserId), check.Equals, nil)
+	ch.Assert(fast.RateLimitWarning, check.IsNil)
+	ch.Assert(ValidateCampaignRateLimit(fast.LaunchDate, fast.SendByDate, 4, DefaultSendInterval), check.NotNil)
+}
+
+func (s *ModelsSuite) TestGenerateSendDateAutoDrip(ch *check.C) {
+	defer setSendIntervalEnv("", "")()
+	launch := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	c := Campaign{Id: 1, LaunchDate: launch}
+	for i := 0; i < 10; i++ {
+		ch.Assert(c.generateSendDate(i, 10, DefaultSendInterval), check.Equals, launch.Add(time.Duration(i)*DefaultSendInterval))
+	}
+	// A send-by date equal to the launch date drips the same way
+	c.SendByDate = launch
+	ch.Assert(c.generateSendDate(9, 10, DefaultSendInterval), check.Equals, launch.Add(9*DefaultSendInterval))
+
+	os.Setenv("AUTO_DRIP_CAMPAIGNS", "false")
+	defer os.Unsetenv("AUTO_DRIP_CAMPAIGNS")
+	ch.Assert(c.generateSendDate(9, 10, DefaultSendInterval), check.Equals, launch)
+}
+
+func (s *ModelsSuite) TestLaunchNowCampaignDrips(ch *check.C) {
+	defer setSendIntervalEnv("30", "")()
+	c := s.createCampaignDependencies(ch)
+	group := Group{Name: "Drip Group", UserId: c.UserId}
+	for i := 0; i < 50; i++ {
+		group.Targets = append(group.Targets, Target{BaseRecipient: BaseRecipient{
+			Email: fmt.Sprintf("drip%d@example.com", i),
+		}})
+	}
+	ch.Assert(PostGroup(&group), check.Equals, nil)
+	c.Groups = []Group{group}
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	ch.Assert(c.Status, check.Equals, CampaignInProgress)
+
+	ms := []MailLog{}
+	err := db.Where("campaign_id = ?", c.Id).Order("send_date asc").Find(&ms).Error
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(ms), check.Equals, 50)
+	// Rather than sending to everyone at launch, each email is sent at the
+	// interval after the one before it
+	ch.Assert(ms[0].SendDate.Sub(c.LaunchDate) < time.Second, check.Equals, true)
+	for i := 1; i < len(ms); i++ {
+		gap := ms[i].SendDate.Sub(ms[i-1].SendDate)
+		ch.Assert(gap >= 29*time.Second && gap <= 31*time.Second, check.Equals, true,
+			check.Commentf("gap between email %d and %d is %v", i-1, i, gap))
+	}
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// sendingHaltId is the ID of the single row holding the sending halt
+const sendingHaltId = 1
+
+// ErrSendingHalted is returned when generating an email while all sending is
+// halted
+var ErrSendingHalted = errors.New("Sending is halted")
+
+// SendingHalt is the organization-wide kill switch for sending email. While
+// it's active, no campaign email is sent, pending sends are cancelled and n8n
+// campaigns aren't launched, until an administrator clears it.
+type SendingHalt struct {
+	Id           int64     `json:"-"`
+	Halted       bool      `json:"halted"`
+	Reason       string    `json:"reason"`
+	HaltedBy     string    `json:"halted_by"`
+	HaltedDate   time.Time `json:"halted_date"`
+	ModifiedDate time.Time `json:"modified_date"`
+}
+
+// TableName specifies the database tablename for Gorm to use
+func (h SendingHalt) TableName() string {
+	return "sending_halt"
+}
+
+// HaltReport is the outcome of halting all sending.
+type HaltReport struct {
+	SendingHalt
+	CancelledSends    int `json:"cancelled_sends"`
+	CancelledN8NSends int `json:"cancelled_n8n_sends"`
+	CancelledLaunches int `json:"cancelled_launches"`
+}
+
+// GetSendingHalt returns the state of the kill switch. If it has never been
+// used, sending isn't halted.
+func GetSendingHalt() (SendingHalt, error) {
+	h := SendingHalt{}
+	err := db.Where("id=?", sendingHaltId).First(&h).Error
+	if err == gorm.ErrRecordNotFound {
+		return SendingHalt{}, nil
+	} else if err != nil {
+		log.Error(err)
+		return h, err
+	}
+	return h, nil
+}
+
+// IsSendingHalted returns true if the kill switch is active.
+func IsSendingHalted() (bool, error) {
+	h, err := GetSendingHalt()
+	return h.Halted, err
+}
+
+// HaltSending activates the kill switch on behalf of the given user. Every
+// pending send is cancelled, including those already scheduled by n8n, and
+// campaigns waiting to be launched with n8n are marked as failed, so nothing
+// is sent once sending is resumed either.
+func HaltSending(u User, reason string) (HaltReport, error) {
+	now := time.Now().UTC()
+	h := SendingHalt{
+		Id:           sendingHaltId,
+		Halted:       true,
+		Reason:       reason,
+		HaltedBy:     u.Username,
+		HaltedDate:   now,
+		ModifiedDate: now,
+	}
+	report := HaltReport{SendingHalt: h}
+	err := db.Save(&h).Error
+	if err != nil {
+		log.Error(err)
+		return report, err
+	}
+	log.WithFields(logrus.Fields{
+		"user_id":  u.Id,
+		"username": u.Username,
+		"reason":   reason,
+	}).Warn("All sending halted")
+
+	report.CancelledSends, err = CancelPendingSends()
+	if err != nil {
+		return report, err
+	}
+	report.CancelledN8NSends, err = cancelScheduledN8NResults()
+	if err != nil {
+		return report, err
+	}
+	launches := db.Model(&Campaign{}).Where("launch_status = ?", N8NLaunchPending).
+		Updates(map[string]interface{}{
+			"launch_status": N8NLaunchFailed,
+			"launch_error":  ErrSendingHalted.Error(),
+		})
+	if launches.Error != nil {
+		log.Error(launches.Error)
+		return report, launches.Error
+	}
+	report.CancelledLaunches = int(launches.RowsAffected)
+	log.WithFields(logrus.Fields{
+		"cancelled_sends":     report.CancelledSends,
+		"cancelled_n8n_sends": report.CancelledN8NSends,
+		"cancelled_launches":  report.CancelledLaunches,
+	}).Warn("Cancelled pending sends after halting sending")
+	return report, nil
+}
+
+// ResumeSending clears the kill switch on behalf of the given user. Sends
+// cancelled while sending was halted aren't restored.
+func ResumeSending(u User) (SendingHalt, error) {
+	h, err := GetSendingHalt()
+	if err != nil {
+		return h, err
+	}
+	h.Id = sendingHaltId
+	h.Halted = false
+	h.ModifiedDate = time.Now().UTC()
+	err = db.Save(&h).Error
+	if err != nil {
+		log.Error(err)
+		return h, err
+	}
+	log.WithFields(logrus.Fields{
+		"user_id":  u.Id,
+		"username": u.Username,
+	}).Warn("Sending resumed")
+	return h, nil
+}
+
+// CancelPendingSends cancels every send which the worker hasn't picked up
+// yet, returning the number cancelled. Sends already being processed are
+// stopped when their email is generated.
+func CancelPendingSends() (int, error) {
+	ms := []*MailLog{}
+	err := db.Where("processing = ?", false).Find(&ms).Error
+	if err != nil {
+		log.Error(err)
+		return 0, err
+	}
+	for _, m := range ms {
+		err = m.cancel()
+		if err != nil {
+			log.Error(err)
+			return 0, err
+		}
+	}
+	return len(ms), nil
+}
+
+// cancel removes the maillog and marks its recipient as cancelled, so that
+// any n8n callbacks for them are ignored.
+func (m *MailLog) cancel() error {
+	r, err := GetResult(m.RId)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	if err == nil && cancellableStatuses[r.Status] {
+		event, err := r.createEvent(EventCancelled, nil)
+		if err != nil {
+			return err
+		}
+		r.Status = StatusCancelled
+		r.ModifiedDate = event.Time
+		err = db.Save(&r).Error
+		if err != nil {
+			return err
+		}
+	}
+	return db.Delete(m).Error
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/gophish/gomail"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) haltSending(ch *check.C) HaltReport {
+	admin, err := GetUser(1)
+	ch.Assert(err, check.Equals, nil)
+	report, err := HaltSending(admin, "wrong list")
+	ch.Assert(err, check.Equals, nil)
+	return report
+}
+
+func (s *ModelsSuite) TestHaltSendingCancelsPendingSends(ch *check.C) {
+	c := s.createCampaign(ch)
+	ms, err := GetMailLogsByCampaign(c.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(ms) > 0, check.Equals, true)
+
+	report := s.haltSending(ch)
+	ch.Assert(report.Halted, check.Equals, true)
+	ch.Assert(report.HaltedBy, check.Equals, "admin")
+	ch.Assert(report.Reason, check.Equals, "wrong list")
+	ch.Assert(report.CancelledSends, check.Equals, len(ms))
+
+	halted, err := IsSendingHalted()
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(halted, check.Equals, true)
+
+	ms, err = GetMailLogsByCampaign(c.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(ms), check.Equals, 0)
+	results := []Result{}
+	ch.Assert(db.Where("campaign_id = ?", c.Id).Find(&results).Error, check.Equals, nil)
+	for _, r := range results {
+		ch.Assert(r.Status, check.Equals, StatusCancelled)
+	}
+}
+
+func (s *ModelsSuite) TestHaltedMailLogNotGenerated(ch *check.C) {
+	c := s.createCampaign(ch)
+	ms, err := GetMailLogsByCampaign(c.Id)
+	ch.Assert(err, check.Equals, nil)
+	m := ms[0]
+	// The maillog is being processed, so it's not cancelled by the halt
+	ch.Assert(m.Lock(), check.Equals, nil)
+	s.haltSending(ch)
+
+	err = m.Generate(gomail.NewMessage())
+	ch.Assert(err, check.Equals, ErrSendingHalted)
+	ch.Assert(m.Error(err), check.Equals, nil)
+	r, err := GetResult(m.RId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(r.Status, check.Equals, StatusCancelled)
+	ms, err = GetMailLogsByCampaign(c.Id)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(ms), check.Equals, 0)
+}
+
+func (s *ModelsSuite) TestHaltedN8NLaunchNotAttempted(ch *check.C) {
+	launched := 0
+	defer stubN8NLaunches(func(c *Campaign) error {
+		launched++
+		return nil
+	})()
+	s.haltSending(ch)
+	c := s.createN8NCampaignDependencies(ch)
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+	makeN8NLaunchDue(ch, c.Id)
+	ch.Assert(RetryN8NLaunches(context.Background(), time.Now().UTC()), check.Equals, nil)
+	ch.Assert(launched, check.Equals, 0)
+
+	got, err := GetCampaign(c.Id, c.UserId)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(got.LaunchStatus, check.Equals, N8NLaunchFailed)
+	ch.Assert(got.LaunchError, check.Equals, ErrSendingHalted.Error())
+}
+
+func (s *ModelsSuite) TestResumeSending(ch *check.C) {
+	s.haltSending(ch)
+	admin, err := GetUser(1)
+	ch.Assert(err, check.Equals, nil)
+	h, err := ResumeSending(admin)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(h.Halted, check.Equals, false)
+	halted, err := IsSendingHalted()
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(halted, check.Equals, false)
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// EventRawCaptureEnabled is recorded in a campaign's timeline when it's
+// created with raw submitted data capture enabled.
+const EventRawCaptureEnabled = "Raw Data Capture Enabled"
+
+// RawCaptureAudit is the detail recorded with EventRawCaptureEnabled, naming
+// the user who enabled raw submitted data capture.
+type RawCaptureAudit struct {
+	UserId   int64  `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// SubmittedDetails returns the details of a form submission as they're
+// recorded for the campaign. Unless the campaign was created with raw
+// submitted data capture enabled, only the names of the submitted fields are
+// kept, and their values are redacted.
+func (c *Campaign) SubmittedDetails(d EventDetails) EventDetails {
+	if c.CaptureRawData {
+		return d
+	}
+	redacted := url.Values{}
+	for name := range d.Payload {
+		if name == RecipientParameter {
+			redacted[name] = d.Payload[name]
+			continue
+		}
+		redacted.Set(name, log.Redacted)
+	}
+	d.Payload = redacted
+	return d
+}
+
+// auditRawCapture records who enabled raw submitted data capture for the
+// campaign, both in its timeline and in the logs.
+func (c *Campaign) auditRawCapture(tx *gorm.DB) error {
+	if !c.CaptureRawData {
+		return nil
+	}
+	audit := RawCaptureAudit{UserId: c.UserId}
+	// The user is looked up within the transaction, since the database may
+	// only allow the one connection the transaction is holding
+	u := User{}
+	err := tx.Select("username").Where("id = ?", c.UserId).First(&u).Error
+	if err == nil {
+		audit.Username = u.Username
+	}
+	details, err := json.Marshal(audit)
+	if err != nil {
+		return err
+	}
+	err = tx.Save(&Event{
+		CampaignId: c.Id,
+		Message:    EventRawCaptureEnabled,
+		Time:       time.Now().UTC(),
+		Details:    string(details),
+	}).Error
+	if err != nil {
+		return err
+	}
+	log.WithFields(logrus.Fields{
+		"campaign_id": c.Id,
+		"user_id":     audit.UserId,
+		"username":    audit.Username,
+	}).Warn("Raw submitted data capture enabled for campaign, submitted credentials will be stored unredacted")
+	return nil
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	check "gopkg.in/check.v1"
+)
+
+func submittedForm() EventDetails {
+	return EventDetails{
+		Payload: url.Values{
+			RecipientParameter: {"1234567"},
+			"username":         {"jdoe"},
+			"password":         {"hunter2"},
+		},
+		Browser: map[string]string{"address": "192.0.2.10"},
+	}
+}
+
+func (s *ModelsSuite) TestSubmittedDetailsRedactedByDefault(ch *check.C) {
+	c := Campaign{}
+	d := c.SubmittedDetails(submittedForm())
+	ch.Assert(d.Payload, check.DeepEquals, url.Values{
+		RecipientParameter: {"1234567"},
+		"username":         {log.Redacted},
+		"password":         {log.Redacted},
+	})
+	ch.Assert(d.Browser["address"], check.Equals, "192.0.2.10")
+}
+
+func (s *ModelsSuite) TestSubmittedDetailsRawCapture(ch *check.C) {
+	c := Campaign{CaptureRawData: true}
+	d := c.SubmittedDetails(submittedForm())
+	ch.Assert(d.Payload, check.DeepEquals, submittedForm().Payload)
+}
+
+func (s *ModelsSuite) TestPostCampaignAuditsRawCapture(ch *check.C) {
+	c := s.createCampaignDependencies(ch)
+	c.CaptureRawData = true
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+
+	events := []Event{}
+	ch.Assert(db.Where("campaign_id=? and message=?", c.Id, EventRawCaptureEnabled).Find(&events).Error, check.Equals, nil)
+	ch.Assert(len(events), check.Equals, 1)
+	audit := RawCaptureAudit{}
+	ch.Assert(json.Unmarshal([]byte(events[0].Details), &audit), check.Equals, nil)
+	ch.Assert(audit.UserId, check.Equals, c.UserId)
+	ch.Assert(audit.Username, check.Equals, "admin")
+
+	// Submissions to the campaign are stored as submitted
+	ch.Assert(len(c.Results) > 0, check.Equals, true)
+	r := c.Results[0]
+	ch.Assert(r.HandleFormSubmit(c.SubmittedDetails(submittedForm())), check.Equals, nil)
+	submitted := Event{}
+	ch.Assert(db.Where("campaign_id=? and message=?", c.Id, EventDataSubmit).First(&submitted).Error, check.Equals, nil)
+	d := EventDetails{}
+	ch.Assert(json.Unmarshal([]byte(submitted.Details), &d), check.Equals, nil)
+	ch.Assert(d.Payload.Get("password"), check.Equals, "hunter2")
+}
+
+func (s *ModelsSuite) TestPostCampaignAuditsRawCaptureSingleConnection(ch *check.C) {
+	c := s.createCampaignDependencies(ch)
+	c.CaptureRawData = true
+	db.DB().SetMaxOpenConns(1)
+	defer db.DB().SetMaxOpenConns(0)
+
+	done := make(chan error, 1)
+	go func() { done <- PostCampaign(&c, c.UserId) }()
+	select {
+	case err := <-done:
+		ch.Assert(err, check.Equals, nil)
+	case <-time.After(10 * time.Second):
+		ch.Fatalf("timed out creating a campaign with a single database connection")
+	}
+	events := []Event{}
+	ch.Assert(db.Where("campaign_id=? and message=?", c.Id, EventRawCaptureEnabled).Find(&events).Error, check.Equals, nil)
+	ch.Assert(len(events), check.Equals, 1)
+	ch.Assert(strings.Contains(events[0].Details, `"admin"`), check.Equals, true)
+}
+
+func (s *ModelsSuite) TestPostCampaignRedactsWithoutRawCapture(ch *check.C) {
+	c := s.createCampaignDependencies(ch)
+	ch.Assert(PostCampaign(&c, c.UserId), check.Equals, nil)
+
+	var count int
+	ch.Assert(db.Model(&Event{}).Where("campaign_id=? and message=?", c.Id, EventRawCaptureEnabled).Count(&count).Error, check.Equals, nil)
+	ch.Assert(count, check.Equals, 0)
+
+	r := c.Results[0]
+	ch.Assert(r.HandleFormSubmit(c.SubmittedDetails(submittedForm())), check.Equals, nil)
+	submitted := Event{}
+	ch.Assert(db.Where("campaign_id=? and message=?", c.Id, EventDataSubmit).First(&submitted).Error, check.Equals, nil)
+	d := EventDetails{}
+	ch.Assert(json.Unmarshal([]byte(submitted.Details), &d), check.Equals, nil)
+	ch.Assert(d.Payload.Get("username"), check.Equals, log.Redacted)
+	ch.Assert(d.Payload.Get("password"), check.Equals, log.Redacted)
+}
@@ -16,6 +16,7 @@ type Template struct {
 	Name           string       `json:"name"`
 	EnvelopeSender string       `json:"envelope_sender"`
 	Subject        string       `json:"subject"`
+	Preheader      string       `json:"preheader"`
 	Text           string       `json:"text"`
 	HTML           string       `json:"html" gorm:"column:html"`
 	ModifiedDate   time.Time    `json:"modified_date"`
@@ -41,12 +42,24 @@ func (t *Template) Validate() error {
 			return err
 		}
 	}
+	if err := validateName("template", t.Name); err != nil {
+		return err
+	}
+	if err := validateContentSize(t.HTML, t.Text); err != nil {
+		return err
+	}
+	if err := ValidateTemplate(t.Subject); err != nil {
+		return err
+	}
 	if err := ValidateTemplate(t.HTML); err != nil {
 		return err
 	}
 	if err := ValidateTemplate(t.Text); err != nil {
 		return err
 	}
+	if err := validatePreheader(t.Preheader); err != nil {
+		return err
+	}
 	for _, a := range t.Attachments {
 		if err := a.Validate(); err != nil {
 			return err
@@ -5,6 +5,7 @@ import (
 	"net/mail"
 	"net/url"
 	"path"
+	"strings"
 	"text/template"
 )
 
@@ -30,6 +31,9 @@ type PhishingTemplateContext struct {
 // NewPhishingTemplateContext returns a populated PhishingTemplateContext,
 // parsing the correct fields from the provided TemplateContext and recipient.
 func NewPhishingTemplateContext(ctx TemplateContext, r BaseRecipient, rid string) (PhishingTemplateContext, error) {
+	// Recipient data may come from untrusted imports, so make sure it can't
+	// smuggle template actions into the rendered content.
+	r.Normalize()
 	f, err := mail.ParseAddress(ctx.getFromAddress())
 	if err != nil {
 		return PhishingTemplateContext{}, err
@@ -55,6 +59,7 @@ func NewPhishingTemplateContext(ctx TemplateContext, r BaseRecipient, rid string
 	phishURL, _ := url.Parse(templateURL)
 	q := phishURL.Query()
 	q.Set(RecipientParameter, rid)
+	addTrackingParams(q, ctx)
 	phishURL.RawQuery = q.Encode()
 
 	trackingURL, _ := url.Parse(templateURL)
@@ -72,11 +77,43 @@ func NewPhishingTemplateContext(ctx TemplateContext, r BaseRecipient, rid string
 	}, nil
 }
 
+// templateFuncs are the functions available to every template, in addition
+// to the text/template builtins.
+var templateFuncs = template.FuncMap{
+	"fallback": fallback,
+}
+
+// fallback returns value, or def if value is blank, so that templates can
+// degrade gracefully when recipient data is missing. For example,
+// {{fallback "there" .FirstName}} renders "there" for a recipient with no
+// first name.
+func fallback(def string, value string) string {
+	if strings.TrimSpace(value) == "" {
+		return def
+	}
+	return value
+}
+
+// FirstNameOr returns the recipient's first name, or def if it is blank.
+func (r BaseRecipient) FirstNameOr(def string) string {
+	return fallback(def, r.FirstName)
+}
+
+// LastNameOr returns the recipient's last name, or def if it is blank.
+func (r BaseRecipient) LastNameOr(def string) string {
+	return fallback(def, r.LastName)
+}
+
+// PositionOr returns the recipient's position, or def if it is blank.
+func (r BaseRecipient) PositionOr(def string) string {
+	return fallback(def, r.Position)
+}
+
 // ExecuteTemplate creates a templated string based on the provided
 // template body and data.
 func ExecuteTemplate(text string, data interface{}) (string, error) {
 	buff := bytes.Buffer{}
-	tmpl, err := template.New("template").Parse(text)
+	tmpl, err := parseTemplate(text)
 	if err != nil {
 		return buff.String(), err
 	}
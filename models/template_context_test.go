@@ -45,3 +45,35 @@ func (s *ModelsSuite) TestNewTemplateContext(c *check.C) {
 	c.Assert(err, check.Equals, nil)
 	c.Assert(got, check.DeepEquals, expected)
 }
+
+func (s *ModelsSuite) TestTemplateFallbacks(c *check.C) {
+	ctx := mockTemplateContext{
+		URL:         "http://example.com",
+		FromAddress: "From Address <from@example.com>",
+	}
+	subject := `{{.FirstNameOr "there"}}, action required`
+	body := `Hi {{fallback "colleague" .FirstName}} {{.LastNameOr ""}}, as {{.PositionOr "a member of staff"}}`
+
+	r := BaseRecipient{FirstName: "Foo", LastName: "Bar", Position: "Engineer", Email: "foo@bar.com"}
+	ptx, err := NewPhishingTemplateContext(ctx, r, "1234567")
+	c.Assert(err, check.Equals, nil)
+	got, err := ExecuteTemplate(subject, ptx)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(got, check.Equals, "Foo, action required")
+	got, err = ExecuteTemplate(body, ptx.HTMLEscaped())
+	c.Assert(err, check.Equals, nil)
+	c.Assert(got, check.Equals, "Hi Foo Bar, as Engineer")
+
+	r = BaseRecipient{FirstName: " ", Email: "foo@bar.com"}
+	ptx, err = NewPhishingTemplateContext(ctx, r, "1234567")
+	c.Assert(err, check.Equals, nil)
+	got, err = ExecuteTemplate(subject, ptx)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(got, check.Equals, "there, action required")
+	got, err = ExecuteTemplate(body, ptx.HTMLEscaped())
+	c.Assert(err, check.Equals, nil)
+	c.Assert(got, check.Equals, "Hi colleague , as a member of staff")
+
+	c.Assert(ValidateTemplate(subject), check.Equals, nil)
+	c.Assert(ValidateTemplate(body), check.Equals, nil)
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"text/template"
+	"text/template/parse"
+)
+
+// ErrTemplateFuncNotAllowed is thrown when a template calls a function which
+// isn't on the allow-list
+var ErrTemplateFuncNotAllowed = errors.New("Template function not allowed")
+
+// builtinTemplateFuncs are the functions text/template makes available to
+// every template.
+var builtinTemplateFuncs = []string{
+	"and", "call", "html", "index", "js", "len", "not", "or", "print",
+	"printf", "println", "slice", "urlquery",
+	"eq", "ge", "gt", "le", "lt", "ne",
+}
+
+// disallowedTemplateFuncs are functions which aren't allowed unless they're
+// explicitly listed in template_functions. call runs any function value it's
+// given, which would let templates reach past the helpers they're meant to
+// have.
+var disallowedTemplateFuncs = map[string]bool{
+	"call": true,
+}
+
+// DefaultTemplateFuncs returns the functions templates may call by default:
+// the text/template builtins other than call, and Gophish's own helpers.
+func DefaultTemplateFuncs() []string {
+	funcs := []string{}
+	for _, name := range builtinTemplateFuncs {
+		if !disallowedTemplateFuncs[name] {
+			funcs = append(funcs, name)
+		}
+	}
+	for name := range templateFuncs {
+		funcs = append(funcs, name)
+	}
+	sort.Strings(funcs)
+	return funcs
+}
+
+// knownTemplateFunc returns true if the function is a builtin or one of
+// Gophish's helpers.
+func knownTemplateFunc(name string) bool {
+	if _, ok := templateFuncs[name]; ok {
+		return true
+	}
+	for _, builtin := range builtinTemplateFuncs {
+		if builtin == name {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedTemplateFuncs returns the functions templates may call. If
+// template_functions is configured, only the functions it lists are
+// allowed, otherwise the defaults are. Functions which don't exist are
+// ignored.
+func allowedTemplateFuncs() map[string]bool {
+	funcs := DefaultTemplateFuncs()
+	if conf != nil && len(conf.TemplateFunctions) > 0 {
+		funcs = conf.TemplateFunctions
+	}
+	allowed := make(map[string]bool, len(funcs))
+	for _, name := range funcs {
+		if knownTemplateFunc(name) {
+			allowed[name] = true
+		}
+	}
+	return allowed
+}
+
+// parseTemplate parses the template text with Gophish's helpers available,
+// rejecting templates which call a function that isn't allowed. Functions
+// which don't exist at all are rejected by the parser.
+func parseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("template").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	allowed := allowedTemplateFuncs()
+	// Templates defined with {{define}} are checked as well
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		if name := disallowedTemplateFunc(t.Tree.Root, allowed); name != "" {
+			return nil, fmt.Errorf("%w: %s", ErrTemplateFuncNotAllowed, name)
+		}
+	}
+	return tmpl, nil
+}
+
+// disallowedTemplateFunc returns the name of the first function called under
+// the node which isn't allowed, or an empty string if they all are.
+func disallowedTemplateFunc(n parse.Node, allowed map[string]bool) string {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return ""
+		}
+		for _, c := range n.Nodes {
+			if name := disallowedTemplateFunc(c, allowed); name != "" {
+				return name
+			}
+		}
+	case *parse.ActionNode:
+		return disallowedTemplateFunc(n.Pipe, allowed)
+	case *parse.PipeNode:
+		if n == nil {
+			return ""
+		}
+		for _, cmd := range n.Cmds {
+			if name := disallowedTemplateFunc(cmd, allowed); name != "" {
+				return name
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if name := disallowedTemplateFunc(arg, allowed); name != "" {
+				return name
+			}
+		}
+	case *parse.ChainNode:
+		return disallowedTemplateFunc(n.Node, allowed)
+	case *parse.IdentifierNode:
+		if !allowed[n.Ident] {
+			return n.Ident
+		}
+	case *parse.IfNode:
+		return disallowedBranchFunc(&n.BranchNode, allowed)
+	case *parse.RangeNode:
+		return disallowedBranchFunc(&n.BranchNode, allowed)
+	case *parse.WithNode:
+		return disallowedBranchFunc(&n.BranchNode, allowed)
+	case *parse.TemplateNode:
+		return disallowedTemplateFunc(n.Pipe, allowed)
+	}
+	return ""
+}
+
+// disallowedBranchFunc returns the first function called in the branch which
+// isn't allowed.
+func disallowedBranchFunc(n *parse.BranchNode, allowed map[string]bool) string {
+	for _, c := range []parse.Node{n.Pipe, n.List, n.ElseList} {
+		if name := disallowedTemplateFunc(c, allowed); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// AllowedTemplateFuncs returns the names of the functions templates may
+// call.
+func AllowedTemplateFuncs() []string {
+	funcs := []string{}
+	for name := range allowedTemplateFuncs() {
+		funcs = append(funcs, name)
+	}
+	sort.Strings(funcs)
+	return funcs
+}
+
+// TemplateContentError is a part of a template which isn't a valid template.
+type TemplateContentError struct {
+	Part  string `json:"part"`
+	Error string `json:"error"`
+}
+
+// ValidateContent checks that each part of the template is a valid template
+// which only calls allowed functions, returning the parts which aren't.
+func (t *Template) ValidateContent() []TemplateContentError {
+	errs := []TemplateContentError{}
+	check := func(part string, err error) {
+		if err != nil {
+			errs = append(errs, TemplateContentError{Part: part, Error: err.Error()})
+		}
+	}
+	check("subject", ValidateTemplate(t.Subject))
+	check("preheader", validatePreheader(t.Preheader))
+	check("text", ValidateTemplate(t.Text))
+	check("html", ValidateTemplate(t.HTML))
+	return errs
+}
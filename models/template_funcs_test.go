@@ -0,0 +1,52 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/gophish/gophish/config"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestTemplateFuncsAllowed(ch *check.C) {
+	for _, text := range []string{
+		`Hi {{fallback "there" .FirstName}}`,
+		`{{if eq .Position "CEO"}}Dear {{.LastName}}{{else}}Hi {{.FirstName}}{{end}}`,
+		`{{printf "%s %s" .FirstName .LastName | html}}`,
+		`{{with .FirstName}}{{len .}}{{end}}`,
+	} {
+		ch.Assert(ValidateTemplate(text), check.Equals, nil, check.Commentf(text))
+	}
+}
+
+func (s *ModelsSuite) TestTemplateFuncsDisallowed(ch *check.C) {
+	// call isn't allowed by default, wherever it appears
+	for _, text := range []string{
+		`{{call .From}}`,
+		`{{if true}}{{else}}{{call .From}}{{end}}`,
+		`{{printf "%s" (call .From)}}`,
+		`{{define "inner"}}{{call .From}}{{end}}{{template "inner" .}}`,
+	} {
+		err := ValidateTemplate(text)
+		ch.Assert(errors.Is(err, ErrTemplateFuncNotAllowed), check.Equals, true, check.Commentf(text))
+	}
+
+	// Functions which don't exist are rejected when the template is parsed
+	ch.Assert(ValidateTemplate(`{{exec "ls"}}`), check.NotNil)
+
+	t := Template{Name: "Disallowed", HTML: "<p>Body</p>", Subject: `{{call .From}}`}
+	ch.Assert(errors.Is(t.Validate(), ErrTemplateFuncNotAllowed), check.Equals, true)
+	errs := t.ValidateContent()
+	ch.Assert(len(errs), check.Equals, 1)
+	ch.Assert(errs[0].Part, check.Equals, "subject")
+}
+
+func (s *ModelsSuite) TestTemplateFuncsConfigured(ch *check.C) {
+	orig := conf
+	defer func() { conf = orig }()
+	conf = &config.Config{TemplateFunctions: []string{"fallback", "exec"}}
+
+	ch.Assert(AllowedTemplateFuncs(), check.DeepEquals, []string{"fallback"})
+	ch.Assert(ValidateTemplate(`Hi {{fallback "there" .FirstName}}`), check.Equals, nil)
+	err := ValidateTemplate(`{{printf "%s" .FirstName}}`)
+	ch.Assert(errors.Is(err, ErrTemplateFuncNotAllowed), check.Equals, true)
+}
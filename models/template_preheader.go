@@ -0,0 +1,83 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxPreheaderLength is the most characters a template's preheader may have.
+// Email clients only show the first hundred or so.
+const MaxPreheaderLength = 255
+
+// ErrPreheaderTooLong is thrown when a template's preheader is longer than
+// MaxPreheaderLength
+var ErrPreheaderTooLong = fmt.Errorf("Preheader must be at most %d characters", MaxPreheaderLength)
+
+// ErrPreheaderMultiline is thrown when a template's preheader spans more than
+// one line
+var ErrPreheaderMultiline = errors.New("Preheader must be a single line")
+
+// preheaderStyle hides the preheader in the body of the email while leaving
+// it as the first text email clients find for the preview.
+const preheaderStyle = "display:none;font-size:1px;line-height:1px;max-height:0px;max-width:0px;opacity:0;overflow:hidden;mso-hide:all;"
+
+// bodyTag matches the opening body tag of an HTML document.
+var bodyTag = regexp.MustCompile(`(?i)<body[^>]*>`)
+
+// templateAction matches the actions of a template, such as {{.FirstName}}.
+var templateAction = regexp.MustCompile(`{{.*?}}`)
+
+// validatePreheader checks the preheader's length and that it's a valid
+// template.
+func validatePreheader(preheader string) error {
+	if utf8.RuneCountInString(preheader) > MaxPreheaderLength {
+		return ErrPreheaderTooLong
+	}
+	if strings.ContainsAny(preheader, "\r\n") {
+		return ErrPreheaderMultiline
+	}
+	return ValidateTemplate(preheader)
+}
+
+// renderPreheader renders the template's preheader for the recipient, escaped
+// for use in HTML.
+func renderPreheader(t Template, ptx PhishingTemplateContext) (string, error) {
+	if t.Preheader == "" {
+		return "", nil
+	}
+	preheader, err := ExecuteTemplate(t.Preheader, ptx)
+	if err != nil {
+		return "", err
+	}
+	return html.EscapeString(preheader), nil
+}
+
+// escapePreheaderTemplate escapes the text of a preheader template for use in
+// HTML, leaving its actions for n8n to render. This matches the escaping of
+// preheaders which are rendered by renderPreheader.
+func escapePreheaderTemplate(preheader string) string {
+	escaped := ""
+	last := 0
+	for _, loc := range templateAction.FindAllStringIndex(preheader, -1) {
+		escaped += html.EscapeString(preheader[last:loc[0]]) + preheader[loc[0]:loc[1]]
+		last = loc[1]
+	}
+	return escaped + html.EscapeString(preheader[last:])
+}
+
+// injectPreheader adds the preheader as hidden text at the top of the HTML
+// body, so that email clients show it as the preview text.
+func injectPreheader(body string, preheader string) string {
+	if preheader == "" {
+		return body
+	}
+	hidden := fmt.Sprintf(`<div style="%s">%s</div>`, preheaderStyle, preheader)
+	if loc := bodyTag.FindStringIndex(body); loc != nil {
+		return body[:loc[1]] + hidden + body[loc[1]:]
+	}
+	return hidden + body
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestMailLogGeneratePreheader(ch *check.C) {
+	campaign := s.createCampaignDependencies(ch)
+	err := db.Model(&Template{}).Where("name = ?", campaign.Template.Name).
+		Update("preheader", "Hi {{.FirstName}}, your <invoice> is ready").Error
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(PostCampaign(&campaign, campaign.UserId), check.Equals, nil)
+	result := campaign.Results[0]
+
+	got := s.emailFromFirstMailLog(campaign, ch)
+	html := string(got.HTML)
+	preheader := fmt.Sprintf("Hi %s, your &lt;invoice&gt; is ready", result.FirstName)
+	hidden := fmt.Sprintf(`<div style="%s">%s</div>`, preheaderStyle, preheader)
+	ch.Assert(strings.HasPrefix(html, hidden), check.Equals, true)
+	ch.Assert(strings.Contains(html, "display:none"), check.Equals, true)
+	ch.Assert(strings.HasSuffix(html, fmt.Sprintf("%s - HTML", result.RId)), check.Equals, true)
+	// The plaintext part is left alone
+	ch.Assert(string(got.Text), check.Equals, fmt.Sprintf("%s - Text", result.RId))
+}
+
+func (s *ModelsSuite) TestInjectPreheader(ch *check.C) {
+	hidden := fmt.Sprintf(`<div style="%s">Preview</div>`, preheaderStyle)
+	ch.Assert(injectPreheader("<p>Body</p>", "Preview"), check.Equals, hidden+"<p>Body</p>")
+	ch.Assert(injectPreheader(`<html><BODY class="x"><p>Body</p></BODY></html>`, "Preview"), check.Equals,
+		`<html><BODY class="x">`+hidden+`<p>Body</p></BODY></html>`)
+	ch.Assert(injectPreheader("<p>Body</p>", ""), check.Equals, "<p>Body</p>")
+}
+
+func (s *ModelsSuite) TestEscapePreheaderTemplate(ch *check.C) {
+	ch.Assert(escapePreheaderTemplate("Hi {{.FirstName}}, your <invoice> & receipt"), check.Equals,
+		"Hi {{.FirstName}}, your &lt;invoice&gt; &amp; receipt")
+	ch.Assert(escapePreheaderTemplate(`{{if eq .Position "R&D"}}Lab{{end}} <b>`), check.Equals,
+		`{{if eq .Position "R&D"}}Lab{{end}} &lt;b&gt;`)
+	ch.Assert(escapePreheaderTemplate(""), check.Equals, "")
+}
+
+func (s *ModelsSuite) TestN8NPayloadEscapesPreheader(ch *check.C) {
+	received := make(chan N8NWebhookPayload, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := N8NWebhookPayload{}
+		ch.Assert(json.NewDecoder(r.Body).Decode(&payload), check.Equals, nil)
+		received <- payload
+	}))
+	defer ts.Close()
+
+	campaign := newVariantCampaign()
+	campaign.Template.Preheader = "Hi {{.FirstName}}, your <invoice> is ready"
+	campaign.TemplateVariants[0].Template.Preheader = "R&D {{.Position}}"
+	campaign.Results = []Result{
+		{RId: "abc123", BaseRecipient: BaseRecipient{Email: "sean@example.com"}},
+		{RId: "def456", BaseRecipient: BaseRecipient{Email: "jane@example.com", Position: "Finance"}},
+	}
+	sender := &N8NSender{
+		webhookURL: ts.URL,
+		jwtSecret:  "secret",
+		emailType:  "test",
+		campaign:   campaign,
+		client:     ts.Client(),
+	}
+	err := sender.Send("from@example.com", []string{"sean@example.com", "jane@example.com"}, &mockWriterTo{campaign: campaign})
+	ch.Assert(err, check.Equals, nil)
+	payload := <-received
+	hidden := fmt.Sprintf(`<div style="%s">Hi {{.FirstName}}, your &lt;invoice&gt; is ready</div>`, preheaderStyle)
+	ch.Assert(strings.HasPrefix(payload.Message, hidden), check.Equals, true)
+	hidden = fmt.Sprintf(`<div style="%s">R&amp;D {{.Position}}</div>`, preheaderStyle)
+	ch.Assert(strings.HasPrefix(payload.Recipients[1].Message, hidden), check.Equals, true)
+	// The preheader fields are left raw, as they may be used outside HTML
+	ch.Assert(payload.Preheader, check.Equals, campaign.Template.Preheader)
+	ch.Assert(payload.Recipients[1].Preheader, check.Equals, "R&D {{.Position}}")
+}
+
+func (s *ModelsSuite) TestTemplatePreheaderValidation(ch *check.C) {
+	t := Template{Name: "Preheader", HTML: "<p>Body</p>"}
+	t.Preheader = strings.Repeat("a", MaxPreheaderLength)
+	ch.Assert(t.Validate(), check.Equals, nil)
+	t.Preheader = strings.Repeat("a", MaxPreheaderLength+1)
+	ch.Assert(t.Validate(), check.Equals, ErrPreheaderTooLong)
+	t.Preheader = "First line\nSecond line"
+	ch.Assert(t.Validate(), check.Equals, ErrPreheaderMultiline)
+	t.Preheader = "Hi {{.FirstName"
+	ch.Assert(t.Validate(), check.NotNil)
+}
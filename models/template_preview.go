@@ -0,0 +1,166 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// The ways a template can be previewed. Faithful previews are the HTML as
+// it's sent, while annotated previews mark where tracking was injected.
+const (
+	PreviewFaithful  = "faithful"
+	PreviewAnnotated = "annotated"
+)
+
+// The markers wrapped around links and images in an annotated preview, given
+// in the data-gophish-preview attribute of the marker span.
+const (
+	PreviewMarkerTrackedLink   = "tracked-link"
+	PreviewMarkerUntrackedLink = "untracked-link"
+	PreviewMarkerPixel         = "tracking-pixel"
+)
+
+// previewMarkerStyles highlight each kind of marker in an annotated preview.
+var previewMarkerStyles = map[string]string{
+	PreviewMarkerTrackedLink:   "outline: 2px solid #2e7d32;",
+	PreviewMarkerUntrackedLink: "outline: 2px dashed #c62828;",
+	PreviewMarkerPixel:         "display: inline-block; width: 8px; height: 8px; background: #1565c0;",
+}
+
+// previewRId is the recipient ID templates are previewed with.
+const previewRId = "123456"
+
+// ErrInvalidPreviewMode is thrown when a template preview is requested in a
+// mode which isn't supported
+var ErrInvalidPreviewMode = errors.New("Preview must be \"faithful\" or \"annotated\"")
+
+// TemplatePreview is a template rendered for one recipient. In an annotated
+// preview, UntrackedLinks are the web links in the HTML which don't identify
+// the recipient, so clicks on them won't be recorded.
+type TemplatePreview struct {
+	Email          string   `json:"email"`
+	Mode           string   `json:"mode"`
+	Subject        string   `json:"subject"`
+	Text           string   `json:"text"`
+	HTML           string   `json:"html"`
+	UntrackedLinks []string `json:"untracked_links,omitempty"`
+}
+
+// ValidatePreviewMode returns an error if the preview mode isn't supported.
+func ValidatePreviewMode(mode string) error {
+	if mode != PreviewFaithful && mode != PreviewAnnotated {
+		return ErrInvalidPreviewMode
+	}
+	return nil
+}
+
+// previewTemplate renders the template for the recipient in the given mode.
+func previewTemplate(t Template, r BaseRecipient, mode string) (TemplatePreview, error) {
+	if err := ValidatePreviewMode(mode); err != nil {
+		return TemplatePreview{}, err
+	}
+	vc := ValidationContext{
+		FromAddress: "foo@bar.com",
+		BaseURL:     "http://example.com",
+	}
+	ptx, err := NewPhishingTemplateContext(vc, r, previewRId)
+	if err != nil {
+		return TemplatePreview{}, err
+	}
+	p := TemplatePreview{Email: r.Email, Mode: mode}
+	p.Subject, err = ExecuteTemplate(t.Subject, ptx)
+	if err != nil {
+		return p, err
+	}
+	p.Text, err = ExecuteTemplate(t.Text, ptx)
+	if err != nil {
+		return p, err
+	}
+	p.HTML, err = ExecuteTemplate(t.HTML, ptx.HTMLEscaped())
+	if err != nil {
+		return p, err
+	}
+	// The preheader is hidden at the top of the HTML, as it is when sent
+	if t.HTML != "" {
+		preheader, err := renderPreheader(t, ptx)
+		if err != nil {
+			return p, err
+		}
+		p.HTML = injectPreheader(p.HTML, preheader)
+	}
+	if mode == PreviewAnnotated && p.HTML != "" {
+		p.HTML, p.UntrackedLinks, err = annotateTracking(p.HTML, ptx.RId)
+	}
+	return p, err
+}
+
+// annotateTracking wraps the links and tracking pixel in the rendered HTML in
+// marker spans, so that they're highlighted when it's shown. Links carrying
+// the recipient's rid are tracked, while other web links are untracked and
+// returned.
+func annotateTracking(html string, rid string) (string, []string, error) {
+	d, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return html, nil, err
+	}
+	untracked := []string{}
+	d.Find("a[href]").Each(func(i int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		u, err := url.Parse(strings.TrimSpace(href))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		if u.Query().Get(RecipientParameter) == rid {
+			wrapPreviewMarker(a, PreviewMarkerTrackedLink)
+			return
+		}
+		untracked = append(untracked, href)
+		wrapPreviewMarker(a, PreviewMarkerUntrackedLink)
+	})
+	d.Find("img[src]").Each(func(i int, img *goquery.Selection) {
+		src, _ := img.Attr("src")
+		u, err := url.Parse(strings.TrimSpace(src))
+		if err != nil || path.Base(u.Path) != "track" || u.Query().Get(RecipientParameter) != rid {
+			return
+		}
+		wrapPreviewMarker(img, PreviewMarkerPixel)
+	})
+	annotated, err := d.Html()
+	if err != nil {
+		return html, nil, err
+	}
+	return annotated, untracked, nil
+}
+
+// wrapPreviewMarker wraps the selection in a span marking it as the given
+// kind of marker.
+func wrapPreviewMarker(s *goquery.Selection, marker string) {
+	s.WrapHtml(fmt.Sprintf(`<span class="gophish-preview-marker" data-gophish-preview="%s" style="%s"></span>`,
+		marker, previewMarkerStyles[marker]))
+}
+
+// PreviewTemplateRender renders the named template for the first target in
+// the named group in the given preview mode. Nothing is sent.
+func PreviewTemplateRender(templateName string, groupName string, uid int64, mode string) (TemplatePreview, error) {
+	if err := ValidatePreviewMode(mode); err != nil {
+		return TemplatePreview{}, err
+	}
+	t, err := GetTemplateByName(templateName, uid)
+	if err != nil {
+		return TemplatePreview{}, err
+	}
+	g, err := GetGroupByName(groupName, uid)
+	if err != nil {
+		return TemplatePreview{}, err
+	}
+	r := BaseRecipient{Email: "user@example.com"}
+	if len(g.Targets) > 0 {
+		r = g.Targets[0].BaseRecipient
+	}
+	return previewTemplate(t, r, mode)
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	check "gopkg.in/check.v1"
+)
+
+func previewTestTemplate() Template {
+	return Template{
+		Name:    "Preview",
+		Subject: "Hello {{.FirstName}}",
+		Text:    "Visit {{.URL}}",
+		HTML:    `<p><a href="{{.URL}}">Sign in</a> or <a href="https://example.org/help">get help</a></p>{{.Tracker}}`,
+	}
+}
+
+func (s *ModelsSuite) TestPreviewTemplateAnnotated(ch *check.C) {
+	r := BaseRecipient{Email: "john@example.com", FirstName: "John"}
+	p, err := previewTemplate(previewTestTemplate(), r, PreviewAnnotated)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(p.Subject, check.Equals, "Hello John")
+
+	trackedURL := "http://example.com?rid=" + previewRId
+	tracked := `<span class="gophish-preview-marker" data-gophish-preview="tracked-link" style="` +
+		previewMarkerStyles[PreviewMarkerTrackedLink] + `"><a href="` + trackedURL + `">Sign in</a></span>`
+	ch.Assert(strings.Contains(p.HTML, tracked), check.Equals, true)
+	ch.Assert(strings.Contains(p.HTML, `data-gophish-preview="untracked-link"`), check.Equals, true)
+	ch.Assert(strings.Contains(p.HTML, `data-gophish-preview="tracking-pixel"`), check.Equals, true)
+	ch.Assert(p.UntrackedLinks, check.DeepEquals, []string{"https://example.org/help"})
+}
+
+func (s *ModelsSuite) TestPreviewTemplateFaithful(ch *check.C) {
+	r := BaseRecipient{Email: "john@example.com", FirstName: "John"}
+	p, err := previewTemplate(previewTestTemplate(), r, PreviewFaithful)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(strings.Contains(p.HTML, "gophish-preview-marker"), check.Equals, false)
+	ch.Assert(strings.HasPrefix(p.HTML, `<p><a href="http://example.com?rid=`+previewRId+`">`), check.Equals, true)
+	ch.Assert(len(p.UntrackedLinks), check.Equals, 0)
+
+	// The preheader is included as it is when sent
+	t := previewTestTemplate()
+	t.Preheader = "Hi {{.FirstName}}, <action> needed"
+	p, err = previewTemplate(t, r, PreviewFaithful)
+	ch.Assert(err, check.Equals, nil)
+	hidden := fmt.Sprintf(`<div style="%s">Hi John, &lt;action&gt; needed</div>`, preheaderStyle)
+	ch.Assert(strings.HasPrefix(p.HTML, hidden+`<p><a href="http://example.com?rid=`+previewRId+`">`), check.Equals, true)
+
+	_, err = previewTemplate(previewTestTemplate(), r, "highlighted")
+	ch.Assert(err, check.Equals, ErrInvalidPreviewMode)
+}
@@ -0,0 +1,252 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"text/template/parse"
+)
+
+// recipientTemplateFields maps the recipient fields available to templates to
+// the names they're imported under.
+var recipientTemplateFields = map[string]string{
+	"Email":     "email",
+	"FirstName": "first_name",
+	"LastName":  "last_name",
+	"Position":  "position",
+}
+
+// recipientFieldOrder is the order recipient fields are looked for in a
+// template error.
+var recipientFieldOrder = []string{"Email", "FirstName", "LastName", "Position"}
+
+// templateErrorAction matches the action a template failed to execute, as
+// given in the error from text/template.
+var templateErrorAction = regexp.MustCompile(`at <([^>]*)>`)
+
+// RenderIssue is a problem rendering part of a template for a recipient.
+// Field is the recipient field involved, if it's known.
+type RenderIssue struct {
+	Email string `json:"email"`
+	Part  string `json:"part"`
+	Field string `json:"field,omitempty"`
+	Error string `json:"error"`
+}
+
+// RenderCheck is the outcome of rendering a template for every target in a
+// group.
+type RenderCheck struct {
+	Template string `json:"template"`
+	Group    string `json:"group"`
+	Total    int    `json:"total"`
+	Failed   int    `json:"failed"`
+	// Errors are parts of the template which failed to render
+	Errors []RenderIssue `json:"errors"`
+	// Missing are recipient fields which are shown without a fallback but
+	// are blank, so they render as nothing
+	Missing []RenderIssue `json:"missing"`
+	// Preview is the template rendered for the group's first target, if a
+	// preview was requested and it rendered
+	Preview *TemplatePreview `json:"preview,omitempty"`
+}
+
+// CheckTemplateRender renders the named template for every target in the
+// named group, reporting the targets it fails to render for. Nothing is sent.
+func CheckTemplateRender(templateName string, groupName string, uid int64) (RenderCheck, error) {
+	t, err := GetTemplateByName(templateName, uid)
+	if err != nil {
+		return RenderCheck{}, err
+	}
+	g, err := GetGroupByName(groupName, uid)
+	if err != nil {
+		return RenderCheck{}, err
+	}
+	rc := checkTemplateRender(t, g.Targets)
+	rc.Group = g.Name
+	return rc, nil
+}
+
+// templatePart is a part of a template which is rendered for each recipient.
+type templatePart struct {
+	name   string
+	render func(ptx PhishingTemplateContext) error
+	// shown are the recipient fields the part shows without a fallback
+	shown []string
+}
+
+// checkTemplateRender renders the template for each of the targets.
+func checkTemplateRender(t Template, targets []Target) RenderCheck {
+	rc := RenderCheck{
+		Template: t.Name,
+		Total:    len(targets),
+		Errors:   []RenderIssue{},
+		Missing:  []RenderIssue{},
+	}
+	parts := []templatePart{}
+	addText := func(name string, text string, escape bool) {
+		if text == "" {
+			return
+		}
+		parts = append(parts, templatePart{
+			name: name,
+			render: func(ptx PhishingTemplateContext) error {
+				if escape {
+					ptx = ptx.HTMLEscaped()
+				}
+				_, err := ExecuteTemplate(text, ptx)
+				return err
+			},
+			shown: shownRecipientFields(text),
+		})
+	}
+	addText("subject", t.Subject, false)
+	addText("text", t.Text, false)
+	addText("html", t.HTML, true)
+	for i := range t.Attachments {
+		a := t.Attachments[i]
+		parts = append(parts, templatePart{
+			name: a.Name,
+			render: func(ptx PhishingTemplateContext) error {
+				_, err := a.ApplyTemplate(ptx)
+				return err
+			},
+		})
+	}
+
+	vc := ValidationContext{
+		FromAddress: "foo@bar.com",
+		BaseURL:     "http://example.com",
+	}
+	for _, target := range targets {
+		failed := false
+		ptx, err := NewPhishingTemplateContext(vc, target.BaseRecipient, "123456")
+		if err != nil {
+			rc.Errors = append(rc.Errors, RenderIssue{Email: target.Email, Part: "url", Error: err.Error()})
+			rc.Failed++
+			continue
+		}
+		for _, p := range parts {
+			if err := p.render(ptx); err != nil {
+				rc.Errors = append(rc.Errors, RenderIssue{
+					Email: target.Email,
+					Part:  p.name,
+					Field: erroredRecipientField(err),
+					Error: err.Error(),
+				})
+				failed = true
+			}
+			for _, field := range p.shown {
+				if strings.TrimSpace(recipientFieldValue(ptx.BaseRecipient, field)) == "" {
+					rc.Missing = append(rc.Missing, RenderIssue{
+						Email: target.Email,
+						Part:  p.name,
+						Field: recipientTemplateFields[field],
+						Error: "Field is blank",
+					})
+				}
+			}
+		}
+		if failed {
+			rc.Failed++
+		}
+	}
+	return rc
+}
+
+// recipientFieldValue returns the value of the named recipient field.
+func recipientFieldValue(r BaseRecipient, field string) string {
+	switch field {
+	case "Email":
+		return r.Email
+	case "FirstName":
+		return r.FirstName
+	case "LastName":
+		return r.LastName
+	case "Position":
+		return r.Position
+	}
+	return ""
+}
+
+// shownRecipientFields returns the recipient fields which the template
+// shows as they are, such as {{.FirstName}}. Fields given to a function such
+// as fallback aren't included, since the function handles blank values.
+func shownRecipientFields(text string) []string {
+	tmpl, err := parseTemplate(text)
+	if err != nil || tmpl.Tree == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	fields := []string{}
+	// guarded are the fields checked by an enclosing {{if}} or {{with}}, so
+	// they're never shown while blank
+	var walk func(n parse.Node, guarded map[string]bool)
+	walkBranch := func(pipe *parse.PipeNode, list, elseList *parse.ListNode, guarded map[string]bool) {
+		inner := guarded
+		if name := pipeRecipientField(pipe); name != "" {
+			inner = map[string]bool{name: true}
+			for k := range guarded {
+				inner[k] = true
+			}
+		}
+		walk(list, inner)
+		walk(elseList, guarded)
+	}
+	walk = func(n parse.Node, guarded map[string]bool) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c, guarded)
+			}
+		case *parse.ActionNode:
+			name := pipeRecipientField(n.Pipe)
+			if name != "" && !guarded[name] && !seen[name] {
+				seen[name] = true
+				fields = append(fields, name)
+			}
+		case *parse.IfNode:
+			walkBranch(n.Pipe, n.List, n.ElseList, guarded)
+		case *parse.WithNode:
+			walkBranch(n.Pipe, n.List, n.ElseList, guarded)
+		case *parse.RangeNode:
+			walk(n.List, guarded)
+			walk(n.ElseList, guarded)
+		}
+	}
+	walk(tmpl.Tree.Root, map[string]bool{})
+	return fields
+}
+
+// pipeRecipientField returns the recipient field a pipeline consists of,
+// such as FirstName for {{.FirstName}}, or an empty string if it's anything
+// else.
+func pipeRecipientField(pipe *parse.PipeNode) string {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return ""
+	}
+	f, ok := pipe.Cmds[0].Args[0].(*parse.FieldNode)
+	if !ok || len(f.Ident) != 1 {
+		return ""
+	}
+	if _, ok := recipientTemplateFields[f.Ident[0]]; !ok {
+		return ""
+	}
+	return f.Ident[0]
+}
+
+// erroredRecipientField returns the name of the recipient field used by the
+// action a template failed to execute, if there is one.
+func erroredRecipientField(err error) string {
+	m := templateErrorAction.FindStringSubmatch(err.Error())
+	if m == nil {
+		return ""
+	}
+	for _, field := range recipientFieldOrder {
+		if strings.Contains(m[1], "."+field) && !strings.Contains(m[1], "."+field+"Or") {
+			return recipientTemplateFields[field]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	check "gopkg.in/check.v1"
+)
+
+func renderCheckTemplate() Template {
+	return Template{
+		Name:    "Render Check",
+		Subject: "Hello {{.FirstName}}",
+		Text:    "Dear {{slice .FirstName 0 3}}, as {{.PositionOr \"a colleague\"}} {{if .LastName}}{{.LastName}}{{end}}",
+		HTML:    "<p>{{fallback \"there\" .FirstName}}</p>",
+	}
+}
+
+func renderCheckTargets() []Target {
+	return []Target{
+		{BaseRecipient: BaseRecipient{Email: "john@example.com", FirstName: "John", LastName: "Doe"}},
+		{BaseRecipient: BaseRecipient{Email: "al@example.com", FirstName: "Al"}},
+	}
+}
+
+func (s *ModelsSuite) TestCheckTemplateRenderReportsProblemTarget(ch *check.C) {
+	rc := checkTemplateRender(renderCheckTemplate(), renderCheckTargets())
+	ch.Assert(rc.Total, check.Equals, 2)
+	ch.Assert(rc.Failed, check.Equals, 1)
+	ch.Assert(len(rc.Errors), check.Equals, 1)
+	ch.Assert(rc.Errors[0].Email, check.Equals, "al@example.com")
+	ch.Assert(rc.Errors[0].Part, check.Equals, "text")
+	ch.Assert(rc.Errors[0].Field, check.Equals, "first_name")
+	ch.Assert(len(rc.Missing), check.Equals, 0)
+}
+
+func (s *ModelsSuite) TestCheckTemplateRenderReportsBlankFields(ch *check.C) {
+	targets := []Target{
+		{BaseRecipient: BaseRecipient{Email: "noname@example.com"}},
+	}
+	rc := checkTemplateRender(renderCheckTemplate(), targets)
+	// The subject shows the blank first name, while the HTML uses a fallback
+	// and the last name is only shown when it's set
+	ch.Assert(rc.Missing, check.DeepEquals, []RenderIssue{
+		{Email: "noname@example.com", Part: "subject", Field: "first_name", Error: "Field is blank"},
+	})
+	ch.Assert(rc.Failed, check.Equals, 1)
+	ch.Assert(rc.Errors[0].Field, check.Equals, "first_name")
+}
+
+func (s *ModelsSuite) TestCheckTemplateRenderByName(ch *check.C) {
+	g := Group{Name: "Render Check Group", UserId: 1, Targets: renderCheckTargets()}
+	ch.Assert(PostGroup(&g), check.Equals, nil)
+	t := renderCheckTemplate()
+	t.UserId = 1
+	ch.Assert(PostTemplate(&t), check.Equals, nil)
+
+	rc, err := CheckTemplateRender(t.Name, g.Name, 1)
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(rc.Group, check.Equals, g.Name)
+	ch.Assert(rc.Failed, check.Equals, 1)
+	ch.Assert(rc.Errors[0].Email, check.Equals, "al@example.com")
+
+	_, err = CheckTemplateRender(t.Name, "No Such Group", 1)
+	ch.Assert(err, check.NotNil)
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// VariantAttributePosition matches recipients on their position, which is
+	// typically used to hold a department or job title.
+	VariantAttributePosition = "position"
+	// VariantAttributeEmailDomain matches recipients on the domain of their
+	// email address.
+	VariantAttributeEmailDomain = "email_domain"
+)
+
+// ErrInvalidVariantAttribute indicates that a template variant uses an
+// attribute which can't be matched against recipients
+var ErrInvalidVariantAttribute = errors.New("Template variants must match on position or email_domain")
+
+// ErrVariantValueNotSpecified indicates that a template variant has no value
+// to match against
+var ErrVariantValueNotSpecified = errors.New("Template variant value not specified")
+
+// ErrVariantTemplateNotSpecified indicates that a template variant doesn't
+// name the template to send
+var ErrVariantTemplateNotSpecified = errors.New("Template variant template not specified")
+
+// TemplateVariant sends an alternate template to the recipients of a campaign
+// whose attribute matches the given value. Variants are checked in order and
+// recipients matching no variant receive the campaign template.
+type TemplateVariant struct {
+	Id         int64    `json:"-"`
+	CampaignId int64    `json:"-"`
+	Attribute  string   `json:"attribute"`
+	Value      string   `json:"value"`
+	TemplateId int64    `json:"-"`
+	Template   Template `json:"template" gorm:"-"`
+}
+
+// Validate checks to make sure the variant can be matched against recipients.
+func (v *TemplateVariant) Validate() error {
+	switch {
+	case v.Attribute != VariantAttributePosition && v.Attribute != VariantAttributeEmailDomain:
+		return ErrInvalidVariantAttribute
+	case strings.TrimSpace(v.Value) == "":
+		return ErrVariantValueNotSpecified
+	case v.Template.Name == "":
+		return ErrVariantTemplateNotSpecified
+	}
+	return nil
+}
+
+// Matches returns true if the recipient's attribute matches the variant.
+// Values are compared case-insensitively.
+func (v *TemplateVariant) Matches(r BaseRecipient) bool {
+	var value string
+	switch v.Attribute {
+	case VariantAttributePosition:
+		value = r.Position
+	case VariantAttributeEmailDomain:
+		if i := strings.LastIndex(r.Email, "@"); i != -1 {
+			value = r.Email[i+1:]
+		}
+	}
+	return strings.EqualFold(strings.TrimSpace(value), strings.TrimSpace(v.Value))
+}
+
+// selectTemplateVariant returns the ID of the template to send to the
+// recipient, or 0 if the campaign template should be used.
+func (c *Campaign) selectTemplateVariant(r BaseRecipient) int64 {
+	for _, v := range c.TemplateVariants {
+		if v.Matches(r) {
+			return v.TemplateId
+		}
+	}
+	return 0
+}
+
+// templateForResult returns the template to send to the given result,
+// falling back to the campaign template if the result's variant isn't loaded.
+func (c *Campaign) templateForResult(r *Result) Template {
+	if r.TemplateId == 0 {
+		return c.Template
+	}
+	for _, v := range c.TemplateVariants {
+		if v.TemplateId == r.TemplateId && v.Template.Id != 0 {
+			return v.Template
+		}
+	}
+	return c.Template
+}
+
+// resolveTemplateVariants looks up the templates named by the campaign's
+// variants.
+func (c *Campaign) resolveTemplateVariants(uid int64) error {
+	for i, v := range c.TemplateVariants {
+		t, err := GetTemplateByName(v.Template.Name, uid)
+		if err == gorm.ErrRecordNotFound {
+			log.WithFields(logrus.Fields{
+				"template": v.Template.Name,
+			}).Error("Template does not exist")
+			return ErrTemplateNotFound
+		} else if err != nil {
+			log.Error(err)
+			return err
+		}
+		c.TemplateVariants[i].Template = t
+		c.TemplateVariants[i].TemplateId = t.Id
+	}
+	return nil
+}
+
+// getTemplateVariants loads the template variants of the campaign, along
+// with their templates.
+func (c *Campaign) getTemplateVariants() error {
+	c.TemplateVariants = []TemplateVariant{}
+	err := db.Where("campaign_id=?", c.Id).Order("id asc").Find(&c.TemplateVariants).Error
+	if err != nil {
+		return err
+	}
+	for i, v := range c.TemplateVariants {
+		t, err := GetTemplate(v.TemplateId, c.UserId)
+		if err == gorm.ErrRecordNotFound {
+			c.TemplateVariants[i].Template = Template{Name: "[Deleted]"}
+			log.Warnf("%s: variant template not found for campaign", err)
+			continue
+		} else if err != nil {
+			return err
+		}
+		c.TemplateVariants[i].Template = t
+	}
+	return nil
+}
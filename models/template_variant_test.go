@@ -0,0 +1,133 @@
+package models
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	check "gopkg.in/check.v1"
+)
+
+func newVariantCampaign() *Campaign {
+	return &Campaign{
+		Id:       1,
+		URL:      "https://phish.example.com",
+		Template: Template{Id: 1, Subject: "Default", HTML: "<p>default</p>"},
+		TemplateVariants: []TemplateVariant{
+			{
+				Attribute:  VariantAttributePosition,
+				Value:      "Finance",
+				TemplateId: 2,
+				Template:   Template{Id: 2, Name: "Invoice", Subject: "Invoice", HTML: "<p>invoice</p>"},
+			},
+			{
+				Attribute:  VariantAttributeEmailDomain,
+				Value:      "partner.com",
+				TemplateId: 3,
+				Template:   Template{Id: 3, Name: "Partner", Subject: "Partner", HTML: "<p>partner</p>"},
+			},
+		},
+	}
+}
+
+func (s *ModelsSuite) TestTemplateVariantSelection(c *check.C) {
+	campaign := newVariantCampaign()
+	testCases := []struct {
+		recipient BaseRecipient
+		expected  int64
+	}{
+		{BaseRecipient{Email: "jane@example.com", Position: "Finance"}, 2},
+		{BaseRecipient{Email: "jane@example.com", Position: " finance "}, 2},
+		{BaseRecipient{Email: "bob@Partner.com", Position: "Engineering"}, 3},
+		// Variants are checked in order
+		{BaseRecipient{Email: "bob@partner.com", Position: "Finance"}, 2},
+		// No match falls back to the campaign template
+		{BaseRecipient{Email: "alice@example.com", Position: "Engineering"}, 0},
+		{BaseRecipient{Email: "alice@example.com"}, 0},
+	}
+	for _, tc := range testCases {
+		c.Assert(campaign.selectTemplateVariant(tc.recipient), check.Equals, tc.expected)
+	}
+
+	c.Assert(campaign.templateForResult(&Result{TemplateId: 2}).Subject, check.Equals, "Invoice")
+	c.Assert(campaign.templateForResult(&Result{TemplateId: 0}).Subject, check.Equals, "Default")
+	// Results pointing to a variant which is no longer loaded get the default
+	c.Assert(campaign.templateForResult(&Result{TemplateId: 42}).Subject, check.Equals, "Default")
+}
+
+func (s *ModelsSuite) TestTemplateVariantValidate(c *check.C) {
+	v := TemplateVariant{Attribute: "department", Value: "Finance", Template: Template{Name: "Invoice"}}
+	c.Assert(v.Validate(), check.Equals, ErrInvalidVariantAttribute)
+	v = TemplateVariant{Attribute: VariantAttributePosition, Template: Template{Name: "Invoice"}}
+	c.Assert(v.Validate(), check.Equals, ErrVariantValueNotSpecified)
+	v = TemplateVariant{Attribute: VariantAttributePosition, Value: "Finance"}
+	c.Assert(v.Validate(), check.Equals, ErrVariantTemplateNotSpecified)
+	v = TemplateVariant{Attribute: VariantAttributeEmailDomain, Value: "partner.com", Template: Template{Name: "Partner"}}
+	c.Assert(v.Validate(), check.Equals, nil)
+}
+
+func (s *ModelsSuite) TestN8NPayloadCarriesTemplateVariant(c *check.C) {
+	received := make(chan N8NWebhookPayload, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := N8NWebhookPayload{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&payload), check.Equals, nil)
+		received <- payload
+	}))
+	defer ts.Close()
+
+	campaign := newVariantCampaign()
+	campaign.Results = []Result{
+		{RId: "finance", TemplateId: 2, BaseRecipient: BaseRecipient{Email: "jane@example.com", Position: "Finance"}},
+		{RId: "default", BaseRecipient: BaseRecipient{Email: "alice@example.com"}},
+	}
+	sender := &N8NSender{
+		webhookURL: ts.URL,
+		jwtSecret:  "secret",
+		emailType:  "test",
+		campaign:   campaign,
+		client:     ts.Client(),
+	}
+	err := sender.Send("from@example.com", []string{"jane@example.com", "alice@example.com"}, &mockWriterTo{campaign: campaign})
+	c.Assert(err, check.Equals, nil)
+
+	payload := <-received
+	c.Assert(payload.Subject, check.Equals, "Default")
+	c.Assert(len(payload.Recipients), check.Equals, 2)
+	c.Assert(payload.Recipients[0].TemplateId, check.Equals, int64(2))
+	c.Assert(payload.Recipients[0].Subject, check.Equals, "Invoice")
+	c.Assert(payload.Recipients[0].Message, check.Equals, "<p>invoice</p>")
+	c.Assert(payload.Recipients[1].TemplateId, check.Equals, int64(0))
+	c.Assert(payload.Recipients[1].Message, check.Equals, "")
+}
+
+func (s *ModelsSuite) TestN8NPayloadCarriesFromName(c *check.C) {
+	received := make(chan N8NWebhookPayload, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := N8NWebhookPayload{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&payload), check.Equals, nil)
+		received <- payload
+	}))
+	defer ts.Close()
+
+	campaign := newVariantCampaign()
+	campaign.Results = []Result{
+		{RId: "default", BaseRecipient: BaseRecipient{Email: "alice@example.com"}},
+	}
+	send := func() N8NWebhookPayload {
+		sender := &N8NSender{
+			webhookURL: ts.URL,
+			jwtSecret:  "secret",
+			emailType:  "test",
+			campaign:   campaign,
+			client:     ts.Client(),
+		}
+		err := sender.Send("from@example.com", []string{"alice@example.com"}, &mockWriterTo{campaign: campaign})
+		c.Assert(err, check.Equals, nil)
+		return <-received
+	}
+
+	// Without an override, n8n falls back to the account's display name
+	c.Assert(send().FromName, check.Equals, "")
+	campaign.FromName = "CEO Office"
+	c.Assert(send().FromName, check.Equals, "CEO Office")
+}
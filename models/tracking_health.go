@@ -0,0 +1,150 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/gophish/gophish/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// TrackingHealthTimeout is the maximum amount of time to wait for the
+// tracking domain health check to complete.
+const TrackingHealthTimeout = 5 * time.Second
+
+// ErrTrackingDomainUnreachable is thrown when the campaign's tracking URL
+// doesn't route back to this instance.
+var ErrTrackingDomainUnreachable = errors.New("Tracking URL does not route to this server")
+
+// ErrTrackingDomainNotAllowed is thrown when the campaign's tracking URL
+// isn't on one of the allowed tracking domains.
+var ErrTrackingDomainNotAllowed = errors.New("Tracking URL is not on an allowed tracking domain")
+
+// trackingHealthSecret is used to sign the echo token so that the check can't
+// be satisfied by an unrelated server. Unless TRACKING_HEALTH_SECRET is set,
+// it's generated when Gophish starts, which works when the admin and phishing
+// servers run in the same process. When the check may be answered by another
+// instance, such as when several instances run behind a load balancer, they
+// must all share TRACKING_HEALTH_SECRET or the check fails.
+var trackingHealthSecret = func() string {
+	if s := os.Getenv("TRACKING_HEALTH_SECRET"); s != "" {
+		return s
+	}
+	return generateSecureKey()
+}()
+
+// GetTrackingHealthPath returns the path on the phishing server which answers
+// the tracking domain health check. Unless a path is configured, it's derived
+// from the health check secret so that it can't be used to recognise the
+// phishing server.
+func GetTrackingHealthPath() string {
+	if conf != nil {
+		if p := conf.GetTrackingHealthCheck().Path; p != "" {
+			return "/" + strings.TrimPrefix(p, "/")
+		}
+	}
+	mac := hmac.New(sha256.New, []byte(trackingHealthSecret))
+	mac.Write([]byte("path"))
+	return "/" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// TrackingHealthResponse returns the expected response to a health check
+// request for the given token.
+func TrackingHealthResponse(token string) string {
+	mac := hmac.New(sha256.New, []byte(trackingHealthSecret))
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+var trackingHealthClient = &http.Client{
+	Timeout: TrackingHealthTimeout,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// CheckTrackingDomain requests the health check endpoint through the given
+// public base URL and confirms the response was generated by this instance.
+func CheckTrackingDomain(baseURL string) error {
+	token := generateSecureKey()
+	u := fmt.Sprintf("%s%s?token=%s", strings.TrimSuffix(baseURL, "/"), GetTrackingHealthPath(), url.QueryEscape(token))
+	resp, err := trackingHealthClient.Get(u)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTrackingDomainUnreachable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: unexpected status %s", ErrTrackingDomainUnreachable, resp.Status)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTrackingDomainUnreachable, err)
+	}
+	if !hmac.Equal([]byte(strings.TrimSpace(string(body))), []byte(TrackingHealthResponse(token))) {
+		return fmt.Errorf("%w: response was not generated by this server", ErrTrackingDomainUnreachable)
+	}
+	return nil
+}
+
+// checkTrackingDomain runs the tracking domain health check for the campaign
+// according to the configured settings. Failures are only logged unless the
+// check is configured to be strict.
+func (c *Campaign) checkTrackingDomain() error {
+	if c.URL == "" || conf == nil {
+		return nil
+	}
+	hc := conf.GetTrackingHealthCheck()
+	if hc.Disabled {
+		return nil
+	}
+	baseURL := GetPublicBaseURL(nil, c.URL)
+	err := CheckTrackingDomain(baseURL)
+	if err == nil {
+		return nil
+	}
+	log.WithFields(logrus.Fields{
+		"campaign": c.Name,
+		"url":      baseURL,
+	}).Warn(err)
+	if hc.Strict {
+		return err
+	}
+	return nil
+}
+
+// checkTrackingHost confirms that the campaign's URL, and the public base URL
+// its tracking links are built from if that's overridden, are on the allowed
+// tracking domains.
+func (c *Campaign) checkTrackingHost() error {
+	if conf == nil || len(conf.AllowedTrackingDomains) == 0 {
+		return nil
+	}
+	urls := []string{}
+	if c.URL != "" {
+		urls = append(urls, c.URL)
+	}
+	if baseURL := GetPublicBaseURL(nil, c.URL); baseURL != strings.TrimSuffix(c.URL, "/") {
+		urls = append(urls, baseURL)
+	}
+	for _, rawURL := range urls {
+		u, err := url.Parse(strings.TrimSpace(rawURL))
+		if err != nil || !conf.IsTrackingHostAllowed(u.Host) {
+			log.WithFields(logrus.Fields{
+				"campaign": c.Name,
+				"url":      rawURL,
+			}).Warn(ErrTrackingDomainNotAllowed)
+			return fmt.Errorf("%w: %s", ErrTrackingDomainNotAllowed, rawURL)
+		}
+	}
+	return nil
+}
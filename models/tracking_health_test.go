@@ -0,0 +1,111 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/gophish/gophish/config"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestCheckTrackingDomain(c *check.C) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Path, check.Equals, GetTrackingHealthPath())
+		fmt.Fprintln(w, TrackingHealthResponse(r.URL.Query().Get("token")))
+	}))
+	defer ts.Close()
+	c.Assert(CheckTrackingDomain(ts.URL+"/"), check.Equals, nil)
+
+	// A server which isn't this instance can't produce the signed response
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, r.URL.Query().Get("token"))
+	}))
+	defer other.Close()
+	err := CheckTrackingDomain(other.URL)
+	c.Assert(errors.Is(err, ErrTrackingDomainUnreachable), check.Equals, true)
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	err = CheckTrackingDomain(down.URL)
+	c.Assert(errors.Is(err, ErrTrackingDomainUnreachable), check.Equals, true)
+}
+
+func (s *ModelsSuite) TestGetTrackingHealthPath(c *check.C) {
+	original := conf.TrackingHealthCheck
+	defer func() { conf.TrackingHealthCheck = original }()
+
+	conf.TrackingHealthCheck = nil
+	path := GetTrackingHealthPath()
+	c.Assert(len(path), check.Equals, 17)
+	c.Assert(GetTrackingHealthPath(), check.Equals, path)
+
+	conf.TrackingHealthCheck = &config.TrackingHealthCheck{Path: "status/ping"}
+	c.Assert(GetTrackingHealthPath(), check.Equals, "/status/ping")
+}
+
+func (s *ModelsSuite) TestCampaignTrackingDomainStrictMode(c *check.C) {
+	original := conf.TrackingHealthCheck
+	defer func() { conf.TrackingHealthCheck = original }()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	campaign := Campaign{Name: "Health Check", URL: down.URL}
+
+	// Failures are only warnings by default
+	conf.TrackingHealthCheck = nil
+	c.Assert(campaign.checkTrackingDomain(), check.Equals, nil)
+
+	conf.TrackingHealthCheck = &config.TrackingHealthCheck{Strict: true}
+	c.Assert(errors.Is(campaign.checkTrackingDomain(), ErrTrackingDomainUnreachable), check.Equals, true)
+
+	conf.TrackingHealthCheck = &config.TrackingHealthCheck{Strict: true, Disabled: true}
+	c.Assert(campaign.checkTrackingDomain(), check.Equals, nil)
+}
+
+func (s *ModelsSuite) TestCampaignTrackingHostAllowList(c *check.C) {
+	original := conf.AllowedTrackingDomains
+	defer func() { conf.AllowedTrackingDomains = original }()
+	campaign := Campaign{Name: "Tracking Domains", URL: "https://phish.example.com:8443/"}
+
+	// Any host is allowed without an allow-list
+	conf.AllowedTrackingDomains = nil
+	c.Assert(campaign.checkTrackingHost(), check.Equals, nil)
+
+	conf.AllowedTrackingDomains = []string{"phish.example.com"}
+	c.Assert(campaign.checkTrackingHost(), check.Equals, nil)
+	conf.AllowedTrackingDomains = []string{"*.example.com"}
+	c.Assert(campaign.checkTrackingHost(), check.Equals, nil)
+
+	conf.AllowedTrackingDomains = []string{"example.com", "*.example.org"}
+	c.Assert(errors.Is(campaign.checkTrackingHost(), ErrTrackingDomainNotAllowed), check.Equals, true)
+	campaign.URL = "https://example.com.attacker.net"
+	c.Assert(errors.Is(campaign.checkTrackingHost(), ErrTrackingDomainNotAllowed), check.Equals, true)
+}
+
+func (s *ModelsSuite) TestCampaignTrackingHostOverride(c *check.C) {
+	original := conf.AllowedTrackingDomains
+	defer func() { conf.AllowedTrackingDomains = original }()
+	defer os.Unsetenv("PUBLIC_BASE_URL")
+	conf.AllowedTrackingDomains = []string{"phish.example.com"}
+	campaign := Campaign{Name: "Tracking Override", URL: "https://phish.example.com"}
+
+	// The public base URL overrides the campaign URL in tracking links, so
+	// it's checked too
+	os.Setenv("PUBLIC_BASE_URL", "https://unowned.example.net")
+	c.Assert(errors.Is(campaign.checkTrackingHost(), ErrTrackingDomainNotAllowed), check.Equals, true)
+	os.Setenv("PUBLIC_BASE_URL", "https://phish.example.com/")
+	c.Assert(campaign.checkTrackingHost(), check.Equals, nil)
+}
+
+func (s *ModelsSuite) TestPostCampaignTrackingHostNotAllowed(c *check.C) {
+	original := conf.AllowedTrackingDomains
+	defer func() { conf.AllowedTrackingDomains = original }()
+	conf.AllowedTrackingDomains = []string{"phish.example.com"}
+	campaign := s.createCampaignDependencies(c)
+	campaign.URL = "https://unowned.example.net"
+	err := PostCampaign(&campaign, campaign.UserId)
+	c.Assert(errors.Is(err, ErrTrackingDomainNotAllowed), check.Equals, true)
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// MaxTrackingParams is the most extra tracking parameters a campaign may add
+// to its URLs.
+const MaxTrackingParams = 10
+
+// ErrInvalidTrackingParams indicates that a campaign's tracking parameters
+// couldn't be parsed as a query string
+var ErrInvalidTrackingParams = errors.New("Tracking parameters must be a query string, such as utm_source=phish&utm_medium=email")
+
+// ErrTrackingParamName indicates that a tracking parameter has an empty or
+// unsupported name
+var ErrTrackingParamName = errors.New("Tracking parameter names may only contain letters, numbers, dots, dashes and underscores")
+
+// ErrTrackingParamCollision indicates that a tracking parameter would replace
+// the recipient ID, or is given more than once
+var ErrTrackingParamCollision = errors.New("Tracking parameters must be unique and can't include " + RecipientParameter)
+
+// ErrTooManyTrackingParams indicates that a campaign has more tracking
+// parameters than are allowed
+var ErrTooManyTrackingParams = errors.New("Too many tracking parameters")
+
+var trackingParamNameRegex = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+
+// parseTrackingParams validates a campaign's tracking parameters, returning
+// them parsed.
+func parseTrackingParams(s string) (url.Values, error) {
+	params, err := url.ParseQuery(strings.TrimPrefix(strings.TrimSpace(s), "?"))
+	if err != nil {
+		return nil, ErrInvalidTrackingParams
+	}
+	if len(params) > MaxTrackingParams {
+		return nil, ErrTooManyTrackingParams
+	}
+	for name, values := range params {
+		if !trackingParamNameRegex.MatchString(name) {
+			return nil, ErrTrackingParamName
+		}
+		if strings.EqualFold(name, RecipientParameter) || len(values) > 1 {
+			return nil, ErrTrackingParamCollision
+		}
+	}
+	return params, nil
+}
+
+// normalizeTrackingParams validates the campaign's tracking parameters and
+// stores them URL-encoded.
+func (c *Campaign) normalizeTrackingParams() error {
+	if strings.TrimSpace(c.TrackingParams) == "" {
+		c.TrackingParams = ""
+		return nil
+	}
+	params, err := parseTrackingParams(c.TrackingParams)
+	if err != nil {
+		return err
+	}
+	c.TrackingParams = params.Encode()
+	return nil
+}
+
+// getTrackingParams returns the extra parameters added to the campaign's
+// landing page and tracking URLs.
+func (c *Campaign) getTrackingParams() url.Values {
+	if c.TrackingParams == "" {
+		return nil
+	}
+	params, err := parseTrackingParams(c.TrackingParams)
+	if err != nil {
+		return nil
+	}
+	return params
+}
+
+// trackingParamsContext is implemented by template contexts which add extra
+// parameters to the generated URLs.
+type trackingParamsContext interface {
+	getTrackingParams() url.Values
+}
+
+// addTrackingParams adds the context's tracking parameters, if any, to the
+// query. The recipient ID is never replaced.
+func addTrackingParams(q url.Values, ctx TemplateContext) {
+	tc, ok := ctx.(trackingParamsContext)
+	if !ok {
+		return
+	}
+	for name, values := range tc.getTrackingParams() {
+		if name == RecipientParameter {
+			continue
+		}
+		q[name] = values
+	}
+}
+
+// appendTrackingParams returns the URL with the tracking parameters added to
+// its query string.
+func appendTrackingParams(u string, params url.Values) string {
+	if len(params) == 0 {
+		return u
+	}
+	sep := "?"
+	if strings.Contains(u, "?") {
+		sep = "&"
+	}
+	return u + sep + params.Encode()
+}
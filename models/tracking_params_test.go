@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestNormalizeTrackingParams(c *check.C) {
+	campaign := Campaign{TrackingParams: "?utm_source=phish&utm_medium=e mail"}
+	c.Assert(campaign.normalizeTrackingParams(), check.Equals, nil)
+	c.Assert(campaign.TrackingParams, check.Equals, "utm_medium=e+mail&utm_source=phish")
+
+	for params, expected := range map[string]error{
+		"rid=123":               ErrTrackingParamCollision,
+		"RID=123":               ErrTrackingParamCollision,
+		"src=a&src=b":           ErrTrackingParamCollision,
+		"bad name=1":            ErrTrackingParamName,
+		"=1":                    ErrTrackingParamName,
+		"src=%zz":               ErrInvalidTrackingParams,
+		"a&b&c&d&e&f&g&h&i&j&k": ErrTooManyTrackingParams,
+	} {
+		campaign := Campaign{TrackingParams: params}
+		c.Assert(campaign.normalizeTrackingParams(), check.Equals, expected, check.Commentf("params %q", params))
+	}
+}
+
+func (s *ModelsSuite) TestTrackingParamsOnTemplateURLs(c *check.C) {
+	campaign := Campaign{
+		URL:            "https://phish.example.com",
+		TrackingParams: "utm_source=phish&utm_medium=email",
+		EmailAccount:   EmailAccount{Email: "it@example.com"},
+	}
+	ptx, err := NewPhishingTemplateContext(&campaign, BaseRecipient{Email: "alice@example.com"}, "abc123")
+	c.Assert(err, check.Equals, nil)
+	for _, u := range []string{ptx.URL, ptx.TrackingURL} {
+		parsed, err := url.Parse(u)
+		c.Assert(err, check.Equals, nil)
+		q := parsed.Query()
+		c.Assert(q.Get(RecipientParameter), check.Equals, "abc123")
+		c.Assert(q.Get("utm_source"), check.Equals, "phish")
+		c.Assert(q.Get("utm_medium"), check.Equals, "email")
+	}
+}
+
+func (s *ModelsSuite) TestTrackingParamsOnN8NURLs(c *check.C) {
+	received := make(chan N8NWebhookPayload, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := N8NWebhookPayload{}
+		c.Assert(json.NewDecoder(r.Body).Decode(&payload), check.Equals, nil)
+		received <- payload
+	}))
+	defer ts.Close()
+
+	campaign := newVariantCampaign()
+	campaign.TrackingParams = "utm_source=phish&utm_campaign=q3+test"
+	campaign.Results = []Result{
+		{RId: "abc123", BaseRecipient: BaseRecipient{Email: "alice@example.com"}},
+	}
+	sender := &N8NSender{
+		webhookURL: ts.URL,
+		jwtSecret:  "secret",
+		emailType:  "test",
+		campaign:   campaign,
+		client:     ts.Client(),
+	}
+	err := sender.Send("from@example.com", []string{"alice@example.com"}, &mockWriterTo{campaign: campaign})
+	c.Assert(err, check.Equals, nil)
+	payload := <-received
+	c.Assert(len(payload.Recipients), check.Equals, 1)
+	r := payload.Recipients[0]
+	for _, u := range []string{r.PhishingURL, r.TrackingURL} {
+		parsed, err := url.Parse(u)
+		c.Assert(err, check.Equals, nil)
+		q := parsed.Query()
+		c.Assert(q.Get(RecipientParameter), check.Equals, "abc123")
+		c.Assert(q.Get("utm_source"), check.Equals, "phish")
+		c.Assert(q.Get("utm_campaign"), check.Equals, "q3 test")
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
+	"github.com/gophish/gophish/auth"
 	"github.com/gophish/gophish/config"
 	log "github.com/gophish/gophish/logger"
 )
@@ -18,7 +20,7 @@ var ErrModifyingOnlyAdmin = errors.New("Cannot remove the only administrator")
 
 // OAuth operations interface for external packages to use
 type OAuthUserOperationsInterface interface {
-	FindOrCreateUser(provider, oauthID, email string) (userID int64, username string, accountLocked bool, isAdmin bool, err error)
+	FindOrCreateUser(provider, oauthID, email, preferredUsername string) (userID int64, username string, accountLocked bool, isAdmin bool, err error)
 	UpdateLastLogin(userID int64) error
 	ValidateAdminPrivilege(userID int64) (bool, error)
 	LogSecurityEvent(userID int64, event, details string) error
@@ -31,8 +33,8 @@ func GetOAuthUserOperations() OAuthUserOperationsInterface {
 
 type oauthUserOps struct{}
 
-func (ops *oauthUserOps) FindOrCreateUser(provider, oauthID, email string) (userID int64, username string, accountLocked bool, isAdmin bool, err error) {
-	user, err := FindOrCreateOAuthUser(provider, oauthID, email)
+func (ops *oauthUserOps) FindOrCreateUser(provider, oauthID, email, preferredUsername string) (userID int64, username string, accountLocked bool, isAdmin bool, err error) {
+	user, err := FindOrCreateOAuthUserAs(provider, oauthID, email, preferredUsername)
 	if err != nil {
 		return 0, "", false, false, err
 	}
@@ -76,6 +78,14 @@ func (ops *oauthUserOps) LogSecurityEvent(userID int64, event, details string) e
 	return service.LogAuthorizationAttempt(ctx, user.Username, event, "security_event", &userID, details)
 }
 
+// SendLoginAlert alerts security monitoring to an authentication failure
+// during single sign-on.
+func (ops *oauthUserOps) SendLoginAlert(r *http.Request, alertType, username, details string) {
+	a := NewLoginAlert(alertType, username, r)
+	a.Details = details
+	SendLoginAlert(a)
+}
+
 // User represents the user model for gophish.
 type User struct {
 	Id                     int64     `json:"id"`
@@ -90,6 +100,37 @@ type User struct {
 	// OAuth fields for SSO integration
 	OAuthProvider          string    `json:"oauth_provider,omitempty" gorm:"column:oauth_provider"`
 	OAuthID                string    `json:"oauth_id,omitempty" gorm:"column:oauth_id"`
+	// OAuthIDIndex finds the user by their OAuth ID once it's encrypted
+	OAuthIDIndex           string    `json:"-" gorm:"column:oauth_id_index"`
+	// SSOManaged accounts may only sign in with single sign-on
+	SSOManaged             bool      `json:"sso_managed" gorm:"column:sso_managed"`
+	// Email is the email verified by the user's identity provider when they
+	// last signed in with single sign-on
+	Email                  string    `json:"email,omitempty" gorm:"column:email"`
+}
+
+// AuthorizationEmail returns the email the user is authorized by: the one
+// verified by their identity provider, or their username for users who
+// haven't signed in with single sign-on.
+func (u User) AuthorizationEmail() string {
+	if u.Email != "" {
+		return u.Email
+	}
+	return u.Username
+}
+
+// ErrSSOManagedPassword is thrown when attempting to set or use a local
+// password for an account managed by single sign-on.
+var ErrSSOManagedPassword = errors.New("This account is managed by single sign-on and can't use a local password")
+
+// LocalPasswordAllowed returns true if the user may set and sign in with a
+// local password. Accounts managed by single sign-on can't, unless this is
+// disabled with the allow_sso_managed_passwords setting.
+func (u *User) LocalPasswordAllowed() bool {
+	if !u.SSOManaged {
+		return true
+	}
+	return conf != nil && conf.GetSSOConfig().AllowSSOManagedPasswords
 }
 
 // GetUser returns the user that the given id corresponds to. If no user is found, an
@@ -232,7 +273,19 @@ func DeleteUser(id int64) error {
 // If no user is found, an error is thrown.
 func GetUserByOAuthID(provider, oauthID string) (User, error) {
 	u := User{}
-	err := db.Preload("Role").Where("oauth_provider = ? AND oauth_id = ?", provider, oauthID).First(&u).Error
+	indexes, err := fieldIndexes(oauthID)
+	if err != nil {
+		return u, err
+	}
+	query := db.Preload("Role").Where("oauth_provider = ?", provider)
+	// OAuth IDs are encrypted when a field encryption key is configured, so
+	// they're found by their index instead
+	if len(indexes) > 0 {
+		query = query.Where("oauth_id_index IN (?) OR oauth_id = ?", indexes, oauthID)
+	} else {
+		query = query.Where("oauth_id = ?", oauthID)
+	}
+	err = query.First(&u).Error
 	return u, err
 }
 
@@ -243,13 +296,25 @@ func PostUser(u *User) error {
 
 // FindOrCreateOAuthUser finds an existing OAuth user or creates a new one
 func FindOrCreateOAuthUser(provider, oauthID, email string) (User, error) {
+	return FindOrCreateOAuthUserAs(provider, oauthID, email, "")
+}
+
+// FindOrCreateOAuthUserAs finds an existing OAuth user, using the preferred
+// username given by the provider's username claim as their username. The
+// email is used if no preferred username is given. Users are still found by
+// their email, which is stored apart from their username to authorize them.
+func FindOrCreateOAuthUserAs(provider, oauthID, email, preferredUsername string) (User, error) {
 	// First, try to find user by OAuth provider and ID
 	existingUser, err := GetUserByOAuthID(provider, oauthID)
 	if err == nil {
 		// User exists, update info if needed
 		needsUpdate := false
-		if existingUser.Username != email {
-			existingUser.Username = email
+		if username := oauthUsername(existingUser, email, preferredUsername); existingUser.Username != username {
+			existingUser.Username = username
+			needsUpdate = true
+		}
+		if existingUser.Email != email {
+			existingUser.Email = email
 			needsUpdate = true
 		}
 
@@ -273,10 +338,21 @@ func FindOrCreateOAuthUser(provider, oauthID, email string) (User, error) {
 	// If not found by OAuth ID, check if user exists by email
 	existingUser, err = GetUserByUsername(email)
 	if err == nil {
-		// User exists with this email but no OAuth link
-		// Link this OAuth account to existing user
+		// User exists with this email but no OAuth link. Linking it to
+		// this OAuth account hands the account over, so check it's allowed.
+		allowed, reason := oauthLinkAllowed(existingUser, provider)
+		if !allowed {
+			auditOAuthLink(existingUser, provider, oauthID, OAuthLinkDenied, reason)
+			return User{}, ErrOAuthLinkRequired
+		}
 		existingUser.OAuthProvider = provider
 		existingUser.OAuthID = oauthID
+		existingUser.Username = oauthUsername(existingUser, email, preferredUsername)
+		existingUser.Email = email
+		// Accounts without a local password are now managed by SSO
+		if existingUser.Hash == "" {
+			existingUser.SSOManaged = true
+		}
 
 		// Check if this is the admin email and update role accordingly
 		if isAdminEmail(email) && existingUser.Role.Slug != RoleAdmin {
@@ -289,6 +365,7 @@ func FindOrCreateOAuthUser(provider, oauthID, email string) (User, error) {
 		if err := PutUser(&existingUser); err != nil {
 			return User{}, fmt.Errorf("failed to link existing user to OAuth: %w", err)
 		}
+		auditOAuthLink(existingUser, provider, oauthID, OAuthLinkLinked, reason)
 		return existingUser, nil
 	}
 
@@ -297,6 +374,29 @@ func FindOrCreateOAuthUser(provider, oauthID, email string) (User, error) {
 	return User{}, fmt.Errorf("user %s is not authorized to access this system - please contact your administrator", email)
 }
 
+// oauthUsername returns the username an OAuth user should have: their
+// preferred username if one is given, or otherwise their email. If another
+// user already has that username, the user's email is used instead, and
+// failing that they keep their current username.
+func oauthUsername(u User, email, preferredUsername string) string {
+	candidates := []string{email}
+	if preferredUsername != "" && preferredUsername != email {
+		candidates = []string{preferredUsername, email}
+	}
+	for _, username := range candidates {
+		if username == u.Username {
+			return username
+		}
+		other, err := GetUserByUsername(username)
+		if err == nil && other.Id != u.Id {
+			log.Warnf("Username %s of OAuth user %d is already in use by user %d", username, u.Id, other.Id)
+			continue
+		}
+		return username
+	}
+	return u.Username
+}
+
 // isAdminEmail checks if the provided email should receive admin privileges
 func isAdminEmail(email string) bool {
 	// Load configuration to check admin emails
@@ -374,3 +474,70 @@ func EnsureAdminEmailAuthorization() error {
 
 	return nil
 }
+
+// BootstrapAdminEmails grants admin access to the configured admin emails
+// when no user has the admin role, so that the first SSO login of one of
+// those emails lands as an admin. Each email is authorized with the admin role
+// and given an admin user which is linked to the OAuth account on first login.
+//
+// This only runs while there are no admins. Once an admin exists, admin
+// access must be granted by that admin.
+func BootstrapAdminEmails(cfg *config.Config) error {
+	adminRole, err := GetRoleBySlug(RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("failed to get admin role: %w", err)
+	}
+	var adminCount int64
+	err = db.Model(&User{}).Where("role_id = ?", adminRole.ID).Count(&adminCount).Error
+	if err != nil {
+		return err
+	}
+	if adminCount > 0 {
+		return nil
+	}
+	adminEmails := cfg.GetAdminEmails()
+	if len(adminEmails) == 0 {
+		return nil
+	}
+	service := NewEmailAuthorizationService()
+	for _, email := range adminEmails {
+		email = strings.TrimSpace(email)
+		if err := service.ValidateEmailFormat(email); err != nil {
+			log.Errorf("Skipping invalid admin email %q: %v", email, err)
+			continue
+		}
+		ae, err := getAuthorizedEmailByNormalized(service.NormalizeEmail(email))
+		if err == nil {
+			ae.Status = "active"
+			ae.RoleID = &adminRole.ID
+			ae.DefaultRole = RoleAdmin
+			err = db.Save(&ae).Error
+		} else {
+			_, err = AddAuthorizedEmail(email, &adminRole.ID, RoleAdmin, nil, nil,
+				"Bootstrapped admin email, no admin users existed")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to authorize admin email %s: %w", email, err)
+		}
+		u, err := GetUserByUsername(email)
+		if err == nil {
+			u.RoleID = adminRole.ID
+			u.Role = adminRole
+			err = PutUser(&u)
+		} else {
+			u = User{
+				Username:   email,
+				Role:       adminRole,
+				RoleID:     adminRole.ID,
+				ApiKey:     auth.GenerateSecureKey(auth.APIKeyLength),
+				SSOManaged: true,
+			}
+			err = db.Save(&u).Error
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create admin user %s: %w", email, err)
+		}
+		log.Warnf("No admin users exist: bootstrapped admin access for %s. Sign in with SSO as this email to administer Gophish.", email)
+	}
+	return nil
+}
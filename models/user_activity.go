@@ -0,0 +1,169 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// DefaultUserActivityLimit is the number of entries returned from a user's
+// activity if no limit is given.
+const DefaultUserActivityLimit = 500
+
+// Types of user activity
+const (
+	UserActivityAuthorization = "authorization"
+	UserActivityCampaign      = "campaign"
+)
+
+// Campaign lifecycle actions shown in a user's activity
+const (
+	ActivityCampaignCreated   = "Campaign Created"
+	ActivityCampaignLaunched  = "Campaign Launched"
+	ActivityCampaignCompleted = "Campaign Completed"
+)
+
+// ErrInvalidActivityRange is thrown when the end of an activity range is
+// before its start
+var ErrInvalidActivityRange = errors.New("The end date must be after the start date")
+
+// UserActivity is an entry in the footprint of a user's account, either an
+// authorization log entry such as a login, or a step in the lifecycle of one
+// of their campaigns.
+type UserActivity struct {
+	Time         time.Time `json:"time"`
+	Type         string    `json:"type"`
+	Action       string    `json:"action"`
+	Result       string    `json:"result,omitempty"`
+	IPAddress    string    `json:"ip_address,omitempty"`
+	UserAgent    string    `json:"user_agent,omitempty"`
+	CampaignId   int64     `json:"campaign_id,omitempty"`
+	CampaignName string    `json:"campaign_name,omitempty"`
+	Details      string    `json:"details,omitempty"`
+}
+
+// UserActivityFilter restricts a user's activity to the entries between
+// Start and End, either of which may be left unset. At most Limit of the
+// most recent entries are returned.
+type UserActivityFilter struct {
+	Start time.Time
+	End   time.Time
+	Limit int
+}
+
+// Validate checks that the filter's range is in order.
+func (f *UserActivityFilter) Validate() error {
+	if !f.Start.IsZero() && !f.End.IsZero() && f.End.Before(f.Start) {
+		return ErrInvalidActivityRange
+	}
+	return nil
+}
+
+// includes returns true if the time is within the filter's range.
+func (f *UserActivityFilter) includes(t time.Time) bool {
+	if t.IsZero() {
+		return false
+	}
+	if !f.Start.IsZero() && t.Before(f.Start) {
+		return false
+	}
+	if !f.End.IsZero() && t.After(f.End) {
+		return false
+	}
+	return true
+}
+
+// campaignActivity returns the lifecycle of the campaign up to the given time.
+func campaignActivity(c Campaign, now time.Time) []UserActivity {
+	entry := func(t time.Time, action string) UserActivity {
+		return UserActivity{
+			Time:         t,
+			Type:         UserActivityCampaign,
+			Action:       action,
+			Result:       c.Status,
+			CampaignId:   c.Id,
+			CampaignName: c.Name,
+		}
+	}
+	as := []UserActivity{entry(c.CreatedDate, ActivityCampaignCreated)}
+	if c.Status != CampaignQueued && !c.LaunchDate.IsZero() && !c.LaunchDate.After(now) {
+		as = append(as, entry(c.LaunchDate, ActivityCampaignLaunched))
+	}
+	if !c.CompletedDate.IsZero() {
+		as = append(as, entry(c.CompletedDate, ActivityCampaignCompleted))
+	}
+	return as
+}
+
+// filterUserActivity returns the entries within the filter's range, most
+// recent first, up to the filter's limit.
+func filterUserActivity(as []UserActivity, f UserActivityFilter) []UserActivity {
+	filtered := []UserActivity{}
+	for _, a := range as {
+		if f.includes(a.Time) {
+			filtered = append(filtered, a)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Time.After(filtered[j].Time)
+	})
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultUserActivityLimit
+	}
+	if len(filtered) > limit {
+		filtered = filtered[:limit]
+	}
+	return filtered
+}
+
+// GetUserActivity returns the activity of the user with the given ID, made up
+// of the authorization log entries recorded against their account or email
+// address and the lifecycle of their campaigns, most recent first.
+func GetUserActivity(uid int64, f UserActivityFilter) ([]UserActivity, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	u, err := GetUser(uid)
+	if err != nil {
+		return nil, err
+	}
+	as := []UserActivity{}
+
+	logs := []EmailAuthorizationLog{}
+	email := NewEmailAuthorizationService().NormalizeEmail(u.Username)
+	query := db.Where("user_id = ? OR normalized_email = ?", uid, email)
+	if !f.Start.IsZero() {
+		query = query.Where("created_at >= ?", f.Start)
+	}
+	if !f.End.IsZero() {
+		query = query.Where("created_at <= ?", f.End)
+	}
+	err = query.Find(&logs).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range logs {
+		as = append(as, UserActivity{
+			Time:      l.CreatedAt,
+			Type:      UserActivityAuthorization,
+			Action:    l.Action,
+			Result:    l.Result,
+			IPAddress: l.IPAddress,
+			UserAgent: l.UserAgent,
+			Details:   l.Details,
+		})
+	}
+
+	cs := []Campaign{}
+	err = db.Select("id, name, status, created_date, launch_date, completed_date").
+		Where("user_id = ?", uid).Find(&cs).Error
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for _, c := range cs {
+		as = append(as, campaignActivity(c, now)...)
+	}
+	return filterUserActivity(as, f), nil
+}
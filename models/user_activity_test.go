@@ -0,0 +1,103 @@
+package models
+
+import (
+	"time"
+
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestCampaignActivity(ch *check.C) {
+	now := time.Date(2025, 12, 10, 12, 0, 0, 0, time.UTC)
+	c := Campaign{
+		Id:          1,
+		Name:        "Payroll",
+		Status:      CampaignQueued,
+		CreatedDate: now.Add(-2 * time.Hour),
+		LaunchDate:  now.Add(time.Hour),
+	}
+	as := campaignActivity(c, now)
+	ch.Assert(len(as), check.Equals, 1)
+	ch.Assert(as[0].Action, check.Equals, ActivityCampaignCreated)
+	ch.Assert(as[0].CampaignName, check.Equals, "Payroll")
+
+	c.Status = CampaignComplete
+	c.LaunchDate = now.Add(-time.Hour)
+	c.CompletedDate = now
+	as = campaignActivity(c, now)
+	actions := []string{}
+	for _, a := range as {
+		actions = append(actions, a.Action)
+	}
+	ch.Assert(actions, check.DeepEquals, []string{ActivityCampaignCreated, ActivityCampaignLaunched, ActivityCampaignCompleted})
+}
+
+func (s *ModelsSuite) TestFilterUserActivity(ch *check.C) {
+	day := func(d int) time.Time {
+		return time.Date(2025, 12, d, 9, 0, 0, 0, time.UTC)
+	}
+	as := []UserActivity{
+		{Time: day(3), Action: "login"},
+		{Time: day(1), Action: "login"},
+		{Time: day(5), Action: ActivityCampaignCreated},
+		{Time: day(2), Action: "logout"},
+		{Action: "unknown time"},
+	}
+
+	// Entries are returned most recent first, leaving out undated ones
+	got := filterUserActivity(as, UserActivityFilter{})
+	ch.Assert(len(got), check.Equals, 4)
+	ch.Assert(got[0].Time, check.Equals, day(5))
+	ch.Assert(got[3].Time, check.Equals, day(1))
+
+	got = filterUserActivity(as, UserActivityFilter{Start: day(2), End: day(3)})
+	ch.Assert(len(got), check.Equals, 2)
+	ch.Assert(got[0].Time, check.Equals, day(3))
+	ch.Assert(got[1].Time, check.Equals, day(2))
+
+	got = filterUserActivity(as, UserActivityFilter{Start: day(2), Limit: 1})
+	ch.Assert(len(got), check.Equals, 1)
+	ch.Assert(got[0].Time, check.Equals, day(5))
+
+	f := UserActivityFilter{Start: day(3), End: day(2)}
+	ch.Assert(f.Validate(), check.Equals, ErrInvalidActivityRange)
+}
+
+func (s *ModelsSuite) TestGetUserActivity(ch *check.C) {
+	campaign := s.createCampaign(ch)
+	u, err := GetUser(1)
+	ch.Assert(err, check.Equals, nil)
+	email := NewEmailAuthorizationService().NormalizeEmail(u.Username)
+	other := int64(2)
+	now := time.Now().UTC()
+	logs := []EmailAuthorizationLog{
+		{Email: u.Username, NormalizedEmail: email, Action: "login", Result: "success", UserID: &u.Id, CreatedAt: now.Add(-48 * time.Hour)},
+		{Email: u.Username, NormalizedEmail: email, Action: "login", Result: "denied", CreatedAt: now.Add(-time.Hour)},
+		{Email: "other@example.com", NormalizedEmail: "other@example.com", Action: "login", Result: "success", UserID: &other, CreatedAt: now},
+	}
+	for i := range logs {
+		ch.Assert(db.Save(&logs[i]).Error, check.Equals, nil)
+	}
+	defer db.Delete(EmailAuthorizationLog{})
+
+	// Logs without a user ID are matched by the user's email address, and
+	// other users' logs are left out
+	as, err := GetUserActivity(1, UserActivityFilter{})
+	ch.Assert(err, check.Equals, nil)
+	types := map[string]int{}
+	for _, a := range as {
+		types[a.Type]++
+	}
+	ch.Assert(types[UserActivityAuthorization], check.Equals, 2)
+	ch.Assert(types[UserActivityCampaign] >= 1, check.Equals, true)
+
+	as, err = GetUserActivity(1, UserActivityFilter{Start: now.Add(-24 * time.Hour), End: now.Add(-30 * time.Minute)})
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(as), check.Equals, 1)
+	ch.Assert(as[0].Result, check.Equals, "denied")
+
+	as, err = GetUserActivity(1, UserActivityFilter{Start: campaign.CreatedDate.Add(-time.Second), End: campaign.CreatedDate.Add(time.Second)})
+	ch.Assert(err, check.Equals, nil)
+	ch.Assert(len(as) >= 1, check.Equals, true)
+	ch.Assert(as[len(as)-1].Type, check.Equals, UserActivityCampaign)
+	ch.Assert(as[len(as)-1].CampaignId, check.Equals, campaign.Id)
+}
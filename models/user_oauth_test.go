@@ -169,7 +169,7 @@ func (s *UserOAuthSuite) TestOAuthUserOperationsInterface(c *check.C) {
 	email := "interface.test@example.com"
 
 	// Test FindOrCreateUser
-	userID, username, accountLocked, isAdmin, err := ops.FindOrCreateUser(provider, oauthID, email)
+	userID, username, accountLocked, isAdmin, err := ops.FindOrCreateUser(provider, oauthID, email, "")
 	c.Assert(err, check.IsNil)
 	c.Assert(userID, check.Not(check.Equals), int64(0))
 	c.Assert(username, check.Equals, email)
@@ -203,7 +203,7 @@ func (s *UserOAuthSuite) TestOAuthUserOperationsAdminUser(c *check.C) {
 	email := "admin.interface@example.com"
 
 	// Create admin user
-	userID, username, accountLocked, isAdmin, err := ops.FindOrCreateUser(provider, oauthID, email)
+	userID, username, accountLocked, isAdmin, err := ops.FindOrCreateUser(provider, oauthID, email, "")
 	c.Assert(err, check.IsNil)
 	c.Assert(isAdmin, check.Equals, false) // Initially not admin
 
@@ -224,7 +224,7 @@ func (s *UserOAuthSuite) TestOAuthUserOperationsAdminUser(c *check.C) {
 	c.Assert(isAdminValidated, check.Equals, true)
 
 	// Verify admin status in user creation result
-	userID2, username2, accountLocked2, isAdmin2, err := ops.FindOrCreateUser(provider, oauthID, email)
+	userID2, username2, accountLocked2, isAdmin2, err := ops.FindOrCreateUser(provider, oauthID, email, "")
 	c.Assert(err, check.IsNil)
 	c.Assert(userID2, check.Equals, userID)
 	c.Assert(username2, check.Equals, username)
@@ -240,7 +240,7 @@ func (s *UserOAuthSuite) TestOAuthUserOperationsAccountLocked(c *check.C) {
 	email := "locked.interface@example.com"
 
 	// Create user first
-	userID, _, _, _, err := ops.FindOrCreateUser(provider, oauthID, email)
+	userID, _, _, _, err := ops.FindOrCreateUser(provider, oauthID, email, "")
 	c.Assert(err, check.IsNil)
 
 	// Lock the account
@@ -251,7 +251,7 @@ func (s *UserOAuthSuite) TestOAuthUserOperationsAccountLocked(c *check.C) {
 	c.Assert(err, check.IsNil)
 
 	// Verify locked status is returned
-	_, _, accountLocked, _, err := ops.FindOrCreateUser(provider, oauthID, email)
+	_, _, accountLocked, _, err := ops.FindOrCreateUser(provider, oauthID, email, "")
 	c.Assert(err, check.IsNil)
 	c.Assert(accountLocked, check.Equals, true)
 }
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"github.com/gophish/gophish/config"
 	"github.com/jinzhu/gorm"
 	"gopkg.in/check.v1"
 )
@@ -103,3 +104,58 @@ func (s *ModelsSuite) TestDeleteLastAdmin(c *check.C) {
 	// Verify that the admin wasn't deleted
 	s.verifyRoleCount(c, role.ID, 1)
 }
+
+func (s *ModelsSuite) TestBootstrapAdminEmails(c *check.C) {
+	adminRole, err := GetRoleBySlug(RoleAdmin)
+	c.Assert(err, check.Equals, nil)
+	userRole, err := GetRoleBySlug(RoleUser)
+	c.Assert(err, check.Equals, nil)
+	conf := &config.Config{SSO: &config.SSOConfig{
+		AdminEmails: []string{"first.admin@example.com"},
+	}}
+
+	// Nothing is bootstrapped while an admin exists
+	c.Assert(BootstrapAdminEmails(conf), check.Equals, nil)
+	_, err = GetUserByUsername("first.admin@example.com")
+	c.Assert(err, check.Equals, gorm.ErrRecordNotFound)
+
+	// Demote the default admin so that there are no admins
+	err = db.Model(&User{}).Where("id = ?", 1).Update("role_id", userRole.ID).Error
+	c.Assert(err, check.Equals, nil)
+	defer func() {
+		db.Model(&User{}).Where("id = ?", 1).Update("role_id", adminRole.ID)
+		db.Where("normalized_email = ?", "first.admin@example.com").Delete(&AuthorizedEmail{})
+	}()
+	c.Assert(BootstrapAdminEmails(conf), check.Equals, nil)
+	u, err := GetUserByUsername("first.admin@example.com")
+	c.Assert(err, check.Equals, nil)
+	c.Assert(u.Role.Slug, check.Equals, RoleAdmin)
+	result, err := NewEmailAuthorizationService().CheckEmailAuthorization("first.admin@example.com")
+	c.Assert(err, check.Equals, nil)
+	c.Assert(result.Authorized, check.Equals, true)
+	c.Assert(result.GetRole(), check.Equals, RoleAdmin)
+
+	// The first SSO login links to the bootstrapped admin
+	u, err = FindOrCreateOAuthUser("microsoft", "first-admin-oauth-id", "first.admin@example.com")
+	c.Assert(err, check.Equals, nil)
+	c.Assert(u.Role.Slug, check.Equals, RoleAdmin)
+
+	// Once an admin exists, further admin emails aren't bootstrapped
+	conf.SSO.AdminEmails = append(conf.SSO.AdminEmails, "second.admin@example.com")
+	c.Assert(BootstrapAdminEmails(conf), check.Equals, nil)
+	_, err = GetUserByUsername("second.admin@example.com")
+	c.Assert(err, check.Equals, gorm.ErrRecordNotFound)
+}
+
+func (s *ModelsSuite) TestLocalPasswordAllowed(c *check.C) {
+	local := User{Username: "local"}
+	c.Assert(local.LocalPasswordAllowed(), check.Equals, true)
+
+	managed := User{Username: "sso@example.com", OAuthProvider: "microsoft", SSOManaged: true}
+	c.Assert(managed.LocalPasswordAllowed(), check.Equals, false)
+
+	original := conf.SSO
+	defer func() { conf.SSO = original }()
+	conf.SSO = &config.SSOConfig{AllowSSOManagedPasswords: true}
+	c.Assert(managed.LocalPasswordAllowed(), check.Equals, true)
+}
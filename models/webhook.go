@@ -2,10 +2,18 @@ package models
 
 import (
 	"errors"
+	"net/http"
+	"regexp"
+	"time"
 
 	log "github.com/gophish/gophish/logger"
+	"github.com/gophish/gophish/webhook"
 )
 
+// MaxWebhookTimeoutSeconds is the longest a webhook may be configured to wait
+// for its endpoint to respond.
+const MaxWebhookTimeoutSeconds = 300
+
 // Webhook represents the webhook model
 type Webhook struct {
 	Id       int64  `json:"id" gorm:"column:id; primary_key:yes"`
@@ -13,6 +21,16 @@ type Webhook struct {
 	URL      string `json:"url"`
 	Secret   string `json:"secret"`
 	IsActive bool   `json:"is_active"`
+	// TimeoutSeconds is how long to wait for the endpoint to respond. If
+	// it's zero, the default timeout is used.
+	TimeoutSeconds int `json:"timeout_seconds"`
+	// SkipTLSVerify disables verifying the endpoint's TLS certificate
+	SkipTLSVerify bool `json:"skip_tls_verify"`
+	// SignatureHeader and SignatureAlgorithm are the HTTP header the
+	// signature is sent in and the HMAC algorithm it's made with, so that
+	// consumers expecting a different scheme can verify it.
+	SignatureHeader    string `json:"signature_header"`
+	SignatureAlgorithm string `json:"signature_algorithm"`
 }
 
 // ErrURLNotSpecified indicates there was no URL specified
@@ -21,6 +39,48 @@ var ErrURLNotSpecified = errors.New("URL can't be empty")
 // ErrNameNotSpecified indicates there was no name specified
 var ErrNameNotSpecified = errors.New("Name can't be empty")
 
+// ErrInvalidWebhookTimeout indicates the webhook timeout is out of range
+var ErrInvalidWebhookTimeout = errors.New("Timeout must be between 0 and 300 seconds")
+
+// ErrInvalidSignatureHeader indicates the signature header isn't a valid
+// HTTP header name
+var ErrInvalidSignatureHeader = errors.New("Signature header must be a valid HTTP header name")
+
+// ErrInvalidSignatureAlgorithm indicates the signature algorithm isn't
+// supported
+var ErrInvalidSignatureAlgorithm = errors.New("Signature algorithm must be one of sha1, sha256 or sha512")
+
+// ErrReservedSignatureHeader indicates the signature header is one which is
+// set by the HTTP client or has a meaning of its own
+var ErrReservedSignatureHeader = errors.New("Signature header can't be a standard HTTP header such as Content-Type or Host")
+
+// signatureHeaderRegex matches the header names signatures may be sent in
+var signatureHeaderRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*$`)
+
+// reservedSignatureHeaders are the headers signatures may not be sent in,
+// keyed by their canonical names. They're either hop-by-hop headers, set by
+// the HTTP client, or describe the request's content, so sending the
+// signature in them would break the request.
+var reservedSignatureHeaders = map[string]bool{
+	"Authorization":       true,
+	"Connection":          true,
+	"Content-Encoding":    true,
+	"Content-Length":      true,
+	"Content-Type":        true,
+	"Cookie":              true,
+	"Expect":              true,
+	"Host":                true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Proxy-Connection":    true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"User-Agent":          true,
+}
+
 // GetWebhooks returns the webhooks
 func GetWebhooks() ([]Webhook, error) {
 	whs := []Webhook{}
@@ -82,5 +142,36 @@ func (wh *Webhook) Validate() error {
 	if wh.Name == "" {
 		return ErrNameNotSpecified
 	}
+	if wh.TimeoutSeconds < 0 || wh.TimeoutSeconds > MaxWebhookTimeoutSeconds {
+		return ErrInvalidWebhookTimeout
+	}
+	// Webhooks which don't specify a signature scheme use the default one
+	if wh.SignatureHeader == "" {
+		wh.SignatureHeader = webhook.SignatureHeader
+	}
+	if wh.SignatureAlgorithm == "" {
+		wh.SignatureAlgorithm = webhook.DefaultSignatureAlgorithm
+	}
+	if !signatureHeaderRegex.MatchString(wh.SignatureHeader) {
+		return ErrInvalidSignatureHeader
+	}
+	if reservedSignatureHeaders[http.CanonicalHeaderKey(wh.SignatureHeader)] {
+		return ErrReservedSignatureHeader
+	}
+	if !webhook.IsSignatureAlgorithm(wh.SignatureAlgorithm) {
+		return ErrInvalidSignatureAlgorithm
+	}
 	return nil
 }
+
+// EndPoint returns the endpoint to send the webhook's events to.
+func (wh *Webhook) EndPoint() webhook.EndPoint {
+	return webhook.EndPoint{
+		URL:                wh.URL,
+		Secret:             wh.Secret,
+		Timeout:            time.Duration(wh.TimeoutSeconds) * time.Second,
+		SkipTLSVerify:      wh.SkipTLSVerify,
+		SignatureHeader:    wh.SignatureHeader,
+		SignatureAlgorithm: wh.SignatureAlgorithm,
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"github.com/gophish/gophish/webhook"
+	check "gopkg.in/check.v1"
+)
+
+func (s *ModelsSuite) TestWebhookSignatureDefaults(ch *check.C) {
+	wh := Webhook{Name: "SIEM", URL: "https://example.com/webhook"}
+	ch.Assert(PostWebhook(&wh), check.Equals, nil)
+	ch.Assert(wh.SignatureHeader, check.Equals, webhook.SignatureHeader)
+	ch.Assert(wh.SignatureAlgorithm, check.Equals, webhook.DefaultSignatureAlgorithm)
+}
+
+func (s *ModelsSuite) TestWebhookAlternateSignature(ch *check.C) {
+	wh := Webhook{
+		Name:               "SOAR",
+		URL:                "https://example.com/webhook",
+		SignatureHeader:    "X-Hub-Signature-256",
+		SignatureAlgorithm: "sha512",
+	}
+	ch.Assert(PostWebhook(&wh), check.Equals, nil)
+	got, err := GetWebhook(wh.Id)
+	ch.Assert(err, check.Equals, nil)
+	ep := got.EndPoint()
+	ch.Assert(ep.SignatureHeader, check.Equals, "X-Hub-Signature-256")
+	ch.Assert(ep.SignatureAlgorithm, check.Equals, "sha512")
+}
+
+func (s *ModelsSuite) TestWebhookInvalidSignature(ch *check.C) {
+	wh := Webhook{Name: "SIEM", URL: "https://example.com/webhook", SignatureHeader: "X Signature"}
+	ch.Assert(wh.Validate(), check.Equals, ErrInvalidSignatureHeader)
+	for _, header := range []string{"Content-Type", "host", "TRANSFER-ENCODING", "Connection"} {
+		wh = Webhook{Name: "SIEM", URL: "https://example.com/webhook", SignatureHeader: header}
+		ch.Assert(wh.Validate(), check.Equals, ErrReservedSignatureHeader)
+	}
+	wh = Webhook{Name: "SIEM", URL: "https://example.com/webhook", SignatureAlgorithm: "md5"}
+	ch.Assert(wh.Validate(), check.Equals, ErrInvalidSignatureAlgorithm)
+}
@@ -0,0 +1,106 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gophish/gophish/models"
+)
+
+// dateFormat is the format of the dates shown on a report.
+const dateFormat = "2006-01-02 15:04 MST"
+
+// formatDate returns the date as shown on a report, or a dash if it's unset.
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(dateFormat)
+}
+
+// percentage returns n as a percentage of the total.
+func percentage(n int64, total int64) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(n)*100/float64(total))
+}
+
+// recipientName returns the full name of a recipient, falling back to their
+// email address.
+func recipientName(first, last, email string) string {
+	name := strings.TrimSpace(first + " " + last)
+	if name == "" {
+		return email
+	}
+	return name
+}
+
+// CampaignPDF returns the PDF document for the campaign report, showing the
+// statistics funnel, the timeline highlights and the top clickers.
+func CampaignPDF(r models.CampaignReport) *Document {
+	d := NewDocument(fmt.Sprintf("Campaign Report: %s", r.Name))
+	d.Subject = "Phishing campaign results"
+	d.Author = "Gophish"
+
+	d.Heading(d.Title, TitleSize)
+	d.Text(fmt.Sprintf("Status: %s", r.Status))
+	d.Text(fmt.Sprintf("Created: %s", formatDate(r.CreatedDate)))
+	d.Text(fmt.Sprintf("Launched: %s", formatDate(r.LaunchDate)))
+	d.Text(fmt.Sprintf("Completed: %s", formatDate(r.CompletedDate)))
+	d.Text(fmt.Sprintf("Generated: %s", formatDate(d.CreationDate)))
+
+	d.Space(TextSize)
+	d.Heading("Results", HeadingSize)
+	funnel := []float64{3, 1, 1}
+	d.Row([]string{"Stage", "Recipients", "Of Total"}, funnel, true)
+	s := r.Stats
+	for _, stage := range []struct {
+		name  string
+		count int64
+	}{
+		{"Targeted", s.Total},
+		{models.EventSent, s.EmailsSent},
+		{models.EventOpened, s.OpenedEmail},
+		{models.EventClicked, s.ClickedLink},
+		{models.EventDataSubmit, s.SubmittedData},
+		{models.EventReported, s.EmailReported},
+		{models.EventAutoReply, s.AutoReplied},
+		{models.EventSendingError, s.Error},
+	} {
+		d.Row([]string{stage.name, fmt.Sprint(stage.count), percentage(stage.count, s.Total)}, funnel, false)
+	}
+
+	d.Space(TextSize)
+	d.Heading("Timeline Highlights", HeadingSize)
+	timeline := []float64{2, 2, 3}
+	if len(r.Highlights) == 0 {
+		d.Text("No events have been recorded.")
+	} else {
+		d.Row([]string{"Time", "Event", "Recipient"}, timeline, true)
+		for _, e := range r.Highlights {
+			d.Row([]string{formatDate(e.Time), e.Message, e.Email}, timeline, false)
+		}
+	}
+
+	d.Space(TextSize)
+	d.Heading("Top Clickers", HeadingSize)
+	clickers := []float64{2, 3, 2, 1}
+	if len(r.TopClickers) == 0 {
+		d.Text("No recipients have clicked the link.")
+	} else {
+		d.Row([]string{"Name", "Email", "Status", "Clicks"}, clickers, true)
+		for _, c := range r.TopClickers {
+			d.Row([]string{recipientName(c.FirstName, c.LastName, c.Email), c.Email, c.Status, fmt.Sprint(c.Clicks)}, clickers, false)
+		}
+	}
+	return d
+}
+
+// WriteCampaignPDF writes the campaign report out as a PDF file.
+func WriteCampaignPDF(w io.Writer, r models.CampaignReport) error {
+	_, err := CampaignPDF(r).WriteTo(w)
+	return err
+}
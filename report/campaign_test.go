@@ -0,0 +1,91 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gophish/gophish/models"
+)
+
+func newTestReport() models.CampaignReport {
+	launch := time.Date(2025, 12, 1, 9, 0, 0, 0, time.UTC)
+	r := models.CampaignReport{
+		Id:         1,
+		Name:       "Q4 Payroll (Finance)",
+		Status:     models.CampaignInProgress,
+		LaunchDate: launch,
+		Stats:      models.CampaignStats{Total: 4, EmailsSent: 4, OpenedEmail: 3, ClickedLink: 2, SubmittedData: 1},
+		Highlights: []models.Event{
+			{Email: "jane@example.com", Time: launch, Message: models.EventSent},
+			{Email: "jane@example.com", Time: launch.Add(time.Hour), Message: models.EventClicked},
+		},
+	}
+	// Enough clickers to run onto a second page
+	for i := 0; i < 60; i++ {
+		r.TopClickers = append(r.TopClickers, models.ReportClicker{
+			Email:     fmt.Sprintf("user%d@example.com", i),
+			FirstName: "José",
+			Status:    models.EventClicked,
+			Clicks:    1,
+		})
+	}
+	return r
+}
+
+func TestCampaignPDF(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := WriteCampaignPDF(buf, newTestReport())
+	if err != nil {
+		t.Fatalf("error writing campaign report: %v", err)
+	}
+	pdf := buf.String()
+	if !strings.HasPrefix(pdf, "%PDF-") {
+		t.Fatalf("report doesn't start with a PDF header: %q", pdf[:20])
+	}
+	if !strings.HasSuffix(strings.TrimSpace(pdf), "%%EOF") {
+		t.Fatalf("report doesn't end with a PDF trailer")
+	}
+}
+
+func TestCampaignPDFContent(t *testing.T) {
+	d := CampaignPDF(newTestReport())
+	d.pdf.SetCompression(false)
+	buf := &bytes.Buffer{}
+	_, err := d.WriteTo(buf)
+	if err != nil {
+		t.Fatalf("error writing campaign report: %v", err)
+	}
+	if d.pdf.PageCount() != 2 {
+		t.Fatalf("unexpected page count. expected 2 got %d", d.pdf.PageCount())
+	}
+	pdf := buf.String()
+	for _, expected := range []string{
+		"/Title ",
+		"/Producer (Gophish)",
+		`(Campaign Report: Q4 Payroll \(Finance\))`,
+		"(Jos\xe9)",
+		"(user59@example.com)",
+	} {
+		if !strings.Contains(pdf, expected) {
+			t.Fatalf("report doesn't contain %q", expected)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	d := NewDocument("Test")
+	d.font(TextSize, false)
+	if s := d.truncate("abcdefghij", 1000); s != "abcdefghij" {
+		t.Fatalf("unexpected truncated text %q", s)
+	}
+	width := d.pdf.GetStringWidth("abc" + ellipsis)
+	if s := d.truncate("abcdefghij", width); s != "abc..." {
+		t.Fatalf("unexpected truncated text %q", s)
+	}
+	if s := d.translate("a\u00e9\u4e16"); s != "a\xe9." {
+		t.Fatalf("unexpected translated text %q", s)
+	}
+}
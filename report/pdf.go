@@ -0,0 +1,147 @@
+package report
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// The layout of a page, in points. Pages are A4.
+const (
+	pageMargin = 50.0
+	fontFamily = "Helvetica"
+)
+
+// Font sizes used in a document
+const (
+	TitleSize   = 18.0
+	HeadingSize = 13.0
+	TextSize    = 10.0
+)
+
+// ellipsis marks text which was truncated to fit its column
+const ellipsis = "..."
+
+// Document is a PDF document made up of lines of text laid out from the top
+// of each page, using the standard Helvetica fonts. A new page is started
+// whenever the current one is full.
+type Document struct {
+	Title        string
+	Author       string
+	Subject      string
+	CreationDate time.Time
+
+	pdf *fpdf.Fpdf
+	// translate converts UTF-8 text to the encoding used by the standard
+	// fonts. Characters the fonts can't show are replaced.
+	translate func(string) string
+}
+
+// NewDocument returns an empty document with the given title.
+func NewDocument(title string) *Document {
+	pdf := fpdf.New("P", "pt", "A4", "")
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, pageMargin)
+	return &Document{
+		Title:        title,
+		CreationDate: time.Now().UTC(),
+		pdf:          pdf,
+		translate:    pdf.UnicodeTranslatorFromDescriptor(""),
+	}
+}
+
+// contentWidth returns the width of the page between the margins.
+func (d *Document) contentWidth() float64 {
+	width, _ := d.pdf.GetPageSize()
+	left, _, right, _ := d.pdf.GetMargins()
+	return width - left - right
+}
+
+// font sets the font used by the text which follows, starting the first page
+// if it hasn't been already.
+func (d *Document) font(size float64, bold bool) {
+	if d.pdf.PageCount() == 0 {
+		d.pdf.AddPage()
+	}
+	style := ""
+	if bold {
+		style = "B"
+	}
+	d.pdf.SetFont(fontFamily, style, size)
+}
+
+// truncate shortens translated text which doesn't fit in the given width in
+// the current font, marking it with an ellipsis.
+func (d *Document) truncate(s string, width float64) string {
+	if d.pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	for i := len(s) - 1; i > 0; i-- {
+		if d.pdf.GetStringWidth(s[:i]+ellipsis) <= width {
+			return s[:i] + ellipsis
+		}
+	}
+	return ""
+}
+
+// Heading writes a bold heading in the given font size.
+func (d *Document) Heading(text string, size float64) {
+	d.font(size, true)
+	width := d.contentWidth()
+	d.pdf.Ln(size * 0.5)
+	d.pdf.CellFormat(width, size*1.5, d.truncate(d.translate(text), width), "", 1, "L", false, 0, "")
+}
+
+// Text writes a paragraph, wrapping it to the width of the page.
+func (d *Document) Text(text string) {
+	d.font(TextSize, false)
+	d.pdf.MultiCell(d.contentWidth(), TextSize*1.5, d.translate(text), "", "L", false)
+}
+
+// Row writes a row of a table. Each column is given a share of the page
+// width in proportion to its weight, and values which don't fit their column
+// are truncated.
+func (d *Document) Row(columns []string, weights []float64, bold bool) {
+	d.font(TextSize, bold)
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	contentWidth := d.contentWidth()
+	height := TextSize * 1.6
+	for i, c := range columns {
+		width := contentWidth / float64(len(columns))
+		if i < len(weights) && total > 0 {
+			width = contentWidth * weights[i] / total
+		}
+		d.pdf.CellFormat(width, height, d.truncate(d.translate(c), width-4), "", 0, "L", false, 0, "")
+	}
+	d.pdf.Ln(height)
+}
+
+// Space moves down the page by the given number of points.
+func (d *Document) Space(height float64) {
+	d.font(TextSize, false)
+	d.pdf.Ln(height)
+}
+
+// WriteTo writes the document out as a PDF file.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if d.pdf.PageCount() == 0 {
+		d.pdf.AddPage()
+	}
+	d.pdf.SetTitle(d.Title, true)
+	d.pdf.SetAuthor(d.Author, true)
+	d.pdf.SetSubject(d.Subject, true)
+	d.pdf.SetProducer("Gophish", false)
+	d.pdf.SetCreationDate(d.CreationDate)
+	buf := &bytes.Buffer{}
+	err := d.pdf.Output(buf)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
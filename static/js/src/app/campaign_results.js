@@ -49,6 +49,13 @@ var statuses = {
         icon: "fa-bullhorn",
         point: "ct-point-reported"
     },
+    //not a status, but is used for the campaign timeline and user timeline
+    "Auto Reply Received": {
+        color: "#95a5a6",
+        label: "label-default",
+        icon: "fa-reply",
+        point: "ct-point-error"
+    },
     "Error": {
         color: "#6c7a89",
         label: "label-default",
@@ -91,9 +98,27 @@ var statuses = {
         icon: "fa-clock-o",
         point: "ct-point-sending"
     },
+    "Cancelled": {
+        color: "#6c7a89",
+        label: "label-default",
+        icon: "fa-ban",
+        point: "ct-point-error"
+    },
+    //not a status, but is used for the campaign timeline and user timeline
+    "Email Cancelled": {
+        color: "#6c7a89",
+        label: "label-default",
+        icon: "fa-ban",
+        point: "ct-point-error"
+    },
     "Campaign Created": {
         label: "label-success",
         icon: "fa-rocket"
+    },
+    "Raw Data Capture Enabled": {
+        color: "#ffa500",
+        label: "label-warning",
+        icon: "fa-unlock"
     }
 }
 
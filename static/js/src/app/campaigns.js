@@ -149,6 +149,9 @@ function launch() {
                     name: $("#page").select2("data")[0].text
                 },
                 email_type: $("#profile").val(),
+                from_name: $("#from_name").val(),
+                tracking_params: $("#tracking_params").val(),
+                capture_raw_data: $("#capture_raw_data").prop("checked"),
                 launch_date: moment($("#launch_date").val(), "MMMM Do YYYY, h:mm a").utc().format(),
                 send_by_date: send_by_date || null,
                 groups: groups,
@@ -260,7 +263,8 @@ function validateRateLimit(name, template, page, profile, users, launchDate, sen
         data: JSON.stringify({
             launch_date: launchDateISO,
             send_by_date: sendByDateISO || null,
-            group_ids: groupIDs
+            group_ids: groupIDs,
+            email_type: profile
         }),
         contentType: "application/json",
         dataType: "json",
@@ -436,6 +440,24 @@ function sendTestEmail() {
     }
 }
 
+// Sends the last test email again, re-rendering its current template
+function resendTestEmail() {
+    $("#sendTestEmailModal\\.flashes").empty()
+    var btnHtml = $("#resendTestModalSubmit").html()
+    $("#resendTestModalSubmit").html('<i class="fa fa-spinner fa-spin"></i> Sending')
+    api.resend_test_email()
+        .success(function (data) {
+            $("#sendTestEmailModal\\.flashes").empty().append("<div style=\"text-align:center\" class=\"alert alert-success\">\
+            <i class=\"fa fa-check-circle\"></i> Email Sent!</div>")
+            $("#resendTestModalSubmit").html(btnHtml)
+        })
+        .error(function (data) {
+            $("#sendTestEmailModal\\.flashes").empty().append("<div style=\"text-align:center\" class=\"alert alert-danger\">\
+            <i class=\"fa fa-exclamation-circle\"></i> " + escapeHtml(data.responseJSON.message) + "</div>")
+            $("#resendTestModalSubmit").html(btnHtml)
+        })
+}
+
 function dismiss() {
     $("#modal\\.flashes").empty();
     $("#name").val("");
@@ -443,6 +465,9 @@ function dismiss() {
     $("#page").val("").change();
     $("#url").val("");
     $("#profile").val("").change();
+    $("#from_name").val("");
+    $("#tracking_params").val("");
+    $("#capture_raw_data").prop("checked", false);
     $("#users").val("").change();
     $("#modal").modal('hide');
 }
@@ -619,6 +644,9 @@ function copy(idx) {
                 $("#profile").trigger("change.select2")
             }
             $("#url").val(campaign.url)
+            $("#from_name").val(campaign.from_name || "")
+            $("#tracking_params").val(campaign.tracking_params || "")
+            $("#capture_raw_data").prop("checked", campaign.capture_raw_data || false)
         })
         .error(function (data) {
             $("#modal\\.flashes").empty().append("<div style=\"text-align:center\" class=\"alert alert-danger\">\
@@ -869,19 +897,21 @@ function callAutopilotAgent1(userPrompt) {
             message += '<small class="text-muted">' + escapeHtml(data.reasoning) + '</small>';
 
             appendAIMessage(message);
-            showTypingIndicator();
 
             // Step 2: Call AI Workflow 2 (Target Filtering)
-            setTimeout(function() {
-                callAutopilotAgent2(userPrompt);
-            }, 500);
+            confirmAutopilotSuggestion(data, 'email type "' + data.email_type_name + '"', function() {
+                showTypingIndicator();
+                setTimeout(function() {
+                    callAutopilotAgent2(userPrompt);
+                }, 500);
+            });
         } else {
             appendAIMessage('<span class="text-danger">✗ Failed to identify email type: ' + escapeHtml(data.error) + '</span>');
         }
     })
     .fail(function(xhr, status, error) {
         hideTypingIndicator();
-        var errorMsg = xhr.responseJSON && xhr.responseJSON.error ? xhr.responseJSON.error : error;
+        var errorMsg = xhr.responseJSON && (xhr.responseJSON.error || xhr.responseJSON.message) || error;
         appendAIMessage('<span class="text-danger">✗ Error calling AI Workflow 1: ' + escapeHtml(errorMsg) + '</span>');
     });
 }
@@ -927,7 +957,7 @@ function callAutopilotAgent2(userPrompt) {
     })
     .fail(function(xhr, status, error) {
         hideTypingIndicator();
-        var errorMsg = xhr.responseJSON && xhr.responseJSON.error ? xhr.responseJSON.error : error;
+        var errorMsg = xhr.responseJSON && (xhr.responseJSON.error || xhr.responseJSON.message) || error;
         appendAIMessage('<span class="text-danger">✗ Error calling AI Workflow 2: ' + escapeHtml(errorMsg) + '</span>');
     });
 }
@@ -971,20 +1001,49 @@ function callAutopilotAgent3(userPrompt, emailType) {
             appendAIMessage(message);
 
             // Step 4: Show campaign preview
-            setTimeout(function() {
-                showAutopilotCampaignPreview();
-            }, 500);
+            confirmAutopilotSuggestion(data, 'template "' + data.template_name + '"', function() {
+                setTimeout(function() {
+                    showAutopilotCampaignPreview();
+                }, 500);
+            });
         } else {
             appendAIMessage('<span class="text-danger">✗ Failed to generate template/page: ' + escapeHtml(data.error) + '</span>');
         }
     })
     .fail(function(xhr, status, error) {
         hideTypingIndicator();
-        var errorMsg = xhr.responseJSON && xhr.responseJSON.error ? xhr.responseJSON.error : error;
+        var errorMsg = xhr.responseJSON && (xhr.responseJSON.error || xhr.responseJSON.message) || error;
         appendAIMessage('<span class="text-danger">✗ Error calling AI Workflow 3: ' + escapeHtml(errorMsg) + '</span>');
     });
 }
 
+// Low confidence autopilot results are only suggestions, so ask the user to
+// confirm them before continuing
+function confirmAutopilotSuggestion(data, suggestion, proceed) {
+    if (!data.requires_confirmation) {
+        proceed();
+        return;
+    }
+    Swal.fire({
+        title: "Use this suggestion?",
+        text: "Autopilot is only " + data.confidence + "% confident in the " + suggestion +
+            " (the minimum to apply it automatically is " + data.min_confidence + "%).",
+        type: "question",
+        animation: false,
+        showCancelButton: true,
+        confirmButtonText: "Use it",
+        cancelButtonText: "Stop",
+        confirmButtonColor: "#428bca",
+        reverseButtons: true
+    }).then(function (result) {
+        if (result.value) {
+            proceed();
+        } else {
+            appendAIMessage('<span class="text-warning">Stopped. Try describing the campaign in more detail.</span>');
+        }
+    });
+}
+
 // Helper function to append AI message (supports HTML content)
 function appendAIMessage(htmlContent) {
     var messageHTML = `
@@ -1220,7 +1279,10 @@ $(document).ready(function () {
                     <i class='fa fa-trash-o'></i>\
                     </button></div>"
                     ]
-                    if (campaign.status == 'Completed') {
+                    // When completed campaigns are archived automatically,
+                    // they stay with the active campaigns until they are
+                    var archived = data.auto_archive ? campaign.archived : campaign.status == 'Completed'
+                    if (archived) {
                         rows['archived'].push(row)
                     } else {
                         rows['active'].push(row)
@@ -289,6 +289,10 @@ var api = {
     send_test_email: function (req) {
         return query("/util/send_test_email", "POST", req, true)
     },
+    // resend_test_email sends the last test email again
+    resend_test_email: function () {
+        return query("/util/resend_test_email", "POST", {}, true)
+    },
     reset: function () {
         return query("/reset", "POST", {}, true)
     },
@@ -20,6 +20,7 @@ function save(idx) {
     }
     template.name = $("#name").val()
     template.subject = $("#subject").val()
+    template.preheader = $("#preheader").val()
     template.envelope_sender = $("#envelope-sender").val()
     template.html = CKEDITOR.instances["html_editor"].getData();
     // Fix the URL Scheme added by CKEditor (until we can remove it from the plugin)
@@ -74,6 +75,7 @@ function dismiss() {
     $("#attachmentsTable").dataTable().DataTable().clear().draw()
     $("#name").val("")
     $("#subject").val("")
+    $("#preheader").val("")
     $("#text_editor").val("")
     $("#html_editor").val("")
     $("#modal").modal('hide')
@@ -191,6 +193,7 @@ function edit(idx) {
         template = templates[idx]
         $("#name").val(template.name)
         $("#subject").val(template.subject)
+        $("#preheader").val(template.preheader)
         $("#envelope-sender").val(template.envelope_sender)
         $("#html_editor").val(template.html)
         $("#text_editor").val(template.text)
@@ -252,6 +255,7 @@ function copy(idx) {
     template = templates[idx]
     $("#name").val("Copy of " + template.name)
     $("#subject").val(template.subject)
+    $("#preheader").val(template.preheader)
     $("#envelope-sender").val(template.envelope_sender)
     $("#html_editor").val(template.html)
     $("#text_editor").val(template.text)
@@ -279,6 +283,22 @@ function copy(idx) {
     }
 }
 
+// loadEmailFile fills in the email content with the source of a saved
+// .eml file, including its attachments and inline images
+function loadEmailFile(files) {
+    if (!files || !files.length) {
+        return
+    }
+    var reader = new FileReader();
+    reader.onload = function (e) {
+        $("#email_content").val(reader.result)
+    }
+    reader.onerror = function (e) {
+        modalError("Unable to read the email file")
+    }
+    reader.readAsText(files[0])
+}
+
 function importEmail() {
     raw = $("#email_content").val()
     convert_links = $("#convert_links_checkbox").prop("checked")
@@ -293,6 +313,19 @@ function importEmail() {
                 $("#text_editor").val(data.text)
                 $("#html_editor").val(data.html)
                 $("#subject").val(data.subject)
+                // Add the email's attachments and inline images
+                var attachmentsTable = $("#attachmentsTable").DataTable()
+                $.each(data.attachments || [], function (i, file) {
+                    var icon = icons[file.type] || "fa-file-o"
+                    attachmentsTable.row.add([
+                        '<i class="fa ' + icon + '"></i>',
+                        escapeHtml(file.name),
+                        '<span class="remove-row"><i class="fa fa-trash-o"></i></span>',
+                        file.content,
+                        file.type || "application/octet-stream"
+                    ])
+                })
+                attachmentsTable.draw()
                 // If the HTML is provided, let's open that view in the editor
                 if (data.html) {
                     CKEDITOR.instances["html_editor"].setMode('wysiwyg')
@@ -400,6 +433,7 @@ $(document).ready(function () {
     });
     $("#importEmailModal").on('hidden.bs.modal', function (event) {
         $("#email_content").val("")
+        $("#email_file").val("")
     })
     CKEDITOR.on('dialogDefinition', function (ev) {
         // Take the dialog name and its definition from the event data.
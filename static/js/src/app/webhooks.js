@@ -5,6 +5,10 @@ const dismiss = () => {
     $("#url").val("");
     $("#secret").val("");
     $("#is_active").prop("checked", false);
+    $("#timeout_seconds").val("");
+    $("#skip_tls_verify").prop("checked", false);
+    $("#signature_header").val("");
+    $("#signature_algorithm").val("sha256");
     $("#flashes").empty();
 };
 
@@ -14,6 +18,10 @@ const saveWebhook = (id) => {
         url: $("#url").val(),
         secret: $("#secret").val(),
         is_active: $("#is_active").is(":checked"),
+        timeout_seconds: parseInt($("#timeout_seconds").val()) || 0,
+        skip_tls_verify: $("#skip_tls_verify").is(":checked"),
+        signature_header: $("#signature_header").val(),
+        signature_algorithm: $("#signature_algorithm").val(),
     };
     if (id != -1) {
         wh.id = parseInt(id);
@@ -95,6 +103,10 @@ const editWebhook = (id) => {
               $("#url").val(wh.url);
               $("#secret").val(wh.secret);
               $("#is_active").prop("checked", wh.is_active);
+              $("#timeout_seconds").val(wh.timeout_seconds || "");
+              $("#skip_tls_verify").prop("checked", wh.skip_tls_verify);
+              $("#signature_header").val(wh.signature_header);
+              $("#signature_algorithm").val(wh.signature_algorithm || "sha256");
           })
           .error(function () {
               errorFlash("Error fetching webhook")
@@ -16,6 +16,7 @@ import (
 	"net/mail"
 	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	log "github.com/gophish/gophish/logger"
@@ -95,10 +96,10 @@ func ParseCSV(r *http.Request) ([]models.Target, error) {
 				break
 			}
 			if fi != -1 && len(record) > fi {
-				fn = record[fi]
+				fn = models.NormalizeRecipientField(record[fi])
 			}
 			if li != -1 && len(record) > li {
-				ln = record[li]
+				ln = models.NormalizeRecipientField(record[li])
 			}
 			if ei != -1 && len(record) > ei {
 				csvEmail, err := mail.ParseAddress(record[ei])
@@ -108,7 +109,7 @@ func ParseCSV(r *http.Request) ([]models.Target, error) {
 				ea = csvEmail.Address
 			}
 			if pi != -1 && len(record) > pi {
-				ps = record[pi]
+				ps = models.NormalizeRecipientField(record[pi])
 			}
 			t := models.Target{
 				BaseRecipient: models.BaseRecipient{
@@ -124,6 +125,120 @@ func ParseCSV(r *http.Request) ([]models.Target, error) {
 	return ts, nil
 }
 
+// CSVRowError describes a row of a target CSV which won't be imported.
+type CSVRowError struct {
+	Line   int    `json:"line"`
+	Email  string `json:"email"`
+	Reason string `json:"reason"`
+}
+
+// CSVValidationReport summarizes the result of validating a target CSV.
+type CSVValidationReport struct {
+	Valid      int           `json:"valid"`
+	Invalid    []CSVRowError `json:"invalid"`
+	Duplicates []CSVRowError `json:"duplicates"`
+}
+
+// ValidateCSV parses a user provided target CSV in the same way as ParseCSV,
+// but rather than returning the targets, reports the rows which have an
+// invalid email address or duplicate an earlier row. Line numbers refer to
+// the line within the uploaded file.
+func ValidateCSV(r *http.Request) (CSVValidationReport, error) {
+	report := CSVValidationReport{
+		Invalid:    []CSVRowError{},
+		Duplicates: []CSVRowError{},
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return report, err
+	}
+	service := models.NewEmailAuthorizationService()
+	seen := map[string]int{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		// Skip the "submit" part
+		if part.FileName() == "" {
+			continue
+		}
+		defer part.Close()
+		reader := csv.NewReader(part)
+		reader.TrimLeadingSpace = true
+		reader.FieldsPerRecord = -1
+		record, err := reader.Read()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		ei := -1
+		for i, v := range record {
+			if emailRegex.MatchString(v) {
+				ei = i
+				break
+			}
+		}
+		if ei == -1 {
+			return report, fmt.Errorf("%s: no email column found", part.FileName())
+		}
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if perr, ok := err.(*csv.ParseError); ok {
+				report.Invalid = append(report.Invalid, CSVRowError{
+					Line:   perr.Line,
+					Reason: perr.Err.Error(),
+				})
+				continue
+			}
+			if err != nil {
+				return report, err
+			}
+			line, _ := reader.FieldPos(0)
+			if len(record) <= ei || strings.TrimSpace(record[ei]) == "" {
+				report.Invalid = append(report.Invalid, CSVRowError{
+					Line:   line,
+					Reason: "email cannot be empty",
+				})
+				continue
+			}
+			raw := strings.TrimSpace(record[ei])
+			address, err := mail.ParseAddress(raw)
+			if err == nil {
+				err = service.ValidateEmailFormat(address.Address)
+			}
+			if err != nil {
+				report.Invalid = append(report.Invalid, CSVRowError{
+					Line:   line,
+					Email:  raw,
+					Reason: err.Error(),
+				})
+				continue
+			}
+			normalized := models.RecipientDedupKey(service.NormalizeEmail(address.Address))
+			if first, ok := seen[normalized]; ok {
+				report.Duplicates = append(report.Duplicates, CSVRowError{
+					Line:   line,
+					Email:  address.Address,
+					Reason: fmt.Sprintf("duplicate of line %d", first),
+				})
+				continue
+			}
+			seen[normalized] = line
+			report.Valid++
+		}
+	}
+	return report, nil
+}
+
 // CheckAndCreateSSL is a helper to setup self-signed certificates for the administrative interface.
 func CheckAndCreateSSL(cp string, kp string) error {
 	// Check whether there is an existing SSL certificate and/or key, and if so, abort execution of this function
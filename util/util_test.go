@@ -60,3 +60,71 @@ func TestParseCSVEmail(t *testing.T) {
 		t.Fatalf("Incorrect targets received. Expected: %#v\nGot: %#v", expected, got)
 	}
 }
+
+func TestParseCSVStripsTemplateSyntax(t *testing.T) {
+	expected := models.Target{
+		BaseRecipient: models.BaseRecipient{
+			FirstName: "John.URL",
+			LastName:  "O'Brien",
+			Email:     "johndoe@example.com",
+		},
+	}
+
+	csvPayload := "{{John.URL}}, O'Brien ,johndoe@example.com"
+	r, err := buildCSVRequest(csvPayload)
+	if err != nil {
+		t.Fatalf("error building CSV request: %v", err)
+	}
+
+	got, err := ParseCSV(r)
+	if err != nil {
+		t.Fatalf("error parsing CSV: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("invalid number of results received from CSV. expected %d got %d", 1, len(got))
+	}
+	if !reflect.DeepEqual(expected, got[0]) {
+		t.Fatalf("Incorrect targets received. Expected: %#v\nGot: %#v", expected, got)
+	}
+}
+
+func TestValidateCSV(t *testing.T) {
+	csvPayload := "John,Doe,johndoe@example.com\n" +
+		"Jane,Doe,not-an-email\n" +
+		"Bob,Smith,\n" +
+		"Johnny,Doe,<JohnDoe@example.com>\n" +
+		"Alice,Jones,alice@example.com\n"
+	r, err := buildCSVRequest(csvPayload)
+	if err != nil {
+		t.Fatalf("error building CSV request: %v", err)
+	}
+
+	got, err := ValidateCSV(r)
+	if err != nil {
+		t.Fatalf("error validating CSV: %v", err)
+	}
+	if got.Valid != 2 {
+		t.Fatalf("invalid number of valid rows. expected %d got %d", 2, got.Valid)
+	}
+	expectedInvalid := []int{3, 4}
+	if len(got.Invalid) != len(expectedInvalid) {
+		t.Fatalf("invalid number of invalid rows. expected %d got %d", len(expectedInvalid), len(got.Invalid))
+	}
+	for i, line := range expectedInvalid {
+		if got.Invalid[i].Line != line {
+			t.Fatalf("unexpected line for invalid row. expected %d got %d", line, got.Invalid[i].Line)
+		}
+		if got.Invalid[i].Reason == "" {
+			t.Fatalf("expected a reason for invalid row on line %d", line)
+		}
+	}
+	if len(got.Duplicates) != 1 {
+		t.Fatalf("invalid number of duplicate rows. expected %d got %d", 1, len(got.Duplicates))
+	}
+	if got.Duplicates[0].Line != 5 {
+		t.Fatalf("unexpected line for duplicate row. expected %d got %d", 5, got.Duplicates[0].Line)
+	}
+	if got.Duplicates[0].Reason != "duplicate of line 2" {
+		t.Fatalf("unexpected reason for duplicate row: %s", got.Duplicates[0].Reason)
+	}
+}
@@ -3,12 +3,17 @@ package webhook
 import (
 	"bytes"
 	"crypto/hmac"
+	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"net/http"
+	"sort"
 	"time"
 
 	log "github.com/gophish/gophish/logger"
@@ -31,8 +36,43 @@ const (
 	// Sha256Prefix is the prefix that specifies the hashing algorithm used
 	// for the signature
 	Sha256Prefix = "sha256"
+
+	// DefaultSignatureAlgorithm is the HMAC algorithm used to sign webhooks
+	// which don't specify one
+	DefaultSignatureAlgorithm = Sha256Prefix
 )
 
+// ErrUnsupportedSignatureAlgorithm is returned when signing with an unknown
+// HMAC algorithm
+var ErrUnsupportedSignatureAlgorithm = errors.New("unsupported signature algorithm")
+
+// signatureAlgorithms maps the names of the HMAC algorithms webhooks can be
+// signed with to their hash functions. The name is also the prefix of the
+// signature.
+var signatureAlgorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// SignatureAlgorithms returns the names of the HMAC algorithms webhooks can
+// be signed with.
+func SignatureAlgorithms() []string {
+	names := make([]string, 0, len(signatureAlgorithms))
+	for name := range signatureAlgorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsSignatureAlgorithm returns true if webhooks can be signed with the HMAC
+// algorithm.
+func IsSignatureAlgorithm(name string) bool {
+	_, ok := signatureAlgorithms[name]
+	return ok
+}
+
 // Sender represents a type which can send webhooks to an EndPoint
 type Sender interface {
 	Send(endPoint EndPoint, data interface{}) error
@@ -40,6 +80,8 @@ type Sender interface {
 
 type defaultSender struct {
 	client *http.Client
+	// insecureTransport is used for endpoints which skip TLS verification
+	insecureTransport *http.Transport
 }
 
 var senderInstance = &defaultSender{
@@ -49,11 +91,24 @@ var senderInstance = &defaultSender{
 			return http.ErrUseLastResponse
 		},
 	},
+	insecureTransport: insecureTransport(http.DefaultTransport.(*http.Transport)),
 }
 
 // SetTransport sets the underlying transport for the default webhook client.
 func SetTransport(tr *http.Transport) {
 	senderInstance.client.Transport = tr
+	senderInstance.insecureTransport = insecureTransport(tr)
+}
+
+// insecureTransport returns a copy of the transport which doesn't verify TLS
+// certificates.
+func insecureTransport(tr *http.Transport) *http.Transport {
+	insecure := tr.Clone()
+	if insecure.TLSClientConfig == nil {
+		insecure.TLSClientConfig = &tls.Config{}
+	}
+	insecure.TLSClientConfig.InsecureSkipVerify = true
+	return insecure
 }
 
 // EndPoint represents a URL to send the webhook to, as well as a secret used
@@ -61,6 +116,34 @@ func SetTransport(tr *http.Transport) {
 type EndPoint struct {
 	URL    string
 	Secret string
+	// Timeout is how long to wait for the endpoint to respond. If it's zero,
+	// DefaultTimeoutSeconds is used.
+	Timeout time.Duration
+	// SkipTLSVerify disables verifying the endpoint's TLS certificate, such
+	// as for internal endpoints with self-signed certificates.
+	SkipTLSVerify bool
+	// SignatureHeader is the name of the HTTP header the signature is sent
+	// in. If it's empty, SignatureHeader is used.
+	SignatureHeader string
+	// SignatureAlgorithm is the HMAC algorithm the payload is signed with. If
+	// it's empty, DefaultSignatureAlgorithm is used.
+	SignatureAlgorithm string
+}
+
+// signatureHeader returns the name of the header to send the signature in.
+func (e EndPoint) signatureHeader() string {
+	if e.SignatureHeader == "" {
+		return SignatureHeader
+	}
+	return e.SignatureHeader
+}
+
+// signatureAlgorithm returns the HMAC algorithm to sign the payload with.
+func (e EndPoint) signatureAlgorithm() string {
+	if e.SignatureAlgorithm == "" {
+		return DefaultSignatureAlgorithm
+	}
+	return e.SignatureAlgorithm
 }
 
 // Send sends data to a single EndPoint
@@ -68,7 +151,8 @@ func Send(endPoint EndPoint, data interface{}) error {
 	return senderInstance.Send(endPoint, data)
 }
 
-// SendAll sends data to multiple EndPoints
+// SendAll sends data to multiple EndPoints. Each EndPoint is sent to
+// separately, so a slow EndPoint doesn't hold up the others.
 func SendAll(endPoints []EndPoint, data interface{}) {
 	for _, e := range endPoints {
 		go func(e EndPoint) {
@@ -90,14 +174,15 @@ func (ds defaultSender) Send(endPoint EndPoint, data interface{}) error {
 		log.Error(err)
 		return err
 	}
-	signat, err := sign(endPoint.Secret, jsonData)
+	algorithm := endPoint.signatureAlgorithm()
+	signat, err := signWith(algorithm, endPoint.Secret, jsonData)
 	if err != nil {
 		log.Error(err)
 		return err
 	}
-	req.Header.Set(SignatureHeader, fmt.Sprintf("%s=%s", Sha256Prefix, signat))
+	req.Header.Set(endPoint.signatureHeader(), fmt.Sprintf("%s=%s", algorithm, signat))
 	req.Header.Set("Content-Type", "application/json")
-	resp, err := ds.client.Do(req)
+	resp, err := ds.clientFor(endPoint).Do(req)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -112,8 +197,33 @@ func (ds defaultSender) Send(endPoint EndPoint, data interface{}) error {
 	return nil
 }
 
+// clientFor returns the client to send to the EndPoint with, applying its
+// timeout and TLS verification options.
+func (ds defaultSender) clientFor(endPoint EndPoint) *http.Client {
+	if endPoint.Timeout == 0 && !endPoint.SkipTLSVerify {
+		return ds.client
+	}
+	client := *ds.client
+	if endPoint.Timeout > 0 {
+		client.Timeout = endPoint.Timeout
+	}
+	if endPoint.SkipTLSVerify {
+		client.Transport = ds.insecureTransport
+	}
+	return &client
+}
+
 func sign(secret string, data []byte) (string, error) {
-	hash1 := hmac.New(sha256.New, []byte(secret))
+	return signWith(DefaultSignatureAlgorithm, secret, data)
+}
+
+// signWith returns the hex encoded HMAC of the data using the algorithm.
+func signWith(algorithm string, secret string, data []byte) (string, error) {
+	newHash, ok := signatureAlgorithms[algorithm]
+	if !ok {
+		return "", ErrUnsupportedSignatureAlgorithm
+	}
+	hash1 := hmac.New(newHash, []byte(secret))
 	_, err := hash1.Write(data)
 	if err != nil {
 		return "", err
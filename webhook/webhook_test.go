@@ -1,6 +1,9 @@
 package webhook
 
 import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -9,6 +12,7 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type mockSender struct {
@@ -101,3 +105,103 @@ func TestSignature(t *testing.T) {
 		t.Fatalf("invalid signature received. expected %s got %s", expected, got)
 	}
 }
+
+func TestSendTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	start := time.Now()
+	err := Send(EndPoint{URL: ts.URL, Timeout: 100 * time.Millisecond}, map[string]string{})
+	if err == nil {
+		t.Fatalf("expected an error sending to a slow endpoint")
+	}
+	if elapsed := time.Since(start); elapsed > DefaultTimeoutSeconds*time.Second/2 {
+		t.Fatalf("endpoint timeout not applied. sending took %s", elapsed)
+	}
+}
+
+func TestSendAllSlowEndPoint(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	received := make(chan struct{}, 1)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer fast.Close()
+
+	SendAll([]EndPoint{{URL: slow.URL}, {URL: fast.URL}}, map[string]string{})
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("fast endpoint was held up by the slow endpoint")
+	}
+}
+
+func TestSendTLSVerify(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	data := map[string]string{}
+
+	// The test server's certificate is self-signed, so it's rejected by
+	// default
+	err := Send(EndPoint{URL: ts.URL}, data)
+	if err == nil {
+		t.Fatalf("expected an error sending to an endpoint with an untrusted certificate")
+	}
+	err = Send(EndPoint{URL: ts.URL, SkipTLSVerify: true}, data)
+	if err != nil {
+		t.Fatalf("error sending data to webhook endpoint skipping TLS verification: %v", err)
+	}
+}
+
+func TestSendAlternateSignature(t *testing.T) {
+	secret := "secret789"
+	data := map[string]string{"key": "val"}
+	body, _ := json.Marshal(data)
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write(body)
+	expected := "sha512=" + hex.EncodeToString(mac.Sum(nil))
+
+	headers := make(chan http.Header, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+	}))
+	defer ts.Close()
+
+	endPoint := EndPoint{
+		URL:                ts.URL,
+		Secret:             secret,
+		SignatureHeader:    "X-Hub-Signature-512",
+		SignatureAlgorithm: "sha512",
+	}
+	err := Send(endPoint, data)
+	if err != nil {
+		t.Fatalf("error sending data to webhook endpoint: %v", err)
+	}
+	h := <-headers
+	if got := h.Get("X-Hub-Signature-512"); got != expected {
+		t.Fatalf("invalid signature received. expected %s got %s", expected, got)
+	}
+	if got := h.Get(SignatureHeader); got != "" {
+		t.Fatalf("unexpected signature in the default header: %s", got)
+	}
+}
+
+func TestSendUnsupportedSignatureAlgorithm(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("webhook sent with an unsupported signature algorithm")
+	}))
+	defer ts.Close()
+	err := Send(EndPoint{URL: ts.URL, SignatureAlgorithm: "md5"}, map[string]string{})
+	if err != ErrUnsupportedSignatureAlgorithm {
+		t.Fatalf("expected %v, got %v", ErrUnsupportedSignatureAlgorithm, err)
+	}
+}
@@ -47,33 +47,51 @@ func WithMailer(m mailer.Mailer) func(*DefaultWorker) error {
 // processCampaigns loads maillogs scheduled to be sent before the provided
 // time and sends them to the mailer.
 func (w *DefaultWorker) processCampaigns(t time.Time) error {
+	halted, err := models.IsSendingHalted()
+	if err != nil {
+		return err
+	}
+	// While sending is halted, sends scheduled in the meantime are cancelled
+	// rather than queued
+	if halted {
+		_, err = models.CancelPendingSends()
+		return err
+	}
 	ms, err := models.GetQueuedMailLogs(t.UTC())
 	if err != nil {
 		log.Error(err)
 		return err
 	}
+	ms, err = deferQuietHours(t.UTC(), ms)
+	if err != nil {
+		return err
+	}
+	// We load each campaign once here to greatly reduce the time it takes to
+	// generate the messages (ref #1726). Maillogs whose campaign couldn't be
+	// loaded within the poll's budget are left for the next poll.
+	campaignCache, err := models.GetQueuedMailContexts(ms)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	ready := []*models.MailLog{}
+	for _, m := range ms {
+		if _, ok := campaignCache[m.CampaignId]; ok {
+			ready = append(ready, m)
+		}
+	}
 	// Lock the MailLogs (they will be unlocked after processing)
-	err = models.LockMailLogs(ms, true)
+	err = models.LockMailLogs(ready, true)
 	if err != nil {
 		return err
 	}
-	campaignCache := make(map[int64]models.Campaign)
 	// We'll group the maillogs by campaign ID to (roughly) group
 	// them by sending profile. This lets the mailer re-use the Sender
 	// instead of having to re-connect to the SMTP server for every
 	// email.
 	msg := make(map[int64][]mailer.Mail)
-	for _, m := range ms {
-		// We cache the campaign here to greatly reduce the time it takes to
-		// generate the message (ref #1726)
-		c, ok := campaignCache[m.CampaignId]
-		if !ok {
-			c, err = models.GetCampaignMailContext(m.CampaignId, m.UserId)
-			if err != nil {
-				return err
-			}
-			campaignCache[c.Id] = c
-		}
+	for _, m := range ready {
+		c := campaignCache[m.CampaignId]
 		m.CacheCampaign(&c)
 		msg[m.CampaignId] = append(msg[m.CampaignId], m)
 	}
@@ -98,13 +116,73 @@ func (w *DefaultWorker) processCampaigns(t time.Time) error {
 	return nil
 }
 
+// deferQuietHours defers the given maillogs if email can't be sent at t
+// because of the organization-wide quiet hours. It returns the maillogs which
+// may still be sent.
+func deferQuietHours(t time.Time, ms []*models.MailLog) ([]*models.MailLog, error) {
+	if len(ms) == 0 {
+		return ms, nil
+	}
+	qh, err := models.GetQuietHours()
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	next := qh.NextAllowed(t)
+	if !next.After(t) {
+		return ms, nil
+	}
+	log.WithFields(logrus.Fields{
+		"num_emails": len(ms),
+		"send_date":  next,
+	}).Info("Deferring emails until the end of quiet hours")
+	for _, m := range ms {
+		err = m.Defer(next)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+	return []*models.MailLog{}, nil
+}
+
 // Start launches the worker to poll the database every minute for any pending maillogs
 // that need to be processed.
 func (w *DefaultWorker) Start() {
 	log.Info("Background Worker Started Successfully - Waiting for Campaigns")
 	go w.mailer.Start(context.Background())
 	for t := range time.Tick(1 * time.Minute) {
-		err := w.processCampaigns(t)
+		// Retry any n8n status callbacks which failed to process
+		err := models.ProcessN8NCallbacks(t.UTC())
+		if err != nil {
+			log.Error(err)
+		}
+		// Retry any n8n campaign launches which failed or were interrupted
+		err = models.RetryN8NLaunches(context.Background(), t.UTC())
+		if err != nil {
+			log.Error(err)
+		}
+		// Clean up orphaned autopilot groups once an hour
+		if retention := models.GetAutopilotGroupRetention(); retention > 0 && t.Minute() == 0 {
+			_, err = models.PurgeOrphanedAutopilotGroups(0, t.UTC().Add(-retention))
+			if err != nil {
+				log.Error(err)
+			}
+		}
+		// Archive long-completed campaigns once an hour, if enabled
+		if age := models.GetAutoArchiveAge(); age > 0 && t.Minute() == 0 {
+			_, err = models.ArchiveCompletedCampaigns(t.UTC().Add(-age))
+			if err != nil {
+				log.Error(err)
+			}
+		}
+		// Prune old authorization logs once an hour, if enabled
+		if retention := models.GetAuthorizationLogRetention(); retention > 0 && t.Minute() == 0 {
+			_, err = models.PruneAuthorizationLogs(t.UTC().Add(-retention), models.GetAuthorizationLogArchiveDir())
+			if err != nil {
+				log.Error(err)
+			}
+		}
+		err = w.processCampaigns(t)
 		if err != nil {
 			log.Error(err)
 			continue
@@ -114,6 +192,21 @@ func (w *DefaultWorker) Start() {
 
 // LaunchCampaign starts a campaign
 func (w *DefaultWorker) LaunchCampaign(c models.Campaign) {
+	halted, err := models.IsSendingHalted()
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if halted {
+		log.WithFields(logrus.Fields{
+			"campaign_id": c.Id,
+		}).Warn("Not launching campaign while sending is halted")
+		_, err = models.CancelPendingSends()
+		if err != nil {
+			log.Error(err)
+		}
+		return
+	}
 	ms, err := models.GetMailLogsByCampaign(c.Id)
 	if err != nil {
 		log.Error(err)
@@ -129,6 +222,12 @@ func (w *DefaultWorker) LaunchCampaign(c models.Campaign) {
 		log.Error(err)
 		return
 	}
+	qh, err := models.GetQuietHours()
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	nextAllowed := qh.NextAllowed(currentTime)
 	for _, m := range ms {
 		// Only send the emails scheduled to be sent for the past minute to
 		// respect the campaign scheduling options
@@ -136,6 +235,13 @@ func (w *DefaultWorker) LaunchCampaign(c models.Campaign) {
 			m.Unlock()
 			continue
 		}
+		if nextAllowed.After(currentTime) {
+			err = m.Defer(nextAllowed)
+			if err != nil {
+				log.Error(err)
+			}
+			continue
+		}
 		err = m.CacheCampaign(&campaignMailCtx)
 		if err != nil {
 			log.Error(err)
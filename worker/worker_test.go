@@ -161,3 +161,51 @@ func TestMailLogGrouping(t *testing.T) {
 		}
 	}
 }
+
+func TestHaltedSendingQueuesNothing(t *testing.T) {
+	setupTest(t)
+
+	campaign, err := setupCampaign(0)
+	if err != nil {
+		t.Fatalf("error creating campaign: %v", err)
+	}
+	ms, err := models.GetMailLogsByCampaign(campaign.Id)
+	if err != nil {
+		t.Fatalf("error getting maillogs for campaign: %v", err)
+	}
+	for _, m := range ms {
+		m.Unlock()
+	}
+	admin, err := models.GetUser(1)
+	if err != nil {
+		t.Fatalf("error getting admin user: %v", err)
+	}
+	_, err = models.HaltSending(admin, "wrong list")
+	if err != nil {
+		t.Fatalf("error halting sending: %v", err)
+	}
+
+	// A campaign created while sending is halted isn't sent either
+	if _, err = setupCampaign(1); err != nil {
+		t.Fatalf("error creating campaign: %v", err)
+	}
+
+	lm := &logMailer{queue: make(chan []mailer.Mail)}
+	worker := &DefaultWorker{}
+	worker.mailer = lm
+	if err = worker.processCampaigns(time.Now()); err != nil {
+		t.Fatalf("error processing campaigns: %v", err)
+	}
+	select {
+	case ms := <-lm.queue:
+		t.Fatalf("unexpected %d emails queued while sending is halted", len(ms))
+	case <-time.After(100 * time.Millisecond):
+	}
+	remaining, err := models.GetQueuedMailLogs(time.Now().UTC())
+	if err != nil {
+		t.Fatalf("error getting maillogs: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Fatalf("unexpected number of pending maillogs: got %d expected 0", len(remaining))
+	}
+}